@@ -0,0 +1,148 @@
+package v1alpha1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// ExtraEgressAnnotation holds a JSON list of raw NetworkPolicyEgressRule appended to the rules
+	// generated by the destinations of an ACL, an escape hatch for peers that destinations can not express
+	ExtraEgressAnnotation = "acl.extensions.tsuru.io/extra-egress"
+
+	// MaxExtraEgressSize is the maximum length in bytes of ExtraEgressAnnotation
+	MaxExtraEgressSize = 16 * 1024
+
+	// MaxExtraEgressRules is the maximum number of rules of ExtraEgressAnnotation
+	MaxExtraEgressRules = 32
+)
+
+// ParseExtraEgress decodes the rules of ExtraEgressAnnotation, unknown fields are rejected and
+// the CIDRs of ipBlocks are returned as canonical CIDRs
+func ParseExtraEgress(value string) ([]netv1.NetworkPolicyEgressRule, error) {
+	if len(value) > MaxExtraEgressSize {
+		return nil, fmt.Errorf("%d bytes exceed the maximum of %d bytes", len(value), MaxExtraEgressSize)
+	}
+
+	var rules []netv1.NetworkPolicyEgressRule
+	decoder := json.NewDecoder(bytes.NewBufferString(value))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&rules)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode a JSON list of egress rules: %s", err.Error())
+	}
+	if _, err = decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("could not decode a JSON list of egress rules: unexpected data after the list")
+	}
+
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no egress rules, remove the annotation instead")
+	}
+	if len(rules) > MaxExtraEgressRules {
+		return nil, fmt.Errorf("%d rules exceed the maximum of %d rules", len(rules), MaxExtraEgressRules)
+	}
+
+	for i := range rules {
+		err = validateExtraEgressRule(&rules[i])
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %s", i, err.Error())
+		}
+	}
+
+	return rules, nil
+}
+
+func validateExtraEgressRule(rule *netv1.NetworkPolicyEgressRule) error {
+	if len(rule.To) == 0 {
+		// a rule without peers would allow every destination
+		return fmt.Errorf("to is required, a rule without peers allows every destination")
+	}
+
+	for i := range rule.To {
+		err := validateExtraEgressPeer(&rule.To[i])
+		if err != nil {
+			return fmt.Errorf("peer %d: %s", i, err.Error())
+		}
+	}
+
+	for i, port := range rule.Ports {
+		err := validateExtraEgressPort(port)
+		if err != nil {
+			return fmt.Errorf("port %d: %s", i, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func validateExtraEgressPeer(peer *netv1.NetworkPolicyPeer) error {
+	if peer.IPBlock != nil {
+		if peer.PodSelector != nil || peer.NamespaceSelector != nil {
+			return fmt.Errorf("ipBlock can not be set with podSelector or namespaceSelector")
+		}
+
+		externalIP := &ACLSpecExternalIP{IP: peer.IPBlock.CIDR, Except: peer.IPBlock.Except}
+		cidr, except, err := externalIP.CIDRs()
+		if err != nil {
+			return err
+		}
+		peer.IPBlock.CIDR, peer.IPBlock.Except = cidr, except
+		return nil
+	}
+
+	if peer.PodSelector == nil && peer.NamespaceSelector == nil {
+		return fmt.Errorf("one of ipBlock, podSelector or namespaceSelector is required")
+	}
+
+	for _, selector := range []*metav1.LabelSelector{peer.PodSelector, peer.NamespaceSelector} {
+		if selector == nil {
+			continue
+		}
+		_, err := metav1.LabelSelectorAsSelector(selector)
+		if err != nil {
+			return fmt.Errorf("invalid selector: %s", err.Error())
+		}
+	}
+
+	return nil
+}
+
+func validateExtraEgressPort(port netv1.NetworkPolicyPort) error {
+	protoPort := ProtoPort{}
+	if port.Protocol != nil {
+		protoPort.Protocol = string(*port.Protocol)
+	}
+
+	if port.Port == nil {
+		if port.EndPort != nil {
+			return fmt.Errorf("endPort requires port")
+		}
+		// every port of the protocol
+		_, err := ParseProtocol(protoPort.Protocol)
+		return err
+	}
+
+	if port.Port.Type == intstr.String {
+		protoPort.Name = port.Port.StrVal
+	} else {
+		if port.Port.IntVal < 1 || port.Port.IntVal > 65535 {
+			return fmt.Errorf("port %d must be between 1 and 65535", port.Port.IntVal)
+		}
+		protoPort.Number = uint16(port.Port.IntVal)
+	}
+
+	if port.EndPort != nil {
+		if *port.EndPort < 1 || *port.EndPort > 65535 {
+			return fmt.Errorf("endPort %d must be between 1 and 65535", *port.EndPort)
+		}
+		protoPort.EndPort = uint16(*port.EndPort)
+	}
+
+	return protoPort.Validate()
+}
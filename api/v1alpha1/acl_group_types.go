@@ -0,0 +1,85 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ACLGroupSpec defines the desired state of ACLGroup, each source gets an ACL with the
+// destinations and the remaining fields of the group
+type ACLGroupSpec struct {
+	//+kubebuilder:validation:MinItems=1
+	Sources      []ACLSpecSource      `json:"sources"`
+	Destinations []ACLSpecDestination `json:"destinations"`
+	Ingress      []ACLSpecIngress     `json:"ingress,omitempty"`
+
+	IPFamilies      []IPFamily              `json:"ipFamilies,omitempty"`
+	CIDRAggregation *ACLSpecCIDRAggregation `json:"cidrAggregation,omitempty"`
+	AddressFilter   *ACLSpecAddressFilter   `json:"addressFilter,omitempty"`
+	Template        *ACLSpecTemplate        `json:"template,omitempty"`
+}
+
+// ACLGroupStatus defines the observed state of ACLGroup
+type ACLGroupStatus struct {
+	// Ready is true when every ACL of the group is ready
+	Ready  bool   `json:"ready"`
+	Reason string `json:"reason,omitempty"`
+
+	// ReadyACLs is the number of ready ACLs of the group
+	ReadyACLs int `json:"readyACLs"`
+	// TotalACLs is the number of sources of the group
+	TotalACLs int `json:"totalACLs"`
+
+	ACLs []ACLGroupStatusACL `json:"acls,omitempty"`
+}
+
+type ACLGroupStatusACL struct {
+	Name string `json:"name"`
+	// Source describes the source of ACL, like "tsuruApp myapp"
+	Source string `json:"source"`
+	Ready  bool   `json:"ready"`
+	Reason string `json:"reason,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Ready",type=boolean,JSONPath=`.status.ready`
+//+kubebuilder:printcolumn:name="Ready-ACLs",type=integer,JSONPath=`.status.readyACLs`
+//+kubebuilder:printcolumn:name="Total-ACLs",type=integer,JSONPath=`.status.totalACLs`
+
+// ACLGroup is the Schema for the aclgroups API
+type ACLGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ACLGroupSpec   `json:"spec,omitempty"`
+	Status ACLGroupStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ACLGroupList contains a list of ACLGroup
+type ACLGroupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ACLGroup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ACLGroup{}, &ACLGroupList{})
+}
@@ -0,0 +1,177 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"time"
+)
+
+// ACLSpecSchedule restricts the egress of the destinations of an ACL to windows of time, outside
+// of them the policy allows no egress
+type ACLSpecSchedule struct {
+	// TimeZone is the IANA name of the time zone of windows, like America/Sao_Paulo or UTC, so
+	// windows follow the daylight saving time of the zone
+	TimeZone string `json:"timeZone"`
+
+	// Windows are the periods the egress of destinations is allowed, overlapping windows are merged
+	//+kubebuilder:validation:MinItems=1
+	Windows []ACLSpecScheduleWindow `json:"windows"`
+}
+
+// ACLSpecScheduleWindow opens at start and closes at end on the listed days, a window whose end
+// is not after its start closes on the next day, like 22:00 to 06:00
+type ACLSpecScheduleWindow struct {
+	// Days are the weekdays the window opens on, every day when empty
+	Days []Weekday `json:"days,omitempty"`
+
+	// Start is the time of day the window opens, as HH:MM
+	Start string `json:"start"`
+
+	// End is the time of day the window closes, as HH:MM
+	End string `json:"end"`
+}
+
+// +kubebuilder:validation:Enum=Sun;Mon;Tue;Wed;Thu;Fri;Sat
+type Weekday string
+
+var weekdays = map[Weekday]time.Weekday{
+	"Sun": time.Sunday,
+	"Mon": time.Monday,
+	"Tue": time.Tuesday,
+	"Wed": time.Wednesday,
+	"Thu": time.Thursday,
+	"Fri": time.Friday,
+	"Sat": time.Saturday,
+}
+
+// scheduleHorizon bounds the search of transitions, every window opens at least once a week
+const scheduleHorizon = 8
+
+func (s *ACLSpecSchedule) Validate() error {
+	_, err := time.LoadLocation(s.TimeZone)
+	if s.TimeZone == "" || err != nil {
+		return fmt.Errorf("invalid timeZone %q, an IANA time zone like America/Sao_Paulo is required", s.TimeZone)
+	}
+
+	if len(s.Windows) == 0 {
+		return fmt.Errorf("at least one window is required")
+	}
+
+	for i, window := range s.Windows {
+		err = window.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid window %d: %s", i, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (w *ACLSpecScheduleWindow) Validate() error {
+	start, err := parseTimeOfDay(w.Start)
+	if err != nil {
+		return fmt.Errorf("invalid start: %s", err.Error())
+	}
+
+	end, err := parseTimeOfDay(w.End)
+	if err != nil {
+		return fmt.Errorf("invalid end: %s", err.Error())
+	}
+
+	if start == end {
+		return fmt.Errorf("start and end are both %s", w.Start)
+	}
+
+	for _, day := range w.Days {
+		if _, ok := weekdays[day]; !ok {
+			return fmt.Errorf("invalid day %q, use one of Sun, Mon, Tue, Wed, Thu, Fri or Sat", day)
+		}
+	}
+
+	return nil
+}
+
+// Open returns whether a window of schedule is open at now and when the next transition, the
+// opening or closing of the windows, happens. Times of day are taken on the calendar of
+// time zone, a time skipped by daylight saving time is moved forward by the length of the gap
+func (s *ACLSpecSchedule) Open(now time.Time) (bool, time.Time, error) {
+	err := s.Validate()
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	location, _ := time.LoadLocation(s.TimeZone)
+	now = now.In(location)
+
+	type interval struct{ start, end time.Time }
+	intervals := []interval{}
+	for day := -1; day <= scheduleHorizon; day++ {
+		date := time.Date(now.Year(), now.Month(), now.Day()+day, 0, 0, 0, 0, location)
+		for _, window := range s.Windows {
+			if !window.opensOn(date.Weekday()) {
+				continue
+			}
+
+			start, _ := parseTimeOfDay(window.Start)
+			end, _ := parseTimeOfDay(window.End)
+			endDate := date
+			if end <= start {
+				endDate = date.AddDate(0, 0, 1)
+			}
+			intervals = append(intervals, interval{
+				start: time.Date(date.Year(), date.Month(), date.Day(), 0, int(start), 0, 0, location),
+				end:   time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 0, int(end), 0, 0, location),
+			})
+		}
+	}
+
+	closesAt := time.Time{}
+	for _, i := range intervals {
+		if !i.start.After(now) && i.end.After(now) && i.end.After(closesAt) {
+			closesAt = i.end
+		}
+	}
+
+	if closesAt.IsZero() {
+		opensAt := time.Time{}
+		for _, i := range intervals {
+			if i.start.After(now) && (opensAt.IsZero() || i.start.Before(opensAt)) {
+				opensAt = i.start
+			}
+		}
+		return false, opensAt, nil
+	}
+
+	// windows that open before the current one closes extend it
+	for extended := true; extended; {
+		extended = false
+		for _, i := range intervals {
+			if !i.start.After(closesAt) && i.end.After(closesAt) {
+				closesAt = i.end
+				extended = true
+			}
+		}
+	}
+
+	return true, closesAt, nil
+}
+
+func (w *ACLSpecScheduleWindow) opensOn(weekday time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, day := range w.Days {
+		if weekdays[day] == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTimeOfDay returns the minutes since midnight of HH:MM
+func parseTimeOfDay(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day as HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
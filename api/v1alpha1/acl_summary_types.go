@@ -0,0 +1,97 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ACLSummarySpec defines the ACLs counted by an ACLSummary
+type ACLSummarySpec struct {
+	// Namespace restricts the summary to the ACLs of a namespace, ACLs of every namespace are counted when empty
+	Namespace string `json:"namespace,omitempty"`
+
+	// MaxFailing is the maximum number of ACLs listed on status.failing, defaults to 10
+	//+kubebuilder:validation:Minimum=0
+	//+kubebuilder:validation:Maximum=100
+	MaxFailing *int32 `json:"maxFailing,omitempty"`
+}
+
+// ACLSummaryStatus counts the ACLs by state, ACLs are counted once on ready, notReady or paused,
+// degraded ACLs are counted on degraded as well
+type ACLSummaryStatus struct {
+	TotalACLs    int `json:"totalACLs"`
+	ReadyACLs    int `json:"readyACLs"`
+	NotReadyACLs int `json:"notReadyACLs"`
+	PausedACLs   int `json:"pausedACLs"`
+	// DegradedACLs have the condition Degraded, their policies miss or use stale rules of some destinations
+	DegradedACLs int `json:"degradedACLs"`
+
+	// Reasons counts the not ready ACLs by the reason of their Ready condition, most frequent first
+	Reasons []ACLSummaryReason `json:"reasons,omitempty"`
+
+	// Failing lists the not ready ACLs that have been failing for the longest time
+	Failing []ACLSummaryFailingACL `json:"failing,omitempty"`
+
+	// LastUpdated is when the counts were taken, in RFC3339
+	LastUpdated string `json:"lastUpdated,omitempty"`
+}
+
+type ACLSummaryReason struct {
+	Reason string `json:"reason"`
+	Count  int    `json:"count"`
+}
+
+type ACLSummaryFailingACL struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Reason    string `json:"reason,omitempty"`
+	// Since is when the ACL became not ready, in RFC3339
+	Since string `json:"since,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Total",type=integer,JSONPath=`.status.totalACLs`
+//+kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyACLs`
+//+kubebuilder:printcolumn:name="Not-Ready",type=integer,JSONPath=`.status.notReadyACLs`
+//+kubebuilder:printcolumn:name="Degraded",type=integer,JSONPath=`.status.degradedACLs`
+//+kubebuilder:printcolumn:name="Paused",type=integer,JSONPath=`.status.pausedACLs`,priority=1
+//+kubebuilder:printcolumn:name="Namespace",type=string,JSONPath=`.spec.namespace`,priority=1
+
+// ACLSummary aggregates the states of ACLs on its status, so dashboards and alerts do not list every ACL
+type ACLSummary struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ACLSummarySpec   `json:"spec,omitempty"`
+	Status ACLSummaryStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ACLSummaryList contains a list of ACLSummary
+type ACLSummaryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ACLSummary `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ACLSummary{}, &ACLSummaryList{})
+}
@@ -25,12 +25,90 @@ import (
 type ACLSpec struct {
 	Source       ACLSpecSource        `json:"source"`
 	Destinations []ACLSpecDestination `json:"destinations"`
+	Ingress      []ACLSpecIngress     `json:"ingress,omitempty"`
+
+	// IPFamilies restricts the addresses resolved for externalDNS destinations, all families are allowed when empty
+	IPFamilies []IPFamily `json:"ipFamilies,omitempty"`
+
+	// CIDRAggregation summarizes the addresses resolved for externalDNS destinations, defaults to the configuration of operator
+	CIDRAggregation *ACLSpecCIDRAggregation `json:"cidrAggregation,omitempty"`
+
+	// AddressFilter drops unexpected addresses resolved for externalDNS destinations, defaults to the configuration of operator
+	AddressFilter *ACLSpecAddressFilter `json:"addressFilter,omitempty"`
+
+	// Template is applied on the policy generated by the ACL
+	Template *ACLSpecTemplate `json:"template,omitempty"`
+
+	// DisableServiceTranslation keeps the addresses of services of the cluster as they are, the pods
+	// behind a service IP are not allowed by pod selectors, only the address itself
+	DisableServiceTranslation bool `json:"disableServiceTranslation,omitempty"`
+
+	// Schedule applies the egress of destinations only during its windows, the egress is always
+	// allowed when empty
+	Schedule *ACLSpecSchedule `json:"schedule,omitempty"`
+}
+
+type ACLSpecTemplate struct {
+	Metadata ACLSpecTemplateMetadata `json:"metadata,omitempty"`
+}
+
+// ACLSpecTemplateMetadata is merged on the metadata of policy, labels and annotations set by others are kept
+type ACLSpecTemplateMetadata struct {
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ACLSpecCIDRAggregation coalesces the addresses of a destination into the smallest list of CIDRs
+type ACLSpecCIDRAggregation struct {
+	// Enabled summarizes adjacent addresses, the CIDRs cover only the resolved addresses
+	Enabled bool `json:"enabled"`
+
+	// IPv4PrefixLength widens IPv4 addresses to their network of this length before the summarization,
+	// allowing addresses that were not resolved, addresses are not widened when empty
+	// +kubebuilder:validation:Minimum=16
+	// +kubebuilder:validation:Maximum=32
+	IPv4PrefixLength *int32 `json:"ipv4PrefixLength,omitempty"`
+
+	// IPv6PrefixLength widens IPv6 addresses to their network of this length before the summarization,
+	// allowing addresses that were not resolved, addresses are not widened when empty
+	// +kubebuilder:validation:Minimum=48
+	// +kubebuilder:validation:Maximum=128
+	IPv6PrefixLength *int32 `json:"ipv6PrefixLength,omitempty"`
+}
+
+// ACLSpecAddressFilter guards the policy against DNS answers pointing to unintended networks,
+// like a public host that starts resolving to a private address
+type ACLSpecAddressFilter struct {
+	// Allow are the CIDRs resolved addresses must be contained in, every address is allowed when empty
+	Allow []string `json:"allow,omitempty"`
+
+	// Deny are the CIDRs of resolved addresses that are dropped, like 10.0.0.0/8 for public hosts
+	Deny []string `json:"deny,omitempty"`
 }
 
+// +kubebuilder:validation:Enum=IPv4;IPv6
+type IPFamily string
+
+const (
+	IPv4Family IPFamily = "IPv4"
+	IPv6Family IPFamily = "IPv6"
+)
+
 type ACLSpecSource struct {
 	TsuruApp      string                `json:"tsuruApp,omitempty"`
 	TsuruJob      string                `json:"tsuruJob,omitempty"`
 	RpaasInstance *ACLSpecRpaasInstance `json:"rpaasInstance,omitempty"`
+	// RawPodSelector selects pods that are not managed by Tsuru, it is used as it is by the policy
+	RawPodSelector *metav1.LabelSelector `json:"rawPodSelector,omitempty"`
+	// Workload selects the pods of a Deployment or StatefulSet of the namespace of ACL, the selector
+	// of workload is looked up on every reconcile, so the policy follows its changes
+	Workload *ACLSpecWorkload `json:"workload,omitempty"`
+}
+
+type ACLSpecWorkload struct {
+	//+kubebuilder:validation:Enum=Deployment;StatefulSet
+	Kind string `json:"kind"`
+	Name string `json:"name"`
 }
 
 type ACLSpecRpaasInstance struct {
@@ -38,23 +116,106 @@ type ACLSpecRpaasInstance struct {
 	Instance    string `json:"instance"`
 }
 
+type ACLSpecTsuruServiceInstance struct {
+	ServiceName string `json:"serviceName"`
+	Instance    string `json:"instance"`
+}
+
 type ACLSpecDestination struct {
 	RuleID string `json:"ruleID,omitempty"`
 
+	// ExpiresAt drops the destination from the policy once it passes, like a temporary allowance,
+	// the destination is kept on spec and reported as expired on status.resolvedDestinations
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	TsuruApp string `json:"tsuruApp,omitempty"`
+	// TsuruAppProcess restricts the pods of tsuruApp to a single process, like web, the router
+	// addresses of app are still allowed
+	TsuruAppProcess string `json:"tsuruAppProcess,omitempty"`
+	// TsuruAppRouters restricts the router addresses of tsuruApp to the routers with these names,
+	// like an external router of an app with an internal one as well, every router when empty
+	TsuruAppRouters []string `json:"tsuruAppRouters,omitempty"`
+	TsuruAppPool    string   `json:"tsuruAppPool,omitempty"`
+	// TsuruAppPoolExcept are apps of tsuruAppPool that are not allowed, neither their pods nor
+	// their router addresses
+	TsuruAppPoolExcept []string              `json:"tsuruAppPoolExcept,omitempty"`
+	RpaasInstance      *ACLSpecRpaasInstance `json:"rpaasInstance,omitempty"`
+	ExternalDNS        *ACLSpecExternalDNS   `json:"externalDNS,omitempty"`
+	ExternalIP         *ACLSpecExternalIP    `json:"externalIP,omitempty"`
+	// ExternalEndpoints is a shorthand for externalIP destinations of single addresses, each endpoint
+	// is ip:port/protocol, like 10.0.0.1:443/tcp or [2001:db8::1]:53/udp, the protocol defaults to TCP
+	ExternalEndpoints []string `json:"externalEndpoints,omitempty"`
+	// ExternalSRV allows the targets of a SRV record on the ports of the answer
+	ExternalSRV *ACLSpecExternalSRV `json:"externalSRV,omitempty"`
+	// KubernetesService allows the pods selected by a service of the cluster on the target ports of service
+	KubernetesService *ACLSpecKubernetesService `json:"kubernetesService,omitempty"`
+	// Deny allows everything inside of a base CIDR except the listed CIDRs
+	Deny *ACLSpecDeny `json:"deny,omitempty"`
+	// TsuruServiceInstance allows the endpoints of an instance of a Tsuru service, read from the custom info of instance
+	TsuruServiceInstance *ACLSpecTsuruServiceInstance `json:"tsuruServiceInstance,omitempty"`
+	// ConfigMap allows the IPs, CIDRs and hostnames listed on a key of a ConfigMap, like an egress
+	// allowlist maintained centrally, the ACL is reconciled again when the ConfigMap changes
+	ConfigMap *ACLSpecConfigMap `json:"configMap,omitempty"`
+}
+
+// ACLSpecIngress describes a peer that is allowed to connect to the pods selected by spec.source
+type ACLSpecIngress struct {
 	TsuruApp      string                `json:"tsuruApp,omitempty"`
+	TsuruJob      string                `json:"tsuruJob,omitempty"`
 	TsuruAppPool  string                `json:"tsuruAppPool,omitempty"`
 	RpaasInstance *ACLSpecRpaasInstance `json:"rpaasInstance,omitempty"`
-	ExternalDNS   *ACLSpecExternalDNS   `json:"externalDNS,omitempty"`
 	ExternalIP    *ACLSpecExternalIP    `json:"externalIP,omitempty"`
 }
 
 type ACLSpecExternalDNS struct {
 	Name  string            `json:"name"`
 	Ports ACLSpecProtoPorts `json:"ports,omitempty"`
+	// Resolver resolves name on custom nameservers instead of the resolver of operator, without
+	// falling back to the system resolver
+	Resolver *ACLSpecDNSResolver `json:"resolver,omitempty"`
+}
+
+type ACLSpecExternalSRV struct {
+	// Name is the SRV record as _service._proto.host, the proto label is the protocol of the ports
+	Name string `json:"name"`
+}
+
+type ACLSpecKubernetesService struct {
+	// Namespace is the namespace of service, defaults to the namespace of ACL
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+type ACLSpecConfigMap struct {
+	// Namespace is the namespace of ConfigMap, it must be the namespace of ACL, ConfigMaps of other
+	// namespaces are not allowed
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Key holds the addresses, one per line or separated by commas or spaces, text after # is a comment
+	Key   string            `json:"key"`
+	Ports ACLSpecProtoPorts `json:"ports,omitempty"`
+}
+
+type ACLSpecDNSResolver struct {
+	// Nameservers are IP addresses with an optional port, 53 is used when the port is omitted,
+	// tls:// URLs of DNS-over-TLS servers, like tls://1.1.1.1, or https:// URLs of DNS-over-HTTPS
+	// servers, like https://dns.example.com/dns-query
+	//+kubebuilder:validation:MinItems=1
+	Nameservers []string `json:"nameservers"`
 }
 
 type ACLSpecExternalIP struct {
-	IP    string            `json:"ip"`
+	IP string `json:"ip"`
+	// Except is a list of IPs or CIDRs inside of IP that must not be allowed
+	Except []string          `json:"except,omitempty"`
+	Ports  ACLSpecProtoPorts `json:"ports,omitempty"`
+}
+
+type ACLSpecDeny struct {
+	// Base is the CIDR that is allowed, defaults to 0.0.0.0/0
+	Base string `json:"base,omitempty"`
+	// CIDRs are IPs or CIDRs inside of base that must not be allowed, overlapping CIDRs are merged
+	CIDRs []string          `json:"cidrs"`
 	Ports ACLSpecProtoPorts `json:"ports,omitempty"`
 }
 
@@ -62,18 +223,140 @@ type ACLSpecProtoPorts []ProtoPort
 
 type ProtoPort struct {
 	Protocol string `json:"protocol"`
-	Number   uint16 `json:"number"`
+	// Number is the port number, a port sets either Number or Name
+	Number uint16 `json:"number,omitempty"`
+	// Name is a named port of the containers of destination pods, like http, it only matches
+	// destinations that are pods, like the pods of a kubernetesService
+	Name string `json:"name,omitempty"`
+	// EndPort allows a range of ports from Number to EndPort, only TCP and UDP are supported
+	EndPort uint16 `json:"endPort,omitempty"`
 }
 
+const (
+	// ACLConditionReady reports whether the NetworkPolicy reflects the latest generation of spec
+	ACLConditionReady = "Ready"
+	// ACLConditionDegraded reports whether the policy misses or uses stale rules of destinations that could not be resolved,
+	// or is kept as it was last applied until they are resolved again
+	ACLConditionDegraded = "Degraded"
+	// ACLConditionPaused is set while the ACL has the annotation acl.extensions.tsuru.io/paused,
+	// its policy is neither created nor updated
+	ACLConditionPaused = "Paused"
+)
+
 // ACLStatus defines the observed state of ACL
 type ACLStatus struct {
-	NetworkPolicy string   `json:"networkPolicy,omitempty"`
+	NetworkPolicy string `json:"networkPolicy,omitempty"`
+	// PolicyBackend is the backend that produced the policy named by networkPolicy
+	PolicyBackend string   `json:"policyBackend,omitempty"`
 	Ready         bool     `json:"ready"`
 	Reason        string   `json:"reason,omitempty"`
 	WarningErrors []string `json:"warningErrors,omitempty"`
 
+	// Warnings lists destinations that are ignored because the policy can not express them,
+	// peers allowed by many destinations with different ports, which get the union of the ports,
+	// and resolved addresses dropped by the address filter
+	Warnings []string `json:"warnings,omitempty"`
+
+	// ExtraEgressRules is the number of raw egress rules of the annotation
+	// acl.extensions.tsuru.io/extra-egress merged into the policy
+	ExtraEgressRules int `json:"extraEgressRules,omitempty"`
+
+	// Schedule reports the windows of spec.schedule, the egress of destinations is applied while open
+	Schedule *ACLStatusSchedule `json:"schedule,omitempty"`
+
+	// NextExpiry is when the next destination expires, in RFC3339, the ACL is reconciled again then
+	NextExpiry string `json:"nextExpiry,omitempty"`
+
+	// InProgress is true while the destinations are resolved over many reconciles, the policy
+	// has the rules of the destinations resolved so far and the stale rules of the remaining ones
+	InProgress bool `json:"inProgress,omitempty"`
+
+	// Source describes spec.source the policy was last applied for, like "tsuruApp myapp"
+	Source string `json:"source,omitempty"`
+
+	// DryRun is true when the operator runs in dry-run mode, the policy is not enforced
+	DryRun bool `json:"dryRun,omitempty"`
+	// DryRunDiff is the difference between the existing and the desired policy in dry-run mode
+	DryRunDiff string `json:"dryRunDiff,omitempty"`
+
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
 	Stale      []ACLStatusStale     `json:"stale,omitempty"`
 	RuleErrors []ACLStatusRuleError `json:"errors,omitempty"`
+
+	// ResolvedDestinations summarizes the peers generated by each destination of spec.destinations
+	ResolvedDestinations []ACLStatusResolvedDestination `json:"resolvedDestinations,omitempty"`
+
+	// LastApplied identifies the egress rules of the last policy written by the operator, the policy
+	// is kept as it is while destinations of the same generation of spec fail to resolve
+	LastApplied *ACLStatusLastApplied `json:"lastApplied,omitempty"`
+
+	// RecentFailures are the last failures of reconcile, oldest first, reason has only the latest
+	RecentFailures []ACLStatusFailure `json:"recentFailures,omitempty"`
+
+	// AddressObjects are the shared address objects that have the ACL on their owners annotation,
+	// the ones no longer used by spec are released on the next reconcile
+	AddressObjects []ACLStatusAddressObject `json:"addressObjects,omitempty"`
+}
+
+// ACLStatusAddressObject is a cluster-scoped address object, like an ACLDNSEntry
+type ACLStatusAddressObject struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// ACLStatusFailure is a reconcile that left the ACL not ready, failures with the same reason and
+// message in a row are kept as a single one
+type ACLStatusFailure struct {
+	// Timestamp is when the failure first happened in a row, in RFC3339
+	Timestamp string `json:"timestamp"`
+	// LastTimestamp is when the failure last happened, in RFC3339, it is refreshed every few
+	// minutes instead of on each reconcile
+	LastTimestamp string `json:"lastTimestamp,omitempty"`
+	// Reason is the reason of the event of failure, like DestinationResolutionFailed
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// ACLStatusSchedule is the state of the windows of spec.schedule
+type ACLStatusSchedule struct {
+	// Open is whether a window is open, the policy allows no egress of destinations while closed
+	Open bool `json:"open"`
+	// NextTransition is when the windows open or close next, in RFC3339
+	NextTransition string `json:"nextTransition,omitempty"`
+}
+
+type ACLStatusLastApplied struct {
+	// Hash is the sha256 of the egress rules of policy
+	Hash string `json:"hash"`
+	// Timestamp is when the policy got these egress rules
+	Timestamp string `json:"timestamp"`
+	// Generation is the generation of ACL the policy was last applied for
+	Generation int64 `json:"generation"`
+}
+
+type ACLStatusResolvedDestination struct {
+	// Index is the position of destination on spec.destinations
+	Index  int    `json:"index"`
+	RuleID string `json:"ruleID,omitempty"`
+	// Destination describes the destination, like "externalDNS example.com"
+	Destination string `json:"destination"`
+
+	// HasRules is false when the destination did not contribute any rule to the policy
+	HasRules bool `json:"hasRules"`
+	// Stale is true when the rules of the last successful resolution are used
+	Stale bool `json:"stale,omitempty"`
+
+	CIDRs     []string `json:"cidrs,omitempty"`
+	Selectors []string `json:"selectors,omitempty"`
+	// FQDNs are hostnames resolved by the policy backend
+	FQDNs []string `json:"fqdns,omitempty"`
+	// RejectedIPs are resolved addresses dropped by the address filter
+	RejectedIPs []string `json:"rejectedIPs,omitempty"`
+	// ExpiredAt is when expiresAt of destination passed, in RFC3339, expired destinations have no rules
+	ExpiredAt string `json:"expiredAt,omitempty"`
 }
 
 type ACLStatusStale struct {
@@ -84,11 +367,14 @@ type ACLStatusStale struct {
 type ACLStatusRuleError struct {
 	RuleID string `json:"ruleID"`
 	Error  string `json:"error"`
+	// Destination describes a destination without ruleID, it is skipped because there are no stale rules
+	Destination string `json:"destination,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:printcolumn:name="Ready",type=boolean,JSONPath=`.status.ready`
+//+kubebuilder:printcolumn:name="Dry-Run",type=boolean,JSONPath=`.status.dryRun`,priority=1
 
 // ACL is the Schema for the acls API
 type ACL struct {
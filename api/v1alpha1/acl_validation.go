@@ -0,0 +1,766 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// validation helpers are shared by the admission webhook and the ACL reconciler
+
+// ParseCIDR accepts a CIDR or a single IP that is converted to a /32 or /128 CIDR,
+// the returned CIDR is canonical, IPv6 is lower-cased and compressed and IPv4-mapped
+// IPv6 addresses are converted to IPv4, so parsing it again returns the same CIDR.
+// The zone of an IPv6 address, like fe80::1%eth0, is dropped
+func ParseCIDR(address string) (string, *net.IPNet, error) {
+	host, prefix, hasPrefix := strings.Cut(address, "/")
+	if zone := strings.IndexByte(host, '%'); zone >= 0 {
+		// a zone only scopes a link-local address to an interface of the node
+		host = host[:zone]
+	}
+
+	if !hasPrefix {
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return "", nil, &net.ParseError{Type: "IP address", Text: address}
+		}
+		if ipv4 := ip.To4(); ipv4 != nil {
+			return ipv4.String() + "/32", &net.IPNet{IP: ipv4, Mask: net.CIDRMask(8*net.IPv4len, 8*net.IPv4len)}, nil
+		}
+		return ip.String() + "/128", &net.IPNet{IP: ip, Mask: net.CIDRMask(8*net.IPv6len, 8*net.IPv6len)}, nil
+	}
+
+	ip, network, err := net.ParseCIDR(host + "/" + prefix)
+	if err != nil {
+		return "", nil, &net.ParseError{Type: "CIDR address", Text: address}
+	}
+
+	ones, bits := network.Mask.Size()
+	if ipv4 := ip.To4(); ipv4 != nil && bits == 8*net.IPv6len {
+		if ones < 96 {
+			return "", nil, fmt.Errorf("invalid CIDR address: %s, the prefix of IPv4-mapped address must be at least 96", address)
+		}
+		ip, ones = ipv4, ones-96
+		network = &net.IPNet{IP: network.IP.To4(), Mask: net.CIDRMask(ones, 8*net.IPv4len)}
+	}
+
+	return ip.String() + "/" + strconv.Itoa(ones), network, nil
+}
+
+// CIDRFamily returns the IP family of a network returned by ParseCIDR
+func CIDRFamily(network *net.IPNet) IPFamily {
+	if len(network.IP) == net.IPv4len {
+		return IPv4Family
+	}
+	return IPv6Family
+}
+
+// Normalize rewrites the IP and except list of externalIP as canonical CIDRs, invalid
+// addresses are kept as they are to be reported by the validation
+func (e *ACLSpecExternalIP) Normalize() {
+	if cidr, _, err := ParseCIDR(e.IP); err == nil {
+		e.IP = cidr
+	}
+
+	for i, exceptIP := range e.Except {
+		if cidr, _, err := ParseCIDR(exceptIP); err == nil {
+			e.Except[i] = cidr
+		}
+	}
+}
+
+// CIDRs returns the main CIDR and the except list of externalIP
+func (e *ACLSpecExternalIP) CIDRs() (string, []string, error) {
+	cidr, network, err := ParseCIDR(e.IP)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid externalIP %q: %s", e.IP, err.Error())
+	}
+	networkBits, _ := network.Mask.Size()
+
+	var except []string
+	for _, exceptIP := range e.Except {
+		exceptCIDR, exceptNetwork, err := ParseCIDR(exceptIP)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid except %q of externalIP %q: %s", exceptIP, e.IP, err.Error())
+		}
+
+		exceptBits, _ := exceptNetwork.Mask.Size()
+		if !network.Contains(exceptNetwork.IP) || len(exceptNetwork.IP) != len(network.IP) || exceptBits <= networkBits {
+			return "", nil, fmt.Errorf("except %q is not contained in externalIP %q", exceptIP, e.IP)
+		}
+
+		except = append(except, exceptCIDR)
+	}
+
+	return cidr, mergeCIDRs(except), nil
+}
+
+// mergeCIDRs removes CIDRs that are contained in another CIDR of the list, CIDRs are
+// aligned to their prefix so overlapping CIDRs are always contained in each other
+func mergeCIDRs(cidrs []string) []string {
+	if len(cidrs) == 0 {
+		return cidrs
+	}
+
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, networks[i], _ = net.ParseCIDR(cidr)
+	}
+
+	merged := []string{}
+	for i, network := range networks {
+		bits, _ := network.Mask.Size()
+		contained := false
+		for j, other := range networks {
+			if i == j || len(other.IP) != len(network.IP) || !other.Contains(network.IP) {
+				continue
+			}
+
+			otherBits, _ := other.Mask.Size()
+			// equal CIDRs keep the first one
+			if otherBits < bits || (otherBits == bits && j < i) {
+				contained = true
+				break
+			}
+		}
+
+		if !contained {
+			merged = append(merged, cidrs[i])
+		}
+	}
+
+	return merged
+}
+
+// ExternalIP returns the externalIP with the same rules of deny
+func (d *ACLSpecDeny) ExternalIP() *ACLSpecExternalIP {
+	base := d.Base
+	if base == "" {
+		base = "0.0.0.0/0"
+	}
+
+	return &ACLSpecExternalIP{
+		IP:     base,
+		Except: d.CIDRs,
+		Ports:  d.Ports,
+	}
+}
+
+func (d *ACLSpecDeny) Validate() error {
+	if len(d.CIDRs) == 0 {
+		return fmt.Errorf("deny requires at least one CIDR")
+	}
+
+	return d.ExternalIP().Validate()
+}
+
+func (e *ACLSpecExternalIP) Validate() error {
+	_, _, err := e.CIDRs()
+	if err != nil {
+		return err
+	}
+
+	return e.Ports.Validate()
+}
+
+func (p ACLSpecProtoPorts) Validate() error {
+	for _, port := range p {
+		err := port.Validate()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *ACLSpecAddressFilter) Validate() error {
+	if len(f.Allow) == 0 && len(f.Deny) == 0 {
+		return fmt.Errorf("addressFilter requires allow or deny CIDRs")
+	}
+
+	for _, cidr := range f.Allow {
+		_, _, err := ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid allow %q of addressFilter: %s", cidr, err.Error())
+		}
+	}
+
+	for _, cidr := range f.Deny {
+		_, _, err := ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid deny %q of addressFilter: %s", cidr, err.Error())
+		}
+	}
+
+	return nil
+}
+
+const (
+	// DNSOverTLSScheme prefixes the nameservers queried with DNS-over-TLS, like tls://1.1.1.1
+	DNSOverTLSScheme = "tls://"
+	// DNSOverHTTPSScheme prefixes the nameservers queried with DNS-over-HTTPS, like https://dns.example.com/dns-query
+	DNSOverHTTPSScheme = "https://"
+)
+
+// ParseNameserver returns the canonical address of nameserver, which is either an IP address with
+// an optional port, 53 by default, queried over UDP, a tls:// URL of a host with an optional port,
+// 853 by default, or an https:// URL whose path defaults to /dns-query. Hosts of URLs may be names,
+// they are resolved by the system resolver and verified by the certificate of server
+func ParseNameserver(nameserver string) (string, error) {
+	invalidErr := fmt.Errorf("invalid nameserver %q, use an IP address with an optional port, a tls:// or an https:// URL", nameserver)
+
+	if strings.HasPrefix(nameserver, DNSOverTLSScheme) || strings.HasPrefix(nameserver, DNSOverHTTPSScheme) {
+		u, err := url.Parse(nameserver)
+		if err != nil || u.User != nil || u.RawQuery != "" || u.Fragment != "" || !validNameserverHost(u.Hostname()) {
+			return "", invalidErr
+		}
+
+		port := u.Port()
+		if port != "" && !validPort(port) {
+			return "", invalidErr
+		}
+
+		host := strings.ToLower(u.Hostname())
+		if ip := net.ParseIP(host); ip != nil {
+			host = ip.String()
+		}
+
+		if u.Scheme+"://" == DNSOverTLSScheme {
+			if u.Path != "" {
+				return "", invalidErr
+			}
+			if port == "" {
+				port = "853"
+			}
+			return DNSOverTLSScheme + net.JoinHostPort(host, port), nil
+		}
+
+		u.Host = host
+		if strings.Contains(host, ":") {
+			u.Host = "[" + host + "]"
+		}
+		if port != "" {
+			u.Host = net.JoinHostPort(host, port)
+		}
+		if u.Path == "" {
+			u.Path = "/dns-query"
+		}
+		return u.String(), nil
+	}
+
+	host, port, err := net.SplitHostPort(nameserver)
+	if err != nil {
+		host, port = nameserver, "53"
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !validPort(port) {
+		return "", invalidErr
+	}
+	return net.JoinHostPort(ip.String(), port), nil
+}
+
+// IsShortHostname reports whether name has a single label, like db, short names are resolved
+// against the search domains of the resolver and may reach different hosts on each namespace
+func IsShortHostname(name string) bool {
+	name = strings.TrimSuffix(name, ".")
+	return !strings.Contains(name, ".") && net.ParseIP(name) == nil
+}
+
+// ShortHostnameError is the error of externalDNS destinations with short names
+func ShortHostnameError(name string) error {
+	return fmt.Errorf("externalDNS name %q is not fully qualified, short names resolve against search domains, use the name with its domain", name)
+}
+
+func validNameserverHost(host string) bool {
+	return net.ParseIP(host) != nil || len(validation.IsDNS1123Subdomain(strings.ToLower(host))) == 0
+}
+
+func validPort(port string) bool {
+	portNumber, err := strconv.Atoi(port)
+	return err == nil && portNumber >= 1 && portNumber <= 65535
+}
+
+// Addresses returns the canonical nameservers of ParseNameserver, sorted and without duplicates
+func (r *ACLSpecDNSResolver) Addresses() ([]string, error) {
+	if len(r.Nameservers) == 0 {
+		return nil, fmt.Errorf("resolver requires at least one nameserver")
+	}
+
+	addresses := []string{}
+	seen := map[string]bool{}
+	for _, nameserver := range r.Nameservers {
+		address, err := ParseNameserver(nameserver)
+		if err != nil {
+			return nil, err
+		}
+
+		if seen[address] {
+			continue
+		}
+		seen[address] = true
+		addresses = append(addresses, address)
+	}
+
+	sort.Strings(addresses)
+
+	return addresses, nil
+}
+
+// Validate checks the entries created by users, the entries of ACLs come from valid externalDNS destinations
+func (s *ACLDNSEntrySpec) Validate() error {
+	if s.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	if strings.HasPrefix(s.Host, "*") || strings.HasPrefix(s.Host, ".") {
+		return fmt.Errorf("host %q is a wildcard, it can not be resolved", s.Host)
+	}
+	if IsShortHostname(s.Host) {
+		return fmt.Errorf("host %q is not fully qualified, short names resolve against the search domains of the operator", s.Host)
+	}
+
+	if len(s.Nameservers) > 0 {
+		resolver := ACLSpecDNSResolver{Nameservers: s.Nameservers}
+		_, err := resolver.Addresses()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, ip := range s.AdditionalIPs {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("invalid additionalIP %q", ip)
+		}
+	}
+
+	return nil
+}
+
+// ParseProtocol accepts TCP, UDP or SCTP in any case, an empty protocol means all protocols
+func ParseProtocol(protocol string) (corev1.Protocol, error) {
+	switch p := corev1.Protocol(strings.ToUpper(protocol)); p {
+	case "", corev1.ProtocolTCP, corev1.ProtocolUDP, corev1.ProtocolSCTP:
+		return p, nil
+	}
+
+	return "", fmt.Errorf("invalid protocol %q, use TCP, UDP or SCTP", protocol)
+}
+
+// ParseIPFamilies accepts a comma separated list of IPv4 and IPv6
+func ParseIPFamilies(value string) ([]IPFamily, error) {
+	var families []IPFamily
+	for _, family := range strings.Split(value, ",") {
+		family = strings.TrimSpace(family)
+		switch IPFamily(family) {
+		case "":
+		case IPv4Family, IPv6Family:
+			families = append(families, IPFamily(family))
+		default:
+			return nil, fmt.Errorf("invalid IP family %q, use %s or %s", family, IPv4Family, IPv6Family)
+		}
+	}
+
+	return families, nil
+}
+
+// ParseAddressFilter accepts comma separated lists of allowed and denied CIDRs, there is no
+// filter when both are empty
+func ParseAddressFilter(allow, deny string) (*ACLSpecAddressFilter, error) {
+	filter := &ACLSpecAddressFilter{
+		Allow: splitCIDRs(allow),
+		Deny:  splitCIDRs(deny),
+	}
+	if len(filter.Allow) == 0 && len(filter.Deny) == 0 {
+		return nil, nil
+	}
+
+	return filter, filter.Validate()
+}
+
+func splitCIDRs(value string) []string {
+	var cidrs []string
+	for _, cidr := range strings.Split(value, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr != "" {
+			cidrs = append(cidrs, cidr)
+		}
+	}
+	return cidrs
+}
+
+func (p *ProtoPort) Validate() error {
+	protocol, err := ParseProtocol(p.Protocol)
+	if err != nil {
+		return err
+	}
+
+	if p.Name != "" {
+		if p.Number != 0 {
+			return fmt.Errorf("port sets both number %d and name %q, use only one of them", p.Number, p.Name)
+		}
+		if p.EndPort != 0 {
+			return fmt.Errorf("endPort is not supported for the named port %q", p.Name)
+		}
+		if errs := validation.IsValidPortName(p.Name); len(errs) > 0 {
+			return fmt.Errorf("invalid port name %q: %s", p.Name, strings.Join(errs, ", "))
+		}
+		return nil
+	}
+
+	if p.Number == 0 {
+		return fmt.Errorf("port sets neither number nor name, set a number between 1 and 65535 or a port name")
+	}
+
+	if p.EndPort != 0 && p.EndPort < p.Number {
+		return fmt.Errorf("invalid port range %d-%d, endPort must be greater than or equal to number", p.Number, p.EndPort)
+	}
+
+	if p.EndPort > p.Number && protocol == corev1.ProtocolSCTP {
+		return fmt.Errorf("port range %d-%d is not supported for protocol %q, use TCP or UDP", p.Number, p.EndPort, p.Protocol)
+	}
+
+	return nil
+}
+
+func (s *ACLSpecSource) Validate() error {
+	fields := 0
+	if s.TsuruApp != "" {
+		fields++
+	}
+	if s.TsuruJob != "" {
+		fields++
+	}
+	if s.RpaasInstance != nil {
+		fields++
+	}
+	if s.RawPodSelector != nil {
+		fields++
+	}
+	if s.Workload != nil {
+		fields++
+	}
+
+	if fields != 1 {
+		return fmt.Errorf("source must set exactly one of tsuruApp, tsuruJob, rpaasInstance, rawPodSelector or workload, found %d", fields)
+	}
+
+	if s.RpaasInstance != nil && (s.RpaasInstance.ServiceName == "" || s.RpaasInstance.Instance == "") {
+		return fmt.Errorf("rpaasInstance requires serviceName and instance")
+	}
+
+	if s.RawPodSelector != nil {
+		// an empty selector would select every pod of the namespace
+		if len(s.RawPodSelector.MatchLabels) == 0 && len(s.RawPodSelector.MatchExpressions) == 0 {
+			return fmt.Errorf("rawPodSelector requires matchLabels or matchExpressions")
+		}
+
+		_, err := metav1.LabelSelectorAsSelector(s.RawPodSelector)
+		if err != nil {
+			return fmt.Errorf("invalid rawPodSelector: %s", err.Error())
+		}
+	}
+
+	if s.Workload != nil {
+		return s.Workload.Validate()
+	}
+
+	return nil
+}
+
+const (
+	WorkloadKindDeployment  = "Deployment"
+	WorkloadKindStatefulSet = "StatefulSet"
+)
+
+func (w *ACLSpecWorkload) Validate() error {
+	if w.Kind != WorkloadKindDeployment && w.Kind != WorkloadKindStatefulSet {
+		return fmt.Errorf("invalid kind %q of workload, use %s or %s", w.Kind, WorkloadKindDeployment, WorkloadKindStatefulSet)
+	}
+	if errs := validation.IsDNS1123Subdomain(w.Name); len(errs) > 0 {
+		return fmt.Errorf("invalid name %q of workload: %s", w.Name, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func (d *ACLSpecDestination) Validate() error {
+	fields := 0
+	if d.TsuruApp != "" {
+		fields++
+	}
+	if d.TsuruAppPool != "" {
+		fields++
+	}
+	if d.RpaasInstance != nil {
+		fields++
+	}
+	if d.ExternalDNS != nil {
+		fields++
+	}
+	if d.ExternalIP != nil {
+		fields++
+	}
+	if len(d.ExternalEndpoints) > 0 {
+		fields++
+	}
+	if d.ExternalSRV != nil {
+		fields++
+	}
+	if d.KubernetesService != nil {
+		fields++
+	}
+	if d.Deny != nil {
+		fields++
+	}
+	if d.TsuruServiceInstance != nil {
+		fields++
+	}
+	if d.ConfigMap != nil {
+		fields++
+	}
+
+	if fields != 1 {
+		return fmt.Errorf("destination must set exactly one of tsuruApp, tsuruAppPool, rpaasInstance, tsuruServiceInstance, externalDNS, externalIP, externalEndpoints, externalSRV, kubernetesService, configMap or deny, found %d", fields)
+	}
+
+	if d.TsuruAppProcess != "" && d.TsuruApp == "" {
+		return fmt.Errorf("tsuruAppProcess requires tsuruApp")
+	}
+
+	if len(d.TsuruAppRouters) > 0 && d.TsuruApp == "" {
+		return fmt.Errorf("tsuruAppRouters requires tsuruApp")
+	}
+	for _, router := range d.TsuruAppRouters {
+		if router == "" || strings.ContainsAny(router, ", ") {
+			return fmt.Errorf("invalid router %q of tsuruAppRouters, router names must not be empty nor have commas or spaces", router)
+		}
+	}
+
+	if len(d.TsuruAppPoolExcept) > 0 && d.TsuruAppPool == "" {
+		return fmt.Errorf("tsuruAppPoolExcept requires tsuruAppPool")
+	}
+	for _, app := range d.TsuruAppPoolExcept {
+		if errs := validation.IsDNS1123Label(app); len(errs) > 0 {
+			return fmt.Errorf("invalid app %q of tsuruAppPoolExcept: %s", app, strings.Join(errs, ", "))
+		}
+	}
+
+	if d.RpaasInstance != nil && (d.RpaasInstance.ServiceName == "" || d.RpaasInstance.Instance == "") {
+		return fmt.Errorf("rpaasInstance requires serviceName and instance")
+	}
+
+	if d.TsuruServiceInstance != nil && (d.TsuruServiceInstance.ServiceName == "" || d.TsuruServiceInstance.Instance == "") {
+		return fmt.Errorf("tsuruServiceInstance requires serviceName and instance")
+	}
+
+	if d.ExternalDNS != nil {
+		if d.ExternalDNS.Name == "" {
+			return fmt.Errorf("externalDNS requires a name")
+		}
+		if IsShortHostname(d.ExternalDNS.Name) {
+			return ShortHostnameError(d.ExternalDNS.Name)
+		}
+		if d.ExternalDNS.Resolver != nil {
+			_, err := d.ExternalDNS.Resolver.Addresses()
+			if err != nil {
+				return err
+			}
+		}
+		return d.ExternalDNS.Ports.Validate()
+	}
+
+	if d.ExternalIP != nil {
+		return d.ExternalIP.Validate()
+	}
+
+	if len(d.ExternalEndpoints) > 0 {
+		_, err := d.ExternalEndpointsIPs()
+		return err
+	}
+
+	if d.ExternalSRV != nil {
+		_, err := d.ExternalSRV.Protocol()
+		return err
+	}
+
+	if d.KubernetesService != nil && d.KubernetesService.Name == "" {
+		return fmt.Errorf("kubernetesService requires a name")
+	}
+
+	if d.Deny != nil {
+		return d.Deny.Validate()
+	}
+
+	if d.ConfigMap != nil {
+		return d.ConfigMap.Validate()
+	}
+
+	return nil
+}
+
+func (c *ACLSpecConfigMap) Validate() error {
+	if c.Name == "" || c.Key == "" {
+		return fmt.Errorf("configMap requires a name and a key")
+	}
+	if c.Namespace != "" {
+		if errs := validation.IsDNS1123Label(c.Namespace); len(errs) > 0 {
+			return fmt.Errorf("invalid namespace %q of configMap: %s", c.Namespace, strings.Join(errs, ", "))
+		}
+	}
+	if errs := validation.IsConfigMapKey(c.Key); len(errs) > 0 {
+		return fmt.Errorf("invalid key %q of configMap: %s", c.Key, strings.Join(errs, ", "))
+	}
+	return c.Ports.Validate()
+}
+
+// MaxConfigMapAddresses caps the addresses of a configMap destination, every hostname is an ACLDNSEntry
+// and every address a peer of the policy
+const MaxConfigMapAddresses = 500
+
+// ParseConfigMapAddresses reads the IPs, CIDRs and hostnames of the key of a configMap destination,
+// they are separated by new lines, commas or spaces, text after # is a comment and repeated addresses
+// are dropped, hostnames are lower-cased, errors name the line of an invalid address instead of its
+// content since ConfigMaps are not meant to be exposed on the status of ACLs
+func ParseConfigMapAddresses(value string) ([]string, error) {
+	addresses := []string{}
+	seen := map[string]bool{}
+	for i, line := range strings.Split(value, "\n") {
+		if j := strings.Index(line, "#"); j >= 0 {
+			line = line[:j]
+		}
+
+		for _, address := range strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\r'
+		}) {
+			if _, _, err := ParseCIDR(address); err != nil {
+				address = strings.TrimSuffix(strings.ToLower(address), ".")
+				if errs := validation.IsDNS1123Subdomain(address); len(errs) > 0 {
+					return nil, fmt.Errorf("invalid address on line %d, use an IP, a CIDR or a hostname", i+1)
+				}
+				if IsShortHostname(address) {
+					return nil, fmt.Errorf("hostname on line %d is not fully qualified, short names resolve against search domains, use the name with its domain", i+1)
+				}
+			}
+
+			if seen[address] {
+				continue
+			}
+			seen[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+
+	if len(addresses) > MaxConfigMapAddresses {
+		return nil, fmt.Errorf("%d addresses exceed the limit of %d addresses", len(addresses), MaxConfigMapAddresses)
+	}
+
+	return addresses, nil
+}
+
+// ParseExternalEndpoint reads an endpoint of externalEndpoints as ip:port/protocol, the protocol
+// defaults to TCP, IPv6 addresses are written in brackets like [2001:db8::1]:443
+func ParseExternalEndpoint(endpoint string) (string, ProtoPort, error) {
+	hostPort, protocol := endpoint, "TCP"
+	if i := strings.LastIndex(endpoint, "/"); i >= 0 {
+		hostPort, protocol = endpoint[:i], endpoint[i+1:]
+	}
+
+	invalid := func(reason string) (string, ProtoPort, error) {
+		return "", ProtoPort{}, fmt.Errorf("invalid externalEndpoint %q, use ip:port/protocol like 10.0.0.1:443/tcp: %s", endpoint, reason)
+	}
+
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return invalid("missing port")
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return invalid(fmt.Sprintf("%q is not an IP address", host))
+	}
+
+	portNumber, err := strconv.Atoi(port)
+	if err != nil || portNumber < 1 || portNumber > 65535 {
+		return invalid(fmt.Sprintf("port %q must be between 1 and 65535", port))
+	}
+
+	parsedProtocol, err := ParseProtocol(protocol)
+	if err != nil || parsedProtocol == "" {
+		return invalid(fmt.Sprintf("protocol %q must be TCP, UDP or SCTP", protocol))
+	}
+
+	return ip.String(), ProtoPort{Protocol: string(parsedProtocol), Number: uint16(portNumber)}, nil
+}
+
+// ExternalEndpointsIPs expands externalEndpoints into externalIP destinations, the endpoints of
+// the same IP are grouped with their ports in the order they appear
+func (d *ACLSpecDestination) ExternalEndpointsIPs() ([]ACLSpecExternalIP, error) {
+	externalIPs := []ACLSpecExternalIP{}
+	indexes := map[string]int{}
+	for _, endpoint := range d.ExternalEndpoints {
+		ip, port, err := ParseExternalEndpoint(endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		i, ok := indexes[ip]
+		if !ok {
+			i = len(externalIPs)
+			indexes[ip] = i
+			externalIPs = append(externalIPs, ACLSpecExternalIP{IP: ip})
+		}
+		externalIPs[i].Ports = append(externalIPs[i].Ports, port)
+	}
+
+	return externalIPs, nil
+}
+
+func (i *ACLSpecIngress) Validate() error {
+	fields := 0
+	if i.TsuruApp != "" {
+		fields++
+	}
+	if i.TsuruJob != "" {
+		fields++
+	}
+	if i.TsuruAppPool != "" {
+		fields++
+	}
+	if i.RpaasInstance != nil {
+		fields++
+	}
+	if i.ExternalIP != nil {
+		fields++
+	}
+
+	if fields != 1 {
+		return fmt.Errorf("ingress must set exactly one of tsuruApp, tsuruJob, tsuruAppPool, rpaasInstance or externalIP, found %d", fields)
+	}
+
+	if i.ExternalIP != nil {
+		return i.ExternalIP.Validate()
+	}
+
+	return nil
+}
+
+// Protocol returns the protocol of the proto label of name, like TCP for _ldap._tcp.example.com
+func (e *ACLSpecExternalSRV) Protocol() (corev1.Protocol, error) {
+	labels := strings.SplitN(e.Name, ".", 3)
+	if len(labels) != 3 || !strings.HasPrefix(labels[0], "_") || !strings.HasPrefix(labels[1], "_") || labels[2] == "" {
+		return "", fmt.Errorf("invalid externalSRV name %q, use _service._proto.host", e.Name)
+	}
+
+	protocol, err := ParseProtocol(strings.TrimPrefix(labels[1], "_"))
+	if err != nil || protocol == "" {
+		return "", fmt.Errorf("invalid externalSRV name %q, the proto label must be _tcp, _udp or _sctp", e.Name)
+	}
+
+	return protocol, nil
+}
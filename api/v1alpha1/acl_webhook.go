@@ -0,0 +1,129 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+func (r *ACL) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-extensions-tsuru-io-v1alpha1-acl,mutating=true,failurePolicy=fail,sideEffects=None,groups=extensions.tsuru.io,resources=acls,verbs=create;update,versions=v1alpha1,name=macl.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &ACL{}
+
+// Default implements webhook.Defaulter, the addresses of externalIP are stored as canonical CIDRs
+func (r *ACL) Default() {
+	for i := range r.Spec.Destinations {
+		if r.Spec.Destinations[i].ExternalIP != nil {
+			r.Spec.Destinations[i].ExternalIP.Normalize()
+		}
+	}
+
+	for i := range r.Spec.Ingress {
+		if r.Spec.Ingress[i].ExternalIP != nil {
+			r.Spec.Ingress[i].ExternalIP.Normalize()
+		}
+	}
+}
+
+//+kubebuilder:webhook:path=/validate-extensions-tsuru-io-v1alpha1-acl,mutating=false,failurePolicy=fail,sideEffects=None,groups=extensions.tsuru.io,resources=acls,verbs=create;update,versions=v1alpha1,name=vacl.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &ACL{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *ACL) ValidateCreate() error {
+	return r.validateACL()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *ACL) ValidateUpdate(old runtime.Object) error {
+	return r.validateACL()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *ACL) ValidateDelete() error {
+	return nil
+}
+
+func (r *ACL) validateACL() error {
+	allErrs := r.Spec.validate(field.NewPath("spec"), r.Namespace)
+
+	if value, ok := r.Annotations[ExtraEgressAnnotation]; ok {
+		_, err := ParseExtraEgress(value)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("metadata", "annotations").Key(ExtraEgressAnnotation), value, err.Error()))
+		}
+	}
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind("ACL").GroupKind(), r.Name, allErrs)
+}
+
+// validate checks the spec of an ACL of namespace, namespace is empty when it is not known yet
+func (s *ACLSpec) validate(path *field.Path, namespace string) field.ErrorList {
+	var allErrs field.ErrorList
+
+	err := s.Source.Validate()
+	if err != nil {
+		allErrs = append(allErrs, field.Invalid(path.Child("source"), describe(s.Source), err.Error()))
+	}
+
+	destinationsPath := path.Child("destinations")
+	if len(s.Destinations) == 0 {
+		allErrs = append(allErrs, field.Required(destinationsPath, "at least one destination is required"))
+	}
+
+	for i := range s.Destinations {
+		err := s.Destinations[i].Validate()
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(destinationsPath.Index(i), describe(s.Destinations[i]), err.Error()))
+			continue
+		}
+
+		configMap := s.Destinations[i].ConfigMap
+		if configMap != nil && namespace != "" && configMap.Namespace != "" && configMap.Namespace != namespace {
+			allErrs = append(allErrs, field.Forbidden(destinationsPath.Index(i).Child("configMap", "namespace"), fmt.Sprintf("only ConfigMaps of namespace %q, the namespace of the ACL, are allowed", namespace)))
+		}
+	}
+
+	ingressPath := path.Child("ingress")
+	for i := range s.Ingress {
+		err := s.Ingress[i].Validate()
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(ingressPath.Index(i), describe(s.Ingress[i]), err.Error()))
+		}
+	}
+
+	if s.AddressFilter != nil {
+		err := s.AddressFilter.Validate()
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("addressFilter"), describe(s.AddressFilter), err.Error()))
+		}
+	}
+
+	if s.Schedule != nil {
+		err := s.Schedule.Validate()
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("schedule"), describe(s.Schedule), err.Error()))
+		}
+	}
+
+	return allErrs
+}
+
+func describe(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
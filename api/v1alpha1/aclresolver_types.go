@@ -27,6 +27,12 @@ import (
 type ACLDNSEntrySpec struct {
 	Host          string   `json:"host"`
 	AdditionalIPs []string `json:"additionalIPs,omitempty"`
+	// Nameservers are queried instead of the resolver of operator when set, as ip:port, tls://host:port
+	// or https:// URLs
+	Nameservers []string `json:"nameservers,omitempty"`
+	// Namespace is set on entries used only by the ACLs of a namespace, entries without
+	// namespace are shared by the ACLs of the whole cluster
+	Namespace string `json:"namespace,omitempty"`
 }
 
 // ACLDNSEntryStatus defines the observed state of ACLDNSEntry
@@ -34,11 +40,32 @@ type ACLDNSEntryStatus struct {
 	IPs    []ACLDNSEntryStatusIP `json:"ips,omitempty"`
 	Ready  bool                  `json:"ready"`
 	Reason string                `json:"reason,omitempty"`
+
+	// Failures are filled when the last lookup of host failed
+	Failures []ResolutionFailure `json:"failures,omitempty"`
+
+	// Truncated is true when addresses were evicted to respect the maximum of IPs per entry,
+	// the policies may not allow every address of host
+	Truncated bool `json:"truncated,omitempty"`
+
+	// CanonicalName is the name host is an alias of on the last lookup, empty when host has no CNAME
+	CanonicalName string `json:"canonicalName,omitempty"`
+	// CNAMEs are the names followed from host to its addresses on the last lookup, the chain
+	// is not known with some resolvers, which report only the canonical name
+	CNAMEs []string `json:"cnames,omitempty"`
+
+	// ConsecutiveFailures counts the lookups of host that failed since the last successful one
+	ConsecutiveFailures int `json:"consecutiveFailures,omitempty"`
+	// RetryInterval is the interval to look up host again while the lookups fail, like 40m0s, it
+	// grows with the consecutive failures up to the maximum backoff of the operator
+	RetryInterval string `json:"retryInterval,omitempty"`
 }
 
 type ACLDNSEntryStatusIP struct {
 	Address    string `json:"address"`
 	ValidUntil string `json:"validUtil"`
+	// LastSeen is the last time the address was resolved, the oldest addresses are evicted first
+	LastSeen string `json:"lastSeen,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -46,8 +73,14 @@ type ACLDNSEntryStatusIP struct {
 //+kubebuilder:resource:scope=Cluster
 //+kubebuilder:printcolumn:name="Ready",type=boolean,JSONPath=`.status.ready`
 //+kubebuilder:printcolumn:name="Addresses",type=string,JSONPath=`.status.ips[*].address`
+//+kubebuilder:printcolumn:name="Truncated",type=boolean,JSONPath=`.status.truncated`,priority=1
+//+kubebuilder:printcolumn:name="Canonical Name",type=string,JSONPath=`.status.canonicalName`,priority=1
+//+kubebuilder:printcolumn:name="Namespace",type=string,JSONPath=`.spec.namespace`,priority=1
+//+kubebuilder:printcolumn:name="Failures",type=integer,JSONPath=`.status.consecutiveFailures`,priority=1
 
-// ACLDNSEntry is the Schema for the ACLDNSEntrys API
+// ACLDNSEntry resolves spec.host and keeps its addresses on status, entries are created for the
+// externalDNS destinations of ACLs and may be created by users to reuse the resolution of the operator,
+// entries with the annotation acl.extensions.tsuru.io/user-owned are never deleted by the operator
 type ACLDNSEntry struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
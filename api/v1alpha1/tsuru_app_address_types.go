@@ -23,6 +23,8 @@ import (
 // TsuruAppAddressSpec defines the desired state of TsuruAppAddress
 type TsuruAppAddressSpec struct {
 	Name string `json:"name,omitempty"`
+	// Routers are the names of the routers of app whose addresses are resolved, every router when empty
+	Routers []string `json:"routers,omitempty"`
 }
 
 // ResourceAddressStatus defines the observed state of TsuruAppAddress and RpaasInstanceAddress
@@ -32,6 +34,20 @@ type ResourceAddressStatus struct {
 	UpdatedAt string   `json:"updatedAt,omitempty"`
 	IPs       []string `json:"ips,omitempty"`
 	Pool      string   `json:"pool,omitempty"`
+
+	// InternalIPs are the addresses of the internal services of app, resolved from the cluster DNS
+	// when the operator runs with --tsuru-app-internal-addresses
+	InternalIPs []string `json:"internalIPs,omitempty"`
+
+	// Failures are the hosts that could not be resolved on last reconcile
+	Failures []ResolutionFailure `json:"failures,omitempty"`
+}
+
+// ResolutionFailure records a host that could not be resolved, timestamp is when the error was first seen
+type ResolutionFailure struct {
+	Host      string `json:"host"`
+	Error     string `json:"error"`
+	Timestamp string `json:"timestamp"`
 }
 
 //+kubebuilder:object:root=true
@@ -0,0 +1,74 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TsuruServiceInstanceAddressSpec defines the desired state of TsuruServiceInstanceAddress
+type TsuruServiceInstanceAddressSpec struct {
+	ServiceName string `json:"serviceName,omitempty"`
+	Instance    string `json:"instance,omitempty"`
+}
+
+// TsuruServiceInstanceAddressStatus holds the endpoints of instance, hostnames are resolved by the
+// ACLDNSEntry objects of the ACLs using the instance
+type TsuruServiceInstanceAddressStatus struct {
+	Ready     bool   `json:"ready"`
+	Reason    string `json:"reason,omitempty"`
+	UpdatedAt string `json:"updatedAt,omitempty"`
+	Pool      string `json:"pool,omitempty"`
+
+	// Endpoints are read from the custom info of instance, sorted by host and port
+	Endpoints []TsuruServiceInstanceEndpoint `json:"endpoints,omitempty"`
+}
+
+type TsuruServiceInstanceEndpoint struct {
+	// Host is a fully qualified hostname, an IP address or a CIDR
+	Host string `json:"host"`
+	// Port restricts the egress to the endpoint to a TCP port, every port is allowed when empty
+	Port uint16 `json:"port,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Ready",type=boolean,JSONPath=`.status.ready`
+//+kubebuilder:printcolumn:name="Endpoints",type=string,JSONPath=`.status.endpoints[*].host`
+
+// TsuruServiceInstanceAddress is the Schema for the tsuruserviceinstanceaddresses API
+type TsuruServiceInstanceAddress struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TsuruServiceInstanceAddressSpec   `json:"spec,omitempty"`
+	Status TsuruServiceInstanceAddressStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// TsuruServiceInstanceAddressList contains a list of TsuruServiceInstanceAddress
+type TsuruServiceInstanceAddressList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TsuruServiceInstanceAddress `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TsuruServiceInstanceAddress{}, &TsuruServiceInstanceAddressList{})
+}
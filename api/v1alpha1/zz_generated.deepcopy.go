@@ -22,8 +22,9 @@ limitations under the License.
 package v1alpha1
 
 import (
-	"k8s.io/api/networking/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -58,7 +59,7 @@ func (in *ACLDNSEntry) DeepCopyInto(out *ACLDNSEntry) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -115,6 +116,16 @@ func (in *ACLDNSEntryList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ACLDNSEntrySpec) DeepCopyInto(out *ACLDNSEntrySpec) {
 	*out = *in
+	if in.AdditionalIPs != nil {
+		in, out := &in.AdditionalIPs, &out.AdditionalIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Nameservers != nil {
+		in, out := &in.Nameservers, &out.Nameservers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLDNSEntrySpec.
@@ -135,6 +146,16 @@ func (in *ACLDNSEntryStatus) DeepCopyInto(out *ACLDNSEntryStatus) {
 		*out = make([]ACLDNSEntryStatusIP, len(*in))
 		copy(*out, *in)
 	}
+	if in.Failures != nil {
+		in, out := &in.Failures, &out.Failures
+		*out = make([]ResolutionFailure, len(*in))
+		copy(*out, *in)
+	}
+	if in.CNAMEs != nil {
+		in, out := &in.CNAMEs, &out.CNAMEs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLDNSEntryStatus.
@@ -162,6 +183,156 @@ func (in *ACLDNSEntryStatusIP) DeepCopy() *ACLDNSEntryStatusIP {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLGroup) DeepCopyInto(out *ACLGroup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLGroup.
+func (in *ACLGroup) DeepCopy() *ACLGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ACLGroup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLGroupList) DeepCopyInto(out *ACLGroupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ACLGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLGroupList.
+func (in *ACLGroupList) DeepCopy() *ACLGroupList {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLGroupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ACLGroupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLGroupSpec) DeepCopyInto(out *ACLGroupSpec) {
+	*out = *in
+	if in.Sources != nil {
+		in, out := &in.Sources, &out.Sources
+		*out = make([]ACLSpecSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Destinations != nil {
+		in, out := &in.Destinations, &out.Destinations
+		*out = make([]ACLSpecDestination, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = make([]ACLSpecIngress, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.IPFamilies != nil {
+		in, out := &in.IPFamilies, &out.IPFamilies
+		*out = make([]IPFamily, len(*in))
+		copy(*out, *in)
+	}
+	if in.CIDRAggregation != nil {
+		in, out := &in.CIDRAggregation, &out.CIDRAggregation
+		*out = new(ACLSpecCIDRAggregation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AddressFilter != nil {
+		in, out := &in.AddressFilter, &out.AddressFilter
+		*out = new(ACLSpecAddressFilter)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Template != nil {
+		in, out := &in.Template, &out.Template
+		*out = new(ACLSpecTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLGroupSpec.
+func (in *ACLGroupSpec) DeepCopy() *ACLGroupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLGroupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLGroupStatus) DeepCopyInto(out *ACLGroupStatus) {
+	*out = *in
+	if in.ACLs != nil {
+		in, out := &in.ACLs, &out.ACLs
+		*out = make([]ACLGroupStatusACL, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLGroupStatus.
+func (in *ACLGroupStatus) DeepCopy() *ACLGroupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLGroupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLGroupStatusACL) DeepCopyInto(out *ACLGroupStatusACL) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLGroupStatusACL.
+func (in *ACLGroupStatusACL) DeepCopy() *ACLGroupStatusACL {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLGroupStatusACL)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ACLList) DeepCopyInto(out *ACLList) {
 	*out = *in
@@ -205,207 +376,858 @@ func (in *ACLSpec) DeepCopyInto(out *ACLSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = make([]ACLSpecIngress, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.IPFamilies != nil {
+		in, out := &in.IPFamilies, &out.IPFamilies
+		*out = make([]IPFamily, len(*in))
+		copy(*out, *in)
+	}
+	if in.CIDRAggregation != nil {
+		in, out := &in.CIDRAggregation, &out.CIDRAggregation
+		*out = new(ACLSpecCIDRAggregation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AddressFilter != nil {
+		in, out := &in.AddressFilter, &out.AddressFilter
+		*out = new(ACLSpecAddressFilter)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Template != nil {
+		in, out := &in.Template, &out.Template
+		*out = new(ACLSpecTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Schedule != nil {
+		in, out := &in.Schedule, &out.Schedule
+		*out = new(ACLSpecSchedule)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpec.
+func (in *ACLSpec) DeepCopy() *ACLSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLSpecAddressFilter) DeepCopyInto(out *ACLSpecAddressFilter) {
+	*out = *in
+	if in.Allow != nil {
+		in, out := &in.Allow, &out.Allow
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Deny != nil {
+		in, out := &in.Deny, &out.Deny
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecAddressFilter.
+func (in *ACLSpecAddressFilter) DeepCopy() *ACLSpecAddressFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLSpecAddressFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLSpecCIDRAggregation) DeepCopyInto(out *ACLSpecCIDRAggregation) {
+	*out = *in
+	if in.IPv4PrefixLength != nil {
+		in, out := &in.IPv4PrefixLength, &out.IPv4PrefixLength
+		*out = new(int32)
+		**out = **in
+	}
+	if in.IPv6PrefixLength != nil {
+		in, out := &in.IPv6PrefixLength, &out.IPv6PrefixLength
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecCIDRAggregation.
+func (in *ACLSpecCIDRAggregation) DeepCopy() *ACLSpecCIDRAggregation {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLSpecCIDRAggregation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLSpecConfigMap) DeepCopyInto(out *ACLSpecConfigMap) {
+	*out = *in
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make(ACLSpecProtoPorts, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecConfigMap.
+func (in *ACLSpecConfigMap) DeepCopy() *ACLSpecConfigMap {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLSpecConfigMap)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLSpecDNSResolver) DeepCopyInto(out *ACLSpecDNSResolver) {
+	*out = *in
+	if in.Nameservers != nil {
+		in, out := &in.Nameservers, &out.Nameservers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecDNSResolver.
+func (in *ACLSpecDNSResolver) DeepCopy() *ACLSpecDNSResolver {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLSpecDNSResolver)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLSpecDeny) DeepCopyInto(out *ACLSpecDeny) {
+	*out = *in
+	if in.CIDRs != nil {
+		in, out := &in.CIDRs, &out.CIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make(ACLSpecProtoPorts, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecDeny.
+func (in *ACLSpecDeny) DeepCopy() *ACLSpecDeny {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLSpecDeny)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLSpecDestination) DeepCopyInto(out *ACLSpecDestination) {
+	*out = *in
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.TsuruAppRouters != nil {
+		in, out := &in.TsuruAppRouters, &out.TsuruAppRouters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TsuruAppPoolExcept != nil {
+		in, out := &in.TsuruAppPoolExcept, &out.TsuruAppPoolExcept
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RpaasInstance != nil {
+		in, out := &in.RpaasInstance, &out.RpaasInstance
+		*out = new(ACLSpecRpaasInstance)
+		**out = **in
+	}
+	if in.ExternalDNS != nil {
+		in, out := &in.ExternalDNS, &out.ExternalDNS
+		*out = new(ACLSpecExternalDNS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExternalIP != nil {
+		in, out := &in.ExternalIP, &out.ExternalIP
+		*out = new(ACLSpecExternalIP)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExternalEndpoints != nil {
+		in, out := &in.ExternalEndpoints, &out.ExternalEndpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExternalSRV != nil {
+		in, out := &in.ExternalSRV, &out.ExternalSRV
+		*out = new(ACLSpecExternalSRV)
+		**out = **in
+	}
+	if in.KubernetesService != nil {
+		in, out := &in.KubernetesService, &out.KubernetesService
+		*out = new(ACLSpecKubernetesService)
+		**out = **in
+	}
+	if in.Deny != nil {
+		in, out := &in.Deny, &out.Deny
+		*out = new(ACLSpecDeny)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TsuruServiceInstance != nil {
+		in, out := &in.TsuruServiceInstance, &out.TsuruServiceInstance
+		*out = new(ACLSpecTsuruServiceInstance)
+		**out = **in
+	}
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(ACLSpecConfigMap)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecDestination.
+func (in *ACLSpecDestination) DeepCopy() *ACLSpecDestination {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLSpecDestination)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLSpecExternalDNS) DeepCopyInto(out *ACLSpecExternalDNS) {
+	*out = *in
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make(ACLSpecProtoPorts, len(*in))
+		copy(*out, *in)
+	}
+	if in.Resolver != nil {
+		in, out := &in.Resolver, &out.Resolver
+		*out = new(ACLSpecDNSResolver)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecExternalDNS.
+func (in *ACLSpecExternalDNS) DeepCopy() *ACLSpecExternalDNS {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLSpecExternalDNS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLSpecExternalIP) DeepCopyInto(out *ACLSpecExternalIP) {
+	*out = *in
+	if in.Except != nil {
+		in, out := &in.Except, &out.Except
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make(ACLSpecProtoPorts, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecExternalIP.
+func (in *ACLSpecExternalIP) DeepCopy() *ACLSpecExternalIP {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLSpecExternalIP)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLSpecExternalSRV) DeepCopyInto(out *ACLSpecExternalSRV) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecExternalSRV.
+func (in *ACLSpecExternalSRV) DeepCopy() *ACLSpecExternalSRV {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLSpecExternalSRV)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLSpecIngress) DeepCopyInto(out *ACLSpecIngress) {
+	*out = *in
+	if in.RpaasInstance != nil {
+		in, out := &in.RpaasInstance, &out.RpaasInstance
+		*out = new(ACLSpecRpaasInstance)
+		**out = **in
+	}
+	if in.ExternalIP != nil {
+		in, out := &in.ExternalIP, &out.ExternalIP
+		*out = new(ACLSpecExternalIP)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecIngress.
+func (in *ACLSpecIngress) DeepCopy() *ACLSpecIngress {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLSpecIngress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLSpecKubernetesService) DeepCopyInto(out *ACLSpecKubernetesService) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecKubernetesService.
+func (in *ACLSpecKubernetesService) DeepCopy() *ACLSpecKubernetesService {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLSpecKubernetesService)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in ACLSpecProtoPorts) DeepCopyInto(out *ACLSpecProtoPorts) {
+	{
+		in := &in
+		*out = make(ACLSpecProtoPorts, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecProtoPorts.
+func (in ACLSpecProtoPorts) DeepCopy() ACLSpecProtoPorts {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLSpecProtoPorts)
+	in.DeepCopyInto(out)
+	return *out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLSpecRpaasInstance) DeepCopyInto(out *ACLSpecRpaasInstance) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecRpaasInstance.
+func (in *ACLSpecRpaasInstance) DeepCopy() *ACLSpecRpaasInstance {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLSpecRpaasInstance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLSpecSchedule) DeepCopyInto(out *ACLSpecSchedule) {
+	*out = *in
+	if in.Windows != nil {
+		in, out := &in.Windows, &out.Windows
+		*out = make([]ACLSpecScheduleWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecSchedule.
+func (in *ACLSpecSchedule) DeepCopy() *ACLSpecSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLSpecSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLSpecScheduleWindow) DeepCopyInto(out *ACLSpecScheduleWindow) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]Weekday, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecScheduleWindow.
+func (in *ACLSpecScheduleWindow) DeepCopy() *ACLSpecScheduleWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLSpecScheduleWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLSpecSource) DeepCopyInto(out *ACLSpecSource) {
+	*out = *in
+	if in.RpaasInstance != nil {
+		in, out := &in.RpaasInstance, &out.RpaasInstance
+		*out = new(ACLSpecRpaasInstance)
+		**out = **in
+	}
+	if in.RawPodSelector != nil {
+		in, out := &in.RawPodSelector, &out.RawPodSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Workload != nil {
+		in, out := &in.Workload, &out.Workload
+		*out = new(ACLSpecWorkload)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecSource.
+func (in *ACLSpecSource) DeepCopy() *ACLSpecSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLSpecSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLSpecTemplate) DeepCopyInto(out *ACLSpecTemplate) {
+	*out = *in
+	in.Metadata.DeepCopyInto(&out.Metadata)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecTemplate.
+func (in *ACLSpecTemplate) DeepCopy() *ACLSpecTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLSpecTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLSpecTemplateMetadata) DeepCopyInto(out *ACLSpecTemplateMetadata) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecTemplateMetadata.
+func (in *ACLSpecTemplateMetadata) DeepCopy() *ACLSpecTemplateMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLSpecTemplateMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLSpecTsuruServiceInstance) DeepCopyInto(out *ACLSpecTsuruServiceInstance) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecTsuruServiceInstance.
+func (in *ACLSpecTsuruServiceInstance) DeepCopy() *ACLSpecTsuruServiceInstance {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLSpecTsuruServiceInstance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLSpecWorkload) DeepCopyInto(out *ACLSpecWorkload) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecWorkload.
+func (in *ACLSpecWorkload) DeepCopy() *ACLSpecWorkload {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLSpecWorkload)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLStatus) DeepCopyInto(out *ACLStatus) {
+	*out = *in
+	if in.WarningErrors != nil {
+		in, out := &in.WarningErrors, &out.WarningErrors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Warnings != nil {
+		in, out := &in.Warnings, &out.Warnings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Schedule != nil {
+		in, out := &in.Schedule, &out.Schedule
+		*out = new(ACLStatusSchedule)
+		**out = **in
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Stale != nil {
+		in, out := &in.Stale, &out.Stale
+		*out = make([]ACLStatusStale, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RuleErrors != nil {
+		in, out := &in.RuleErrors, &out.RuleErrors
+		*out = make([]ACLStatusRuleError, len(*in))
+		copy(*out, *in)
+	}
+	if in.ResolvedDestinations != nil {
+		in, out := &in.ResolvedDestinations, &out.ResolvedDestinations
+		*out = make([]ACLStatusResolvedDestination, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastApplied != nil {
+		in, out := &in.LastApplied, &out.LastApplied
+		*out = new(ACLStatusLastApplied)
+		**out = **in
+	}
+	if in.RecentFailures != nil {
+		in, out := &in.RecentFailures, &out.RecentFailures
+		*out = make([]ACLStatusFailure, len(*in))
+		copy(*out, *in)
+	}
+	if in.AddressObjects != nil {
+		in, out := &in.AddressObjects, &out.AddressObjects
+		*out = make([]ACLStatusAddressObject, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLStatus.
+func (in *ACLStatus) DeepCopy() *ACLStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLStatusAddressObject) DeepCopyInto(out *ACLStatusAddressObject) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLStatusAddressObject.
+func (in *ACLStatusAddressObject) DeepCopy() *ACLStatusAddressObject {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLStatusAddressObject)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLStatusFailure) DeepCopyInto(out *ACLStatusFailure) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLStatusFailure.
+func (in *ACLStatusFailure) DeepCopy() *ACLStatusFailure {
+	if in == nil {
+		return nil
+	}
+	out := new(ACLStatusFailure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLStatusLastApplied) DeepCopyInto(out *ACLStatusLastApplied) {
+	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpec.
-func (in *ACLSpec) DeepCopy() *ACLSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLStatusLastApplied.
+func (in *ACLStatusLastApplied) DeepCopy() *ACLStatusLastApplied {
 	if in == nil {
 		return nil
 	}
-	out := new(ACLSpec)
+	out := new(ACLStatusLastApplied)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ACLSpecDestination) DeepCopyInto(out *ACLSpecDestination) {
+func (in *ACLStatusResolvedDestination) DeepCopyInto(out *ACLStatusResolvedDestination) {
 	*out = *in
-	if in.RpaasInstance != nil {
-		in, out := &in.RpaasInstance, &out.RpaasInstance
-		*out = new(ACLSpecRpaasInstance)
-		**out = **in
+	if in.CIDRs != nil {
+		in, out := &in.CIDRs, &out.CIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
-	if in.ExternalDNS != nil {
-		in, out := &in.ExternalDNS, &out.ExternalDNS
-		*out = new(ACLSpecExternalDNS)
-		(*in).DeepCopyInto(*out)
+	if in.Selectors != nil {
+		in, out := &in.Selectors, &out.Selectors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
-	if in.ExternalIP != nil {
-		in, out := &in.ExternalIP, &out.ExternalIP
-		*out = new(ACLSpecExternalIP)
-		(*in).DeepCopyInto(*out)
+	if in.FQDNs != nil {
+		in, out := &in.FQDNs, &out.FQDNs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RejectedIPs != nil {
+		in, out := &in.RejectedIPs, &out.RejectedIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecDestination.
-func (in *ACLSpecDestination) DeepCopy() *ACLSpecDestination {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLStatusResolvedDestination.
+func (in *ACLStatusResolvedDestination) DeepCopy() *ACLStatusResolvedDestination {
 	if in == nil {
 		return nil
 	}
-	out := new(ACLSpecDestination)
+	out := new(ACLStatusResolvedDestination)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ACLSpecExternalDNS) DeepCopyInto(out *ACLSpecExternalDNS) {
+func (in *ACLStatusRuleError) DeepCopyInto(out *ACLStatusRuleError) {
 	*out = *in
-	if in.Ports != nil {
-		in, out := &in.Ports, &out.Ports
-		*out = make(ACLSpecProtoPorts, len(*in))
-		copy(*out, *in)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecExternalDNS.
-func (in *ACLSpecExternalDNS) DeepCopy() *ACLSpecExternalDNS {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLStatusRuleError.
+func (in *ACLStatusRuleError) DeepCopy() *ACLStatusRuleError {
 	if in == nil {
 		return nil
 	}
-	out := new(ACLSpecExternalDNS)
+	out := new(ACLStatusRuleError)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ACLSpecExternalIP) DeepCopyInto(out *ACLSpecExternalIP) {
+func (in *ACLStatusSchedule) DeepCopyInto(out *ACLStatusSchedule) {
 	*out = *in
-	if in.Ports != nil {
-		in, out := &in.Ports, &out.Ports
-		*out = make(ACLSpecProtoPorts, len(*in))
-		copy(*out, *in)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecExternalIP.
-func (in *ACLSpecExternalIP) DeepCopy() *ACLSpecExternalIP {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLStatusSchedule.
+func (in *ACLStatusSchedule) DeepCopy() *ACLStatusSchedule {
 	if in == nil {
 		return nil
 	}
-	out := new(ACLSpecExternalIP)
+	out := new(ACLStatusSchedule)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in ACLSpecProtoPorts) DeepCopyInto(out *ACLSpecProtoPorts) {
-	{
-		in := &in
-		*out = make(ACLSpecProtoPorts, len(*in))
-		copy(*out, *in)
+func (in *ACLStatusStale) DeepCopyInto(out *ACLStatusStale) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]networkingv1.NetworkPolicyEgressRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecProtoPorts.
-func (in ACLSpecProtoPorts) DeepCopy() ACLSpecProtoPorts {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLStatusStale.
+func (in *ACLStatusStale) DeepCopy() *ACLStatusStale {
 	if in == nil {
 		return nil
 	}
-	out := new(ACLSpecProtoPorts)
+	out := new(ACLStatusStale)
 	in.DeepCopyInto(out)
-	return *out
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ACLSpecRpaasInstance) DeepCopyInto(out *ACLSpecRpaasInstance) {
+func (in *ACLSummary) DeepCopyInto(out *ACLSummary) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecRpaasInstance.
-func (in *ACLSpecRpaasInstance) DeepCopy() *ACLSpecRpaasInstance {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSummary.
+func (in *ACLSummary) DeepCopy() *ACLSummary {
 	if in == nil {
 		return nil
 	}
-	out := new(ACLSpecRpaasInstance)
+	out := new(ACLSummary)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ACLSummary) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ACLSpecSource) DeepCopyInto(out *ACLSpecSource) {
+func (in *ACLSummaryFailingACL) DeepCopyInto(out *ACLSummaryFailingACL) {
 	*out = *in
-	if in.RpaasInstance != nil {
-		in, out := &in.RpaasInstance, &out.RpaasInstance
-		*out = new(ACLSpecRpaasInstance)
-		**out = **in
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSpecSource.
-func (in *ACLSpecSource) DeepCopy() *ACLSpecSource {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSummaryFailingACL.
+func (in *ACLSummaryFailingACL) DeepCopy() *ACLSummaryFailingACL {
 	if in == nil {
 		return nil
 	}
-	out := new(ACLSpecSource)
+	out := new(ACLSummaryFailingACL)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ACLStatus) DeepCopyInto(out *ACLStatus) {
+func (in *ACLSummaryList) DeepCopyInto(out *ACLSummaryList) {
 	*out = *in
-	if in.WarningErrors != nil {
-		in, out := &in.WarningErrors, &out.WarningErrors
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
-	if in.Stale != nil {
-		in, out := &in.Stale, &out.Stale
-		*out = make([]ACLStatusStale, len(*in))
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ACLSummary, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	if in.RuleErrors != nil {
-		in, out := &in.RuleErrors, &out.RuleErrors
-		*out = make([]ACLStatusRuleError, len(*in))
-		copy(*out, *in)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSummaryList.
+func (in *ACLSummaryList) DeepCopy() *ACLSummaryList {
+	if in == nil {
+		return nil
 	}
+	out := new(ACLSummaryList)
+	in.DeepCopyInto(out)
+	return out
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLStatus.
-func (in *ACLStatus) DeepCopy() *ACLStatus {
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ACLSummaryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACLSummaryReason) DeepCopyInto(out *ACLSummaryReason) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSummaryReason.
+func (in *ACLSummaryReason) DeepCopy() *ACLSummaryReason {
 	if in == nil {
 		return nil
 	}
-	out := new(ACLStatus)
+	out := new(ACLSummaryReason)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ACLStatusRuleError) DeepCopyInto(out *ACLStatusRuleError) {
+func (in *ACLSummarySpec) DeepCopyInto(out *ACLSummarySpec) {
 	*out = *in
+	if in.MaxFailing != nil {
+		in, out := &in.MaxFailing, &out.MaxFailing
+		*out = new(int32)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLStatusRuleError.
-func (in *ACLStatusRuleError) DeepCopy() *ACLStatusRuleError {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSummarySpec.
+func (in *ACLSummarySpec) DeepCopy() *ACLSummarySpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ACLStatusRuleError)
+	out := new(ACLSummarySpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ACLStatusStale) DeepCopyInto(out *ACLStatusStale) {
+func (in *ACLSummaryStatus) DeepCopyInto(out *ACLSummaryStatus) {
 	*out = *in
-	if in.Rules != nil {
-		in, out := &in.Rules, &out.Rules
-		*out = make([]v1.NetworkPolicyEgressRule, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.Reasons != nil {
+		in, out := &in.Reasons, &out.Reasons
+		*out = make([]ACLSummaryReason, len(*in))
+		copy(*out, *in)
+	}
+	if in.Failing != nil {
+		in, out := &in.Failing, &out.Failing
+		*out = make([]ACLSummaryFailingACL, len(*in))
+		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLStatusStale.
-func (in *ACLStatusStale) DeepCopy() *ACLStatusStale {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACLSummaryStatus.
+func (in *ACLSummaryStatus) DeepCopy() *ACLSummaryStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(ACLStatusStale)
+	out := new(ACLSummaryStatus)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -425,6 +1247,21 @@ func (in *ProtoPort) DeepCopy() *ProtoPort {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResolutionFailure) DeepCopyInto(out *ResolutionFailure) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResolutionFailure.
+func (in *ResolutionFailure) DeepCopy() *ResolutionFailure {
+	if in == nil {
+		return nil
+	}
+	out := new(ResolutionFailure)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceAddressStatus) DeepCopyInto(out *ResourceAddressStatus) {
 	*out = *in
@@ -433,6 +1270,16 @@ func (in *ResourceAddressStatus) DeepCopyInto(out *ResourceAddressStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.InternalIPs != nil {
+		in, out := &in.InternalIPs, &out.InternalIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Failures != nil {
+		in, out := &in.Failures, &out.Failures
+		*out = make([]ResolutionFailure, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceAddressStatus.
@@ -524,7 +1371,7 @@ func (in *TsuruAppAddress) DeepCopyInto(out *TsuruAppAddress) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -581,6 +1428,11 @@ func (in *TsuruAppAddressList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TsuruAppAddressSpec) DeepCopyInto(out *TsuruAppAddressSpec) {
 	*out = *in
+	if in.Routers != nil {
+		in, out := &in.Routers, &out.Routers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TsuruAppAddressSpec.
@@ -592,3 +1444,112 @@ func (in *TsuruAppAddressSpec) DeepCopy() *TsuruAppAddressSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TsuruServiceInstanceAddress) DeepCopyInto(out *TsuruServiceInstanceAddress) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TsuruServiceInstanceAddress.
+func (in *TsuruServiceInstanceAddress) DeepCopy() *TsuruServiceInstanceAddress {
+	if in == nil {
+		return nil
+	}
+	out := new(TsuruServiceInstanceAddress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TsuruServiceInstanceAddress) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TsuruServiceInstanceAddressList) DeepCopyInto(out *TsuruServiceInstanceAddressList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TsuruServiceInstanceAddress, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TsuruServiceInstanceAddressList.
+func (in *TsuruServiceInstanceAddressList) DeepCopy() *TsuruServiceInstanceAddressList {
+	if in == nil {
+		return nil
+	}
+	out := new(TsuruServiceInstanceAddressList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TsuruServiceInstanceAddressList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TsuruServiceInstanceAddressSpec) DeepCopyInto(out *TsuruServiceInstanceAddressSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TsuruServiceInstanceAddressSpec.
+func (in *TsuruServiceInstanceAddressSpec) DeepCopy() *TsuruServiceInstanceAddressSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TsuruServiceInstanceAddressSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TsuruServiceInstanceAddressStatus) DeepCopyInto(out *TsuruServiceInstanceAddressStatus) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]TsuruServiceInstanceEndpoint, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TsuruServiceInstanceAddressStatus.
+func (in *TsuruServiceInstanceAddressStatus) DeepCopy() *TsuruServiceInstanceAddressStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TsuruServiceInstanceAddressStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TsuruServiceInstanceEndpoint) DeepCopyInto(out *TsuruServiceInstanceEndpoint) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TsuruServiceInstanceEndpoint.
+func (in *TsuruServiceInstanceEndpoint) DeepCopy() *TsuruServiceInstanceEndpoint {
+	if in == nil {
+		return nil
+	}
+	out := new(TsuruServiceInstanceEndpoint)
+	in.DeepCopyInto(out)
+	return out
+}
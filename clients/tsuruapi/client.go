@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 
 	"github.com/pkg/errors"
 	"github.com/tsuru/tsuru/app"
@@ -13,6 +14,24 @@ import (
 type Client interface {
 	AppInfo(ctx context.Context, appName string) (*app.App, error)
 	ServiceInstanceInfo(ctx context.Context, serviceName, instance string) (*ServiceInstanceInfo, error)
+	// PoolApps returns the names of apps deployed on pool
+	PoolApps(ctx context.Context, pool string) ([]string, error)
+}
+
+// StatusError is returned when Tsuru API responds with an unexpected status code
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("failed to request, status code: %d", e.StatusCode)
+}
+
+// Transient reports whether the request may succeed when retried
+func (e *StatusError) Transient() bool {
+	return e.StatusCode >= http.StatusInternalServerError ||
+		e.StatusCode == http.StatusTooManyRequests ||
+		e.StatusCode == http.StatusRequestTimeout
 }
 
 type ServiceInstanceInfo struct {
@@ -36,7 +55,7 @@ func (c *client) AppInfo(ctx context.Context, appName string) (*app.App, error)
 	// TODO add cache
 	var appData app.App
 
-	req, err := http.NewRequest(http.MethodGet, c.host+"/apps/"+appName, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.host+"/apps/"+appName, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -51,7 +70,7 @@ func (c *client) AppInfo(ctx context.Context, appName string) (*app.App, error)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to request, status code: %d", resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	err = json.NewDecoder(resp.Body).Decode(&appData)
@@ -68,7 +87,7 @@ func (c *client) AppInfo(ctx context.Context, appName string) (*app.App, error)
 func (c *client) ServiceInstanceInfo(ctx context.Context, serviceName, instance string) (*ServiceInstanceInfo, error) {
 	// TODO add cache
 	info := &ServiceInstanceInfo{}
-	req, err := http.NewRequest(http.MethodGet, c.host+"/services/"+serviceName+"/instances/"+instance, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.host+"/services/"+serviceName+"/instances/"+instance, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -83,7 +102,7 @@ func (c *client) ServiceInstanceInfo(ctx context.Context, serviceName, instance
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to request, status code: %d", resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	err = json.NewDecoder(resp.Body).Decode(info)
@@ -93,3 +112,38 @@ func (c *client) ServiceInstanceInfo(ctx context.Context, serviceName, instance
 
 	return info, nil
 }
+
+func (c *client) PoolApps(ctx context.Context, pool string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.host+"/apps?pool="+url.QueryEscape(pool), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	apps := []struct {
+		Name string `json:"name"`
+	}{}
+	err = json.NewDecoder(resp.Body).Decode(&apps)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(apps))
+	for _, app := range apps {
+		names = append(names, app.Name)
+	}
+
+	return names, nil
+}
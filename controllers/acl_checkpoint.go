@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	netv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+// DefaultReconcileDeadline is used by ACLReconciler without a ReconcileDeadline
+const DefaultReconcileDeadline = 30 * time.Second
+
+func reconcileDeadline(deadline time.Duration) time.Duration {
+	if deadline <= 0 {
+		return DefaultReconcileDeadline
+	}
+	return deadline
+}
+
+// destinationResult is the outcome of the rules generation of a destination
+type destinationResult struct {
+	rules       []netv1.NetworkPolicyEgressRule
+	err         error
+	rejectedIPs []string
+}
+
+type aclCheckpoint struct {
+	generation int64
+	results    []destinationResult
+}
+
+// aclCheckpoints keeps the results of the destinations resolved by a reconcile that ran out of
+// its deadline, the next reconcile of the same generation of spec continues after them
+type aclCheckpoints struct {
+	mu          sync.Mutex
+	checkpoints map[types.NamespacedName]aclCheckpoint
+}
+
+// Get returns the results of the first destinations of acl, nil when the spec changed
+func (c *aclCheckpoints) Get(acl *v1alpha1.ACL) []destinationResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	checkpoint, ok := c.checkpoints[types.NamespacedName{Namespace: acl.Namespace, Name: acl.Name}]
+	if !ok || checkpoint.generation != acl.Generation {
+		return nil
+	}
+	return copyDestinationResults(checkpoint.results)
+}
+
+func (c *aclCheckpoints) Set(acl *v1alpha1.ACL, results []destinationResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.checkpoints == nil {
+		c.checkpoints = map[types.NamespacedName]aclCheckpoint{}
+	}
+	c.checkpoints[types.NamespacedName{Namespace: acl.Namespace, Name: acl.Name}] = aclCheckpoint{
+		generation: acl.Generation,
+		results:    copyDestinationResults(results),
+	}
+}
+
+func (c *aclCheckpoints) Forget(name types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.checkpoints, name)
+}
+
+// copyDestinationResults copies the rules of results, the reconcile goes on changing them
+func copyDestinationResults(results []destinationResult) []destinationResult {
+	copied := make([]destinationResult, len(results))
+	for i, result := range results {
+		copied[i] = result
+		copied[i].rules = copyEgressRules(result.rules)
+	}
+	return copied
+}
+
+// destinationResults generates the rules of the destinations of acl, starting after the ones of
+// its checkpoint. Destinations are not started once the deadline is over, at least one is,
+// the results are shorter than spec.destinations when the remaining ones are left for the next reconcile.
+// Destinations expired at now are not resolved
+func (r *ACLReconciler) destinationResults(ctx context.Context, acl *v1alpha1.ACL, backend PolicyBackend, addressOptions addressOptions, now time.Time) ([]destinationResult, bool) {
+	deadline := time.Now().Add(reconcileDeadline(r.ReconcileDeadline))
+	checkpoint := r.checkpoints.Get(acl)
+
+	results := make([]destinationResult, 0, len(acl.Spec.Destinations))
+	results = append(results, checkpoint...)
+	for i := len(results); i < len(acl.Spec.Destinations); i++ {
+		if i > len(checkpoint) && time.Now().After(deadline) {
+			r.checkpoints.Set(acl, results)
+			return results, true
+		}
+
+		destination := acl.Spec.Destinations[i]
+		if destinationExpired(destination, now) {
+			// expired destinations are not resolved, they have no rules
+			results = append(results, destinationResult{})
+			continue
+		}
+
+		if resolvedByBackend(destination, backend) {
+			// hostnames are resolved by the backend
+			results = append(results, destinationResult{})
+			continue
+		}
+
+		result := destinationResult{}
+		result.rules, result.err = r.egressRulesForDestination(ctx, destination, addressOptions)
+		if destination.ExternalDNS != nil {
+			result.rejectedIPs = addressOptions.rejectedAddresses[destination.ExternalDNS.Name]
+		}
+		results = append(results, result)
+	}
+
+	r.checkpoints.Forget(types.NamespacedName{Namespace: acl.Namespace, Name: acl.Name})
+	return results, false
+}
+
+// keepAppliedInProgress reports whether the policy applied for the generation of acl is kept while
+// the remaining destinations are resolved, a policy applied before a destination expired or before
+// the windows of schedule closed is not
+func keepAppliedInProgress(acl *v1alpha1.ACL, oldStatus *v1alpha1.ACLStatus, schedule *aclSchedule, now time.Time) bool {
+	if !acl.Status.InProgress || acl.Status.LastApplied == nil || acl.Status.LastApplied.Generation != acl.Generation {
+		return false
+	}
+	if !schedule.allowsEgress() || (oldStatus.Schedule != nil && !oldStatus.Schedule.Open) {
+		return false
+	}
+	return !expiredSince(acl.Spec.Destinations, acl.Status.LastApplied.Timestamp, now)
+}
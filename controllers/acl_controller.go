@@ -24,46 +24,104 @@ import (
 	"reflect"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync/atomic"
+	"text/template"
 	"time"
 
 	"github.com/pkg/errors"
 	tsuruErrors "github.com/tsuru/tsuru/errors"
-	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	v1alpha1 "github.com/tsuru/acl-operator/api/v1alpha1"
 	"github.com/tsuru/acl-operator/clients/tsuruapi"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	netv1 "k8s.io/api/networking/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-var (
-	requeueAfter = time.Minute * 10
+const (
+	// DefaultRequeueInterval is used by reconcilers without a RequeueInterval
+	DefaultRequeueInterval = 10 * time.Minute
+
+	// DefaultNamespaceLabelKey follows our common practice to add name of namespace as a label
+	DefaultNamespaceLabelKey = "name"
+
+	// pendingAddressRequeueInterval is used while the address objects of an ACL are not reconciled yet
+	pendingAddressRequeueInterval = 5 * time.Second
+
+	// skippedDestinationRequeueInterval is used to retry the destinations skipped by an ACL
+	skippedDestinationRequeueInterval = 30 * time.Second
+)
 
+var (
 	desiredPolicyType = []netv1.PolicyType{
 		netv1.PolicyTypeEgress,
 	}
+
+	desiredPolicyTypeWithIngress = []netv1.PolicyType{
+		netv1.PolicyTypeEgress,
+		netv1.PolicyTypeIngress,
+	}
 )
 
 const (
 	externalDNSIndex   = "external-dns-name"
+	externalIPIndex    = "external-ip"
 	rpaasInstanceIndex = "rpaas-instance-name"
 	tsuruAppNameIndex  = "tsuru-app-name"
+	tsuruAppPoolIndex  = "tsuru-app-pool"
+
+	tsuruServiceInstanceIndex = "tsuru-service-instance-name"
+)
+
+const (
+	eventReasonNetworkPolicyCreated        = "NetworkPolicyCreated"
+	eventReasonNetworkPolicyUpdated        = "NetworkPolicyUpdated"
+	eventReasonNetworkPolicyFailed         = "NetworkPolicyFailed"
+	eventReasonInvalidSource               = "InvalidSource"
+	eventReasonInvalidExtraEgress          = "InvalidExtraEgress"
+	eventReasonInvalidSchedule             = "InvalidSchedule"
+	eventReasonInvalidPolicyName           = "InvalidPolicyName"
+	eventReasonPolicyTooLarge              = "PolicyTooLarge"
+	eventReasonDestinationResolutionFailed = "DestinationResolutionFailed"
+	eventReasonIngressResolutionFailed     = "IngressResolutionFailed"
+	eventReasonServiceLookupFailed         = "ServiceLookupFailed"
+	eventReasonNoEgressRules               = "NoEgressRules"
+	eventReasonUnsupportedDestination      = "UnsupportedDestination"
+	eventReasonPolicyConflict              = "PolicyConflict"
+	eventReasonOverlappingPorts            = "OverlappingPorts"
+	eventReasonAddressesRejected           = "AddressesRejected"
+	eventReasonSourceChanged               = "SourceChanged"
+	eventReasonDestinationExpired          = "DestinationExpired"
+	eventReasonWorkloadNotFound            = "WorkloadNotFound"
+	conditionReasonReconciled              = "Reconciled"
+	conditionReasonDryRun                  = "DryRun"
+	conditionReasonRuleErrors              = "RuleErrors"
+	conditionReasonDestinationsSkipped     = "DestinationsSkipped"
+	conditionReasonPaused                  = "Paused"
+	conditionReasonLastAppliedKept         = "LastAppliedKept"
+	conditionReasonInProgress              = "InProgress"
+	maxEventMessageLength                  = 1024
 )
 
 // ACLReconciler reconciles a ACL object
@@ -72,71 +130,272 @@ type ACLReconciler struct {
 	Scheme   *runtime.Scheme
 	TsuruAPI tsuruapi.Client
 	Resolver ACLDNSResolver
+	Recorder record.EventRecorder
+
+	// APIReader reads the ConfigMaps of configMap destinations and the workloads of sources, only
+	// their metadata is cached by the manager, defaults to Client
+	APIReader client.Reader
+
+	// RequeueInterval is the interval to reconcile again an ACL, defaults to DefaultRequeueInterval
+	RequeueInterval time.Duration
+
+	// RequeueJitter spreads each periodic requeue by up to this fraction of the interval,
+	// more or less, the requeues are not spread when zero
+	RequeueJitter float64
+
+	// TsuruAPITimeout is the deadline of each call to Tsuru API, defaults to DefaultTsuruAPITimeout
+	TsuruAPITimeout time.Duration
+
+	// NamespaceLabelKey is the label of namespaces that holds its name, defaults to DefaultNamespaceLabelKey
+	NamespaceLabelKey string
+
+	// LabelScheme are the labels of pods used by selectors, empty keys use DefaultLabelScheme
+	LabelScheme LabelScheme
+
+	// PolicyBackend writes the policies generated by ACLs, defaults to kubernetes NetworkPolicies
+	PolicyBackend PolicyBackend
+
+	// IPFamilies are used by ACLs without spec.ipFamilies, all families are allowed when empty
+	IPFamilies []v1alpha1.IPFamily
+
+	// CIDRAggregation is used by ACLs without spec.cidrAggregation, addresses are not summarized when nil
+	CIDRAggregation *v1alpha1.ACLSpecCIDRAggregation
+
+	// AddressFilter is used by ACLs without spec.addressFilter, resolved addresses are not filtered when nil
+	AddressFilter *v1alpha1.ACLSpecAddressFilter
+
+	// DryRun computes the policies without writing them, the changes are reported on status.dryRunDiff
+	DryRun bool
+
+	// ClusterDNSEgress adds to every policy an egress rule allowing DNS queries to ClusterDNS
+	ClusterDNSEgress bool
+
+	// ClusterDNS are the pods allowed by ClusterDNSEgress, defaults to DefaultClusterDNS
+	ClusterDNS *ClusterDNS
+
+	// NamespacedDNSEntries creates an ACLDNSEntry per namespace for each host, so the ACLs of a namespace
+	// do not share the resolution of others, by default a single entry is shared by the whole cluster
+	NamespacedDNSEntries bool
+
+	// TsuruAppServiceIPs allows the cluster IPs of the services of apps on tsuruApp destinations as
+	// ipBlock peers along with their pods, for network plugins that evaluate policies before the
+	// service VIP is translated to a pod address
+	TsuruAppServiceIPs bool
+
+	// ForcePolicyOwnership overwrites existing policies with the name of an ACL policy even when
+	// they were not written by the operator, by default the ACL is unready with a conflict
+	ForcePolicyOwnership bool
+
+	// AbortOnDestinationError keeps the policy untouched when a destination without ruleID fails,
+	// by default the destination is skipped and the rules of the other destinations are applied
+	AbortOnDestinationError bool
+
+	// ReconcileDeadline bounds the time spent generating the rules of the destinations of an ACL,
+	// the rules generated so far are applied and the ACL is requeued to continue the remaining
+	// destinations, defaults to DefaultReconcileDeadline
+	ReconcileDeadline time.Duration
+
+	// Selector restricts the reconciled ACLs to the ones with matching labels, so many instances of
+	// the operator reconcile a shard of the ACLs each, every ACL is reconciled when nil
+	Selector labels.Selector
+
+	// Refresh enqueues ACLs on demand, like the refreshes of AdminServer
+	Refresh *RefreshTrigger
+
+	// MaxPolicySize is the largest policy applied, in bytes of its rules encoded as JSON, larger
+	// policies make the ACL unready, defaults to DefaultMaxPolicySize and there is no limit when negative
+	MaxPolicySize int
+
+	// PolicyNameTemplate names the policies of ACLs without one on status, parsed by
+	// ParsePolicyNameTemplate, defaults to DefaultPolicyNameTemplate
+	PolicyNameTemplate *template.Template
+
+	// MaxConcurrentReconciles is the number of ACLs reconciled at once, defaults to DefaultACLConcurrency
+	MaxConcurrentReconciles int
+
+	// now returns the time windows of spec.schedule are evaluated at, defaults to time.Now
+	now func() time.Time
 
 	serviceCache atomic.Pointer[serviceCache]
+	poolApps     poolAppsCache
+	memo         reconcileMemo
+	priorities   aclPriorities
+	checkpoints  aclCheckpoints
+	conflicts    applyConflicts
 }
 
 //+kubebuilder:rbac:groups=extensions.tsuru.io,resources=acls,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=extensions.tsuru.io,resources=acls/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=extensions.tsuru.io,resources=acls/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
-func (r *ACLReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *ACLReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 	l := log.FromContext(ctx)
 
 	acl := &v1alpha1.ACL{}
-	err := r.Client.Get(ctx, req.NamespacedName, acl)
+	outcome := reconcileResultNoop
+	defer func() {
+		if outcome == reconcileResultNoop && !acl.Status.Ready && !acl.Status.DryRun {
+			outcome = reconcileResultError
+		}
+		observeReconcileResult("acl", outcome, err)
+	}()
+
+	err = r.Client.Get(ctx, req.NamespacedName, acl)
 	if k8sErrors.IsNotFound(err) {
+		r.memo.Forget(req.NamespacedName)
+		r.checkpoints.Forget(req.NamespacedName)
+		r.conflicts.reset(req.NamespacedName)
+		r.priorities.done(req.NamespacedName)
+		return ctrl.Result{}, nil
 	} else if err != nil {
 		l.Error(err, "could not get ACL object")
 		return ctrl.Result{}, err
 	}
 
-	oldStatus := acl.Status.DeepCopy()
+	if !r.selects(acl) {
+		// events of other sources, like refreshes and owned policies, may reach ACLs of other shards
+		l.V(1).Info("ACL does not match the selector of the operator, ignoring it")
+		outcome = reconcileResultIgnored
+		return ctrl.Result{}, nil
+	}
 
-	networkPolicy := &netv1.NetworkPolicy{}
-	networkPolicyName := acl.Status.NetworkPolicy
-	if networkPolicyName == "" {
-		networkPolicyName = "acl-" + req.Name
+	priority := aclPriority(acl)
+	if r.priorities.wait(req.NamespacedName, priority) {
+		l.V(1).Info("ACLs with a higher priority are pending, deferring reconcile", "priority", priority)
+		outcome = reconcileResultDeferred
+		return ctrl.Result{RequeueAfter: aclPriorityRequeueDelay}, nil
 	}
+	defer r.priorities.done(req.NamespacedName)
 
-	err = r.Client.Get(ctx, client.ObjectKey{
-		Namespace: req.Namespace,
-		Name:      networkPolicyName,
-	}, networkPolicy)
+	backend := r.policyBackend()
+	ctx, l = objectLogger(ctx, acl, "backend", backend.Name(), "destinations", len(acl.Spec.Destinations))
 
-	if k8sErrors.IsNotFound(err) {
-	} else if err != nil {
-		l.Error(err, "could not get NetworkPolicy object")
+	if !acl.DeletionTimestamp.IsZero() {
+		r.memo.Forget(req.NamespacedName)
+		r.checkpoints.Forget(req.NamespacedName)
+		r.conflicts.reset(req.NamespacedName)
+		err = r.finalizeACL(ctx, acl)
+		if err != nil {
+			l.Error(err, "could not finalize ACL object")
+		}
 		return ctrl.Result{}, err
 	}
 
-	networkPolicyHasChanges := false
-	statusNeedsUpdate := false
-	networkPolicy.ObjectMeta.Namespace = acl.ObjectMeta.Namespace
-	networkPolicy.ObjectMeta.Name = networkPolicyName
+	if isPaused(acl) {
+		r.memo.Forget(req.NamespacedName)
+		outcome = reconcileResultPaused
+		return ctrl.Result{}, r.setPausedStatus(ctx, acl)
+	}
+
+	err = r.ensureFinalizer(ctx, acl)
+	if err != nil {
+		l.Error(err, "could not add finalizer to ACL object")
+		return ctrl.Result{}, err
+	}
 
-	if len(networkPolicy.OwnerReferences) == 0 {
-		networkPolicy.OwnerReferences = []v1.OwnerReference{
-			*metav1.NewControllerRef(acl, acl.GroupVersionKind()),
+	// address objects are created while rules are generated, even when some destination fails
+	defer func() {
+		ownerErr := r.addAddressOwner(ctx, acl)
+		if ownerErr != nil {
+			l.Error(ownerErr, "could not register ACL as owner of address objects")
+			if err == nil {
+				err = ownerErr
+			}
+		}
+	}()
+
+	schedule, err := r.schedule(acl)
+	if err != nil {
+		err = r.setUnreadyStatus(ctx, acl, eventReasonInvalidSchedule, "invalid spec.schedule, err: "+err.Error())
+		return ctrl.Result{}, err
+	}
+	// the ACL is reconciled again when its windows open or close
+	defer func() {
+		result = schedule.requeue(result)
+	}()
+
+	// temporary destinations are dropped as soon as they expire
+	now := r.timeNow()
+	defer func() {
+		result = requeueAt(result, nextDestinationExpiry(acl.Spec.Destinations, now), now)
+	}()
+
+	// the key is taken before the rules are generated, so changes made meanwhile invalidate it
+	memoKey := r.memoKey(ctx, acl)
+	if memoKey != "" && schedule != nil {
+		memoKey += ",schedule=" + strconv.FormatBool(schedule.open)
+	}
+	if expired := expiredDestinations(acl.Spec.Destinations, now); memoKey != "" && expired > 0 {
+		memoKey += ",expired=" + strconv.Itoa(expired)
+	}
+	if policy, ok := r.memo.Get(acl, memoKey); ok {
+		l.V(1).Info("inputs of ACL did not change since last reconcile, reusing its policy")
+		outcome, err = r.applyPolicy(ctx, acl, backend, policy, false)
+		if err != nil || outcome == reconcileResultConflict {
+			r.memo.Forget(req.NamespacedName)
+			return ctrl.Result{RequeueAfter: conflictRequeueInterval(outcome)}, err
 		}
+		r.memo.Set(acl, memoKey, policy)
 
-		networkPolicyHasChanges = true
+		return ctrl.Result{
+			Requeue:      true,
+			RequeueAfter: jitterRequeue(requeueInterval(r.RequeueInterval), r.RequeueJitter),
+		}, nil
 	}
+	r.memo.Forget(req.NamespacedName)
+
+	oldStatus := acl.Status.DeepCopy()
+
+	// the condition is only kept while the ACL is paused
+	meta.RemoveStatusCondition(&acl.Status.Conditions, v1alpha1.ACLConditionPaused)
 
-	if !reflect.DeepEqual(networkPolicy.Spec.PolicyTypes, desiredPolicyType) {
-		networkPolicy.Spec.PolicyTypes = desiredPolicyType
-		networkPolicyHasChanges = true
+	statusNeedsUpdate := false
+
+	// ACLs keep the name of their existing policy, a new template only names new policies
+	policyName := acl.Status.NetworkPolicy
+	if policyName == "" {
+		policyName, err = r.policyName(acl)
+		if err != nil {
+			err = r.setUnreadyStatus(ctx, acl, eventReasonInvalidPolicyName, "could not render the name of NetworkPolicy, err: "+err.Error())
+			return ctrl.Result{}, err
+		}
 	}
 
 	podSelector := r.podSelectorForSource(acl.Spec.Source)
+	if podSelector == nil && acl.Spec.Source.Workload != nil && acl.Spec.Source.Workload.Validate() == nil {
+		podSelector, err = r.podSelectorForWorkload(ctx, acl.Namespace, acl.Spec.Source.Workload)
+		var notFoundErr *workloadNotFoundError
+		if errors.As(err, &notFoundErr) {
+			// the workload may be created after the ACL, the policy is kept as it is meanwhile
+			err = r.setUnreadyStatus(ctx, acl, eventReasonWorkloadNotFound, err.Error())
+			return ctrl.Result{RequeueAfter: missingWorkloadRequeueInterval}, err
+		} else if errors.Is(err, errEmptyWorkloadSelector) {
+			err = r.setUnreadyStatus(ctx, acl, eventReasonInvalidSource, "invalid spec.source, err: "+err.Error())
+			return ctrl.Result{}, err
+		} else if err != nil {
+			l.Error(err, "could not get workload of spec.source")
+			return ctrl.Result{}, err
+		}
+	}
 	if podSelector == nil {
-		err = r.setUnreadyStatus(ctx, acl, "No podSelector generated by spec.source")
+		err = r.setUnreadyStatus(ctx, acl, eventReasonInvalidSource, "No podSelector generated by spec.source")
 		return ctrl.Result{}, err
 	}
 
-	if !reflect.DeepEqual(networkPolicy.Spec.PodSelector.MatchLabels, podSelector) {
-		networkPolicy.Spec.PodSelector.MatchLabels = podSelector
-		networkPolicyHasChanges = true
+	err = acl.Spec.Source.Validate()
+	if err != nil {
+		err = r.setUnreadyStatus(ctx, acl, eventReasonInvalidSource, "invalid spec.source, err: "+err.Error())
+		return ctrl.Result{}, err
+	}
+
+	var extraEgressRules []netv1.NetworkPolicyEgressRule
+	if value, ok := acl.Annotations[v1alpha1.ExtraEgressAnnotation]; ok {
+		extraEgressRules, err = v1alpha1.ParseExtraEgress(value)
+		if err != nil {
+			err = r.setUnreadyStatus(ctx, acl, eventReasonInvalidExtraEgress, "invalid annotation "+v1alpha1.ExtraEgressAnnotation+", err: "+err.Error())
+			return ctrl.Result{}, err
+		}
 	}
 
 	newEgressRules := []netv1.NetworkPolicyEgressRule{}
@@ -150,26 +409,130 @@ func (r *ACLReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 		mapStaleEgress[stale.RuleID] = stale.Rules
 	}
 
-	for _, destination := range acl.Spec.Destinations {
-		egressRules, err := r.egressRulesForDestination(ctx, destination)
+	warnings := []string{}
+	rejectedWarnings := []string{}
+	unresolved := []string{}
+	destinationRules := []destinationEgressRules{}
+	pending := false
+	skippedErrors := []v1alpha1.ACLStatusRuleError{}
+	fqdns := []v1alpha1.ACLSpecExternalDNS{}
+	addressOptions := r.addressOptions(acl)
+	resolvedDestinations := make([]v1alpha1.ACLStatusResolvedDestination, 0, len(acl.Spec.Destinations))
+	expired := []string{}
+	results, inProgress := r.destinationResults(ctx, acl, backend, addressOptions, now)
+	for i, result := range results {
+		destination := acl.Spec.Destinations[i]
+		if destinationExpired(destination, now) {
+			resolvedDestination := newResolvedDestination(i, destination, nil, false)
+			resolvedDestination.ExpiredAt = destination.ExpiresAt.UTC().Format(time.RFC3339)
+			if !expiredOnStatus(oldStatus, i) {
+				l.Info("destination expired, it is dropped from the policy", "destination", resolvedDestination.Destination, "expiredAt", resolvedDestination.ExpiredAt)
+				r.recordEvent(acl, corev1.EventTypeNormal, eventReasonDestinationExpired, "destination "+resolvedDestination.Destination+" expired at "+resolvedDestination.ExpiredAt+", it is dropped from the policy")
+			}
+			resolvedDestinations = append(resolvedDestinations, resolvedDestination)
+			expired = append(expired, resolvedDestination.Destination)
+			continue
+		}
+
+		if resolvedByBackend(destination, backend) {
+			// hostnames are resolved by the backend, no ACLDNSEntry is required,
+			// custom nameservers are only known by ACLDNSEntry
+			fqdns = append(fqdns, *destination.ExternalDNS)
+			resolvedDestination := newResolvedDestination(i, destination, nil, false)
+			resolvedDestination.HasRules = true
+			resolvedDestination.FQDNs = []string{destination.ExternalDNS.Name}
+			resolvedDestinations = append(resolvedDestinations, resolvedDestination)
+			continue
+		}
+
+		egressRules, err := result.rules, result.err
+		var unsupportedErr *unsupportedDestinationError
+		if errors.As(err, &unsupportedErr) {
+			warnings = append(warnings, unsupportedErr.Error())
+			resolvedDestinations = append(resolvedDestinations, newResolvedDestination(i, destination, nil, false))
+			continue
+		}
+
+		stale := false
+
+		if errors.Is(err, ErrAddressNotReady) {
+			l.Info("address object is not reconciled yet", "reason", err.Error())
+			pending = true
+			err = nil
+			if staleRules, ok := mapStaleEgress[destination.RuleID]; ok {
+				// keep the rules of last resolution until the new object is reconciled
+				egressRules = staleRules
+				stale = true
+			}
+		}
+
 		// TODO: think about inconsistences, or temporarrly inconsistences
-		if err != nil && destination.RuleID == "" {
+		if err != nil && destination.RuleID == "" && !r.AbortOnDestinationError {
+			// without ruleID its not possible to do a stale, the destination is skipped
+			destinationJSON, _ := json.Marshal(destination)
+			l.Error(err, "could not generate egress rule for destination, skipping it", "destination", string(destinationJSON))
+			skippedErrors = append(skippedErrors, v1alpha1.ACLStatusRuleError{
+				Destination: describeDestination(destination),
+				Error:       err.Error(),
+			})
+			egressRules = nil
+		} else if err != nil && destination.RuleID == "" {
 			// without ruleID its not possible to do a stale
 			destinationJSON, _ := json.Marshal(destination)
 			l.Error(err, "could not generate egress rule for destination", "destination", string(destinationJSON))
-			err = r.setUnreadyStatus(ctx, acl, "could not generate egress rule for destination "+string(destinationJSON)+", err: "+err.Error())
+			err = r.setUnreadyStatus(ctx, acl, eventReasonDestinationResolutionFailed, "could not generate egress rule for destination "+string(destinationJSON)+", err: "+err.Error())
 			return ctrl.Result{}, err
 		} else if err != nil {
 			ruleIDErrors[destination.RuleID] = err.Error()
 			egressRules = mapStaleEgress[destination.RuleID] // try to use stale
+			stale = len(egressRules) > 0
 			ruleIDDestinations[destination.RuleID] = copyEgressRules(egressRules)
 		} else if err == nil && destination.RuleID != "" {
 			ruleIDDestinations[destination.RuleID] = copyEgressRules(egressRules)
 		}
 
+		var resolutionErr *resolutionError
+		if errors.As(err, &resolutionErr) && !stale {
+			unresolved = append(unresolved, describeDestination(destination))
+		}
+
+		l.V(1).Info("egress rules generated for destination", "destination", describeDestination(destination), "rules", len(egressRules), "stale", stale)
+		resolvedDestination := newResolvedDestination(i, destination, egressRules, stale)
+		resolvedDestination.RejectedIPs = result.rejectedIPs
+		if len(resolvedDestination.RejectedIPs) > 0 {
+			rejectedWarnings = append(rejectedWarnings, fmt.Sprintf("%s resolved to addresses rejected by addressFilter: %s", resolvedDestination.Destination, strings.Join(resolvedDestination.RejectedIPs, ", ")))
+		}
+		resolvedDestinations = append(resolvedDestinations, resolvedDestination)
+		destinationRules = append(destinationRules, destinationEgressRules{destination: describeDestination(destination), rules: egressRules})
 		newEgressRules = append(newEgressRules, egressRules...)
 	}
 
+	if inProgress {
+		l.Info("reconcile deadline is over, applying the rules of the destinations resolved so far", "resolved", len(results))
+		for i, destination := range acl.Spec.Destinations[len(results):] {
+			// the remaining destinations keep their last rules until they are resolved again
+			staleRules, ok := mapStaleEgress[destination.RuleID]
+			if destinationExpired(destination, now) {
+				staleRules, ok = nil, false
+			}
+			if destination.RuleID != "" && ok {
+				ruleIDDestinations[destination.RuleID] = copyEgressRules(staleRules)
+				newEgressRules = append(newEgressRules, staleRules...)
+			}
+			resolvedDestinations = append(resolvedDestinations, newResolvedDestination(len(results)+i, destination, staleRules, ok))
+		}
+	}
+
+	if len(resolvedDestinations) == 0 {
+		resolvedDestinations = nil
+	}
+	acl.Status.ResolvedDestinations = resolvedDestinations
+
+	acl.Status.NextExpiry = ""
+	if next := nextDestinationExpiry(acl.Spec.Destinations, now); !next.IsZero() {
+		acl.Status.NextExpiry = next.UTC().Format(time.RFC3339)
+	}
+
 	acl.Status.Stale = make([]v1alpha1.ACLStatusStale, 0, len(ruleIDDestinations))
 	acl.Status.RuleErrors = make([]v1alpha1.ACLStatusRuleError, 0, len(ruleIDErrors))
 
@@ -193,61 +556,275 @@ func (r *ACLReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 		return acl.Status.RuleErrors[i].RuleID < acl.Status.RuleErrors[j].RuleID
 	})
 
+	// without stale rules the policy would lose the destinations that failed to resolve, a policy
+	// of a previous spec is not kept, its destinations may be gone
+	// nor a policy that still allows destinations that expired since
+	keepLastApplied := len(unresolved) > 0 && !r.DryRun && schedule.allowsEgress() &&
+		acl.Status.LastApplied != nil && acl.Status.LastApplied.Generation == acl.Generation &&
+		!expiredSince(acl.Spec.Destinations, acl.Status.LastApplied.Timestamp, now)
+
+	for _, skippedErr := range skippedErrors {
+		if !keepLastApplied && !containsRuleError(oldStatus.RuleErrors, skippedErr) {
+			r.recordEvent(acl, corev1.EventTypeWarning, eventReasonDestinationResolutionFailed, "could not generate egress rule for destination "+skippedErr.Destination+", skipping it, err: "+skippedErr.Error)
+		}
+	}
+	acl.Status.RuleErrors = append(acl.Status.RuleErrors, skippedErrors...)
+
+	overlapWarnings := overlappingPortsWarnings(destinationRules)
+	allWarnings := append(append(warnings, overlapWarnings...), rejectedWarnings...)
+	if len(allWarnings) == 0 {
+		allWarnings = nil
+	}
+	if !reflect.DeepEqual(acl.Status.Warnings, allWarnings) {
+		for _, warning := range warnings {
+			r.recordEvent(acl, corev1.EventTypeWarning, eventReasonUnsupportedDestination, warning)
+		}
+		for _, warning := range overlapWarnings {
+			r.recordEvent(acl, corev1.EventTypeWarning, eventReasonOverlappingPorts, warning)
+		}
+		for _, warning := range rejectedWarnings {
+			r.recordEvent(acl, corev1.EventTypeWarning, eventReasonAddressesRejected, warning)
+		}
+		acl.Status.Warnings = allWarnings
+	}
+
 	acl.Status.Ready = len(acl.Status.RuleErrors) == 0
 	acl.Status.Reason = ""
+	if acl.Status.Ready {
+		setACLReadyCondition(acl, metav1.ConditionTrue, conditionReasonReconciled, "")
+		setACLDegradedCondition(acl, metav1.ConditionFalse, conditionReasonReconciled, "")
+	} else if len(skippedErrors) > 0 {
+		message := fmt.Sprintf("%d destinations could not be resolved and are skipped, the other destinations are applied, see status.errors", len(skippedErrors))
+		setACLReadyCondition(acl, metav1.ConditionFalse, conditionReasonDestinationsSkipped, message)
+		setACLDegradedCondition(acl, metav1.ConditionTrue, conditionReasonDestinationsSkipped, message)
+	} else {
+		setACLReadyCondition(acl, metav1.ConditionFalse, conditionReasonRuleErrors, "some destinations could not be resolved, stale rules are used, see status.errors")
+		setACLDegradedCondition(acl, metav1.ConditionTrue, conditionReasonRuleErrors, "some destinations could not be resolved, stale rules are used, see status.errors")
+	}
+
+	acl.Status.InProgress = inProgress
+	if inProgress {
+		message := fmt.Sprintf("%d of %d destinations are resolved, the remaining destinations are resolved on the next reconcile", len(results), len(acl.Spec.Destinations))
+		acl.Status.Ready = false
+		acl.Status.Reason = message
+		setACLReadyCondition(acl, metav1.ConditionFalse, conditionReasonInProgress, message)
+	}
+
+	if keepLastApplied {
+		err = r.keepLastAppliedPolicy(ctx, acl, oldStatus, unresolved)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		return r.requeueACL(inProgress, pending, true), nil
+	}
 
-	newEgressRules, err = r.fillPodSelectorByCIDR(ctx, newEgressRules)
-	if err != nil {
-		l.Error(err, "could not generate egress rule based on kubernetes selector", "destination")
-		err = r.setUnreadyStatus(ctx, acl, "could not generate egress rule based on kubernetes selector, err: "+err.Error())
-		return ctrl.Result{}, err
+	acl.Status.Schedule = schedule.status()
+	if keepAppliedInProgress(acl, oldStatus, schedule, now) && !r.DryRun {
+		// the applied policy has the remaining destinations, applying only the ones resolved so far
+		// would drop them until the reconcile that resolves them
+		l.Info("keeping the applied policy until every destination is resolved", "resolved", len(results), "lastApplied", acl.Status.LastApplied.Timestamp)
+		if reflect.DeepEqual(oldStatus, &acl.Status) {
+			return r.requeueACL(inProgress, pending, len(skippedErrors) > 0), nil
+		}
+		err = r.Client.Status().Update(ctx, acl)
+		if err != nil {
+			l.Error(err, "could not update acl status")
+			return ctrl.Result{}, err
+		}
+		return r.requeueACL(inProgress, pending, len(skippedErrors) > 0), nil
 	}
 
-	if len(newEgressRules) == 0 {
-		err = r.setUnreadyStatus(ctx, acl, "No egress generated by spec.destinations")
-		return ctrl.Result{}, err
+	if !schedule.allowsEgress() {
+		// destinations are still resolved, so the status has their rules when the windows open
+		l.Info("windows of spec.schedule are closed, the policy allows no egress of destinations", "opensAt", acl.Status.Schedule.NextTransition)
+		newEgressRules = nil
+		extraEgressRules = nil
+		fqdns = nil
 	}
 
-	if !reflect.DeepEqual(oldStatus, acl.Status) {
-		statusNeedsUpdate = true
+	if !acl.Spec.DisableServiceTranslation {
+		newEgressRules, err = r.fillPodSelectorByCIDR(ctx, newEgressRules)
+		if err != nil {
+			l.Error(err, "could not generate egress rule based on kubernetes selector", "destination")
+			err = r.setUnreadyStatus(ctx, acl, eventReasonServiceLookupFailed, "could not generate egress rule based on kubernetes selector, err: "+err.Error())
+			return ctrl.Result{}, err
+		}
 	}
 
-	if !reflect.DeepEqual(networkPolicy.Spec.Egress, newEgressRules) {
-		networkPolicy.Spec.Egress = newEgressRules
-		networkPolicyHasChanges = true
+	// raw rules are appended as they are, their peers are not looked up
+	if len(extraEgressRules) > 0 {
+		l.V(1).Info("merging extra egress rules of annotation", "rules", len(extraEgressRules))
 	}
+	acl.Status.ExtraEgressRules = len(extraEgressRules)
+	newEgressRules = mergeEgressRules(append(newEgressRules, extraEgressRules...))
+	l = l.WithValues("egressRules", len(newEgressRules))
+	ctx = log.IntoContext(ctx, l)
 
-	if networkPolicy.CreationTimestamp.IsZero() {
-		err = r.Client.Create(ctx, networkPolicy)
+	// once every destination expired the policy allows no egress, like a closed schedule
+	if len(newEgressRules) == 0 && len(fqdns) == 0 && schedule.allowsEgress() && len(expired) == 0 {
+		reason := "No egress generated by spec.destinations"
+		for _, skippedErr := range skippedErrors {
+			reason += ", skipped destination " + skippedErr.Destination + ", err: " + skippedErr.Error
+		}
+		err = r.setUnreadyStatus(ctx, acl, eventReasonNoEgressRules, reason)
+		if err != nil || (!pending && len(skippedErrors) == 0 && !inProgress) {
+			return ctrl.Result{}, err
+		}
+		return r.requeueACL(inProgress, pending, len(skippedErrors) > 0), nil
+	}
+
+	if r.ClusterDNSEgress {
+		newEgressRules = append(newEgressRules, r.clusterDNSEgressRule())
+	}
+
+	var newIngressRules []netv1.NetworkPolicyIngressRule
+	for _, ingress := range acl.Spec.Ingress {
+		ingressRules, err := r.ingressRulesForSource(ctx, ingress, acl.Namespace)
+		if errors.Is(err, ErrAddressNotReady) {
+			// the peers known so far are used until the address object is reconciled
+			l.Info("address object is not reconciled yet", "reason", err.Error())
+			pending = true
+			err = nil
+		}
 		if err != nil {
-			l.Error(err, "could not create NetworkPolicy object")
-			statusErr := r.setUnreadyStatus(ctx, acl, "could not create NetworkPolicy object, err: "+err.Error())
-			if statusErr != nil {
-				l.Error(err, "could not update status")
-			}
+			ingressJSON, _ := json.Marshal(ingress)
+			l.Error(err, "could not generate ingress rule for source", "ingress", string(ingressJSON))
+			err = r.setUnreadyStatus(ctx, acl, eventReasonIngressResolutionFailed, "could not generate ingress rule for source "+string(ingressJSON)+", err: "+err.Error())
 			return ctrl.Result{}, err
 		}
-		l.Info("NetworkPolicy object has been created")
 
-		acl.Status.NetworkPolicy = networkPolicy.Name
-		acl.Status.Ready = true
-		acl.Status.Reason = ""
+		newIngressRules = append(newIngressRules, ingressRules...)
+	}
+
+	if !reflect.DeepEqual(oldStatus, acl.Status) {
 		statusNeedsUpdate = true
+	}
+
+	policy := &aclPolicy{
+		Name:        policyName,
+		PodSelector: *podSelector,
+		Egress:      newEgressRules,
+		Ingress:     newIngressRules,
+		FQDNs:       fqdns,
+		TakeOver:    r.ForcePolicyOwnership,
+	}
+	if acl.Spec.Template != nil {
+		policy.Metadata = acl.Spec.Template.Metadata
+	}
 
-	} else if networkPolicyHasChanges {
-		err = r.Client.Update(ctx, networkPolicy)
+	// the API server would refuse the policy with an opaque error, the applied policy is kept
+	if err = r.checkPolicySize(policy); err != nil {
+		l.Info("policy is too large, keeping the applied policy", "reason", err.Error())
+		err = r.setUnreadyStatus(ctx, acl, eventReasonPolicyTooLarge, err.Error())
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		return r.requeueACL(inProgress, pending, len(skippedErrors) > 0), nil
+	}
+
+	if r.DryRun {
+		err = r.reportDryRun(ctx, acl, backend, policy)
 		if err != nil {
-			l.Error(err, "could not update NetworkPolicy object")
-			statusErr := r.setUnreadyStatus(ctx, acl, "could not update NetworkPolicy object, err: "+err.Error())
-			if statusErr != nil {
-				l.Error(err, "could not update status")
-			}
 			return ctrl.Result{}, err
 		}
 
-		l.Info("NetworkPolicy object has been updated")
+		return r.requeueACL(inProgress, pending, len(skippedErrors) > 0), nil
+	}
+
+	outcome, err = r.applyPolicy(ctx, acl, backend, policy, statusNeedsUpdate)
+	if err != nil || outcome == reconcileResultConflict {
+		return ctrl.Result{RequeueAfter: conflictRequeueInterval(outcome)}, err
+	}
+
+	// pending and failed destinations are generated again on the next reconcile
+	if !pending && !inProgress && acl.Status.Ready {
+		r.memo.Set(acl, memoKey, policy)
+	}
+
+	return r.requeueACL(inProgress, pending, len(skippedErrors) > 0), nil
+}
+
+// applyPolicy writes policy with the backend and updates the status of acl when it changes,
+// returns whether the policy was created, updated or kept
+func (r *ACLReconciler) applyPolicy(ctx context.Context, acl *v1alpha1.ACL, backend PolicyBackend, policy *aclPolicy, statusNeedsUpdate bool) (string, error) {
+	l := log.FromContext(ctx)
+
+	if acl.Status.DryRun {
+		acl.Status.DryRun = false
+		acl.Status.DryRunDiff = ""
+		statusNeedsUpdate = true
+	}
+
+	name := types.NamespacedName{Namespace: acl.Namespace, Name: acl.Name}
+	applied, err := backend.Apply(ctx, acl, policy)
+	if k8sErrors.IsConflict(err) {
+		// another writer changed the policy between the read and the write of the backend
+		if conflicts := r.conflicts.add(name); conflicts < maxApplyConflicts {
+			l.Info("policy changed while it was applied, retrying", "conflicts", conflicts, "reason", err.Error())
+			return reconcileResultConflict, nil
+		}
+		err = errors.Wrapf(err, "policy changed while it was applied %d times in a row", maxApplyConflicts)
+	}
+	var conflictErr *policyConflictError
+	if errors.As(err, &conflictErr) {
+		// retrying does not help until someone removes the policy, the ACL is reconciled again on the next interval
+		l.Info(err.Error())
+		return reconcileResultNoop, r.setUnreadyStatus(ctx, acl, eventReasonPolicyConflict, err.Error())
+	}
+	if err != nil {
+		l.Error(err, "could not apply policy")
+		statusErr := r.setUnreadyStatus(ctx, acl, eventReasonNetworkPolicyFailed, err.Error())
+		if statusErr != nil {
+			l.Error(statusErr, "could not update status")
+		}
+		return "", err
+	}
+	r.conflicts.reset(name)
+
+	if lastApplied := lastAppliedEgress(acl, policy); acl.Status.LastApplied == nil || acl.Status.LastApplied.Hash != lastApplied.Hash {
+		acl.Status.LastApplied = lastApplied
+		statusNeedsUpdate = true
+	} else if acl.Status.LastApplied.Generation != acl.Generation {
+		acl.Status.LastApplied.Generation = acl.Generation
+		statusNeedsUpdate = true
+	}
+
+	switch applied.outcome {
+	case reconcileResultCreated:
+		l.Info(backend.Kind() + " object has been created")
+		r.recordEvent(acl, corev1.EventTypeNormal, eventReasonNetworkPolicyCreated, backend.Kind()+" "+policy.Name+" has been created")
 
-		acl.Status.NetworkPolicy = networkPolicy.Name
+		// the readiness computed by reconcile is kept, skipped destinations leave the ACL not ready
+		acl.Status.NetworkPolicy = policy.Name
+		statusNeedsUpdate = true
+
+	case reconcileResultUpdated:
+		message := backend.Kind() + " " + policy.Name + " has been updated"
+		if applied.egressDiff.empty() {
+			l.Info(backend.Kind() + " object has been updated")
+		} else {
+			l.Info(backend.Kind()+" object has been updated", applied.egressDiff.keysAndValues()...)
+			message += ", " + applied.egressDiff.String()
+		}
+		r.recordEvent(acl, corev1.EventTypeNormal, eventReasonNetworkPolicyUpdated, message)
+
+		acl.Status.NetworkPolicy = policy.Name
+		statusNeedsUpdate = true
+	}
+
+	if acl.Status.PolicyBackend != backend.Name() {
+		acl.Status.PolicyBackend = backend.Name()
+		statusNeedsUpdate = true
+	}
+
+	if source := describeSource(acl.Spec.Source); acl.Status.Source != source {
+		if acl.Status.Source != "" {
+			// the policy keeps its name, only the pods it selects change
+			message := fmt.Sprintf("spec.source changed from %s to %s, %s %s selects the pods of the new source", acl.Status.Source, source, backend.Kind(), policy.Name)
+			l.Info(message)
+			r.recordEvent(acl, corev1.EventTypeNormal, eventReasonSourceChanged, message)
+		}
+		acl.Status.Source = source
 		statusNeedsUpdate = true
 	}
 
@@ -255,21 +832,161 @@ func (r *ACLReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 		err = r.Client.Status().Update(ctx, acl)
 		if err != nil {
 			l.Error(err, "could not update status for ACL object")
-			return ctrl.Result{}, err
+			return "", err
+		}
+	}
+
+	return applied.outcome, nil
+}
+
+// lastAppliedEgress identifies the egress rules of policy, FQDNs are egress rules of the backend as well
+func lastAppliedEgress(acl *v1alpha1.ACL, policy *aclPolicy) *v1alpha1.ACLStatusLastApplied {
+	data, _ := json.Marshal([]interface{}{policy.Egress, policy.FQDNs})
+	return &v1alpha1.ACLStatusLastApplied{
+		Hash:       sha256String(string(data)),
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		Generation: acl.Generation,
+	}
+}
+
+// reportDryRun records the changes of policy on status, the ACL is never ready in dry-run mode
+func (r *ACLReconciler) reportDryRun(ctx context.Context, acl *v1alpha1.ACL, backend PolicyBackend, policy *aclPolicy) error {
+	l := log.FromContext(ctx)
+
+	diff, err := backend.Diff(ctx, acl, policy)
+	if err != nil {
+		l.Error(err, "could not compute policy diff", "backend", backend.Name())
+		statusErr := r.setUnreadyStatus(ctx, acl, eventReasonNetworkPolicyFailed, err.Error())
+		if statusErr != nil {
+			l.Error(statusErr, "could not update status")
 		}
+		return err
 	}
 
+	reason := "dry-run: " + backend.Kind() + " " + policy.Name + " is not applied"
+	if diff == "" {
+		reason += ", no changes"
+	} else {
+		l.Info("dry-run: policy has changes", "kind", backend.Kind(), "name", policy.Name, "diff", diff)
+		reason += ", changes on status.dryRunDiff"
+	}
+
+	acl.Status.DryRun = true
+	acl.Status.DryRunDiff = diff
+	acl.Status.PolicyBackend = backend.Name()
+	acl.Status.Ready = false
+	acl.Status.Reason = reason
+	setACLReadyCondition(acl, metav1.ConditionFalse, conditionReasonDryRun, reason)
+
+	err = r.Client.Status().Update(ctx, acl)
+	if err != nil {
+		l.Error(err, "could not update status for ACL object")
+	}
+	return err
+}
+
+// conflictRequeueInterval requeues an ACL shortly after a conflict on its policy, errors are
+// requeued with the backoff of the queue
+func conflictRequeueInterval(outcome string) time.Duration {
+	if outcome == reconcileResultConflict {
+		return applyConflictRequeueInterval
+	}
+	return 0
+}
+
+// requeueACL requeues an ACL in progress right away, so the remaining destinations are resolved
+// by the next reconcile, other ACLs are requeued after requeueIntervalForACL
+func (r *ACLReconciler) requeueACL(inProgress, pending, skipped bool) ctrl.Result {
+	if inProgress {
+		return ctrl.Result{Requeue: true}
+	}
 	return ctrl.Result{
 		Requeue:      true,
-		RequeueAfter: requeueAfter,
-	}, nil
+		RequeueAfter: jitterRequeue(requeueIntervalForACL(r.RequeueInterval, pending, skipped), r.RequeueJitter),
+	}
+}
+
+// requeueIntervalForACL reconciles an ACL again shortly while its address objects are
+// pending, the watches on them enqueue the ACL as well, the interval covers a missed event.
+// Skipped destinations are retried sooner than the regular interval
+func requeueIntervalForACL(interval time.Duration, pending, skipped bool) time.Duration {
+	if pending {
+		return pendingAddressRequeueInterval
+	}
+	interval = requeueInterval(interval)
+	if skipped && interval > skippedDestinationRequeueInterval {
+		return skippedDestinationRequeueInterval
+	}
+	return interval
+}
+
+func requeueInterval(interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return DefaultRequeueInterval
+	}
+	return interval
 }
 
-func (r *ACLReconciler) setUnreadyStatus(ctx context.Context, acl *v1alpha1.ACL, reason string) error {
+func (r *ACLReconciler) setUnreadyStatus(ctx context.Context, acl *v1alpha1.ACL, eventReason, reason string) error {
 	l := log.FromContext(ctx)
 
+	// an ACL failing the same way on every reconcile records a single event
+	if acl.Status.Ready || acl.Status.Reason != reason {
+		r.recordEvent(acl, corev1.EventTypeWarning, eventReason, reason)
+	}
+
 	acl.Status.Ready = false
 	acl.Status.Reason = reason
+	recordFailure(acl, eventReason, reason, time.Now())
+	setACLReadyCondition(acl, metav1.ConditionFalse, eventReason, reason)
+
+	err := r.Client.Status().Update(ctx, acl)
+	if err != nil {
+		l.Error(err, "could not update acl status")
+	}
+	return err
+}
+
+// keepLastAppliedPolicy leaves the policy as it was last applied while destinations fail to resolve,
+// the status reports the errors of this reconcile and the ACL is ready again once they resolve
+func (r *ACLReconciler) keepLastAppliedPolicy(ctx context.Context, acl *v1alpha1.ACL, oldStatus *v1alpha1.ACLStatus, unresolved []string) error {
+	l := log.FromContext(ctx)
+
+	message := fmt.Sprintf("could not resolve %s, the policy applied at %s is kept, see status.errors", strings.Join(unresolved, ", "), acl.Status.LastApplied.Timestamp)
+	l.Info("destinations could not be resolved, keeping the last applied policy", "destinations", unresolved, "lastApplied", acl.Status.LastApplied.Timestamp)
+	if oldStatus.Reason != message {
+		r.recordEvent(acl, corev1.EventTypeWarning, eventReasonDestinationResolutionFailed, message)
+	}
+
+	acl.Status.Ready = false
+	acl.Status.Reason = message
+	recordFailure(acl, eventReasonDestinationResolutionFailed, message, time.Now())
+	setACLReadyCondition(acl, metav1.ConditionFalse, conditionReasonLastAppliedKept, message)
+	setACLDegradedCondition(acl, metav1.ConditionTrue, conditionReasonLastAppliedKept, message)
+
+	err := r.Client.Status().Update(ctx, acl)
+	if err != nil {
+		l.Error(err, "could not update acl status")
+	}
+	return err
+}
+
+// setPausedStatus leaves the policy as it is, the ACL is not requeued until the annotation is removed
+func (r *ACLReconciler) setPausedStatus(ctx context.Context, acl *v1alpha1.ACL) error {
+	l := log.FromContext(ctx)
+	l.V(1).Info("ACL is paused, skipping reconcile", "annotation", pausedAnnotation)
+
+	if meta.IsStatusConditionTrue(acl.Status.Conditions, v1alpha1.ACLConditionPaused) {
+		return nil
+	}
+
+	meta.SetStatusCondition(&acl.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ACLConditionPaused,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: acl.Generation,
+		Reason:             conditionReasonPaused,
+		Message:            "reconcile is paused by the annotation " + pausedAnnotation,
+	})
 
 	err := r.Client.Status().Update(ctx, acl)
 	if err != nil {
@@ -278,7 +995,49 @@ func (r *ACLReconciler) setUnreadyStatus(ctx context.Context, acl *v1alpha1.ACL,
 	return err
 }
 
-func (r *ACLReconciler) podSelectorForSource(source v1alpha1.ACLSpecSource) map[string]string {
+func setACLReadyCondition(acl *v1alpha1.ACL, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&acl.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ACLConditionReady,
+		Status:             status,
+		ObservedGeneration: acl.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+func setACLDegradedCondition(acl *v1alpha1.ACL, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&acl.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ACLConditionDegraded,
+		Status:             status,
+		ObservedGeneration: acl.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+func containsRuleError(ruleErrors []v1alpha1.ACLStatusRuleError, ruleError v1alpha1.ACLStatusRuleError) bool {
+	for _, existing := range ruleErrors {
+		if existing == ruleError {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ACLReconciler) recordEvent(acl *v1alpha1.ACL, eventType, reason, message string) {
+	if r.Recorder == nil {
+		return
+	}
+
+	// destinations are serialized on messages, keep them far away from the etcd limits
+	if len(message) > maxEventMessageLength {
+		message = message[:maxEventMessageLength-3] + "..."
+	}
+
+	r.Recorder.Event(acl, eventType, reason, message)
+}
+
+func (r *ACLReconciler) podSelectorForSource(source v1alpha1.ACLSpecSource) *metav1.LabelSelector {
 	if source.TsuruApp != "" {
 		return r.podSelectorForTsuruApp(source.TsuruApp)
 	}
@@ -291,58 +1050,163 @@ func (r *ACLReconciler) podSelectorForSource(source v1alpha1.ACLSpecSource) map[
 		return r.podSelectorForRpasInstance(source.RpaasInstance)
 	}
 
+	if source.RawPodSelector != nil {
+		return source.RawPodSelector.DeepCopy()
+	}
+
 	return nil
 }
 
-func (r *ACLReconciler) egressRulesForDestination(ctx context.Context, destination v1alpha1.ACLSpecDestination) ([]netv1.NetworkPolicyEgressRule, error) {
+func (r *ACLReconciler) egressRulesForDestination(ctx context.Context, destination v1alpha1.ACLSpecDestination, addressOptions addressOptions) ([]netv1.NetworkPolicyEgressRule, error) {
+	defer observeDestinationDuration(destination, time.Now())
+
+	err := destination.Validate()
+	if err != nil {
+		return nil, err
+	}
+
 	if destination.TsuruApp != "" {
-		return r.egressRulesForTsuruApp(ctx, destination.TsuruApp)
+		return r.egressRulesForTsuruApp(ctx, destination.TsuruApp, destination.TsuruAppProcess, destination.TsuruAppRouters, addressOptions)
 	} else if destination.TsuruAppPool != "" {
-		return r.egressRulesForTsuruAppPool(ctx, destination.TsuruAppPool)
+		return r.egressRulesForTsuruAppPool(ctx, destination.TsuruAppPool, destination.TsuruAppPoolExcept)
 	} else if destination.ExternalDNS != nil {
-		return r.egressRulesForExternalDNS(ctx, destination.ExternalDNS)
+		return r.egressRulesForExternalDNS(ctx, destination.ExternalDNS, addressOptions)
 	} else if destination.ExternalIP != nil {
 		return r.egressRulesForExternalIP(ctx, destination.ExternalIP)
+	} else if len(destination.ExternalEndpoints) > 0 {
+		return r.egressRulesForExternalEndpoints(ctx, destination)
+	} else if destination.ExternalSRV != nil {
+		return r.egressRulesForExternalSRV(ctx, destination.ExternalSRV, addressOptions)
+	} else if destination.KubernetesService != nil {
+		return r.egressRulesForKubernetesService(ctx, destination.KubernetesService, addressOptions)
+	} else if destination.Deny != nil {
+		return r.egressRulesForExternalIP(ctx, destination.Deny.ExternalIP())
 	} else if destination.RpaasInstance != nil {
 		return r.egressRulesForRpaasInstance(ctx, destination.RpaasInstance)
+	} else if destination.TsuruServiceInstance != nil {
+		return r.egressRulesForTsuruServiceInstance(ctx, destination.TsuruServiceInstance, addressOptions)
+	} else if destination.ConfigMap != nil {
+		return r.egressRulesForConfigMap(ctx, destination.ConfigMap, addressOptions)
 	}
 	return nil, nil
 }
 
-func (r *ACLReconciler) egressRulesForTsuruApp(ctx context.Context, tsuruApp string) ([]netv1.NetworkPolicyEgressRule, error) {
+func (r *ACLReconciler) ingressRulesForSource(ctx context.Context, ingress v1alpha1.ACLSpecIngress, namespace string) ([]netv1.NetworkPolicyIngressRule, error) {
+	err := ingress.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if ingress.TsuruApp != "" {
+		return r.ingressRulesForTsuruApp(ctx, ingress.TsuruApp, namespace)
+	} else if ingress.TsuruJob != "" {
+		return r.ingressRulesForPeers([]netv1.NetworkPolicyPeer{
+			{
+				PodSelector: r.podSelectorForTsuruJob(ingress.TsuruJob),
+			},
+		}, nil), nil
+	} else if ingress.TsuruAppPool != "" {
+		// router addresses are not used here, the inbound traffic comes from the pods of pool
+		return r.ingressRulesForPeers(r.tsuruAppPoolPeers(ingress.TsuruAppPool), nil), nil
+	} else if ingress.RpaasInstance != nil {
+		return r.ingressRulesForRpaasInstance(ctx, ingress.RpaasInstance)
+	} else if ingress.ExternalIP != nil {
+		egress, err := r.egressRulesForExternalIP(ctx, ingress.ExternalIP)
+		if err != nil {
+			return nil, err
+		}
+		return r.ingressRulesForEgressRules(egress), nil
+	}
+	return nil, nil
+}
+
+func (r *ACLReconciler) ingressRulesForTsuruApp(ctx context.Context, tsuruApp, namespace string) ([]netv1.NetworkPolicyIngressRule, error) {
 	l := log.FromContext(ctx)
 
-	allErrors := &tsuruErrors.MultiError{}
-	egress := []netv1.NetworkPolicyEgressRule{
+	existingTsuruAppAddress, err := r.ensureTsuruAppAddress(ctx, tsuruApp, nil)
+	if errors.Is(err, ErrAddressNotReady) {
+		return r.ingressRulesForPeers(r.tsuruAppPeers(r.podSelectorForTsuruApp(tsuruApp), "", namespace), nil), err
+	} else if err != nil {
+		l.Error(err, "could not get TsuruAppAddress", "appName", tsuruApp)
+		return nil, err
+	}
+
+	// router IPs are not used here, the inbound traffic comes from the pods of app
+	from := r.tsuruAppPeers(r.podSelectorForTsuruApp(tsuruApp), existingTsuruAppAddress.Status.Pool, namespace)
+	return r.ingressRulesForPeers(from, nil), nil
+}
+
+func (r *ACLReconciler) ingressRulesForRpaasInstance(ctx context.Context, rpaasInstance *v1alpha1.ACLSpecRpaasInstance) ([]netv1.NetworkPolicyIngressRule, error) {
+	l := log.FromContext(ctx)
+
+	from := []netv1.NetworkPolicyPeer{
 		{
-			To: []netv1.NetworkPolicyPeer{
-				{
-					PodSelector: &metav1.LabelSelector{
-						MatchLabels: r.podSelectorForTsuruApp(tsuruApp),
-					},
-				},
-			},
+			PodSelector: r.podSelectorForRpasInstance(rpaasInstance),
+		},
+	}
+
+	existingRpaasInstanceAddress, err := r.ensureRpaasInstanceAddress(ctx, rpaasInstance)
+	if errors.Is(err, ErrAddressNotReady) {
+		return r.ingressRulesForPeers(from, nil), err
+	} else if err != nil {
+		l.Error(err, "could not get RpaasInstanceAddress",
+			"rpaasInstance", rpaasInstance.Instance,
+			"rpaasService", rpaasInstance.ServiceName,
+		)
+		return nil, err
+	}
+
+	if existingRpaasInstanceAddress.Status.Pool != "" {
+		from = append(from, netv1.NetworkPolicyPeer{
+			PodSelector:       r.podSelectorForRpasInstance(rpaasInstance),
+			NamespaceSelector: r.namespaceSelector(existingRpaasInstanceAddress.Spec.ServiceName + "-" + existingRpaasInstanceAddress.Status.Pool),
+		})
+	}
+
+	return r.ingressRulesForPeers(from, nil), nil
+}
+
+func (r *ACLReconciler) ingressRulesForPeers(from []netv1.NetworkPolicyPeer, ports []netv1.NetworkPolicyPort) []netv1.NetworkPolicyIngressRule {
+	return []netv1.NetworkPolicyIngressRule{
+		{
+			From:  from,
+			Ports: ports,
 		},
 	}
+}
+
+// ingressRulesForEgressRules reuses the peers generated for egress as the allowed sources of traffic
+func (r *ACLReconciler) ingressRulesForEgressRules(egress []netv1.NetworkPolicyEgressRule) []netv1.NetworkPolicyIngressRule {
+	result := make([]netv1.NetworkPolicyIngressRule, 0, len(egress))
+	for _, egressRule := range egress {
+		result = append(result, netv1.NetworkPolicyIngressRule{
+			From:  egressRule.To,
+			Ports: egressRule.Ports,
+		})
+	}
+	return result
+}
 
-	existingTsuruAppAddress, err := r.ensureTsuruAppAddress(ctx, tsuruApp)
+// egressRulesForTsuruApp allows the pods of tsuruApp, only the ones of process when it is not empty, and
+// the addresses of its routers, only the ones of routers when it is not empty
+func (r *ACLReconciler) egressRulesForTsuruApp(ctx context.Context, tsuruApp, process string, routers []string, addressOptions addressOptions) ([]netv1.NetworkPolicyEgressRule, error) {
+	l := log.FromContext(ctx)
 
-	if err != nil {
+	allErrors := &tsuruErrors.MultiError{}
+	podSelector := r.podSelectorForTsuruAppProcess(tsuruApp, process)
+
+	existingTsuruAppAddress, err := r.ensureTsuruAppAddress(ctx, tsuruApp, routers)
+	if errors.Is(err, ErrAddressNotReady) {
+		return []netv1.NetworkPolicyEgressRule{
+			{To: r.tsuruAppPeers(podSelector, "", addressOptions.namespace)},
+		}, err
+	} else if err != nil {
 		l.Error(err, "could not get TsuruAppAddress", "appName", tsuruApp)
 		return nil, err
 	}
 
-	if existingTsuruAppAddress.Status.Pool != "" {
-		egress[0].To = append(egress[0].To, netv1.NetworkPolicyPeer{
-			PodSelector: &metav1.LabelSelector{
-				MatchLabels: r.podSelectorForTsuruApp(tsuruApp),
-			},
-			NamespaceSelector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"name": "tsuru-" + existingTsuruAppAddress.Status.Pool,
-				},
-			},
-		})
+	egress := []netv1.NetworkPolicyEgressRule{
+		{To: r.tsuruAppPeers(podSelector, existingTsuruAppAddress.Status.Pool, addressOptions.namespace)},
 	}
 
 	resourceEgress, errors := r.egressRulesForResourceAddressStatus(ctx, existingTsuruAppAddress.Status)
@@ -351,14 +1215,78 @@ func (r *ACLReconciler) egressRulesForTsuruApp(ctx context.Context, tsuruApp str
 		allErrors.Add(err)
 	}
 
+	if r.TsuruAppServiceIPs && existingTsuruAppAddress.Status.Pool != "" {
+		serviceEgress, err := r.egressRulesForTsuruAppServices(ctx, podSelector, existingTsuruAppAddress.Status.Pool)
+		if err != nil {
+			l.Error(err, "could not get services of app", "appName", tsuruApp)
+			allErrors.Add(err)
+		}
+		egress = append(egress, serviceEgress...)
+	}
+
 	return egress, allErrors.ToError()
 }
 
+// egressRulesForTsuruAppServices allows the cluster IPs of the services on the namespace of pool with
+// the labels of podSelector, like the services of each process of an app, headless services have none
+func (r *ACLReconciler) egressRulesForTsuruAppServices(ctx context.Context, podSelector *metav1.LabelSelector, pool string) ([]netv1.NetworkPolicyEgressRule, error) {
+	selector, err := metav1.LabelSelectorAsSelector(podSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := r.getServiceCache().GetByLabels(ctx, tsuruAppNamespace(pool), selector)
+	if err != nil {
+		return nil, err
+	}
+
+	egress := []netv1.NetworkPolicyEgressRule{}
+	for _, svc := range services {
+		for _, clusterIP := range serviceClusterIPs(svc) {
+			ipEgress, err := r.egressRulesForExternalIP(ctx, &v1alpha1.ACLSpecExternalIP{
+				IP: clusterIP,
+			})
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not generate egress rule for cluster IP of service %s/%s", svc.Namespace, svc.Name)
+			}
+			egress = append(egress, ipEgress...)
+		}
+	}
+	return egress, nil
+}
+
+// tsuruAppNamespace is the namespace of the apps of pool
+func tsuruAppNamespace(pool string) string {
+	return "tsuru-" + pool
+}
+
+// tsuruAppPeers selects the pods of an app, its pool is returned by Tsuru API and kept on the status of
+// TsuruAppAddress. While the pool is unknown only the pods on the namespace of policy are selected, once
+// it is known the peer without namespace selector is kept only when the app runs on the namespace of policy,
+// otherwise it would allow any pod of the namespace of policy with the labels of app
+func (r *ACLReconciler) tsuruAppPeers(podSelector *metav1.LabelSelector, pool, policyNamespace string) []netv1.NetworkPolicyPeer {
+	if pool == "" {
+		return []netv1.NetworkPolicyPeer{{PodSelector: podSelector}}
+	}
+
+	namespace := tsuruAppNamespace(pool)
+	peers := []netv1.NetworkPolicyPeer{}
+	if namespace == policyNamespace {
+		peers = append(peers, netv1.NetworkPolicyPeer{PodSelector: podSelector.DeepCopy()})
+	}
+
+	return append(peers, netv1.NetworkPolicyPeer{
+		PodSelector:       podSelector.DeepCopy(),
+		NamespaceSelector: r.namespaceSelector(namespace),
+	})
+}
+
 func (r *ACLReconciler) egressRulesForResourceAddressStatus(ctx context.Context, status v1alpha1.ResourceAddressStatus) ([]netv1.NetworkPolicyEgressRule, []error) {
 	errs := []error{}
 	egresses := []netv1.NetworkPolicyEgressRule{}
 
-	for _, routerIP := range status.IPs {
+	// internal addresses are only filled for TsuruAppAddress objects when enabled
+	for _, routerIP := range append(append([]string{}, status.IPs...), status.InternalIPs...) {
 		addrEgresses, err := r.egressRulesForExternalIP(ctx, &v1alpha1.ACLSpecExternalIP{
 			IP: routerIP,
 		})
@@ -373,127 +1301,387 @@ func (r *ACLReconciler) egressRulesForResourceAddressStatus(ctx context.Context,
 	return egresses, errs
 }
 
-func (r *ACLReconciler) egressRulesForTsuruAppPool(ctx context.Context, tsuruAppPool string) ([]netv1.NetworkPolicyEgressRule, error) {
+// egressRulesForTsuruAppPool allows the pods of pool and the router addresses of its apps, except
+// the apps of exceptApps, the apps of pool are listed on Tsuru API and cached for poolAppsCacheTTL
+func (r *ACLReconciler) egressRulesForTsuruAppPool(ctx context.Context, tsuruAppPool string, exceptApps []string) ([]netv1.NetworkPolicyEgressRule, error) {
+	l := log.FromContext(ctx)
+
 	egress := []netv1.NetworkPolicyEgressRule{
 		{
-			To: []netv1.NetworkPolicyPeer{
-				{
-					PodSelector: &metav1.LabelSelector{
-						MatchLabels: map[string]string{
-							"tsuru.io/app-pool": tsuruAppPool,
-						},
-					},
-				},
-				{
-					PodSelector: &metav1.LabelSelector{
-						MatchLabels: map[string]string{
-							"tsuru.io/app-pool": tsuruAppPool,
-						},
-					},
-					NamespaceSelector: &metav1.LabelSelector{
-						MatchLabels: map[string]string{
-							"name": "tsuru-" + tsuruAppPool,
-						},
-					},
-				},
-			},
+			To: r.tsuruAppPoolPeers(tsuruAppPool, exceptApps...),
 		},
 	}
 
-	return egress, nil
+	var apps []string
+	err := callTsuruAPI(ctx, r.TsuruAPITimeout, "apps of pool "+tsuruAppPool, func(ctx context.Context) (err error) {
+		apps, err = r.poolApps.Get(ctx, r.TsuruAPI, tsuruAppPool)
+		return err
+	})
+	if err != nil {
+		l.Error(err, "could not list apps of pool", "pool", tsuruAppPool)
+		if isTransientError(err) {
+			return nil, &resolutionError{err: err}
+		}
+		return nil, err
+	}
+
+	allErrors := &tsuruErrors.MultiError{}
+	var pendingErr error
+	for _, app := range apps {
+		if containsString(exceptApps, app) {
+			continue
+		}
+
+		existingTsuruAppAddress, err := r.ensureTsuruAppAddress(ctx, app, nil)
+		if errors.Is(err, ErrAddressNotReady) {
+			pendingErr = err
+			continue
+		} else if err != nil {
+			l.Error(err, "could not get TsuruAppAddress", "appName", app)
+			return nil, err
+		}
+
+		resourceEgress, errors := r.egressRulesForResourceAddressStatus(ctx, existingTsuruAppAddress.Status)
+		egress = append(egress, resourceEgress...)
+		for _, err := range errors {
+			allErrors.Add(err)
+		}
+	}
+
+	if err = allErrors.ToError(); err != nil {
+		return egress, err
+	}
+
+	return egress, pendingErr
+}
+
+func (r *ACLReconciler) tsuruAppPoolPeers(tsuruAppPool string, exceptApps ...string) []netv1.NetworkPolicyPeer {
+	return []netv1.NetworkPolicyPeer{
+		{
+			PodSelector: r.podSelectorForTsuruAppPool(tsuruAppPool, exceptApps...),
+		},
+		{
+			PodSelector:       r.podSelectorForTsuruAppPool(tsuruAppPool, exceptApps...),
+			NamespaceSelector: r.namespaceSelector(tsuruAppNamespace(tsuruAppPool)),
+		},
+	}
 }
 
-func (r *ACLReconciler) egressRulesForExternalDNS(ctx context.Context, externalDNS *v1alpha1.ACLSpecExternalDNS) ([]netv1.NetworkPolicyEgressRule, error) {
+func (r *ACLReconciler) egressRulesForExternalDNS(ctx context.Context, externalDNS *v1alpha1.ACLSpecExternalDNS, addressOptions addressOptions) ([]netv1.NetworkPolicyEgressRule, error) {
 	l := log.FromContext(ctx)
 
 	if isWildCard(externalDNS.Name) {
-		return nil, nil
+		return nil, &unsupportedDestinationError{
+			message: fmt.Sprintf("wildcard destination %q is ignored, NetworkPolicy can not express hostnames", externalDNS.Name),
+		}
 	}
 
-	existingDNSEntry, err := r.ensureDNSEntry(ctx, externalDNS.Name)
+	// ACLs created before the validation of names are reported instead of resolving the short name
+	if v1alpha1.IsShortHostname(externalDNS.Name) {
+		return nil, v1alpha1.ShortHostnameError(externalDNS.Name)
+	}
 
-	if err != nil {
+	existingDNSEntry, err := r.ensureDNSEntry(ctx, externalDNS, addressOptions.dnsEntryNamespace)
+	if errors.Is(err, ErrAddressNotReady) {
+		return nil, err
+	} else if err != nil {
 		l.Error(err, "could not get ACLDNSEntry", "destination", externalDNS.Name)
 		return nil, err
 	}
 
 	if !existingDNSEntry.Status.Ready {
-		l.Info("DNSEntry is not ready yet")
-		return nil, nil
+		// the failure of resolution is reported as the error of destination, stale rules are used
+		return nil, &resolutionError{err: errors.New(existingDNSEntry.Status.Reason)}
 	}
 
-	to := []netv1.NetworkPolicyPeer{}
+	resolved := make([]string, 0, len(existingDNSEntry.Status.IPs))
 	for _, ip := range existingDNSEntry.Status.IPs {
-		cidr := ipToCIDR(ip.Address)
-		if cidr == "" {
+		resolved = append(resolved, ip.Address)
+	}
+
+	// additionalIPs are set by hand, only the answers of DNS are filtered
+	addresses, rejected, err := filterAddresses(resolved, addressOptions.addressFilter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid addressFilter: %w", err)
+	}
+	if len(rejected) > 0 {
+		l.Info("resolved addresses rejected by addressFilter", "destination", externalDNS.Name, "addresses", rejected)
+		addressOptions.rejectAddresses(externalDNS.Name, rejected)
+	}
+	if len(addresses) == 0 && len(rejected) > 0 && len(existingDNSEntry.Spec.AdditionalIPs) == 0 {
+		// a rule without peers would allow every destination
+		return nil, fmt.Errorf("every address resolved for %s is rejected by addressFilter: %s", externalDNS.Name, strings.Join(rejected, ", "))
+	}
+	addresses = append(addresses, existingDNSEntry.Spec.AdditionalIPs...)
+
+	to := []netv1.NetworkPolicyPeer{}
+	seen := map[string]bool{}
+	aggregated := []string{}
+	for _, address := range addresses {
+		cidr, family := ipToCIDR(address)
+		if cidr == "" || seen[cidr] || !allowsIPFamily(addressOptions.ipFamilies, family) {
 			continue
 		}
+		seen[cidr] = true
+
+		if addressOptions.aggregates() && !addressOptions.disableServiceTranslation {
+			// addresses of services are kept as they are, so their pods are allowed by fillPodSelectorByCIDR
+			services, err := r.getServiceCache().GetAllByIP(ctx, strings.Split(cidr, "/")[0])
+			if err != nil {
+				return nil, err
+			}
+			if len(services) == 0 {
+				aggregated = append(aggregated, address)
+				continue
+			}
+		}
 
 		to = append(to, netv1.NetworkPolicyPeer{IPBlock: &netv1.IPBlock{
 			CIDR: cidr,
 		}})
 	}
 
-	for _, ip := range existingDNSEntry.Spec.AdditionalIPs {
-		cidr := ipToCIDR(ip)
-		if cidr == "" {
-			continue
-		}
-
+	for _, cidr := range aggregateCIDRs(aggregated, addressOptions.cidrAggregation) {
 		to = append(to, netv1.NetworkPolicyPeer{IPBlock: &netv1.IPBlock{
 			CIDR: cidr,
 		}})
 	}
 
+	if len(to) == 0 {
+		// a rule without peers would allow every destination, like a host with only AAAA records
+		// on an ACL of ipFamilies IPv4
+		return nil, &unsupportedDestinationError{
+			message: fmt.Sprintf("%s has no address of the ipFamilies %v of ACL, it is ignored", externalDNS.Name, addressOptions.ipFamilies),
+		}
+	}
+
+	ports, err := r.ports(externalDNS.Ports)
+	if err != nil {
+		return nil, err
+	}
+
 	egress := []netv1.NetworkPolicyEgressRule{
 		{
 			To:    to,
-			Ports: r.ports(externalDNS.Ports),
+			Ports: ports,
 		},
 	}
 
 	return egress, nil
 }
 
-func ipToCIDR(address string) string {
-	if strings.Contains(address, ":") {
-		return address + "/128"
+// egressRulesForExternalSRV resolves the targets of the SRV record on each reconcile, a rule is
+// generated for each port of the answer with the addresses of the targets on that port
+func (r *ACLReconciler) egressRulesForExternalSRV(ctx context.Context, externalSRV *v1alpha1.ACLSpecExternalSRV, addressOptions addressOptions) ([]netv1.NetworkPolicyEgressRule, error) {
+	protocol, err := externalSRV.Protocol()
+	if err != nil {
+		return nil, err
+	}
+
+	srvs, err := lookupSRV(ctx, r.Resolver, externalSRV.Name)
+	if err != nil {
+		return nil, &resolutionError{err: errors.Wrapf(err, "could not lookup SRV record %q", externalSRV.Name)}
+	}
+
+	cidrsByPort := map[uint16]map[string]bool{}
+	for _, srv := range srvs {
+		target := strings.TrimSuffix(srv.Target, ".")
+		// a target "." means that the service is not available on the domain
+		if target == "" || srv.Port == 0 {
+			continue
+		}
+
+		ipAddrs, _, err := lookupIPAddrTTL(ctx, r.Resolver, target)
+		if err != nil {
+			return nil, &resolutionError{err: errors.Wrapf(err, "could not resolve target %q of SRV record %q", target, externalSRV.Name)}
+		}
+
+		for _, ipAddr := range ipAddrs {
+			cidr, family := ipToCIDR(ipAddr.IP.String())
+			if cidr == "" || !allowsIPFamily(addressOptions.ipFamilies, family) {
+				continue
+			}
+
+			if cidrsByPort[srv.Port] == nil {
+				cidrsByPort[srv.Port] = map[string]bool{}
+			}
+			cidrsByPort[srv.Port][cidr] = true
+		}
+	}
+
+	if len(cidrsByPort) == 0 {
+		return nil, fmt.Errorf("SRV record %q has no addresses", externalSRV.Name)
+	}
+
+	portNumbers := make([]int, 0, len(cidrsByPort))
+	for port := range cidrsByPort {
+		portNumbers = append(portNumbers, int(port))
+	}
+	sort.Ints(portNumbers)
+
+	egress := []netv1.NetworkPolicyEgressRule{}
+	for _, port := range portNumbers {
+		cidrs := make([]string, 0, len(cidrsByPort[uint16(port)]))
+		for cidr := range cidrsByPort[uint16(port)] {
+			cidrs = append(cidrs, cidr)
+		}
+		sort.Strings(cidrs)
+
+		to := make([]netv1.NetworkPolicyPeer, 0, len(cidrs))
+		for _, cidr := range cidrs {
+			to = append(to, netv1.NetworkPolicyPeer{IPBlock: &netv1.IPBlock{
+				CIDR: cidr,
+			}})
+		}
+
+		ports, err := r.ports([]v1alpha1.ProtoPort{{Protocol: string(protocol), Number: uint16(port)}})
+		if err != nil {
+			return nil, err
+		}
+
+		egress = append(egress, netv1.NetworkPolicyEgressRule{
+			To:    to,
+			Ports: ports,
+		})
+	}
+
+	return egress, nil
+}
+
+// ipToCIDR returns the canonical CIDR of a single address as v1alpha1.ParseCIDR does,
+// the CIDR is empty for CIDRs and addresses that are neither IPv4 nor IPv6
+func ipToCIDR(address string) (string, v1alpha1.IPFamily) {
+	if strings.Contains(address, "/") {
+		return "", ""
 	}
-	if strings.Contains(address, ".") {
-		return address + "/32"
+
+	cidr, network, err := v1alpha1.ParseCIDR(address)
+	if err != nil {
+		return "", ""
 	}
 
-	return ""
+	return cidr, v1alpha1.CIDRFamily(network)
 }
 
-func (r *ACLReconciler) egressRulesForExternalIP(ctx context.Context, externalIP *v1alpha1.ACLSpecExternalIP) ([]netv1.NetworkPolicyEgressRule, error) {
-	var cidr string
+// addressOptions restricts and summarizes the addresses resolved for the destinations of an ACL
+type addressOptions struct {
+	ipFamilies      []v1alpha1.IPFamily
+	cidrAggregation *v1alpha1.ACLSpecCIDRAggregation
+	addressFilter   *v1alpha1.ACLSpecAddressFilter
+
+	// rejectedAddresses collects the addresses dropped by addressFilter for each externalDNS name
+	rejectedAddresses map[string][]string
+
+	// dnsEntryNamespace is the namespace of the ACLDNSEntry objects, empty for shared entries
+	dnsEntryNamespace string
+
+	// namespace is the namespace of ACL, services of kubernetesService destinations and ConfigMaps of
+	// configMap destinations default to it
+	namespace string
+
+	// disableServiceTranslation aggregates the addresses of services like any other, they are not
+	// translated into pod selectors
+	disableServiceTranslation bool
+}
+
+func (o addressOptions) aggregates() bool {
+	return o.cidrAggregation != nil && o.cidrAggregation.Enabled
+}
 
-	cidr = externalIP.IP
-	if !strings.Contains(cidr, "/") {
-		if strings.Contains(cidr, ":") {
-			cidr = cidr + "/128"
-		} else if strings.Contains(cidr, ".") {
-			cidr = cidr + "/32"
+func (o addressOptions) rejectAddresses(name string, addresses []string) {
+	if o.rejectedAddresses != nil {
+		o.rejectedAddresses[name] = addresses
+	}
+}
+
+func (r *ACLReconciler) addressOptions(acl *v1alpha1.ACL) addressOptions {
+	options := addressOptions{
+		ipFamilies:        r.IPFamilies,
+		cidrAggregation:   r.CIDRAggregation,
+		addressFilter:     r.AddressFilter,
+		rejectedAddresses: map[string][]string{},
+		dnsEntryNamespace: r.dnsEntryNamespace(acl),
+		namespace:         acl.Namespace,
+
+		disableServiceTranslation: acl.Spec.DisableServiceTranslation,
+	}
+
+	if len(acl.Spec.IPFamilies) > 0 {
+		options.ipFamilies = acl.Spec.IPFamilies
+	}
+
+	if acl.Spec.CIDRAggregation != nil {
+		options.cidrAggregation = acl.Spec.CIDRAggregation
+	}
+
+	if acl.Spec.AddressFilter != nil {
+		options.addressFilter = acl.Spec.AddressFilter
+	}
+
+	return options
+}
+
+func allowsIPFamily(ipFamilies []v1alpha1.IPFamily, family v1alpha1.IPFamily) bool {
+	if len(ipFamilies) == 0 {
+		return true
+	}
+
+	for _, allowed := range ipFamilies {
+		if allowed == family {
+			return true
 		}
 	}
 
+	return false
+}
+
+func (r *ACLReconciler) egressRulesForExternalIP(ctx context.Context, externalIP *v1alpha1.ACLSpecExternalIP) ([]netv1.NetworkPolicyEgressRule, error) {
+	cidr, except, err := externalIP.CIDRs()
+	if err != nil {
+		return nil, err
+	}
+
+	ports, err := r.ports(externalIP.Ports)
+	if err != nil {
+		return nil, err
+	}
+
 	egress := []netv1.NetworkPolicyEgressRule{
 		{
 			To: []netv1.NetworkPolicyPeer{
 				{
 					IPBlock: &netv1.IPBlock{
-						CIDR: cidr,
+						CIDR:   cidr,
+						Except: except,
 					},
 				},
 			},
-			Ports: r.ports(externalIP.Ports),
+			Ports: ports,
 		},
 	}
 
 	return egress, nil
 }
 
+// egressRulesForExternalEndpoints allows each IP of externalEndpoints on its ports, like the externalIP destinations they stand for
+func (r *ACLReconciler) egressRulesForExternalEndpoints(ctx context.Context, destination v1alpha1.ACLSpecDestination) ([]netv1.NetworkPolicyEgressRule, error) {
+	externalIPs, err := destination.ExternalEndpointsIPs()
+	if err != nil {
+		return nil, err
+	}
+
+	egress := []netv1.NetworkPolicyEgressRule{}
+	for i := range externalIPs {
+		rules, err := r.egressRulesForExternalIP(ctx, &externalIPs[i])
+		if err != nil {
+			return nil, err
+		}
+		egress = append(egress, rules...)
+	}
+
+	return egress, nil
+}
+
 func (r *ACLReconciler) egressRulesForRpaasInstance(ctx context.Context, rpaasInstance *v1alpha1.ACLSpecRpaasInstance) ([]netv1.NetworkPolicyEgressRule, error) {
 	l := log.FromContext(ctx)
 
@@ -502,17 +1690,16 @@ func (r *ACLReconciler) egressRulesForRpaasInstance(ctx context.Context, rpaasIn
 		{
 			To: []netv1.NetworkPolicyPeer{
 				{
-					PodSelector: &metav1.LabelSelector{
-						MatchLabels: r.podSelectorForRpasInstance(rpaasInstance),
-					},
+					PodSelector: r.podSelectorForRpasInstance(rpaasInstance),
 				},
 			},
 		},
 	}
 
 	existingRpaasInstanceAddress, err := r.ensureRpaasInstanceAddress(ctx, rpaasInstance)
-
-	if err != nil {
+	if errors.Is(err, ErrAddressNotReady) {
+		return egress, err
+	} else if err != nil {
 		l.Error(err, "could not get RpaasInstanceAddress",
 			"rpaasInstance", rpaasInstance.Instance,
 			"rpaasService", rpaasInstance.ServiceName,
@@ -522,14 +1709,8 @@ func (r *ACLReconciler) egressRulesForRpaasInstance(ctx context.Context, rpaasIn
 
 	if existingRpaasInstanceAddress.Status.Pool != "" {
 		egress[0].To = append(egress[0].To, netv1.NetworkPolicyPeer{
-			PodSelector: &metav1.LabelSelector{
-				MatchLabels: r.podSelectorForRpasInstance(rpaasInstance),
-			},
-			NamespaceSelector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"name": existingRpaasInstanceAddress.Spec.ServiceName + "-" + existingRpaasInstanceAddress.Status.Pool,
-				},
-			},
+			PodSelector:       r.podSelectorForRpasInstance(rpaasInstance),
+			NamespaceSelector: r.namespaceSelector(existingRpaasInstanceAddress.Spec.ServiceName + "-" + existingRpaasInstanceAddress.Status.Pool),
 		})
 	}
 	resourceEgress, errors := r.egressRulesForResourceAddressStatus(ctx, existingRpaasInstanceAddress.Status)
@@ -541,12 +1722,73 @@ func (r *ACLReconciler) egressRulesForRpaasInstance(ctx context.Context, rpaasIn
 	return egress, allErrors.ToError()
 }
 
-func (r *ACLReconciler) ensureDNSEntry(ctx context.Context, host string) (*v1alpha1.ACLDNSEntry, error) {
+// egressRulesForTsuruServiceInstance allows each endpoint of instance, hostnames are resolved by their
+// ACLDNSEntry like externalDNS destinations and addresses are allowed like externalIP destinations
+func (r *ACLReconciler) egressRulesForTsuruServiceInstance(ctx context.Context, instance *v1alpha1.ACLSpecTsuruServiceInstance, addressOptions addressOptions) ([]netv1.NetworkPolicyEgressRule, error) {
+	l := log.FromContext(ctx)
+
+	existingInstanceAddress, err := r.ensureTsuruServiceInstanceAddress(ctx, instance)
+	if errors.Is(err, ErrAddressNotReady) {
+		return nil, err
+	} else if err != nil {
+		l.Error(err, "could not get TsuruServiceInstanceAddress",
+			"instance", instance.Instance,
+			"serviceName", instance.ServiceName,
+		)
+		return nil, err
+	}
+
+	if !existingInstanceAddress.Status.Ready {
+		return nil, &resolutionError{err: errors.New(existingInstanceAddress.Status.Reason)}
+	}
+
+	if len(existingInstanceAddress.Status.Endpoints) == 0 {
+		return nil, &unsupportedDestinationError{
+			message: fmt.Sprintf("tsuruServiceInstance %s/%s has no endpoints on its custom info, it is ignored", instance.ServiceName, instance.Instance),
+		}
+	}
+
+	allErrors := &tsuruErrors.MultiError{}
+	var pendingErr error
+	egress := []netv1.NetworkPolicyEgressRule{}
+	for _, endpoint := range existingInstanceAddress.Status.Endpoints {
+		var ports v1alpha1.ACLSpecProtoPorts
+		if endpoint.Port != 0 {
+			ports = v1alpha1.ACLSpecProtoPorts{{Protocol: string(corev1.ProtocolTCP), Number: endpoint.Port}}
+		}
+
+		var endpointEgress []netv1.NetworkPolicyEgressRule
+		if isIPRange(endpoint.Host) {
+			endpointEgress, err = r.egressRulesForExternalIP(ctx, &v1alpha1.ACLSpecExternalIP{IP: endpoint.Host, Ports: ports})
+		} else {
+			endpointEgress, err = r.egressRulesForExternalDNS(ctx, &v1alpha1.ACLSpecExternalDNS{Name: endpoint.Host, Ports: ports}, addressOptions)
+		}
+
+		if errors.Is(err, ErrAddressNotReady) {
+			// the other endpoints are still allowed while the entry is resolved
+			pendingErr = err
+		} else if err != nil {
+			allErrors.Add(errors.Wrapf(err, "could not generate egress rule for endpoint %q", endpoint.Host))
+		}
+		egress = append(egress, endpointEgress...)
+	}
+
+	if pendingErr != nil {
+		return egress, pendingErr
+	}
+	return egress, allErrors.ToError()
+}
+
+// ensureDNSEntry returns the ACLDNSEntry of externalDNS, creating it when missing, the error wraps
+// ErrAddressNotReady while the entry was not resolved by ACLDNSEntryReconciler yet
+func (r *ACLReconciler) ensureDNSEntry(ctx context.Context, externalDNS *v1alpha1.ACLSpecExternalDNS, namespace string) (*v1alpha1.ACLDNSEntry, error) {
 	l := log.FromContext(ctx)
 
 	existingDNSEntry := &v1alpha1.ACLDNSEntry{}
 
-	resourceName := validResourceName(host)
+	host := externalDNSHost(externalDNS)
+	nameservers := externalDNSNameservers(externalDNS)
+	resourceName := dnsEntryName(host, nameservers, namespace)
 	err := r.Client.Get(ctx, types.NamespacedName{
 		Name: resourceName,
 	}, existingDNSEntry)
@@ -557,7 +1799,9 @@ func (r *ACLReconciler) ensureDNSEntry(ctx context.Context, host string) (*v1alp
 				Name: resourceName,
 			},
 			Spec: v1alpha1.ACLDNSEntrySpec{
-				Host: host,
+				Host:        host,
+				Nameservers: nameservers,
+				Namespace:   namespace,
 			},
 		}
 
@@ -567,33 +1811,28 @@ func (r *ACLReconciler) ensureDNSEntry(ctx context.Context, host string) (*v1alp
 			return nil, err
 		}
 
-		subReconciler := &ACLDNSEntryReconciler{
-			Client:   r.Client,
-			Scheme:   r.Scheme,
-			Resolver: r.Resolver,
-		}
-
-		err = subReconciler.FillStatus(ctx, dnsEntry)
-
-		if err != nil {
-			l.Error(err, "could not fill status for DNSEntry", "dnsEntryName", resourceName)
-			return nil, err
-		}
-
-		return dnsEntry, nil
+		// the status is filled by ACLDNSEntryReconciler, the ACL is reconciled again when it changes
+		return dnsEntry, &pendingAddressError{kind: "ACLDNSEntry", name: dnsEntry.Name}
 	} else if err != nil {
 		l.Error(err, "could not get ACLDNSEntry", "dnsEntryName", resourceName)
 		return nil, err
 	}
 
+	if !existingDNSEntry.Status.Ready && existingDNSEntry.Status.Reason == "" {
+		return existingDNSEntry, &pendingAddressError{kind: "ACLDNSEntry", name: existingDNSEntry.Name}
+	}
+
 	return existingDNSEntry, nil
 }
 
-func (r *ACLReconciler) ensureTsuruAppAddress(ctx context.Context, appName string) (*v1alpha1.TsuruAppAddress, error) {
+// ensureTsuruAppAddress returns the TsuruAppAddress of app with the addresses of routers, every router when
+// empty, creating it when missing, the error wraps ErrAddressNotReady while the status was not filled by
+// TsuruAppAddressReconciler yet
+func (r *ACLReconciler) ensureTsuruAppAddress(ctx context.Context, appName string, routers []string) (*v1alpha1.TsuruAppAddress, error) {
 	l := log.FromContext(ctx)
 
 	existingTsuruAppAddress := &v1alpha1.TsuruAppAddress{}
-	resourceName := validResourceName(appName)
+	resourceName := tsuruAppAddressName(appName, routers)
 	err := r.Client.Get(ctx, types.NamespacedName{
 		Name: resourceName,
 	}, existingTsuruAppAddress)
@@ -604,7 +1843,8 @@ func (r *ACLReconciler) ensureTsuruAppAddress(ctx context.Context, appName strin
 				Name: resourceName,
 			},
 			Spec: v1alpha1.TsuruAppAddressSpec{
-				Name: resourceName,
+				Name:    validResourceName(appName),
+				Routers: sortedRouters(routers),
 			},
 		}
 
@@ -614,28 +1854,41 @@ func (r *ACLReconciler) ensureTsuruAppAddress(ctx context.Context, appName strin
 			return nil, err
 		}
 
-		subReconciler := &TsuruAppAddressReconciler{
-			Client:   r.Client,
-			Scheme:   r.Scheme,
-			Resolver: r.Resolver,
-			TsuruAPI: r.TsuruAPI,
-		}
-
-		err = subReconciler.FillStatus(ctx, tsuruAppAddress)
-		if err != nil {
-			l.Error(err, "could not fill status of TsuruAppAddress", "tsuruAppName", resourceName)
-			return nil, err
-		}
-
-		return tsuruAppAddress, nil
+		// the status is filled by TsuruAppAddressReconciler, the ACL is reconciled again when it changes
+		return tsuruAppAddress, &pendingAddressError{kind: "TsuruAppAddress", name: tsuruAppAddress.Name}
 	} else if err != nil {
 		l.Error(err, "could not get TsuruAppAddress", "tsuruAppName", resourceName)
 		return nil, err
 	}
 
-	return existingTsuruAppAddress, nil
+	if isResourceAddressPending(existingTsuruAppAddress.Status) {
+		return existingTsuruAppAddress, &pendingAddressError{kind: "TsuruAppAddress", name: existingTsuruAppAddress.Name}
+	}
+
+	return existingTsuruAppAddress, nil
+}
+
+// tsuruAppAddressName keeps the TsuruAppAddress of an app restricted to some routers apart from the
+// address of every router of app, which is shared by tsuruAppPool destinations as well
+func tsuruAppAddressName(appName string, routers []string) string {
+	name := appName
+	if len(routers) > 0 {
+		name += "@" + strings.Join(sortedRouters(routers), ",")
+	}
+	return validResourceName(name)
+}
+
+func sortedRouters(routers []string) []string {
+	if len(routers) == 0 {
+		return nil
+	}
+	sorted := append([]string{}, routers...)
+	sort.Strings(sorted)
+	return sorted
 }
 
+// ensureRpaasInstanceAddress returns the RpaasInstanceAddress of instance, creating it when missing, the error
+// wraps ErrAddressNotReady while the status was not filled by RpaasInstanceAddressReconciler yet
 func (r *ACLReconciler) ensureRpaasInstanceAddress(ctx context.Context, rpaasInstance *v1alpha1.ACLSpecRpaasInstance) (*v1alpha1.RpaasInstanceAddress, error) {
 	l := log.FromContext(ctx)
 
@@ -662,72 +1915,186 @@ func (r *ACLReconciler) ensureRpaasInstanceAddress(ctx context.Context, rpaasIns
 			return nil, err
 		}
 
-		subReconciler := &RpaasInstanceAddressReconciler{
-			Client:   r.Client,
-			Scheme:   r.Scheme,
-			Resolver: r.Resolver,
-			TsuruAPI: r.TsuruAPI,
-		}
+		// the status is filled by RpaasInstanceAddressReconciler, the ACL is reconciled again when it changes
+		return rpaasInstanceAddress, &pendingAddressError{kind: "RpaasInstanceAddress", name: rpaasInstanceAddress.Name}
+	} else if err != nil {
+		l.Error(err, "could not get RpaasInstanceAddress", "name", resourceName)
+		return nil, err
+	}
+
+	if isResourceAddressPending(existingRpaasInstanceAddress.Status) {
+		return existingRpaasInstanceAddress, &pendingAddressError{kind: "RpaasInstanceAddress", name: existingRpaasInstanceAddress.Name}
+	}
+
+	return existingRpaasInstanceAddress, nil
+}
+
+// ensureTsuruServiceInstanceAddress returns the TsuruServiceInstanceAddress of instance, creating it when missing,
+// the error wraps ErrAddressNotReady while the status was not filled by TsuruServiceInstanceAddressReconciler yet
+func (r *ACLReconciler) ensureTsuruServiceInstanceAddress(ctx context.Context, instance *v1alpha1.ACLSpecTsuruServiceInstance) (*v1alpha1.TsuruServiceInstanceAddress, error) {
+	l := log.FromContext(ctx)
+
+	existingInstanceAddress := &v1alpha1.TsuruServiceInstanceAddress{}
+	resourceName := tsuruServiceInstanceAddressName(instance)
+	err := r.Client.Get(ctx, types.NamespacedName{
+		Name: resourceName,
+	}, existingInstanceAddress)
 
-		err = subReconciler.FillStatus(ctx, rpaasInstanceAddress)
+	if k8sErrors.IsNotFound(err) {
+		instanceAddress := &v1alpha1.TsuruServiceInstanceAddress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: resourceName,
+			},
+			Spec: v1alpha1.TsuruServiceInstanceAddressSpec{
+				ServiceName: instance.ServiceName,
+				Instance:    instance.Instance,
+			},
+		}
 
+		err = r.Client.Create(ctx, instanceAddress)
 		if err != nil {
-			l.Error(err, "could not fill status of RpaasInstanceAddress", "name", resourceName)
+			l.Error(err, "could not create TsuruServiceInstanceAddress object")
 			return nil, err
 		}
-		return rpaasInstanceAddress, err
+
+		// the status is filled by TsuruServiceInstanceAddressReconciler, the ACL is reconciled again when it changes
+		return instanceAddress, &pendingAddressError{kind: "TsuruServiceInstanceAddress", name: instanceAddress.Name}
 	} else if err != nil {
-		l.Error(err, "could not get RpaasInstanceAddress", "name", resourceName)
+		l.Error(err, "could not get TsuruServiceInstanceAddress", "name", resourceName)
 		return nil, err
 	}
 
-	return existingRpaasInstanceAddress, nil
+	if !existingInstanceAddress.Status.Ready && existingInstanceAddress.Status.Reason == "" {
+		return existingInstanceAddress, &pendingAddressError{kind: "TsuruServiceInstanceAddress", name: existingInstanceAddress.Name}
+	}
+
+	return existingInstanceAddress, nil
+}
+
+func tsuruServiceInstanceAddressName(instance *v1alpha1.ACLSpecTsuruServiceInstance) string {
+	return validResourceName(instance.ServiceName + "-" + instance.Instance)
 }
 
-func (r *ACLReconciler) ports(p []v1alpha1.ProtoPort) []netv1.NetworkPolicyPort {
+func (r *ACLReconciler) ports(p []v1alpha1.ProtoPort) ([]netv1.NetworkPolicyPort, error) {
 	var result []netv1.NetworkPolicyPort
 	for _, port := range p {
+		err := port.Validate()
+		if err != nil {
+			return nil, err
+		}
+
 		var protocol *corev1.Protocol
 		if port.Protocol != "" {
-			p := corev1.Protocol(strings.ToUpper(port.Protocol))
+			p, _ := v1alpha1.ParseProtocol(port.Protocol)
 			protocol = &p
 		}
 
 		portNumber := intstr.FromInt(int(port.Number))
-		result = append(result, netv1.NetworkPolicyPort{
+		if port.Name != "" {
+			portNumber = intstr.FromString(port.Name)
+		}
+		networkPolicyPort := netv1.NetworkPolicyPort{
 			Protocol: protocol,
 			Port:     &portNumber,
-		})
+		}
+
+		if port.EndPort > port.Number {
+			endPort := int32(port.EndPort)
+			networkPolicyPort.EndPort = &endPort
+		}
+
+		result = append(result, networkPolicyPort)
 	}
-	return result
+	return result, nil
+}
+
+// namespaceSelector scopes a peer to a single namespace, so calico does not need to evaluate pods of all namespaces
+func (r *ACLReconciler) namespaceSelector(namespace string) *metav1.LabelSelector {
+	labelKey := r.NamespaceLabelKey
+	if labelKey == "" {
+		labelKey = DefaultNamespaceLabelKey
+	}
+
+	return &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			labelKey: namespace,
+		},
+	}
+}
+
+func (r *ACLReconciler) podSelectorForTsuruApp(tsuruApp string) *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			r.LabelScheme.WithDefaults().AppName: tsuruApp,
+		},
+	}
+}
+
+func (r *ACLReconciler) podSelectorForTsuruAppProcess(tsuruApp, process string) *metav1.LabelSelector {
+	selector := r.podSelectorForTsuruApp(tsuruApp)
+	if process != "" {
+		selector.MatchLabels[r.LabelScheme.WithDefaults().AppProcess] = process
+	}
+	return selector
 }
 
-func (r *ACLReconciler) podSelectorForTsuruApp(tsuruApp string) map[string]string {
-	return map[string]string{
-		"tsuru.io/app-name": tsuruApp,
+func (r *ACLReconciler) podSelectorForTsuruJob(tsuruJob string) *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			r.LabelScheme.WithDefaults().JobName: tsuruJob,
+		},
+	}
+}
+
+// podSelectorForTsuruAppPool selects the pods of pool, the pods of exceptApps are left out by a
+// NotIn expression on the app label
+func (r *ACLReconciler) podSelectorForTsuruAppPool(tsuruAppPool string, exceptApps ...string) *metav1.LabelSelector {
+	labelScheme := r.LabelScheme.WithDefaults()
+	selector := &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			labelScheme.AppPool: tsuruAppPool,
+		},
+	}
+	if len(exceptApps) > 0 {
+		values := append([]string{}, exceptApps...)
+		sort.Strings(values)
+		selector.MatchExpressions = []metav1.LabelSelectorRequirement{
+			{
+				Key:      labelScheme.AppName,
+				Operator: metav1.LabelSelectorOpNotIn,
+				Values:   values,
+			},
+		}
 	}
+	return selector
 }
 
-func (r *ACLReconciler) podSelectorForTsuruJob(tsuruJob string) map[string]string {
-	return map[string]string{
-		"tsuru.io/job-name": tsuruJob,
+func (r *ACLReconciler) podSelectorForRpasInstance(rpaasInstance *v1alpha1.ACLSpecRpaasInstance) *metav1.LabelSelector {
+	labelScheme := r.LabelScheme.WithDefaults()
+	return &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			labelScheme.RpaasInstance: rpaasInstance.Instance,
+			labelScheme.RpaasService:  rpaasInstance.ServiceName,
+		},
 	}
 }
 
-func (r *ACLReconciler) podSelectorForRpasInstance(rpaasInstance *v1alpha1.ACLSpecRpaasInstance) map[string]string {
-	return map[string]string{
-		"rpaas.extensions.tsuru.io/instance-name": rpaasInstance.Instance,
-		"rpaas.extensions.tsuru.io/service-name":  rpaasInstance.ServiceName,
+// apiReader reads the objects whose metadata is cached only
+func (r *ACLReconciler) apiReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
 	}
+	return r.Client
 }
 
 func (r *ACLReconciler) getServiceCache() *serviceCache {
 	s := r.serviceCache.Load()
 	if s == nil {
-		s = &serviceCache{
+		// concurrent reconciles may get here together, all of them share the first cache stored
+		r.serviceCache.CompareAndSwap(nil, &serviceCache{
 			Client: r.Client,
-		}
-		r.serviceCache.Store(s)
+		})
+		s = r.serviceCache.Load()
 	}
 
 	return s
@@ -747,28 +2114,26 @@ func (r *ACLReconciler) fillPodSelectorByCIDR(ctx context.Context, rules []netv1
 				if strings.HasSuffix(to.IPBlock.CIDR, "/32") || strings.HasSuffix(to.IPBlock.CIDR, "/128") {
 					ip := strings.Split(to.IPBlock.CIDR, "/")[0]
 
-					svc, err := serviceCache.GetByIP(ctx, ip)
+					// the IP may be the address of a service or of an endpoint, like a pod, of many services
+					services, err := serviceCache.GetAllByIP(ctx, ip)
 					if err != nil {
 						return nil, err
 					}
 
-					if svc == nil {
+					var peers []netv1.NetworkPolicyPeer
+					for _, svc := range services {
+						peer, ok := r.peerForService(svc)
+						if ok && !containsPeer(peers, peer) {
+							peers = append(peers, peer)
+						}
+					}
+
+					if len(peers) == 0 {
 						continue toLoop
 					}
 
 					result = append(result, netv1.NetworkPolicyEgressRule{
-						To: []netv1.NetworkPolicyPeer{
-							{
-								PodSelector: &metav1.LabelSelector{
-									MatchLabels: svc.Spec.Selector,
-								},
-								NamespaceSelector: &metav1.LabelSelector{
-									MatchLabels: map[string]string{
-										"name": svc.Namespace, // we have a common practice to add name of namespace as a label
-									},
-								},
-							},
-						},
+						To: peers,
 					})
 				}
 			}
@@ -778,12 +2143,110 @@ func (r *ACLReconciler) fillPodSelectorByCIDR(ctx context.Context, rules []netv1
 	return result, nil
 }
 
+func containsPeer(peers []netv1.NetworkPolicyPeer, peer netv1.NetworkPolicyPeer) bool {
+	for _, existing := range peers {
+		if reflect.DeepEqual(existing, peer) {
+			return true
+		}
+	}
+	return false
+}
+
+// peerForService selects the pods behind service, services without selector have endpoints
+// managed by someone else and can not be selected
+func (r *ACLReconciler) peerForService(svc *corev1.Service) (netv1.NetworkPolicyPeer, bool) {
+	if len(svc.Spec.Selector) == 0 {
+		return netv1.NetworkPolicyPeer{}, false
+	}
+
+	return netv1.NetworkPolicyPeer{
+		PodSelector: &metav1.LabelSelector{
+			MatchLabels: svc.Spec.Selector,
+		},
+		NamespaceSelector: r.namespaceSelector(svc.Namespace),
+	}, true
+}
+
+// egressRulesForKubernetesService allows the pods selected by a service on its target ports, headless
+// services are selected the same way, ExternalName services and services without selector are not supported
+func (r *ACLReconciler) egressRulesForKubernetesService(ctx context.Context, kubernetesService *v1alpha1.ACLSpecKubernetesService, addressOptions addressOptions) ([]netv1.NetworkPolicyEgressRule, error) {
+	namespace := kubernetesService.Namespace
+	if namespace == "" {
+		namespace = addressOptions.namespace
+	}
+
+	svc, err := r.getServiceCache().GetByName(ctx, namespace, kubernetesService.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if svc == nil {
+		return nil, fmt.Errorf("service %s/%s not found", namespace, kubernetesService.Name)
+	}
+
+	if svc.Spec.Type == corev1.ServiceTypeExternalName {
+		return nil, &unsupportedDestinationError{
+			message: fmt.Sprintf("service %s/%s is an ExternalName service, use an externalDNS destination with %q", namespace, svc.Name, svc.Spec.ExternalName),
+		}
+	}
+
+	peer, ok := r.peerForService(svc)
+	if !ok {
+		return nil, &unsupportedDestinationError{
+			message: fmt.Sprintf("service %s/%s has no selector, its endpoints can not be selected, use an externalIP destination", namespace, svc.Name),
+		}
+	}
+
+	return []netv1.NetworkPolicyEgressRule{
+		{
+			To:    []netv1.NetworkPolicyPeer{peer},
+			Ports: servicePorts(svc),
+		},
+	}, nil
+}
+
+// servicePorts returns the target ports of service, the traffic reaches the pods on them after the
+// address of service is translated, a service without ports allows every port
+func servicePorts(svc *corev1.Service) []netv1.NetworkPolicyPort {
+	var ports []netv1.NetworkPolicyPort
+	seen := map[string]bool{}
+	for _, servicePort := range svc.Spec.Ports {
+		protocol := servicePort.Protocol
+		if protocol == "" {
+			protocol = corev1.ProtocolTCP
+		}
+
+		port := servicePort.TargetPort
+		if (port.Type == intstr.Int && port.IntVal == 0) || (port.Type == intstr.String && port.StrVal == "") {
+			port = intstr.FromInt(int(servicePort.Port))
+		}
+
+		key := string(protocol) + "/" + port.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		ports = append(ports, netv1.NetworkPolicyPort{
+			Protocol: &protocol,
+			Port:     &port,
+		})
+	}
+
+	return ports
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ACLReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("acl-operator")
+	}
+
 	ctrl, err := ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.ACL{}).
-		WithOptions(controller.Options{MaxConcurrentReconciles: 4, RecoverPanic: true}).
-		Owns(&netv1.NetworkPolicy{}).
+		For(&v1alpha1.ACL{}, builder.WithPredicates(r.selectorPredicate())).
+		WithOptions(controller.Options{MaxConcurrentReconciles: maxConcurrentReconciles(r.MaxConcurrentReconciles, DefaultACLConcurrency), RecoverPanic: true}).
+		// edits and deletions of a policy enqueue its ACL, instead of waiting for the next requeue
+		Owns(r.policyBackend().NewObject()).
 		Build(r)
 
 	if err != nil {
@@ -804,97 +2267,140 @@ func (r *ACLReconciler) SetupWithManager(mgr ctrl.Manager) error {
 }
 
 func (r *ACLReconciler) setupIndexes(mgr ctrl.Manager) error {
-	err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1alpha1.ACL{}, externalDNSIndex, func(o client.Object) []string {
-		acl, ok := o.(*v1alpha1.ACL)
-		if !ok {
-			return nil
+	for index := range aclIndexes {
+		err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1alpha1.ACL{}, index, aclIndexKeys(index))
+		if err != nil {
+			return err
 		}
+	}
 
-		keys := []string{}
-		for _, destination := range acl.Spec.Destinations {
-			if destination.ExternalDNS != nil {
-				keys = append(keys, destination.ExternalDNS.Name)
-			}
+	for index := range aclSourceIndexes {
+		err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1alpha1.ACL{}, index, aclIndexKeys(index))
+		if err != nil {
+			return err
 		}
+	}
 
-		return keys
-	})
+	err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1.Service{}, serviceIPIndex, serviceIPIndexKeys)
 	if err != nil {
 		return err
 	}
 
-	err = mgr.GetFieldIndexer().IndexField(context.Background(), &v1alpha1.ACL{}, rpaasInstanceIndex, func(o client.Object) []string {
-		acl, ok := o.(*v1alpha1.ACL)
-		if !ok {
-			return nil
-		}
-
-		keys := []string{}
-		for _, destination := range acl.Spec.Destinations {
-			if destination.RpaasInstance != nil {
-				keys = append(keys, destination.RpaasInstance.ServiceName+"/"+destination.RpaasInstance.Instance)
-			}
-		}
+	return mgr.GetFieldIndexer().IndexField(context.Background(), &discoveryv1.EndpointSlice{}, endpointSliceIPIndex, endpointSliceIPIndexKeys)
+}
 
-		return keys
-	})
+func (r *ACLReconciler) setupWatchers(ctrl controller.Controller) error {
+	// ACLs with a priority are recorded, so the ones with a lower priority wait for them
+	err := ctrl.Watch(&source.Kind{Type: &v1alpha1.ACL{}}, r.priorities.eventHandler(), r.selectorPredicate())
 	if err != nil {
 		return err
 	}
 
-	err = mgr.GetFieldIndexer().IndexField(context.Background(), &v1alpha1.ACL{}, tsuruAppNameIndex, func(o client.Object) []string {
-		acl, ok := o.(*v1alpha1.ACL)
-		if !ok {
-			return nil
-		}
+	err = ctrl.Watch(&source.Kind{Type: &v1alpha1.ACLDNSEntry{}},
+		handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+			dnsEntry, ok := o.(*v1alpha1.ACLDNSEntry)
+			if !ok {
+				return nil
+			}
 
-		keys := []string{}
-		for _, destination := range acl.Spec.Destinations {
-			if destination.TsuruApp != "" {
-				keys = append(keys, destination.TsuruApp)
+			requests := r.reconcileRequestsForIndex(externalDNSIndex, dnsEntry.Spec.Host)
+			// entries of the endpoints of tsuruServiceInstance destinations are only known by their owners
+			requests = appendOwnerRequests(requests, dnsEntry)
+			if dnsEntry.Spec.Namespace == "" {
+				return requests
 			}
-		}
 
-		return keys
-	})
+			// entries of a namespace are only used by the ACLs of the namespace
+			n := 0
+			for _, request := range requests {
+				if request.Namespace == dnsEntry.Spec.Namespace {
+					requests[n] = request
+					n++
+				}
+			}
+			return requests[:n]
+		}),
+		addressStatusChanged,
+	)
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
-
-func (r *ACLReconciler) setupWatchers(ctrl controller.Controller) error {
-	err := ctrl.Watch(&source.Kind{Type: &v1alpha1.ACLDNSEntry{}},
+	err = ctrl.Watch(&source.Kind{Type: &v1alpha1.RpaasInstanceAddress{}},
 		handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
-			dnsEntry, ok := o.(*v1alpha1.ACLDNSEntry)
+			rpaasInstanceAddress, ok := o.(*v1alpha1.RpaasInstanceAddress)
 			if !ok {
 				return nil
 			}
 
-			return r.reconcileRequestsForIndex(externalDNSIndex, dnsEntry.Spec.Host)
+			value := rpaasInstanceAddress.Spec.ServiceName + "/" + rpaasInstanceAddress.Spec.Instance
+			return r.reconcileRequestsForIndex(rpaasInstanceIndex, value)
 		}),
+		addressStatusChanged,
 	)
 	if err != nil {
 		return err
 	}
 
-	err = ctrl.Watch(&source.Kind{Type: &v1alpha1.RpaasInstanceAddress{}},
+	err = ctrl.Watch(&source.Kind{Type: &v1alpha1.TsuruServiceInstanceAddress{}},
 		handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
-			rpaasInstanceAddress, ok := o.(*v1alpha1.RpaasInstanceAddress)
+			instanceAddress, ok := o.(*v1alpha1.TsuruServiceInstanceAddress)
 			if !ok {
 				return nil
 			}
 
-			value := rpaasInstanceAddress.Spec.ServiceName + "/" + rpaasInstanceAddress.Spec.Instance
-			return r.reconcileRequestsForIndex(rpaasInstanceIndex, value)
+			value := instanceAddress.Spec.ServiceName + "/" + instanceAddress.Spec.Instance
+			return r.reconcileRequestsForIndex(tsuruServiceInstanceIndex, value)
+		}),
+		addressStatusChanged,
+	)
+	if err != nil {
+		return err
+	}
 
+	// the addresses of configMap destinations are read through APIReader on every reconcile, only the
+	// metadata of ConfigMaps is watched, ConfigMaps that are not referenced by any ACL enqueue nothing
+	err = ctrl.Watch(&source.Kind{Type: configMapMetadata()},
+		handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+			return r.reconcileRequestsForIndex(configMapIndex, client.ObjectKeyFromObject(o).String())
 		}),
 	)
 	if err != nil {
 		return err
 	}
 
+	// workloads of sources enqueue their ACLs when created, deleted or when their spec changes, which
+	// may change their selector, updates of status do not
+	for _, kind := range []string{v1alpha1.WorkloadKindDeployment, v1alpha1.WorkloadKindStatefulSet} {
+		kind := kind
+		err = ctrl.Watch(&source.Kind{Type: workloadMetadata(kind)},
+			handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+				return r.reconcileRequestsForIndex(workloadIndex, workloadKey(o.GetNamespace(), kind, o.GetName()))
+			}),
+			predicate.GenerationChangedPredicate{},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = ctrl.Watch(&source.Kind{Type: &corev1.Service{}}, serviceCacheEventHandler(r.getServiceCache))
+	if err != nil {
+		return err
+	}
+
+	err = ctrl.Watch(&source.Kind{Type: &discoveryv1.EndpointSlice{}}, endpointSliceCacheEventHandler(r.getServiceCache))
+	if err != nil {
+		return err
+	}
+
+	if r.Refresh != nil {
+		err = ctrl.Watch(&source.Channel{Source: r.Refresh.acls}, &handler.EnqueueRequestForObject{})
+		if err != nil {
+			return err
+		}
+	}
+
 	err = ctrl.Watch(&source.Kind{Type: &v1alpha1.TsuruAppAddress{}},
 		handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
 			tsuruAppAddress, ok := o.(*v1alpha1.TsuruAppAddress)
@@ -902,8 +2408,14 @@ func (r *ACLReconciler) setupWatchers(ctrl controller.Controller) error {
 				return nil
 			}
 
-			return r.reconcileRequestsForIndex(tsuruAppNameIndex, tsuruAppAddress.Spec.Name)
+			requests := r.reconcileRequestsForIndex(tsuruAppNameIndex, tsuruAppAddress.Spec.Name)
+			if tsuruAppAddress.Status.Pool != "" {
+				// router addresses of apps are allowed by tsuruAppPool destinations as well
+				requests = append(requests, r.reconcileRequestsForIndex(tsuruAppPoolIndex, tsuruAppAddress.Status.Pool)...)
+			}
+			return requests
 		}),
+		addressStatusChanged,
 	)
 	if err != nil {
 		return err
@@ -912,48 +2424,191 @@ func (r *ACLReconciler) setupWatchers(ctrl controller.Controller) error {
 	return nil
 }
 
-func (r *ACLReconciler) reconcileRequestsForIndex(index, value string) []reconcile.Request {
-	list := &v1alpha1.ACLList{}
-	err := r.Client.List(context.Background(), list, &client.ListOptions{FieldSelector: fields.SelectorFromSet(fields.Set{
-		index: value,
-	})})
+// addressStatusChanged ignores updates of address objects that keep the status, like the owner
+// references added by ACLs, so an ACL is reconciled again only when the addresses change
+var addressStatusChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return !reflect.DeepEqual(addressObjectStatus(e.ObjectOld), addressObjectStatus(e.ObjectNew))
+	},
+}
+
+func addressObjectStatus(o client.Object) interface{} {
+	switch obj := o.(type) {
+	case *v1alpha1.ACLDNSEntry:
+		return obj.Status
+	case *v1alpha1.TsuruAppAddress:
+		return obj.Status
+	case *v1alpha1.RpaasInstanceAddress:
+		return obj.Status
+	case *v1alpha1.TsuruServiceInstanceAddress:
+		return obj.Status
+	}
+	return o
+}
+
+// appendOwnerRequests adds the ACLs on the owners annotation of obj that are not on requests yet
+func appendOwnerRequests(requests []reconcile.Request, obj client.Object) []reconcile.Request {
+	seen := map[types.NamespacedName]bool{}
+	for _, request := range requests {
+		seen[request.NamespacedName] = true
+	}
+
+	for _, owner := range aclOwners(obj) {
+		parts := strings.SplitN(owner, "/", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		name := types.NamespacedName{Namespace: parts[0], Name: parts[1]}
+		if !seen[name] {
+			seen[name] = true
+			requests = append(requests, reconcile.Request{NamespacedName: name})
+		}
+	}
+	return requests
+}
 
+func (r *ACLReconciler) reconcileRequestsForIndex(index, value string) []reconcile.Request {
+	acls, err := r.listACLsForIndex(context.Background(), index, value)
 	if err != nil {
 		log.Log.Error(err, "could not list ACLs")
 		return nil
 	}
 
-	requests := make([]reconcile.Request, len(list.Items))
+	requests := make([]reconcile.Request, 0, len(acls))
 
-	for i := range list.Items {
-		requests[i].Namespace = list.Items[i].Namespace
-		requests[i].Name = list.Items[i].Name
+	for i := range acls {
+		if !r.selects(&acls[i]) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&acls[i])})
 	}
 
 	return requests
 }
 
 func isWildCard(name string) bool {
-	return name != "" && name[0] == '.'
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "*.")
 }
 
+// unsupportedDestinationError is returned by destinations that can not be expressed by the policy,
+// they are reported on status.warnings instead of failing the reconcile of ACL
+type unsupportedDestinationError struct {
+	message string
+}
+
+func (e *unsupportedDestinationError) Error() string {
+	return e.message
+}
+
+// resolutionError is returned by destinations whose addresses could not be resolved, like a failed
+// DNS lookup or an unreachable Tsuru API, the last applied policy is kept until they resolve again
+type resolutionError struct {
+	err error
+}
+
+func (e *resolutionError) Error() string {
+	return e.err.Error()
+}
+
+func (e *resolutionError) Unwrap() error {
+	return e.err
+}
+
+// ErrAddressNotReady is wrapped by the errors of address objects that were not reconciled by their
+// controller yet, the ACL is requeued shortly instead of being marked as not ready
+var ErrAddressNotReady = errors.New("address object is not ready")
+
+// pendingAddressError is returned by destinations whose address object was not reconciled by its
+// controller yet, the rules generated so far or the stale rules are used without failing the ACL
+type pendingAddressError struct {
+	kind string
+	name string
+}
+
+func (e *pendingAddressError) Error() string {
+	return fmt.Sprintf("%s %s is not reconciled yet", e.kind, e.name)
+}
+
+func (e *pendingAddressError) Unwrap() error {
+	return ErrAddressNotReady
+}
+
+// isResourceAddressPending reports whether the status was never filled, a failed resolution always records a reason
+func isResourceAddressPending(status v1alpha1.ResourceAddressStatus) bool {
+	return !status.Ready && status.Reason == ""
+}
+
+// dnsEntryName keeps the ACLDNSEntry of a host resolved by custom nameservers apart from the
+// entry resolved by the resolver of operator, and the entries of each namespace apart from the shared one
+func dnsEntryName(host string, nameservers []string, namespace string) string {
+	name := host
+	if len(nameservers) > 0 {
+		name += "@" + strings.Join(nameservers, ",")
+	}
+	if namespace != "" {
+		name += "/" + namespace
+	}
+
+	return validResourceName(name)
+}
+
+// dnsEntryNamespace returns the namespace of the ACLDNSEntry objects of acl, empty for shared entries
+func (r *ACLReconciler) dnsEntryNamespace(acl *v1alpha1.ACL) string {
+	if !r.NamespacedDNSEntries {
+		return ""
+	}
+	return acl.Namespace
+}
+
+// externalDNSHost is the host of the ACLDNSEntry of a destination, hostnames are case insensitive
+// and absolute, ACLs with the same host in any case and with or without the trailing dot share the entry
+func externalDNSHost(externalDNS *v1alpha1.ACLSpecExternalDNS) string {
+	return strings.ToLower(strings.TrimSuffix(externalDNS.Name, "."))
+}
+
+// resolvedByBackend reports whether the hostname of destination is resolved by a backend that
+// supports FQDNs, short names are reported by egressRulesForExternalDNS instead
+func resolvedByBackend(destination v1alpha1.ACLSpecDestination, backend PolicyBackend) bool {
+	return destination.ExternalDNS != nil && destination.ExternalDNS.Resolver == nil &&
+		backend.SupportsFQDN() && !v1alpha1.IsShortHostname(destination.ExternalDNS.Name)
+}
+
+// externalDNSNameservers returns nil for destinations without a valid custom resolver
+func externalDNSNameservers(externalDNS *v1alpha1.ACLSpecExternalDNS) []string {
+	if externalDNS.Resolver == nil {
+		return nil
+	}
+
+	nameservers, err := externalDNS.Resolver.Addresses()
+	if err != nil {
+		return nil
+	}
+	return nameservers
+}
+
+// validResourceName is lowercased, hostnames are case insensitive and must share the same objects
 func validResourceName(name string) string {
+	name = strings.ToLower(name)
 	if errs := validation.IsDNS1123Subdomain(name); len(errs) == 0 {
 		return name
 	}
 
 	truncatedName := regexp.MustCompile("[^a-z0-9.-]").ReplaceAllString(name, "-")
+	// a run of separators with a dash would be an empty label or a label ending with a dash
+	truncatedName = regexp.MustCompile("[.-]*-[.-]*").ReplaceAllString(truncatedName, "-")
+	truncatedName = regexp.MustCompile(`\.{2,}`).ReplaceAllString(truncatedName, ".")
 	truncatedName = regexp.MustCompile("^[^a-z0-9]+").ReplaceAllString(truncatedName, "")
 
 	digest := sha256String(name)[:10]
 
 	const maxChars = 253
 
-	if len(truncatedName) < maxChars-11 {
-		return truncatedName + "-" + digest
+	if len(truncatedName) > maxChars-11 {
+		truncatedName = truncatedName[:maxChars-11]
 	}
 
-	return truncatedName[:maxChars-11] + "-" + digest
+	// the digest is joined by a dash, a label can not end with a dash or a dot
+	return strings.TrimRight(truncatedName, "-.") + "-" + digest
 }
 
 func sha256String(str string) string {
@@ -971,3 +2626,77 @@ func copyEgressRules(in []netv1.NetworkPolicyEgressRule) []netv1.NetworkPolicyEg
 
 	return out
 }
+
+// mergeEgressRules combines the peers of rules with the same ports, peers and rules are
+// deduplicated and sorted to keep the NetworkPolicy stable across reconciles.
+// Rules without peers allow all destinations, so they are never merged with rules with peers.
+func mergeEgressRules(in []netv1.NetworkPolicyEgressRule) []netv1.NetworkPolicyEgressRule {
+	result := []netv1.NetworkPolicyEgressRule{}
+	ruleIndexByPorts := map[string]int{}
+	seenPeers := map[int]map[string]bool{}
+
+	for _, rule := range in {
+		key := egressPortsKey(rule.Ports)
+		if len(rule.To) == 0 {
+			key = "all:" + key
+		}
+
+		index, ok := ruleIndexByPorts[key]
+		if !ok {
+			index = len(result)
+			ruleIndexByPorts[key] = index
+			seenPeers[index] = map[string]bool{}
+			result = append(result, netv1.NetworkPolicyEgressRule{
+				Ports: rule.Ports,
+			})
+		}
+
+		for _, peer := range rule.To {
+			peerKey := jsonKey(peer)
+			if seenPeers[index][peerKey] {
+				continue
+			}
+			seenPeers[index][peerKey] = true
+			result[index].To = append(result[index].To, peer)
+		}
+	}
+
+	for i := range result {
+		to := result[i].To
+		sort.SliceStable(to, func(a, b int) bool {
+			return jsonKey(to[a]) < jsonKey(to[b])
+		})
+	}
+
+	sortEgressRules(result)
+
+	return result
+}
+
+// sortEgressRules orders rules by their peers and then by their ports, so the order
+// of destinations and resolved addresses does not generate updates of NetworkPolicy
+func sortEgressRules(rules []netv1.NetworkPolicyEgressRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		toI, toJ := jsonKey(rules[i].To), jsonKey(rules[j].To)
+		if toI != toJ {
+			return toI < toJ
+		}
+		return egressPortsKey(rules[i].Ports) < egressPortsKey(rules[j].Ports)
+	})
+}
+
+// egressPortsKey identifies a set of ports regardless of their order
+func egressPortsKey(ports []netv1.NetworkPolicyPort) string {
+	keys := make([]string, 0, len(ports))
+	for _, port := range ports {
+		keys = append(keys, jsonKey(port))
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// jsonKey is used to compare and sort peers and ports, ipBlock peers are sorted by CIDR and come before selectors
+func jsonKey(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
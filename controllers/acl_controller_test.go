@@ -2,27 +2,47 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/tsuru/acl-operator/api/scheme"
 	v1alpha1 "github.com/tsuru/acl-operator/api/v1alpha1"
 	"github.com/tsuru/acl-operator/clients/tsuruapi"
 	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/provision"
 	appTypes "github.com/tsuru/tsuru/types/app"
+	"golang.org/x/net/dns/dnsmessage"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	netv1 "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 func (suite *ControllerSuite) TestACLReconcilerSimpleReconcile() {
@@ -60,7 +80,7 @@ func (suite *ControllerSuite) TestACLReconcilerSimpleReconcile() {
 	}
 
 	reconciler := &ACLReconciler{
-		Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build(),
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
 		Scheme:   scheme.Scheme,
 		Resolver: &fakeResolver{},
 		TsuruAPI: &fakeTsuruAPI{},
@@ -130,28 +150,28 @@ func (suite *ControllerSuite) TestACLReconcilerSimpleReconcile() {
 		"tsuru.io/app-name": "myapp",
 	}, existingNP.Spec.PodSelector.MatchLabels)
 	suite.Assert().Equal(netv1.NetworkPolicyEgressRule{
-		Ports: []netv1.NetworkPolicyPort{
-			{
-				Port: &intstr.IntOrString{
-					IntVal: 80,
-				},
-				Protocol: &tcp,
-			},
-		},
 		To: []netv1.NetworkPolicyPeer{
 			{
 				IPBlock: &netv1.IPBlock{
-					CIDR: "100.100.100.100/32",
+					CIDR: "1.1.1.1/32",
 				},
 			},
 		},
 	}, existingNP.Spec.Egress[0])
 
 	suite.Assert().Equal(netv1.NetworkPolicyEgressRule{
+		Ports: []netv1.NetworkPolicyPort{
+			{
+				Port: &intstr.IntOrString{
+					IntVal: 80,
+				},
+				Protocol: &tcp,
+			},
+		},
 		To: []netv1.NetworkPolicyPeer{
 			{
 				IPBlock: &netv1.IPBlock{
-					CIDR: "1.1.1.1/32",
+					CIDR: "100.100.100.100/32",
 				},
 			},
 		},
@@ -199,24 +219,42 @@ func (suite *ControllerSuite) TestACLReconcilerStaleReconcile() {
 	}
 
 	reconciler := &ACLReconciler{
-		Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build(),
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
 		Scheme:   scheme.Scheme,
 		Resolver: &fakeResolver{},
 		TsuruAPI: &fakeTsuruAPI{},
 	}
-	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+	result, err := reconciler.Reconcile(ctx, controllerruntime.Request{
 		NamespacedName: types.NamespacedName{
 			Name:      "myapp",
 			Namespace: "default",
 		},
 	})
 	suite.Require().NoError(err)
+	suite.Assert().Equal(pendingAddressRequeueInterval, result.RequeueAfter)
 
+	// the ACLDNSEntry is not resolved yet, stale rules are kept without errors
 	existingACL := &v1alpha1.ACL{}
-
 	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
 	suite.Require().NoError(err)
 	suite.Assert().True(existingACL.Status.Ready)
+	suite.Assert().Equal(acl.Status.Stale, existingACL.Status.Stale)
+	suite.Assert().Len(existingACL.Status.RuleErrors, 0)
+
+	reconcileAddressObjects(ctx, suite.T(), reconciler)
+	result, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+	})
+	suite.Require().NoError(err)
+	suite.Assert().Equal(DefaultRequeueInterval, result.RequeueAfter)
+
+	// the policy was created by the first pass, the rule error keeps the ACL unready
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().False(existingACL.Status.Ready)
 	suite.Assert().Equal("", existingACL.Status.Reason)
 	suite.Assert().Equal([]v1alpha1.ACLStatusStale{
 		{
@@ -354,10 +392,10 @@ func (suite *ControllerSuite) TestACLReconcilerDestinationAppReconcile() {
 	}
 
 	reconciler := &ACLReconciler{
-		Client: fake.NewClientBuilder().
+		Client: withServerSideApply(fake.NewClientBuilder().
 			WithScheme(scheme.Scheme).
 			WithRuntimeObjects(acl, dnsEntry1, dnsEntry2, tsuruAppAddress, svc).
-			Build(),
+			Build()),
 		Scheme:   scheme.Scheme,
 		Resolver: &fakeResolver{},
 		TsuruAPI: &fakeTsuruAPI{},
@@ -384,45 +422,41 @@ func (suite *ControllerSuite) TestACLReconcilerDestinationAppReconcile() {
 	suite.Assert().Equal(map[string]string{
 		"tsuru.io/app-name": "myapp",
 	}, existingNP.Spec.PodSelector.MatchLabels)
-	suite.Assert().Len(existingNP.Spec.Egress, 4)
-
-	suite.Assert().Len(existingNP.Spec.Egress[0].To, 1)
-	suite.Assert().Len(existingNP.Spec.Egress[1].To, 1)
-	suite.Assert().Len(existingNP.Spec.Egress[2].To, 1)
-	suite.Assert().Len(existingNP.Spec.Egress[3].To, 1)
-
-	suite.Assert().Equal(netv1.NetworkPolicyPeer{
-		PodSelector: &metav1.LabelSelector{
-			MatchLabels: map[string]string{
-				"tsuru.io/app-name": "my-other-app",
+	// rules with same ports are merged, peers are sorted with ipBlocks first
+	suite.Require().Len(existingNP.Spec.Egress, 1)
+	// Ports must be nil when use podSelector, some services has port translation and does not match
+	suite.Assert().Nil(existingNP.Spec.Egress[0].Ports)
+	suite.Assert().Equal([]netv1.NetworkPolicyPeer{
+		{
+			IPBlock: &netv1.IPBlock{
+				CIDR: "1.1.1.1/32",
 			},
 		},
-	}, existingNP.Spec.Egress[0].To[0])
-	suite.Assert().Equal(netv1.NetworkPolicyPeer{
-		IPBlock: &netv1.IPBlock{
-			CIDR: "1.1.1.1/32",
-		},
-	}, existingNP.Spec.Egress[1].To[0])
-	// Ports must be nil when use podSelector, some services has port translation and does not match
-	suite.Assert().Nil(existingNP.Spec.Egress[2].Ports)
-	suite.Assert().Equal(netv1.NetworkPolicyPeer{
-		PodSelector: &metav1.LabelSelector{
-			MatchLabels: map[string]string{
-				"svc": "my-awesome-service",
+		{
+			IPBlock: &netv1.IPBlock{
+				CIDR: "2.2.2.2/32",
 			},
 		},
-		NamespaceSelector: &metav1.LabelSelector{
-			MatchLabels: map[string]string{
-				"name": "default",
+		{
+			PodSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"svc": "my-awesome-service",
+				},
+			},
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"name": "default",
+				},
 			},
 		},
-	}, existingNP.Spec.Egress[2].To[0])
-
-	suite.Assert().Equal(netv1.NetworkPolicyPeer{
-		IPBlock: &netv1.IPBlock{
-			CIDR: "2.2.2.2/32",
+		{
+			PodSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"tsuru.io/app-name": "my-other-app",
+				},
+			},
 		},
-	}, existingNP.Spec.Egress[3].To[0])
+	}, existingNP.Spec.Egress[0].To)
 }
 
 func (suite *ControllerSuite) TestACLReconcilerDestinationExternalDNSReconcile() {
@@ -466,10 +500,10 @@ func (suite *ControllerSuite) TestACLReconcilerDestinationExternalDNSReconcile()
 	}
 
 	reconciler := &ACLReconciler{
-		Client: fake.NewClientBuilder().
+		Client: withServerSideApply(fake.NewClientBuilder().
 			WithScheme(scheme.Scheme).
 			WithRuntimeObjects(acl, dnsEntry1).
-			Build(),
+			Build()),
 		Scheme:   scheme.Scheme,
 		Resolver: &fakeResolver{},
 		TsuruAPI: &fakeTsuruAPI{},
@@ -570,7 +604,7 @@ func (suite *ControllerSuite) TestACLReconcilerDestinationRPaaSReconcile() {
 	}
 
 	reconciler := &ACLReconciler{
-		Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl, dnsEntry1, dnsEntry2).Build(),
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl, dnsEntry1, dnsEntry2).Build()),
 		Scheme:   scheme.Scheme,
 		Resolver: &fakeResolver{},
 		TsuruAPI: &fakeTsuruAPI{},
@@ -583,6 +617,15 @@ func (suite *ControllerSuite) TestACLReconcilerDestinationRPaaSReconcile() {
 	})
 	suite.Require().NoError(err)
 
+	reconcileAddressObjects(ctx, suite.T(), reconciler)
+	_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+	})
+	suite.Require().NoError(err)
+
 	existingACL := &v1alpha1.ACL{}
 	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
 	suite.Require().NoError(err)
@@ -599,85 +642,5639 @@ func (suite *ControllerSuite) TestACLReconcilerDestinationRPaaSReconcile() {
 	suite.Assert().Equal(map[string]string{
 		"tsuru.io/app-name": "myapp",
 	}, existingNP.Spec.PodSelector.MatchLabels)
-	suite.Require().Len(existingNP.Spec.Egress, 2)
-
-	suite.Assert().Len(existingNP.Spec.Egress[0].To, 1)
-	suite.Assert().Len(existingNP.Spec.Egress[1].To, 1)
+	suite.Require().Len(existingNP.Spec.Egress, 1)
 
-	suite.Assert().Equal(netv1.NetworkPolicyPeer{
-		PodSelector: &metav1.LabelSelector{
-			MatchLabels: map[string]string{
-				"rpaas.extensions.tsuru.io/instance-name": "my-instance",
-				"rpaas.extensions.tsuru.io/service-name":  "rpaasv2",
+	suite.Assert().Equal([]netv1.NetworkPolicyPeer{
+		{
+			IPBlock: &netv1.IPBlock{
+				CIDR: "3.3.3.3/32",
 			},
 		},
-	}, existingNP.Spec.Egress[0].To[0])
-	suite.Assert().Equal(netv1.NetworkPolicyPeer{
-		IPBlock: &netv1.IPBlock{
-			CIDR: "3.3.3.3/32",
+		{
+			PodSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"rpaas.extensions.tsuru.io/instance-name": "my-instance",
+					"rpaas.extensions.tsuru.io/service-name":  "rpaasv2",
+				},
+			},
 		},
-	}, existingNP.Spec.Egress[1].To[0])
-
-}
+	}, existingNP.Spec.Egress[0].To)
 
-type fakeTsuruAPI struct {
 }
 
-func (f *fakeTsuruAPI) AppInfo(ctx context.Context, appName string) (*app.App, error) {
-	if appName == "my-other-app" {
-		return &app.App{
-			Name: appName,
-			Pool: "my-pool",
-			Routers: []appTypes.AppRouter{
+func (suite *ControllerSuite) TestACLReconcilerIngressReconcile() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
 				{
-					Name: "https-router",
-					Addresses: []string{
-						"https://myapp.io",
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "1.1.1.1/32",
 					},
 				},
+			},
+			Ingress: []v1alpha1.ACLSpecIngress{
 				{
-					Name: "http-router",
-					Addresses: []string{
-						"http.myapp.io",
+					TsuruJob: "myjob",
+				},
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "10.0.0.0/8",
+						Ports: v1alpha1.ACLSpecProtoPorts{
+							{
+								Protocol: "tcp",
+								Number:   8888,
+							},
+						},
 					},
 				},
 			},
-		}, nil
+		},
 	}
 
-	return nil, errors.New("no app found")
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+	})
+	suite.Require().NoError(err)
+
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().True(existingACL.Status.Ready)
+
+	existingNP := &netv1.NetworkPolicy{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{
+		Namespace: existingACL.Namespace,
+		Name:      existingACL.Status.NetworkPolicy,
+	}, existingNP)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]netv1.PolicyType{netv1.PolicyTypeEgress, netv1.PolicyTypeIngress}, existingNP.Spec.PolicyTypes)
+	suite.Require().Len(existingNP.Spec.Egress, 1)
+	suite.Require().Len(existingNP.Spec.Ingress, 2)
+
+	tcp := corev1.ProtocolTCP
+	suite.Assert().Equal(netv1.NetworkPolicyIngressRule{
+		From: []netv1.NetworkPolicyPeer{
+			{
+				PodSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"tsuru.io/job-name": "myjob",
+					},
+				},
+			},
+		},
+	}, existingNP.Spec.Ingress[0])
+	suite.Assert().Equal(netv1.NetworkPolicyIngressRule{
+		From: []netv1.NetworkPolicyPeer{
+			{
+				IPBlock: &netv1.IPBlock{
+					CIDR: "10.0.0.0/8",
+				},
+			},
+		},
+		Ports: []netv1.NetworkPolicyPort{
+			{
+				Port: &intstr.IntOrString{
+					IntVal: 8888,
+				},
+				Protocol: &tcp,
+			},
+		},
+	}, existingNP.Spec.Ingress[1])
 }
 
-func (f *fakeTsuruAPI) ServiceInstanceInfo(ctx context.Context, service, instance string) (*tsuruapi.ServiceInstanceInfo, error) {
-	if service == "rpaasv2" && instance == "my-instance" {
-		return &tsuruapi.ServiceInstanceInfo{
-			CustomInfo: map[string]interface{}{
-				"Address": "3.3.3.3",
+func (suite *ControllerSuite) TestACLReconcilerIngressPendingAddress() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
 			},
-		}, nil
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "1.1.1.1/32",
+					},
+				},
+			},
+			Ingress: []v1alpha1.ACLSpecIngress{
+				{
+					TsuruApp: "otherapp",
+				},
+			},
+		},
 	}
 
-	return nil, errors.New("not implemented yet")
-}
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	reconcile := func() (controllerruntime.Result, *netv1.NetworkPolicy) {
+		result, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
 
-func TestValidResourceName(t *testing.T) {
-	expectations := map[string]string{
-		"user":         "user",
-		"*.globo.com":  "globo.com-102f523825",
-		".google.com":  "google.com-5d59719991",
-		"10.1.1.1/10":  "10.1.1.1-10-22f870d4a0",
-		"facebook.com": "facebook.com",
+		np := &netv1.NetworkPolicy{}
+		err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-myapp"}, np)
+		suite.Require().NoError(err)
+		return result, np
+	}
+
+	// the policy is written with the peers known so far and the ACL is requeued shortly
+	result, np := reconcile()
+	suite.Assert().Equal(pendingAddressRequeueInterval, result.RequeueAfter)
+	suite.Require().Len(np.Spec.Ingress, 1)
+	suite.Assert().Equal([]netv1.NetworkPolicyPeer{
+		{PodSelector: reconciler.podSelectorForTsuruApp("otherapp")},
+	}, np.Spec.Ingress[0].From)
+
+	tsuruAppAddress := &v1alpha1.TsuruAppAddress{}
+	err := reconciler.Client.Get(ctx, client.ObjectKey{Name: "otherapp"}, tsuruAppAddress)
+	suite.Require().NoError(err)
+	tsuruAppAddress.Status = v1alpha1.ResourceAddressStatus{Ready: true, Pool: "my-pool"}
+	err = reconciler.Client.Status().Update(ctx, tsuruAppAddress)
+	suite.Require().NoError(err)
+
+	result, np = reconcile()
+	suite.Assert().Equal(DefaultRequeueInterval, result.RequeueAfter)
+	suite.Require().Len(np.Spec.Ingress, 1)
+	// once the pool is known only the pods of the namespace of app are selected
+	suite.Assert().Equal([]netv1.NetworkPolicyPeer{
+		{PodSelector: reconciler.podSelectorForTsuruApp("otherapp"), NamespaceSelector: reconciler.namespaceSelector("tsuru-my-pool")},
+	}, np.Spec.Ingress[0].From)
+}
 
-		strings.Repeat("testing-", 30): "testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing--2744fb94f9",
+func TestEnsureAddressNotReady(t *testing.T) {
+	ctx := context.Background()
+	failedAddress := &v1alpha1.TsuruAppAddress{
+		ObjectMeta: v1.ObjectMeta{Name: "failed-app"},
+		Spec:       v1alpha1.TsuruAppAddressSpec{Name: "failed-app"},
+		Status:     v1alpha1.ResourceAddressStatus{Reason: "App not found"},
+	}
+	reconciler := &ACLReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(failedAddress).Build(),
+		Scheme: scheme.Scheme,
 	}
 
-	for input, expectedOutput := range expectations {
-		output := validResourceName(input)
-		assert.Equal(t, expectedOutput, output, "input", input)
+	tsuruAppAddress, err := reconciler.ensureTsuruAppAddress(ctx, "new-app", nil)
+	assert.True(t, errors.Is(err, ErrAddressNotReady))
+	assert.EqualError(t, err, "TsuruAppAddress new-app is not reconciled yet")
+	require.NotNil(t, tsuruAppAddress)
+	assert.Equal(t, "new-app", tsuruAppAddress.Name)
 
-		errs := validation.IsDNS1123Subdomain(output)
+	// the object is still not ready when it is found without status
+	_, err = reconciler.ensureTsuruAppAddress(ctx, "new-app", nil)
+	assert.True(t, errors.Is(err, ErrAddressNotReady))
 
-		assert.Len(t, errs, 0)
+	_, err = reconciler.ensureRpaasInstanceAddress(ctx, &v1alpha1.ACLSpecRpaasInstance{ServiceName: "rpaasv2", Instance: "my-instance"})
+	assert.True(t, errors.Is(err, ErrAddressNotReady))
+
+	_, err = reconciler.ensureDNSEntry(ctx, &v1alpha1.ACLSpecExternalDNS{Name: "example.com"}, "")
+	assert.True(t, errors.Is(err, ErrAddressNotReady))
+
+	// a failed resolution is not pending, the reason is reported by the destination
+	tsuruAppAddress, err = reconciler.ensureTsuruAppAddress(ctx, "failed-app", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "App not found", tsuruAppAddress.Status.Reason)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerEgressOnlyReconcile() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "1.1.1.1/32",
+					},
+				},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
 	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+	})
+	suite.Require().NoError(err)
+
+	existingNP := &netv1.NetworkPolicy{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{
+		Namespace: "default",
+		Name:      "acl-myapp",
+	}, existingNP)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]netv1.PolicyType{netv1.PolicyTypeEgress}, existingNP.Spec.PolicyTypes)
+	suite.Assert().Nil(existingNP.Spec.Ingress)
+}
 
+func (suite *ControllerSuite) TestACLReconcilerRecordEvents() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "1.1.1.1/32",
+					},
+				},
+			},
+		},
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+		Recorder: recorder,
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Len(recorder.Events, 1)
+	suite.Assert().Equal("Normal NetworkPolicyCreated NetworkPolicy acl-myapp has been created", <-recorder.Events)
+
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	existingACL.Spec.Destinations = []v1alpha1.ACLSpecDestination{
+		{
+			ExternalDNS: &v1alpha1.ACLSpecExternalDNS{
+				Name: "timeout.com.br" + strings.Repeat("a", 2000),
+			},
+		},
+	}
+	// the fake client does not bump the generation on changes of spec
+	existingACL.Generation++
+	err = reconciler.Client.Update(ctx, existingACL)
+	suite.Require().NoError(err)
+
+	_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Len(recorder.Events, 1)
+	suite.Assert().Equal("Warning NoEgressRules No egress generated by spec.destinations", <-recorder.Events)
+
+	reconcileAddressObjects(ctx, suite.T(), reconciler)
+	_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+	})
+	suite.Require().NoError(err)
+	// the failing destination is skipped, so there are no egress rules as well
+	suite.Require().Len(recorder.Events, 2)
+	event := <-recorder.Events
+	suite.Assert().True(strings.HasPrefix(event, "Warning DestinationResolutionFailed could not generate egress rule for destination"))
+	suite.Assert().True(strings.HasSuffix(event, "..."))
+	suite.Assert().LessOrEqual(len(event), maxEventMessageLength+len("Warning DestinationResolutionFailed "))
+	event = <-recorder.Events
+	suite.Assert().True(strings.HasPrefix(event, "Warning NoEgressRules No egress generated by spec.destinations, skipped destination"))
+	suite.Assert().LessOrEqual(len(event), maxEventMessageLength+len("Warning NoEgressRules "))
+}
+
+func (suite *ControllerSuite) TestACLReconcilerReadyCondition() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:       "myapp",
+			Namespace:  "default",
+			Generation: 2,
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "1.1.1.1/32",
+					},
+				},
+			},
+		},
+	}
+	invalidACL := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:       "invalid",
+			Namespace:  "default",
+			Generation: 5,
+		},
+		Spec: v1alpha1.ACLSpec{
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "1.1.1.1/32",
+					},
+				},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl, invalidACL).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+
+	for _, name := range []string{"myapp", "invalid"} {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      name,
+				Namespace: "default",
+			},
+		})
+		suite.Require().NoError(err)
+	}
+
+	existingACL := &v1alpha1.ACL{}
+	err := reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	condition := meta.FindStatusCondition(existingACL.Status.Conditions, v1alpha1.ACLConditionReady)
+	suite.Require().NotNil(condition)
+	suite.Assert().Equal(metav1.ConditionTrue, condition.Status)
+	suite.Assert().Equal("Reconciled", condition.Reason)
+	suite.Assert().Equal(int64(2), condition.ObservedGeneration)
+	suite.Assert().True(existingACL.Status.Ready)
+
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(invalidACL), existingACL)
+	suite.Require().NoError(err)
+	condition = meta.FindStatusCondition(existingACL.Status.Conditions, v1alpha1.ACLConditionReady)
+	suite.Require().NotNil(condition)
+	suite.Assert().Equal(metav1.ConditionFalse, condition.Status)
+	suite.Assert().Equal("InvalidSource", condition.Reason)
+	suite.Assert().Equal("No podSelector generated by spec.source", condition.Message)
+	suite.Assert().Equal(int64(5), condition.ObservedGeneration)
+	suite.Assert().False(existingACL.Status.Ready)
+	suite.Assert().Equal("No podSelector generated by spec.source", existingACL.Status.Reason)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerFinalizerReleasesAddressObjects() {
+	ctx := context.Background()
+	newACL := func(name string) *v1alpha1.ACL {
+		return &v1alpha1.ACL{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: v1alpha1.ACLSpec{
+				Source: v1alpha1.ACLSpecSource{
+					TsuruApp: name,
+				},
+				Destinations: []v1alpha1.ACLSpecDestination{
+					{
+						ExternalDNS: &v1alpha1.ACLSpecExternalDNS{
+							Name: "www.google.com.br",
+						},
+					},
+				},
+			},
+		}
+	}
+	acl1 := newACL("myapp1")
+	acl2 := newACL("myapp2")
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl1, acl2).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+
+	reconcileACL := func(acl *v1alpha1.ACL) {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+	}
+	deleteACL := func(acl *v1alpha1.ACL) {
+		existingACL := &v1alpha1.ACL{}
+		err := reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+		suite.Require().NoError(err)
+		err = reconciler.Client.Delete(ctx, existingACL)
+		suite.Require().NoError(err)
+	}
+
+	reconcileACL(acl1)
+	reconcileACL(acl2)
+
+	existingACL := &v1alpha1.ACL{}
+	err := reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl1), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]string{aclCleanupFinalizer}, existingACL.Finalizers)
+
+	dnsEntry := &v1alpha1.ACLDNSEntry{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Name: "www.google.com.br"}, dnsEntry)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("default/myapp1,default/myapp2", dnsEntry.Annotations[aclOwnersAnnotation])
+
+	deleteACL(acl1)
+	reconcileACL(acl1)
+
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl1), existingACL)
+	suite.Require().True(k8sErrors.IsNotFound(err))
+
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Name: "www.google.com.br"}, dnsEntry)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("default/myapp2", dnsEntry.Annotations[aclOwnersAnnotation])
+
+	deleteACL(acl2)
+	reconcileACL(acl2)
+
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl2), existingACL)
+	suite.Require().True(k8sErrors.IsNotFound(err))
+
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Name: "www.google.com.br"}, dnsEntry)
+	suite.Require().True(k8sErrors.IsNotFound(err))
+}
+
+func (suite *ControllerSuite) TestACLReconcilerFinalizerKeepsTsuruAppAddressOfPool() {
+	ctx := context.Background()
+	appACL := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp1",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp1",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					TsuruApp: "my-other-app",
+				},
+			},
+		},
+	}
+	poolACL := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp2",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp2",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					TsuruAppPool: "my-pool",
+				},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client: withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(appACL, poolACL).Build()),
+		Scheme: scheme.Scheme,
+		Resolver: &fakeResolver{
+			hosts: map[string][]string{
+				"myapp.io":      {"10.1.1.2"},
+				"http.myapp.io": {"10.1.1.3"},
+			},
+		},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+
+	reconcileACL := func(acl *v1alpha1.ACL) {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+	}
+
+	reconcileACL(appACL)
+	reconcileACL(poolACL)
+	reconcileAddressObjects(ctx, suite.T(), reconciler)
+	reconcileACL(appACL)
+	reconcileACL(poolACL)
+
+	// the address of an app of pool is owned by the ACLs of the app and of the pool
+	tsuruAppAddress := &v1alpha1.TsuruAppAddress{}
+	err := reconciler.Client.Get(ctx, client.ObjectKey{Name: "my-other-app"}, tsuruAppAddress)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("my-pool", tsuruAppAddress.Status.Pool)
+	suite.Assert().Equal([]string{"default/myapp1", "default/myapp2"}, aclOwners(tsuruAppAddress))
+
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(poolACL), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().Contains(existingACL.Status.AddressObjects, v1alpha1.ACLStatusAddressObject{Kind: "TsuruAppAddress", Name: "my-other-app"})
+
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(appACL), existingACL)
+	suite.Require().NoError(err)
+	err = reconciler.Client.Delete(ctx, existingACL)
+	suite.Require().NoError(err)
+	reconcileACL(appACL)
+
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Name: "my-other-app"}, tsuruAppAddress)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]string{"default/myapp2"}, aclOwners(tsuruAppAddress))
+
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(poolACL), existingACL)
+	suite.Require().NoError(err)
+	err = reconciler.Client.Delete(ctx, existingACL)
+	suite.Require().NoError(err)
+	reconcileACL(poolACL)
+
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Name: "my-other-app"}, tsuruAppAddress)
+	suite.Require().True(k8sErrors.IsNotFound(err))
+}
+
+func (suite *ControllerSuite) TestACLReconcilerReleasesRemovedDestinations() {
+	ctx := context.Background()
+	newACL := func(name string, hosts ...string) *v1alpha1.ACL {
+		acl := &v1alpha1.ACL{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: v1alpha1.ACLSpec{
+				Source: v1alpha1.ACLSpecSource{
+					TsuruApp: name,
+				},
+			},
+		}
+		for _, host := range hosts {
+			acl.Spec.Destinations = append(acl.Spec.Destinations, v1alpha1.ACLSpecDestination{
+				ExternalDNS: &v1alpha1.ACLSpecExternalDNS{Name: host},
+			})
+		}
+		return acl
+	}
+	acl1 := newACL("myapp1", "www.google.com.br", "shared.example.com", "only.example.com")
+	acl2 := newACL("myapp2", "shared.example.com")
+
+	reconciler := &ACLReconciler{
+		Client: withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl1, acl2).Build()),
+		Scheme: scheme.Scheme,
+		Resolver: &fakeResolver{hosts: map[string][]string{
+			"shared.example.com": {"10.0.0.1"},
+			"only.example.com":   {"10.0.0.2"},
+		}},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	reconcileACL := func(acl *v1alpha1.ACL) *v1alpha1.ACL {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+
+		existingACL := &v1alpha1.ACL{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+		suite.Require().NoError(err)
+		return existingACL
+	}
+
+	existingACL := reconcileACL(acl1)
+	reconcileACL(acl2)
+	suite.Assert().Equal([]v1alpha1.ACLStatusAddressObject{
+		{Kind: "ACLDNSEntry", Name: "only.example.com"},
+		{Kind: "ACLDNSEntry", Name: "shared.example.com"},
+		{Kind: "ACLDNSEntry", Name: "www.google.com.br"},
+	}, existingACL.Status.AddressObjects)
+
+	// the destinations removed from spec release their entries on the next reconcile
+	existingACL.Spec.Destinations = existingACL.Spec.Destinations[:1]
+	err := reconciler.Client.Update(ctx, existingACL)
+	suite.Require().NoError(err)
+	existingACL = reconcileACL(acl1)
+	suite.Assert().Equal([]v1alpha1.ACLStatusAddressObject{
+		{Kind: "ACLDNSEntry", Name: "www.google.com.br"},
+	}, existingACL.Status.AddressObjects)
+
+	dnsEntry := &v1alpha1.ACLDNSEntry{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Name: "only.example.com"}, dnsEntry)
+	suite.Assert().True(k8sErrors.IsNotFound(err))
+
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Name: "shared.example.com"}, dnsEntry)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("default/myapp2", dnsEntry.Annotations[aclOwnersAnnotation])
+
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Name: "www.google.com.br"}, dnsEntry)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("default/myapp1", dnsEntry.Annotations[aclOwnersAnnotation])
+}
+
+func (suite *ControllerSuite) TestACLReconcilerFinalizerKeepsUserOwnedDNSEntry() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{ExternalDNS: &v1alpha1.ACLSpecExternalDNS{Name: "www.google.com.br"}},
+			},
+		},
+	}
+	userDNSEntry := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        "www.google.com.br",
+			Annotations: map[string]string{userOwnedAnnotation: "true"},
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{Host: "www.google.com.br"},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl, userDNSEntry).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	reconcileACL := func() {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+	}
+
+	reconcileACL()
+
+	dnsEntry := &v1alpha1.ACLDNSEntry{}
+	err := reconciler.Client.Get(ctx, client.ObjectKeyFromObject(userDNSEntry), dnsEntry)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("default/myapp", dnsEntry.Annotations[aclOwnersAnnotation])
+
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	err = reconciler.Client.Delete(ctx, existingACL)
+	suite.Require().NoError(err)
+	reconcileACL()
+
+	// the entry is released by the ACL and kept for its user
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(userDNSEntry), dnsEntry)
+	suite.Require().NoError(err)
+	suite.Assert().NotContains(dnsEntry.Annotations, aclOwnersAnnotation)
+	suite.Assert().Equal("true", dnsEntry.Annotations[userOwnedAnnotation])
+}
+
+func (suite *ControllerSuite) TestACLReconcilerFinalizerWithMissingAddressObjects() {
+	ctx := context.Background()
+	now := v1.Now()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:              "myapp",
+			Namespace:         "default",
+			Finalizers:        []string{aclCleanupFinalizer},
+			DeletionTimestamp: &now,
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					TsuruApp: "otherapp",
+				},
+				{
+					RpaasInstance: &v1alpha1.ACLSpecRpaasInstance{
+						ServiceName: "rpaasv2",
+						Instance:    "my-instance",
+					},
+				},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().True(k8sErrors.IsNotFound(err))
+}
+
+func (suite *ControllerSuite) TestACLReconcilerInvalidDestination() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					TsuruApp: "other-app",
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "1.1.1.1",
+					},
+				},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().False(existingACL.Status.Ready)
+	suite.Assert().Contains(existingACL.Status.Reason, "destination must set exactly one of tsuruApp, tsuruAppPool, rpaasInstance, tsuruServiceInstance, externalDNS, externalIP, externalEndpoints, externalSRV, kubernetesService, configMap or deny, found 2")
+}
+
+func (suite *ControllerSuite) TestACLReconcilerSkipFailingDestination() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "1.1.1.1",
+					},
+				},
+				{
+					ExternalDNS: &v1alpha1.ACLSpecExternalDNS{
+						Name: "failing.io",
+					},
+				},
+			},
+		},
+	}
+	dnsEntry := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "failing.io",
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host: "failing.io",
+		},
+		Status: v1alpha1.ACLDNSEntryStatus{
+			Reason: "timeout for host",
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl, dnsEntry).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	result, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+	suite.Assert().Equal(skippedDestinationRequeueInterval, result.RequeueAfter)
+
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("acl-myapp", existingACL.Status.NetworkPolicy)
+	suite.Assert().Equal([]v1alpha1.ACLStatusRuleError{
+		{
+			Destination: "externalDNS failing.io",
+			Error:       "timeout for host",
+		},
+	}, existingACL.Status.RuleErrors)
+	degraded := meta.FindStatusCondition(existingACL.Status.Conditions, v1alpha1.ACLConditionDegraded)
+	suite.Require().NotNil(degraded)
+	suite.Assert().Equal(metav1.ConditionTrue, degraded.Status)
+	suite.Assert().Equal(conditionReasonDestinationsSkipped, degraded.Reason)
+	// the creation of the policy does not make the ACL ready
+	suite.Assert().False(existingACL.Status.Ready)
+	ready := meta.FindStatusCondition(existingACL.Status.Conditions, v1alpha1.ACLConditionReady)
+	suite.Require().NotNil(ready)
+	suite.Assert().Equal(metav1.ConditionFalse, ready.Status)
+	suite.Assert().Equal(conditionReasonDestinationsSkipped, ready.Reason)
+
+	existingNP := &netv1.NetworkPolicy{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{
+		Namespace: existingACL.Namespace,
+		Name:      existingACL.Status.NetworkPolicy,
+	}, existingNP)
+	suite.Require().NoError(err)
+	suite.Require().Len(existingNP.Spec.Egress, 1)
+	suite.Assert().Equal([]netv1.NetworkPolicyPeer{
+		{IPBlock: &netv1.IPBlock{CIDR: "1.1.1.1/32"}},
+	}, existingNP.Spec.Egress[0].To)
+
+	// the whole ACL fails when the destinations are not skipped
+	reconciler = &ACLReconciler{
+		Client:                  withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl, dnsEntry).Build()),
+		Scheme:                  scheme.Scheme,
+		Resolver:                &fakeResolver{},
+		TsuruAPI:                &fakeTsuruAPI{},
+		AbortOnDestinationError: true,
+	}
+	_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().False(existingACL.Status.Ready)
+	suite.Assert().Empty(existingACL.Status.NetworkPolicy)
+	suite.Assert().Contains(existingACL.Status.Reason, "timeout for host")
+}
+
+func (suite *ControllerSuite) TestACLReconcilerWildcardDestination() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalDNS: &v1alpha1.ACLSpecExternalDNS{
+						Name: ".example.com",
+					},
+				},
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "1.1.1.1",
+					},
+				},
+			},
+		},
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+		Recorder: recorder,
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().True(existingACL.Status.Ready)
+	suite.Assert().Equal([]string{
+		`wildcard destination ".example.com" is ignored, NetworkPolicy can not express hostnames`,
+	}, existingACL.Status.Warnings)
+	suite.Assert().Equal(`Warning UnsupportedDestination wildcard destination ".example.com" is ignored, NetworkPolicy can not express hostnames`, <-recorder.Events)
+
+	existingNP := &netv1.NetworkPolicy{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{
+		Namespace: existingACL.Namespace,
+		Name:      existingACL.Status.NetworkPolicy,
+	}, existingNP)
+	suite.Require().NoError(err)
+	suite.Assert().Len(existingNP.Spec.Egress, 1)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerShortHostname() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					RuleID: "database",
+					ExternalDNS: &v1alpha1.ACLSpecExternalDNS{
+						Name: "db",
+					},
+				},
+				{
+					ExternalDNS: &v1alpha1.ACLSpecExternalDNS{
+						Name: "WWW.google.com.br.",
+					},
+				},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]v1alpha1.ACLStatusRuleError{
+		{RuleID: "database", Error: `externalDNS name "db" is not fully qualified, short names resolve against search domains, use the name with its domain`},
+	}, existingACL.Status.RuleErrors)
+
+	// no entry is created for the short name, the trailing dot does not change the entry of host
+	dnsEntries := &v1alpha1.ACLDNSEntryList{}
+	err = reconciler.Client.List(ctx, dnsEntries)
+	suite.Require().NoError(err)
+	suite.Require().Len(dnsEntries.Items, 1)
+	suite.Assert().Equal("www.google.com.br", dnsEntries.Items[0].Name)
+	suite.Assert().Equal("www.google.com.br", dnsEntries.Items[0].Spec.Host)
+}
+
+func TestACLSpecDestinationShortHostname(t *testing.T) {
+	for name, short := range map[string]bool{
+		"db":              true,
+		"db.":             true,
+		"localhost":       true,
+		"db.example.com":  false,
+		"db.example.com.": false,
+		"10.0.0.1":        false,
+		"2001:db8::1":     false,
+	} {
+		destination := v1alpha1.ACLSpecDestination{ExternalDNS: &v1alpha1.ACLSpecExternalDNS{Name: name}}
+		err := destination.Validate()
+		if short {
+			assert.EqualError(t, err, fmt.Sprintf("externalDNS name %q is not fully qualified, short names resolve against search domains, use the name with its domain", name), name)
+		} else {
+			assert.NoError(t, err, name)
+		}
+	}
+
+	entry := v1alpha1.ACLDNSEntrySpec{Host: "db"}
+	assert.EqualError(t, entry.Validate(), `host "db" is not fully qualified, short names resolve against the search domains of the operator`)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerSecondReconcileIsNoop() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "3.3.3.3",
+					},
+				},
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "1.1.1.1",
+						Ports: v1alpha1.ACLSpecProtoPorts{
+							{Protocol: "tcp", Number: 443},
+						},
+					},
+				},
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "2.2.2.2",
+					},
+				},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+
+	existingNP := &netv1.NetworkPolicy{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{
+		Namespace: existingACL.Namespace,
+		Name:      existingACL.Status.NetworkPolicy,
+	}, existingNP)
+	suite.Require().NoError(err)
+	suite.Require().Len(existingNP.Spec.Egress, 2)
+
+	// the order of destinations must not change the NetworkPolicy
+	destinations := existingACL.Spec.Destinations
+	for i, j := 0, len(destinations)-1; i < j; i, j = i+1, j-1 {
+		destinations[i], destinations[j] = destinations[j], destinations[i]
+	}
+	err = reconciler.Client.Update(ctx, existingACL)
+	suite.Require().NoError(err)
+
+	_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	secondNP := &netv1.NetworkPolicy{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(existingNP), secondNP)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(existingNP.ResourceVersion, secondNP.ResourceVersion)
+	suite.Assert().Equal(existingNP.Spec, secondNP.Spec)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerInvalidProtocol() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "1.1.1.1",
+						Ports: v1alpha1.ACLSpecProtoPorts{
+							{Protocol: "tpc", Number: 80},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().False(existingACL.Status.Ready)
+	suite.Assert().Contains(existingACL.Status.Reason, `invalid protocol "tpc", use TCP, UDP or SCTP`)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerIPFamilies() {
+	ctx := context.Background()
+	dnsEntry := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: validResourceName("dual-stack.io"),
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host:          "dual-stack.io",
+			AdditionalIPs: []string{"10.0.0.1", "::ffff:10.0.0.2", "invalid"},
+		},
+		Status: v1alpha1.ACLDNSEntryStatus{
+			Ready: true,
+			IPs: []v1alpha1.ACLDNSEntryStatusIP{
+				{Address: "10.0.0.1"},
+				{Address: "2001:db8::1"},
+			},
+		},
+	}
+
+	newACL := func(name string, ipFamilies []v1alpha1.IPFamily) *v1alpha1.ACL {
+		return &v1alpha1.ACL{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: v1alpha1.ACLSpec{
+				Source: v1alpha1.ACLSpecSource{
+					TsuruApp: "myapp",
+				},
+				Destinations: []v1alpha1.ACLSpecDestination{
+					{
+						ExternalDNS: &v1alpha1.ACLSpecExternalDNS{
+							Name: "dual-stack.io",
+						},
+					},
+				},
+				IPFamilies: ipFamilies,
+			},
+		}
+	}
+
+	tests := []struct {
+		acl           *v1alpha1.ACL
+		expectedCIDRs []string
+	}{
+		{
+			acl:           newACL("both", nil),
+			expectedCIDRs: []string{"10.0.0.1/32", "10.0.0.2/32", "2001:db8::1/128"},
+		},
+		{
+			acl:           newACL("ipv4", []v1alpha1.IPFamily{v1alpha1.IPv4Family}),
+			expectedCIDRs: []string{"10.0.0.1/32", "10.0.0.2/32"},
+		},
+		{
+			acl:           newACL("ipv6", []v1alpha1.IPFamily{v1alpha1.IPv6Family}),
+			expectedCIDRs: []string{"2001:db8::1/128"},
+		},
+	}
+
+	for _, tt := range tests {
+		reconciler := &ACLReconciler{
+			Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(tt.acl, dnsEntry).Build()),
+			Scheme:   scheme.Scheme,
+			Resolver: &fakeResolver{},
+			TsuruAPI: &fakeTsuruAPI{},
+		}
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(tt.acl),
+		})
+		suite.Require().NoError(err)
+
+		existingNP := &netv1.NetworkPolicy{}
+		err = reconciler.Client.Get(ctx, client.ObjectKey{
+			Namespace: tt.acl.Namespace,
+			Name:      "acl-" + tt.acl.Name,
+		}, existingNP)
+		suite.Require().NoError(err)
+		suite.Require().Len(existingNP.Spec.Egress, 1)
+
+		cidrs := []string{}
+		for _, peer := range existingNP.Spec.Egress[0].To {
+			cidrs = append(cidrs, peer.IPBlock.CIDR)
+		}
+		suite.Assert().Equal(tt.expectedCIDRs, cidrs, tt.acl.Name)
+	}
+
+	// the families of the reconciler are used when the ACL does not set them
+	acl := newACL("default-families", nil)
+	reconciler := &ACLReconciler{
+		Client:     withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl, dnsEntry).Build()),
+		Scheme:     scheme.Scheme,
+		Resolver:   &fakeResolver{},
+		TsuruAPI:   &fakeTsuruAPI{},
+		IPFamilies: []v1alpha1.IPFamily{v1alpha1.IPv6Family},
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	existingNP := &netv1.NetworkPolicy{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{
+		Namespace: acl.Namespace,
+		Name:      "acl-" + acl.Name,
+	}, existingNP)
+	suite.Require().NoError(err)
+	suite.Require().Len(existingNP.Spec.Egress, 1)
+	suite.Assert().Equal([]netv1.NetworkPolicyPeer{
+		{IPBlock: &netv1.IPBlock{CIDR: "2001:db8::1/128"}},
+	}, existingNP.Spec.Egress[0].To)
+
+	// a host without addresses of the families is ignored, a rule without peers would allow every destination
+	v6Only := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: validResourceName("v6-only.io"),
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host: "v6-only.io",
+		},
+		Status: v1alpha1.ACLDNSEntryStatus{
+			Ready: true,
+			IPs:   []v1alpha1.ACLDNSEntryStatusIP{{Address: "2001:db8::2"}},
+		},
+	}
+	acl = newACL("ipv4-only", []v1alpha1.IPFamily{v1alpha1.IPv4Family})
+	acl.Spec.Destinations = []v1alpha1.ACLSpecDestination{
+		{ExternalDNS: &v1alpha1.ACLSpecExternalDNS{Name: "v6-only.io", Ports: v1alpha1.ACLSpecProtoPorts{{Protocol: "tcp", Number: 443}}}},
+		{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.9"}},
+	}
+	reconciler = &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl, v6Only).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	err = reconciler.Client.Get(ctx, client.ObjectKey{
+		Namespace: acl.Namespace,
+		Name:      "acl-" + acl.Name,
+	}, existingNP)
+	suite.Require().NoError(err)
+	suite.Require().Len(existingNP.Spec.Egress, 1)
+	suite.Assert().Equal([]netv1.NetworkPolicyPeer{
+		{IPBlock: &netv1.IPBlock{CIDR: "10.0.0.9/32"}},
+	}, existingNP.Spec.Egress[0].To)
+
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]string{"v6-only.io has no address of the ipFamilies [IPv4] of ACL, it is ignored"}, existingACL.Status.Warnings)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerCIDRAggregation() {
+	ctx := context.Background()
+	dnsEntry := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: validResourceName("many-ips.io"),
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host: "many-ips.io",
+		},
+		Status: v1alpha1.ACLDNSEntryStatus{
+			Ready: true,
+			IPs: []v1alpha1.ACLDNSEntryStatusIP{
+				{Address: "10.0.0.4"},
+				{Address: "10.0.0.5"},
+				{Address: "10.0.0.6"},
+				{Address: "10.0.0.7"},
+				{Address: "10.0.0.9"},
+				{Address: "10.0.0.10"},
+			},
+		},
+	}
+	svc := &corev1.Service{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "my-service",
+			Namespace: "default",
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "10.0.0.10",
+			Selector: map[string]string{
+				"svc": "my-service",
+			},
+		},
+	}
+
+	newACL := func(name string, aggregation *v1alpha1.ACLSpecCIDRAggregation) *v1alpha1.ACL {
+		return &v1alpha1.ACL{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: v1alpha1.ACLSpec{
+				Source: v1alpha1.ACLSpecSource{
+					TsuruApp: "myapp",
+				},
+				Destinations: []v1alpha1.ACLSpecDestination{
+					{
+						ExternalDNS: &v1alpha1.ACLSpecExternalDNS{
+							Name: "many-ips.io",
+						},
+					},
+				},
+				CIDRAggregation: aggregation,
+			},
+		}
+	}
+
+	tests := []struct {
+		acl           *v1alpha1.ACL
+		expectedCIDRs []string
+	}{
+		{
+			// the reconciler aggregates ACLs without spec.cidrAggregation
+			acl:           newACL("default", nil),
+			expectedCIDRs: []string{"10.0.0.10/32", "10.0.0.4/30", "10.0.0.9/32"},
+		},
+		{
+			acl:           newACL("disabled", &v1alpha1.ACLSpecCIDRAggregation{}),
+			expectedCIDRs: []string{"10.0.0.10/32", "10.0.0.4/32", "10.0.0.5/32", "10.0.0.6/32", "10.0.0.7/32", "10.0.0.9/32"},
+		},
+	}
+
+	for _, tt := range tests {
+		reconciler := &ACLReconciler{
+			Client:          withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(tt.acl, dnsEntry, svc).Build()),
+			Scheme:          scheme.Scheme,
+			Resolver:        &fakeResolver{},
+			TsuruAPI:        &fakeTsuruAPI{},
+			CIDRAggregation: &v1alpha1.ACLSpecCIDRAggregation{Enabled: true},
+		}
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(tt.acl),
+		})
+		suite.Require().NoError(err)
+
+		existingNP := &netv1.NetworkPolicy{}
+		err = reconciler.Client.Get(ctx, client.ObjectKey{
+			Namespace: tt.acl.Namespace,
+			Name:      "acl-" + tt.acl.Name,
+		}, existingNP)
+		suite.Require().NoError(err)
+		suite.Require().Len(existingNP.Spec.Egress, 1)
+
+		cidrs := []string{}
+		selectors := 0
+		for _, peer := range existingNP.Spec.Egress[0].To {
+			if peer.IPBlock != nil {
+				cidrs = append(cidrs, peer.IPBlock.CIDR)
+			} else {
+				selectors++
+			}
+		}
+		suite.Assert().Equal(tt.expectedCIDRs, cidrs, tt.acl.Name)
+		// the address of service is not aggregated, so its pods are allowed
+		suite.Assert().Equal(1, selectors, tt.acl.Name)
+	}
+}
+
+func (suite *ControllerSuite) TestACLReconcilerResolvedDestinations() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					RuleID: "rule-1",
+					ExternalDNS: &v1alpha1.ACLSpecExternalDNS{
+						Name: "myapp.io",
+					},
+				},
+				{
+					TsuruApp: "my-other-app",
+				},
+				{
+					ExternalDNS: &v1alpha1.ACLSpecExternalDNS{
+						Name: "*.example.com",
+					},
+				},
+				{
+					Deny: &v1alpha1.ACLSpecDeny{
+						CIDRs: []string{"10.0.0.0/8"},
+					},
+				},
+			},
+		},
+	}
+
+	dnsEntry := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "myapp.io",
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host: "myapp.io",
+		},
+		Status: v1alpha1.ACLDNSEntryStatus{
+			Ready: true,
+			IPs: []v1alpha1.ACLDNSEntryStatusIP{
+				{Address: "1.1.1.1"},
+				{Address: "2.2.2.2"},
+			},
+		},
+	}
+
+	tsuruAppAddress := &v1alpha1.TsuruAppAddress{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "my-other-app",
+		},
+		Spec: v1alpha1.TsuruAppAddressSpec{
+			Name: "my-other-app",
+		},
+		Status: v1alpha1.ResourceAddressStatus{
+			Ready: true,
+			Pool:  "my-pool",
+			IPs: []string{
+				"3.3.3.3",
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl, dnsEntry, tsuruAppAddress).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]v1alpha1.ACLStatusResolvedDestination{
+		{
+			Index:       0,
+			RuleID:      "rule-1",
+			Destination: "externalDNS myapp.io",
+			HasRules:    true,
+			CIDRs:       []string{"1.1.1.1/32", "2.2.2.2/32"},
+		},
+		{
+			Index:       1,
+			Destination: "tsuruApp my-other-app",
+			HasRules:    true,
+			CIDRs:       []string{"3.3.3.3/32"},
+			Selectors: []string{
+				"pods tsuru.io/app-name=my-other-app in namespaces name=tsuru-my-pool",
+			},
+		},
+		{
+			Index:       2,
+			Destination: "externalDNS *.example.com",
+		},
+		{
+			Index:       3,
+			Destination: "deny 10.0.0.0/8",
+			HasRules:    true,
+			CIDRs:       []string{"0.0.0.0/0 except 10.0.0.0/8"},
+		},
+	}, existingACL.Status.ResolvedDestinations)
+
+	// a destination that fails to resolve keeps the stale rules
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(dnsEntry), dnsEntry)
+	suite.Require().NoError(err)
+	dnsEntry.Status = v1alpha1.ACLDNSEntryStatus{Reason: "no such host"}
+	err = reconciler.Client.Status().Update(ctx, dnsEntry)
+	suite.Require().NoError(err)
+
+	_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Require().Len(existingACL.Status.ResolvedDestinations, 4)
+	suite.Assert().Equal(v1alpha1.ACLStatusResolvedDestination{
+		Index:       0,
+		RuleID:      "rule-1",
+		Destination: "externalDNS myapp.io",
+		HasRules:    true,
+		Stale:       true,
+		CIDRs:       []string{"1.1.1.1/32", "2.2.2.2/32"},
+	}, existingACL.Status.ResolvedDestinations[0])
+}
+
+func (suite *ControllerSuite) TestACLReconcilerDryRun() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "1.1.1.1",
+					},
+				},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+		DryRun:   true,
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().False(existingACL.Status.Ready)
+	suite.Assert().True(existingACL.Status.DryRun)
+	suite.Assert().Equal("dry-run: NetworkPolicy acl-myapp is not applied, changes on status.dryRunDiff", existingACL.Status.Reason)
+	suite.Assert().Contains(existingACL.Status.DryRunDiff, "1.1.1.1/32")
+
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-myapp"}, &netv1.NetworkPolicy{})
+	suite.Assert().True(k8sErrors.IsNotFound(err))
+
+	reconciler.DryRun = false
+	_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().True(existingACL.Status.Ready)
+	suite.Assert().False(existingACL.Status.DryRun)
+	suite.Assert().Empty(existingACL.Status.DryRunDiff)
+
+	// without changes the diff is empty
+	reconciler.DryRun = true
+	_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().True(existingACL.Status.DryRun)
+	suite.Assert().Empty(existingACL.Status.DryRunDiff)
+	suite.Assert().Equal("dry-run: NetworkPolicy acl-myapp is not applied, no changes", existingACL.Status.Reason)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerCiliumBackend() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalDNS: &v1alpha1.ACLSpecExternalDNS{
+						Name: "*.example.com",
+						Ports: v1alpha1.ACLSpecProtoPorts{
+							{Protocol: "tcp", Number: 443},
+						},
+					},
+				},
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "1.1.1.1",
+					},
+				},
+			},
+		},
+	}
+
+	cli := withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build())
+	reconciler := &ACLReconciler{
+		Client:        cli,
+		Scheme:        scheme.Scheme,
+		Resolver:      &fakeResolver{},
+		TsuruAPI:      &fakeTsuruAPI{},
+		PolicyBackend: &ciliumPolicyBackend{Client: cli},
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	existingACL := &v1alpha1.ACL{}
+	err = cli.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().True(existingACL.Status.Ready)
+	suite.Assert().Equal(PolicyBackendCilium, existingACL.Status.PolicyBackend)
+	suite.Assert().Empty(existingACL.Status.Warnings)
+
+	dnsEntries := &v1alpha1.ACLDNSEntryList{}
+	err = cli.List(ctx, dnsEntries)
+	suite.Require().NoError(err)
+	suite.Assert().Empty(dnsEntries.Items)
+
+	ciliumPolicy := reconciler.PolicyBackend.NewObject()
+	err = cli.Get(ctx, client.ObjectKey{
+		Namespace: existingACL.Namespace,
+		Name:      existingACL.Status.NetworkPolicy,
+	}, ciliumPolicy)
+	suite.Require().NoError(err)
+	suite.Assert().Len(ciliumPolicy.GetOwnerReferences(), 1)
+
+	spec, err := json.Marshal(ciliumPolicy.(*unstructured.Unstructured).Object["spec"])
+	suite.Require().NoError(err)
+	suite.Assert().JSONEq(`{
+		"endpointSelector": {"matchLabels": {"tsuru.io/app-name": "myapp"}},
+		"egress": [
+			{
+				"toFQDNs": [{"matchPattern": "*.example.com"}],
+				"toPorts": [{"ports": [{"port": "443", "protocol": "TCP"}]}]
+			},
+			{
+				"toEndpoints": [{"matchLabels": {"k8s:io.kubernetes.pod.namespace": "kube-system", "k8s:k8s-app": "kube-dns"}}],
+				"toPorts": [{"ports": [{"port": "53", "protocol": "ANY"}], "rules": {"dns": [{"matchPattern": "*"}]}}]
+			},
+			{
+				"toCIDRSet": [{"cidr": "1.1.1.1/32"}]
+			}
+		]
+	}`, string(spec))
+
+	_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	secondPolicy := reconciler.PolicyBackend.NewObject()
+	err = cli.Get(ctx, client.ObjectKeyFromObject(ciliumPolicy), secondPolicy)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(ciliumPolicy.GetResourceVersion(), secondPolicy.GetResourceVersion())
+}
+
+func (suite *ControllerSuite) TestACLReconcilerExternalDNSResolver() {
+	ctx := context.Background()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	defer conn.Close()
+
+	go serveFakeDNS(conn, map[dnsmessage.Type][]dnsmessage.Resource{
+		dnsmessage.TypeA: {
+			{
+				Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 300},
+				Body:   &dnsmessage.AResource{A: [4]byte{10, 2, 2, 2}},
+			},
+		},
+	})
+
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalDNS: &v1alpha1.ACLSpecExternalDNS{
+						Name: "internal.example.com",
+						Resolver: &v1alpha1.ACLSpecDNSResolver{
+							Nameservers: []string{conn.LocalAddr().String()},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client: withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme: scheme.Scheme,
+		// the default resolver does not know the host
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	for i := 0; i < 2; i++ {
+		_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      "myapp",
+				Namespace: "default",
+			},
+		})
+		suite.Require().NoError(err)
+		reconcileAddressObjects(ctx, suite.T(), reconciler)
+	}
+
+	dnsEntry := &v1alpha1.ACLDNSEntry{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Name: dnsEntryName("internal.example.com", []string{conn.LocalAddr().String()}, "")}, dnsEntry)
+	suite.Require().NoError(err)
+	suite.Assert().NotEqual(validResourceName("internal.example.com"), dnsEntry.Name)
+	suite.Assert().Equal([]string{conn.LocalAddr().String()}, dnsEntry.Spec.Nameservers)
+	suite.Assert().True(dnsEntry.Status.Ready)
+
+	existingNP := &netv1.NetworkPolicy{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-myapp"}, existingNP)
+	suite.Require().NoError(err)
+	suite.Require().Len(existingNP.Spec.Egress, 1)
+	suite.Assert().Equal([]netv1.NetworkPolicyPeer{
+		{IPBlock: &netv1.IPBlock{CIDR: "10.2.2.2/32"}},
+	}, existingNP.Spec.Egress[0].To)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerPolicyMetadata() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "1.1.1.1/32",
+					},
+				},
+			},
+			Template: &v1alpha1.ACLSpecTemplate{
+				Metadata: v1alpha1.ACLSpecTemplateMetadata{
+					Labels:      map[string]string{"team": "a-team"},
+					Annotations: map[string]string{"owner": "a-team@example.com", "runbook": "https://example.com"},
+				},
+			},
+		},
+	}
+	existingNP := &netv1.NetworkPolicy{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        "acl-myapp",
+			Namespace:   "default",
+			Labels:      map[string]string{policyManagedByLabel: policyManagedByValue},
+			Annotations: map[string]string{"monitoring.example.com/scrape": "true"},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl, existingNP).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	reconcile := func() *netv1.NetworkPolicy {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      "myapp",
+				Namespace: "default",
+			},
+		})
+		suite.Require().NoError(err)
+
+		np := &netv1.NetworkPolicy{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(existingNP), np)
+		suite.Require().NoError(err)
+		return np
+	}
+
+	np := reconcile()
+	suite.Assert().Equal(map[string]string{"team": "a-team", policyManagedByLabel: policyManagedByValue, policyACLLabel: "myapp"}, np.Labels)
+	suite.Assert().Equal(map[string]string{
+		"monitoring.example.com/scrape": "true",
+		"owner":                         "a-team@example.com",
+		"runbook":                       "https://example.com",
+	}, np.Annotations)
+
+	// keys removed from the template are removed from the policy, others are kept
+	err := reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), acl)
+	suite.Require().NoError(err)
+	acl.Spec.Template.Metadata = v1alpha1.ACLSpecTemplateMetadata{
+		Annotations: map[string]string{"owner": "b-team@example.com"},
+	}
+	err = reconciler.Client.Update(ctx, acl)
+	suite.Require().NoError(err)
+
+	np = reconcile()
+	suite.Assert().Equal(map[string]string{policyManagedByLabel: policyManagedByValue, policyACLLabel: "myapp"}, np.Labels)
+	suite.Assert().Equal(map[string]string{
+		"monitoring.example.com/scrape": "true",
+		"owner":                         "b-team@example.com",
+	}, np.Annotations)
+
+	// the policy is not updated when the metadata is up to date
+	secondNP := reconcile()
+	suite.Assert().Equal(np.ResourceVersion, secondNP.ResourceVersion)
+
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), acl)
+	suite.Require().NoError(err)
+	acl.Spec.Template = nil
+	err = reconciler.Client.Update(ctx, acl)
+	suite.Require().NoError(err)
+
+	np = reconcile()
+	suite.Assert().Equal(map[string]string{"monitoring.example.com/scrape": "true"}, np.Annotations)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerSharedDNSEntryIgnoresCase() {
+	ctx := context.Background()
+	newACL := func(name, host string) *v1alpha1.ACL {
+		return &v1alpha1.ACL{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: v1alpha1.ACLSpec{
+				Source: v1alpha1.ACLSpecSource{
+					TsuruApp: name,
+				},
+				Destinations: []v1alpha1.ACLSpecDestination{
+					{
+						ExternalDNS: &v1alpha1.ACLSpecExternalDNS{
+							Name: host,
+						},
+					},
+				},
+			},
+		}
+	}
+	upperACL := newACL("upper", "Example.COM")
+	lowerACL := newACL("lower", "example.com")
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(upperACL, lowerACL).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	for _, acl := range []*v1alpha1.ACL{upperACL, lowerACL} {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+	}
+
+	dnsEntries := &v1alpha1.ACLDNSEntryList{}
+	err := reconciler.Client.List(ctx, dnsEntries)
+	suite.Require().NoError(err)
+	suite.Require().Len(dnsEntries.Items, 1)
+	suite.Assert().Equal("example.com", dnsEntries.Items[0].Name)
+	suite.Assert().Equal("example.com", dnsEntries.Items[0].Spec.Host)
+}
+
+// managerInformers are fake informers for a manager, the sources of its controllers start at once
+// so the informers are locked, the metadata watches of ConfigMaps and workloads are served as well,
+// FakeInformers only knows the kinds of the types of scheme
+type managerInformers struct {
+	*informertest.FakeInformers
+	mu sync.Mutex
+}
+
+func (c *managerInformers) GetInformer(ctx context.Context, obj client.Object) (cache.Informer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var informer cache.Informer
+	var err error
+	if metadata, ok := obj.(*metav1.PartialObjectMetadata); ok {
+		informer, err = c.FakeInformers.GetInformerForKind(ctx, metadata.GroupVersionKind())
+	} else {
+		informer, err = c.FakeInformers.GetInformer(ctx, obj)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &lockedInformer{Informer: informer, mu: &c.mu}, nil
+}
+
+// delete sends the deletion of obj to the handlers of its informer
+func (c *managerInformers) delete(obj client.Object) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	informer, err := c.FakeInformers.FakeInformerFor(obj)
+	if err != nil {
+		return err
+	}
+	informer.Delete(obj)
+	return nil
+}
+
+type lockedInformer struct {
+	cache.Informer
+	mu *sync.Mutex
+}
+
+func (i *lockedInformer) AddEventHandler(handler toolscache.ResourceEventHandler) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.Informer.AddEventHandler(handler)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerOwnedPolicyChanges() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		TypeMeta: v1.TypeMeta{
+			APIVersion: v1alpha1.GroupVersion.String(),
+			Kind:       "ACL",
+		},
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+			UID:       "acl-uid",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "1.1.1.1/32",
+					},
+				},
+			},
+		},
+	}
+	// a policy owned by something else, without controller, is taken over by the ACL when forced
+	existingNP := &netv1.NetworkPolicy{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "acl-myapp",
+			Namespace: "default",
+			OwnerReferences: []v1.OwnerReference{
+				{APIVersion: "v1", Kind: "ConfigMap", Name: "other", UID: "other-uid"},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:               withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl, existingNP).Build()),
+		Scheme:               scheme.Scheme,
+		Resolver:             &fakeResolver{},
+		ForcePolicyOwnership: true,
+		TsuruAPI:             &fakeTsuruAPI{},
+	}
+	reconcile := func() {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+	}
+
+	reconcile()
+	np := &netv1.NetworkPolicy{}
+	err := reconciler.Client.Get(ctx, client.ObjectKeyFromObject(existingNP), np)
+	suite.Require().NoError(err)
+	suite.Require().Len(np.OwnerReferences, 2)
+	suite.Assert().Equal("other", np.OwnerReferences[0].Name)
+	suite.Assert().True(v1.IsControlledBy(np, acl))
+
+	// the Owns watch of SetupWithManager enqueues the controller of a deleted policy, the manager runs
+	// on fake informers since envtest binaries are not available to the tests of this package
+	informers := &managerInformers{FakeInformers: &informertest.FakeInformers{Scheme: scheme.Scheme}}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(v1alpha1.GroupVersion.WithKind("ACL"), meta.RESTScopeNamespace)
+	mgr, err := controllerruntime.NewManager(&rest.Config{Host: "http://127.0.0.1:0"}, controllerruntime.Options{
+		Scheme:             scheme.Scheme,
+		MetricsBindAddress: "0",
+		MapperProvider:     func(*rest.Config) (meta.RESTMapper, error) { return mapper, nil },
+		NewCache: func(*rest.Config, cache.Options) (cache.Cache, error) {
+			return informers, nil
+		},
+		NewClient: func(cache.Cache, *rest.Config, client.Options, ...client.Object) (client.Client, error) {
+			return reconciler.Client, nil
+		},
+	})
+	suite.Require().NoError(err)
+	reconciler.Recorder = record.NewFakeRecorder(10)
+	suite.Require().NoError(reconciler.SetupWithManager(mgr))
+
+	mgrCtx, cancel := context.WithCancel(ctx)
+	mgrDone := make(chan error)
+	go func() {
+		mgrDone <- mgr.Start(mgrCtx)
+	}()
+	defer func() {
+		cancel()
+		suite.Require().NoError(<-mgrDone)
+	}()
+
+	err = reconciler.Client.Delete(ctx, np)
+	suite.Require().NoError(err)
+
+	// the event is sent again until the watch, registered once the manager starts, enqueues the ACL
+	suite.Require().Eventually(func() bool {
+		suite.Require().NoError(informers.delete(np))
+		return reconciler.Client.Get(ctx, client.ObjectKeyFromObject(existingNP), &netv1.NetworkPolicy{}) == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	reconcile()
+	np = &netv1.NetworkPolicy{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(existingNP), np)
+	suite.Require().NoError(err)
+	suite.Assert().True(v1.IsControlledBy(np, acl))
+	suite.Require().Len(np.Spec.Egress, 1)
+	suite.Assert().Equal("1.1.1.1/32", np.Spec.Egress[0].To[0].IPBlock.CIDR)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerNamespacedDNSEntries() {
+	ctx := context.Background()
+	newACL := func(namespace string) *v1alpha1.ACL {
+		return &v1alpha1.ACL{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "myapp",
+				Namespace: namespace,
+			},
+			Spec: v1alpha1.ACLSpec{
+				Source: v1alpha1.ACLSpecSource{
+					TsuruApp: "myapp",
+				},
+				Destinations: []v1alpha1.ACLSpecDestination{
+					{
+						ExternalDNS: &v1alpha1.ACLSpecExternalDNS{
+							Name: "example.com",
+						},
+					},
+				},
+			},
+		}
+	}
+	teamA, teamB := newACL("team-a"), newACL("team-b")
+
+	reconciler := &ACLReconciler{
+		Client: withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(teamA, teamB).Build()),
+		Scheme: scheme.Scheme,
+		Resolver: &fakeResolver{
+			hosts: map[string][]string{
+				"example.com": {"1.1.1.1"},
+			},
+		},
+		TsuruAPI:             &fakeTsuruAPI{},
+		NamespacedDNSEntries: true,
+	}
+	reconcileACLs := func() {
+		for _, acl := range []*v1alpha1.ACL{teamA, teamB} {
+			_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+				NamespacedName: client.ObjectKeyFromObject(acl),
+			})
+			suite.Require().NoError(err)
+		}
+	}
+
+	reconcileACLs()
+	reconcileAddressObjects(ctx, suite.T(), reconciler)
+	reconcileACLs()
+
+	dnsEntries := &v1alpha1.ACLDNSEntryList{}
+	err := reconciler.Client.List(ctx, dnsEntries)
+	suite.Require().NoError(err)
+	suite.Require().Len(dnsEntries.Items, 2)
+	for _, namespace := range []string{"team-a", "team-b"} {
+		dnsEntry := &v1alpha1.ACLDNSEntry{}
+		err = reconciler.Client.Get(ctx, client.ObjectKey{Name: dnsEntryName("example.com", nil, namespace)}, dnsEntry)
+		suite.Require().NoError(err)
+		suite.Assert().Equal("example.com", dnsEntry.Spec.Host)
+		suite.Assert().Equal(namespace, dnsEntry.Spec.Namespace)
+		suite.Assert().Equal([]string{aclOwnerKey(&v1alpha1.ACL{ObjectMeta: v1.ObjectMeta{Namespace: namespace, Name: "myapp"}})}, aclOwners(dnsEntry))
+
+		np := &netv1.NetworkPolicy{}
+		err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "acl-myapp"}, np)
+		suite.Require().NoError(err)
+		suite.Require().Len(np.Spec.Egress, 1)
+		suite.Assert().Equal("1.1.1.1/32", np.Spec.Egress[0].To[0].IPBlock.CIDR)
+	}
+}
+
+func (suite *ControllerSuite) TestACLReconcilerClusterDNSEgress() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "1.1.1.1/32",
+					},
+				},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:           withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:           scheme.Scheme,
+		Resolver:         &fakeResolver{},
+		TsuruAPI:         &fakeTsuruAPI{},
+		ClusterDNSEgress: true,
+	}
+	reconcile := func() *netv1.NetworkPolicy {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      "myapp",
+				Namespace: "default",
+			},
+		})
+		suite.Require().NoError(err)
+
+		np := &netv1.NetworkPolicy{}
+		err = reconciler.Client.Get(ctx, types.NamespacedName{Name: "acl-myapp", Namespace: "default"}, np)
+		suite.Require().NoError(err)
+		return np
+	}
+
+	dnsPort := intstr.FromInt(53)
+	udp := corev1.ProtocolUDP
+	tcp := corev1.ProtocolTCP
+	dnsPorts := []netv1.NetworkPolicyPort{
+		{Protocol: &udp, Port: &dnsPort},
+		{Protocol: &tcp, Port: &dnsPort},
+	}
+
+	np := reconcile()
+	suite.Require().Len(np.Spec.Egress, 2)
+	suite.Assert().Equal("1.1.1.1/32", np.Spec.Egress[0].To[0].IPBlock.CIDR)
+	suite.Assert().Equal(netv1.NetworkPolicyEgressRule{
+		To: []netv1.NetworkPolicyPeer{
+			{
+				NamespaceSelector: &v1.LabelSelector{MatchLabels: map[string]string{"name": "kube-system"}},
+				PodSelector:       &v1.LabelSelector{MatchLabels: map[string]string{"k8s-app": "kube-dns"}},
+			},
+		},
+		Ports: dnsPorts,
+	}, np.Spec.Egress[1])
+
+	reconciler.ClusterDNS = &ClusterDNS{
+		Namespace: "dns",
+		PodLabels: map[string]string{"app": "coredns"},
+	}
+	np = reconcile()
+	suite.Require().Len(np.Spec.Egress, 2)
+	suite.Assert().Equal(netv1.NetworkPolicyEgressRule{
+		To: []netv1.NetworkPolicyPeer{
+			{
+				NamespaceSelector: &v1.LabelSelector{MatchLabels: map[string]string{"name": "dns"}},
+				PodSelector:       &v1.LabelSelector{MatchLabels: map[string]string{"app": "coredns"}},
+			},
+		},
+		Ports: dnsPorts,
+	}, np.Spec.Egress[1])
+
+	reconciler.ClusterDNSEgress = false
+	np = reconcile()
+	suite.Require().Len(np.Spec.Egress, 1)
+	suite.Assert().Equal("1.1.1.1/32", np.Spec.Egress[0].To[0].IPBlock.CIDR)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerDestinationTsuruAppPool() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					TsuruAppPool: "my-pool",
+				},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client: withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme: scheme.Scheme,
+		Resolver: &fakeResolver{
+			hosts: map[string][]string{
+				"myapp.io":      {"10.1.1.2"},
+				"http.myapp.io": {"10.1.1.3"},
+			},
+		},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	result, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+	})
+	suite.Require().NoError(err)
+	suite.Assert().Equal(pendingAddressRequeueInterval, result.RequeueAfter)
+
+	tsuruAppAddress := &v1alpha1.TsuruAppAddress{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Name: "my-other-app"}, tsuruAppAddress)
+	suite.Require().NoError(err)
+
+	reconcileAddressObjects(ctx, suite.T(), reconciler)
+	result, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+	})
+	suite.Require().NoError(err)
+	suite.Assert().Equal(DefaultRequeueInterval, result.RequeueAfter)
+
+	existingNP := &netv1.NetworkPolicy{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-myapp"}, existingNP)
+	suite.Require().NoError(err)
+	suite.Require().Len(existingNP.Spec.Egress, 1)
+	suite.Assert().ElementsMatch([]netv1.NetworkPolicyPeer{
+		{IPBlock: &netv1.IPBlock{CIDR: "10.1.1.2/32"}},
+		{IPBlock: &netv1.IPBlock{CIDR: "10.1.1.3/32"}},
+		reconciler.tsuruAppPoolPeers("my-pool")[0],
+		reconciler.tsuruAppPoolPeers("my-pool")[1],
+	}, existingNP.Spec.Egress[0].To)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerDestinationTsuruAppPoolExcept() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					TsuruAppPool:       "my-pool",
+					TsuruAppPoolExcept: []string{"my-other-app", "legacy-app"},
+				},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	result, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+	})
+	suite.Require().NoError(err)
+	suite.Assert().Equal(DefaultRequeueInterval, result.RequeueAfter)
+
+	// the router addresses of excluded apps are not needed
+	tsuruAppAddress := &v1alpha1.TsuruAppAddress{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Name: "my-other-app"}, tsuruAppAddress)
+	suite.Assert().True(k8sErrors.IsNotFound(err))
+
+	expectedSelector := &v1.LabelSelector{
+		MatchLabels: map[string]string{"tsuru.io/app-pool": "my-pool"},
+		MatchExpressions: []v1.LabelSelectorRequirement{
+			{Key: "tsuru.io/app-name", Operator: v1.LabelSelectorOpNotIn, Values: []string{"legacy-app", "my-other-app"}},
+		},
+	}
+
+	existingNP := &netv1.NetworkPolicy{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-myapp"}, existingNP)
+	suite.Require().NoError(err)
+	suite.Require().Len(existingNP.Spec.Egress, 1)
+	suite.Assert().ElementsMatch([]netv1.NetworkPolicyPeer{
+		{PodSelector: expectedSelector},
+		{PodSelector: expectedSelector, NamespaceSelector: reconciler.namespaceSelector("tsuru-my-pool")},
+	}, existingNP.Spec.Egress[0].To)
+
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Require().Len(existingACL.Status.ResolvedDestinations, 1)
+	suite.Assert().Equal("tsuruAppPool my-pool except my-other-app,legacy-app", existingACL.Status.ResolvedDestinations[0].Destination)
+
+	invalid := v1alpha1.ACLSpecDestination{TsuruApp: "my-app", TsuruAppPoolExcept: []string{"my-other-app"}}
+	suite.Assert().EqualError(invalid.Validate(), "tsuruAppPoolExcept requires tsuruAppPool")
+
+	invalid = v1alpha1.ACLSpecDestination{TsuruAppPool: "my-pool", TsuruAppPoolExcept: []string{"My_App"}}
+	suite.Assert().ErrorContains(invalid.Validate(), `invalid app "My_App" of tsuruAppPoolExcept`)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerDestinationTsuruAppRouters() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					TsuruApp:        "my-other-app",
+					TsuruAppRouters: []string{"http-router"},
+				},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client: withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme: scheme.Scheme,
+		Resolver: &fakeResolver{
+			hosts: map[string][]string{
+				"myapp.io":      {"10.1.1.2"},
+				"http.myapp.io": {"10.1.1.3"},
+			},
+		},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	// the address of every router of app is kept apart, it is shared by tsuruAppPool destinations
+	tsuruAppAddress := &v1alpha1.TsuruAppAddress{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Name: "my-other-app"}, tsuruAppAddress)
+	suite.Assert().True(k8sErrors.IsNotFound(err))
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Name: tsuruAppAddressName("my-other-app", []string{"http-router"})}, tsuruAppAddress)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("my-other-app", tsuruAppAddress.Spec.Name)
+	suite.Assert().Equal([]string{"http-router"}, tsuruAppAddress.Spec.Routers)
+
+	reconcileAddressObjects(ctx, suite.T(), reconciler)
+	_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Name: tsuruAppAddressName("my-other-app", []string{"http-router"})}, tsuruAppAddress)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]string{"10.1.1.3"}, tsuruAppAddress.Status.IPs)
+
+	existingNP := &netv1.NetworkPolicy{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-myapp"}, existingNP)
+	suite.Require().NoError(err)
+	suite.Require().Len(existingNP.Spec.Egress, 1)
+	suite.Assert().ElementsMatch([]netv1.NetworkPolicyPeer{
+		{PodSelector: reconciler.podSelectorForTsuruApp("my-other-app"), NamespaceSelector: reconciler.namespaceSelector("tsuru-my-pool")},
+		{IPBlock: &netv1.IPBlock{CIDR: "10.1.1.3/32"}},
+	}, existingNP.Spec.Egress[0].To)
+
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Require().Len(existingACL.Status.ResolvedDestinations, 1)
+	suite.Assert().Equal("tsuruApp my-other-app routers http-router", existingACL.Status.ResolvedDestinations[0].Destination)
+
+	invalid := v1alpha1.ACLSpecDestination{TsuruAppPool: "my-pool", TsuruAppRouters: []string{"http-router"}}
+	suite.Assert().EqualError(invalid.Validate(), "tsuruAppRouters requires tsuruApp")
+
+	invalid = v1alpha1.ACLSpecDestination{TsuruApp: "my-other-app", TsuruAppRouters: []string{"http-router,https-router"}}
+	suite.Assert().EqualError(invalid.Validate(), `invalid router "http-router,https-router" of tsuruAppRouters, router names must not be empty nor have commas or spaces`)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerDestinationTsuruAppServiceIPs() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					TsuruApp:        "my-other-app",
+					TsuruAppProcess: "web",
+				},
+			},
+		},
+	}
+
+	tsuruAppAddress := &v1alpha1.TsuruAppAddress{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "my-other-app",
+		},
+		Spec: v1alpha1.TsuruAppAddressSpec{
+			Name: "my-other-app",
+		},
+		Status: v1alpha1.ResourceAddressStatus{
+			Ready: true,
+			Pool:  "my-pool",
+			IPs: []string{
+				"3.3.3.3",
+			},
+		},
+	}
+
+	webLabels := map[string]string{"tsuru.io/app-name": "my-other-app", "tsuru.io/app-process": "web"}
+	webService := &corev1.Service{
+		ObjectMeta: v1.ObjectMeta{Name: "my-other-app-web", Namespace: "tsuru-my-pool", Labels: webLabels},
+		Spec:       corev1.ServiceSpec{ClusterIP: "10.96.0.10", Selector: webLabels},
+	}
+	headlessService := &corev1.Service{
+		ObjectMeta: v1.ObjectMeta{Name: "my-other-app-web-units", Namespace: "tsuru-my-pool", Labels: webLabels},
+		Spec:       corev1.ServiceSpec{ClusterIP: corev1.ClusterIPNone, Selector: webLabels},
+	}
+	workerService := &corev1.Service{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "my-other-app-worker",
+			Namespace: "tsuru-my-pool",
+			Labels:    map[string]string{"tsuru.io/app-name": "my-other-app", "tsuru.io/app-process": "worker"},
+		},
+		Spec: corev1.ServiceSpec{ClusterIP: "10.96.0.11"},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:             withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl, tsuruAppAddress, webService, headlessService, workerService).Build()),
+		Scheme:             scheme.Scheme,
+		Resolver:           &fakeResolver{},
+		TsuruAPI:           &fakeTsuruAPI{},
+		TsuruAppServiceIPs: true,
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	existingNP := &netv1.NetworkPolicy{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-myapp"}, existingNP)
+	suite.Require().NoError(err)
+
+	ipBlocks := []string{}
+	for _, rule := range existingNP.Spec.Egress {
+		for _, peer := range rule.To {
+			if peer.IPBlock != nil {
+				ipBlocks = append(ipBlocks, peer.IPBlock.CIDR)
+			}
+		}
+	}
+	// only the service of the process of destination, the headless service has no cluster IP
+	suite.Assert().ElementsMatch([]string{"3.3.3.3/32", "10.96.0.10/32"}, ipBlocks)
+
+	// the cluster IPs are not allowed by default
+	reconciler.TsuruAppServiceIPs = false
+	_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-myapp"}, existingNP)
+	suite.Require().NoError(err)
+	for _, rule := range existingNP.Spec.Egress {
+		for _, peer := range rule.To {
+			if peer.IPBlock != nil {
+				suite.Assert().NotEqual("10.96.0.10/32", peer.IPBlock.CIDR)
+			}
+		}
+	}
+}
+
+func (suite *ControllerSuite) TestACLReconcilerDestinationTsuruAppProcess() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					TsuruApp:        "my-other-app",
+					TsuruAppProcess: "web",
+				},
+			},
+		},
+	}
+
+	tsuruAppAddress := &v1alpha1.TsuruAppAddress{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "my-other-app",
+		},
+		Spec: v1alpha1.TsuruAppAddressSpec{
+			Name: "my-other-app",
+		},
+		Status: v1alpha1.ResourceAddressStatus{
+			Ready: true,
+			Pool:  "my-pool",
+			IPs: []string{
+				"3.3.3.3",
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl, tsuruAppAddress).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	processSelector := &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			"tsuru.io/app-name":    "my-other-app",
+			"tsuru.io/app-process": "web",
+		},
+	}
+
+	existingNP := &netv1.NetworkPolicy{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-myapp"}, existingNP)
+	suite.Require().NoError(err)
+	suite.Require().Len(existingNP.Spec.Egress, 1)
+	suite.Assert().ElementsMatch([]netv1.NetworkPolicyPeer{
+		{PodSelector: processSelector, NamespaceSelector: reconciler.namespaceSelector("tsuru-my-pool")},
+		{IPBlock: &netv1.IPBlock{CIDR: "3.3.3.3/32"}},
+	}, existingNP.Spec.Egress[0].To)
+
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Require().Len(existingACL.Status.ResolvedDestinations, 1)
+	suite.Assert().Equal("tsuruApp my-other-app process web", existingACL.Status.ResolvedDestinations[0].Destination)
+
+	invalid := v1alpha1.ACLSpecDestination{TsuruAppProcess: "web", TsuruAppPool: "my-pool"}
+	suite.Assert().EqualError(invalid.Validate(), "tsuruAppProcess requires tsuruApp")
+}
+
+func TestTsuruAppPeers(t *testing.T) {
+	reconciler := &ACLReconciler{}
+	podSelector := reconciler.podSelectorForTsuruApp("my-app")
+
+	// the pool is not known yet
+	assert.Equal(t, []netv1.NetworkPolicyPeer{
+		{PodSelector: podSelector},
+	}, reconciler.tsuruAppPeers(podSelector, "", "default"))
+
+	assert.Equal(t, []netv1.NetworkPolicyPeer{
+		{PodSelector: podSelector, NamespaceSelector: reconciler.namespaceSelector("tsuru-my-pool")},
+	}, reconciler.tsuruAppPeers(podSelector, "my-pool", "default"))
+
+	// the app runs on the namespace of policy, which may not have the namespace label
+	assert.Equal(t, []netv1.NetworkPolicyPeer{
+		{PodSelector: podSelector},
+		{PodSelector: podSelector, NamespaceSelector: reconciler.namespaceSelector("tsuru-my-pool")},
+	}, reconciler.tsuruAppPeers(podSelector, "my-pool", "tsuru-my-pool"))
+}
+
+func TestACLSpecDNSResolverAddresses(t *testing.T) {
+	resolver := &v1alpha1.ACLSpecDNSResolver{
+		Nameservers: []string{"10.0.0.2", "10.0.0.1:5353", "fd00::1", "10.0.0.2:53"},
+	}
+	addresses, err := resolver.Addresses()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1:5353", "10.0.0.2:53", "[fd00::1]:53"}, addresses)
+
+	resolver = &v1alpha1.ACLSpecDNSResolver{
+		Nameservers: []string{"tls://1.1.1.1", "tls://dns.Example.com:8853", "https://DNS.example.com", "https://[2001:db8::1]:8443/resolve", "tls://1.1.1.1:853"},
+	}
+	addresses, err = resolver.Addresses()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://[2001:db8::1]:8443/resolve", "https://dns.example.com/dns-query", "tls://1.1.1.1:853", "tls://dns.example.com:8853"}, addresses)
+
+	for _, nameserver := range []string{"ns1.example.com", "10.0.0.1:0", "10.0.0.1:dns", "tls://dns.example.com/path", "https://user@dns.example.com", "https://dns.example.com/?name=x", "tls://", "udp://10.0.0.1"} {
+		resolver = &v1alpha1.ACLSpecDNSResolver{Nameservers: []string{nameserver}}
+		_, err = resolver.Addresses()
+		assert.EqualError(t, err, fmt.Sprintf("invalid nameserver %q, use an IP address with an optional port, a tls:// or an https:// URL", nameserver))
+	}
+
+	_, err = (&v1alpha1.ACLSpecDNSResolver{}).Addresses()
+	assert.EqualError(t, err, "resolver requires at least one nameserver")
+}
+
+// reconcileAddressObjects runs the controllers of address objects created by the ACLReconciler,
+// their statuses are filled asynchronously on a real cluster
+func (suite *ControllerSuite) TestACLReconcilerDestinationConfigMap() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ConfigMap: &v1alpha1.ACLSpecConfigMap{
+						Name:  "egress-allowlist",
+						Key:   "addresses",
+						Ports: v1alpha1.ACLSpecProtoPorts{{Protocol: "TCP", Number: 443}},
+					},
+				},
+			},
+		},
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "egress-allowlist",
+			Namespace: "default",
+		},
+		Data: map[string]string{
+			"addresses": "# corporate services\n10.0.0.0/24, 192.168.1.1\nAPI.example.com # payments\n10.0.0.0/24\n",
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client: withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl, configMap).Build()),
+		Scheme: scheme.Scheme,
+		Resolver: &fakeResolver{
+			hosts: map[string][]string{
+				"api.example.com": {"10.1.1.9"},
+			},
+		},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	// hostnames of the list are resolved by ACLDNSEntry objects
+	dnsEntry := &v1alpha1.ACLDNSEntry{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Name: dnsEntryName("api.example.com", nil, "")}, dnsEntry)
+	suite.Require().NoError(err)
+
+	reconcileAddressObjects(ctx, suite.T(), reconciler)
+	_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	egressPeers := func() []netv1.NetworkPolicyPeer {
+		existingNP := &netv1.NetworkPolicy{}
+		err := reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-myapp"}, existingNP)
+		suite.Require().NoError(err)
+		peers := []netv1.NetworkPolicyPeer{}
+		for _, rule := range existingNP.Spec.Egress {
+			suite.Require().Len(rule.Ports, 1)
+			suite.Assert().Equal(int32(443), rule.Ports[0].Port.IntVal)
+			peers = append(peers, rule.To...)
+		}
+		return peers
+	}
+	suite.Assert().ElementsMatch([]netv1.NetworkPolicyPeer{
+		{IPBlock: &netv1.IPBlock{CIDR: "10.0.0.0/24"}},
+		{IPBlock: &netv1.IPBlock{CIDR: "192.168.1.1/32"}},
+		{IPBlock: &netv1.IPBlock{CIDR: "10.1.1.9/32"}},
+	}, egressPeers())
+
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Name: dnsEntryName("api.example.com", nil, "")}, dnsEntry)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]string{aclOwnerKey(acl)}, aclOwners(dnsEntry))
+
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().True(existingACL.Status.Ready)
+	suite.Require().Len(existingACL.Status.ResolvedDestinations, 1)
+	suite.Assert().Equal("configMap egress-allowlist key addresses", existingACL.Status.ResolvedDestinations[0].Destination)
+
+	// changes of the ConfigMap reconcile the ACLs that reference it
+	suite.Assert().Equal([]reconcile.Request{{NamespacedName: client.ObjectKeyFromObject(acl)}}, reconciler.reconcileRequestsForIndex(configMapIndex, "default/egress-allowlist"))
+	suite.Assert().Empty(reconciler.reconcileRequestsForIndex(configMapIndex, "other/egress-allowlist"))
+
+	configMap.Data["addresses"] = "172.16.0.0/16"
+	err = reconciler.Client.Update(ctx, configMap)
+	suite.Require().NoError(err)
+	_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]netv1.NetworkPolicyPeer{
+		{IPBlock: &netv1.IPBlock{CIDR: "172.16.0.0/16"}},
+	}, egressPeers())
+
+	configMap.Data["addresses"] = "10.0.0.1\nnot_a_host"
+	err = reconciler.Client.Update(ctx, configMap)
+	suite.Require().NoError(err)
+	_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().False(existingACL.Status.Ready)
+	suite.Assert().Contains(existingACL.Status.Reason, `invalid key "addresses" of configMap default/egress-allowlist: invalid address on line 2, use an IP, a CIDR or a hostname`)
+	suite.Assert().NotContains(existingACL.Status.Reason, "not_a_host")
+
+	// ConfigMaps of other namespaces are never read
+	foreignConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "egress-allowlist", Namespace: "infra"},
+		Data:       map[string]string{"addresses": "10.9.9.9"},
+	}
+	err = reconciler.Client.Create(ctx, foreignConfigMap)
+	suite.Require().NoError(err)
+	existingACL.Spec.Destinations[0].ConfigMap.Namespace = "infra"
+	err = reconciler.Client.Update(ctx, existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().ErrorContains(existingACL.ValidateUpdate(acl), `spec.destinations[0].configMap.namespace: Forbidden: only ConfigMaps of namespace "default", the namespace of the ACL, are allowed`)
+	_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().False(existingACL.Status.Ready)
+	suite.Assert().Contains(existingACL.Status.Reason, "configMap infra/egress-allowlist is not on namespace default, only ConfigMaps of the namespace of the ACL are allowed")
+	suite.Assert().NotContains(fmt.Sprint(egressPeers()), "10.9.9.9")
+
+	invalid := v1alpha1.ACLSpecDestination{ConfigMap: &v1alpha1.ACLSpecConfigMap{Name: "egress-allowlist"}}
+	suite.Assert().EqualError(invalid.Validate(), "configMap requires a name and a key")
+
+	invalid = v1alpha1.ACLSpecDestination{ConfigMap: &v1alpha1.ACLSpecConfigMap{Namespace: "Infra", Name: "egress-allowlist", Key: "addresses"}}
+	suite.Assert().ErrorContains(invalid.Validate(), `invalid namespace "Infra" of configMap`)
+}
+
+func TestParseConfigMapAddresses(t *testing.T) {
+	addresses, err := v1alpha1.ParseConfigMapAddresses("# allowlist\n10.0.0.1 10.0.1.0/24,Example.COM.\n\n  2001:db8::/32\t# ipv6\nexample.com\n")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.1.0/24", "example.com", "2001:db8::/32"}, addresses)
+
+	addresses, err = v1alpha1.ParseConfigMapAddresses("# nothing yet\n")
+	require.NoError(t, err)
+	assert.Empty(t, addresses)
+
+	_, err = v1alpha1.ParseConfigMapAddresses("db")
+	assert.EqualError(t, err, "hostname on line 1 is not fully qualified, short names resolve against search domains, use the name with its domain")
+
+	_, err = v1alpha1.ParseConfigMapAddresses("10.0.0.1\n# wildcards\n*.example.com")
+	assert.EqualError(t, err, "invalid address on line 3, use an IP, a CIDR or a hostname")
+
+	lines := []string{}
+	for i := 0; i <= v1alpha1.MaxConfigMapAddresses; i++ {
+		lines = append(lines, fmt.Sprintf("10.%d.%d.1", i/256, i%256))
+	}
+	_, err = v1alpha1.ParseConfigMapAddresses(strings.Join(lines, "\n"))
+	assert.EqualError(t, err, fmt.Sprintf("%d addresses exceed the limit of %d addresses", v1alpha1.MaxConfigMapAddresses+1, v1alpha1.MaxConfigMapAddresses))
+}
+
+func reconcileAddressObjects(ctx context.Context, t require.TestingT, reconciler *ACLReconciler) {
+	dnsEntries := &v1alpha1.ACLDNSEntryList{}
+	err := reconciler.Client.List(ctx, dnsEntries)
+	require.NoError(t, err)
+	dnsEntryReconciler := &ACLDNSEntryReconciler{
+		Client:   reconciler.Client,
+		Scheme:   reconciler.Scheme,
+		Resolver: reconciler.Resolver,
+	}
+	for _, dnsEntry := range dnsEntries.Items {
+		_, err = dnsEntryReconciler.Reconcile(ctx, controllerruntime.Request{NamespacedName: client.ObjectKeyFromObject(&dnsEntry)})
+		require.NoError(t, err)
+	}
+
+	tsuruAppAddresses := &v1alpha1.TsuruAppAddressList{}
+	err = reconciler.Client.List(ctx, tsuruAppAddresses)
+	require.NoError(t, err)
+	tsuruAppAddressReconciler := &TsuruAppAddressReconciler{
+		Client:   reconciler.Client,
+		Scheme:   reconciler.Scheme,
+		Resolver: reconciler.Resolver,
+		TsuruAPI: reconciler.TsuruAPI,
+	}
+	for _, tsuruAppAddress := range tsuruAppAddresses.Items {
+		_, err = tsuruAppAddressReconciler.Reconcile(ctx, controllerruntime.Request{NamespacedName: client.ObjectKeyFromObject(&tsuruAppAddress)})
+		require.NoError(t, err)
+	}
+
+	rpaasInstanceAddresses := &v1alpha1.RpaasInstanceAddressList{}
+	err = reconciler.Client.List(ctx, rpaasInstanceAddresses)
+	require.NoError(t, err)
+	rpaasInstanceAddressReconciler := &RpaasInstanceAddressReconciler{
+		Client:   reconciler.Client,
+		Scheme:   reconciler.Scheme,
+		Resolver: reconciler.Resolver,
+		TsuruAPI: reconciler.TsuruAPI,
+	}
+	for _, rpaasInstanceAddress := range rpaasInstanceAddresses.Items {
+		_, err = rpaasInstanceAddressReconciler.Reconcile(ctx, controllerruntime.Request{NamespacedName: client.ObjectKeyFromObject(&rpaasInstanceAddress)})
+		require.NoError(t, err)
+	}
+}
+
+type fakeTsuruAPI struct {
+}
+
+func (f *fakeTsuruAPI) AppInfo(ctx context.Context, appName string) (*app.App, error) {
+	if appName == "my-other-app" {
+		return &app.App{
+			Name: appName,
+			Pool: "my-pool",
+			Routers: []appTypes.AppRouter{
+				{
+					Name: "https-router",
+					Addresses: []string{
+						"https://myapp.io",
+					},
+				},
+				{
+					Name: "http-router",
+					Addresses: []string{
+						"http.myapp.io",
+					},
+				},
+			},
+			InternalAddresses: []provision.AppInternalAddress{
+				{Domain: "my-other-app-web.tsuru-my-pool.svc.cluster.local", Protocol: "TCP", Port: 80, Process: "web"},
+				{Domain: "my-other-app-web.tsuru-my-pool.svc.cluster.local", Protocol: "TCP", Port: 8888, Process: "web"},
+				{Domain: "my-other-app-worker.tsuru-my-pool.svc.cluster.local", Protocol: "TCP", Port: 80, Process: "worker"},
+			},
+		}, nil
+	}
+
+	return nil, errors.New("no app found")
+}
+
+func (f *fakeTsuruAPI) ServiceInstanceInfo(ctx context.Context, service, instance string) (*tsuruapi.ServiceInstanceInfo, error) {
+	if service == "rpaasv2" && instance == "my-instance" {
+		return &tsuruapi.ServiceInstanceInfo{
+			CustomInfo: map[string]interface{}{
+				"Address": "3.3.3.3",
+			},
+		}, nil
+	}
+
+	return nil, errors.New("not implemented yet")
+}
+
+func (f *fakeTsuruAPI) PoolApps(ctx context.Context, pool string) ([]string, error) {
+	if pool == "my-pool" {
+		return []string{"my-other-app"}, nil
+	}
+
+	return nil, nil
+}
+
+func TestValidResourceName(t *testing.T) {
+	expectations := map[string]string{
+		"user":         "user",
+		"*.globo.com":  "globo.com-102f523825",
+		".google.com":  "google.com-5d59719991",
+		"10.1.1.1/10":  "10.1.1.1-10-22f870d4a0",
+		"facebook.com": "facebook.com",
+
+		strings.Repeat("testing-", 30): "testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-testing-2744fb94f9",
+
+		"Example.COM":        "example.com",
+		"*.Globo.com":        "globo.com-102f523825",
+		"my--app__name":      "my-app-name-447efa510b",
+		"example.com.@1.1.1": "example.com-1.1.1-89da0fd013",
+		"a..b":               "a.b-f62b42414c",
+	}
+
+	for input, expectedOutput := range expectations {
+		output := validResourceName(input)
+		assert.Equal(t, expectedOutput, output, "input", input)
+
+		errs := validation.IsDNS1123Subdomain(output)
+
+		assert.Len(t, errs, 0)
+	}
+
+}
+
+func TestACLReconcilerPortRanges(t *testing.T) {
+	r := &ACLReconciler{}
+	tcp := corev1.ProtocolTCP
+	endPort := int32(32767)
+
+	ports, err := r.ports([]v1alpha1.ProtoPort{
+		{Protocol: "tcp", Number: 30000, EndPort: 32767},
+		{Protocol: "tcp", Number: 443, EndPort: 443},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []netv1.NetworkPolicyPort{
+		{
+			Protocol: &tcp,
+			Port:     &intstr.IntOrString{IntVal: 30000},
+			EndPort:  &endPort,
+		},
+		{
+			Protocol: &tcp,
+			Port:     &intstr.IntOrString{IntVal: 443},
+		},
+	}, ports)
+
+	_, err = r.ports([]v1alpha1.ProtoPort{
+		{Protocol: "tcp", Number: 8080, EndPort: 80},
+	})
+	assert.EqualError(t, err, "invalid port range 8080-80, endPort must be greater than or equal to number")
+
+	_, err = r.ports([]v1alpha1.ProtoPort{
+		{Protocol: "sctp", Number: 80, EndPort: 90},
+	})
+	assert.EqualError(t, err, `port range 80-90 is not supported for protocol "sctp", use TCP or UDP`)
+}
+
+func TestACLReconcilerNamedPorts(t *testing.T) {
+	r := &ACLReconciler{}
+	tcp := corev1.ProtocolTCP
+	udp := corev1.ProtocolUDP
+
+	ports, err := r.ports([]v1alpha1.ProtoPort{
+		{Protocol: "tcp", Number: 8080},
+		{Protocol: "tcp", Name: "http"},
+		{Protocol: "udp", Name: "dns"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []netv1.NetworkPolicyPort{
+		{Protocol: &tcp, Port: &intstr.IntOrString{IntVal: 8080}},
+		{Protocol: &tcp, Port: &intstr.IntOrString{Type: intstr.String, StrVal: "http"}},
+		{Protocol: &udp, Port: &intstr.IntOrString{Type: intstr.String, StrVal: "dns"}},
+	}, ports)
+
+	ciliumPorts, err := ciliumPortsForProtoPorts(v1alpha1.ACLSpecProtoPorts{
+		{Protocol: "tcp", Number: 8080},
+		{Protocol: "tcp", Name: "http"},
+	})
+	require.NoError(t, err)
+	require.Len(t, ciliumPorts, 1)
+	assert.Equal(t, "8080", ciliumPorts[0].Ports[0].Port)
+	assert.Equal(t, "http", ciliumPorts[0].Ports[1].Port)
+
+	tests := []struct {
+		port          v1alpha1.ProtoPort
+		expectedError string
+	}{
+		{
+			port:          v1alpha1.ProtoPort{Protocol: "tcp", Number: 80, Name: "http"},
+			expectedError: `port sets both number 80 and name "http", use only one of them`,
+		},
+		{
+			port:          v1alpha1.ProtoPort{Protocol: "tcp"},
+			expectedError: "port sets neither number nor name, set a number between 1 and 65535 or a port name",
+		},
+		{
+			port:          v1alpha1.ProtoPort{Protocol: "tcp", Name: "http", EndPort: 90},
+			expectedError: `endPort is not supported for the named port "http"`,
+		},
+		{
+			port:          v1alpha1.ProtoPort{Protocol: "tcp", Name: "Http_Port"},
+			expectedError: `invalid port name "Http_Port": must contain only alpha-numeric characters (a-z, 0-9), and hyphens (-)`,
+		},
+	}
+
+	for _, tt := range tests {
+		_, err = r.ports([]v1alpha1.ProtoPort{tt.port})
+		assert.EqualError(t, err, tt.expectedError)
+	}
+}
+
+func TestACLReconcilerExternalEndpoints(t *testing.T) {
+	r := &ACLReconciler{}
+	tcp := corev1.ProtocolTCP
+	udp := corev1.ProtocolUDP
+
+	rules, err := r.egressRulesForDestination(context.Background(), v1alpha1.ACLSpecDestination{
+		ExternalEndpoints: []string{"10.0.0.1:443/tcp", "[2001:db8::1]:53/UDP", "10.0.0.1:80"},
+	}, addressOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []netv1.NetworkPolicyEgressRule{
+		{
+			To: []netv1.NetworkPolicyPeer{{IPBlock: &netv1.IPBlock{CIDR: "10.0.0.1/32"}}},
+			Ports: []netv1.NetworkPolicyPort{
+				{Protocol: &tcp, Port: &intstr.IntOrString{IntVal: 443}},
+				{Protocol: &tcp, Port: &intstr.IntOrString{IntVal: 80}},
+			},
+		},
+		{
+			To: []netv1.NetworkPolicyPeer{{IPBlock: &netv1.IPBlock{CIDR: "2001:db8::1/128"}}},
+			Ports: []netv1.NetworkPolicyPort{
+				{Protocol: &udp, Port: &intstr.IntOrString{IntVal: 53}},
+			},
+		},
+	}, rules)
+
+	tests := []struct {
+		endpoint      string
+		expectedError string
+	}{
+		{
+			endpoint:      "10.0.0.1/tcp",
+			expectedError: `invalid externalEndpoint "10.0.0.1/tcp", use ip:port/protocol like 10.0.0.1:443/tcp: missing port`,
+		},
+		{
+			endpoint:      "example.com:443/tcp",
+			expectedError: `invalid externalEndpoint "example.com:443/tcp", use ip:port/protocol like 10.0.0.1:443/tcp: "example.com" is not an IP address`,
+		},
+		{
+			endpoint:      "10.0.0.1:0/tcp",
+			expectedError: `invalid externalEndpoint "10.0.0.1:0/tcp", use ip:port/protocol like 10.0.0.1:443/tcp: port "0" must be between 1 and 65535`,
+		},
+		{
+			endpoint:      "10.0.0.1:443/icmp",
+			expectedError: `invalid externalEndpoint "10.0.0.1:443/icmp", use ip:port/protocol like 10.0.0.1:443/tcp: protocol "icmp" must be TCP, UDP or SCTP`,
+		},
+		{
+			endpoint:      "2001:db8::1:53/udp",
+			expectedError: `invalid externalEndpoint "2001:db8::1:53/udp", use ip:port/protocol like 10.0.0.1:443/tcp: missing port`,
+		},
+	}
+
+	for _, tt := range tests {
+		destination := v1alpha1.ACLSpecDestination{ExternalEndpoints: []string{"10.0.0.2:443/tcp", tt.endpoint}}
+		assert.EqualError(t, destination.Validate(), tt.expectedError)
+		_, err = r.egressRulesForDestination(context.Background(), destination, addressOptions{})
+		assert.EqualError(t, err, tt.expectedError)
+	}
+
+	destination := v1alpha1.ACLSpecDestination{
+		ExternalIP:        &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.1"},
+		ExternalEndpoints: []string{"10.0.0.2:443/tcp"},
+	}
+	assert.EqualError(t, destination.Validate(), "destination must set exactly one of tsuruApp, tsuruAppPool, rpaasInstance, tsuruServiceInstance, externalDNS, externalIP, externalEndpoints, externalSRV, kubernetesService, configMap or deny, found 2")
+}
+
+func TestACLReconcilerPortProtocols(t *testing.T) {
+	r := &ACLReconciler{}
+	tcp := corev1.ProtocolTCP
+	udp := corev1.ProtocolUDP
+	sctp := corev1.ProtocolSCTP
+
+	ports, err := r.ports([]v1alpha1.ProtoPort{
+		{Protocol: "tcp", Number: 80},
+		{Protocol: "Udp", Number: 53},
+		{Protocol: "SCTP", Number: 9899},
+		{Number: 8080},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []netv1.NetworkPolicyPort{
+		{Protocol: &tcp, Port: &intstr.IntOrString{IntVal: 80}},
+		{Protocol: &udp, Port: &intstr.IntOrString{IntVal: 53}},
+		{Protocol: &sctp, Port: &intstr.IntOrString{IntVal: 9899}},
+		{Port: &intstr.IntOrString{IntVal: 8080}},
+	}, ports)
+
+	_, err = r.ports([]v1alpha1.ProtoPort{
+		{Protocol: "tpc", Number: 80},
+	})
+	assert.EqualError(t, err, `invalid protocol "tpc", use TCP, UDP or SCTP`)
+}
+
+func TestACLReconcilerExternalIPExcept(t *testing.T) {
+	r := &ACLReconciler{}
+	ctx := context.Background()
+
+	egress, err := r.egressRulesForExternalIP(ctx, &v1alpha1.ACLSpecExternalIP{
+		IP:     "10.0.0.0/8",
+		Except: []string{"10.1.1.1", "10.2.0.0/16"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []netv1.NetworkPolicyEgressRule{
+		{
+			To: []netv1.NetworkPolicyPeer{
+				{
+					IPBlock: &netv1.IPBlock{
+						CIDR:   "10.0.0.0/8",
+						Except: []string{"10.1.1.1/32", "10.2.0.0/16"},
+					},
+				},
+			},
+		},
+	}, egress)
+
+	_, err = r.egressRulesForExternalIP(ctx, &v1alpha1.ACLSpecExternalIP{
+		IP:     "10.0.0.0/8",
+		Except: []string{"192.168.0.1"},
+	})
+	assert.EqualError(t, err, `except "192.168.0.1" is not contained in externalIP "10.0.0.0/8"`)
+
+	_, err = r.egressRulesForExternalIP(ctx, &v1alpha1.ACLSpecExternalIP{
+		IP:     "10.0.0.0/16",
+		Except: []string{"10.0.0.0/8"},
+	})
+	assert.EqualError(t, err, `except "10.0.0.0/8" is not contained in externalIP "10.0.0.0/16"`)
+
+	_, err = r.egressRulesForExternalIP(ctx, &v1alpha1.ACLSpecExternalIP{
+		IP: "10.0.0.300",
+	})
+	assert.EqualError(t, err, `invalid externalIP "10.0.0.300": invalid IP address: 10.0.0.300`)
+}
+
+func TestACLDefaultExternalIP(t *testing.T) {
+	tests := []struct {
+		ip             string
+		except         []string
+		expected       string
+		expectedExcept []string
+		// invalid addresses are kept to be reported by the validation
+		invalid bool
+	}{
+		{ip: "10.1.1.1", expected: "10.1.1.1/32"},
+		{ip: "2001:DB8:0:0::1", expected: "2001:db8::1/128"},
+		{ip: "::ffff:10.1.1.1", expected: "10.1.1.1/32"},
+		{ip: "10.0.0.0/8", except: []string{"10.1.1.1", "10.2.0.0/16"}, expected: "10.0.0.0/8", expectedExcept: []string{"10.1.1.1/32", "10.2.0.0/16"}},
+		{ip: "2001:DB8::/32", expected: "2001:db8::/32"},
+		{ip: "::FFFF:10.1.1.0/120", expected: "10.1.1.0/24"},
+		{ip: "FE80::1%eth0", expected: "fe80::1/128"},
+		{ip: "10.0.0.300", expected: "10.0.0.300", invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			acl := &v1alpha1.ACL{
+				Spec: v1alpha1.ACLSpec{
+					Destinations: []v1alpha1.ACLSpecDestination{
+						{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: tt.ip, Except: tt.except}},
+					},
+					Ingress: []v1alpha1.ACLSpecIngress{
+						{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: tt.ip}},
+					},
+				},
+			}
+
+			acl.Default()
+			assert.Equal(t, tt.expected, acl.Spec.Destinations[0].ExternalIP.IP)
+			assert.Equal(t, tt.expectedExcept, acl.Spec.Destinations[0].ExternalIP.Except)
+			assert.Equal(t, tt.expected, acl.Spec.Ingress[0].ExternalIP.IP)
+
+			// the reconciler generates the same CIDR for the normalized spec
+			r := &ACLReconciler{}
+			egress, err := r.egressRulesForExternalIP(context.Background(), acl.Spec.Destinations[0].ExternalIP)
+			if tt.invalid {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, egress[0].To[0].IPBlock.CIDR)
+		})
+	}
+}
+
+func TestIPToCIDR(t *testing.T) {
+	tests := []struct {
+		address  string
+		cidr     string
+		ipFamily v1alpha1.IPFamily
+	}{
+		{address: "10.1.1.1", cidr: "10.1.1.1/32", ipFamily: v1alpha1.IPv4Family},
+		{address: "::ffff:10.1.1.1", cidr: "10.1.1.1/32", ipFamily: v1alpha1.IPv4Family},
+		{address: "::FFFF:A01:101", cidr: "10.1.1.1/32", ipFamily: v1alpha1.IPv4Family},
+		{address: "2001:DB8:0:0::1", cidr: "2001:db8::1/128", ipFamily: v1alpha1.IPv6Family},
+		{address: "fe80::1%eth0", cidr: "fe80::1/128", ipFamily: v1alpha1.IPv6Family},
+		{address: "10.1.1.0/24"},
+		{address: "10.0.0.300"},
+		{address: "example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			cidr, ipFamily := ipToCIDR(tt.address)
+			assert.Equal(t, tt.cidr, cidr)
+			assert.Equal(t, tt.ipFamily, ipFamily)
+		})
+	}
+
+	dnsEntry := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "myapp.io",
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host:          "myapp.io",
+			AdditionalIPs: []string{"2001:DB8::1", "::ffff:10.1.1.2"},
+		},
+		Status: v1alpha1.ACLDNSEntryStatus{
+			Ready: true,
+			IPs: []v1alpha1.ACLDNSEntryStatusIP{
+				{Address: "::ffff:10.1.1.1"},
+				{Address: "10.1.1.1"},
+				{Address: "2001:db8::1"},
+				{Address: "fe80::1%eth0"},
+			},
+		},
+	}
+	r := &ACLReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(dnsEntry).Build(),
+	}
+
+	// addresses are deduplicated by their canonical CIDR
+	egress, err := r.egressRulesForExternalDNS(context.Background(), &v1alpha1.ACLSpecExternalDNS{Name: "myapp.io"}, addressOptions{})
+	require.NoError(t, err)
+	require.Len(t, egress, 1)
+	cidrs := []string{}
+	for _, to := range egress[0].To {
+		cidrs = append(cidrs, to.IPBlock.CIDR)
+	}
+	assert.Equal(t, []string{"10.1.1.1/32", "2001:db8::1/128", "fe80::1/128", "10.1.1.2/32"}, cidrs)
+
+	egress, err = r.egressRulesForExternalDNS(context.Background(), &v1alpha1.ACLSpecExternalDNS{Name: "myapp.io"}, addressOptions{ipFamilies: []v1alpha1.IPFamily{v1alpha1.IPv4Family}})
+	require.NoError(t, err)
+	require.Len(t, egress, 1)
+	cidrs = []string{}
+	for _, to := range egress[0].To {
+		cidrs = append(cidrs, to.IPBlock.CIDR)
+	}
+	assert.Equal(t, []string{"10.1.1.1/32", "10.1.1.2/32"}, cidrs)
+}
+
+func TestACLReconcilerDenyDestination(t *testing.T) {
+	r := &ACLReconciler{}
+	ctx := context.Background()
+
+	egress, err := r.egressRulesForDestination(ctx, v1alpha1.ACLSpecDestination{
+		Deny: &v1alpha1.ACLSpecDeny{
+			CIDRs: []string{"10.1.0.0/16", "192.168.0.1", "10.0.0.0/8", "192.168.0.1/32"},
+		},
+	}, addressOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []netv1.NetworkPolicyEgressRule{
+		{
+			To: []netv1.NetworkPolicyPeer{
+				{
+					IPBlock: &netv1.IPBlock{
+						CIDR:   "0.0.0.0/0",
+						Except: []string{"192.168.0.1/32", "10.0.0.0/8"},
+					},
+				},
+			},
+		},
+	}, egress)
+
+	egress, err = r.egressRulesForDestination(ctx, v1alpha1.ACLSpecDestination{
+		Deny: &v1alpha1.ACLSpecDeny{
+			Base:  "172.16.0.0/12",
+			CIDRs: []string{"172.16.1.0/24"},
+			Ports: v1alpha1.ACLSpecProtoPorts{
+				{Protocol: "tcp", Number: 443},
+			},
+		},
+	}, addressOptions{})
+	require.NoError(t, err)
+	require.Len(t, egress, 1)
+	assert.Equal(t, &netv1.IPBlock{
+		CIDR:   "172.16.0.0/12",
+		Except: []string{"172.16.1.0/24"},
+	}, egress[0].To[0].IPBlock)
+	assert.Len(t, egress[0].Ports, 1)
+
+	_, err = r.egressRulesForDestination(ctx, v1alpha1.ACLSpecDestination{
+		Deny: &v1alpha1.ACLSpecDeny{
+			Base:  "172.16.0.0/12",
+			CIDRs: []string{"10.0.0.0/8"},
+		},
+	}, addressOptions{})
+	assert.EqualError(t, err, `except "10.0.0.0/8" is not contained in externalIP "172.16.0.0/12"`)
+
+	_, err = r.egressRulesForDestination(ctx, v1alpha1.ACLSpecDestination{
+		Deny: &v1alpha1.ACLSpecDeny{},
+	}, addressOptions{})
+	assert.EqualError(t, err, "deny requires at least one CIDR")
+}
+
+func TestACLReconcilerNamespaceLabelKey(t *testing.T) {
+	r := &ACLReconciler{}
+	peers := r.tsuruAppPoolPeers("my-pool")
+	require.Len(t, peers, 2)
+	assert.Equal(t, &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			"name": "tsuru-my-pool",
+		},
+	}, peers[1].NamespaceSelector)
+
+	r = &ACLReconciler{NamespaceLabelKey: "kubernetes.io/metadata.name"}
+	peers = r.tsuruAppPoolPeers("my-pool")
+	require.Len(t, peers, 2)
+	assert.Nil(t, peers[0].NamespaceSelector)
+	assert.Equal(t, &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			"kubernetes.io/metadata.name": "tsuru-my-pool",
+		},
+	}, peers[1].NamespaceSelector)
+}
+
+func TestACLReconcilerLabelScheme(t *testing.T) {
+	r := &ACLReconciler{}
+	assert.Equal(t, map[string]string{"tsuru.io/app-name": "my-app"}, r.podSelectorForTsuruApp("my-app").MatchLabels)
+	assert.Equal(t, map[string]string{"tsuru.io/app-pool": "my-pool"}, r.tsuruAppPoolPeers("my-pool")[0].PodSelector.MatchLabels)
+	assert.Equal(t, map[string]string{"tsuru.io/app-name": "my-app"}, r.podSelectorForTsuruAppProcess("my-app", "").MatchLabels)
+
+	r = &ACLReconciler{
+		LabelScheme: LabelScheme{
+			AppName:       "example.com/app",
+			AppProcess:    "example.com/process",
+			AppPool:       "example.com/pool",
+			RpaasInstance: "example.com/rpaas-instance",
+		},
+	}
+	assert.Equal(t, map[string]string{"example.com/app": "my-app"}, r.podSelectorForTsuruApp("my-app").MatchLabels)
+	assert.Equal(t, map[string]string{"example.com/app": "my-app", "example.com/process": "web"}, r.podSelectorForTsuruAppProcess("my-app", "web").MatchLabels)
+	assert.Equal(t, map[string]string{"tsuru.io/job-name": "my-job"}, r.podSelectorForTsuruJob("my-job").MatchLabels)
+	assert.Equal(t, map[string]string{
+		"example.com/rpaas-instance":             "my-instance",
+		"rpaas.extensions.tsuru.io/service-name": "rpaasv2",
+	}, r.podSelectorForRpasInstance(&v1alpha1.ACLSpecRpaasInstance{ServiceName: "rpaasv2", Instance: "my-instance"}).MatchLabels)
+
+	for _, peer := range r.tsuruAppPoolPeers("my-pool") {
+		assert.Equal(t, map[string]string{"example.com/pool": "my-pool"}, peer.PodSelector.MatchLabels)
+	}
+}
+
+func TestAddressStatusChanged(t *testing.T) {
+	pending := &v1alpha1.TsuruAppAddress{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-app"},
+		Spec:       v1alpha1.TsuruAppAddressSpec{Name: "my-app"},
+	}
+
+	owned := pending.DeepCopy()
+	owned.OwnerReferences = []metav1.OwnerReference{{APIVersion: "extensions.tsuru.io/v1alpha1", Kind: "ACL", Name: "my-acl"}}
+	assert.False(t, addressStatusChanged.Update(event.UpdateEvent{ObjectOld: pending, ObjectNew: owned}))
+
+	ready := owned.DeepCopy()
+	ready.Status.Ready = true
+	ready.Status.IPs = []string{"10.0.0.1"}
+	assert.True(t, addressStatusChanged.Update(event.UpdateEvent{ObjectOld: owned, ObjectNew: ready}))
+
+	dnsEntry := &v1alpha1.ACLDNSEntry{Spec: v1alpha1.ACLDNSEntrySpec{Host: "example.com"}}
+	resolved := dnsEntry.DeepCopy()
+	resolved.Status.IPs = []v1alpha1.ACLDNSEntryStatusIP{{Address: "1.1.1.1"}}
+	assert.True(t, addressStatusChanged.Update(event.UpdateEvent{ObjectOld: dnsEntry, ObjectNew: resolved}))
+	assert.True(t, addressStatusChanged.Create(event.CreateEvent{Object: dnsEntry}))
+	assert.True(t, addressStatusChanged.Delete(event.DeleteEvent{Object: dnsEntry}))
+}
+
+func TestMergeEgressRules(t *testing.T) {
+	tcp := corev1.ProtocolTCP
+	port80 := intstr.FromInt(80)
+	port443 := intstr.FromInt(443)
+	webPorts := []netv1.NetworkPolicyPort{
+		{Protocol: &tcp, Port: &port80},
+		{Protocol: &tcp, Port: &port443},
+	}
+	reversedWebPorts := []netv1.NetworkPolicyPort{
+		{Protocol: &tcp, Port: &port443},
+		{Protocol: &tcp, Port: &port80},
+	}
+	peer := func(cidr string) netv1.NetworkPolicyPeer {
+		return netv1.NetworkPolicyPeer{IPBlock: &netv1.IPBlock{CIDR: cidr}}
+	}
+
+	rules := []netv1.NetworkPolicyEgressRule{
+		{To: []netv1.NetworkPolicyPeer{peer("2.2.2.2/32")}, Ports: webPorts},
+		{To: []netv1.NetworkPolicyPeer{peer("3.3.3.3/32")}},
+		{Ports: webPorts},
+		{To: []netv1.NetworkPolicyPeer{peer("1.1.1.1/32"), peer("2.2.2.2/32")}, Ports: reversedWebPorts},
+	}
+
+	expected := []netv1.NetworkPolicyEgressRule{
+		{To: []netv1.NetworkPolicyPeer{peer("1.1.1.1/32"), peer("2.2.2.2/32")}, Ports: webPorts},
+		{To: []netv1.NetworkPolicyPeer{peer("3.3.3.3/32")}},
+		{Ports: webPorts},
+	}
+
+	assert.Equal(t, expected, mergeEgressRules(rules))
+	assert.Equal(t, expected, mergeEgressRules(mergeEgressRules(rules)))
+}
+
+func (suite *ControllerSuite) TestACLReconcilerExternalSRV() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalSRV: &v1alpha1.ACLSpecExternalSRV{
+						Name: "_ldap._tcp.example.com",
+					},
+				},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client: withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme: scheme.Scheme,
+		Resolver: &fakeResolver{
+			srvs: map[string][]*net.SRV{
+				"_ldap._tcp.example.com": {
+					{Target: "ldap1.example.com.", Port: 389},
+					{Target: "ldap2.example.com.", Port: 389},
+					{Target: "ldap3.example.com.", Port: 636},
+				},
+			},
+			hosts: map[string][]string{
+				"ldap1.example.com": {"10.0.0.1", "10.0.0.2"},
+				"ldap2.example.com": {"10.0.0.2", "10.0.0.3"},
+				"ldap3.example.com": {"10.0.0.1"},
+			},
+		},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+	})
+	suite.Require().NoError(err)
+
+	np := &netv1.NetworkPolicy{}
+	err = reconciler.Client.Get(ctx, types.NamespacedName{Name: "acl-myapp", Namespace: "default"}, np)
+	suite.Require().NoError(err)
+
+	tcp := corev1.ProtocolTCP
+	ldapPort := intstr.FromInt(389)
+	ldapsPort := intstr.FromInt(636)
+	suite.Assert().Equal([]netv1.NetworkPolicyEgressRule{
+		{
+			To: []netv1.NetworkPolicyPeer{
+				{IPBlock: &netv1.IPBlock{CIDR: "10.0.0.1/32"}},
+				{IPBlock: &netv1.IPBlock{CIDR: "10.0.0.2/32"}},
+				{IPBlock: &netv1.IPBlock{CIDR: "10.0.0.3/32"}},
+			},
+			Ports: []netv1.NetworkPolicyPort{{Protocol: &tcp, Port: &ldapPort}},
+		},
+		{
+			To: []netv1.NetworkPolicyPeer{
+				{IPBlock: &netv1.IPBlock{CIDR: "10.0.0.1/32"}},
+			},
+			Ports: []netv1.NetworkPolicyPort{{Protocol: &tcp, Port: &ldapsPort}},
+		},
+	}, np.Spec.Egress)
+}
+
+func TestACLSpecExternalSRVProtocol(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol corev1.Protocol
+		err      string
+	}{
+		{name: "_ldap._tcp.example.com", protocol: corev1.ProtocolTCP},
+		{name: "_sip._UDP.example.com", protocol: corev1.ProtocolUDP},
+		{name: "ldap._tcp.example.com", err: `invalid externalSRV name "ldap._tcp.example.com", use _service._proto.host`},
+		{name: "_ldap._tcp", err: `invalid externalSRV name "_ldap._tcp", use _service._proto.host`},
+		{name: "_ldap._icmp.example.com", err: `invalid externalSRV name "_ldap._icmp.example.com", the proto label must be _tcp, _udp or _sctp`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			protocol, err := (&v1alpha1.ACLSpecExternalSRV{Name: tt.name}).Protocol()
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.protocol, protocol)
+		})
+	}
+}
+
+func (suite *ControllerSuite) TestACLReconcilerMemoizesUnchangedInputs() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalDNS: &v1alpha1.ACLSpecExternalDNS{
+						Name: "example.com",
+					},
+				},
+			},
+		},
+	}
+	dnsEntry := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "example.com",
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host: "example.com",
+		},
+		Status: v1alpha1.ACLDNSEntryStatus{
+			Ready: true,
+			IPs:   []v1alpha1.ACLDNSEntryStatusIP{{Address: "1.1.1.1"}},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl, dnsEntry).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	reconcile := func() []netv1.NetworkPolicyEgressRule {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+
+		np := &netv1.NetworkPolicy{}
+		err = reconciler.Client.Get(ctx, types.NamespacedName{Name: "acl-myapp", Namespace: "default"}, np)
+		suite.Require().NoError(err)
+		return np.Spec.Egress
+	}
+
+	// the first reconcile registers the ACL as owner of the entry, which changes its resourceVersion
+	reconcile()
+	egress := reconcile()
+	suite.Require().Len(egress, 1)
+	suite.Assert().Equal("1.1.1.1/32", egress[0].To[0].IPBlock.CIDR)
+
+	entry, ok := reconciler.memo.entries[client.ObjectKeyFromObject(acl)]
+	suite.Require().True(ok)
+
+	// the memoized policy is applied without generating the rules again
+	entry.policy.Egress = []netv1.NetworkPolicyEgressRule{{To: []netv1.NetworkPolicyPeer{{IPBlock: &netv1.IPBlock{CIDR: "9.9.9.9/32"}}}}}
+	egress = reconcile()
+	suite.Require().Len(egress, 1)
+	suite.Assert().Equal("9.9.9.9/32", egress[0].To[0].IPBlock.CIDR)
+
+	// a new resourceVersion of the entry invalidates the memoized policy
+	existingDNSEntry := &v1alpha1.ACLDNSEntry{}
+	err := reconciler.Client.Get(ctx, client.ObjectKeyFromObject(dnsEntry), existingDNSEntry)
+	suite.Require().NoError(err)
+	existingDNSEntry.Status.IPs = []v1alpha1.ACLDNSEntryStatusIP{{Address: "2.2.2.2"}}
+	err = reconciler.Client.Status().Update(ctx, existingDNSEntry)
+	suite.Require().NoError(err)
+
+	egress = reconcile()
+	suite.Require().Len(egress, 1)
+	suite.Assert().Equal("2.2.2.2/32", egress[0].To[0].IPBlock.CIDR)
+
+	// ACLs with destinations resolved outside of address objects are never memoized
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	existingACL.Spec.Destinations = append(existingACL.Spec.Destinations, v1alpha1.ACLSpecDestination{TsuruAppPool: "mypool"})
+	err = reconciler.Client.Update(ctx, existingACL)
+	suite.Require().NoError(err)
+
+	reconcile()
+	_, ok = reconciler.memo.entries[client.ObjectKeyFromObject(acl)]
+	suite.Assert().False(ok)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerRawPodSelectorSource() {
+	ctx := context.Background()
+	podSelector := &v1.LabelSelector{
+		MatchLabels: map[string]string{"app": "legacy"},
+		MatchExpressions: []v1.LabelSelectorRequirement{
+			{Key: "tier", Operator: v1.LabelSelectorOpIn, Values: []string{"web", "worker"}},
+		},
+	}
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "legacy",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				RawPodSelector: podSelector,
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "1.1.1.1/32",
+					},
+				},
+			},
+		},
+	}
+	invalidACL := acl.DeepCopy()
+	invalidACL.Name = "invalid"
+	invalidACL.Spec.Source.TsuruApp = "myapp"
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl, invalidACL).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	for _, name := range []string{"legacy", "invalid"} {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      name,
+				Namespace: "default",
+			},
+		})
+		suite.Require().NoError(err)
+	}
+
+	np := &netv1.NetworkPolicy{}
+	err := reconciler.Client.Get(ctx, types.NamespacedName{Name: "acl-legacy", Namespace: "default"}, np)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(*podSelector, np.Spec.PodSelector)
+
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(invalidACL), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().False(existingACL.Status.Ready)
+	suite.Assert().Equal("invalid spec.source, err: source must set exactly one of tsuruApp, tsuruJob, rpaasInstance, rawPodSelector or workload, found 2", existingACL.Status.Reason)
+
+	err = reconciler.Client.Get(ctx, types.NamespacedName{Name: "acl-invalid", Namespace: "default"}, np)
+	suite.Assert().True(k8sErrors.IsNotFound(err))
+}
+
+func (suite *ControllerSuite) TestACLReconcilerWorkloadSource() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "api",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				Workload: &v1alpha1.ACLSpecWorkload{Kind: "Deployment", Name: "api"},
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "1.1.1.1/32"}},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	reconcileACL := func() (controllerruntime.Result, *v1alpha1.ACL) {
+		result, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+
+		existingACL := &v1alpha1.ACL{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+		suite.Require().NoError(err)
+		return result, existingACL
+	}
+
+	// the ACL waits for its workload
+	result, existingACL := reconcileACL()
+	suite.Assert().Equal(missingWorkloadRequeueInterval, result.RequeueAfter)
+	suite.Assert().False(existingACL.Status.Ready)
+	suite.Assert().Equal("workload default/Deployment/api not found", existingACL.Status.Reason)
+	np := &netv1.NetworkPolicy{}
+	err := reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-api"}, np)
+	suite.Assert().True(k8sErrors.IsNotFound(err))
+
+	// the selector is used instead of the labels of the pod template, which change on rollouts
+	deployment := &appsv1.Deployment{
+		ObjectMeta: v1.ObjectMeta{
+			Name:       "api",
+			Namespace:  "default",
+			Generation: 1,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &v1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: v1.ObjectMeta{Labels: map[string]string{"app": "api", "version": "v1"}},
+			},
+		},
+	}
+	err = reconciler.Client.Create(ctx, deployment)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]reconcile.Request{{NamespacedName: client.ObjectKeyFromObject(acl)}}, reconciler.reconcileRequestsForIndex(workloadIndex, workloadKey("default", "Deployment", "api")))
+	suite.Assert().Empty(reconciler.reconcileRequestsForIndex(workloadIndex, workloadKey("default", "StatefulSet", "api")))
+
+	_, existingACL = reconcileACL()
+	suite.Assert().True(existingACL.Status.Ready)
+	suite.Assert().Equal("workload Deployment/api", describeSource(existingACL.Spec.Source))
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-api"}, np)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(v1.LabelSelector{MatchLabels: map[string]string{"app": "api"}}, np.Spec.PodSelector)
+
+	// a new selector is derived again, the memo of the previous policy is not reused
+	deployment.Spec.Selector = &v1.LabelSelector{MatchLabels: map[string]string{"app": "api", "tier": "backend"}}
+	deployment.Generation = 2
+	err = reconciler.Client.Update(ctx, deployment)
+	suite.Require().NoError(err)
+	_, existingACL = reconcileACL()
+	suite.Assert().True(existingACL.Status.Ready)
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-api"}, np)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(v1.LabelSelector{MatchLabels: map[string]string{"app": "api", "tier": "backend"}}, np.Spec.PodSelector)
+
+	// a workload without selector would select every pod of the namespace
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "api",
+			Namespace: "default",
+		},
+	}
+	err = reconciler.Client.Create(ctx, statefulSet)
+	suite.Require().NoError(err)
+	existingACL.Spec.Source.Workload.Kind = "StatefulSet"
+	err = reconciler.Client.Update(ctx, existingACL)
+	suite.Require().NoError(err)
+	result, existingACL = reconcileACL()
+	suite.Assert().Zero(result.RequeueAfter)
+	suite.Assert().False(existingACL.Status.Ready)
+	suite.Assert().Equal("invalid spec.source, err: StatefulSet default/api: the selector of workload is empty", existingACL.Status.Reason)
+
+	invalid := v1alpha1.ACLSpecSource{Workload: &v1alpha1.ACLSpecWorkload{Kind: "DaemonSet", Name: "api"}}
+	suite.Assert().EqualError(invalid.Validate(), `invalid kind "DaemonSet" of workload, use Deployment or StatefulSet`)
+
+	invalid = v1alpha1.ACLSpecSource{Workload: &v1alpha1.ACLSpecWorkload{Kind: "Deployment"}}
+	suite.Assert().ErrorContains(invalid.Validate(), `invalid name "" of workload`)
+}
+
+func TestCiliumPeersMatchExpressions(t *testing.T) {
+	endpoints, cidrs, all := ciliumPeers([]netv1.NetworkPolicyPeer{
+		{
+			PodSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "legacy"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"web"}},
+				},
+			},
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "team", Operator: metav1.LabelSelectorOpExists},
+				},
+			},
+		},
+	})
+	assert.False(t, all)
+	assert.Empty(t, cidrs)
+	assert.Equal(t, []ciliumSelector{
+		{
+			MatchLabels: map[string]string{"app": "legacy"},
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"web"}},
+				{Key: ciliumNamespaceLabelPrefix + "team", Operator: metav1.LabelSelectorOpExists},
+			},
+		},
+	}, endpoints)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerPolicyConflict() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "1.1.1.1/32",
+					},
+				},
+			},
+		},
+	}
+	// a policy bootstrapped by another team before the ACL
+	existingNP := &netv1.NetworkPolicy{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "acl-myapp",
+			Namespace: "default",
+		},
+		Spec: netv1.NetworkPolicySpec{
+			Egress: []netv1.NetworkPolicyEgressRule{
+				{To: []netv1.NetworkPolicyPeer{{IPBlock: &netv1.IPBlock{CIDR: "10.0.0.0/8"}}}},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl, existingNP).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	reconcile := func() *netv1.NetworkPolicy {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+
+		np := &netv1.NetworkPolicy{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(existingNP), np)
+		suite.Require().NoError(err)
+		return np
+	}
+
+	np := reconcile()
+	suite.Assert().Equal(existingNP.Spec.Egress, np.Spec.Egress)
+	suite.Assert().Empty(np.OwnerReferences)
+
+	existingACL := &v1alpha1.ACL{}
+	err := reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().False(existingACL.Status.Ready)
+	suite.Assert().Equal("NetworkPolicy acl-myapp already exists and is not managed by acl-operator, it is not overwritten, remove it or set --force-policy-ownership to take it over", existingACL.Status.Reason)
+	condition := meta.FindStatusCondition(existingACL.Status.Conditions, v1alpha1.ACLConditionReady)
+	suite.Require().NotNil(condition)
+	suite.Assert().Equal("PolicyConflict", condition.Reason)
+
+	reconciler.ForcePolicyOwnership = true
+	np = reconcile()
+	suite.Require().Len(np.Spec.Egress, 1)
+	suite.Assert().Equal("1.1.1.1/32", np.Spec.Egress[0].To[0].IPBlock.CIDR)
+	suite.Assert().Equal(policyManagedByValue, np.Labels[policyManagedByLabel])
+
+	// policies written by the operator are updated without the force option
+	reconciler.ForcePolicyOwnership = false
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	existingACL.Spec.Destinations[0].ExternalIP.IP = "2.2.2.2/32"
+	err = reconciler.Client.Update(ctx, existingACL)
+	suite.Require().NoError(err)
+
+	np = reconcile()
+	suite.Require().Len(np.Spec.Egress, 1)
+	suite.Assert().Equal("2.2.2.2/32", np.Spec.Egress[0].To[0].IPBlock.CIDR)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerPolicyServerSideApply() {
+	ctx := context.Background()
+	name := strings.Repeat("a", 70)
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{
+						IP: "1.1.1.1/32",
+					},
+				},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	reconcile := func() *netv1.NetworkPolicy {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+
+		np := &netv1.NetworkPolicy{}
+		err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-" + name}, np)
+		suite.Require().NoError(err)
+		return np
+	}
+
+	np := reconcile()
+	aclLabel := np.Labels[policyACLLabel]
+	suite.Assert().Len(aclLabel, validation.LabelValueMaxLength)
+	suite.Assert().Empty(validation.IsValidLabelValue(aclLabel))
+	suite.Assert().Equal(policyACLLabelValue(name), aclLabel)
+	suite.Assert().Equal(policyManagedByValue, np.Labels[policyManagedByLabel])
+	suite.Assert().True(metav1.IsControlledBy(np, acl))
+
+	// labels of others are kept by the apply
+	np.Labels["team"] = "a-team"
+	err := reconciler.Client.Update(ctx, np)
+	suite.Require().NoError(err)
+
+	secondNP := reconcile()
+	suite.Assert().Equal("a-team", secondNP.Labels["team"])
+	suite.Assert().Equal(np.ResourceVersion, secondNP.ResourceVersion)
+}
+
+func TestPolicyACLLabelValue(t *testing.T) {
+	assert.Equal(t, "myapp", policyACLLabelValue("myapp"))
+
+	long := strings.Repeat("a", 60) + "-" + strings.Repeat("b", 10)
+	value := policyACLLabelValue(long)
+	assert.Len(t, value, validation.LabelValueMaxLength)
+	assert.Empty(t, validation.IsValidLabelValue(value))
+	assert.NotEqual(t, value, policyACLLabelValue(long+"c"))
+}
+
+func (suite *ControllerSuite) TestACLReconcilerKubernetesService() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{KubernetesService: &v1alpha1.ACLSpecKubernetesService{Name: "api"}},
+				{KubernetesService: &v1alpha1.ACLSpecKubernetesService{Namespace: "databases", Name: "postgres"}},
+				{KubernetesService: &v1alpha1.ACLSpecKubernetesService{Name: "external"}},
+				{KubernetesService: &v1alpha1.ACLSpecKubernetesService{Name: "manual"}},
+				{KubernetesService: &v1alpha1.ACLSpecKubernetesService{Name: "missing"}},
+			},
+		},
+	}
+
+	api := &corev1.Service{
+		ObjectMeta: v1.ObjectMeta{Name: "api", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "10.96.0.10",
+			Selector:  map[string]string{"app": "api"},
+			Ports: []corev1.ServicePort{
+				{Name: "http", Port: 80, TargetPort: intstr.FromString("http")},
+				{Name: "grpc", Port: 9090, Protocol: corev1.ProtocolTCP},
+				{Name: "metrics", Port: 8080, TargetPort: intstr.FromString("http")},
+			},
+		},
+	}
+	headless := &corev1.Service{
+		ObjectMeta: v1.ObjectMeta{Name: "postgres", Namespace: "databases"},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Selector:  map[string]string{"app": "postgres"},
+			Ports: []corev1.ServicePort{
+				{Port: 5432, TargetPort: intstr.FromInt(5432)},
+			},
+		},
+	}
+	externalName := &corev1.Service{
+		ObjectMeta: v1.ObjectMeta{Name: "external", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Type:         corev1.ServiceTypeExternalName,
+			ExternalName: "db.example.com",
+		},
+	}
+	withoutSelector := &corev1.Service{
+		ObjectMeta: v1.ObjectMeta{Name: "manual", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "10.96.0.11",
+			Ports:     []corev1.ServicePort{{Port: 443}},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl, api, headless, externalName, withoutSelector).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]string{
+		`service default/external is an ExternalName service, use an externalDNS destination with "db.example.com"`,
+		"service default/manual has no selector, its endpoints can not be selected, use an externalIP destination",
+	}, existingACL.Status.Warnings)
+	suite.Require().Len(existingACL.Status.RuleErrors, 1)
+	suite.Assert().Equal("service default/missing not found", existingACL.Status.RuleErrors[0].Error)
+
+	existingNP := &netv1.NetworkPolicy{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-myapp"}, existingNP)
+	suite.Require().NoError(err)
+
+	tcp := corev1.ProtocolTCP
+	httpPort := intstr.FromString("http")
+	grpcPort := intstr.FromInt(9090)
+	postgresPort := intstr.FromInt(5432)
+	suite.Assert().Contains(existingNP.Spec.Egress, netv1.NetworkPolicyEgressRule{
+		To: []netv1.NetworkPolicyPeer{
+			{
+				PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+				NamespaceSelector: reconciler.namespaceSelector("default"),
+			},
+		},
+		Ports: []netv1.NetworkPolicyPort{
+			{Protocol: &tcp, Port: &httpPort},
+			{Protocol: &tcp, Port: &grpcPort},
+		},
+	})
+	suite.Assert().Contains(existingNP.Spec.Egress, netv1.NetworkPolicyEgressRule{
+		To: []netv1.NetworkPolicyPeer{
+			{
+				PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "postgres"}},
+				NamespaceSelector: reconciler.namespaceSelector("databases"),
+			},
+		},
+		Ports: []netv1.NetworkPolicyPort{
+			{Protocol: &tcp, Port: &postgresPort},
+		},
+	})
+}
+
+func (suite *ControllerSuite) TestACLReconcilerDisableServiceTranslation() {
+	ctx := context.Background()
+	svc := &corev1.Service{
+		ObjectMeta: v1.ObjectMeta{Name: "api", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "10.96.0.10",
+			Selector:  map[string]string{"app": "api"},
+		},
+	}
+	newACL := func(name string, disabled bool) *v1alpha1.ACL {
+		return &v1alpha1.ACL{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: v1alpha1.ACLSpec{
+				Source: v1alpha1.ACLSpecSource{
+					TsuruApp: "myapp",
+				},
+				Destinations: []v1alpha1.ACLSpecDestination{
+					{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.96.0.10"}},
+				},
+				DisableServiceTranslation: disabled,
+			},
+		}
+	}
+
+	reconciler := &ACLReconciler{
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	ipBlockPeer := netv1.NetworkPolicyPeer{
+		IPBlock: &netv1.IPBlock{CIDR: "10.96.0.10/32"},
+	}
+	podSelectorPeer := netv1.NetworkPolicyPeer{
+		PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+		NamespaceSelector: reconciler.namespaceSelector("default"),
+	}
+
+	tests := []struct {
+		acl           *v1alpha1.ACL
+		expectedPeers []netv1.NetworkPolicyPeer
+	}{
+		{
+			acl:           newACL("translated", false),
+			expectedPeers: []netv1.NetworkPolicyPeer{ipBlockPeer, podSelectorPeer},
+		},
+		{
+			acl:           newACL("raw", true),
+			expectedPeers: []netv1.NetworkPolicyPeer{ipBlockPeer},
+		},
+	}
+
+	for _, tt := range tests {
+		reconciler.Client = withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(tt.acl, svc).Build())
+		reconciler.serviceCache.Store(nil)
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(tt.acl),
+		})
+		suite.Require().NoError(err)
+
+		existingNP := &netv1.NetworkPolicy{}
+		err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-" + tt.acl.Name}, existingNP)
+		suite.Require().NoError(err)
+		suite.Require().Len(existingNP.Spec.Egress, 1, tt.acl.Name)
+		suite.Assert().ElementsMatch(tt.expectedPeers, existingNP.Spec.Egress[0].To, tt.acl.Name)
+	}
+}
+
+func (suite *ControllerSuite) TestACLReconcilerPaused() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        "myapp",
+			Namespace:   "default",
+			Annotations: map[string]string{pausedAnnotation: "true"},
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.1/32"}},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	reconcile := func() (controllerruntime.Result, *v1alpha1.ACL) {
+		result, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+
+		existingACL := &v1alpha1.ACL{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+		suite.Require().NoError(err)
+		return result, existingACL
+	}
+
+	result, existingACL := reconcile()
+	suite.Assert().Equal(controllerruntime.Result{}, result)
+	suite.Assert().True(meta.IsStatusConditionTrue(existingACL.Status.Conditions, v1alpha1.ACLConditionPaused))
+	suite.Assert().Empty(existingACL.Finalizers)
+
+	existingNP := &netv1.NetworkPolicy{}
+	err := reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-myapp"}, existingNP)
+	suite.Assert().True(k8sErrors.IsNotFound(err))
+
+	// removing the annotation resumes the reconciles
+	delete(existingACL.Annotations, pausedAnnotation)
+	err = reconciler.Client.Update(ctx, existingACL)
+	suite.Require().NoError(err)
+
+	result, existingACL = reconcile()
+	suite.Assert().True(result.Requeue)
+	suite.Assert().True(existingACL.Status.Ready)
+	suite.Assert().Nil(meta.FindStatusCondition(existingACL.Status.Conditions, v1alpha1.ACLConditionPaused))
+
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-myapp"}, existingNP)
+	suite.Require().NoError(err)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerPriority() {
+	ctx := context.Background()
+	newACL := func(name string, annotations map[string]string) *v1alpha1.ACL {
+		return &v1alpha1.ACL{
+			ObjectMeta: v1.ObjectMeta{
+				Name:        name,
+				Namespace:   "default",
+				Annotations: annotations,
+			},
+			Spec: v1alpha1.ACLSpec{
+				Source: v1alpha1.ACLSpecSource{
+					TsuruApp: name,
+				},
+				Destinations: []v1alpha1.ACLSpecDestination{
+					{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.1/32"}},
+				},
+			},
+		}
+	}
+	critical := newACL("myapp", map[string]string{aclPriorityAnnotation: "10"})
+	longTail := newACL("my-other-app", nil)
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(critical, longTail).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	reconcile := func(acl *v1alpha1.ACL) (controllerruntime.Result, *v1alpha1.ACL) {
+		result, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+
+		existingACL := &v1alpha1.ACL{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+		suite.Require().NoError(err)
+		return result, existingACL
+	}
+
+	q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer q.ShutDown()
+	eventHandler := reconciler.priorities.eventHandler()
+	eventHandler.Create(event.CreateEvent{Object: longTail}, q)
+	eventHandler.Create(event.CreateEvent{Object: critical}, q)
+	suite.Assert().Equal(1, q.Len())
+
+	// the long tail waits while the critical ACL is pending
+	result, existingACL := reconcile(longTail)
+	suite.Assert().Equal(controllerruntime.Result{RequeueAfter: aclPriorityRequeueDelay}, result)
+	suite.Assert().False(existingACL.Status.Ready)
+
+	result, existingACL = reconcile(critical)
+	suite.Assert().True(result.Requeue)
+	suite.Assert().True(existingACL.Status.Ready)
+
+	result, existingACL = reconcile(longTail)
+	suite.Assert().True(result.Requeue)
+	suite.Assert().True(existingACL.Status.Ready)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerSourceChange() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.1/32"}},
+			},
+		},
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+		Recorder: recorder,
+	}
+	reconcile := func(source v1alpha1.ACLSpecSource) (*v1alpha1.ACL, *netv1.NetworkPolicy) {
+		existingACL := &v1alpha1.ACL{}
+		err := reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+		suite.Require().NoError(err)
+		existingACL.Spec.Source = source
+		existingACL.Generation++
+		err = reconciler.Client.Update(ctx, existingACL)
+		suite.Require().NoError(err)
+
+		_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+		suite.Require().NoError(err)
+		existingNP := &netv1.NetworkPolicy{}
+		err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: existingACL.Status.NetworkPolicy}, existingNP)
+		suite.Require().NoError(err)
+		return existingACL, existingNP
+	}
+
+	existingACL, existingNP := reconcile(acl.Spec.Source)
+	suite.Assert().Equal("tsuruApp myapp", existingACL.Status.Source)
+	suite.Assert().Equal("Normal NetworkPolicyCreated NetworkPolicy acl-myapp has been created", <-recorder.Events)
+	suite.Assert().Empty(recorder.Events)
+
+	existingACL, existingNP = reconcile(v1alpha1.ACLSpecSource{
+		RpaasInstance: &v1alpha1.ACLSpecRpaasInstance{ServiceName: "rpaasv2", Instance: "my-instance"},
+	})
+	suite.Assert().Equal("acl-myapp", existingNP.Name)
+	suite.Assert().Equal(map[string]string{
+		"rpaas.extensions.tsuru.io/instance-name": "my-instance",
+		"rpaas.extensions.tsuru.io/service-name":  "rpaasv2",
+	}, existingNP.Spec.PodSelector.MatchLabels)
+	suite.Assert().Equal("rpaasInstance rpaasv2/my-instance", existingACL.Status.Source)
+	suite.Assert().Equal("Normal NetworkPolicyUpdated NetworkPolicy acl-myapp has been updated", <-recorder.Events)
+	suite.Assert().Equal("Normal SourceChanged spec.source changed from tsuruApp myapp to rpaasInstance rpaasv2/my-instance, NetworkPolicy acl-myapp selects the pods of the new source", <-recorder.Events)
+
+	// the changes of egress are recorded with the update
+	existingACL.Spec.Destinations = []v1alpha1.ACLSpecDestination{
+		{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.2/32"}},
+	}
+	err := reconciler.Client.Update(ctx, existingACL)
+	suite.Require().NoError(err)
+	existingACL, existingNP = reconcile(existingACL.Spec.Source)
+	suite.Assert().Equal("Normal NetworkPolicyUpdated NetworkPolicy acl-myapp has been updated, egress added: 10.0.0.2/32 any port; egress removed: 10.0.0.1/32 any port", <-recorder.Events)
+	suite.Assert().Empty(recorder.Events)
+
+	// labels written by others, like a policy from before server-side apply, are not merged, apply replaces
+	// the selector as a whole
+	existingNP.Spec.PodSelector.MatchLabels["tsuru.io/app-process"] = "web"
+	err = reconciler.Client.Update(ctx, existingNP)
+	suite.Require().NoError(err)
+
+	existingACL, existingNP = reconcile(v1alpha1.ACLSpecSource{TsuruApp: "my-other-app"})
+	suite.Assert().Equal(map[string]string{"tsuru.io/app-name": "my-other-app"}, existingNP.Spec.PodSelector.MatchLabels)
+	suite.Assert().Equal("tsuruApp my-other-app", existingACL.Status.Source)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerDeadline() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:       "myapp",
+			Namespace:  "default",
+			Generation: 1,
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{RuleID: "rule-1", ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.1/32"}},
+				{RuleID: "rule-2", ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.2/32"}},
+				{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.3/32"}},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+		// every reconcile resolves a single destination
+		ReconcileDeadline: time.Nanosecond,
+	}
+	reconcile := func() (controllerruntime.Result, *v1alpha1.ACL, []string) {
+		result, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+
+		existingACL := &v1alpha1.ACL{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+		suite.Require().NoError(err)
+		existingNP := &netv1.NetworkPolicy{}
+		err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-myapp"}, existingNP)
+		suite.Require().NoError(err)
+
+		cidrs := []string{}
+		for _, rule := range existingNP.Spec.Egress {
+			for _, to := range rule.To {
+				if to.IPBlock != nil {
+					cidrs = append(cidrs, to.IPBlock.CIDR)
+				}
+			}
+		}
+		return result, existingACL, cidrs
+	}
+
+	result, existingACL, cidrs := reconcile()
+	suite.Assert().Equal(controllerruntime.Result{Requeue: true}, result)
+	suite.Assert().Equal([]string{"10.0.0.1/32"}, cidrs)
+	suite.Assert().True(existingACL.Status.InProgress)
+	suite.Assert().False(existingACL.Status.Ready)
+	suite.Assert().Equal("1 of 3 destinations are resolved, the remaining destinations are resolved on the next reconcile", existingACL.Status.Reason)
+	readyCondition := meta.FindStatusCondition(existingACL.Status.Conditions, v1alpha1.ACLConditionReady)
+	suite.Require().NotNil(readyCondition)
+	suite.Assert().Equal("InProgress", readyCondition.Reason)
+	suite.Assert().Len(existingACL.Status.ResolvedDestinations, 3)
+
+	// the policy applied for this generation is kept until every destination is resolved
+	result, existingACL, cidrs = reconcile()
+	suite.Assert().Equal(controllerruntime.Result{Requeue: true}, result)
+	suite.Assert().Equal([]string{"10.0.0.1/32"}, cidrs)
+	suite.Assert().Equal("2 of 3 destinations are resolved, the remaining destinations are resolved on the next reconcile", existingACL.Status.Reason)
+
+	result, existingACL, cidrs = reconcile()
+	suite.Assert().NotZero(result.RequeueAfter)
+	suite.Assert().Equal([]string{"10.0.0.1/32", "10.0.0.2/32", "10.0.0.3/32"}, cidrs)
+	suite.Assert().False(existingACL.Status.InProgress)
+	suite.Assert().True(existingACL.Status.Ready)
+	suite.Assert().Equal("", existingACL.Status.Reason)
+
+	// a later reconcile running out of its deadline does not drop the remaining destinations,
+	// even the ones without ruleID, the memo is reused while no input changes
+	reconciler.memo.Forget(client.ObjectKeyFromObject(acl))
+	result, existingACL, cidrs = reconcile()
+	suite.Assert().Equal(controllerruntime.Result{Requeue: true}, result)
+	suite.Assert().Equal([]string{"10.0.0.1/32", "10.0.0.2/32", "10.0.0.3/32"}, cidrs)
+	suite.Assert().True(existingACL.Status.InProgress)
+	for i := 0; i < 2; i++ {
+		_, existingACL, cidrs = reconcile()
+	}
+	suite.Assert().Equal([]string{"10.0.0.1/32", "10.0.0.2/32", "10.0.0.3/32"}, cidrs)
+	suite.Assert().False(existingACL.Status.InProgress)
+
+	// a new generation starts over, the remaining destinations keep their stale rules
+	existingACL.Spec.Destinations[0].ExternalIP.IP = "10.0.0.4/32"
+	existingACL.Generation++
+	err := reconciler.Client.Update(ctx, existingACL)
+	suite.Require().NoError(err)
+
+	result, existingACL, cidrs = reconcile()
+	suite.Assert().Equal(controllerruntime.Result{Requeue: true}, result)
+	suite.Assert().ElementsMatch([]string{"10.0.0.4/32", "10.0.0.2/32"}, cidrs)
+	suite.Assert().True(existingACL.Status.InProgress)
+	suite.Assert().True(existingACL.Status.ResolvedDestinations[1].Stale)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerSelector() {
+	ctx := context.Background()
+	newACL := func(name, shard string) *v1alpha1.ACL {
+		return &v1alpha1.ACL{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels:    map[string]string{"shard": shard},
+			},
+			Spec: v1alpha1.ACLSpec{
+				Source: v1alpha1.ACLSpecSource{
+					TsuruApp: name,
+				},
+				Destinations: []v1alpha1.ACLSpecDestination{
+					{ExternalDNS: &v1alpha1.ACLSpecExternalDNS{Name: "example.com"}},
+				},
+			},
+		}
+	}
+	aclA := newACL("myapp", "a")
+	aclB := newACL("other-app", "b")
+
+	selector, err := labels.Parse("shard=a")
+	suite.Require().NoError(err)
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(aclA, aclB).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+		Selector: selector,
+	}
+
+	for _, acl := range []*v1alpha1.ACL{aclA, aclB} {
+		_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+	}
+
+	// the other ACL is left untouched
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(aclA), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().Contains(existingACL.Finalizers, aclCleanupFinalizer)
+
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(aclB), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().Empty(existingACL.Finalizers)
+	suite.Assert().Equal(v1alpha1.ACLStatus{}, existingACL.Status)
+
+	suite.Assert().Equal([]reconcile.Request{
+		{NamespacedName: client.ObjectKeyFromObject(aclA)},
+	}, reconciler.reconcileRequestsForIndex(externalDNSIndex, "example.com"))
+
+	suite.Assert().True(reconciler.selects(aclA))
+	suite.Assert().False(reconciler.selects(aclB))
+	suite.Assert().False(reconciler.selects(&v1alpha1.ACL{}))
+	suite.Assert().True((&ACLReconciler{}).selects(aclB))
+}
+
+func (suite *ControllerSuite) TestACLReconcilerExtraEgress() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+			Annotations: map[string]string{
+				v1alpha1.ExtraEgressAnnotation: `[{"to": [{"ipBlock": {"cidr": "192.168.0.1"}}, {"namespaceSelector": {"matchLabels": {"name": "monitoring"}}}], "ports": [{"protocol": "UDP", "port": 8125}]}]`,
+			},
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.1/32"}},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	reconcile := func() (*v1alpha1.ACL, *netv1.NetworkPolicy) {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+
+		existingACL := &v1alpha1.ACL{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+		suite.Require().NoError(err)
+		existingNP := &netv1.NetworkPolicy{}
+		err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-myapp"}, existingNP)
+		if !k8sErrors.IsNotFound(err) {
+			suite.Require().NoError(err)
+		}
+		return existingACL, existingNP
+	}
+
+	existingACL, existingNP := reconcile()
+	suite.Assert().True(existingACL.Status.Ready)
+	suite.Assert().Equal(1, existingACL.Status.ExtraEgressRules)
+	udp := corev1.ProtocolUDP
+	suite.Assert().Equal([]netv1.NetworkPolicyEgressRule{
+		{
+			To: []netv1.NetworkPolicyPeer{{IPBlock: &netv1.IPBlock{CIDR: "10.0.0.1/32"}}},
+		},
+		{
+			To: []netv1.NetworkPolicyPeer{
+				{IPBlock: &netv1.IPBlock{CIDR: "192.168.0.1/32"}},
+				{NamespaceSelector: &v1.LabelSelector{MatchLabels: map[string]string{"name": "monitoring"}}},
+			},
+			Ports: []netv1.NetworkPolicyPort{{Protocol: &udp, Port: &intstr.IntOrString{IntVal: 8125}}},
+		},
+	}, existingNP.Spec.Egress)
+
+	// a malformed annotation keeps the policy as it is
+	existingACL.Annotations[v1alpha1.ExtraEgressAnnotation] = `[{"to": []}]`
+	err := reconciler.Client.Update(ctx, existingACL)
+	suite.Require().NoError(err)
+
+	existingACL, existingNP = reconcile()
+	suite.Assert().False(existingACL.Status.Ready)
+	suite.Assert().Equal("invalid annotation acl.extensions.tsuru.io/extra-egress, err: rule 0: to is required, a rule without peers allows every destination", existingACL.Status.Reason)
+	suite.Assert().Len(existingNP.Spec.Egress, 2)
+
+	delete(existingACL.Annotations, v1alpha1.ExtraEgressAnnotation)
+	err = reconciler.Client.Update(ctx, existingACL)
+	suite.Require().NoError(err)
+
+	existingACL, existingNP = reconcile()
+	suite.Assert().True(existingACL.Status.Ready)
+	suite.Assert().Equal(0, existingACL.Status.ExtraEgressRules)
+	suite.Assert().Len(existingNP.Spec.Egress, 1)
+}
+
+func TestParseExtraEgress(t *testing.T) {
+	rules, err := v1alpha1.ParseExtraEgress(`[{"to": [{"ipBlock": {"cidr": "10.0.0.0/8", "except": ["10.1.1.1"]}}], "ports": [{"port": "http"}, {"protocol": "TCP", "port": 8000, "endPort": 8080}, {"protocol": "UDP"}]}]`)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, &netv1.IPBlock{CIDR: "10.0.0.0/8", Except: []string{"10.1.1.1/32"}}, rules[0].To[0].IPBlock)
+
+	tests := []struct {
+		value         string
+		expectedError string
+	}{
+		{
+			value:         `{"to": []}`,
+			expectedError: "could not decode a JSON list of egress rules: json: cannot unmarshal object into Go value of type []v1.NetworkPolicyEgressRule",
+		},
+		{
+			value:         `[{"to": [{"ipBlock": {"cidr": "10.0.0.1"}}], "from": []}]`,
+			expectedError: `could not decode a JSON list of egress rules: json: unknown field "from"`,
+		},
+		{
+			value:         `[{"to": [{"ipBlock": {"cidr": "10.0.0.1"}}]}] []`,
+			expectedError: "could not decode a JSON list of egress rules: unexpected data after the list",
+		},
+		{
+			value:         `[]`,
+			expectedError: "no egress rules, remove the annotation instead",
+		},
+		{
+			value:         `[{"ports": [{"port": 443}]}]`,
+			expectedError: "rule 0: to is required, a rule without peers allows every destination",
+		},
+		{
+			value:         `[{"to": [{"ipBlock": {"cidr": "10.0.0.300"}}]}]`,
+			expectedError: `rule 0: peer 0: invalid externalIP "10.0.0.300": invalid IP address: 10.0.0.300`,
+		},
+		{
+			value:         `[{"to": [{"ipBlock": {"cidr": "10.0.0.0/8"}, "podSelector": {}}]}]`,
+			expectedError: "rule 0: peer 0: ipBlock can not be set with podSelector or namespaceSelector",
+		},
+		{
+			value:         `[{"to": [{}]}]`,
+			expectedError: "rule 0: peer 0: one of ipBlock, podSelector or namespaceSelector is required",
+		},
+		{
+			value:         `[{"to": [{"podSelector": {"matchExpressions": [{"key": "app", "operator": "Near"}]}}]}]`,
+			expectedError: `rule 0: peer 0: invalid selector: "Near" is not a valid pod selector operator`,
+		},
+		{
+			value:         `[{"to": [{"podSelector": {}}], "ports": [{"protocol": "ICMP", "port": 1}]}]`,
+			expectedError: `rule 0: port 0: invalid protocol "ICMP", use TCP, UDP or SCTP`,
+		},
+		{
+			value:         `[{"to": [{"podSelector": {}}], "ports": [{"port": 70000}]}]`,
+			expectedError: "rule 0: port 0: port 70000 must be between 1 and 65535",
+		},
+		{
+			value:         `[{"to": [{"podSelector": {}}], "ports": [{"port": 8080, "endPort": 8000}]}]`,
+			expectedError: "rule 0: port 0: invalid port range 8080-8000, endPort must be greater than or equal to number",
+		},
+		{
+			value:         `[{"to": [{"podSelector": {}}], "ports": [{"endPort": 8000}]}]`,
+			expectedError: "rule 0: port 0: endPort requires port",
+		},
+		{
+			value:         `[{"to": [{"podSelector": {}}]}, {"to": [{"podSelector": {}}], "ports": [{"port": "Not_A_Name"}]}]`,
+			expectedError: `rule 1: port 0: invalid port name "Not_A_Name": must contain only alpha-numeric characters (a-z, 0-9), and hyphens (-)`,
+		},
+		{
+			value:         "[" + strings.Repeat(`{"to": [{"podSelector": {}}]},`, v1alpha1.MaxExtraEgressRules) + `{"to": [{"podSelector": {}}]}]`,
+			expectedError: "33 rules exceed the maximum of 32 rules",
+		},
+		{
+			value:         strings.Repeat(" ", v1alpha1.MaxExtraEgressSize+1),
+			expectedError: "16385 bytes exceed the maximum of 16384 bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := v1alpha1.ParseExtraEgress(tt.value)
+		assert.EqualError(t, err, tt.expectedError, tt.value)
+	}
+}
+
+// conflictClient fails the next patches of NetworkPolicies with a conflict
+type conflictClient struct {
+	client.Client
+	conflicts int
+}
+
+func (c *conflictClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if _, ok := obj.(*netv1.NetworkPolicy); ok && c.conflicts > 0 {
+		c.conflicts--
+		return k8sErrors.NewConflict(netv1.Resource("networkpolicies"), obj.GetName(), errors.New("the object has been modified"))
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerApplyConflict() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.1/32"}},
+			},
+		},
+	}
+
+	conflicts := &conflictClient{
+		Client:    withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		conflicts: 1,
+	}
+	reconciler := &ACLReconciler{
+		Client:   conflicts,
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	reconcile := func() (controllerruntime.Result, *v1alpha1.ACL, error) {
+		result, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+
+		existingACL := &v1alpha1.ACL{}
+		getErr := reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+		suite.Require().NoError(getErr)
+		return result, existingACL, err
+	}
+
+	// a conflict is retried shortly without touching the status
+	result, existingACL, err := reconcile()
+	suite.Require().NoError(err)
+	suite.Assert().Equal(controllerruntime.Result{RequeueAfter: applyConflictRequeueInterval}, result)
+	suite.Assert().Equal("", existingACL.Status.Reason)
+	suite.Assert().Nil(meta.FindStatusCondition(existingACL.Status.Conditions, v1alpha1.ACLConditionReady))
+
+	result, existingACL, err = reconcile()
+	suite.Require().NoError(err)
+	suite.Assert().True(result.Requeue)
+	suite.Assert().True(existingACL.Status.Ready)
+	suite.Assert().Equal("acl-myapp", existingACL.Status.NetworkPolicy)
+
+	// persistent conflicts make the ACL unready
+	conflicts.conflicts = maxApplyConflicts
+	existingACL.Spec.Destinations[0].ExternalIP.IP = "10.0.0.2/32"
+	existingACL.Generation++
+	err = reconciler.Client.Update(ctx, existingACL)
+	suite.Require().NoError(err)
+
+	for i := 1; i < maxApplyConflicts; i++ {
+		result, existingACL, err = reconcile()
+		suite.Require().NoError(err)
+		suite.Assert().Equal(controllerruntime.Result{RequeueAfter: applyConflictRequeueInterval}, result)
+		suite.Assert().True(existingACL.Status.Ready)
+	}
+
+	_, existingACL, err = reconcile()
+	suite.Require().Error(err)
+	suite.Assert().True(k8sErrors.IsConflict(err))
+	suite.Assert().False(existingACL.Status.Ready)
+	suite.Assert().Contains(existingACL.Status.Reason, "policy changed while it was applied 3 times in a row")
+
+	result, existingACL, err = reconcile()
+	suite.Require().NoError(err)
+	suite.Assert().True(result.Requeue)
+	suite.Assert().True(existingACL.Status.Ready)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerOverlappingPorts() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.1", Ports: v1alpha1.ACLSpecProtoPorts{{Protocol: "TCP", Number: 443}}}},
+				{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.1/32", Ports: v1alpha1.ACLSpecProtoPorts{{Protocol: "UDP", Number: 53}, {Protocol: "TCP", Number: 8000, EndPort: 8080}}}},
+				{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.2", Ports: v1alpha1.ACLSpecProtoPorts{{Protocol: "TCP", Number: 443}}}},
+				{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.2/32", Ports: v1alpha1.ACLSpecProtoPorts{{Protocol: "TCP", Number: 443}}}},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	// the same ports of 10.0.0.2 are not reported
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().True(existingACL.Status.Ready)
+	suite.Assert().Equal([]string{
+		"10.0.0.1/32 is allowed by externalIP 10.0.0.1 on TCP/443 and by externalIP 10.0.0.1/32 on TCP/8000-8080,UDP/53, the policy allows the union of their ports",
+	}, existingACL.Status.Warnings)
+
+	existingNP := &netv1.NetworkPolicy{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-myapp"}, existingNP)
+	suite.Require().NoError(err)
+	suite.Assert().Len(existingNP.Spec.Egress, 2)
+}
+
+func TestDescribePorts(t *testing.T) {
+	tcp, udp := corev1.ProtocolTCP, corev1.ProtocolUDP
+	port := intstr.FromInt(443)
+	namedPort := intstr.FromString("http")
+	endPort := int32(8080)
+
+	assert.Equal(t, "all ports", describePorts(nil))
+	assert.Equal(t, "TCP/443", describePorts([]netv1.NetworkPolicyPort{{Port: &port}}))
+	assert.Equal(t, "TCP/443-8080,TCP/http,UDP", describePorts([]netv1.NetworkPolicyPort{
+		{Protocol: &udp},
+		{Protocol: &tcp, Port: &namedPort},
+		{Protocol: &tcp, Port: &port, EndPort: &endPort},
+	}))
+}
+
+func (suite *ControllerSuite) TestACLReconcilerAddressFilter() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{ExternalDNS: &v1alpha1.ACLSpecExternalDNS{Name: "public.io"}},
+				{ExternalDNS: &v1alpha1.ACLSpecExternalDNS{Name: "private.io"}},
+			},
+		},
+	}
+
+	newDNSEntry := func(host string, addresses ...string) *v1alpha1.ACLDNSEntry {
+		dnsEntry := &v1alpha1.ACLDNSEntry{
+			ObjectMeta: v1.ObjectMeta{Name: host},
+			Spec:       v1alpha1.ACLDNSEntrySpec{Host: host},
+			Status:     v1alpha1.ACLDNSEntryStatus{Ready: true},
+		}
+		for _, address := range addresses {
+			dnsEntry.Status.IPs = append(dnsEntry.Status.IPs, v1alpha1.ACLDNSEntryStatusIP{
+				Address:    address,
+				ValidUntil: time.Now().Format(time.RFC3339),
+			})
+		}
+		return dnsEntry
+	}
+
+	reconciler := &ACLReconciler{
+		Client: withServerSideApply(fake.NewClientBuilder().
+			WithScheme(scheme.Scheme).
+			WithRuntimeObjects(acl, newDNSEntry("public.io", "1.1.1.1", "10.0.0.1"), newDNSEntry("private.io", "192.168.0.1")).
+			Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+		AddressFilter: &v1alpha1.ACLSpecAddressFilter{
+			Deny: []string{"10.0.0.0/8", "192.168.0.0/16"},
+		},
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Require().Len(existingACL.Status.RuleErrors, 1)
+	suite.Assert().Equal("every address resolved for private.io is rejected by addressFilter: 192.168.0.1", existingACL.Status.RuleErrors[0].Error)
+	suite.Require().Len(existingACL.Status.ResolvedDestinations, 2)
+	suite.Assert().Equal([]string{"1.1.1.1/32"}, existingACL.Status.ResolvedDestinations[0].CIDRs)
+	suite.Assert().Equal([]string{"10.0.0.1"}, existingACL.Status.ResolvedDestinations[0].RejectedIPs)
+	suite.Assert().False(existingACL.Status.ResolvedDestinations[1].HasRules)
+	suite.Assert().Equal([]string{"192.168.0.1"}, existingACL.Status.ResolvedDestinations[1].RejectedIPs)
+	suite.Assert().Equal([]string{
+		"externalDNS public.io resolved to addresses rejected by addressFilter: 10.0.0.1",
+		"externalDNS private.io resolved to addresses rejected by addressFilter: 192.168.0.1",
+	}, existingACL.Status.Warnings)
+
+	existingNP := &netv1.NetworkPolicy{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-myapp"}, existingNP)
+	suite.Require().NoError(err)
+	suite.Require().Len(existingNP.Spec.Egress, 1)
+	suite.Assert().Equal([]netv1.NetworkPolicyPeer{{IPBlock: &netv1.IPBlock{CIDR: "1.1.1.1/32"}}}, existingNP.Spec.Egress[0].To)
+
+	// the filter of ACL replaces the one of operator
+	existingACL.Spec.AddressFilter = &v1alpha1.ACLSpecAddressFilter{Allow: []string{"192.168.0.0/16"}}
+	err = reconciler.Client.Update(ctx, existingACL)
+	suite.Require().NoError(err)
+	_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Require().Len(existingACL.Status.ResolvedDestinations, 2)
+	suite.Assert().Equal([]string{"1.1.1.1", "10.0.0.1"}, existingACL.Status.ResolvedDestinations[0].RejectedIPs)
+	suite.Assert().Equal([]string{"192.168.0.1/32"}, existingACL.Status.ResolvedDestinations[1].CIDRs)
+	suite.Assert().Empty(existingACL.Status.ResolvedDestinations[1].RejectedIPs)
+}
+
+func TestFilterAddresses(t *testing.T) {
+	addresses := []string{"1.1.1.1", "10.0.0.1", "::ffff:10.0.0.2", "2001:db8::1"}
+
+	permitted, rejected, err := filterAddresses(addresses, nil)
+	require.NoError(t, err)
+	assert.Equal(t, addresses, permitted)
+	assert.Empty(t, rejected)
+
+	permitted, rejected, err = filterAddresses(addresses, &v1alpha1.ACLSpecAddressFilter{Deny: []string{"10.0.0.0/8"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.1.1.1", "2001:db8::1"}, permitted)
+	assert.Equal(t, []string{"10.0.0.1", "::ffff:10.0.0.2"}, rejected)
+
+	permitted, rejected, err = filterAddresses(addresses, &v1alpha1.ACLSpecAddressFilter{Allow: []string{"10.0.0.0/8", "2001:db8::/32"}, Deny: []string{"10.0.0.2"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "2001:db8::1"}, permitted)
+	assert.Equal(t, []string{"1.1.1.1", "::ffff:10.0.0.2"}, rejected)
+
+	_, _, err = filterAddresses(addresses, &v1alpha1.ACLSpecAddressFilter{Deny: []string{"10.0.0.0/33"}})
+	assert.Error(t, err)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerKeepsLastAppliedPolicy() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:       "myapp",
+			Namespace:  "default",
+			Generation: 1,
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "2.2.2.2"}},
+				{ExternalDNS: &v1alpha1.ACLSpecExternalDNS{Name: "myapp.io"}},
+			},
+		},
+	}
+	dnsEntry := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{Name: "myapp.io"},
+		Spec:       v1alpha1.ACLDNSEntrySpec{Host: "myapp.io"},
+		Status: v1alpha1.ACLDNSEntryStatus{
+			Ready: true,
+			IPs: []v1alpha1.ACLDNSEntryStatusIP{
+				{Address: "1.1.1.1", ValidUntil: time.Now().Format(time.RFC3339)},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl, dnsEntry).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	reconcile := func() (*v1alpha1.ACL, *netv1.NetworkPolicy) {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+
+		existingACL := &v1alpha1.ACL{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+		suite.Require().NoError(err)
+		existingNP := &netv1.NetworkPolicy{}
+		err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-myapp"}, existingNP)
+		suite.Require().NoError(err)
+		return existingACL, existingNP
+	}
+
+	existingACL, existingNP := reconcile()
+	suite.Assert().True(existingACL.Status.Ready)
+	suite.Require().NotNil(existingACL.Status.LastApplied)
+	suite.Assert().NotEmpty(existingACL.Status.LastApplied.Hash)
+	suite.Assert().Equal(int64(1), existingACL.Status.LastApplied.Generation)
+	lastApplied := *existingACL.Status.LastApplied
+	appliedEgress := existingNP.Spec.Egress
+	suite.Require().Len(appliedEgress, 1)
+	suite.Assert().Len(appliedEgress[0].To, 2)
+
+	err := reconciler.Client.Get(ctx, client.ObjectKeyFromObject(dnsEntry), dnsEntry)
+	suite.Require().NoError(err)
+	dnsEntry.Status.Ready = false
+	dnsEntry.Status.Reason = "lookup myapp.io: no such host"
+	err = reconciler.Client.Status().Update(ctx, dnsEntry)
+	suite.Require().NoError(err)
+
+	// the policy is not rewritten without the peers of myapp.io
+	existingACL, existingNP = reconcile()
+	suite.Assert().Equal(appliedEgress, existingNP.Spec.Egress)
+	suite.Assert().False(existingACL.Status.Ready)
+	suite.Assert().Equal(lastApplied, *existingACL.Status.LastApplied)
+	suite.Assert().Equal("could not resolve externalDNS myapp.io, the policy applied at "+lastApplied.Timestamp+" is kept, see status.errors", existingACL.Status.Reason)
+	suite.Require().Len(existingACL.Status.RuleErrors, 1)
+	suite.Assert().Equal("lookup myapp.io: no such host", existingACL.Status.RuleErrors[0].Error)
+	degraded := meta.FindStatusCondition(existingACL.Status.Conditions, v1alpha1.ACLConditionDegraded)
+	suite.Require().NotNil(degraded)
+	suite.Assert().Equal(metav1.ConditionTrue, degraded.Status)
+	suite.Assert().Equal(conditionReasonLastAppliedKept, degraded.Reason)
+
+	dnsEntry.Status.Ready = true
+	dnsEntry.Status.Reason = ""
+	dnsEntry.Status.IPs = append(dnsEntry.Status.IPs, v1alpha1.ACLDNSEntryStatusIP{Address: "1.1.1.2", ValidUntil: time.Now().Format(time.RFC3339)})
+	err = reconciler.Client.Status().Update(ctx, dnsEntry)
+	suite.Require().NoError(err)
+
+	existingACL, existingNP = reconcile()
+	suite.Assert().True(existingACL.Status.Ready)
+	suite.Assert().Empty(existingACL.Status.RuleErrors)
+	suite.Assert().NotEqual(lastApplied.Hash, existingACL.Status.LastApplied.Hash)
+	suite.Require().Len(existingNP.Spec.Egress, 1)
+	suite.Assert().Len(existingNP.Spec.Egress[0].To, 3)
+	suite.Assert().True(meta.IsStatusConditionFalse(existingACL.Status.Conditions, v1alpha1.ACLConditionDegraded))
+}
+
+func TestACLScheduleOpen(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	businessHours := []v1alpha1.ACLSpecScheduleWindow{{Start: "09:00", End: "18:00"}}
+	tests := []struct {
+		name         string
+		schedule     v1alpha1.ACLSpecSchedule
+		now          time.Time
+		expectedOpen bool
+		expectedNext time.Time
+	}{
+		{
+			name:         "open",
+			schedule:     v1alpha1.ACLSpecSchedule{TimeZone: "UTC", Windows: businessHours},
+			now:          time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC),
+			expectedOpen: true,
+			expectedNext: time.Date(2026, 10, 12, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "closed",
+			schedule:     v1alpha1.ACLSpecSchedule{TimeZone: "UTC", Windows: businessHours},
+			now:          time.Date(2026, 10, 12, 18, 0, 0, 0, time.UTC),
+			expectedOpen: false,
+			expectedNext: time.Date(2026, 10, 13, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "time zone",
+			schedule:     v1alpha1.ACLSpecSchedule{TimeZone: "America/Sao_Paulo", Windows: businessHours},
+			now:          time.Date(2026, 10, 12, 11, 0, 0, 0, time.UTC),
+			expectedOpen: false,
+			expectedNext: time.Date(2026, 10, 12, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "overnight",
+			schedule: v1alpha1.ACLSpecSchedule{TimeZone: "UTC", Windows: []v1alpha1.ACLSpecScheduleWindow{
+				{Days: []v1alpha1.Weekday{"Mon"}, Start: "22:00", End: "06:00"},
+			}},
+			now:          time.Date(2026, 10, 13, 5, 0, 0, 0, time.UTC),
+			expectedOpen: true,
+			expectedNext: time.Date(2026, 10, 13, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "weekly",
+			schedule: v1alpha1.ACLSpecSchedule{TimeZone: "UTC", Windows: []v1alpha1.ACLSpecScheduleWindow{
+				{Days: []v1alpha1.Weekday{"Sat"}, Start: "10:00", End: "12:00"},
+			}},
+			now:          time.Date(2026, 10, 17, 12, 30, 0, 0, time.UTC),
+			expectedOpen: false,
+			expectedNext: time.Date(2026, 10, 24, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "adjacent windows",
+			schedule: v1alpha1.ACLSpecSchedule{TimeZone: "UTC", Windows: []v1alpha1.ACLSpecScheduleWindow{
+				{Start: "00:00", End: "02:00"},
+				{Start: "20:00", End: "00:00"},
+			}},
+			now:          time.Date(2026, 10, 12, 21, 0, 0, 0, time.UTC),
+			expectedOpen: true,
+			expectedNext: time.Date(2026, 10, 13, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			// clocks move from 02:00 to 03:00, the window is open for 3 hours
+			name: "daylight saving time",
+			schedule: v1alpha1.ACLSpecSchedule{TimeZone: "America/New_York", Windows: []v1alpha1.ACLSpecScheduleWindow{
+				{Start: "01:00", End: "05:00"},
+			}},
+			now:          time.Date(2026, 3, 8, 1, 30, 0, 0, newYork),
+			expectedOpen: true,
+			expectedNext: time.Date(2026, 3, 8, 5, 0, 0, 0, newYork),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			open, next, err := tt.schedule.Open(tt.now)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedOpen, open)
+			assert.True(t, tt.expectedNext.Equal(next), "expected %s, got %s", tt.expectedNext, next)
+		})
+	}
+
+	invalid := []struct {
+		schedule      v1alpha1.ACLSpecSchedule
+		expectedError string
+	}{
+		{
+			schedule:      v1alpha1.ACLSpecSchedule{Windows: businessHours},
+			expectedError: `invalid timeZone "", an IANA time zone like America/Sao_Paulo is required`,
+		},
+		{
+			schedule:      v1alpha1.ACLSpecSchedule{TimeZone: "UTC"},
+			expectedError: "at least one window is required",
+		},
+		{
+			schedule:      v1alpha1.ACLSpecSchedule{TimeZone: "UTC", Windows: []v1alpha1.ACLSpecScheduleWindow{{Start: "24:00", End: "06:00"}}},
+			expectedError: `invalid window 0: invalid start: "24:00" is not a time of day as HH:MM`,
+		},
+		{
+			schedule:      v1alpha1.ACLSpecSchedule{TimeZone: "UTC", Windows: []v1alpha1.ACLSpecScheduleWindow{{Start: "06:00", End: "06:00"}}},
+			expectedError: "invalid window 0: start and end are both 06:00",
+		},
+		{
+			schedule:      v1alpha1.ACLSpecSchedule{TimeZone: "UTC", Windows: []v1alpha1.ACLSpecScheduleWindow{{Days: []v1alpha1.Weekday{"Monday"}, Start: "06:00", End: "08:00"}}},
+			expectedError: `invalid window 0: invalid day "Monday", use one of Sun, Mon, Tue, Wed, Thu, Fri or Sat`,
+		},
+	}
+
+	for _, tt := range invalid {
+		_, _, err := tt.schedule.Open(time.Now())
+		assert.EqualError(t, err, tt.expectedError)
+	}
+}
+
+func (suite *ControllerSuite) TestACLReconcilerSchedule() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "maintenance",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.1"}},
+			},
+			Schedule: &v1alpha1.ACLSpecSchedule{
+				TimeZone: "UTC",
+				Windows:  []v1alpha1.ACLSpecScheduleWindow{{Start: "02:00", End: "04:00"}},
+			},
+		},
+	}
+
+	now := time.Date(2026, 10, 12, 1, 0, 0, 0, time.UTC)
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+		// the transitions come before the regular requeue
+		RequeueInterval: 48 * time.Hour,
+		now:             func() time.Time { return now },
+	}
+	reconcile := func() (controllerruntime.Result, *v1alpha1.ACL, *netv1.NetworkPolicy) {
+		result, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+
+		existingACL := &v1alpha1.ACL{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+		suite.Require().NoError(err)
+
+		existingNP := &netv1.NetworkPolicy{}
+		err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-maintenance"}, existingNP)
+		suite.Require().NoError(err)
+		return result, existingACL, existingNP
+	}
+
+	// closed, the policy denies every egress
+	result, existingACL, existingNP := reconcile()
+	suite.Assert().Equal(time.Hour, result.RequeueAfter)
+	suite.Assert().True(existingACL.Status.Ready)
+	suite.Assert().Equal(&v1alpha1.ACLStatusSchedule{Open: false, NextTransition: "2026-10-12T02:00:00Z"}, existingACL.Status.Schedule)
+	suite.Assert().Empty(existingNP.Spec.Egress)
+	suite.Assert().Contains(existingNP.Spec.PolicyTypes, netv1.PolicyTypeEgress)
+
+	// open, the rules of destinations are applied until the window closes
+	now = time.Date(2026, 10, 12, 3, 55, 0, 0, time.UTC)
+	result, existingACL, existingNP = reconcile()
+	suite.Assert().Equal(5*time.Minute, result.RequeueAfter)
+	suite.Assert().Equal(&v1alpha1.ACLStatusSchedule{Open: true, NextTransition: "2026-10-12T04:00:00Z"}, existingACL.Status.Schedule)
+	suite.Require().Len(existingNP.Spec.Egress, 1)
+	suite.Assert().Equal("10.0.0.1/32", existingNP.Spec.Egress[0].To[0].IPBlock.CIDR)
+
+	// the memo of the open policy is not reused after the window closes
+	now = time.Date(2026, 10, 12, 4, 0, 0, 0, time.UTC)
+	result, existingACL, existingNP = reconcile()
+	suite.Assert().Equal(22*time.Hour, result.RequeueAfter)
+	suite.Assert().False(existingACL.Status.Schedule.Open)
+	suite.Assert().Empty(existingNP.Spec.Egress)
+
+	existingACL.Spec.Schedule.TimeZone = "Mars/Olympus_Mons"
+	err := reconciler.Client.Update(ctx, existingACL)
+	suite.Require().NoError(err)
+	_, existingACL, _ = reconcile()
+	suite.Assert().False(existingACL.Status.Ready)
+	suite.Assert().Equal(`invalid spec.schedule, err: invalid timeZone "Mars/Olympus_Mons", an IANA time zone like America/Sao_Paulo is required`, existingACL.Status.Reason)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerScheduleCiliumBackend() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "maintenance",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.1"}},
+			},
+			Schedule: &v1alpha1.ACLSpecSchedule{
+				TimeZone: "UTC",
+				Windows:  []v1alpha1.ACLSpecScheduleWindow{{Start: "02:00", End: "04:00"}},
+			},
+		},
+	}
+
+	cli := withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build())
+	reconciler := &ACLReconciler{
+		Client:        cli,
+		Scheme:        scheme.Scheme,
+		Resolver:      &fakeResolver{},
+		TsuruAPI:      &fakeTsuruAPI{},
+		PolicyBackend: &ciliumPolicyBackend{Client: cli},
+		now:           func() time.Time { return time.Date(2026, 10, 12, 1, 0, 0, 0, time.UTC) },
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(acl),
+	})
+	suite.Require().NoError(err)
+
+	ciliumPolicy := reconciler.PolicyBackend.NewObject()
+	err = cli.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-maintenance"}, ciliumPolicy)
+	suite.Require().NoError(err)
+
+	// closed, an empty egress rule denies every egress, a policy without egress would allow it
+	spec, err := json.Marshal(ciliumPolicy.(*unstructured.Unstructured).Object["spec"])
+	suite.Require().NoError(err)
+	suite.Assert().JSONEq(`{
+		"endpointSelector": {"matchLabels": {"tsuru.io/app-name": "myapp"}},
+		"egress": [{}]
+	}`, string(spec))
+}
+
+func (suite *ControllerSuite) TestACLReconcilerDestinationExpiry() {
+	ctx := context.Background()
+	expiresAt := func(t time.Time) *v1.Time {
+		expires := v1.NewTime(t)
+		return &expires
+	}
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "incident",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.1"}},
+				{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.2"}, ExpiresAt: expiresAt(time.Date(2026, 10, 12, 3, 0, 0, 0, time.UTC))},
+				{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.3"}, ExpiresAt: expiresAt(time.Date(2026, 10, 12, 1, 30, 0, 0, time.UTC))},
+			},
+		},
+	}
+
+	now := time.Date(2026, 10, 12, 1, 0, 0, 0, time.UTC)
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+		// the expiries come before the regular requeue
+		RequeueInterval: 48 * time.Hour,
+		now:             func() time.Time { return now },
+	}
+	reconcile := func() (controllerruntime.Result, *v1alpha1.ACL, []string) {
+		result, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+
+		existingACL := &v1alpha1.ACL{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+		suite.Require().NoError(err)
+
+		existingNP := &netv1.NetworkPolicy{}
+		err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-incident"}, existingNP)
+		suite.Require().NoError(err)
+		cidrs := []string{}
+		for _, rule := range existingNP.Spec.Egress {
+			for _, peer := range rule.To {
+				cidrs = append(cidrs, peer.IPBlock.CIDR)
+			}
+		}
+		return result, existingACL, cidrs
+	}
+
+	// the earliest expiry is the requeue
+	result, existingACL, cidrs := reconcile()
+	suite.Assert().Equal(30*time.Minute, result.RequeueAfter)
+	suite.Assert().True(existingACL.Status.Ready)
+	suite.Assert().Equal("2026-10-12T01:30:00Z", existingACL.Status.NextExpiry)
+	suite.Assert().ElementsMatch([]string{"10.0.0.1/32", "10.0.0.2/32", "10.0.0.3/32"}, cidrs)
+
+	// the memo of the policy is not reused after a destination expires
+	now = time.Date(2026, 10, 12, 1, 30, 0, 0, time.UTC)
+	result, existingACL, cidrs = reconcile()
+	suite.Assert().Equal(90*time.Minute, result.RequeueAfter)
+	suite.Assert().True(existingACL.Status.Ready)
+	suite.Assert().Equal("2026-10-12T03:00:00Z", existingACL.Status.NextExpiry)
+	suite.Assert().ElementsMatch([]string{"10.0.0.1/32", "10.0.0.2/32"}, cidrs)
+	suite.Require().Len(existingACL.Status.ResolvedDestinations, 3)
+	suite.Assert().Equal("", existingACL.Status.ResolvedDestinations[1].ExpiredAt)
+	suite.Assert().Equal("2026-10-12T01:30:00Z", existingACL.Status.ResolvedDestinations[2].ExpiredAt)
+	suite.Assert().False(existingACL.Status.ResolvedDestinations[2].HasRules)
+
+	now = time.Date(2026, 10, 12, 4, 0, 0, 0, time.UTC)
+	result, existingACL, cidrs = reconcile()
+	suite.Assert().Equal(48*time.Hour, result.RequeueAfter)
+	suite.Assert().Equal("", existingACL.Status.NextExpiry)
+	suite.Assert().Equal([]string{"10.0.0.1/32"}, cidrs)
+
+	// once every destination expired the policy allows no egress
+	existingACL.Spec.Destinations = existingACL.Spec.Destinations[1:]
+	err := reconciler.Client.Update(ctx, existingACL)
+	suite.Require().NoError(err)
+	_, existingACL, cidrs = reconcile()
+	suite.Assert().True(existingACL.Status.Ready)
+	suite.Assert().Empty(cidrs)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerDestinationExpiryCiliumBackend() {
+	ctx := context.Background()
+	expiresAt := v1.NewTime(time.Date(2026, 10, 12, 1, 30, 0, 0, time.UTC))
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "incident",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.1"}, ExpiresAt: &expiresAt},
+			},
+		},
+	}
+
+	now := time.Date(2026, 10, 12, 1, 0, 0, 0, time.UTC)
+	cli := withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl).Build())
+	reconciler := &ACLReconciler{
+		Client:        cli,
+		Scheme:        scheme.Scheme,
+		Resolver:      &fakeResolver{},
+		TsuruAPI:      &fakeTsuruAPI{},
+		PolicyBackend: &ciliumPolicyBackend{Client: cli},
+		now:           func() time.Time { return now },
+	}
+	policySpec := func() string {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+
+		ciliumPolicy := reconciler.PolicyBackend.NewObject()
+		err = cli.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-incident"}, ciliumPolicy)
+		suite.Require().NoError(err)
+		spec, err := json.Marshal(ciliumPolicy.(*unstructured.Unstructured).Object["spec"])
+		suite.Require().NoError(err)
+		return string(spec)
+	}
+
+	suite.Assert().JSONEq(`{
+		"endpointSelector": {"matchLabels": {"tsuru.io/app-name": "myapp"}},
+		"egress": [{"toCIDRSet": [{"cidr": "10.0.0.1/32"}]}]
+	}`, policySpec())
+
+	// once every destination expired the egress is denied instead of left unrestricted
+	now = time.Date(2026, 10, 12, 2, 0, 0, 0, time.UTC)
+	suite.Assert().JSONEq(`{
+		"endpointSelector": {"matchLabels": {"tsuru.io/app-name": "myapp"}},
+		"egress": [{}]
+	}`, policySpec())
+}
+
+func TestExpiredSince(t *testing.T) {
+	expiresAt := v1.NewTime(time.Date(2026, 10, 12, 1, 0, 0, 0, time.UTC))
+	destinations := []v1alpha1.ACLSpecDestination{
+		{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.1"}},
+		{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.2"}, ExpiresAt: &expiresAt},
+	}
+
+	// the destination is not expired yet
+	assert.False(t, expiredSince(destinations, "2026-10-12T00:00:00Z", time.Date(2026, 10, 12, 0, 30, 0, 0, time.UTC)))
+	// the policy applied before the expiry still allows the destination
+	assert.True(t, expiredSince(destinations, "2026-10-12T00:00:00Z", time.Date(2026, 10, 12, 2, 0, 0, 0, time.UTC)))
+	// the policy applied after the expiry does not
+	assert.False(t, expiredSince(destinations, "2026-10-12T01:30:00Z", time.Date(2026, 10, 12, 2, 0, 0, 0, time.UTC)))
+	assert.True(t, expiredSince(destinations, "", time.Date(2026, 10, 12, 2, 0, 0, 0, time.UTC)))
+}
+
+func TestParsePolicyNameTemplate(t *testing.T) {
+	_, err := ParsePolicyNameTemplate("{{.Name")
+	assert.Error(t, err)
+
+	_, err = ParsePolicyNameTemplate("{{.Nmae}}-egress")
+	assert.ErrorContains(t, err, "can't evaluate field Nmae")
+
+	tmpl, err := ParsePolicyNameTemplate("{{.Labels.team}}-{{.Name}}")
+	require.NoError(t, err)
+
+	name, err := renderPolicyName(tmpl, &v1alpha1.ACL{ObjectMeta: v1.ObjectMeta{Name: "myapp", Labels: map[string]string{"team": "Payments"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "payments-myapp", name)
+
+	// missing labels render empty, the name is made valid
+	name, err = renderPolicyName(tmpl, &v1alpha1.ACL{ObjectMeta: v1.ObjectMeta{Name: "myapp"}})
+	require.NoError(t, err)
+	assert.Regexp(t, "^myapp-[0-9a-f]{10}$", name)
+
+	tmpl, err = ParsePolicyNameTemplate("{{.Labels.team}}")
+	require.NoError(t, err)
+	_, err = renderPolicyName(tmpl, &v1alpha1.ACL{ObjectMeta: v1.ObjectMeta{Name: "myapp"}})
+	assert.EqualError(t, err, "policy name template renders an empty name")
+}
+
+func (suite *ControllerSuite) TestACLReconcilerPolicyNameTemplate() {
+	ctx := context.Background()
+	newACL := func(name string) *v1alpha1.ACL {
+		return &v1alpha1.ACL{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: v1alpha1.ACLSpec{
+				Source: v1alpha1.ACLSpecSource{
+					TsuruApp: name,
+				},
+				Destinations: []v1alpha1.ACLSpecDestination{
+					{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.1/32"}},
+				},
+			},
+		}
+	}
+	newApp := newACL("newapp")
+	existingApp := newACL("existingapp")
+	existingApp.Status.NetworkPolicy = "acl-existingapp"
+
+	tmpl, err := ParsePolicyNameTemplate("{{.Namespace}}-{{.Name}}-egress")
+	suite.Require().NoError(err)
+	reconciler := &ACLReconciler{
+		Client:             withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(newApp, existingApp).Build()),
+		Scheme:             scheme.Scheme,
+		Resolver:           &fakeResolver{},
+		TsuruAPI:           &fakeTsuruAPI{},
+		PolicyNameTemplate: tmpl,
+	}
+	reconcile := func(acl *v1alpha1.ACL) *v1alpha1.ACL {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+
+		existingACL := &v1alpha1.ACL{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+		suite.Require().NoError(err)
+		return existingACL
+	}
+
+	existingACL := reconcile(newApp)
+	suite.Assert().True(existingACL.Status.Ready)
+	suite.Assert().Equal("default-newapp-egress", existingACL.Status.NetworkPolicy)
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "default-newapp-egress"}, &netv1.NetworkPolicy{})
+	suite.Assert().NoError(err)
+
+	// ACLs with a policy keep its name, so it is not orphaned
+	existingACL = reconcile(existingApp)
+	suite.Assert().True(existingACL.Status.Ready)
+	suite.Assert().Equal("acl-existingapp", existingACL.Status.NetworkPolicy)
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "default-existingapp-egress"}, &netv1.NetworkPolicy{})
+	suite.Assert().True(k8sErrors.IsNotFound(err))
+}
+
+func (suite *ControllerSuite) TestACLReconcilerPolicyTooLarge() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{ExternalDNS: &v1alpha1.ACLSpecExternalDNS{Name: "big.example.com"}},
+			},
+		},
+	}
+	dnsEntry := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "big.example.com",
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host: "big.example.com",
+		},
+		Status: v1alpha1.ACLDNSEntryStatus{
+			Ready: true,
+			IPs: []v1alpha1.ACLDNSEntryStatusIP{
+				{Address: "10.0.0.1", ValidUntil: time.Now().Format(time.RFC3339)},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:        withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl, dnsEntry).Build()),
+		Scheme:        scheme.Scheme,
+		Resolver:      &fakeResolver{},
+		TsuruAPI:      &fakeTsuruAPI{},
+		MaxPolicySize: 16 * 1024,
+	}
+	reconcile := func() *v1alpha1.ACL {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+
+		existingACL := &v1alpha1.ACL{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+		suite.Require().NoError(err)
+		return existingACL
+	}
+
+	existingACL := reconcile()
+	suite.Require().True(existingACL.Status.Ready)
+
+	// the host resolves to a thousand addresses, the policy applied before is kept
+	existingEntry := &v1alpha1.ACLDNSEntry{}
+	err := reconciler.Client.Get(ctx, client.ObjectKeyFromObject(dnsEntry), existingEntry)
+	suite.Require().NoError(err)
+	existingEntry.Status.IPs = nil
+	for i := 0; i < 1000; i++ {
+		existingEntry.Status.IPs = append(existingEntry.Status.IPs, v1alpha1.ACLDNSEntryStatusIP{
+			Address:    fmt.Sprintf("10.%d.%d.1", i/250, i%250),
+			ValidUntil: time.Now().Format(time.RFC3339),
+		})
+	}
+	err = reconciler.Client.Status().Update(ctx, existingEntry)
+	suite.Require().NoError(err)
+
+	existingACL = reconcile()
+	suite.Assert().False(existingACL.Status.Ready)
+	suite.Assert().Regexp(`^policy acl-myapp is too large, its rules have \d+ bytes and the limit is 16384 bytes`, existingACL.Status.Reason)
+	ready := meta.FindStatusCondition(existingACL.Status.Conditions, v1alpha1.ACLConditionReady)
+	suite.Require().NotNil(ready)
+	suite.Assert().Equal(eventReasonPolicyTooLarge, ready.Reason)
+	suite.Require().Len(existingACL.Status.RecentFailures, 1)
+	suite.Assert().Equal(eventReasonPolicyTooLarge, existingACL.Status.RecentFailures[0].Reason)
+	suite.Assert().Equal(existingACL.Status.Reason, existingACL.Status.RecentFailures[0].Message)
+
+	existingNP := &netv1.NetworkPolicy{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-myapp"}, existingNP)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]netv1.NetworkPolicyPeer{{IPBlock: &netv1.IPBlock{CIDR: "10.0.0.1/32"}}}, existingNP.Spec.Egress[0].To)
+
+	// no limit is checked when negative
+	reconciler.MaxPolicySize = -1
+	existingACL = reconcile()
+	suite.Assert().True(existingACL.Status.Ready)
+	// the failures are kept once the ACL is ready again
+	suite.Assert().Len(existingACL.Status.RecentFailures, 1)
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "acl-myapp"}, existingNP)
+	suite.Require().NoError(err)
+	suite.Assert().Len(existingNP.Spec.Egress[0].To, 1000)
 }
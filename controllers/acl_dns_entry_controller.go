@@ -26,55 +26,147 @@ import (
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlbuilder "sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/tsuru/acl-operator/api/v1alpha1"
 	extensionstsuruiov1alpha1 "github.com/tsuru/acl-operator/api/v1alpha1"
 )
 
-const dayFormat = "2006-01-02"
+const (
+	dayFormat = "2006-01-02"
+
+	// DefaultDNSEntryGracePeriod is used by reconcilers without a GracePeriod
+	DefaultDNSEntryGracePeriod = 30 * time.Minute
+
+	// DefaultDNSFailureBackoffThreshold is used by reconcilers without a FailureBackoffThreshold
+	DefaultDNSFailureBackoffThreshold = 3
+
+	// DefaultDNSMaxFailureBackoff is used by reconcilers without a MaxFailureBackoff
+	DefaultDNSMaxFailureBackoff = time.Hour
+
+	// lastSeenRefreshInterval avoids an update of status on every lookup only to refresh lastSeen,
+	// the addresses of the last lookup are always kept, so the precision only matters for older ones
+	lastSeenRefreshInterval = 5 * time.Minute
+)
 
 type ACLDNSResolver interface {
 	LookupIPAddr(context.Context, string) ([]net.IPAddr, error)
 }
 
-var DefaultResolver ACLDNSResolver = &net.Resolver{}
+var DefaultResolver = NewResolver(nil)
 
 // ACLDNSEntryReconciler reconciles a ACLDNSEntry object
 type ACLDNSEntryReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Resolver ACLDNSResolver
+
+	RequeueInterval time.Duration
+
+	// RequeueJitter spreads each periodic requeue by up to this fraction of the interval,
+	// more or less, the requeues are not spread when zero
+	RequeueJitter float64
+
+	// MaxIPsPerEntry limits the addresses kept on status of an entry, the least recently
+	// seen addresses are evicted first, there is no limit when zero
+	MaxIPsPerEntry int
+
+	// GracePeriod keeps an address on status after it stops resolving, so flapping answers
+	// do not narrow the policies, defaults to DefaultDNSEntryGracePeriod
+	GracePeriod time.Duration
+
+	// LookupTimeout is the deadline of the lookup of host, defaults to DefaultDNSLookupTimeout
+	LookupTimeout time.Duration
+
+	// FailureBackoffThreshold is the number of consecutive failed lookups of an entry after which
+	// its requeue interval doubles on each failure, defaults to DefaultDNSFailureBackoffThreshold
+	FailureBackoffThreshold int
+
+	// MaxFailureBackoff caps the requeue interval of entries that keep failing, defaults to DefaultDNSMaxFailureBackoff
+	MaxFailureBackoff time.Duration
+
+	// Refresh enqueues entries on demand, like the refreshes of AdminServer
+	Refresh *RefreshTrigger
+
+	// MaxConcurrentReconciles is the number of entries resolved at once, defaults to DefaultDNSEntryConcurrency
+	MaxConcurrentReconciles int
 }
 
 //+kubebuilder:rbac:groups=extensions.tsuru.io,resources=ACLDNSEntrys,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=extensions.tsuru.io,resources=ACLDNSEntrys/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=extensions.tsuru.io,resources=ACLDNSEntrys/finalizers,verbs=update
 
-func (r *ACLDNSEntryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *ACLDNSEntryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
 	l := log.FromContext(ctx)
 
 	dnsEntry := &v1alpha1.ACLDNSEntry{}
+	outcome := reconcileResultNoop
+	defer func() {
+		observeReconcileResult("acldnsentry", outcome, err)
+	}()
 
-	err := r.Client.Get(ctx, req.NamespacedName, dnsEntry)
+	err = r.Client.Get(ctx, req.NamespacedName, dnsEntry)
 	if k8sErrors.IsNotFound(err) {
+		dnsEntryResolvedIPs.DeleteLabelValues(req.Name)
 		return ctrl.Result{}, nil
 	} else if err != nil {
 		l.Error(err, "could not get ACLDNSEntry object")
 		return ctrl.Result{}, err
 	}
 
+	ctx, l = objectLogger(ctx, dnsEntry, "host", dnsEntry.Spec.Host)
+
+	// refreshes on demand are done during failovers, a cached answer would be the address left behind
+	if r.Refresh.takeUncachedDNSEntry(dnsEntry.Name) {
+		l.Info("refresh of ACLDNSEntry requested, skipping the DNS cache")
+		ctx = WithoutDNSCache(ctx)
+	}
+
+	// the addresses are kept as they are, the ACLs using them are not affected
+	if isPaused(dnsEntry) {
+		l.V(1).Info("ACLDNSEntry is paused, skipping reconcile", "annotation", pausedAnnotation)
+		outcome = reconcileResultPaused
+		return ctrl.Result{}, nil
+	}
+
 	existingStatus := dnsEntry.Status.DeepCopy()
 
-	err = r.FillStatus(ctx, dnsEntry)
+	// entries created by users may be invalid, retrying does not help until spec changes
+	err = dnsEntry.Spec.Validate()
+	if err != nil {
+		l.Info("invalid ACLDNSEntry", "error", err.Error())
+		outcome = reconcileResultError
+
+		dnsEntry.Status.Ready = false
+		dnsEntry.Status.Reason = "invalid spec: " + err.Error()
+		if reflect.DeepEqual(existingStatus, &dnsEntry.Status) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, r.Client.Status().Update(ctx, dnsEntry)
+	}
+
+	ttl, err := r.fillStatus(ctx, dnsEntry)
 
 	if err != nil {
-		l.Error(err, "could not resolve address", "host", dnsEntry.Spec.Host)
+		l.Error(err, "could not resolve address")
 
 		dnsEntry.Status.Ready = false
 		dnsEntry.Status.Reason = err.Error()
+		outcome = reconcileResultError
+
+		// hosts that never resolve, like typos, are looked up less and less often
+		dnsEntry.Status.ConsecutiveFailures++
+		retryInterval := r.failureRequeueInterval(dnsEntry.Status.ConsecutiveFailures)
+		dnsEntry.Status.RetryInterval = ""
+		if retryInterval != requeueInterval(r.RequeueInterval) {
+			dnsEntry.Status.RetryInterval = retryInterval.String()
+		}
 
 		statusErr := r.Client.Status().Update(ctx, dnsEntry)
 		if statusErr != nil {
@@ -83,39 +175,67 @@ func (r *ACLDNSEntryReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 		return ctrl.Result{
 			Requeue:      true,
-			RequeueAfter: time.Minute * 10,
+			RequeueAfter: jitterRequeue(retryInterval, r.RequeueJitter),
 		}, nil
 	}
 
+	dnsEntryResolvedIPs.WithLabelValues(dnsEntry.Name).Set(float64(len(dnsEntry.Status.IPs)))
+
 	if !reflect.DeepEqual(existingStatus, dnsEntry.Status) {
 		err = r.Client.Status().Update(ctx, dnsEntry)
 		if err != nil {
 			l.Error(err, "could not update status for ACLDNSEntry object")
 			return ctrl.Result{}, err
 		}
+		outcome = reconcileResultUpdated
 	}
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{
+		Requeue:      true,
+		RequeueAfter: jitterRequeue(requeueAfterForTTL(ttl, r.RequeueInterval), r.RequeueJitter),
+	}, nil
 }
 
 func (r *ACLDNSEntryReconciler) FillStatus(ctx context.Context, dnsEntry *v1alpha1.ACLDNSEntry) error {
-	timoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	_, err := r.fillStatus(ctx, dnsEntry)
+	return err
+}
+
+func (r *ACLDNSEntryReconciler) fillStatus(ctx context.Context, dnsEntry *v1alpha1.ACLDNSEntry) (time.Duration, error) {
+	timoutCtx, cancel := withDNSLookupTimeout(ctx, r.LookupTimeout)
 	defer cancel()
-	ipAddrs, err := r.Resolver.LookupIPAddr(timoutCtx, dnsEntry.Spec.Host)
+	resolver := r.Resolver
+	if len(dnsEntry.Spec.Nameservers) > 0 {
+		resolver = &nameserversResolver{Nameservers: dnsEntry.Spec.Nameservers}
+	}
+	answer, err := lookupDNSAnswer(timoutCtx, resolver, dnsEntry.Spec.Host)
 
 	if err != nil {
-		return err
+		dnsLookupFailuresTotal.WithLabelValues(dnsEntry.Spec.Host).Inc()
+		dnsEntry.Status.Failures = []extensionstsuruiov1alpha1.ResolutionFailure{
+			resolutionFailure(dnsEntry.Spec.Host, err, dnsEntry.Status.Failures),
+		}
+		return 0, err
 	}
 
 	now := time.Now().UTC()
-	validUntil := now.Add(7 * 24 * time.Hour)
+	gracePeriod := r.GracePeriod
+	if gracePeriod <= 0 {
+		gracePeriod = DefaultDNSEntryGracePeriod
+	}
+	validUntil := now.Add(gracePeriod)
 
 	missingIpAddrs := []net.IPAddr{}
+	resolved := map[string]bool{}
 statusLoop:
-	for _, foundIP := range ipAddrs {
+	for _, foundIP := range answer.IPAddrs {
+		resolved[foundIP.IP.String()] = true
 		for i, existingIP := range dnsEntry.Status.IPs {
 			if existingIP.Address == foundIP.IP.String() {
 				dnsEntry.Status.IPs[i].ValidUntil = validUntil.Format(dayFormat)
+				if lastSeen, _ := time.Parse(time.RFC3339, existingIP.LastSeen); now.Sub(lastSeen) >= lastSeenRefreshInterval {
+					dnsEntry.Status.IPs[i].LastSeen = now.Format(time.RFC3339)
+				}
 				continue statusLoop
 			}
 		}
@@ -127,6 +247,7 @@ statusLoop:
 		dnsEntry.Status.IPs = append(dnsEntry.Status.IPs, extensionstsuruiov1alpha1.ACLDNSEntryStatusIP{
 			Address:    foundIP.IP.String(),
 			ValidUntil: validUntil.Format(dayFormat),
+			LastSeen:   now.Format(time.RFC3339),
 		})
 	}
 
@@ -136,24 +257,117 @@ statusLoop:
 
 	n := 0
 	for _, ip := range dnsEntry.Status.IPs {
-		t, _ := time.Parse(dayFormat, ip.ValidUntil)
-
-		if !now.After(t) && !t.IsZero() {
+		if resolved[ip.Address] || !dnsEntryIPExpired(ip, now, gracePeriod) {
 			dnsEntry.Status.IPs[n] = ip
 			n++
 		}
 	}
 	dnsEntry.Status.IPs = dnsEntry.Status.IPs[:n]
+	dnsEntry.Status.IPs, dnsEntry.Status.Truncated = evictDNSEntryIPs(dnsEntry.Status.IPs, resolved, r.MaxIPsPerEntry)
+	dnsEntry.Status.CNAMEs = answer.CNAMEs
+	dnsEntry.Status.CanonicalName = ""
+	if len(answer.CNAMEs) > 0 {
+		dnsEntry.Status.CanonicalName = answer.CNAMEs[len(answer.CNAMEs)-1]
+	}
 	dnsEntry.Status.Ready = true
+	dnsEntry.Status.ConsecutiveFailures = 0
+	dnsEntry.Status.RetryInterval = ""
 	dnsEntry.Status.Reason = ""
+	dnsEntry.Status.Failures = nil
+
+	return answer.TTL, nil
+}
+
+// dnsEntryIPExpired reports whether an address that is not resolved anymore left the grace period,
+// lastSeen is refreshed every lastSeenRefreshInterval, which is the precision of the grace period.
+// Addresses stored before lastSeen existed expire by validUntil
+func dnsEntryIPExpired(ip extensionstsuruiov1alpha1.ACLDNSEntryStatusIP, now time.Time, gracePeriod time.Duration) bool {
+	if lastSeen, err := time.Parse(time.RFC3339, ip.LastSeen); err == nil {
+		return now.Sub(lastSeen) > gracePeriod
+	}
 
-	return nil
+	t, _ := time.Parse(dayFormat, ip.ValidUntil)
+	return t.IsZero() || now.After(t)
 }
 
+// evictDNSEntryIPs keeps at most max addresses, the addresses of the last lookup are kept first
+// followed by the most recently seen ones, ips is expected to be sorted by address
+func evictDNSEntryIPs(ips []extensionstsuruiov1alpha1.ACLDNSEntryStatusIP, resolved map[string]bool, max int) ([]extensionstsuruiov1alpha1.ACLDNSEntryStatusIP, bool) {
+	if max <= 0 || len(ips) <= max {
+		return ips, false
+	}
+
+	byRecency := make([]extensionstsuruiov1alpha1.ACLDNSEntryStatusIP, len(ips))
+	copy(byRecency, ips)
+	sort.SliceStable(byRecency, func(i, j int) bool {
+		if resolved[byRecency[i].Address] != resolved[byRecency[j].Address] {
+			return resolved[byRecency[i].Address]
+		}
+		// RFC3339 timestamps in UTC are ordered as strings
+		return byRecency[i].LastSeen > byRecency[j].LastSeen
+	})
+
+	kept := byRecency[:max]
+	sort.Slice(kept, func(i, j int) bool {
+		return kept[i].Address < kept[j].Address
+	})
+
+	return kept, true
+}
+
+// failureRequeueInterval is the requeue interval after failures consecutive failed lookups, it doubles
+// on each failure from FailureBackoffThreshold on, up to MaxFailureBackoff
+func (r *ACLDNSEntryReconciler) failureRequeueInterval(failures int) time.Duration {
+	interval := requeueInterval(r.RequeueInterval)
+
+	threshold := r.FailureBackoffThreshold
+	if threshold <= 0 {
+		threshold = DefaultDNSFailureBackoffThreshold
+	}
+	maxBackoff := r.MaxFailureBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultDNSMaxFailureBackoff
+	}
+
+	for i := threshold; i <= failures && interval < maxBackoff; i++ {
+		interval *= 2
+	}
+	if interval > maxBackoff && maxBackoff > requeueInterval(r.RequeueInterval) {
+		interval = maxBackoff
+	}
+
+	return interval
+}
+
+// resolutionFailure keeps the timestamp of a previous failure with the same error,
+// so a host that keeps failing does not update the status on every reconcile
+func resolutionFailure(host string, err error, previous []extensionstsuruiov1alpha1.ResolutionFailure) extensionstsuruiov1alpha1.ResolutionFailure {
+	for _, failure := range previous {
+		if failure.Host == host && failure.Error == err.Error() {
+			return failure
+		}
+	}
+
+	return extensionstsuruiov1alpha1.ResolutionFailure{
+		Host:      host,
+		Error:     err.Error(),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// dnsEntrySpecChanged ignores the updates of status written by the reconciler itself, the failed
+// lookups update consecutiveFailures and would be retried right away instead of after their backoff
+var dnsEntrySpecChanged = predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{})
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ACLDNSEntryReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&extensionstsuruiov1alpha1.ACLDNSEntry{}).
-		WithOptions(controller.Options{MaxConcurrentReconciles: 4, RecoverPanic: true}).
-		Complete(r)
+	builder := ctrl.NewControllerManagedBy(mgr).
+		For(&extensionstsuruiov1alpha1.ACLDNSEntry{}, ctrlbuilder.WithPredicates(dnsEntrySpecChanged)).
+		WithOptions(controller.Options{MaxConcurrentReconciles: maxConcurrentReconciles(r.MaxConcurrentReconciles, DefaultDNSEntryConcurrency), RecoverPanic: true})
+
+	if r.Refresh != nil {
+		builder = builder.Watches(&source.Channel{Source: r.Refresh.dnsEntries}, &handler.EnqueueRequestForObject{})
+	}
+
+	return builder.Complete(r)
 }
@@ -4,7 +4,11 @@ import (
 	"context"
 	"errors"
 	"net"
+	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
 	"github.com/tsuru/acl-operator/api/scheme"
 	v1alpha1 "github.com/tsuru/acl-operator/api/v1alpha1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -12,11 +16,31 @@ import (
 	controllerruntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 )
 
 type fakeResolver struct {
 	hosts  map[string][]string
 	errors map[string]error
+	ttls   map[string]time.Duration
+	srvs   map[string][]*net.SRV
+}
+
+func (f *fakeResolver) LookupSRV(ctx context.Context, name string) ([]*net.SRV, error) {
+	if srvs, ok := f.srvs[name]; ok {
+		return srvs, nil
+	}
+
+	if err, ok := f.errors[name]; ok {
+		return nil, err
+	}
+
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (f *fakeResolver) LookupIPAddrTTL(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+	ipAddrs, err := f.LookupIPAddr(ctx, host)
+	return ipAddrs, f.ttls[host], err
 }
 
 func (f *fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
@@ -88,6 +112,146 @@ func (suite *ControllerSuite) TestACLDNSEntryReconcilerSimpleReconcile() {
 	suite.Assert().Equal("8.8.8.8", existingResolver.Status.IPs[1].Address)
 }
 
+func (suite *ControllerSuite) TestACLDNSEntryReconcilerRefreshSkipsDNSCache() {
+	ctx := context.Background()
+	dnsEntry := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "failover.example.com",
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host: "failover.example.com",
+		},
+	}
+
+	resolver := &fakeResolver{
+		hosts: map[string][]string{"failover.example.com": {"10.0.0.1"}},
+		ttls:  map[string]time.Duration{"failover.example.com": time.Hour},
+	}
+	reconciler := &ACLDNSEntryReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(dnsEntry).Build(),
+		Scheme:   scheme.Scheme,
+		Resolver: &cachingResolver{Resolver: resolver},
+		Refresh:  NewRefreshTrigger(),
+	}
+	reconcile := func() []string {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(dnsEntry),
+		})
+		suite.Require().NoError(err)
+
+		existing := &v1alpha1.ACLDNSEntry{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(dnsEntry), existing)
+		suite.Require().NoError(err)
+		addresses := []string{}
+		for _, ip := range existing.Status.IPs {
+			addresses = append(addresses, ip.Address)
+		}
+		return addresses
+	}
+
+	suite.Assert().Equal([]string{"10.0.0.1"}, reconcile())
+
+	// the answer is cached until its TTL expires
+	resolver.hosts["failover.example.com"] = []string{"10.0.0.2"}
+	suite.Assert().Equal([]string{"10.0.0.1"}, reconcile())
+
+	// a refresh sees the new address right away, the address of the grace period is kept
+	err := reconciler.Refresh.RefreshDNSEntry(dnsEntry)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]string{"10.0.0.1", "10.0.0.2"}, reconcile())
+	suite.Assert().False(reconciler.Refresh.takeUncachedDNSEntry(dnsEntry.Name))
+}
+
+// cnameResolver reports the CNAMEs of hosts with the addresses of fakeResolver
+type cnameResolver struct {
+	fakeResolver
+	cnames map[string][]string
+}
+
+func (c *cnameResolver) LookupDNSAnswer(ctx context.Context, host string) (*DNSAnswer, error) {
+	ipAddrs, ttl, err := c.fakeResolver.LookupIPAddrTTL(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	return &DNSAnswer{IPAddrs: ipAddrs, TTL: ttl, CNAMEs: c.cnames[host]}, nil
+}
+
+func (suite *ControllerSuite) TestACLDNSEntryReconcilerCanonicalName() {
+	ctx := context.Background()
+	dnsEntry := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "www.google.com.br",
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host: "www.google.com.br",
+		},
+	}
+
+	resolver := &cnameResolver{
+		cnames: map[string][]string{
+			"www.google.com.br": {"www3.l.google.com", "lb.google.com"},
+		},
+	}
+	reconciler := &ACLDNSEntryReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(dnsEntry).Build(),
+		Scheme:   scheme.Scheme,
+		Resolver: resolver,
+	}
+	reconcile := func() *v1alpha1.ACLDNSEntry {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(dnsEntry),
+		})
+		suite.Require().NoError(err)
+
+		existing := &v1alpha1.ACLDNSEntry{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(dnsEntry), existing)
+		suite.Require().NoError(err)
+		return existing
+	}
+
+	existing := reconcile()
+	suite.Assert().True(existing.Status.Ready)
+	suite.Assert().Equal("lb.google.com", existing.Status.CanonicalName)
+	suite.Assert().Equal([]string{"www3.l.google.com", "lb.google.com"}, existing.Status.CNAMEs)
+
+	// the host stops being an alias
+	resolver.cnames = nil
+	existing = reconcile()
+	suite.Assert().Empty(existing.Status.CanonicalName)
+	suite.Assert().Empty(existing.Status.CNAMEs)
+	suite.Assert().Len(existing.Status.IPs, 2)
+}
+
+func (suite *ControllerSuite) TestACLDNSEntryReconcilerPaused() {
+	ctx := context.Background()
+	dnsEntry := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        "www.google.com.br",
+			Annotations: map[string]string{pausedAnnotation: "true"},
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host: "www.google.com.br",
+		},
+	}
+
+	reconciler := &ACLDNSEntryReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(dnsEntry).Build(),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+	}
+	result, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(dnsEntry),
+	})
+	suite.Require().NoError(err)
+	suite.Assert().Equal(controllerruntime.Result{}, result)
+
+	existing := &v1alpha1.ACLDNSEntry{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(dnsEntry), existing)
+	suite.Require().NoError(err)
+	suite.Assert().False(existing.Status.Ready)
+	suite.Assert().Empty(existing.Status.IPs)
+}
+
 func (suite *ControllerSuite) TestACLDNSEntryReconcilerSimpleReconcileExisting() {
 	ctx := context.Background()
 	resolver := &v1alpha1.ACLDNSEntry{
@@ -138,6 +302,144 @@ func (suite *ControllerSuite) TestACLDNSEntryReconcilerSimpleReconcileExisting()
 	suite.Assert().Equal("9.9.9.9", existingResolver.Status.IPs[2].Address)
 }
 
+func (suite *ControllerSuite) TestACLDNSEntryReconcilerMaxIPsPerEntry() {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	validUntil := now.Add(24 * time.Hour).Format(dayFormat)
+	resolver := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "cdn.io",
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host: "cdn.io",
+		},
+		Status: v1alpha1.ACLDNSEntryStatus{
+			IPs: []v1alpha1.ACLDNSEntryStatusIP{
+				{
+					Address:    "1.1.1.1",
+					ValidUntil: validUntil,
+					LastSeen:   now.Add(-20 * time.Minute).Format(time.RFC3339),
+				},
+				{
+					Address:    "2.2.2.2",
+					ValidUntil: validUntil,
+					LastSeen:   now.Add(-10 * time.Minute).Format(time.RFC3339),
+				},
+				{
+					Address:    "3.3.3.3",
+					ValidUntil: validUntil,
+					LastSeen:   now.Add(-25 * time.Minute).Format(time.RFC3339),
+				},
+			},
+		},
+	}
+
+	reconciler := &ACLDNSEntryReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(resolver).Build(),
+		Scheme: scheme.Scheme,
+		Resolver: &fakeResolver{
+			hosts: map[string][]string{
+				"cdn.io": {"4.4.4.4", "5.5.5.5"},
+			},
+		},
+		MaxIPsPerEntry: 3,
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(resolver),
+	})
+	suite.Require().NoError(err)
+
+	existingResolver := &v1alpha1.ACLDNSEntry{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(resolver), existingResolver)
+	suite.Require().NoError(err)
+
+	suite.Assert().True(existingResolver.Status.Ready)
+	suite.Assert().True(existingResolver.Status.Truncated)
+	addresses := []string{}
+	for _, ip := range existingResolver.Status.IPs {
+		addresses = append(addresses, ip.Address)
+		suite.Assert().NotEmpty(ip.LastSeen)
+	}
+	// the resolved addresses are kept, followed by the most recently seen
+	suite.Assert().Equal([]string{"2.2.2.2", "4.4.4.4", "5.5.5.5"}, addresses)
+
+	// without a limit every address is kept
+	reconciler.MaxIPsPerEntry = 0
+	reconciler.Resolver = &fakeResolver{
+		hosts: map[string][]string{
+			"cdn.io": {"6.6.6.6"},
+		},
+	}
+	_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(resolver),
+	})
+	suite.Require().NoError(err)
+
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(resolver), existingResolver)
+	suite.Require().NoError(err)
+	suite.Assert().False(existingResolver.Status.Truncated)
+	suite.Assert().Len(existingResolver.Status.IPs, 4)
+}
+
+func (suite *ControllerSuite) TestACLDNSEntryReconcilerGracePeriod() {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	resolver := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "www.google.com.br",
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host: "www.google.com.br",
+		},
+		Status: v1alpha1.ACLDNSEntryStatus{
+			IPs: []v1alpha1.ACLDNSEntryStatusIP{
+				{
+					// stopped resolving recently, kept by the grace period
+					Address:    "1.1.1.1",
+					ValidUntil: now.Format(dayFormat),
+					LastSeen:   now.Add(-10 * time.Minute).Format(time.RFC3339),
+				},
+				{
+					Address:    "2.2.2.2",
+					ValidUntil: now.Format(dayFormat),
+					LastSeen:   now.Add(-time.Hour).Format(time.RFC3339),
+				},
+				{
+					// resolved addresses are kept regardless of lastSeen
+					Address:    "8.8.8.8",
+					ValidUntil: now.Format(dayFormat),
+					LastSeen:   now.Add(-time.Hour).Format(time.RFC3339),
+				},
+			},
+		},
+	}
+
+	reconciler := &ACLDNSEntryReconciler{
+		Client:      fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(resolver).Build(),
+		Scheme:      scheme.Scheme,
+		Resolver:    &fakeResolver{},
+		GracePeriod: 30 * time.Minute,
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(resolver),
+	})
+	suite.Require().NoError(err)
+
+	existingResolver := &v1alpha1.ACLDNSEntry{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(resolver), existingResolver)
+	suite.Require().NoError(err)
+
+	addresses := []string{}
+	for _, ip := range existingResolver.Status.IPs {
+		addresses = append(addresses, ip.Address)
+	}
+	suite.Assert().Equal([]string{"1.1.1.1", "8.8.4.4", "8.8.8.8"}, addresses)
+	suite.Assert().Equal(now.Add(-10*time.Minute).Format(time.RFC3339), existingResolver.Status.IPs[0].LastSeen)
+	lastSeen, err := time.Parse(time.RFC3339, existingResolver.Status.IPs[2].LastSeen)
+	suite.Require().NoError(err)
+	suite.Assert().WithinDuration(now, lastSeen, time.Minute)
+}
+
 func (suite *ControllerSuite) TestACLDNSEntryReconcilerTimeoutReconcile() {
 	ctx := context.Background()
 	resolver := &v1alpha1.ACLDNSEntry{
@@ -168,4 +470,311 @@ func (suite *ControllerSuite) TestACLDNSEntryReconcilerTimeoutReconcile() {
 	suite.Require().Len(existingResolver.Status.IPs, 0)
 	suite.Assert().False(existingResolver.Status.Ready)
 	suite.Assert().Equal("timeout for host", existingResolver.Status.Reason)
+	suite.Require().Len(existingResolver.Status.Failures, 1)
+	suite.Assert().Equal("timeout.com.br", existingResolver.Status.Failures[0].Host)
+	suite.Assert().Equal("timeout for host", existingResolver.Status.Failures[0].Error)
+	suite.Assert().NotEmpty(existingResolver.Status.Failures[0].Timestamp)
+}
+
+func (suite *ControllerSuite) TestACLDNSEntryReconcilerLookupTimeout() {
+	ctx := context.Background()
+	dnsEntry := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "slow.com.br",
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host: "slow.com.br",
+		},
+	}
+
+	reconciler := &ACLDNSEntryReconciler{
+		Client:        fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(dnsEntry).Build(),
+		Scheme:        scheme.Scheme,
+		Resolver:      &blockingResolver{},
+		LookupTimeout: 50 * time.Millisecond,
+	}
+
+	start := time.Now()
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(dnsEntry),
+	})
+	suite.Require().NoError(err)
+	suite.Assert().Less(time.Since(start), DefaultDNSLookupTimeout)
+
+	existing := &v1alpha1.ACLDNSEntry{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(dnsEntry), existing)
+	suite.Require().NoError(err)
+	suite.Assert().False(existing.Status.Ready)
+	suite.Assert().Equal("lookup slow.com.br: i/o timeout", existing.Status.Reason)
+}
+
+func (suite *ControllerSuite) TestACLDNSEntryReconcilerRequeueWithTTL() {
+	ctx := context.Background()
+	resolver := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "www.google.com.br",
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host: "www.google.com.br",
+		},
+	}
+
+	reconciler := &ACLDNSEntryReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(resolver).Build(),
+		Scheme: scheme.Scheme,
+		Resolver: &fakeResolver{
+			ttls: map[string]time.Duration{
+				"www.google.com.br": time.Minute * 2,
+			},
+		},
+	}
+	result, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: types.NamespacedName{
+			Name: "www.google.com.br",
+		},
+	})
+	suite.Require().NoError(err)
+	suite.Assert().Equal(time.Minute*2, result.RequeueAfter)
+}
+
+func (suite *ControllerSuite) TestACLDNSEntryReconcilerRequeueWithoutTTL() {
+	ctx := context.Background()
+	resolver := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "www.google.com.br",
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host: "www.google.com.br",
+		},
+	}
+
+	reconciler := &ACLDNSEntryReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(resolver).Build(),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+	}
+	result, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: types.NamespacedName{
+			Name: "www.google.com.br",
+		},
+	})
+	suite.Require().NoError(err)
+	suite.Assert().Equal(DefaultRequeueInterval, result.RequeueAfter)
+}
+
+func (suite *ControllerSuite) TestACLDNSEntryReconcilerRequeueJitter() {
+	ctx := context.Background()
+	resolver := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "www.google.com.br",
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host: "www.google.com.br",
+		},
+	}
+
+	reconciler := &ACLDNSEntryReconciler{
+		Client:        fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(resolver).Build(),
+		Scheme:        scheme.Scheme,
+		Resolver:      &fakeResolver{},
+		RequeueJitter: 0.2,
+	}
+	for i := 0; i < 20; i++ {
+		result, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: types.NamespacedName{
+				Name: "www.google.com.br",
+			},
+		})
+		suite.Require().NoError(err)
+		suite.Assert().GreaterOrEqual(result.RequeueAfter, 8*time.Minute)
+		suite.Assert().LessOrEqual(result.RequeueAfter, 12*time.Minute)
+	}
+}
+
+func (suite *ControllerSuite) TestACLDNSEntryReconcilerMetrics() {
+	ctx := context.Background()
+	resolver := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "metrics.com.br",
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host: "metrics.com.br",
+		},
+	}
+
+	reconciler := &ACLDNSEntryReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(resolver).Build(),
+		Scheme: scheme.Scheme,
+		Resolver: &fakeResolver{
+			hosts: map[string][]string{
+				"metrics.com.br": {"1.1.1.1", "1.1.1.2"},
+			},
+		},
+	}
+
+	updatedBefore := testutil.ToFloat64(reconcileResultsTotal.WithLabelValues("acldnsentry", reconcileResultUpdated))
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: types.NamespacedName{
+			Name: "metrics.com.br",
+		},
+	})
+	suite.Require().NoError(err)
+	suite.Assert().Equal(updatedBefore+1, testutil.ToFloat64(reconcileResultsTotal.WithLabelValues("acldnsentry", reconcileResultUpdated)))
+	suite.Assert().Equal(float64(2), testutil.ToFloat64(dnsEntryResolvedIPs.WithLabelValues("metrics.com.br")))
+
+	reconciler.Resolver = &fakeResolver{
+		errors: map[string]error{
+			"metrics.com.br": errors.New("no such host"),
+		},
+	}
+	failuresBefore := testutil.ToFloat64(dnsLookupFailuresTotal.WithLabelValues("metrics.com.br"))
+	errorsBefore := testutil.ToFloat64(reconcileResultsTotal.WithLabelValues("acldnsentry", reconcileResultError))
+	_, err = reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: types.NamespacedName{
+			Name: "metrics.com.br",
+		},
+	})
+	suite.Require().NoError(err)
+	suite.Assert().Equal(failuresBefore+1, testutil.ToFloat64(dnsLookupFailuresTotal.WithLabelValues("metrics.com.br")))
+	suite.Assert().Equal(errorsBefore+1, testutil.ToFloat64(reconcileResultsTotal.WithLabelValues("acldnsentry", reconcileResultError)))
+}
+
+func (suite *ControllerSuite) TestACLDNSEntryReconcilerInvalidSpec() {
+	ctx := context.Background()
+	dnsEntry := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        "my-entry",
+			Annotations: map[string]string{userOwnedAnnotation: "true"},
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host:        "www.google.com.br",
+			Nameservers: []string{"ns1.google.com"},
+		},
+	}
+
+	reconciler := &ACLDNSEntryReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(dnsEntry).Build(),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+	}
+	reconcile := func() (controllerruntime.Result, *v1alpha1.ACLDNSEntry) {
+		result, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(dnsEntry),
+		})
+		suite.Require().NoError(err)
+
+		existing := &v1alpha1.ACLDNSEntry{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(dnsEntry), existing)
+		suite.Require().NoError(err)
+		return result, existing
+	}
+
+	result, existing := reconcile()
+	suite.Assert().Equal(controllerruntime.Result{}, result)
+	suite.Assert().False(existing.Status.Ready)
+	suite.Assert().Equal(`invalid spec: invalid nameserver "ns1.google.com", use an IP address with an optional port, a tls:// or an https:// URL`, existing.Status.Reason)
+
+	existing.Spec.Nameservers = nil
+	err := reconciler.Client.Update(ctx, existing)
+	suite.Require().NoError(err)
+
+	result, existing = reconcile()
+	suite.Assert().True(result.Requeue)
+	suite.Assert().True(existing.Status.Ready)
+	suite.Assert().Empty(existing.Status.Reason)
+	suite.Assert().Len(existing.Status.IPs, 2)
+}
+
+func (suite *ControllerSuite) TestACLDNSEntryReconcilerFailureBackoff() {
+	ctx := context.Background()
+	dnsEntry := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "typo.google.com.br",
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host: "typo.google.com.br",
+		},
+	}
+
+	resolver := &fakeResolver{}
+	reconciler := &ACLDNSEntryReconciler{
+		Client:            fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(dnsEntry).Build(),
+		Scheme:            scheme.Scheme,
+		Resolver:          resolver,
+		RequeueInterval:   10 * time.Minute,
+		MaxFailureBackoff: time.Hour,
+	}
+	reconcile := func() (controllerruntime.Result, *v1alpha1.ACLDNSEntry) {
+		result, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(dnsEntry),
+		})
+		suite.Require().NoError(err)
+
+		existing := &v1alpha1.ACLDNSEntry{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(dnsEntry), existing)
+		suite.Require().NoError(err)
+		return result, existing
+	}
+
+	expected := []struct {
+		requeueAfter  time.Duration
+		retryInterval string
+	}{
+		{requeueAfter: 10 * time.Minute},
+		{requeueAfter: 10 * time.Minute},
+		{requeueAfter: 20 * time.Minute, retryInterval: "20m0s"},
+		{requeueAfter: 40 * time.Minute, retryInterval: "40m0s"},
+		{requeueAfter: time.Hour, retryInterval: "1h0m0s"},
+		{requeueAfter: time.Hour, retryInterval: "1h0m0s"},
+	}
+	for i, e := range expected {
+		result, existing := reconcile()
+		suite.Assert().Equal(e.requeueAfter, result.RequeueAfter, "failure %d", i+1)
+		suite.Assert().Equal(i+1, existing.Status.ConsecutiveFailures)
+		suite.Assert().Equal(e.retryInterval, existing.Status.RetryInterval, "failure %d", i+1)
+		suite.Assert().False(existing.Status.Ready)
+	}
+
+	// a successful lookup resets the backoff
+	resolver.hosts = map[string][]string{"typo.google.com.br": {"8.8.8.8"}}
+	result, existing := reconcile()
+	suite.Assert().Equal(10*time.Minute, result.RequeueAfter)
+	suite.Assert().True(existing.Status.Ready)
+	suite.Assert().Zero(existing.Status.ConsecutiveFailures)
+	suite.Assert().Empty(existing.Status.RetryInterval)
+}
+
+func TestFailureRequeueInterval(t *testing.T) {
+	r := &ACLDNSEntryReconciler{RequeueInterval: time.Minute, FailureBackoffThreshold: 1, MaxFailureBackoff: 5 * time.Minute}
+	assert.Equal(t, time.Minute, r.failureRequeueInterval(0))
+	assert.Equal(t, 2*time.Minute, r.failureRequeueInterval(1))
+	assert.Equal(t, 4*time.Minute, r.failureRequeueInterval(2))
+	assert.Equal(t, 5*time.Minute, r.failureRequeueInterval(3))
+	assert.Equal(t, 5*time.Minute, r.failureRequeueInterval(100))
+
+	// a cap below the requeue interval does not shorten it
+	r.MaxFailureBackoff = 30 * time.Second
+	assert.Equal(t, time.Minute, r.failureRequeueInterval(10))
+}
+
+func TestDNSEntrySpecChanged(t *testing.T) {
+	dnsEntry := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{Name: "typo", Generation: 1},
+		Spec:       v1alpha1.ACLDNSEntrySpec{Host: "exampel.com"},
+	}
+
+	failed := dnsEntry.DeepCopy()
+	failed.Status.ConsecutiveFailures = 1
+	failed.Status.Reason = "no such host"
+	assert.False(t, dnsEntrySpecChanged.Update(event.UpdateEvent{ObjectOld: dnsEntry, ObjectNew: failed}))
+
+	paused := failed.DeepCopy()
+	paused.Annotations = map[string]string{pausedAnnotation: "true"}
+	assert.True(t, dnsEntrySpecChanged.Update(event.UpdateEvent{ObjectOld: failed, ObjectNew: paused}))
+
+	edited := failed.DeepCopy()
+	edited.Spec.Host = "example.com"
+	edited.Generation = 2
+	assert.True(t, dnsEntrySpecChanged.Update(event.UpdateEvent{ObjectOld: failed, ObjectNew: edited}))
+	assert.True(t, dnsEntrySpecChanged.Create(event.CreateEvent{Object: dnsEntry}))
 }
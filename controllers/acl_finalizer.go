@@ -0,0 +1,400 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1alpha1 "github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+const (
+	aclCleanupFinalizer = "acl.extensions.tsuru.io/cleanup"
+
+	// aclOwnersAnnotation holds a comma separated list of namespace/name of ACLs that uses a shared address object
+	aclOwnersAnnotation = "acl.extensions.tsuru.io/owners"
+
+	// userOwnedAnnotation marks address objects created by users, like an ACLDNSEntry of an external tool,
+	// they count as a reference of their own and are kept when the last ACL releases them
+	userOwnedAnnotation = "acl.extensions.tsuru.io/user-owned"
+)
+
+func (r *ACLReconciler) ensureFinalizer(ctx context.Context, acl *v1alpha1.ACL) error {
+	if controllerutil.ContainsFinalizer(acl, aclCleanupFinalizer) {
+		return nil
+	}
+
+	controllerutil.AddFinalizer(acl, aclCleanupFinalizer)
+	return r.Client.Update(ctx, acl)
+}
+
+func (r *ACLReconciler) finalizeACL(ctx context.Context, acl *v1alpha1.ACL) error {
+	l := log.FromContext(ctx)
+
+	if !controllerutil.ContainsFinalizer(acl, aclCleanupFinalizer) {
+		return nil
+	}
+
+	// the endpoints are read before their TsuruServiceInstanceAddress is released
+	objs, err := r.addressObjectsWithEndpoints(ctx, acl)
+	if err != nil {
+		return err
+	}
+	// objects of destinations removed from spec that were not released yet
+	objs = append(objs, unusedAddressObjects(acl.Status.AddressObjects, objs)...)
+
+	owner := aclOwnerKey(acl)
+	for _, obj := range objs {
+		err := r.releaseAddressObject(ctx, obj, owner)
+		if err != nil {
+			l.Error(err, "could not release address object", "name", obj.GetName())
+			return err
+		}
+	}
+
+	controllerutil.RemoveFinalizer(acl, aclCleanupFinalizer)
+	err = r.Client.Update(ctx, acl)
+	if k8sErrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// addAddressOwner registers the ACL on owners annotation of all address objects used by the ACL,
+// the objects on status.addressObjects that are no longer used are released
+func (r *ACLReconciler) addAddressOwner(ctx context.Context, acl *v1alpha1.ACL) error {
+	objs, err := r.addressObjectsWithEndpoints(ctx, acl)
+	if err != nil {
+		return err
+	}
+
+	owner := aclOwnerKey(acl)
+	owned := []v1alpha1.ACLStatusAddressObject{}
+	for _, obj := range objs {
+		err := r.Client.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+		if k8sErrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		owned = append(owned, addressObjectRef(obj))
+
+		owners := aclOwners(obj)
+		if containsString(owners, owner) {
+			continue
+		}
+
+		setACLOwners(obj, append(owners, owner))
+		err = r.Client.Update(ctx, obj)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, obj := range unusedAddressObjects(acl.Status.AddressObjects, objs) {
+		log.FromContext(ctx).Info("releasing address object no longer used by ACL", "kind", addressObjectRef(obj).Kind, "name", obj.GetName())
+		err = r.releaseAddressObject(ctx, obj, owner)
+		if err != nil {
+			return err
+		}
+	}
+
+	sort.Slice(owned, func(i, j int) bool {
+		if owned[i].Kind != owned[j].Kind {
+			return owned[i].Kind < owned[j].Kind
+		}
+		return owned[i].Name < owned[j].Name
+	})
+	if len(owned) == 0 {
+		owned = nil
+	}
+	if reflect.DeepEqual(acl.Status.AddressObjects, owned) {
+		return nil
+	}
+
+	// only the list is patched, the status written by reconcile is kept as it is
+	original := acl.DeepCopy()
+	acl.Status.AddressObjects = owned
+	return r.Client.Status().Patch(ctx, acl, client.MergeFrom(original))
+}
+
+// addressObjectRef is the reference of obj on status.addressObjects
+func addressObjectRef(obj client.Object) v1alpha1.ACLStatusAddressObject {
+	kind := ""
+	switch obj.(type) {
+	case *v1alpha1.ACLDNSEntry:
+		kind = "ACLDNSEntry"
+	case *v1alpha1.TsuruAppAddress:
+		kind = "TsuruAppAddress"
+	case *v1alpha1.RpaasInstanceAddress:
+		kind = "RpaasInstanceAddress"
+	case *v1alpha1.TsuruServiceInstanceAddress:
+		kind = "TsuruServiceInstanceAddress"
+	}
+	return v1alpha1.ACLStatusAddressObject{Kind: kind, Name: obj.GetName()}
+}
+
+// unusedAddressObjects returns the objects of refs that are not on objs, refs of unknown kinds are ignored
+func unusedAddressObjects(refs []v1alpha1.ACLStatusAddressObject, objs []client.Object) []client.Object {
+	used := map[v1alpha1.ACLStatusAddressObject]bool{}
+	for _, obj := range objs {
+		used[addressObjectRef(obj)] = true
+	}
+
+	unused := []client.Object{}
+	for _, ref := range refs {
+		if used[ref] {
+			continue
+		}
+
+		var obj client.Object
+		switch ref.Kind {
+		case "ACLDNSEntry":
+			obj = &v1alpha1.ACLDNSEntry{}
+		case "TsuruAppAddress":
+			obj = &v1alpha1.TsuruAppAddress{}
+		case "RpaasInstanceAddress":
+			obj = &v1alpha1.RpaasInstanceAddress{}
+		case "TsuruServiceInstanceAddress":
+			obj = &v1alpha1.TsuruServiceInstanceAddress{}
+		default:
+			continue
+		}
+		obj.SetName(ref.Name)
+		unused = append(unused, obj)
+	}
+	return unused
+}
+
+// releaseAddressObject removes the ACL from owners annotation, the object is deleted when no ACL remains
+func (r *ACLReconciler) releaseAddressObject(ctx context.Context, obj client.Object, owner string) error {
+	err := r.Client.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+	if k8sErrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	owners := []string{}
+	for _, existing := range aclOwners(obj) {
+		if existing != owner {
+			owners = append(owners, existing)
+		}
+	}
+
+	if len(owners) == 0 && !isUserOwned(obj) {
+		err = r.Client.Delete(ctx, obj)
+		if k8sErrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	setACLOwners(obj, owners)
+	return r.Client.Update(ctx, obj)
+}
+
+// addressObjectsWithEndpoints returns the address objects of addressObjectsForACL and the ACLDNSEntry objects
+// of the hostnames of the endpoints of tsuruServiceInstance destinations, which are only known by the status
+// of their TsuruServiceInstanceAddress, and of the hostnames listed on the ConfigMaps of configMap destinations,
+// the TsuruAppAddress objects of the apps of tsuruAppPool destinations are found by the pool on their status
+func (r *ACLReconciler) addressObjectsWithEndpoints(ctx context.Context, acl *v1alpha1.ACL) ([]client.Object, error) {
+	dnsEntryNamespace := r.dnsEntryNamespace(acl)
+	objs := addressObjectsForACL(acl, dnsEntryNamespace)
+	seen := map[string]bool{}
+	seenTsuruApps := map[string]bool{}
+	for _, obj := range objs {
+		switch obj.(type) {
+		case *v1alpha1.ACLDNSEntry:
+			seen[obj.GetName()] = true
+		case *v1alpha1.TsuruAppAddress:
+			seenTsuruApps[obj.GetName()] = true
+		}
+	}
+
+	var allTsuruAppAddress *v1alpha1.TsuruAppAddressList
+	addPoolApps := func(tsuruAppPool string, exceptApps []string) error {
+		if allTsuruAppAddress == nil {
+			allTsuruAppAddress = &v1alpha1.TsuruAppAddressList{}
+			err := r.Client.List(ctx, allTsuruAppAddress)
+			if err != nil {
+				return err
+			}
+		}
+
+		// egressRulesForTsuruAppPool uses the addresses of every router of apps
+		for i := range allTsuruAppAddress.Items {
+			appAddress := &allTsuruAppAddress.Items[i]
+			if appAddress.Status.Pool != tsuruAppPool || len(appAddress.Spec.Routers) > 0 ||
+				containsString(exceptApps, appAddress.Spec.Name) || seenTsuruApps[appAddress.Name] {
+				continue
+			}
+			seenTsuruApps[appAddress.Name] = true
+			obj := &v1alpha1.TsuruAppAddress{}
+			obj.Name = appAddress.Name
+			objs = append(objs, obj)
+		}
+		return nil
+	}
+
+	addDNSEntries := func(names []string) {
+		for _, name := range names {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			obj := &v1alpha1.ACLDNSEntry{}
+			obj.Name = name
+			objs = append(objs, obj)
+		}
+	}
+
+	for _, destination := range acl.Spec.Destinations {
+		if destination.TsuruAppPool != "" {
+			err := addPoolApps(destination.TsuruAppPool, destination.TsuruAppPoolExcept)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if destination.ConfigMap != nil {
+			names, err := configMapDNSEntryNames(ctx, r.apiReader(), destination.ConfigMap, acl.Namespace, dnsEntryNamespace)
+			if err != nil {
+				return nil, err
+			}
+			addDNSEntries(names)
+			continue
+		}
+
+		if destination.TsuruServiceInstance == nil {
+			continue
+		}
+
+		instanceAddress := &v1alpha1.TsuruServiceInstanceAddress{}
+		err := r.Client.Get(ctx, client.ObjectKey{Name: tsuruServiceInstanceAddressName(destination.TsuruServiceInstance)}, instanceAddress)
+		if k8sErrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		addDNSEntries(endpointDNSEntryNames(instanceAddress.Status.Endpoints, dnsEntryNamespace))
+	}
+
+	return objs, nil
+}
+
+// endpointDNSEntryNames are the names of the ACLDNSEntry objects of the hostnames of endpoints
+func endpointDNSEntryNames(endpoints []v1alpha1.TsuruServiceInstanceEndpoint, dnsEntryNamespace string) []string {
+	names := []string{}
+	for _, endpoint := range endpoints {
+		if isIPRange(endpoint.Host) {
+			continue
+		}
+		names = append(names, dnsEntryName(externalDNSHost(&v1alpha1.ACLSpecExternalDNS{Name: endpoint.Host}), nil, dnsEntryNamespace))
+	}
+	return names
+}
+
+// addressObjectsForACL returns the cluster-scoped address objects created by ensureDNSEntry, ensureTsuruAppAddress,
+// ensureRpaasInstanceAddress and ensureTsuruServiceInstanceAddress for an ACL, dnsEntryNamespace is empty for shared entries
+func addressObjectsForACL(acl *v1alpha1.ACL, dnsEntryNamespace string) []client.Object {
+	objs := []client.Object{}
+	seen := map[string]bool{}
+	add := func(obj client.Object, kind string) {
+		key := kind + "/" + obj.GetName()
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		objs = append(objs, obj)
+	}
+
+	addTsuruApp := func(tsuruApp string, routers []string) {
+		obj := &v1alpha1.TsuruAppAddress{}
+		obj.Name = tsuruAppAddressName(tsuruApp, routers)
+		add(obj, "TsuruAppAddress")
+	}
+
+	addRpaasInstance := func(rpaasInstance *v1alpha1.ACLSpecRpaasInstance) {
+		obj := &v1alpha1.RpaasInstanceAddress{}
+		obj.Name = validResourceName(rpaasInstance.ServiceName + "-" + rpaasInstance.Instance)
+		add(obj, "RpaasInstanceAddress")
+	}
+
+	for _, destination := range acl.Spec.Destinations {
+		if destination.TsuruApp != "" {
+			addTsuruApp(destination.TsuruApp, destination.TsuruAppRouters)
+		} else if destination.ExternalDNS != nil {
+			obj := &v1alpha1.ACLDNSEntry{}
+			obj.Name = dnsEntryName(externalDNSHost(destination.ExternalDNS), externalDNSNameservers(destination.ExternalDNS), dnsEntryNamespace)
+			add(obj, "ACLDNSEntry")
+		} else if destination.RpaasInstance != nil {
+			addRpaasInstance(destination.RpaasInstance)
+		} else if destination.TsuruServiceInstance != nil {
+			obj := &v1alpha1.TsuruServiceInstanceAddress{}
+			obj.Name = tsuruServiceInstanceAddressName(destination.TsuruServiceInstance)
+			add(obj, "TsuruServiceInstanceAddress")
+		}
+	}
+
+	for _, ingress := range acl.Spec.Ingress {
+		if ingress.TsuruApp != "" {
+			addTsuruApp(ingress.TsuruApp, nil)
+		} else if ingress.RpaasInstance != nil {
+			addRpaasInstance(ingress.RpaasInstance)
+		}
+	}
+
+	return objs
+}
+
+func isUserOwned(obj client.Object) bool {
+	userOwned, _ := strconv.ParseBool(obj.GetAnnotations()[userOwnedAnnotation])
+	return userOwned
+}
+
+func aclOwnerKey(acl *v1alpha1.ACL) string {
+	return types.NamespacedName{Namespace: acl.Namespace, Name: acl.Name}.String()
+}
+
+func aclOwners(obj client.Object) []string {
+	value := obj.GetAnnotations()[aclOwnersAnnotation]
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+func setACLOwners(obj client.Object, owners []string) {
+	sort.Strings(owners)
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if len(owners) == 0 {
+		delete(annotations, aclOwnersAnnotation)
+	} else {
+		annotations[aclOwnersAnnotation] = strings.Join(owners, ",")
+	}
+	obj.SetAnnotations(annotations)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
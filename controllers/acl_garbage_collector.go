@@ -19,6 +19,22 @@ type ACLGarbageCollector struct {
 	DryRun       bool
 	DryRunOutput io.Writer
 	Logger       logr.Logger
+
+	// LabelScheme are the labels of tsuru jobs, empty keys use DefaultLabelScheme
+	LabelScheme LabelScheme
+
+	// NamespacedDNSEntries must match the option of ACLReconciler, entries of the other mode are collected
+	NamespacedDNSEntries bool
+
+	// APIReader reads the ConfigMaps of configMap destinations, which are not cached, defaults to Client
+	APIReader client.Reader
+}
+
+func (a *ACLGarbageCollector) apiReader() client.Reader {
+	if a.APIReader != nil {
+		return a.APIReader
+	}
+	return a.Client
 }
 
 type appACLKey struct {
@@ -45,27 +61,33 @@ func (a *ACLGarbageCollector) Run(ctx context.Context) {
 func (a *ACLGarbageCollector) Loop(ctx context.Context) error {
 	appACLs := map[appACLKey]struct{}{}
 	jobACLs := map[jobACLKey]struct{}{}
-	dnsEntries := map[string]struct{}{}
-	tsuruApps := map[string]struct{}{}
+	dnsEntries := map[string]string{}
+	tsuruApps := map[string]string{}
 	rpaaInstances := map[v1alpha1.ACLSpecRpaasInstance]string{}
+	serviceInstances := map[v1alpha1.ACLSpecTsuruServiceInstance]string{}
+	tsuruAppPools := map[string]struct{}{}
 
 	allDNSEntries, err := a.allDNSEntries(ctx)
 	if err != nil {
 		return err
 	}
-	dnsEntries = make(map[string]struct{}, len(allDNSEntries))
-	for _, dnsEntry := range allDNSEntries {
-		dnsEntries[dnsEntry.Spec.Host] = struct{}{}
+	dnsEntries = make(map[string]string, len(allDNSEntries))
+	for i := range allDNSEntries {
+		// entries created by users are not referenced by any ACL
+		if isUserOwned(&allDNSEntries[i]) {
+			continue
+		}
+		dnsEntries[allDNSEntries[i].Name] = allDNSEntries[i].Spec.Host
 	}
 
 	allTsuruAppAddress, err := a.allTsuruAppAddress(ctx)
 	if err != nil {
 		return err
 	}
-	tsuruApps = make(map[string]struct{}, len(allTsuruAppAddress))
+	tsuruApps = make(map[string]string, len(allTsuruAppAddress))
 	appACLs = make(map[appACLKey]struct{}, len(allTsuruAppAddress)) // fair aproximation
 	for _, tsuruAppAddress := range allTsuruAppAddress {
-		tsuruApps[tsuruAppAddress.Spec.Name] = struct{}{}
+		tsuruApps[tsuruAppAddress.Name] = tsuruAppAddress.Spec.Name
 	}
 
 	allRPaaSInstancesAddresses, err := a.allRPaaSInstancesAddresses(ctx)
@@ -82,6 +104,22 @@ func (a *ACLGarbageCollector) Loop(ctx context.Context) error {
 		rpaaInstances[key] = rpaaInstanceAddress.ObjectMeta.Name
 	}
 
+	allServiceInstancesAddresses, err := a.allServiceInstancesAddresses(ctx)
+	if err != nil {
+		return err
+	}
+	serviceInstances = make(map[v1alpha1.ACLSpecTsuruServiceInstance]string, len(allServiceInstancesAddresses))
+	serviceInstanceEndpoints := make(map[v1alpha1.ACLSpecTsuruServiceInstance][]v1alpha1.TsuruServiceInstanceEndpoint, len(allServiceInstancesAddresses))
+	for _, serviceInstanceAddress := range allServiceInstancesAddresses {
+		key := v1alpha1.ACLSpecTsuruServiceInstance{
+			ServiceName: serviceInstanceAddress.Spec.ServiceName,
+			Instance:    serviceInstanceAddress.Spec.Instance,
+		}
+
+		serviceInstances[key] = serviceInstanceAddress.ObjectMeta.Name
+		serviceInstanceEndpoints[key] = serviceInstanceAddress.Status.Endpoints
+	}
+
 	allACLSs, err := a.allACLs(ctx)
 	if err != nil {
 		return err
@@ -103,24 +141,68 @@ func (a *ACLGarbageCollector) Loop(ctx context.Context) error {
 
 		for _, destination := range acl.Spec.Destinations {
 			if destination.ExternalDNS != nil {
-				_, found := dnsEntries[destination.ExternalDNS.Name]
-				if found {
-					delete(dnsEntries, destination.ExternalDNS.Name) // the remain keys on dnsEntries must be garbage collected
+				dnsEntryNamespace := ""
+				if a.NamespacedDNSEntries {
+					dnsEntryNamespace = acl.Namespace
 				}
-			} else if destination.TsuruApp != "" {
-				_, found := tsuruApps[destination.TsuruApp]
+				dnsEntryName := dnsEntryName(externalDNSHost(destination.ExternalDNS), externalDNSNameservers(destination.ExternalDNS), dnsEntryNamespace)
+				_, found := dnsEntries[dnsEntryName]
 				if found {
-					delete(tsuruApps, destination.TsuruApp) // the remain keys on tsuruApps must be garbage collected
+					delete(dnsEntries, dnsEntryName) // the remain keys on dnsEntries must be garbage collected
 				}
+			} else if destination.TsuruAppPool != "" {
+				tsuruAppPools[destination.TsuruAppPool] = struct{}{}
+			} else if destination.TsuruApp != "" {
+				delete(tsuruApps, tsuruAppAddressName(destination.TsuruApp, destination.TsuruAppRouters)) // the remain keys on tsuruApps must be garbage collected
 			} else if destination.RpaasInstance != nil {
 				_, found := rpaaInstances[*destination.RpaasInstance]
 				if found {
 					delete(rpaaInstances, *destination.RpaasInstance) // the remain keys on rpaaInstances must be garbage collected
 				}
+			} else if destination.TsuruServiceInstance != nil {
+				delete(serviceInstances, *destination.TsuruServiceInstance) // the remain keys on serviceInstances must be garbage collected
+
+				// hostnames of the endpoints of instance are resolved by entries of the ACL
+				dnsEntryNamespace := ""
+				if a.NamespacedDNSEntries {
+					dnsEntryNamespace = acl.Namespace
+				}
+				for _, dnsEntryName := range endpointDNSEntryNames(serviceInstanceEndpoints[*destination.TsuruServiceInstance], dnsEntryNamespace) {
+					delete(dnsEntries, dnsEntryName)
+				}
+			} else if destination.ConfigMap != nil {
+				// hostnames listed on the ConfigMap are resolved by entries of the ACL
+				dnsEntryNamespace := ""
+				if a.NamespacedDNSEntries {
+					dnsEntryNamespace = acl.Namespace
+				}
+				dnsEntryNames, err := configMapDNSEntryNames(ctx, a.apiReader(), destination.ConfigMap, acl.Namespace, dnsEntryNamespace)
+				if err != nil {
+					return err
+				}
+				for _, dnsEntryName := range dnsEntryNames {
+					delete(dnsEntries, dnsEntryName)
+				}
+			}
+		}
+
+		// addresses of ingress peers are used like the ones of destinations
+		for _, ingress := range acl.Spec.Ingress {
+			if ingress.TsuruApp != "" {
+				delete(tsuruApps, tsuruAppAddressName(ingress.TsuruApp, nil)) // the remain keys on tsuruApps must be garbage collected
+			} else if ingress.RpaasInstance != nil {
+				delete(rpaaInstances, *ingress.RpaasInstance) // the remain keys on rpaaInstances must be garbage collected
 			}
 		}
 	}
 
+	// addresses of every router of apps are used by tsuruAppPool destinations of their pools
+	for _, tsuruAppAddress := range allTsuruAppAddress {
+		if _, found := tsuruAppPools[tsuruAppAddress.Status.Pool]; found && len(tsuruAppAddress.Spec.Routers) == 0 {
+			delete(tsuruApps, tsuruAppAddress.Name)
+		}
+	}
+
 	allTsuruApps, err := a.allTsuruApps(ctx)
 	if err != nil {
 		return err
@@ -142,7 +224,7 @@ func (a *ACLGarbageCollector) Loop(ctx context.Context) error {
 	}
 	for _, tsuruJob := range allTsuruJobs {
 		key := jobACLKey{
-			Job:       tsuruJob.Labels[tsuruJobLabel],
+			Job:       tsuruJob.Labels[a.LabelScheme.WithDefaults().JobName],
 			Namespace: tsuruJob.Namespace,
 		}
 		_, found := jobACLs[key]
@@ -152,10 +234,10 @@ func (a *ACLGarbageCollector) Loop(ctx context.Context) error {
 	}
 
 	if a.DryRun {
-		for dnsEntry := range dnsEntries {
-			fmt.Fprintln(a.DryRunOutput, "dnsEntry is marked to delete", dnsEntry)
+		for _, host := range dnsEntries {
+			fmt.Fprintln(a.DryRunOutput, "dnsEntry is marked to delete", host)
 		}
-		for tsuruApp := range tsuruApps {
+		for _, tsuruApp := range tsuruApps {
 			fmt.Fprintf(a.DryRunOutput, "tsuruApp is marked to delete: %q\n", tsuruApp)
 		}
 
@@ -163,6 +245,10 @@ func (a *ACLGarbageCollector) Loop(ctx context.Context) error {
 			fmt.Fprintln(a.DryRunOutput, "rpaaInstance is marked to delete", rpaasInstanceName)
 		}
 
+		for _, serviceInstanceName := range serviceInstances {
+			fmt.Fprintln(a.DryRunOutput, "serviceInstance is marked to delete", serviceInstanceName)
+		}
+
 		for appACL := range appACLs {
 			fmt.Fprintln(a.DryRunOutput, "APP ACL is marked to delete", appACL.Namespace, "/", appACL.App)
 		}
@@ -173,21 +259,21 @@ func (a *ACLGarbageCollector) Loop(ctx context.Context) error {
 		return nil
 	}
 
-	for dnsEntry := range dnsEntries {
+	for dnsEntryName, host := range dnsEntries {
 		err = a.Client.Delete(ctx, &v1alpha1.ACLDNSEntry{
 			ObjectMeta: v1.ObjectMeta{
-				Name: validResourceName(dnsEntry),
+				Name: dnsEntryName,
 			},
 		})
 		if err != nil {
-			a.Logger.Error(err, "failed to remove dnsEntry", "dnsEntry", dnsEntry)
+			a.Logger.Error(err, "failed to remove dnsEntry", "dnsEntry", host)
 		}
 	}
 
-	for tsuruApp := range tsuruApps {
+	for name, tsuruApp := range tsuruApps {
 		err = a.Client.Delete(ctx, &v1alpha1.TsuruAppAddress{
 			ObjectMeta: v1.ObjectMeta{
-				Name: validResourceName(tsuruApp),
+				Name: name,
 			},
 		})
 		if err != nil {
@@ -206,6 +292,17 @@ func (a *ACLGarbageCollector) Loop(ctx context.Context) error {
 		}
 	}
 
+	for _, serviceInstanceName := range serviceInstances {
+		err = a.Client.Delete(ctx, &v1alpha1.TsuruServiceInstanceAddress{
+			ObjectMeta: v1.ObjectMeta{
+				Name: serviceInstanceName,
+			},
+		})
+		if err != nil {
+			a.Logger.Error(err, "failed to remove tsuruServiceInstanceAddress", "tsuruServiceInstanceAddress", serviceInstanceName)
+		}
+	}
+
 	for appACL := range appACLs {
 		err = a.Client.Delete(ctx, &v1alpha1.ACL{
 			ObjectMeta: v1.ObjectMeta{
@@ -337,6 +434,32 @@ func (a *ACLGarbageCollector) allRPaaSInstancesAddresses(ctx context.Context) ([
 	return result, nil
 }
 
+func (a *ACLGarbageCollector) allServiceInstancesAddresses(ctx context.Context) ([]v1alpha1.TsuruServiceInstanceAddress, error) {
+	result := []v1alpha1.TsuruServiceInstanceAddress{}
+
+	continueToken := ""
+
+	for {
+		allServiceInstancesAddress := &v1alpha1.TsuruServiceInstanceAddressList{}
+
+		err := a.Client.List(ctx, allServiceInstancesAddress, &client.ListOptions{
+			Continue: continueToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, allServiceInstancesAddress.Items...)
+
+		if allServiceInstancesAddress.Continue == "" {
+			break
+		}
+
+		continueToken = allServiceInstancesAddress.Continue
+	}
+
+	return result, nil
+}
+
 func (a *ACLGarbageCollector) allTsuruApps(ctx context.Context) ([]tsuruv1.App, error) {
 	result := []tsuruv1.App{}
 
@@ -380,7 +503,7 @@ func (a *ACLGarbageCollector) allTsuruJobs(ctx context.Context) ([]batchv1.CronJ
 
 	cronjobLoop:
 		for _, cronjob := range allTsuruJobs.Items {
-			if cronjob.Labels[tsuruJobLabel] == "" {
+			if cronjob.Labels[a.LabelScheme.WithDefaults().JobName] == "" {
 				continue cronjobLoop
 			}
 			result = append(result, cronjob)
@@ -10,6 +10,7 @@ import (
 	"github.com/tsuru/acl-operator/api/scheme"
 	"github.com/tsuru/acl-operator/api/v1alpha1"
 	tsuruv1 "github.com/tsuru/tsuru/provision/kubernetes/pkg/apis/tsuru/v1"
+	corev1 "k8s.io/api/core/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -398,6 +399,188 @@ func TestLoopExternalDNS(t *testing.T) {
 	assert.True(t, k8sErrors.IsNotFound(err))
 }
 
+func TestLoopConfigMapDNSEntries(t *testing.T) {
+	ctx := context.Background()
+
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace: "default",
+			Name:      "my-app",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "my-app",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ConfigMap: &v1alpha1.ACLSpecConfigMap{
+						Name: "egress-allowlist",
+						Key:  "addresses",
+					},
+				},
+			},
+		},
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace: "default",
+			Name:      "egress-allowlist",
+		},
+		Data: map[string]string{
+			"addresses": "10.0.0.0/8\nto-keep.example.com",
+		},
+	}
+
+	dnsEntry1 := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "to-keep.example.com",
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host: "to-keep.example.com",
+		},
+	}
+
+	dnsEntry2 := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "to-delete.example.com",
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host: "to-delete.example.com",
+		},
+	}
+
+	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(
+		acl, configMap, dnsEntry1, dnsEntry2,
+	).Build()
+	gc := &ACLGarbageCollector{
+		Client: client,
+	}
+	err := gc.Loop(ctx)
+
+	require.NoError(t, err)
+
+	existingDNSEntry := &v1alpha1.ACLDNSEntry{}
+	err = client.Get(ctx, types.NamespacedName{
+		Name: "to-keep.example.com",
+	}, existingDNSEntry)
+	assert.NoError(t, err)
+
+	err = client.Get(ctx, types.NamespacedName{
+		Name: "to-delete.example.com",
+	}, existingDNSEntry)
+	assert.True(t, k8sErrors.IsNotFound(err))
+}
+
+func TestLoopKeepsUserOwnedDNSEntry(t *testing.T) {
+	ctx := context.Background()
+
+	dnsEntry := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        "external-tool.example.com",
+			Annotations: map[string]string{userOwnedAnnotation: "true"},
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host: "external-tool.example.com",
+		},
+	}
+
+	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(dnsEntry).Build()
+	gc := &ACLGarbageCollector{
+		Client: client,
+	}
+	err := gc.Loop(ctx)
+	require.NoError(t, err)
+
+	existingDNSEntry := &v1alpha1.ACLDNSEntry{}
+	err = client.Get(ctx, types.NamespacedName{
+		Name: "external-tool.example.com",
+	}, existingDNSEntry)
+	assert.NoError(t, err)
+}
+
+func TestLoopNamespacedExternalDNS(t *testing.T) {
+	ctx := context.Background()
+
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace: "default",
+			Name:      "my-app",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "my-app",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalDNS: &v1alpha1.ACLSpecExternalDNS{
+						Name: "example.com",
+					},
+				},
+			},
+		},
+	}
+
+	app := &tsuruv1.App{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "my-app",
+		},
+		Spec: tsuruv1.AppSpec{
+			NamespaceName: "default",
+		},
+	}
+
+	namespacedEntry := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: dnsEntryName("example.com", nil, "default"),
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host:      "example.com",
+			Namespace: "default",
+		},
+	}
+
+	otherNamespaceEntry := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: dnsEntryName("example.com", nil, "other"),
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host:      "example.com",
+			Namespace: "other",
+		},
+	}
+
+	// the shared entry of the previous mode is not used anymore
+	sharedEntry := &v1alpha1.ACLDNSEntry{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "example.com",
+		},
+		Spec: v1alpha1.ACLDNSEntrySpec{
+			Host: "example.com",
+		},
+	}
+
+	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(
+		acl, app, namespacedEntry, otherNamespaceEntry, sharedEntry,
+	).Build()
+	gc := &ACLGarbageCollector{
+		Client:               client,
+		NamespacedDNSEntries: true,
+	}
+	err := gc.Loop(ctx)
+
+	require.NoError(t, err)
+
+	existingDNSEntry := &v1alpha1.ACLDNSEntry{}
+	err = client.Get(ctx, types.NamespacedName{Name: namespacedEntry.Name}, existingDNSEntry)
+	assert.NoError(t, err)
+
+	for _, name := range []string{otherNamespaceEntry.Name, sharedEntry.Name} {
+		err = client.Get(ctx, types.NamespacedName{Name: name}, existingDNSEntry)
+		assert.True(t, k8sErrors.IsNotFound(err), name)
+	}
+}
+
 func TestLoopTsuruAddress(t *testing.T) {
 	ctx := context.Background()
 
@@ -414,6 +597,9 @@ func TestLoopTsuruAddress(t *testing.T) {
 				{
 					TsuruApp: "to-keep",
 				},
+				{
+					TsuruAppPool: "my-pool",
+				},
 			},
 		},
 	}
@@ -445,8 +631,20 @@ func TestLoopTsuruAddress(t *testing.T) {
 		},
 	}
 
+	tsuruAddress3 := &v1alpha1.TsuruAppAddress{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "in-pool",
+		},
+		Spec: v1alpha1.TsuruAppAddressSpec{
+			Name: "in-pool",
+		},
+		Status: v1alpha1.ResourceAddressStatus{
+			Pool: "my-pool",
+		},
+	}
+
 	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(
-		acl, app, tsuruAddress1, tsuruAddress2,
+		acl, app, tsuruAddress1, tsuruAddress2, tsuruAddress3,
 	).Build()
 	gc := &ACLGarbageCollector{
 		Client: client,
@@ -460,6 +658,90 @@ func TestLoopTsuruAddress(t *testing.T) {
 		Name: "to-delete",
 	}, existingTsuruAppAddress)
 	assert.True(t, k8sErrors.IsNotFound(err))
+
+	err = client.Get(ctx, types.NamespacedName{
+		Name: "in-pool",
+	}, existingTsuruAppAddress)
+	assert.NoError(t, err)
+}
+
+func TestLoopTsuruAddressRouters(t *testing.T) {
+	ctx := context.Background()
+
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace: "default",
+			Name:      "my-app",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "my-app",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					TsuruApp:        "my-other-app",
+					TsuruAppRouters: []string{"external-router"},
+				},
+				{
+					TsuruAppPool: "my-pool",
+				},
+			},
+		},
+	}
+
+	app := &tsuruv1.App{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "my-app",
+		},
+		Spec: tsuruv1.AppSpec{
+			NamespaceName: "default",
+		},
+	}
+
+	toKeep := &v1alpha1.TsuruAppAddress{
+		ObjectMeta: v1.ObjectMeta{
+			Name: tsuruAppAddressName("my-other-app", []string{"external-router"}),
+		},
+		Spec: v1alpha1.TsuruAppAddressSpec{
+			Name:    "my-other-app",
+			Routers: []string{"external-router"},
+		},
+	}
+
+	// addresses restricted to some routers are not used by tsuruAppPool destinations
+	toDelete := &v1alpha1.TsuruAppAddress{
+		ObjectMeta: v1.ObjectMeta{
+			Name: tsuruAppAddressName("my-other-app", []string{"internal-router"}),
+		},
+		Spec: v1alpha1.TsuruAppAddressSpec{
+			Name:    "my-other-app",
+			Routers: []string{"internal-router"},
+		},
+		Status: v1alpha1.ResourceAddressStatus{
+			Pool: "my-pool",
+		},
+	}
+
+	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(
+		acl, app, toKeep, toDelete,
+	).Build()
+	gc := &ACLGarbageCollector{
+		Client: client,
+	}
+	err := gc.Loop(ctx)
+
+	require.NoError(t, err)
+
+	existingTsuruAppAddress := &v1alpha1.TsuruAppAddress{}
+	err = client.Get(ctx, types.NamespacedName{
+		Name: toDelete.Name,
+	}, existingTsuruAppAddress)
+	assert.True(t, k8sErrors.IsNotFound(err))
+
+	err = client.Get(ctx, types.NamespacedName{
+		Name: toKeep.Name,
+	}, existingTsuruAppAddress)
+	assert.NoError(t, err)
 }
 
 func TestLoopRPaaSAddress(t *testing.T) {
@@ -531,6 +813,112 @@ func TestLoopRPaaSAddress(t *testing.T) {
 	assert.True(t, k8sErrors.IsNotFound(err))
 }
 
+func TestLoopIngressAddresses(t *testing.T) {
+	ctx := context.Background()
+
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace: "default",
+			Name:      "my-app",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "my-app",
+			},
+			Ingress: []v1alpha1.ACLSpecIngress{
+				{
+					TsuruApp: "to-keep",
+				},
+				{
+					RpaasInstance: &v1alpha1.ACLSpecRpaasInstance{
+						ServiceName: "rpaasv2",
+						Instance:    "to-keep",
+					},
+				},
+			},
+		},
+	}
+
+	app := &tsuruv1.App{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "my-app",
+		},
+		Spec: tsuruv1.AppSpec{
+			NamespaceName: "default",
+		},
+	}
+
+	tsuruAddress1 := &v1alpha1.TsuruAppAddress{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "to-keep",
+		},
+		Spec: v1alpha1.TsuruAppAddressSpec{
+			Name: "to-keep",
+		},
+	}
+
+	tsuruAddress2 := &v1alpha1.TsuruAppAddress{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "to-delete",
+		},
+		Spec: v1alpha1.TsuruAppAddressSpec{
+			Name: "to-delete",
+		},
+	}
+
+	rpaasInstanceAddress1 := &v1alpha1.RpaasInstanceAddress{
+		ObjectMeta: v1.ObjectMeta{
+			Name: validResourceName("rpaasv2-to-keep"),
+		},
+		Spec: v1alpha1.RpaasInstanceAddressSpec{
+			ServiceName: "rpaasv2",
+			Instance:    "to-keep",
+		},
+	}
+
+	rpaasInstanceAddress2 := &v1alpha1.RpaasInstanceAddress{
+		ObjectMeta: v1.ObjectMeta{
+			Name: validResourceName("rpaasv2-to-delete"),
+		},
+		Spec: v1alpha1.RpaasInstanceAddressSpec{
+			ServiceName: "rpaasv2",
+			Instance:    "to-delete",
+		},
+	}
+
+	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(
+		acl, app, tsuruAddress1, tsuruAddress2, rpaasInstanceAddress1, rpaasInstanceAddress2,
+	).Build()
+	gc := &ACLGarbageCollector{
+		Client: client,
+	}
+	err := gc.Loop(ctx)
+
+	require.NoError(t, err)
+
+	existingTsuruAppAddress := &v1alpha1.TsuruAppAddress{}
+	err = client.Get(ctx, types.NamespacedName{
+		Name: "to-keep",
+	}, existingTsuruAppAddress)
+	assert.NoError(t, err)
+
+	err = client.Get(ctx, types.NamespacedName{
+		Name: "to-delete",
+	}, existingTsuruAppAddress)
+	assert.True(t, k8sErrors.IsNotFound(err))
+
+	existingRpaasInstanceAddress := &v1alpha1.RpaasInstanceAddress{}
+	err = client.Get(ctx, types.NamespacedName{
+		Name: validResourceName("rpaasv2-to-keep"),
+	}, existingRpaasInstanceAddress)
+	assert.NoError(t, err)
+
+	err = client.Get(ctx, types.NamespacedName{
+		Name: validResourceName("rpaasv2-to-delete"),
+	}, existingRpaasInstanceAddress)
+	assert.True(t, k8sErrors.IsNotFound(err))
+}
+
 func TestLoopCleanAppACL(t *testing.T) {
 	ctx := context.Background()
 
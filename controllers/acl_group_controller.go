@@ -0,0 +1,321 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1alpha1 "github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+// aclGroupLabel holds the name of the ACLGroup that generated an ACL
+const aclGroupLabel = "acl.extensions.tsuru.io/group"
+
+// ACLGroupReconciler reconciles an ACLGroup object, each source of the group gets an ACL owned by
+// the group, ACLs of removed sources are deleted and the ACLs of a deleted group are garbage collected
+type ACLGroupReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// MaxConcurrentReconciles is the number of objects reconciled at once, defaults to DefaultConcurrency
+	MaxConcurrentReconciles int
+}
+
+//+kubebuilder:rbac:groups=extensions.tsuru.io,resources=aclgroups,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=extensions.tsuru.io,resources=aclgroups/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=extensions.tsuru.io,resources=aclgroups/finalizers,verbs=update
+
+func (r *ACLGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
+	l := log.FromContext(ctx)
+
+	group := &v1alpha1.ACLGroup{}
+	outcome := reconcileResultNoop
+	defer func() {
+		observeReconcileResult("aclgroup", outcome, err)
+	}()
+
+	err = r.Client.Get(ctx, req.NamespacedName, group)
+	if k8sErrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		l.Error(err, "could not get ACLGroup object")
+		return ctrl.Result{}, err
+	}
+
+	if !group.DeletionTimestamp.IsZero() {
+		// the ACLs are removed by the garbage collector of kubernetes
+		return ctrl.Result{}, nil
+	}
+
+	ctx, l = objectLogger(ctx, group)
+
+	desiredACLs, err := aclsForGroup(group)
+	if err != nil {
+		outcome = reconcileResultError
+		return ctrl.Result{}, r.updateStatus(ctx, group, v1alpha1.ACLGroupStatus{
+			Reason:    err.Error(),
+			TotalACLs: len(group.Spec.Sources),
+		})
+	}
+
+	existingACLs := &v1alpha1.ACLList{}
+	err = r.Client.List(ctx, existingACLs, client.InNamespace(group.Namespace), client.MatchingLabels{aclGroupLabel: group.Name})
+	if err != nil {
+		l.Error(err, "could not list ACLs of group")
+		return ctrl.Result{}, err
+	}
+
+	ownedACLs := map[string]*v1alpha1.ACL{}
+	for i := range existingACLs.Items {
+		if metav1.IsControlledBy(&existingACLs.Items[i], group) {
+			ownedACLs[existingACLs.Items[i].Name] = &existingACLs.Items[i]
+		}
+	}
+
+	status := v1alpha1.ACLGroupStatus{TotalACLs: len(desiredACLs)}
+	var conflicts []string
+	for _, desiredACL := range desiredACLs {
+		acl, result, err := r.ensureACL(ctx, group, desiredACL, ownedACLs[desiredACL.Name])
+		if err != nil {
+			var conflictErr *aclGroupConflictError
+			if !errors.As(err, &conflictErr) {
+				l.Error(err, "could not ensure ACL of group", "acl", desiredACL.Name)
+				return ctrl.Result{}, err
+			}
+
+			conflicts = append(conflicts, err.Error())
+			status.ACLs = append(status.ACLs, v1alpha1.ACLGroupStatusACL{
+				Name:   desiredACL.Name,
+				Source: describeSource(desiredACL.Spec.Source),
+				Reason: err.Error(),
+			})
+			continue
+		}
+
+		if result != reconcileResultNoop {
+			outcome = reconcileResultUpdated
+		}
+
+		status.ACLs = append(status.ACLs, v1alpha1.ACLGroupStatusACL{
+			Name:   acl.Name,
+			Source: describeSource(acl.Spec.Source),
+			Ready:  acl.Status.Ready,
+			Reason: acl.Status.Reason,
+		})
+		if acl.Status.Ready {
+			status.ReadyACLs++
+		}
+	}
+
+	// ACLs of sources removed from the group
+	for name, acl := range ownedACLs {
+		if _, ok := desiredACLs[name]; ok {
+			continue
+		}
+
+		err = r.Client.Delete(ctx, acl)
+		if err != nil && !k8sErrors.IsNotFound(err) {
+			l.Error(err, "could not delete ACL of removed source", "acl", name)
+			return ctrl.Result{}, err
+		}
+		l.Info("ACL of removed source deleted", "acl", name)
+		outcome = reconcileResultUpdated
+	}
+
+	sort.Slice(status.ACLs, func(i, j int) bool {
+		return status.ACLs[i].Name < status.ACLs[j].Name
+	})
+
+	status.Ready = status.ReadyACLs == status.TotalACLs
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		status.Reason = conflicts[0]
+	} else if !status.Ready {
+		status.Reason = fmt.Sprintf("%d of %d ACLs are not ready", status.TotalACLs-status.ReadyACLs, status.TotalACLs)
+	}
+
+	return ctrl.Result{}, r.updateStatus(ctx, group, status)
+}
+
+// ensureACL creates or updates the ACL of a source, an existing ACL that is not controlled by the
+// group is not overwritten
+func (r *ACLGroupReconciler) ensureACL(ctx context.Context, group *v1alpha1.ACLGroup, desiredACL *v1alpha1.ACL, acl *v1alpha1.ACL) (*v1alpha1.ACL, string, error) {
+	if acl == nil {
+		acl = &v1alpha1.ACL{}
+		err := r.Client.Get(ctx, client.ObjectKeyFromObject(desiredACL), acl)
+		if k8sErrors.IsNotFound(err) {
+			err = controllerutil.SetControllerReference(group, desiredACL, r.Scheme)
+			if err != nil {
+				return nil, "", err
+			}
+
+			err = r.Client.Create(ctx, desiredACL)
+			if err != nil {
+				return nil, "", err
+			}
+			return desiredACL, reconcileResultCreated, nil
+		} else if err != nil {
+			return nil, "", err
+		}
+
+		if !metav1.IsControlledBy(acl, group) {
+			return nil, "", &aclGroupConflictError{acl: acl.Name, group: group.Name}
+		}
+	}
+
+	if reflect.DeepEqual(acl.Spec, desiredACL.Spec) && acl.Labels[aclGroupLabel] == group.Name {
+		return acl, reconcileResultNoop, nil
+	}
+
+	acl.Spec = desiredACL.Spec
+	if acl.Labels == nil {
+		acl.Labels = map[string]string{}
+	}
+	acl.Labels[aclGroupLabel] = group.Name
+
+	err := r.Client.Update(ctx, acl)
+	if err != nil {
+		return nil, "", err
+	}
+	return acl, reconcileResultUpdated, nil
+}
+
+func (r *ACLGroupReconciler) updateStatus(ctx context.Context, group *v1alpha1.ACLGroup, status v1alpha1.ACLGroupStatus) error {
+	if reflect.DeepEqual(group.Status, status) {
+		return nil
+	}
+
+	group.Status = status
+	return r.Client.Status().Update(ctx, group)
+}
+
+// aclsForGroup returns the desired ACL of each source of group by name, the ACLs are defaulted and
+// validated like the webhook of ACL does, so an invalid group is reported on its status instead of
+// failing every create and a defaulted field does not differ from the existing ACL on every reconcile
+func aclsForGroup(group *v1alpha1.ACLGroup) (map[string]*v1alpha1.ACL, error) {
+	acls := map[string]*v1alpha1.ACL{}
+	for i, source := range group.Spec.Sources {
+		err := source.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("invalid spec.sources[%d]: %s", i, err.Error())
+		}
+
+		name := validResourceName(group.Name + "-" + aclGroupSourceKey(source))
+		if _, ok := acls[name]; ok {
+			return nil, fmt.Errorf("invalid spec.sources[%d]: %s is repeated", i, describeSource(source))
+		}
+
+		spec := group.Spec.DeepCopy()
+		acls[name] = &v1alpha1.ACL{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: group.Namespace,
+				Labels: map[string]string{
+					aclGroupLabel: group.Name,
+				},
+			},
+			Spec: v1alpha1.ACLSpec{
+				Source:          *source.DeepCopy(),
+				Destinations:    spec.Destinations,
+				Ingress:         spec.Ingress,
+				IPFamilies:      spec.IPFamilies,
+				CIDRAggregation: spec.CIDRAggregation,
+				AddressFilter:   spec.AddressFilter,
+				Template:        spec.Template,
+			},
+		}
+
+		acls[name].Default()
+		err = acls[name].ValidateCreate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return acls, nil
+}
+
+// aclGroupSourceKey is the suffix of the name of the ACL of source
+func aclGroupSourceKey(source v1alpha1.ACLSpecSource) string {
+	switch {
+	case source.TsuruApp != "":
+		return source.TsuruApp
+	case source.TsuruJob != "":
+		return "job-" + source.TsuruJob
+	case source.RpaasInstance != nil:
+		return source.RpaasInstance.ServiceName + "-" + source.RpaasInstance.Instance
+	case source.RawPodSelector != nil:
+		selector, _ := json.Marshal(source.RawPodSelector)
+		return "selector-" + sha256String(string(selector))[:10]
+	case source.Workload != nil:
+		return strings.ToLower(source.Workload.Kind) + "-" + source.Workload.Name
+	}
+
+	return ""
+}
+
+func describeSource(source v1alpha1.ACLSpecSource) string {
+	switch {
+	case source.TsuruApp != "":
+		return "tsuruApp " + source.TsuruApp
+	case source.TsuruJob != "":
+		return "tsuruJob " + source.TsuruJob
+	case source.RpaasInstance != nil:
+		return "rpaasInstance " + source.RpaasInstance.ServiceName + "/" + source.RpaasInstance.Instance
+	case source.RawPodSelector != nil:
+		return "rawPodSelector " + metav1.FormatLabelSelector(source.RawPodSelector)
+	case source.Workload != nil:
+		return "workload " + source.Workload.Kind + "/" + source.Workload.Name
+	}
+
+	return ""
+}
+
+// aclGroupConflictError is returned when the ACL of a source exists and is not controlled by the group
+type aclGroupConflictError struct {
+	acl   string
+	group string
+}
+
+func (e *aclGroupConflictError) Error() string {
+	return fmt.Sprintf("ACL %s already exists and is not managed by ACLGroup %s, it is not overwritten", e.acl, e.group)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ACLGroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.ACLGroup{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: maxConcurrentReconciles(r.MaxConcurrentReconciles, DefaultConcurrency), RecoverPanic: true}).
+		// status changes of the ACLs enqueue their group, so readiness is aggregated
+		Owns(&v1alpha1.ACL{}).
+		Complete(r)
+}
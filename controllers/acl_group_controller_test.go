@@ -0,0 +1,258 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/tsuru/acl-operator/api/scheme"
+	v1alpha1 "github.com/tsuru/acl-operator/api/v1alpha1"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func (suite *ControllerSuite) TestACLGroupReconcilerFanOut() {
+	ctx := context.Background()
+	group := &v1alpha1.ACLGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backends",
+			Namespace: "default",
+			UID:       types.UID("group-uid"),
+		},
+		Spec: v1alpha1.ACLGroupSpec{
+			Sources: []v1alpha1.ACLSpecSource{
+				{TsuruApp: "app1"},
+				{TsuruJob: "job1"},
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "1.1.1.1/32"},
+				},
+			},
+		},
+	}
+
+	reconciler := &ACLGroupReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(group).Build(),
+		Scheme: scheme.Scheme,
+	}
+	reconcile := func() *v1alpha1.ACLGroup {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(group),
+		})
+		suite.Require().NoError(err)
+
+		existingGroup := &v1alpha1.ACLGroup{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(group), existingGroup)
+		suite.Require().NoError(err)
+		return existingGroup
+	}
+
+	existingGroup := reconcile()
+	suite.Assert().False(existingGroup.Status.Ready)
+	suite.Assert().Equal(0, existingGroup.Status.ReadyACLs)
+	suite.Assert().Equal(2, existingGroup.Status.TotalACLs)
+	suite.Assert().Equal("2 of 2 ACLs are not ready", existingGroup.Status.Reason)
+	suite.Assert().Equal([]v1alpha1.ACLGroupStatusACL{
+		{Name: "backends-app1", Source: "tsuruApp app1"},
+		{Name: "backends-job-job1", Source: "tsuruJob job1"},
+	}, existingGroup.Status.ACLs)
+
+	acl := &v1alpha1.ACL{}
+	err := reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "backends-app1"}, acl)
+	suite.Require().NoError(err)
+	suite.Assert().True(metav1.IsControlledBy(acl, group))
+	suite.Assert().Equal("backends", acl.Labels[aclGroupLabel])
+	suite.Assert().Equal(v1alpha1.ACLSpecSource{TsuruApp: "app1"}, acl.Spec.Source)
+	suite.Assert().Equal(group.Spec.Destinations, acl.Spec.Destinations)
+
+	// readiness of ACLs is aggregated on the group
+	acl.Status.Ready = true
+	err = reconciler.Client.Status().Update(ctx, acl)
+	suite.Require().NoError(err)
+
+	existingGroup = reconcile()
+	suite.Assert().Equal(1, existingGroup.Status.ReadyACLs)
+	suite.Assert().Equal("1 of 2 ACLs are not ready", existingGroup.Status.Reason)
+
+	// shared destinations are synced and ACLs of removed sources are deleted
+	existingGroup.Spec.Sources = existingGroup.Spec.Sources[:1]
+	existingGroup.Spec.Destinations = []v1alpha1.ACLSpecDestination{{TsuruApp: "otherapp"}}
+	err = reconciler.Client.Update(ctx, existingGroup)
+	suite.Require().NoError(err)
+
+	existingGroup = reconcile()
+	suite.Assert().True(existingGroup.Status.Ready)
+	suite.Assert().Empty(existingGroup.Status.Reason)
+	suite.Assert().Equal(1, existingGroup.Status.TotalACLs)
+
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "backends-app1"}, acl)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]v1alpha1.ACLSpecDestination{{TsuruApp: "otherapp"}}, acl.Spec.Destinations)
+
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "backends-job-job1"}, &v1alpha1.ACL{})
+	suite.Assert().True(k8sErrors.IsNotFound(err))
+}
+
+func (suite *ControllerSuite) TestACLGroupReconcilerConflict() {
+	ctx := context.Background()
+	group := &v1alpha1.ACLGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backends",
+			Namespace: "default",
+			UID:       types.UID("group-uid"),
+		},
+		Spec: v1alpha1.ACLGroupSpec{
+			Sources: []v1alpha1.ACLSpecSource{
+				{TsuruApp: "app1"},
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "1.1.1.1/32"},
+				},
+			},
+		},
+	}
+	existingACL := &v1alpha1.ACL{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backends-app1",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{TsuruApp: "app1"},
+		},
+	}
+
+	reconciler := &ACLGroupReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(group, existingACL).Build(),
+		Scheme: scheme.Scheme,
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(group),
+	})
+	suite.Require().NoError(err)
+
+	existingGroup := &v1alpha1.ACLGroup{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(group), existingGroup)
+	suite.Require().NoError(err)
+	suite.Assert().False(existingGroup.Status.Ready)
+	suite.Assert().Equal("ACL backends-app1 already exists and is not managed by ACLGroup backends, it is not overwritten", existingGroup.Status.Reason)
+
+	acl := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(existingACL), acl)
+	suite.Require().NoError(err)
+	suite.Assert().Empty(acl.Spec.Destinations)
+	suite.Assert().Empty(acl.OwnerReferences)
+}
+
+func (suite *ControllerSuite) TestACLGroupReconcilerInvalidSources() {
+	ctx := context.Background()
+	group := &v1alpha1.ACLGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backends",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLGroupSpec{
+			Sources: []v1alpha1.ACLSpecSource{
+				{TsuruApp: "app1"},
+				{TsuruApp: "app1"},
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{TsuruApp: "otherapp"},
+			},
+		},
+	}
+
+	reconciler := &ACLGroupReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(group).Build(),
+		Scheme: scheme.Scheme,
+	}
+	_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: client.ObjectKeyFromObject(group),
+	})
+	suite.Require().NoError(err)
+
+	existingGroup := &v1alpha1.ACLGroup{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(group), existingGroup)
+	suite.Require().NoError(err)
+	suite.Assert().False(existingGroup.Status.Ready)
+	suite.Assert().Equal("invalid spec.sources[1]: tsuruApp app1 is repeated", existingGroup.Status.Reason)
+
+	acls := &v1alpha1.ACLList{}
+	err = reconciler.Client.List(ctx, acls)
+	suite.Require().NoError(err)
+	suite.Assert().Empty(acls.Items)
+}
+
+func (suite *ControllerSuite) TestACLGroupReconcilerDefaultsAndValidatesACLs() {
+	ctx := context.Background()
+	group := &v1alpha1.ACLGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backends",
+			Namespace: "default",
+			UID:       types.UID("group-uid"),
+		},
+		Spec: v1alpha1.ACLGroupSpec{
+			Sources: []v1alpha1.ACLSpecSource{
+				{TsuruApp: "app1"},
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "1.1.1.1"},
+				},
+			},
+		},
+	}
+
+	reconciler := &ACLGroupReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(group).Build(),
+		Scheme: scheme.Scheme,
+	}
+	reconcile := func() *v1alpha1.ACLGroup {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(group),
+		})
+		suite.Require().NoError(err)
+
+		existingGroup := &v1alpha1.ACLGroup{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(group), existingGroup)
+		suite.Require().NoError(err)
+		return existingGroup
+	}
+
+	existingGroup := reconcile()
+	suite.Assert().Equal(1, existingGroup.Status.TotalACLs)
+
+	// the ACL is created as defaulted by the webhook, so it is not updated again
+	acl := &v1alpha1.ACL{}
+	err := reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "backends-app1"}, acl)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("1.1.1.1/32", acl.Spec.Destinations[0].ExternalIP.IP)
+
+	reconcile()
+	existingACL := &v1alpha1.ACL{}
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(acl.ResourceVersion, existingACL.ResourceVersion)
+
+	// an invalid spec is reported on the status of group and the existing ACLs are kept
+	existingGroup.Spec.Destinations = []v1alpha1.ACLSpecDestination{
+		{
+			ExternalDNS: &v1alpha1.ACLSpecExternalDNS{Name: "db"},
+		},
+	}
+	err = reconciler.Client.Update(ctx, existingGroup)
+	suite.Require().NoError(err)
+
+	existingGroup = reconcile()
+	suite.Assert().False(existingGroup.Status.Ready)
+	suite.Assert().Contains(existingGroup.Status.Reason, `ACL.extensions.tsuru.io "backends-app1" is invalid: spec.destinations[0]`)
+	suite.Assert().Contains(existingGroup.Status.Reason, `is not fully qualified`)
+
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(acl.Spec, existingACL.Spec)
+}
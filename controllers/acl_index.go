@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+// aclIndexes return the keys of a destination of an ACL of namespace on each index of ACLs, the indexes
+// find the ACLs that reference a changed address object, destinations without a key return empty strings
+var aclIndexes = map[string]func(namespace string, destination v1alpha1.ACLSpecDestination) []string{
+	externalDNSIndex: func(namespace string, destination v1alpha1.ACLSpecDestination) []string {
+		if destination.ExternalDNS == nil {
+			return nil
+		}
+		// ACLDNSEntry objects hold the hosts of externalDNSHost
+		return []string{externalDNSHost(destination.ExternalDNS)}
+	},
+	externalIPIndex: func(namespace string, destination v1alpha1.ACLSpecDestination) []string {
+		if destination.ExternalIP != nil {
+			return []string{canonicalCIDR(destination.ExternalIP.IP)}
+		}
+
+		// the IPs of externalEndpoints are keyed like the externalIP destinations they stand for
+		externalIPs, _ := destination.ExternalEndpointsIPs()
+		keys := make([]string, 0, len(externalIPs))
+		for _, externalIP := range externalIPs {
+			keys = append(keys, canonicalCIDR(externalIP.IP))
+		}
+		return keys
+	},
+	rpaasInstanceIndex: func(namespace string, destination v1alpha1.ACLSpecDestination) []string {
+		if destination.RpaasInstance == nil {
+			return nil
+		}
+		return []string{destination.RpaasInstance.ServiceName + "/" + destination.RpaasInstance.Instance}
+	},
+	tsuruServiceInstanceIndex: func(namespace string, destination v1alpha1.ACLSpecDestination) []string {
+		if destination.TsuruServiceInstance == nil {
+			return nil
+		}
+		return []string{destination.TsuruServiceInstance.ServiceName + "/" + destination.TsuruServiceInstance.Instance}
+	},
+	tsuruAppNameIndex: func(namespace string, destination v1alpha1.ACLSpecDestination) []string {
+		return []string{destination.TsuruApp}
+	},
+	tsuruAppPoolIndex: func(namespace string, destination v1alpha1.ACLSpecDestination) []string {
+		return []string{destination.TsuruAppPool}
+	},
+	configMapIndex: func(namespace string, destination v1alpha1.ACLSpecDestination) []string {
+		if destination.ConfigMap == nil {
+			return nil
+		}
+		return []string{configMapKey(destination.ConfigMap, namespace).String()}
+	},
+}
+
+// aclSourceIndexes return the keys of the source of an ACL of namespace on each index of ACLs, the
+// indexes find the ACLs of a changed workload, sources without a key return empty strings
+var aclSourceIndexes = map[string]func(namespace string, source v1alpha1.ACLSpecSource) []string{
+	workloadIndex: func(namespace string, source v1alpha1.ACLSpecSource) []string {
+		if source.Workload == nil {
+			return nil
+		}
+		return []string{workloadKey(namespace, source.Workload.Kind, source.Workload.Name)}
+	},
+}
+
+func aclIndexKeys(index string) client.IndexerFunc {
+	if keysForSource, ok := aclSourceIndexes[index]; ok {
+		return func(o client.Object) []string {
+			acl, ok := o.(*v1alpha1.ACL)
+			if !ok {
+				return nil
+			}
+
+			keys := []string{}
+			for _, key := range keysForSource(acl.Namespace, acl.Spec.Source) {
+				if key != "" {
+					keys = append(keys, key)
+				}
+			}
+			return keys
+		}
+	}
+
+	keysForDestination := aclIndexes[index]
+	return func(o client.Object) []string {
+		acl, ok := o.(*v1alpha1.ACL)
+		if !ok {
+			return nil
+		}
+
+		keys := []string{}
+		for _, destination := range acl.Spec.Destinations {
+			for _, key := range keysForDestination(acl.Namespace, destination) {
+				if key != "" {
+					keys = append(keys, key)
+				}
+			}
+		}
+
+		return keys
+	}
+}
+
+// canonicalCIDR keeps invalid addresses as they are, they never match a valid one
+func canonicalCIDR(address string) string {
+	if cidr, _, err := v1alpha1.ParseCIDR(address); err == nil {
+		return cidr
+	}
+	return address
+}
+
+// ACLsReferencing returns the ACLs with a destination to key, which is a host of externalDNS,
+// an IP or CIDR of externalIP, a tsuru app, a tsuru app pool, a rpaas instance or a tsuru service
+// instance as service/instance, or a ConfigMap as namespace/name
+func (r *ACLReconciler) ACLsReferencing(ctx context.Context, key string) ([]v1alpha1.ACL, error) {
+	indexes := make([]string, 0, len(aclIndexes))
+	for index := range aclIndexes {
+		indexes = append(indexes, index)
+	}
+	sort.Strings(indexes)
+
+	seen := map[client.ObjectKey]bool{}
+	acls := []v1alpha1.ACL{}
+	for _, index := range indexes {
+		value := key
+		switch index {
+		case externalDNSIndex:
+			value = strings.ToLower(key)
+		case externalIPIndex:
+			value = canonicalCIDR(key)
+		}
+
+		indexACLs, err := r.listACLsForIndex(ctx, index, value)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, acl := range indexACLs {
+			if !seen[client.ObjectKeyFromObject(&acl)] {
+				seen[client.ObjectKeyFromObject(&acl)] = true
+				acls = append(acls, acl)
+			}
+		}
+	}
+
+	sort.Slice(acls, func(i, j int) bool {
+		if acls[i].Namespace != acls[j].Namespace {
+			return acls[i].Namespace < acls[j].Namespace
+		}
+		return acls[i].Name < acls[j].Name
+	})
+
+	return acls, nil
+}
+
+func (r *ACLReconciler) listACLsForIndex(ctx context.Context, index, value string) ([]v1alpha1.ACL, error) {
+	return aclsForIndex(ctx, r.Client, index, value)
+}
+
+// aclsForIndex checks the keys of the listed ACLs again, the index only narrows the list
+// and readers that do not know the index may ignore the field selector
+func aclsForIndex(ctx context.Context, reader client.Reader, index, value string) ([]v1alpha1.ACL, error) {
+	list := &v1alpha1.ACLList{}
+	err := reader.List(ctx, list, client.MatchingFields{index: value})
+	if err != nil {
+		return nil, err
+	}
+
+	indexKeys := aclIndexKeys(index)
+	acls := []v1alpha1.ACL{}
+	for _, acl := range list.Items {
+		for _, key := range indexKeys(&acl) {
+			if key == value {
+				acls = append(acls, acl)
+				break
+			}
+		}
+	}
+
+	return acls, nil
+}
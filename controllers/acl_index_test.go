@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/tsuru/acl-operator/api/scheme"
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+func TestACLIndexKeys(t *testing.T) {
+	acl := &v1alpha1.ACL{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default"},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{Workload: &v1alpha1.ACLSpecWorkload{Kind: "StatefulSet", Name: "db"}},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{ExternalDNS: &v1alpha1.ACLSpecExternalDNS{Name: "Example.COM"}},
+				{ExternalDNS: &v1alpha1.ACLSpecExternalDNS{Name: "other.example.com"}},
+				{RpaasInstance: &v1alpha1.ACLSpecRpaasInstance{ServiceName: "rpaasv2", Instance: "my-instance"}},
+				{TsuruApp: "my-app"},
+				{TsuruAppPool: "my-pool"},
+				{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "1.1.1.1"}},
+				{ExternalEndpoints: []string{"10.0.0.1:443/tcp", "10.0.0.2:53/udp", "10.0.0.1:80/tcp"}},
+				{ConfigMap: &v1alpha1.ACLSpecConfigMap{Name: "egress-allowlist", Key: "addresses"}},
+				{ConfigMap: &v1alpha1.ACLSpecConfigMap{Namespace: "infra", Name: "egress-allowlist", Key: "addresses"}},
+			},
+		},
+	}
+
+	assert.Equal(t, []string{"example.com", "other.example.com"}, aclIndexKeys(externalDNSIndex)(acl))
+	assert.Equal(t, []string{"1.1.1.1/32", "10.0.0.1/32", "10.0.0.2/32"}, aclIndexKeys(externalIPIndex)(acl))
+	assert.Equal(t, []string{"rpaasv2/my-instance"}, aclIndexKeys(rpaasInstanceIndex)(acl))
+	assert.Equal(t, []string{"my-app"}, aclIndexKeys(tsuruAppNameIndex)(acl))
+	assert.Equal(t, []string{"my-pool"}, aclIndexKeys(tsuruAppPoolIndex)(acl))
+	assert.Equal(t, []string{"default/egress-allowlist", "default/egress-allowlist"}, aclIndexKeys(configMapIndex)(acl))
+	assert.Equal(t, []string{"default/StatefulSet/db"}, aclIndexKeys(workloadIndex)(acl))
+	assert.Empty(t, aclIndexKeys(workloadIndex)(&v1alpha1.ACL{}))
+	assert.Empty(t, aclIndexKeys(tsuruAppNameIndex)(&v1alpha1.ACL{}))
+	assert.Nil(t, aclIndexKeys(tsuruAppNameIndex)(&v1alpha1.ACLDNSEntry{}))
+}
+
+func TestACLsReferencing(t *testing.T) {
+	newACL := func(namespace, name string, destinations ...v1alpha1.ACLSpecDestination) *v1alpha1.ACL {
+		return &v1alpha1.ACL{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: v1alpha1.ACLSpec{
+				Source:       v1alpha1.ACLSpecSource{TsuruApp: name},
+				Destinations: destinations,
+			},
+		}
+	}
+
+	reconciler := &ACLReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(
+			newACL("tsuru", "app1",
+				v1alpha1.ACLSpecDestination{ExternalDNS: &v1alpha1.ACLSpecExternalDNS{Name: "example.com"}},
+				v1alpha1.ACLSpecDestination{TsuruApp: "app2"},
+			),
+			newACL("default", "app2",
+				v1alpha1.ACLSpecDestination{ExternalDNS: &v1alpha1.ACLSpecExternalDNS{Name: "Example.COM"}},
+				v1alpha1.ACLSpecDestination{ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "10.0.0.1/32"}},
+			),
+			newACL("tsuru", "app3",
+				v1alpha1.ACLSpecDestination{ExternalDNS: &v1alpha1.ACLSpecExternalDNS{Name: "other.example.com"}},
+				v1alpha1.ACLSpecDestination{RpaasInstance: &v1alpha1.ACLSpecRpaasInstance{ServiceName: "rpaasv2", Instance: "my-instance"}},
+			),
+		).Build(),
+		Scheme: scheme.Scheme,
+	}
+
+	referencing := func(key string) []string {
+		acls, err := reconciler.ACLsReferencing(context.Background(), key)
+		require.NoError(t, err)
+		names := []string{}
+		for _, acl := range acls {
+			names = append(names, acl.Namespace+"/"+acl.Name)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"default/app2", "tsuru/app1"}, referencing("example.com"))
+	assert.Equal(t, []string{"default/app2", "tsuru/app1"}, referencing("EXAMPLE.com"))
+	assert.Equal(t, []string{"tsuru/app3"}, referencing("other.example.com"))
+	assert.Equal(t, []string{"default/app2"}, referencing("10.0.0.1"))
+	assert.Equal(t, []string{"tsuru/app1"}, referencing("app2"))
+	assert.Equal(t, []string{"tsuru/app3"}, referencing("rpaasv2/my-instance"))
+	assert.Empty(t, referencing("app1"))
+	assert.Empty(t, referencing("10.0.0.2"))
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// aclPriorityAnnotation holds an integer, ACLs with a higher priority are reconciled before
+	// the ones with a lower priority, the ACLs without it have defaultACLPriority
+	aclPriorityAnnotation = "acl.extensions.tsuru.io/priority"
+
+	defaultACLPriority = 0
+
+	// aclPriorityRequeueDelay is the delay of an ACL that waits for ACLs with a higher priority
+	aclPriorityRequeueDelay = time.Second
+
+	// maxACLPriorityWait bounds the wait for an enqueued ACL with a higher priority, so an ACL
+	// that is never reconciled, like one deleted before its event, does not hold the others
+	maxACLPriorityWait = time.Minute
+)
+
+// aclPriority is the priority of the annotation of obj, values that are not integers and
+// negative values are defaultACLPriority
+func aclPriority(obj client.Object) int {
+	priority, err := strconv.Atoi(obj.GetAnnotations()[aclPriorityAnnotation])
+	if err != nil || priority < defaultACLPriority {
+		return defaultACLPriority
+	}
+	return priority
+}
+
+// aclPriorities tracks the enqueued ACLs with a priority above the default, the queue of
+// controller-runtime is FIFO, so ACLs with a lower priority are requeued while they are pending
+type aclPriorities struct {
+	mu      sync.Mutex
+	pending map[types.NamespacedName]pendingACLPriority
+}
+
+type pendingACLPriority struct {
+	priority int
+	since    time.Time
+}
+
+func (p *aclPriorities) enqueued(name types.NamespacedName, priority int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if priority <= defaultACLPriority {
+		delete(p.pending, name)
+		return
+	}
+
+	if p.pending == nil {
+		p.pending = map[types.NamespacedName]pendingACLPriority{}
+	}
+	if pending, ok := p.pending[name]; ok && pending.priority == priority {
+		return
+	}
+	p.pending[name] = pendingACLPriority{priority: priority, since: time.Now()}
+}
+
+// wait tells whether an ACL with a higher priority than priority is pending
+func (p *aclPriorities) wait(name types.NamespacedName, priority int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for pendingName, pending := range p.pending {
+		if time.Since(pending.since) > maxACLPriorityWait {
+			delete(p.pending, pendingName)
+			continue
+		}
+		if pendingName != name && pending.priority > priority {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *aclPriorities) done(name types.NamespacedName) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.pending, name)
+}
+
+// eventHandler records the ACLs with a priority before enqueuing them, the ACLs without it are
+// enqueued by the handler of For
+func (p *aclPriorities) eventHandler() handler.EventHandler {
+	enqueue := func(obj client.Object, q workqueue.RateLimitingInterface) {
+		priority := aclPriority(obj)
+		name := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+		p.enqueued(name, priority)
+		if priority > defaultACLPriority {
+			q.Add(reconcile.Request{NamespacedName: name})
+		}
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+			enqueue(e.Object, q)
+		},
+		UpdateFunc: func(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+			enqueue(e.ObjectNew, q)
+		},
+		DeleteFunc: func(e event.DeleteEvent, _ workqueue.RateLimitingInterface) {
+			p.done(types.NamespacedName{Namespace: e.Object.GetNamespace(), Name: e.Object.GetName()})
+		},
+		GenericFunc: func(e event.GenericEvent, q workqueue.RateLimitingInterface) {
+			enqueue(e.Object, q)
+		},
+	}
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+func TestACLPriority(t *testing.T) {
+	tests := map[string]int{
+		"":    defaultACLPriority,
+		"10":  10,
+		"-5":  defaultACLPriority,
+		"top": defaultACLPriority,
+	}
+
+	for value, expected := range tests {
+		acl := &v1alpha1.ACL{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{aclPriorityAnnotation: value}}}
+		assert.Equal(t, expected, aclPriority(acl), value)
+	}
+}
+
+func TestACLPrioritiesWait(t *testing.T) {
+	critical := types.NamespacedName{Namespace: "default", Name: "critical"}
+	important := types.NamespacedName{Namespace: "default", Name: "important"}
+	longTail := types.NamespacedName{Namespace: "default", Name: "long-tail"}
+
+	p := &aclPriorities{}
+	assert.False(t, p.wait(longTail, defaultACLPriority))
+
+	p.enqueued(critical, 10)
+	p.enqueued(important, 5)
+	p.enqueued(longTail, defaultACLPriority)
+	assert.True(t, p.wait(longTail, defaultACLPriority))
+	assert.True(t, p.wait(important, 5))
+	assert.False(t, p.wait(critical, 10))
+
+	p.done(critical)
+	assert.False(t, p.wait(important, 5))
+	assert.True(t, p.wait(longTail, defaultACLPriority))
+
+	// ACLs that are never reconciled stop holding the others
+	p.pending[important] = pendingACLPriority{priority: 5, since: time.Now().Add(-2 * maxACLPriorityWait)}
+	assert.False(t, p.wait(longTail, defaultACLPriority))
+	assert.Empty(t, p.pending)
+}
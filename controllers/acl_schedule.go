@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+// aclSchedule is the state of the windows of spec.schedule at the time of a reconcile
+type aclSchedule struct {
+	open bool
+	next time.Time
+	now  time.Time
+}
+
+// schedule evaluates spec.schedule of acl, it is nil for ACLs without schedule, which are always open
+func (r *ACLReconciler) schedule(acl *v1alpha1.ACL) (*aclSchedule, error) {
+	if acl.Spec.Schedule == nil {
+		return nil, nil
+	}
+
+	now := r.timeNow()
+	open, next, err := acl.Spec.Schedule.Open(now)
+	if err != nil {
+		return nil, err
+	}
+	return &aclSchedule{open: open, next: next, now: now}, nil
+}
+
+func (r *ACLReconciler) timeNow() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+// allowsEgress reports whether the egress of destinations is applied
+func (s *aclSchedule) allowsEgress() bool {
+	return s == nil || s.open
+}
+
+func (s *aclSchedule) status() *v1alpha1.ACLStatusSchedule {
+	if s == nil {
+		return nil
+	}
+	return &v1alpha1.ACLStatusSchedule{
+		Open:           s.open,
+		NextTransition: s.next.UTC().Format(time.RFC3339),
+	}
+}
+
+// requeue reconciles the ACL again at the next transition of windows when it comes before the
+// requeue of result, results of immediate requeues are kept as they are
+func (s *aclSchedule) requeue(result ctrl.Result) ctrl.Result {
+	if s == nil {
+		return result
+	}
+	return requeueAt(result, s.next, s.now)
+}
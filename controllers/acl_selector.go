@@ -0,0 +1,19 @@
+package controllers
+
+import (
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// selects tells whether the ACL obj belongs to the shard of the reconciler, every ACL
+// belongs to it without Selector
+func (r *ACLReconciler) selects(obj client.Object) bool {
+	return r.Selector == nil || r.Selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+// selectorPredicate drops the events of ACLs outside of the shard, an ACL whose labels stop
+// matching is left as it is, with its policy, for the shard it moved to
+func (r *ACLReconciler) selectorPredicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(r.selects)
+}
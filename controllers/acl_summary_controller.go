@@ -0,0 +1,279 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+const (
+	// DefaultSummaryInterval is used by ACLSummaryReconciler without an Interval
+	DefaultSummaryInterval = 30 * time.Second
+
+	// summaryResyncInterval counts the ACLs again without events, covering a missed one
+	summaryResyncInterval = 5 * time.Minute
+
+	defaultSummaryMaxFailing = 10
+
+	// summaryReasonNotReconciled is the reason of ACLs without Ready condition
+	summaryReasonNotReconciled = "NotReconciled"
+)
+
+// ACLSummaryReconciler counts the ACLs by state on the status of ACLSummary objects. Status changes
+// of ACLs enqueue the summaries, which are counted at most once per Interval, changes made
+// meanwhile are counted on the next count
+type ACLSummaryReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Interval is the minimum time between the counts of a summary, defaults to DefaultSummaryInterval
+	Interval time.Duration
+
+	now func() time.Time
+
+	mu      sync.Mutex
+	counted map[types.NamespacedName]summaryCount
+}
+
+// summaryCount is the last count of a summary, a new generation of spec is counted right away
+type summaryCount struct {
+	at         time.Time
+	generation int64
+}
+
+//+kubebuilder:rbac:groups=extensions.tsuru.io,resources=aclsummaries,verbs=get;list;watch
+//+kubebuilder:rbac:groups=extensions.tsuru.io,resources=aclsummaries/status,verbs=get;update;patch
+
+func (r *ACLSummaryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
+	l := log.FromContext(ctx)
+
+	summary := &v1alpha1.ACLSummary{}
+	outcome := reconcileResultNoop
+	defer func() {
+		observeReconcileResult("aclsummary", outcome, err)
+	}()
+
+	err = r.Client.Get(ctx, req.NamespacedName, summary)
+	if k8sErrors.IsNotFound(err) {
+		r.forget(req.NamespacedName)
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		l.Error(err, "could not get ACLSummary object")
+		return ctrl.Result{}, err
+	}
+
+	now := r.timeNow()
+	if wait := r.wait(summary, now); wait > 0 {
+		outcome = reconcileResultDeferred
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+
+	acls := &v1alpha1.ACLList{}
+	err = r.Client.List(ctx, acls, client.InNamespace(summary.Spec.Namespace))
+	if err != nil {
+		l.Error(err, "could not list ACLs")
+		return ctrl.Result{}, err
+	}
+
+	status := summarizeACLs(acls.Items, summaryMaxFailing(summary))
+	status.LastUpdated = summary.Status.LastUpdated
+	if !reflect.DeepEqual(summary.Status, status) {
+		status.LastUpdated = now.UTC().Format(time.RFC3339)
+		summary.Status = status
+		err = r.Client.Status().Update(ctx, summary)
+		if err != nil {
+			l.Error(err, "could not update status of ACLSummary object")
+			return ctrl.Result{}, err
+		}
+		outcome = reconcileResultUpdated
+	}
+	r.count(summary, now)
+
+	return ctrl.Result{RequeueAfter: summaryResyncInterval}, nil
+}
+
+// wait returns how long summary waits for its next count, zero when it is counted right away
+func (r *ACLSummaryReconciler) wait(summary *v1alpha1.ACLSummary, now time.Time) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	last, ok := r.counted[types.NamespacedName{Name: summary.Name}]
+	if !ok || last.generation != summary.Generation {
+		return 0
+	}
+
+	interval := r.Interval
+	if interval <= 0 {
+		interval = DefaultSummaryInterval
+	}
+	if elapsed := now.Sub(last.at); elapsed < interval {
+		return interval - elapsed
+	}
+	return 0
+}
+
+func (r *ACLSummaryReconciler) count(summary *v1alpha1.ACLSummary, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.counted == nil {
+		r.counted = map[types.NamespacedName]summaryCount{}
+	}
+	r.counted[types.NamespacedName{Name: summary.Name}] = summaryCount{at: now, generation: summary.Generation}
+}
+
+func (r *ACLSummaryReconciler) forget(name types.NamespacedName) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.counted, name)
+}
+
+func (r *ACLSummaryReconciler) timeNow() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+func summaryMaxFailing(summary *v1alpha1.ACLSummary) int {
+	if summary.Spec.MaxFailing == nil {
+		return defaultSummaryMaxFailing
+	}
+	return int(*summary.Spec.MaxFailing)
+}
+
+// summarizeACLs counts acls by state, the not ready ACLs failing for the longest time come first
+// on failing, ties are sorted by namespace and name
+func summarizeACLs(acls []v1alpha1.ACL, maxFailing int) v1alpha1.ACLSummaryStatus {
+	status := v1alpha1.ACLSummaryStatus{TotalACLs: len(acls)}
+	reasons := map[string]int{}
+	failing := []v1alpha1.ACLSummaryFailingACL{}
+
+	for i := range acls {
+		acl := &acls[i]
+		if meta.IsStatusConditionTrue(acl.Status.Conditions, v1alpha1.ACLConditionDegraded) {
+			status.DegradedACLs++
+		}
+
+		switch {
+		case isPaused(acl):
+			status.PausedACLs++
+		case acl.Status.Ready:
+			status.ReadyACLs++
+		default:
+			status.NotReadyACLs++
+
+			reason, since := summaryReasonNotReconciled, ""
+			if ready := meta.FindStatusCondition(acl.Status.Conditions, v1alpha1.ACLConditionReady); ready != nil {
+				reason = ready.Reason
+				since = ready.LastTransitionTime.UTC().Format(time.RFC3339)
+			}
+			reasons[reason]++
+			failing = append(failing, v1alpha1.ACLSummaryFailingACL{
+				Namespace: acl.Namespace,
+				Name:      acl.Name,
+				Reason:    acl.Status.Reason,
+				Since:     since,
+			})
+		}
+	}
+
+	for reason, count := range reasons {
+		status.Reasons = append(status.Reasons, v1alpha1.ACLSummaryReason{Reason: reason, Count: count})
+	}
+	sort.Slice(status.Reasons, func(i, j int) bool {
+		if status.Reasons[i].Count != status.Reasons[j].Count {
+			return status.Reasons[i].Count > status.Reasons[j].Count
+		}
+		return status.Reasons[i].Reason < status.Reasons[j].Reason
+	})
+
+	// RFC3339 times in UTC sort as strings, ACLs without condition were never reconciled and come last
+	sort.Slice(failing, func(i, j int) bool {
+		if failing[i].Since != failing[j].Since {
+			return failing[j].Since == "" || (failing[i].Since != "" && failing[i].Since < failing[j].Since)
+		}
+		if failing[i].Namespace != failing[j].Namespace {
+			return failing[i].Namespace < failing[j].Namespace
+		}
+		return failing[i].Name < failing[j].Name
+	})
+	if len(failing) > maxFailing {
+		failing = failing[:maxFailing]
+	}
+	if len(failing) > 0 {
+		status.Failing = failing
+	}
+
+	return status
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ACLSummaryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		// status updates of summaries are written by the reconciler itself and must not enqueue them
+		For(&v1alpha1.ACLSummary{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1, RecoverPanic: true}).
+		Watches(&source.Kind{Type: &v1alpha1.ACL{}},
+			handler.EnqueueRequestsFromMapFunc(r.summariesForACL),
+			builder.WithPredicates(aclStateChangedPredicate())).
+		Complete(r)
+}
+
+// summariesForACL enqueues the summaries counting the ACL
+func (r *ACLSummaryReconciler) summariesForACL(o client.Object) []reconcile.Request {
+	summaries := &v1alpha1.ACLSummaryList{}
+	err := r.Client.List(context.Background(), summaries)
+	if err != nil {
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, summary := range summaries.Items {
+		if summary.Spec.Namespace == "" || summary.Spec.Namespace == o.GetNamespace() {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: summary.Name}})
+		}
+	}
+	return requests
+}
+
+// aclStateChangedPredicate filters the updates of ACLs that do not change their state, like the
+// updates of the rules on status done on every reconcile
+func aclStateChangedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldACL, ok := e.ObjectOld.(*v1alpha1.ACL)
+			if !ok {
+				return true
+			}
+			newACL, ok := e.ObjectNew.(*v1alpha1.ACL)
+			if !ok {
+				return true
+			}
+			return oldACL.Status.Ready != newACL.Status.Ready ||
+				oldACL.Status.Reason != newACL.Status.Reason ||
+				isPaused(oldACL) != isPaused(newACL) ||
+				!reflect.DeepEqual(oldACL.Status.Conditions, newACL.Status.Conditions)
+		},
+	}
+}
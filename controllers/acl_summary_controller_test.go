@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/tsuru/acl-operator/api/scheme"
+	v1alpha1 "github.com/tsuru/acl-operator/api/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func (suite *ControllerSuite) TestACLSummaryReconciler() {
+	ctx := context.Background()
+	longAgo := metav1.NewTime(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+	recently := metav1.NewTime(time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC))
+
+	newACL := func(namespace, name string, ready bool, conditions ...metav1.Condition) *v1alpha1.ACL {
+		return &v1alpha1.ACL{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Status: v1alpha1.ACLStatus{
+				Ready:      ready,
+				Conditions: conditions,
+			},
+		}
+	}
+	degraded := newACL("default", "degraded", false,
+		metav1.Condition{Type: v1alpha1.ACLConditionReady, Status: metav1.ConditionFalse, Reason: conditionReasonRuleErrors, LastTransitionTime: recently},
+		metav1.Condition{Type: v1alpha1.ACLConditionDegraded, Status: metav1.ConditionTrue, Reason: conditionReasonRuleErrors, LastTransitionTime: recently},
+	)
+	degraded.Status.Reason = "some destinations could not be resolved"
+	invalid := newACL("team", "invalid", false,
+		metav1.Condition{Type: v1alpha1.ACLConditionReady, Status: metav1.ConditionFalse, Reason: eventReasonInvalidSource, LastTransitionTime: longAgo},
+	)
+	invalid.Status.Reason = "No podSelector generated by spec.source"
+	paused := newACL("default", "paused", true)
+	paused.Annotations = map[string]string{pausedAnnotation: "true"}
+	maxFailing := int32(2)
+	summary := &v1alpha1.ACLSummary{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+		Spec:       v1alpha1.ACLSummarySpec{MaxFailing: &maxFailing},
+	}
+	teamSummary := &v1alpha1.ACLSummary{
+		ObjectMeta: metav1.ObjectMeta{Name: "team"},
+		Spec:       v1alpha1.ACLSummarySpec{Namespace: "team"},
+	}
+
+	now := time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)
+	reconciler := &ACLSummaryReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(
+			summary, teamSummary, degraded, invalid, paused,
+			newACL("default", "ready", true),
+			newACL("default", "new", false),
+		).Build(),
+		Scheme: scheme.Scheme,
+		now:    func() time.Time { return now },
+	}
+	count := func(summary *v1alpha1.ACLSummary) (controllerruntime.Result, *v1alpha1.ACLSummary) {
+		result, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(summary),
+		})
+		suite.Require().NoError(err)
+
+		existingSummary := &v1alpha1.ACLSummary{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(summary), existingSummary)
+		suite.Require().NoError(err)
+		return result, existingSummary
+	}
+
+	result, existingSummary := count(summary)
+	suite.Assert().Equal(summaryResyncInterval, result.RequeueAfter)
+	suite.Assert().Equal(v1alpha1.ACLSummaryStatus{
+		TotalACLs:    5,
+		ReadyACLs:    1,
+		NotReadyACLs: 3,
+		PausedACLs:   1,
+		DegradedACLs: 1,
+		Reasons: []v1alpha1.ACLSummaryReason{
+			{Reason: eventReasonInvalidSource, Count: 1},
+			{Reason: summaryReasonNotReconciled, Count: 1},
+			{Reason: conditionReasonRuleErrors, Count: 1},
+		},
+		Failing: []v1alpha1.ACLSummaryFailingACL{
+			{Namespace: "team", Name: "invalid", Reason: "No podSelector generated by spec.source", Since: "2026-10-01T00:00:00Z"},
+			{Namespace: "default", Name: "degraded", Reason: "some destinations could not be resolved", Since: "2026-10-12T00:00:00Z"},
+		},
+		LastUpdated: "2026-10-12T10:00:00Z",
+	}, existingSummary.Status)
+
+	_, existingTeamSummary := count(teamSummary)
+	suite.Assert().Equal(1, existingTeamSummary.Status.TotalACLs)
+	suite.Assert().Equal(1, existingTeamSummary.Status.NotReadyACLs)
+
+	// changes are counted once the interval since the last count is over
+	err := reconciler.Client.Delete(ctx, invalid)
+	suite.Require().NoError(err)
+	now = now.Add(10 * time.Second)
+	result, existingSummary = count(summary)
+	suite.Assert().Equal(DefaultSummaryInterval-10*time.Second, result.RequeueAfter)
+	suite.Assert().Equal(5, existingSummary.Status.TotalACLs)
+
+	now = now.Add(DefaultSummaryInterval)
+	result, existingSummary = count(summary)
+	suite.Assert().Equal(summaryResyncInterval, result.RequeueAfter)
+	suite.Assert().Equal(4, existingSummary.Status.TotalACLs)
+	suite.Assert().Equal("2026-10-12T10:00:40Z", existingSummary.Status.LastUpdated)
+
+	// unchanged counts are not written again
+	now = now.Add(DefaultSummaryInterval)
+	_, existingSummary = count(summary)
+	suite.Assert().Equal("2026-10-12T10:00:40Z", existingSummary.Status.LastUpdated)
+
+	// a new spec is counted right away
+	existingSummary.Spec.Namespace = "team"
+	existingSummary.Generation++
+	err = reconciler.Client.Update(ctx, existingSummary)
+	suite.Require().NoError(err)
+	now = now.Add(time.Second)
+	_, existingSummary = count(summary)
+	suite.Assert().Equal(0, existingSummary.Status.TotalACLs)
+
+	suite.Assert().Equal([]reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: "cluster"}},
+		{NamespacedName: types.NamespacedName{Name: "team"}},
+	}, reconciler.summariesForACL(invalid))
+	suite.Assert().Empty(reconciler.summariesForACL(paused))
+}
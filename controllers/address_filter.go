@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"net"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+// filterAddresses splits the resolved addresses in the ones permitted by filter and the ones
+// it rejects, every address is permitted by a nil filter
+func filterAddresses(addresses []string, filter *v1alpha1.ACLSpecAddressFilter) (permitted []string, rejected []string, err error) {
+	if filter == nil {
+		return addresses, nil, nil
+	}
+
+	allow, err := parseNetworks(filter.Allow)
+	if err != nil {
+		return nil, nil, err
+	}
+	deny, err := parseNetworks(filter.Deny)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, address := range addresses {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			continue
+		}
+
+		if (len(allow) > 0 && !containsIP(allow, ip)) || containsIP(deny, ip) {
+			rejected = append(rejected, address)
+			continue
+		}
+		permitted = append(permitted, address)
+	}
+
+	return permitted, rejected, nil
+}
+
+func parseNetworks(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := v1alpha1.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// containsIP compares IPv4 addresses with IPv4 networks only, like the CIDRs of ipToCIDR,
+// so ::ffff:10.0.0.1 is contained in 10.0.0.0/8
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	if ipv4 := ip.To4(); ipv4 != nil {
+		ip = ipv4
+	}
+
+	for _, network := range networks {
+		if len(network.IP) == len(ip) && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
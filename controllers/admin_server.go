@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+// refreshQueueSize bounds the refreshes waiting for their controller, a full queue rejects
+// new refreshes instead of blocking the request
+const refreshQueueSize = 64
+
+var errRefreshQueueFull = errors.New("too many pending refreshes, try again later")
+
+// RefreshTrigger enqueues reconciles on demand, out of the periodic requeues, its channels
+// are watched by the controllers of ACL and of the address objects
+type RefreshTrigger struct {
+	dnsEntries               chan event.GenericEvent
+	acls                     chan event.GenericEvent
+	tsuruAppAddresses        chan event.GenericEvent
+	rpaasInstanceAddresses   chan event.GenericEvent
+	serviceInstanceAddresses chan event.GenericEvent
+
+	// uncachedDNSEntries are the entries refreshed on demand, their next lookup skips the DNS cache
+	mu                 sync.Mutex
+	uncachedDNSEntries map[string]bool
+}
+
+func NewRefreshTrigger() *RefreshTrigger {
+	return &RefreshTrigger{
+		dnsEntries:               make(chan event.GenericEvent, refreshQueueSize),
+		acls:                     make(chan event.GenericEvent, refreshQueueSize),
+		tsuruAppAddresses:        make(chan event.GenericEvent, refreshQueueSize),
+		rpaasInstanceAddresses:   make(chan event.GenericEvent, refreshQueueSize),
+		serviceInstanceAddresses: make(chan event.GenericEvent, refreshQueueSize),
+	}
+}
+
+// RefreshDNSEntry resolves the host of dnsEntry again without the answer kept by the DNS cache,
+// which would be returned until its TTL expires
+func (t *RefreshTrigger) RefreshDNSEntry(dnsEntry *v1alpha1.ACLDNSEntry) error {
+	t.mu.Lock()
+	if t.uncachedDNSEntries == nil {
+		t.uncachedDNSEntries = map[string]bool{}
+	}
+	t.uncachedDNSEntries[dnsEntry.Name] = true
+	t.mu.Unlock()
+
+	return sendRefresh(t.dnsEntries, dnsEntry)
+}
+
+// takeUncachedDNSEntry reports whether the entry of name was refreshed on demand since its last
+// reconcile, the mark is cleared
+func (t *RefreshTrigger) takeUncachedDNSEntry(name string) bool {
+	if t == nil {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	uncached := t.uncachedDNSEntries[name]
+	delete(t.uncachedDNSEntries, name)
+	return uncached
+}
+
+func (t *RefreshTrigger) RefreshACL(acl *v1alpha1.ACL) error {
+	return sendRefresh(t.acls, acl)
+}
+
+func sendRefresh(events chan<- event.GenericEvent, obj client.Object) error {
+	select {
+	case events <- event.GenericEvent{Object: obj}:
+		return nil
+	default:
+		return errRefreshQueueFull
+	}
+}
+
+// AdminServer serves the endpoints used by operators during incidents on a listener apart
+// from metrics and probes, every request requires the bearer token
+type AdminServer struct {
+	Client  client.Reader
+	Refresh *RefreshTrigger
+	Addr    string
+	Token   string
+	Logger  logr.Logger
+}
+
+type refreshResponse struct {
+	DNSEntry string   `json:"dnsEntry"`
+	ACLs     []string `json:"acls,omitempty"`
+}
+
+func (s *AdminServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/refresh/dnsentry/", s.refreshDNSEntry)
+	mux.HandleFunc("/explain/egress", s.explainEgress)
+	return s.authenticate(mux)
+}
+
+func (s *AdminServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		authorization := req.Header.Get("Authorization")
+		token := strings.TrimPrefix(authorization, "Bearer ")
+		if token == authorization || s.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// refreshDNSEntry resolves the host of an ACLDNSEntry right away, the ACLs referencing it are
+// reconciled when the addresses change, or right away as well with ?acls=true
+func (s *AdminServer) refreshDNSEntry(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(req.URL.Path, "/refresh/dnsentry/")
+	if name == "" || strings.Contains(name, "/") {
+		http.Error(w, "use /refresh/dnsentry/{name}", http.StatusNotFound)
+		return
+	}
+
+	withACLs := false
+	if value := req.URL.Query().Get("acls"); value != "" {
+		var err error
+		withACLs, err = strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid acls %q, use true or false", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx := req.Context()
+	dnsEntry := &v1alpha1.ACLDNSEntry{}
+	err := s.Client.Get(ctx, client.ObjectKey{Name: name}, dnsEntry)
+	if k8sErrors.IsNotFound(err) {
+		http.Error(w, fmt.Sprintf("ACLDNSEntry %s not found", name), http.StatusNotFound)
+		return
+	} else if err != nil {
+		s.Logger.Error(err, "could not get ACLDNSEntry", "name", name)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := refreshResponse{DNSEntry: name}
+	var acls []v1alpha1.ACL
+	if withACLs {
+		acls, err = s.aclsForDNSEntry(ctx, dnsEntry)
+		if err != nil {
+			s.Logger.Error(err, "could not list ACLs", "name", name)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = s.Refresh.RefreshDNSEntry(dnsEntry)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	for i := range acls {
+		err = s.Refresh.RefreshACL(&acls[i])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		response.ACLs = append(response.ACLs, acls[i].Namespace+"/"+acls[i].Name)
+	}
+
+	s.Logger.Info("refresh of ACLDNSEntry requested", "name", name, "acls", response.ACLs)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
+
+// explainEgress tells whether the policies of ACLs allow a connection of the pods of a source and
+// which rules allow it, e.g. /explain/egress?tsuruApp=myapp&ip=10.1.1.1&port=443&protocol=TCP
+func (s *AdminServer) explainEgress(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := req.URL.Query()
+	connection, err := parseEgressConnection(query.Get("tsuruApp"), query.Get("rpaasInstance"), query.Get("ip"), query.Get("port"), query.Get("protocol"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	explanation, err := explainEgress(req.Context(), s.Client, connection)
+	if err != nil {
+		s.Logger.Error(err, "could not explain egress", "connection", connection.String())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(explanation)
+}
+
+// aclsForDNSEntry returns the same ACLs enqueued by the watch of ACLDNSEntry
+func (s *AdminServer) aclsForDNSEntry(ctx context.Context, dnsEntry *v1alpha1.ACLDNSEntry) ([]v1alpha1.ACL, error) {
+	acls, err := aclsForIndex(ctx, s.Client, externalDNSIndex, dnsEntry.Spec.Host)
+	if err != nil || dnsEntry.Spec.Namespace == "" {
+		return acls, err
+	}
+
+	n := 0
+	for _, acl := range acls {
+		if acl.Namespace == dnsEntry.Spec.Namespace {
+			acls[n] = acl
+			n++
+		}
+	}
+	return acls[:n], nil
+}
+
+// Start implements manager.Runnable, the server stops with the manager
+func (s *AdminServer) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	s.Logger.Info("starting admin server", "address", listener.Addr().String())
+	err = server.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, only the leader runs the
+// controllers that consume the refreshes
+func (s *AdminServer) NeedLeaderElection() bool {
+	return true
+}
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/tsuru/acl-operator/api/scheme"
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+func TestAdminServerRefreshDNSEntry(t *testing.T) {
+	newACL := func(namespace, name, host string) *v1alpha1.ACL {
+		return &v1alpha1.ACL{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: v1alpha1.ACLSpec{
+				Source: v1alpha1.ACLSpecSource{TsuruApp: name},
+				Destinations: []v1alpha1.ACLSpecDestination{
+					{ExternalDNS: &v1alpha1.ACLSpecExternalDNS{Name: host}},
+				},
+			},
+		}
+	}
+
+	server := &AdminServer{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(
+			&v1alpha1.ACLDNSEntry{
+				ObjectMeta: metav1.ObjectMeta{Name: "example.com"},
+				Spec:       v1alpha1.ACLDNSEntrySpec{Host: "example.com"},
+			},
+			newACL("tsuru", "app1", "example.com"),
+			newACL("default", "app2", "Example.COM"),
+			newACL("default", "app3", "other.example.com"),
+		).Build(),
+		Refresh: NewRefreshTrigger(),
+		Token:   "secret",
+		Logger:  logr.Discard(),
+	}
+	handler := server.Handler()
+
+	request := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodPost, "/refresh/dnsentry/example.com", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodPost, "/refresh/dnsentry/example.com", "wrong").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, request(http.MethodGet, "/refresh/dnsentry/example.com", "secret").Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodPost, "/refresh/dnsentry/missing.com", "secret").Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/refresh/dnsentry/example.com?acls=maybe", "secret").Code)
+	assert.Empty(t, server.Refresh.dnsEntries)
+
+	recorder := request(http.MethodPost, "/refresh/dnsentry/example.com", "secret")
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+	assert.JSONEq(t, `{"dnsEntry": "example.com"}`, recorder.Body.String())
+	require.Len(t, server.Refresh.dnsEntries, 1)
+	assert.Equal(t, "example.com", (<-server.Refresh.dnsEntries).Object.GetName())
+	assert.Empty(t, server.Refresh.acls)
+
+	recorder = request(http.MethodPost, "/refresh/dnsentry/example.com?acls=true", "secret")
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+	assert.JSONEq(t, `{"dnsEntry": "example.com", "acls": ["default/app2", "tsuru/app1"]}`, recorder.Body.String())
+	require.Len(t, server.Refresh.dnsEntries, 1)
+	require.Len(t, server.Refresh.acls, 2)
+}
+
+func TestRefreshTriggerQueueFull(t *testing.T) {
+	trigger := &RefreshTrigger{
+		dnsEntries: make(chan event.GenericEvent, 1),
+	}
+
+	dnsEntry := &v1alpha1.ACLDNSEntry{ObjectMeta: metav1.ObjectMeta{Name: "example.com"}}
+	require.NoError(t, trigger.RefreshDNSEntry(dnsEntry))
+	assert.Equal(t, errRefreshQueueFull, trigger.RefreshDNSEntry(dnsEntry))
+}
+
+func TestAdminServerExplainEgress(t *testing.T) {
+	tcp, udp := corev1.ProtocolTCP, corev1.ProtocolUDP
+	https, dns := intstr.FromInt(443), intstr.FromInt(53)
+
+	server := &AdminServer{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(
+			&v1alpha1.ACL{
+				ObjectMeta: metav1.ObjectMeta{Name: "myapp", Namespace: "default"},
+				Spec: v1alpha1.ACLSpec{
+					Source: v1alpha1.ACLSpecSource{TsuruApp: "myapp"},
+				},
+				Status: v1alpha1.ACLStatus{
+					NetworkPolicy: "acl-myapp",
+					ResolvedDestinations: []v1alpha1.ACLStatusResolvedDestination{
+						{Index: 0, Destination: "externalDNS example.com", CIDRs: []string{"1.1.1.1/32"}},
+					},
+				},
+			},
+			&netv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "acl-myapp", Namespace: "default"},
+				Spec: netv1.NetworkPolicySpec{
+					Egress: []netv1.NetworkPolicyEgressRule{
+						{
+							To:    []netv1.NetworkPolicyPeer{{IPBlock: &netv1.IPBlock{CIDR: "1.1.1.1/32"}}},
+							Ports: []netv1.NetworkPolicyPort{{Protocol: &tcp, Port: &https}},
+						},
+						{
+							To: []netv1.NetworkPolicyPeer{{IPBlock: &netv1.IPBlock{CIDR: "10.0.0.0/8", Except: []string{"10.1.0.0/16"}}}},
+						},
+						{
+							To:    []netv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"k8s-app": "kube-dns"}}}},
+							Ports: []netv1.NetworkPolicyPort{{Protocol: &udp, Port: &dns}},
+						},
+					},
+				},
+			},
+		).Build(),
+		Refresh: NewRefreshTrigger(),
+		Token:   "secret",
+		Logger:  logr.Discard(),
+	}
+	handler := server.Handler()
+
+	explain := func(query string) (int, *egressExplanation) {
+		req := httptest.NewRequest(http.MethodGet, "/explain/egress?"+query, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusOK {
+			return recorder.Code, nil
+		}
+
+		explanation := &egressExplanation{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), explanation))
+		return recorder.Code, explanation
+	}
+
+	_, explanation := explain("tsuruApp=myapp&ip=1.1.1.1&port=443")
+	assert.True(t, explanation.Allowed)
+	assert.Equal(t, []string{"default/myapp"}, explanation.ACLs)
+	assert.Equal(t, []egressMatch{{
+		ACL:           "default/myapp",
+		NetworkPolicy: "default/acl-myapp",
+		Rule:          0,
+		Peer:          "1.1.1.1/32",
+		Ports:         "TCP/443",
+		Destinations:  []string{"externalDNS example.com"},
+	}}, explanation.Matches)
+	assert.Empty(t, explanation.Notes)
+
+	_, explanation = explain("tsuruApp=myapp&ip=1.1.1.1&port=80")
+	assert.False(t, explanation.Allowed)
+	assert.Equal(t, "no egress rule of the policies of ACLs allows TCP to 1.1.1.1:80", explanation.Reason)
+
+	_, explanation = explain("tsuruApp=myapp&ip=10.1.2.3&port=80&protocol=udp")
+	assert.False(t, explanation.Allowed)
+
+	_, explanation = explain("tsuruApp=myapp&ip=10.2.0.1&port=53&protocol=udp")
+	assert.True(t, explanation.Allowed)
+	require.Len(t, explanation.Matches, 1)
+	assert.Equal(t, 1, explanation.Matches[0].Rule)
+	assert.Equal(t, "10.0.0.0/8 except 10.1.0.0/16", explanation.Matches[0].Peer)
+	assert.Empty(t, explanation.Matches[0].Destinations)
+	assert.Equal(t, []string{"NetworkPolicy default/acl-myapp: rule 2 allows pods k8s-app=kube-dns, pod addresses are not evaluated"}, explanation.Notes)
+
+	_, explanation = explain("tsuruApp=other-app&ip=1.1.1.1&port=80")
+	assert.True(t, explanation.Allowed)
+	assert.Empty(t, explanation.ACLs)
+
+	code, _ := explain("ip=1.1.1.1&port=80")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = explain("tsuruApp=myapp&ip=1.1.1.1&port=http")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = explain("rpaasInstance=rpaasv2&ip=1.1.1.1&port=80")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
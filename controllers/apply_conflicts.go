@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// applyConflictRequeueInterval is used when a policy changed while it was applied, the next
+	// reconcile applies it on top of the new version
+	applyConflictRequeueInterval = time.Second
+
+	// maxApplyConflicts is the number of consecutive conflicts on the policy of an ACL after which
+	// the ACL is unready, fewer conflicts are benign races with other writers of the policy
+	maxApplyConflicts = 3
+)
+
+// applyConflicts counts the consecutive conflicts while applying the policy of each ACL
+type applyConflicts struct {
+	mu        sync.Mutex
+	conflicts map[types.NamespacedName]int
+}
+
+// add records a conflict on the policy of name, returns the consecutive conflicts
+func (c *applyConflicts) add(name types.NamespacedName) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conflicts == nil {
+		c.conflicts = map[types.NamespacedName]int{}
+	}
+	c.conflicts[name]++
+	return c.conflicts[name]
+}
+
+func (c *applyConflicts) reset(name types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.conflicts, name)
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/tsuru/acl-operator/clients/tsuruapi"
+)
+
+const (
+	defaultBackoffBase = 5 * time.Second
+	defaultBackoffMax  = 5 * time.Minute
+
+	// backoffJitterFactor spreads the retries of objects that failed at the same time
+	backoffJitterFactor = 0.5
+)
+
+// requeueBackoff counts the consecutive transient failures of each object to compute an exponential requeue
+type requeueBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+
+	mu       sync.Mutex
+	failures map[string]int
+}
+
+// Next registers a failure of key and returns the interval to retry it
+func (b *requeueBackoff) Next(key string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures == nil {
+		b.failures = map[string]int{}
+	}
+
+	base := b.Base
+	if base <= 0 {
+		base = defaultBackoffBase
+	}
+	max := b.Max
+	if max <= 0 {
+		max = defaultBackoffMax
+	}
+
+	d := base
+	for i := 0; i < b.failures[key] && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+
+	b.failures[key]++
+
+	return wait.Jitter(d, backoffJitterFactor)
+}
+
+// Reset forgets the failures of key
+func (b *requeueBackoff) Reset(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.failures, key)
+}
+
+// isTransientError reports whether an error of the Tsuru API or of the resolution may
+// succeed on a retry, missing apps and client errors of the API are permanent
+func isTransientError(err error) bool {
+	if errors.Is(err, errAppNotFound) || errors.Is(err, errInstanceNotFound) {
+		return false
+	}
+
+	var statusErr *tsuruapi.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Transient()
+	}
+
+	return true
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"net/netip"
+	"sort"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+// aggregateCIDRs returns the smallest list of CIDRs that covers addresses, addresses are
+// widened to the prefix lengths of aggregation when they are set, otherwise the CIDRs
+// cover exactly the addresses. Invalid addresses are ignored
+func aggregateCIDRs(addresses []string, aggregation *v1alpha1.ACLSpecCIDRAggregation) []string {
+	prefixes := make([]netip.Prefix, 0, len(addresses))
+	for _, address := range addresses {
+		addr, err := netip.ParseAddr(address)
+		if err != nil {
+			continue
+		}
+		addr = addr.Unmap()
+
+		bits := addr.BitLen()
+		if addr.Is4() && aggregation.IPv4PrefixLength != nil {
+			bits = int(*aggregation.IPv4PrefixLength)
+		} else if addr.Is6() && aggregation.IPv6PrefixLength != nil {
+			bits = int(*aggregation.IPv6PrefixLength)
+		}
+
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		prefixes = append(prefixes, prefix)
+	}
+
+	// IPv4 goes first, shorter prefixes go before the prefixes they contain
+	sort.Slice(prefixes, func(i, j int) bool {
+		if c := prefixes[i].Addr().Compare(prefixes[j].Addr()); c != 0 {
+			return c < 0
+		}
+		return prefixes[i].Bits() < prefixes[j].Bits()
+	})
+
+	merged := []netip.Prefix{}
+	for _, prefix := range prefixes {
+		if len(merged) > 0 && merged[len(merged)-1].Overlaps(prefix) {
+			// sorted prefixes only overlap when the last one contains prefix
+			continue
+		}
+		merged = append(merged, prefix)
+
+		// siblings are replaced by their parent, the parent may have a sibling on the previous position
+		for len(merged) >= 2 {
+			last, previous := merged[len(merged)-1], merged[len(merged)-2]
+			if last.Bits() != previous.Bits() || last.Bits() == 0 || last.Addr().Is4() != previous.Addr().Is4() {
+				break
+			}
+
+			parent := netip.PrefixFrom(previous.Addr(), previous.Bits()-1).Masked()
+			if parent != netip.PrefixFrom(last.Addr(), last.Bits()-1).Masked() {
+				break
+			}
+
+			merged = append(merged[:len(merged)-2], parent)
+		}
+	}
+
+	cidrs := make([]string, len(merged))
+	for i, prefix := range merged {
+		cidrs[i] = prefix.String()
+	}
+
+	return cidrs
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+func TestAggregateCIDRs(t *testing.T) {
+	prefixLength := func(bits int32) *int32 {
+		return &bits
+	}
+
+	tests := []struct {
+		name        string
+		addresses   []string
+		aggregation v1alpha1.ACLSpecCIDRAggregation
+		expected    []string
+	}{
+		{
+			name:      "contiguous",
+			addresses: []string{"10.0.0.3", "10.0.0.0", "10.0.0.2", "10.0.0.1"},
+			expected:  []string{"10.0.0.0/30"},
+		},
+		{
+			name:      "contiguous not aligned",
+			addresses: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"},
+			expected:  []string{"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/32"},
+		},
+		{
+			name:      "non-contiguous",
+			addresses: []string{"10.0.0.1", "10.0.0.3", "192.168.0.10", "10.0.0.5"},
+			expected:  []string{"10.0.0.1/32", "10.0.0.3/32", "10.0.0.5/32", "192.168.0.10/32"},
+		},
+		{
+			name:      "duplicated and mapped",
+			addresses: []string{"10.0.0.0", "::ffff:10.0.0.1", "10.0.0.1", "invalid"},
+			expected:  []string{"10.0.0.0/31"},
+		},
+		{
+			name:      "ipv6",
+			addresses: []string{"2001:db8::3", "2001:db8::2", "10.0.0.1", "2001:db8::4"},
+			expected:  []string{"10.0.0.1/32", "2001:db8::2/127", "2001:db8::4/128"},
+		},
+		{
+			name:      "widened",
+			addresses: []string{"10.0.0.1", "10.0.1.200", "10.0.3.1", "2001:db8::1", "2001:db8:0:1::1"},
+			aggregation: v1alpha1.ACLSpecCIDRAggregation{
+				IPv4PrefixLength: prefixLength(24),
+				IPv6PrefixLength: prefixLength(64),
+			},
+			expected: []string{"10.0.0.0/23", "10.0.3.0/24", "2001:db8::/63"},
+		},
+		{
+			name:     "empty",
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.aggregation.Enabled = true
+			assert.Equal(t, tt.expected, aggregateCIDRs(tt.addresses, &tt.aggregation))
+		})
+	}
+}
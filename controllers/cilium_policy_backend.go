@@ -0,0 +1,446 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	netv1 "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1alpha1 "github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+//+kubebuilder:rbac:groups=cilium.io,resources=ciliumnetworkpolicies,verbs=get;list;watch;create;update;patch;delete
+
+var ciliumNetworkPolicyGVK = schema.GroupVersionKind{
+	Group:   "cilium.io",
+	Version: "v2",
+	Kind:    "CiliumNetworkPolicy",
+}
+
+// ciliumNamespaceLabelPrefix is how cilium exposes the labels of namespace on endpoints
+const ciliumNamespaceLabelPrefix = "io.cilium.k8s.namespace.labels."
+
+// cilium types are declared here with only the fields used by operator,
+// the policy is written as unstructured to avoid a dependency on cilium
+type ciliumNetworkPolicySpec struct {
+	EndpointSelector ciliumSelector      `json:"endpointSelector"`
+	Egress           []ciliumEgressRule  `json:"egress,omitempty"`
+	Ingress          []ciliumIngressRule `json:"ingress,omitempty"`
+}
+
+type ciliumSelector struct {
+	MatchLabels      map[string]string                 `json:"matchLabels,omitempty"`
+	MatchExpressions []metav1.LabelSelectorRequirement `json:"matchExpressions,omitempty"`
+}
+
+type ciliumEgressRule struct {
+	ToEndpoints []ciliumSelector     `json:"toEndpoints,omitempty"`
+	ToCIDRSet   []ciliumCIDRRule     `json:"toCIDRSet,omitempty"`
+	ToFQDNs     []ciliumFQDNSelector `json:"toFQDNs,omitempty"`
+	ToEntities  []string             `json:"toEntities,omitempty"`
+	ToPorts     []ciliumPortRule     `json:"toPorts,omitempty"`
+}
+
+type ciliumIngressRule struct {
+	FromEndpoints []ciliumSelector `json:"fromEndpoints,omitempty"`
+	FromCIDRSet   []ciliumCIDRRule `json:"fromCIDRSet,omitempty"`
+	FromEntities  []string         `json:"fromEntities,omitempty"`
+	ToPorts       []ciliumPortRule `json:"toPorts,omitempty"`
+}
+
+type ciliumCIDRRule struct {
+	CIDR   string   `json:"cidr"`
+	Except []string `json:"except,omitempty"`
+}
+
+type ciliumFQDNSelector struct {
+	MatchName    string `json:"matchName,omitempty"`
+	MatchPattern string `json:"matchPattern,omitempty"`
+}
+
+type ciliumPortRule struct {
+	Ports []ciliumPortProtocol `json:"ports,omitempty"`
+	Rules *ciliumL7Rules       `json:"rules,omitempty"`
+}
+
+type ciliumPortProtocol struct {
+	Port     string `json:"port"`
+	EndPort  int32  `json:"endPort,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+}
+
+type ciliumL7Rules struct {
+	DNS []ciliumFQDNSelector `json:"dns,omitempty"`
+}
+
+// ciliumDNSProxyRule allows the pods to query kube-dns through the DNS proxy of cilium,
+// toFQDNs rules only work for names that are seen by the proxy
+var ciliumDNSProxyRule = ciliumEgressRule{
+	ToEndpoints: []ciliumSelector{
+		{
+			MatchLabels: map[string]string{
+				"k8s:io.kubernetes.pod.namespace": "kube-system",
+				"k8s:k8s-app":                     "kube-dns",
+			},
+		},
+	},
+	ToPorts: []ciliumPortRule{
+		{
+			Ports: []ciliumPortProtocol{
+				{Port: "53", Protocol: "ANY"},
+			},
+			Rules: &ciliumL7Rules{
+				DNS: []ciliumFQDNSelector{
+					{MatchPattern: "*"},
+				},
+			},
+		},
+	},
+}
+
+// ciliumPolicyBackend writes CiliumNetworkPolicies, hostnames are sent as toFQDNs rules
+type ciliumPolicyBackend struct {
+	client.Client
+}
+
+func (b *ciliumPolicyBackend) Name() string {
+	return PolicyBackendCilium
+}
+
+func (b *ciliumPolicyBackend) Kind() string {
+	return ciliumNetworkPolicyGVK.Kind
+}
+
+func (b *ciliumPolicyBackend) NewObject() client.Object {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(ciliumNetworkPolicyGVK)
+	return obj
+}
+
+func (b *ciliumPolicyBackend) SupportsFQDN() bool {
+	return true
+}
+
+func (b *ciliumPolicyBackend) Apply(ctx context.Context, acl *v1alpha1.ACL, policy *aclPolicy) (applyResult, error) {
+	spec, err := ciliumSpecForPolicy(policy)
+	if err != nil {
+		return applyResult{}, err
+	}
+
+	desiredSpec, err := toUnstructuredMap(spec)
+	if err != nil {
+		return applyResult{}, err
+	}
+
+	ciliumPolicy := b.NewObject().(*unstructured.Unstructured)
+	err = b.Client.Get(ctx, client.ObjectKey{
+		Namespace: acl.Namespace,
+		Name:      policy.Name,
+	}, ciliumPolicy)
+
+	if k8sErrors.IsNotFound(err) {
+		ciliumPolicy = b.newPolicy(acl, policy, desiredSpec)
+		err = b.Client.Create(ctx, ciliumPolicy)
+		if err != nil {
+			return applyResult{}, errors.Wrap(err, "could not create CiliumNetworkPolicy object")
+		}
+		return applyResult{outcome: reconcileResultCreated}, nil
+	} else if err != nil {
+		return applyResult{}, errors.Wrap(err, "could not get CiliumNetworkPolicy object")
+	}
+
+	if !policy.TakeOver {
+		err = checkPolicyOwnership(b.Kind(), ciliumPolicy, acl)
+		if err != nil {
+			return applyResult{}, err
+		}
+	}
+
+	hasChanges := false
+	if ensureControllerRef(ciliumPolicy, acl) {
+		hasChanges = true
+	}
+
+	if mergePolicyMetadata(ciliumPolicy, policy.Metadata) {
+		hasChanges = true
+	}
+
+	if ensurePolicyLabels(ciliumPolicy, acl) {
+		hasChanges = true
+	}
+
+	existingSpec, err := toUnstructuredMap(ciliumPolicy.Object["spec"])
+	if err != nil {
+		return applyResult{}, err
+	}
+
+	if !reflect.DeepEqual(existingSpec, desiredSpec) {
+		ciliumPolicy.Object["spec"] = desiredSpec
+		hasChanges = true
+	}
+
+	if !hasChanges {
+		return applyResult{outcome: reconcileResultNoop}, nil
+	}
+
+	err = b.Client.Update(ctx, ciliumPolicy)
+	if err != nil {
+		return applyResult{}, errors.Wrap(err, "could not update CiliumNetworkPolicy object")
+	}
+	return applyResult{outcome: reconcileResultUpdated}, nil
+}
+
+func (b *ciliumPolicyBackend) Render(acl *v1alpha1.ACL, policy *aclPolicy) (client.Object, error) {
+	spec, err := ciliumSpecForPolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	desiredSpec, err := toUnstructuredMap(spec)
+	if err != nil {
+		return nil, err
+	}
+	return b.newPolicy(acl, policy, desiredSpec), nil
+}
+
+// newPolicy is the CiliumNetworkPolicy created for policy, controlled by acl
+func (b *ciliumPolicyBackend) newPolicy(acl *v1alpha1.ACL, policy *aclPolicy, spec map[string]interface{}) *unstructured.Unstructured {
+	ciliumPolicy := b.NewObject().(*unstructured.Unstructured)
+	ciliumPolicy.SetNamespace(acl.Namespace)
+	ciliumPolicy.SetName(policy.Name)
+	ensureControllerRef(ciliumPolicy, acl)
+	ciliumPolicy.Object["spec"] = spec
+	mergePolicyMetadata(ciliumPolicy, policy.Metadata)
+	ensurePolicyLabels(ciliumPolicy, acl)
+	return ciliumPolicy
+}
+
+func (b *ciliumPolicyBackend) Diff(ctx context.Context, acl *v1alpha1.ACL, policy *aclPolicy) (string, error) {
+	spec, err := ciliumSpecForPolicy(policy)
+	if err != nil {
+		return "", err
+	}
+
+	desiredSpec, err := toUnstructuredMap(spec)
+	if err != nil {
+		return "", err
+	}
+
+	ciliumPolicy := b.NewObject().(*unstructured.Unstructured)
+	err = b.Client.Get(ctx, client.ObjectKey{
+		Namespace: acl.Namespace,
+		Name:      policy.Name,
+	}, ciliumPolicy)
+
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return "", errors.Wrap(err, "could not get CiliumNetworkPolicy object")
+	}
+
+	existingSpec, err := toUnstructuredMap(ciliumPolicy.Object["spec"])
+	if err != nil {
+		return "", err
+	}
+
+	return policyDiff(existingSpec, desiredSpec)
+}
+
+func ciliumSpecForPolicy(policy *aclPolicy) (*ciliumNetworkPolicySpec, error) {
+	spec := &ciliumNetworkPolicySpec{
+		EndpointSelector: ciliumSelector{
+			MatchLabels:      policy.PodSelector.MatchLabels,
+			MatchExpressions: policy.PodSelector.MatchExpressions,
+		},
+	}
+
+	for _, fqdn := range policy.FQDNs {
+		ports, err := ciliumPortsForProtoPorts(fqdn.Ports)
+		if err != nil {
+			return nil, err
+		}
+
+		spec.Egress = append(spec.Egress, ciliumEgressRule{
+			ToFQDNs: []ciliumFQDNSelector{ciliumFQDNSelectorForName(fqdn.Name)},
+			ToPorts: ports,
+		})
+	}
+
+	if len(policy.FQDNs) > 0 {
+		spec.Egress = append(spec.Egress, ciliumDNSProxyRule)
+	}
+
+	for _, egressRule := range policy.Egress {
+		endpoints, cidrs, all := ciliumPeers(egressRule.To)
+		ports := ciliumPortsForNetworkPolicyPorts(egressRule.Ports)
+
+		if all {
+			spec.Egress = append(spec.Egress, ciliumEgressRule{ToEntities: []string{"all"}, ToPorts: ports})
+			continue
+		}
+		if len(endpoints) > 0 {
+			spec.Egress = append(spec.Egress, ciliumEgressRule{ToEndpoints: endpoints, ToPorts: ports})
+		}
+		if len(cidrs) > 0 {
+			spec.Egress = append(spec.Egress, ciliumEgressRule{ToCIDRSet: cidrs, ToPorts: ports})
+		}
+	}
+
+	// cilium only denies the egress of endpoints selected by a rule with an egress section, an
+	// empty rule denies every egress like a NetworkPolicy without egress rules
+	if len(spec.Egress) == 0 {
+		spec.Egress = []ciliumEgressRule{{}}
+	}
+
+	for _, ingressRule := range policy.Ingress {
+		endpoints, cidrs, all := ciliumPeers(ingressRule.From)
+		ports := ciliumPortsForNetworkPolicyPorts(ingressRule.Ports)
+
+		if all {
+			spec.Ingress = append(spec.Ingress, ciliumIngressRule{FromEntities: []string{"all"}, ToPorts: ports})
+			continue
+		}
+		if len(endpoints) > 0 {
+			spec.Ingress = append(spec.Ingress, ciliumIngressRule{FromEndpoints: endpoints, ToPorts: ports})
+		}
+		if len(cidrs) > 0 {
+			spec.Ingress = append(spec.Ingress, ciliumIngressRule{FromCIDRSet: cidrs, ToPorts: ports})
+		}
+	}
+
+	return spec, nil
+}
+
+// ciliumPeers splits peers by type, cilium does not allow to mix endpoints and CIDRs on the same rule
+func ciliumPeers(peers []netv1.NetworkPolicyPeer) ([]ciliumSelector, []ciliumCIDRRule, bool) {
+	if len(peers) == 0 {
+		return nil, nil, true
+	}
+
+	var endpoints []ciliumSelector
+	var cidrs []ciliumCIDRRule
+	for _, peer := range peers {
+		if peer.IPBlock != nil {
+			cidrs = append(cidrs, ciliumCIDRRule{
+				CIDR:   peer.IPBlock.CIDR,
+				Except: peer.IPBlock.Except,
+			})
+			continue
+		}
+
+		matchLabels := map[string]string{}
+		var matchExpressions []metav1.LabelSelectorRequirement
+		if peer.PodSelector != nil {
+			for k, v := range peer.PodSelector.MatchLabels {
+				matchLabels[k] = v
+			}
+			matchExpressions = append(matchExpressions, peer.PodSelector.MatchExpressions...)
+		}
+		if peer.NamespaceSelector != nil {
+			for k, v := range peer.NamespaceSelector.MatchLabels {
+				matchLabels[ciliumNamespaceLabelPrefix+k] = v
+			}
+			for _, expression := range peer.NamespaceSelector.MatchExpressions {
+				expression.Key = ciliumNamespaceLabelPrefix + expression.Key
+				matchExpressions = append(matchExpressions, expression)
+			}
+		}
+
+		endpoints = append(endpoints, ciliumSelector{MatchLabels: matchLabels, MatchExpressions: matchExpressions})
+	}
+
+	return endpoints, cidrs, false
+}
+
+func ciliumFQDNSelectorForName(name string) ciliumFQDNSelector {
+	if isWildCard(name) {
+		return ciliumFQDNSelector{MatchPattern: "*." + strings.TrimPrefix(strings.TrimPrefix(name, "*"), ".")}
+	}
+
+	return ciliumFQDNSelector{MatchName: name}
+}
+
+func ciliumPortsForProtoPorts(protoPorts v1alpha1.ACLSpecProtoPorts) ([]ciliumPortRule, error) {
+	if len(protoPorts) == 0 {
+		return nil, nil
+	}
+
+	ports := []ciliumPortProtocol{}
+	for _, port := range protoPorts {
+		err := port.Validate()
+		if err != nil {
+			return nil, err
+		}
+
+		ciliumPort := ciliumPortProtocol{
+			Port:     strconv.Itoa(int(port.Number)),
+			Protocol: ciliumProtocol(port.Protocol),
+		}
+		if port.Name != "" {
+			ciliumPort.Port = port.Name
+		}
+		if port.EndPort > port.Number {
+			ciliumPort.EndPort = int32(port.EndPort)
+		}
+
+		ports = append(ports, ciliumPort)
+	}
+
+	return []ciliumPortRule{{Ports: ports}}, nil
+}
+
+func ciliumPortsForNetworkPolicyPorts(networkPolicyPorts []netv1.NetworkPolicyPort) []ciliumPortRule {
+	if len(networkPolicyPorts) == 0 {
+		return nil
+	}
+
+	ports := []ciliumPortProtocol{}
+	for _, networkPolicyPort := range networkPolicyPorts {
+		ciliumPort := ciliumPortProtocol{
+			Protocol: "ANY",
+		}
+		if networkPolicyPort.Protocol != nil {
+			ciliumPort.Protocol = string(*networkPolicyPort.Protocol)
+		}
+		if networkPolicyPort.Port != nil {
+			ciliumPort.Port = networkPolicyPort.Port.String()
+		}
+		if networkPolicyPort.EndPort != nil {
+			ciliumPort.EndPort = *networkPolicyPort.EndPort
+		}
+
+		ports = append(ports, ciliumPort)
+	}
+
+	return []ciliumPortRule{{Ports: ports}}
+}
+
+func ciliumProtocol(protocol string) string {
+	if protocol == "" {
+		return "ANY"
+	}
+	return strings.ToUpper(protocol)
+}
+
+// toUnstructuredMap converts the value to the same representation of objects read from API
+func toUnstructuredMap(value interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{}
+	err = json.Unmarshal(data, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
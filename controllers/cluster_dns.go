@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// ClusterDNS are the pods that answer the DNS queries of the cluster
+type ClusterDNS struct {
+	Namespace string
+	PodLabels map[string]string
+}
+
+// DefaultClusterDNS is the kube-dns deployment, coredns pods keep the k8s-app=kube-dns label
+var DefaultClusterDNS = ClusterDNS{
+	Namespace: "kube-system",
+	PodLabels: map[string]string{
+		"k8s-app": "kube-dns",
+	},
+}
+
+// clusterDNSEgressRule allows the pods of ClusterDNS on port 53, a pod selected by any egress policy
+// is denied everything else, including the name resolution of the destinations of its ACL
+func (r *ACLReconciler) clusterDNSEgressRule() netv1.NetworkPolicyEgressRule {
+	clusterDNS := DefaultClusterDNS
+	if r.ClusterDNS != nil {
+		clusterDNS = *r.ClusterDNS
+	}
+
+	dnsPort := intstr.FromInt(53)
+	udp := corev1.ProtocolUDP
+	tcp := corev1.ProtocolTCP
+
+	return netv1.NetworkPolicyEgressRule{
+		To: []netv1.NetworkPolicyPeer{
+			{
+				NamespaceSelector: r.namespaceSelector(clusterDNS.Namespace),
+				PodSelector: &metav1.LabelSelector{
+					MatchLabels: clusterDNS.PodLabels,
+				},
+			},
+		},
+		Ports: []netv1.NetworkPolicyPort{
+			{Protocol: &udp, Port: &dnsPort},
+			{Protocol: &tcp, Port: &dnsPort},
+		},
+	}
+}
@@ -0,0 +1,22 @@
+package controllers
+
+const (
+	// DefaultACLConcurrency is the MaxConcurrentReconciles of ACLReconciler without one
+	DefaultACLConcurrency = 4
+
+	// DefaultDNSEntryConcurrency is the MaxConcurrentReconciles of ACLDNSEntryReconciler without one
+	DefaultDNSEntryConcurrency = 4
+
+	// DefaultConcurrency is the MaxConcurrentReconciles of the other reconcilers without one, like
+	// the address reconcilers
+	DefaultConcurrency = 2
+)
+
+// maxConcurrentReconciles returns the workers of a controller, concurrency when it is positive and
+// defaultConcurrency otherwise
+func maxConcurrentReconciles(concurrency, defaultConcurrency int) int {
+	if concurrency <= 0 {
+		return defaultConcurrency
+	}
+	return concurrency
+}
@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+
+const configMapIndex = "config-map"
+
+// configMapMetadata is the metadata of a ConfigMap, ConfigMaps are watched by their metadata so the
+// manager does not cache the data of every ConfigMap of the cluster
+func configMapMetadata() *metav1.PartialObjectMetadata {
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+	return obj
+}
+
+// configMapKey is the namespace/name of the ConfigMap of destination, namespace is the namespace of ACL,
+// ConfigMaps are only read from the namespace of ACL so an ACL does not expose ConfigMaps of other tenants
+func configMapKey(configMap *v1alpha1.ACLSpecConfigMap, namespace string) client.ObjectKey {
+	return client.ObjectKey{Namespace: namespace, Name: configMap.Name}
+}
+
+// foreignConfigMap reports whether destination names a ConfigMap outside of namespace, the namespace of ACL
+func foreignConfigMap(configMap *v1alpha1.ACLSpecConfigMap, namespace string) bool {
+	return configMap.Namespace != "" && configMap.Namespace != namespace
+}
+
+// configMapAddresses returns the addresses listed on the key of the ConfigMap of destination
+func configMapAddresses(ctx context.Context, reader client.Reader, configMap *v1alpha1.ACLSpecConfigMap, namespace string) ([]string, error) {
+	if foreignConfigMap(configMap, namespace) {
+		return nil, fmt.Errorf("configMap %s/%s is not on namespace %s, only ConfigMaps of the namespace of the ACL are allowed", configMap.Namespace, configMap.Name, namespace)
+	}
+
+	key := configMapKey(configMap, namespace)
+	existingConfigMap := &corev1.ConfigMap{}
+	err := reader.Get(ctx, key, existingConfigMap)
+	if k8sErrors.IsNotFound(err) {
+		return nil, fmt.Errorf("configMap %s not found", key)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return addressesOfConfigMap(existingConfigMap, configMap.Key)
+}
+
+func addressesOfConfigMap(configMap *corev1.ConfigMap, key string) ([]string, error) {
+	value, ok := configMap.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("configMap %s/%s has no key %q", configMap.Namespace, configMap.Name, key)
+	}
+
+	addresses, err := v1alpha1.ParseConfigMapAddresses(value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid key %q of configMap %s/%s", key, configMap.Namespace, configMap.Name)
+	}
+	return addresses, nil
+}
+
+// egressRulesForConfigMap allows the addresses listed on a ConfigMap, hostnames are resolved by
+// ACLDNSEntry objects like the ones of externalDNS destinations
+func (r *ACLReconciler) egressRulesForConfigMap(ctx context.Context, configMap *v1alpha1.ACLSpecConfigMap, addressOptions addressOptions) ([]netv1.NetworkPolicyEgressRule, error) {
+	addresses, err := configMapAddresses(ctx, r.apiReader(), configMap, addressOptions.namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(addresses) == 0 {
+		key := configMapKey(configMap, addressOptions.namespace)
+		return nil, &unsupportedDestinationError{
+			message: fmt.Sprintf("key %q of configMap %s has no addresses, it is ignored", configMap.Key, key),
+		}
+	}
+
+	allErrors := &tsuruErrors.MultiError{}
+	var pendingErr error
+	egress := []netv1.NetworkPolicyEgressRule{}
+	for _, address := range addresses {
+		var addressEgress []netv1.NetworkPolicyEgressRule
+		if isIPRange(address) {
+			addressEgress, err = r.egressRulesForExternalIP(ctx, &v1alpha1.ACLSpecExternalIP{IP: address, Ports: configMap.Ports})
+		} else {
+			addressEgress, err = r.egressRulesForExternalDNS(ctx, &v1alpha1.ACLSpecExternalDNS{Name: address, Ports: configMap.Ports}, addressOptions)
+		}
+
+		if errors.Is(err, ErrAddressNotReady) {
+			// the other addresses are still allowed while the entry is resolved
+			pendingErr = err
+		} else if err != nil {
+			allErrors.Add(errors.Wrapf(err, "could not generate egress rule for address %q", address))
+		}
+		egress = append(egress, addressEgress...)
+	}
+
+	if pendingErr != nil {
+		return egress, pendingErr
+	}
+	return egress, allErrors.ToError()
+}
+
+// configMapDNSEntryNames are the names of the ACLDNSEntry objects of the hostnames listed on a ConfigMap,
+// a missing ConfigMap or an invalid list has none
+func configMapDNSEntryNames(ctx context.Context, reader client.Reader, configMap *v1alpha1.ACLSpecConfigMap, namespace, dnsEntryNamespace string) ([]string, error) {
+	if foreignConfigMap(configMap, namespace) {
+		return nil, nil
+	}
+
+	existingConfigMap := &corev1.ConfigMap{}
+	err := reader.Get(ctx, configMapKey(configMap, namespace), existingConfigMap)
+	if k8sErrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	addresses, err := addressesOfConfigMap(existingConfigMap, configMap.Key)
+	if err != nil {
+		return nil, nil
+	}
+
+	names := []string{}
+	for _, address := range addresses {
+		if isIPRange(address) {
+			continue
+		}
+		names = append(names, dnsEntryName(externalDNSHost(&v1alpha1.ACLSpecExternalDNS{Name: address}), nil, dnsEntryNamespace))
+	}
+	return names, nil
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+// destinationExpired reports whether expiresAt of destination passed at now
+func destinationExpired(destination v1alpha1.ACLSpecDestination, now time.Time) bool {
+	return destination.ExpiresAt != nil && !destination.ExpiresAt.Time.After(now)
+}
+
+// expiredDestinations counts the destinations expired at now
+func expiredDestinations(destinations []v1alpha1.ACLSpecDestination, now time.Time) int {
+	expired := 0
+	for _, destination := range destinations {
+		if destinationExpired(destination, now) {
+			expired++
+		}
+	}
+	return expired
+}
+
+// nextDestinationExpiry returns the earliest expiry of destinations after now, zero when none expires
+func nextDestinationExpiry(destinations []v1alpha1.ACLSpecDestination, now time.Time) time.Time {
+	var next time.Time
+	for _, destination := range destinations {
+		if destination.ExpiresAt == nil || !destination.ExpiresAt.Time.After(now) {
+			continue
+		}
+		if next.IsZero() || destination.ExpiresAt.Time.Before(next) {
+			next = destination.ExpiresAt.Time
+		}
+	}
+	return next
+}
+
+// expiredSince reports whether a destination expired after the policy of since was applied, that
+// policy still allows it, since is a RFC3339 timestamp and an invalid one is assumed to be older
+func expiredSince(destinations []v1alpha1.ACLSpecDestination, since string, now time.Time) bool {
+	sinceTime, err := time.Parse(time.RFC3339, since)
+	for _, destination := range destinations {
+		if !destinationExpired(destination, now) {
+			continue
+		}
+		if err != nil || !destination.ExpiresAt.Time.Before(sinceTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// expiredOnStatus reports whether the destination of index was already expired on status
+func expiredOnStatus(status *v1alpha1.ACLStatus, index int) bool {
+	for _, resolved := range status.ResolvedDestinations {
+		if resolved.Index == index {
+			return resolved.ExpiredAt != ""
+		}
+	}
+	return false
+}
+
+// requeueAt reconciles the ACL again at when it comes before the requeue of result, results of
+// immediate requeues are kept as they are, a zero when keeps result
+func requeueAt(result ctrl.Result, when, now time.Time) ctrl.Result {
+	if when.IsZero() || (result.Requeue && result.RequeueAfter == 0) {
+		return result
+	}
+
+	until := when.Sub(now)
+	if until <= 0 {
+		// when is at the same second of now, it is reconciled right after
+		until = time.Second
+	}
+	if result.RequeueAfter == 0 || until < result.RequeueAfter {
+		result.Requeue = true
+		result.RequeueAfter = until
+	}
+	return result
+}
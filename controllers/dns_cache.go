@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"container/list"
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+const defaultDNSCacheMaxEntries = 1024
+
+type dnsCacheBypassKey struct{}
+
+// WithoutDNSCache returns a context that forces the cachingResolver to query the
+// underlying resolver, the fresh answer replaces the cached one
+func WithoutDNSCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dnsCacheBypassKey{}, true)
+}
+
+func dnsCacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(dnsCacheBypassKey{}).(bool)
+	return bypass
+}
+
+type dnsCacheEntry struct {
+	host    string
+	ipAddrs []net.IPAddr
+	cnames  []string
+	expires time.Time
+}
+
+// cachingResolver keeps the answers of Resolver until the TTL of records expires,
+// answers without a known TTL are never cached
+type cachingResolver struct {
+	Resolver   ACLDNSResolver
+	MaxEntries int
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+	now     func() time.Time
+}
+
+func (c *cachingResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	ipAddrs, _, err := c.LookupIPAddrTTL(ctx, host)
+	return ipAddrs, err
+}
+
+func (c *cachingResolver) LookupIPAddrTTL(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+	answer, err := c.LookupDNSAnswer(ctx, host)
+	if err != nil {
+		return nil, 0, err
+	}
+	return answer.IPAddrs, answer.TTL, nil
+}
+
+func (c *cachingResolver) LookupDNSAnswer(ctx context.Context, host string) (*DNSAnswer, error) {
+	if !dnsCacheBypassed(ctx) {
+		answer, ok := c.get(host)
+		if ok {
+			return answer, nil
+		}
+	}
+
+	answer, err := lookupDNSAnswer(ctx, c.Resolver, host)
+	if err != nil {
+		return nil, err
+	}
+
+	c.set(host, answer)
+	return answer, nil
+}
+
+// LookupSRV is not cached, SRV answers do not report their TTL
+func (c *cachingResolver) LookupSRV(ctx context.Context, name string) ([]*net.SRV, error) {
+	return lookupSRV(ctx, c.Resolver, name)
+}
+
+func (c *cachingResolver) get(host string) (*DNSAnswer, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[host]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*dnsCacheEntry)
+	ttl := entry.expires.Sub(c.timeNow())
+	if ttl <= 0 {
+		c.lru.Remove(elem)
+		delete(c.entries, host)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	return &DNSAnswer{
+		IPAddrs: copyIPAddrs(entry.ipAddrs),
+		TTL:     ttl,
+		CNAMEs:  append([]string(nil), entry.cnames...),
+	}, true
+}
+
+func (c *cachingResolver) set(host string, answer *DNSAnswer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[host]; ok {
+		c.lru.Remove(elem)
+		delete(c.entries, host)
+	}
+
+	if answer.TTL <= 0 || len(answer.IPAddrs) == 0 {
+		return
+	}
+
+	if c.entries == nil {
+		c.entries = map[string]*list.Element{}
+		c.lru = list.New()
+	}
+
+	c.entries[host] = c.lru.PushFront(&dnsCacheEntry{
+		host:    host,
+		ipAddrs: copyIPAddrs(answer.IPAddrs),
+		cnames:  append([]string(nil), answer.CNAMEs...),
+		expires: c.timeNow().Add(answer.TTL),
+	})
+
+	maxEntries := c.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = defaultDNSCacheMaxEntries
+	}
+
+	for c.lru.Len() > maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*dnsCacheEntry).host)
+	}
+}
+
+func (c *cachingResolver) timeNow() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+func copyIPAddrs(in []net.IPAddr) []net.IPAddr {
+	out := make([]net.IPAddr, len(in))
+	copy(out, in)
+	return out
+}
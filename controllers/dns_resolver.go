@@ -0,0 +1,387 @@
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// minRequeueAfterTTL avoids a hot loop of reconciles for records with a very short TTL
+	minRequeueAfterTTL = 30 * time.Second
+
+	// DefaultDNSLookupTimeout is used by reconcilers without a LookupTimeout
+	DefaultDNSLookupTimeout = 10 * time.Second
+)
+
+var errDNSTruncated = errors.New("dns response is truncated")
+
+// ACLDNSTTLResolver is implemented by resolvers that are able to report the TTL of the answers
+type ACLDNSTTLResolver interface {
+	LookupIPAddrTTL(context.Context, string) ([]net.IPAddr, time.Duration, error)
+}
+
+// lookupIPAddrTTL returns the addresses of host and the minimum TTL across the answers,
+// a zero TTL means that the resolver does not know the TTL of records
+func lookupIPAddrTTL(ctx context.Context, resolver ACLDNSResolver, host string) ([]net.IPAddr, time.Duration, error) {
+	if ttlResolver, ok := resolver.(ACLDNSTTLResolver); ok {
+		return ttlResolver.LookupIPAddrTTL(ctx, host)
+	}
+
+	ipAddrs, err := resolver.LookupIPAddr(ctx, host)
+	return ipAddrs, 0, err
+}
+
+// DNSAnswer is the outcome of a lookup of host
+type DNSAnswer struct {
+	IPAddrs []net.IPAddr
+	// TTL is the minimum TTL across the records, zero when it is not known
+	TTL time.Duration
+	// CNAMEs are the names followed from host to its addresses, in order, the last one is the
+	// canonical name of host. Resolvers that do not know the chain report only the canonical name
+	CNAMEs []string
+}
+
+// ACLDNSCNAMEResolver is implemented by resolvers that are able to report the CNAMEs followed
+// to the addresses of host
+type ACLDNSCNAMEResolver interface {
+	LookupDNSAnswer(context.Context, string) (*DNSAnswer, error)
+}
+
+// lookupDNSAnswer returns an answer without CNAMEs for resolvers that only report addresses
+func lookupDNSAnswer(ctx context.Context, resolver ACLDNSResolver, host string) (*DNSAnswer, error) {
+	if cnameResolver, ok := resolver.(ACLDNSCNAMEResolver); ok {
+		return cnameResolver.LookupDNSAnswer(ctx, host)
+	}
+
+	ipAddrs, ttl, err := lookupIPAddrTTL(ctx, resolver, host)
+	if err != nil {
+		return nil, err
+	}
+	return &DNSAnswer{IPAddrs: ipAddrs, TTL: ttl}, nil
+}
+
+// ACLDNSSRVResolver is implemented by resolvers that are able to look up SRV records
+type ACLDNSSRVResolver interface {
+	LookupSRV(context.Context, string) ([]*net.SRV, error)
+}
+
+// lookupSRV returns the SRV records of name, which is the full _service._proto.host name
+func lookupSRV(ctx context.Context, resolver ACLDNSResolver, name string) ([]*net.SRV, error) {
+	srvResolver, ok := resolver.(ACLDNSSRVResolver)
+	if !ok {
+		return nil, errors.Errorf("resolver does not support SRV lookups of %s", name)
+	}
+
+	return srvResolver.LookupSRV(ctx, name)
+}
+
+// withDNSLookupTimeout returns a context for the lookups of a reconcile, a deadline of ctx
+// that is shorter than timeout is kept
+func withDNSLookupTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = DefaultDNSLookupTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// requeueAfterForTTL never goes beyond the interval of reconciler
+func requeueAfterForTTL(ttl, interval time.Duration) time.Duration {
+	interval = requeueInterval(interval)
+	if ttl <= 0 || ttl > interval {
+		return interval
+	}
+
+	if ttl < minRequeueAfterTTL {
+		return minRequeueAfterTTL
+	}
+
+	return ttl
+}
+
+func minTTL(a, b time.Duration) time.Duration {
+	if a <= 0 {
+		return b
+	}
+	if b <= 0 {
+		return a
+	}
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// ttlResolver queries the nameservers of resolv.conf directly to know the TTL of records,
+// when it is not possible the lookup is delegated to the Fallback resolver without TTL
+type ttlResolver struct {
+	Fallback   *net.Resolver
+	ResolvConf string
+
+	// Nameservers are queried instead of the nameservers of ResolvConf, like DNS-over-TLS or
+	// DNS-over-HTTPS servers, lookups fail without Fallback when none of them answers
+	Nameservers []string
+	Transport   *dnsTransport
+}
+
+func (r *ttlResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	ipAddrs, _, err := r.LookupIPAddrTTL(ctx, host)
+	return ipAddrs, err
+}
+
+func (r *ttlResolver) LookupIPAddrTTL(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+	answer, err := r.LookupDNSAnswer(ctx, host)
+	if err != nil {
+		return nil, 0, err
+	}
+	return answer.IPAddrs, answer.TTL, nil
+}
+
+func (r *ttlResolver) LookupDNSAnswer(ctx context.Context, host string) (*DNSAnswer, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return &DNSAnswer{IPAddrs: []net.IPAddr{{IP: ip}}}, nil
+	}
+
+	nameservers := r.Nameservers
+	var err error
+	if len(nameservers) == 0 {
+		nameservers, err = readNameservers(r.ResolvConf)
+	}
+	if err == nil && len(nameservers) > 0 {
+		var answer *DNSAnswer
+		answer, err = lookupNameservers(ctx, r.Transport, nameservers, host)
+		if err == nil && len(answer.IPAddrs) > 0 {
+			return answer, nil
+		}
+	}
+
+	if r.Fallback == nil {
+		if err == nil {
+			err = errors.Errorf("no addresses found for %s on nameservers %s", host, strings.Join(nameservers, ", "))
+		}
+		return nil, err
+	}
+
+	// short names, search domains, /etc/hosts and truncated responses are handled by the system resolver
+	ipAddrs, err := r.Fallback.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	answer := &DNSAnswer{IPAddrs: ipAddrs}
+	// the system resolver only reports the canonical name, without the chain
+	if cname, err := r.Fallback.LookupCNAME(ctx, host); err == nil && canonicalDNSName(cname) != canonicalDNSName(host) {
+		answer.CNAMEs = []string{canonicalDNSName(cname)}
+	}
+
+	return answer, nil
+}
+
+func (r *ttlResolver) LookupSRV(ctx context.Context, name string) ([]*net.SRV, error) {
+	if r.Fallback == nil {
+		return nil, errors.Errorf("SRV lookups of %s require the fallback to the system resolver", name)
+	}
+	_, srvs, err := r.Fallback.LookupSRV(ctx, "", "", name)
+	return srvs, err
+}
+
+// nameserversResolver queries only Nameservers, without the fallback to the system resolver,
+// hosts are always fully qualified since search domains of resolv.conf do not apply
+type nameserversResolver struct {
+	Nameservers []string
+	Transport   *dnsTransport
+}
+
+func (r *nameserversResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	ipAddrs, _, err := r.LookupIPAddrTTL(ctx, host)
+	return ipAddrs, err
+}
+
+func (r *nameserversResolver) LookupIPAddrTTL(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+	answer, err := r.LookupDNSAnswer(ctx, host)
+	if err != nil {
+		return nil, 0, err
+	}
+	return answer.IPAddrs, answer.TTL, nil
+}
+
+func (r *nameserversResolver) LookupDNSAnswer(ctx context.Context, host string) (*DNSAnswer, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return &DNSAnswer{IPAddrs: []net.IPAddr{{IP: ip}}}, nil
+	}
+
+	answer, err := lookupNameservers(ctx, r.Transport, r.Nameservers, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(answer.IPAddrs) == 0 {
+		return nil, errors.Errorf("no addresses found for %s on nameservers %s", host, strings.Join(r.Nameservers, ", "))
+	}
+
+	return answer, nil
+}
+
+func lookupNameservers(ctx context.Context, transport *dnsTransport, nameservers []string, host string) (*DNSAnswer, error) {
+	if !strings.HasSuffix(host, ".") {
+		host = host + "."
+	}
+
+	name, err := dnsmessage.NewName(host)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, nameserver := range nameservers {
+		answer, err := lookupDNSAnswerOnServer(ctx, transport, nameserver, name)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		return answer, nil
+	}
+
+	return nil, lastErr
+}
+
+func lookupDNSAnswerOnServer(ctx context.Context, transport *dnsTransport, server string, name dnsmessage.Name) (*DNSAnswer, error) {
+	var ipAddrs []net.IPAddr
+	var ttl time.Duration
+	var lastErr error
+	cnames := map[string]string{}
+
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		resp, err := exchangeDNSMessage(ctx, transport, server, name, qtype)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		for _, answer := range resp.Answers {
+			switch body := answer.Body.(type) {
+			case *dnsmessage.AResource:
+				ip := make(net.IP, net.IPv4len)
+				copy(ip, body.A[:])
+				ipAddrs = append(ipAddrs, net.IPAddr{IP: ip})
+			case *dnsmessage.AAAAResource:
+				ip := make(net.IP, net.IPv6len)
+				copy(ip, body.AAAA[:])
+				ipAddrs = append(ipAddrs, net.IPAddr{IP: ip})
+			case *dnsmessage.CNAMEResource:
+				cnames[canonicalDNSName(answer.Header.Name.String())] = canonicalDNSName(body.CNAME.String())
+			default:
+				continue
+			}
+
+			ttl = minTTL(ttl, time.Duration(answer.Header.TTL)*time.Second)
+		}
+	}
+
+	if len(ipAddrs) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	return &DNSAnswer{
+		IPAddrs: ipAddrs,
+		TTL:     ttl,
+		CNAMEs:  cnameChain(canonicalDNSName(name.String()), cnames),
+	}, nil
+}
+
+// cnameChain follows the CNAMEs of the answers from host, records of other names are ignored
+// and a loop ends the chain
+func cnameChain(host string, cnames map[string]string) []string {
+	var chain []string
+	seen := map[string]bool{host: true}
+	for target, ok := cnames[host]; ok && !seen[target]; target, ok = cnames[target] {
+		seen[target] = true
+		chain = append(chain, target)
+	}
+	return chain
+}
+
+// canonicalDNSName lowercases name without the trailing dot
+func canonicalDNSName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+func exchangeDNSMessage(ctx context.Context, transport *dnsTransport, server string, name dnsmessage.Name, qtype dnsmessage.Type) (*dnsmessage.Message, error) {
+	id := uint16(rand.Uint32())
+	query := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:               id,
+			RecursionDesired: true,
+		},
+		Questions: []dnsmessage.Question{
+			{
+				Name:  name,
+				Type:  qtype,
+				Class: dnsmessage.ClassINET,
+			},
+		},
+	}
+
+	packedQuery, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	packedResp, err := transport.exchange(ctx, server, packedQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dnsmessage.Message{}
+	err = resp.Unpack(packedResp)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.ID != id {
+		return nil, errors.Errorf("dns response id mismatch from %s", server)
+	}
+
+	if resp.Truncated && !isStreamDNSServer(server) {
+		return nil, errDNSTruncated
+	}
+
+	if resp.RCode != dnsmessage.RCodeSuccess {
+		return nil, errors.Errorf("dns query for %s failed with %s", name.String(), resp.RCode.String())
+	}
+
+	return resp, nil
+}
+
+func readNameservers(resolvConf string) ([]string, error) {
+	if resolvConf == "" {
+		resolvConf = "/etc/resolv.conf"
+	}
+
+	f, err := os.Open(resolvConf)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	nameservers := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			continue
+		}
+
+		nameservers = append(nameservers, net.JoinHostPort(fields[1], "53"))
+	}
+
+	return nameservers, scanner.Err()
+}
@@ -0,0 +1,403 @@
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestRequeueAfterForTTL(t *testing.T) {
+	assert.Equal(t, DefaultRequeueInterval, requeueAfterForTTL(0, 0))
+	assert.Equal(t, DefaultRequeueInterval, requeueAfterForTTL(time.Hour, 0))
+	assert.Equal(t, time.Minute*5, requeueAfterForTTL(time.Minute*5, 0))
+	assert.Equal(t, minRequeueAfterTTL, requeueAfterForTTL(time.Second, 0))
+	assert.Equal(t, time.Minute*2, requeueAfterForTTL(time.Minute*5, time.Minute*2))
+	assert.Equal(t, time.Minute*2, requeueAfterForTTL(0, time.Minute*2))
+}
+
+func TestWithDNSLookupTimeout(t *testing.T) {
+	ctx, cancel := withDNSLookupTimeout(context.Background(), 0)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(DefaultDNSLookupTimeout), deadline, time.Second)
+
+	ctx, cancel = withDNSLookupTimeout(context.Background(), time.Minute)
+	defer cancel()
+	deadline, _ = ctx.Deadline()
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	// the shorter deadline of the parent wins
+	parent, cancelParent := context.WithTimeout(context.Background(), time.Second)
+	defer cancelParent()
+	parentDeadline, _ := parent.Deadline()
+	ctx, cancel = withDNSLookupTimeout(parent, time.Minute)
+	defer cancel()
+	deadline, _ = ctx.Deadline()
+	assert.Equal(t, parentDeadline, deadline)
+}
+
+// blockingResolver answers only when the context of lookup is done
+type blockingResolver struct{}
+
+func (b *blockingResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	<-ctx.Done()
+	return nil, &net.DNSError{Err: "i/o timeout", Name: host, IsTimeout: true}
+}
+
+func TestTTLResolverLookupIPAddrTTL(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	go serveFakeDNS(conn, map[dnsmessage.Type][]dnsmessage.Resource{
+		dnsmessage.TypeA: {
+			{
+				Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 300},
+				Body:   &dnsmessage.AResource{A: [4]byte{10, 1, 1, 1}},
+			},
+			{
+				Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 120},
+				Body:   &dnsmessage.AResource{A: [4]byte{10, 1, 1, 2}},
+			},
+		},
+	})
+
+	server := conn.LocalAddr().String()
+	name := dnsmessage.MustNewName("www.example.com.")
+	answer, err := lookupDNSAnswerOnServer(context.Background(), nil, server, name)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second*120, answer.TTL)
+	assert.Empty(t, answer.CNAMEs)
+	require.Len(t, answer.IPAddrs, 2)
+	assert.Equal(t, "10.1.1.1", answer.IPAddrs[0].IP.String())
+	assert.Equal(t, "10.1.1.2", answer.IPAddrs[1].IP.String())
+
+	// IP literals are not queried
+	resolver := &ttlResolver{Fallback: &net.Resolver{}}
+	ipAddrs, ttl, err := resolver.LookupIPAddrTTL(context.Background(), "192.168.1.1")
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+	assert.Equal(t, []net.IPAddr{{IP: net.ParseIP("192.168.1.1")}}, ipAddrs)
+}
+
+func TestLookupDNSAnswerCNAMEChain(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	go serveFakeDNS(conn, map[dnsmessage.Type][]dnsmessage.Resource{
+		dnsmessage.TypeA: {
+			{
+				Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("lb.cloud.example.net."), Type: dnsmessage.TypeCNAME, Class: dnsmessage.ClassINET, TTL: 60},
+				Body:   &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("lb-1234.region.cloud.example.net.")},
+			},
+			{
+				Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("WWW.example.com."), Type: dnsmessage.TypeCNAME, Class: dnsmessage.ClassINET, TTL: 300},
+				Body:   &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("LB.cloud.example.net.")},
+			},
+			{
+				Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("lb-1234.region.cloud.example.net."), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 30},
+				Body:   &dnsmessage.AResource{A: [4]byte{10, 1, 1, 1}},
+			},
+		},
+	})
+
+	resolver := &nameserversResolver{Nameservers: []string{conn.LocalAddr().String()}}
+	answer, err := resolver.LookupDNSAnswer(context.Background(), "www.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"lb.cloud.example.net", "lb-1234.region.cloud.example.net"}, answer.CNAMEs)
+	assert.Equal(t, []net.IPAddr{{IP: net.ParseIP("10.1.1.1").To4()}}, answer.IPAddrs)
+	assert.Equal(t, 30*time.Second, answer.TTL)
+
+	// callers that only need addresses are not affected
+	ipAddrs, ttl, err := lookupIPAddrTTL(context.Background(), resolver, "www.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, answer.IPAddrs, ipAddrs)
+	assert.Equal(t, answer.TTL, ttl)
+}
+
+func TestCNAMEChain(t *testing.T) {
+	cnames := map[string]string{
+		"a.example.com": "b.example.com",
+		"b.example.com": "c.example.com",
+		"x.example.com": "y.example.com",
+		"c.example.com": "a.example.com",
+	}
+	assert.Equal(t, []string{"b.example.com", "c.example.com"}, cnameChain("a.example.com", cnames))
+	assert.Nil(t, cnameChain("other.example.com", cnames))
+}
+
+func serveFakeDNS(conn net.PacketConn, answers map[dnsmessage.Type][]dnsmessage.Resource) {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		packed, err := fakeDNSResponse(buf[:n], answers)
+		if err != nil {
+			continue
+		}
+
+		conn.WriteTo(packed, addr)
+	}
+}
+
+// fakeDNSResponse answers a packed query with the answers of its type
+func fakeDNSResponse(packedQuery []byte, answers map[dnsmessage.Type][]dnsmessage.Resource) ([]byte, error) {
+	query := dnsmessage.Message{}
+	err := query.Unpack(packedQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 query.ID,
+			Response:           true,
+			RecursionAvailable: true,
+		},
+		Questions: query.Questions,
+	}
+
+	for _, answer := range answers[query.Questions[0].Type] {
+		if answer.Header.Name.Length == 0 {
+			answer.Header.Name = query.Questions[0].Name
+		}
+		resp.Answers = append(resp.Answers, answer)
+	}
+
+	return resp.Pack()
+}
+
+var fakeEncryptedDNSAnswers = map[dnsmessage.Type][]dnsmessage.Resource{
+	dnsmessage.TypeA: {
+		{
+			Header: dnsmessage.ResourceHeader{Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+			Body:   &dnsmessage.AResource{A: [4]byte{10, 2, 2, 2}},
+		},
+	},
+}
+
+func TestDNSOverHTTPS(t *testing.T) {
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/dns-query" || r.Method != http.MethodPost || r.Header.Get("Content-Type") != dnsMessageContentType {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		query, _ := io.ReadAll(r.Body)
+		resp, err := fakeDNSResponse(query, fakeEncryptedDNSAnswers)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", dnsMessageContentType)
+		w.Write(resp)
+	}))
+	defer server.Close()
+
+	transport := &dnsTransport{TLSConfig: server.Client().Transport.(*http.Transport).TLSClientConfig}
+	resolver := &nameserversResolver{
+		Nameservers: []string{server.URL + "/dns-query"},
+		Transport:   transport,
+	}
+	answer, err := resolver.LookupDNSAnswer(context.Background(), "www.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []net.IPAddr{{IP: net.ParseIP("10.2.2.2").To4()}}, answer.IPAddrs)
+	assert.Equal(t, time.Minute, answer.TTL)
+	assert.Equal(t, 2, requests)
+
+	resolver.Nameservers = []string{server.URL + "/other"}
+	_, err = resolver.LookupDNSAnswer(context.Background(), "www.example.com")
+	assert.EqualError(t, err, "dns-over-https server "+server.URL+"/other answered with status code 400")
+}
+
+func TestDNSOverTLS(t *testing.T) {
+	certServer := httptest.NewTLSServer(http.NotFoundHandler())
+	defer certServer.Close()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", certServer.TLS)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	var connections int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&connections, 1)
+			go func() {
+				defer conn.Close()
+				for {
+					var length [2]byte
+					if _, err := io.ReadFull(conn, length[:]); err != nil {
+						return
+					}
+					query := make([]byte, binary.BigEndian.Uint16(length[:]))
+					if _, err := io.ReadFull(conn, query); err != nil {
+						return
+					}
+					resp, err := fakeDNSResponse(query, fakeEncryptedDNSAnswers)
+					if err != nil {
+						return
+					}
+					binary.BigEndian.PutUint16(length[:], uint16(len(resp)))
+					conn.Write(append(length[:], resp...))
+				}
+			}()
+		}
+	}()
+
+	transport := &dnsTransport{TLSConfig: certServer.Client().Transport.(*http.Transport).TLSClientConfig}
+	resolver := &nameserversResolver{
+		Nameservers: []string{v1alpha1.DNSOverTLSScheme + listener.Addr().String()},
+		Transport:   transport,
+	}
+	for i := 0; i < 3; i++ {
+		answer, err := resolver.LookupDNSAnswer(context.Background(), "www.example.com")
+		require.NoError(t, err)
+		assert.Equal(t, []net.IPAddr{{IP: net.ParseIP("10.2.2.2").To4()}}, answer.IPAddrs)
+	}
+	// the connection is reused by the queries of every lookup
+	assert.Equal(t, int32(1), atomic.LoadInt32(&connections))
+
+	// a connection closed by the server is replaced
+	transport.idleConn(listener.Addr().String()).Close()
+	transport.release(listener.Addr().String(), &closedConn{})
+	_, err = resolver.LookupDNSAnswer(context.Background(), "www.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&connections))
+
+	// servers are verified by their certificates
+	resolver.Transport = &dnsTransport{}
+	_, err = resolver.LookupDNSAnswer(context.Background(), "www.example.com")
+	assert.ErrorContains(t, err, "certificate")
+}
+
+// closedConn fails every read and write, like a connection closed by the server
+type closedConn struct {
+	net.Conn
+}
+
+func (c *closedConn) Write(b []byte) (int, error)   { return 0, net.ErrClosed }
+func (c *closedConn) Read(b []byte) (int, error)    { return 0, net.ErrClosed }
+func (c *closedConn) SetDeadline(t time.Time) error { return nil }
+func (c *closedConn) Close() error                  { return nil }
+
+func TestTTLResolverWithoutFallback(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	go serveFakeDNS(conn, map[dnsmessage.Type][]dnsmessage.Resource{})
+
+	resolver := &ttlResolver{Nameservers: []string{conn.LocalAddr().String()}}
+	_, err = resolver.LookupDNSAnswer(context.Background(), "localhost")
+	assert.EqualError(t, err, "no addresses found for localhost on nameservers "+conn.LocalAddr().String())
+
+	_, err = resolver.LookupSRV(context.Background(), "_sip._tcp.example.com")
+	assert.EqualError(t, err, "SRV lookups of _sip._tcp.example.com require the fallback to the system resolver")
+
+	// the system resolver knows localhost
+	resolver.Fallback = &net.Resolver{}
+	answer, err := resolver.LookupDNSAnswer(context.Background(), "localhost")
+	require.NoError(t, err)
+	assert.NotEmpty(t, answer.IPAddrs)
+}
+
+type countingResolver struct {
+	fakeResolver
+	lookups map[string]int
+}
+
+func (c *countingResolver) LookupIPAddrTTL(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+	c.lookups[host]++
+	return c.fakeResolver.LookupIPAddrTTL(ctx, host)
+}
+
+func TestCachingResolver(t *testing.T) {
+	now := time.Now()
+	resolver := &countingResolver{
+		fakeResolver: fakeResolver{
+			hosts: map[string][]string{
+				"a.io":     {"10.1.1.1"},
+				"b.io":     {"10.1.1.2"},
+				"c.io":     {"10.1.1.3"},
+				"nottl.io": {"10.1.1.4"},
+			},
+			ttls: map[string]time.Duration{
+				"a.io": time.Minute,
+				"b.io": time.Minute,
+				"c.io": time.Minute,
+			},
+		},
+		lookups: map[string]int{},
+	}
+	cache := &cachingResolver{
+		Resolver:   resolver,
+		MaxEntries: 2,
+		now:        func() time.Time { return now },
+	}
+	ctx := context.Background()
+
+	ipAddrs, ttl, err := cache.LookupIPAddrTTL(ctx, "a.io")
+	require.NoError(t, err)
+	assert.Equal(t, "10.1.1.1", ipAddrs[0].IP.String())
+	assert.Equal(t, time.Minute, ttl)
+
+	now = now.Add(time.Second * 20)
+	ipAddrs, ttl, err = cache.LookupIPAddrTTL(ctx, "a.io")
+	require.NoError(t, err)
+	assert.Equal(t, "10.1.1.1", ipAddrs[0].IP.String())
+	assert.Equal(t, time.Second*40, ttl)
+	assert.Equal(t, 1, resolver.lookups["a.io"])
+
+	// bypass queries the resolver again
+	_, _, err = cache.LookupIPAddrTTL(WithoutDNSCache(ctx), "a.io")
+	require.NoError(t, err)
+	assert.Equal(t, 2, resolver.lookups["a.io"])
+
+	// answers without TTL are not cached
+	_, _, err = cache.LookupIPAddrTTL(ctx, "nottl.io")
+	require.NoError(t, err)
+	_, _, err = cache.LookupIPAddrTTL(ctx, "nottl.io")
+	require.NoError(t, err)
+	assert.Equal(t, 2, resolver.lookups["nottl.io"])
+
+	// b.io and c.io evicts the least recently used a.io
+	_, _, err = cache.LookupIPAddrTTL(ctx, "b.io")
+	require.NoError(t, err)
+	_, _, err = cache.LookupIPAddrTTL(ctx, "c.io")
+	require.NoError(t, err)
+	_, _, err = cache.LookupIPAddrTTL(ctx, "a.io")
+	require.NoError(t, err)
+	assert.Equal(t, 3, resolver.lookups["a.io"])
+
+	// expired entries are resolved again
+	now = now.Add(time.Minute * 2)
+	_, _, err = cache.LookupIPAddrTTL(ctx, "a.io")
+	require.NoError(t, err)
+	assert.Equal(t, 4, resolver.lookups["a.io"])
+
+	// errors are not cached
+	_, _, err = cache.LookupIPAddrTTL(ctx, "unknown.io")
+	assert.Error(t, err)
+}
@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+const (
+	// dnsMessageContentType is the media type of DNS-over-HTTPS requests and responses, RFC 8484
+	dnsMessageContentType = "application/dns-message"
+
+	// maxDNSMessageSize is the largest DNS message over TCP, TLS or HTTPS
+	maxDNSMessageSize = 65535
+
+	// maxIdleDNSConnsPerServer are the DNS-over-TLS connections kept open to a server between lookups
+	maxIdleDNSConnsPerServer = 2
+
+	// dnsIdleConnTimeout closes the connections unused for longer than servers usually keep them
+	dnsIdleConnTimeout = 30 * time.Second
+)
+
+// dnsTransport sends DNS queries to nameservers, over UDP for ip:port nameservers, over TLS for
+// tls:// nameservers and over HTTPS for https:// nameservers. Connections of TLS and HTTPS are
+// reused between lookups, the deadline of each query comes from the context of the lookup
+type dnsTransport struct {
+	// TLSConfig verifies the certificates of servers, the system roots are used when nil
+	TLSConfig *tls.Config
+
+	httpOnce   sync.Once
+	httpClient *http.Client
+
+	mu   sync.Mutex
+	idle map[string][]idleDNSConn
+}
+
+type idleDNSConn struct {
+	conn  net.Conn
+	since time.Time
+}
+
+var defaultDNSTransport = &dnsTransport{}
+
+// exchange sends a packed query to server and returns the packed response
+func (t *dnsTransport) exchange(ctx context.Context, server string, query []byte) ([]byte, error) {
+	if t == nil {
+		t = defaultDNSTransport
+	}
+
+	switch {
+	case strings.HasPrefix(server, v1alpha1.DNSOverTLSScheme):
+		return t.exchangeTLS(ctx, strings.TrimPrefix(server, v1alpha1.DNSOverTLSScheme), query)
+	case strings.HasPrefix(server, v1alpha1.DNSOverHTTPSScheme):
+		return t.exchangeHTTPS(ctx, server, query)
+	}
+
+	return exchangeUDP(ctx, server, query)
+}
+
+// isStreamDNSServer reports whether responses of server are never truncated
+func isStreamDNSServer(server string) bool {
+	return strings.HasPrefix(server, v1alpha1.DNSOverTLSScheme) || strings.HasPrefix(server, v1alpha1.DNSOverHTTPSScheme)
+}
+
+func exchangeUDP(ctx context.Context, server string, query []byte) ([]byte, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	_, err = conn.Write(query)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 512)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf[:n], nil
+}
+
+func (t *dnsTransport) exchangeHTTPS(ctx context.Context, server string, query []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", dnsMessageContentType)
+	req.Header.Set("Accept", dnsMessageContentType)
+
+	resp, err := t.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// the body is drained, so the connection is reused
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxDNSMessageSize))
+		return nil, errors.Errorf("dns-over-https server %s answered with status code %d", server, resp.StatusCode)
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, dnsMessageContentType) {
+		return nil, errors.Errorf("dns-over-https server %s answered with content type %q", server, contentType)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxDNSMessageSize))
+}
+
+func (t *dnsTransport) client() *http.Client {
+	t.httpOnce.Do(func() {
+		t.httpClient = &http.Client{
+			Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				TLSClientConfig:     t.TLSConfig,
+				ForceAttemptHTTP2:   true,
+				MaxIdleConnsPerHost: maxIdleDNSConnsPerServer,
+				IdleConnTimeout:     dnsIdleConnTimeout,
+				TLSHandshakeTimeout: DefaultDNSLookupTimeout,
+			},
+		}
+	})
+	return t.httpClient
+}
+
+// exchangeTLS sends query on an idle connection to address, a connection closed by the server
+// meanwhile is replaced by a new one
+func (t *dnsTransport) exchangeTLS(ctx context.Context, address string, query []byte) ([]byte, error) {
+	if conn := t.idleConn(address); conn != nil {
+		resp, err := exchangeStream(ctx, conn, query)
+		if err == nil {
+			t.release(address, conn)
+			return resp, nil
+		}
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, err
+		}
+	}
+
+	conn, err := t.dialTLS(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := exchangeStream(ctx, conn, query)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	t.release(address, conn)
+	return resp, nil
+}
+
+func (t *dnsTransport) dialTLS(ctx context.Context, address string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{}
+	if t.TLSConfig != nil {
+		config = t.TLSConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+
+	dialer := &tls.Dialer{Config: config}
+	return dialer.DialContext(ctx, "tcp", address)
+}
+
+// exchangeStream writes query and reads the response prefixed by their length, RFC 7858
+func exchangeStream(ctx context.Context, conn net.Conn, query []byte) ([]byte, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(DefaultDNSLookupTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	msg := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(msg, uint16(len(query)))
+	copy(msg[2:], query)
+	_, err := conn.Write(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	var length [2]byte
+	_, err = io.ReadFull(conn, length[:])
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	_, err = io.ReadFull(conn, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// idleConn returns an idle connection to address, connections idle for too long are closed
+func (t *dnsTransport) idleConn(address string) net.Conn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for conns := t.idle[address]; len(conns) > 0; conns = t.idle[address] {
+		idle := conns[len(conns)-1]
+		t.idle[address] = conns[:len(conns)-1]
+		if time.Since(idle.since) < dnsIdleConnTimeout {
+			return idle.conn
+		}
+		idle.conn.Close()
+	}
+	return nil
+}
+
+func (t *dnsTransport) release(address string, conn net.Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.idle[address]) >= maxIdleDNSConnsPerServer {
+		conn.Close()
+		return
+	}
+	if t.idle == nil {
+		t.idle = map[string][]idleDNSConn{}
+	}
+	t.idle[address] = append(t.idle[address], idleDNSConn{conn: conn, since: time.Now()})
+}
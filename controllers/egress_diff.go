@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxEgressDiffEntries bounds the entries of each side of a diff on logs and events, large
+// changes like a new pool of many addresses would flood them otherwise
+const maxEgressDiffEntries = 10
+
+// egressRulesDiff are the peers and ports allowed by an updated policy that were not allowed
+// before, and the ones that are not allowed anymore, each entry is a peer with one of its ports
+type egressRulesDiff struct {
+	Added   []string
+	Removed []string
+}
+
+// diffEgressRules compares the pairs of peer and port of existing and desired rules, rules are
+// compared by what they allow, so moving a peer between merged rules is not a change
+func diffEgressRules(existing, desired []netv1.NetworkPolicyEgressRule) *egressRulesDiff {
+	existingEntries := egressEntries(existing)
+	desiredEntries := egressEntries(desired)
+
+	diff := &egressRulesDiff{}
+	for entry := range desiredEntries {
+		if !existingEntries[entry] {
+			diff.Added = append(diff.Added, entry)
+		}
+	}
+	for entry := range existingEntries {
+		if !desiredEntries[entry] {
+			diff.Removed = append(diff.Removed, entry)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	return diff
+}
+
+func (d *egressRulesDiff) empty() bool {
+	return d == nil || (len(d.Added) == 0 && len(d.Removed) == 0)
+}
+
+// keysAndValues are the bounded entries of the diff for structured logs, with the total of each side
+func (d *egressRulesDiff) keysAndValues() []interface{} {
+	return []interface{}{
+		"addedEgress", boundedEgressEntries(d.Added),
+		"addedEgressCount", len(d.Added),
+		"removedEgress", boundedEgressEntries(d.Removed),
+		"removedEgressCount", len(d.Removed),
+	}
+}
+
+// String is the bounded summary of the diff recorded on events
+func (d *egressRulesDiff) String() string {
+	parts := []string{}
+	if len(d.Added) > 0 {
+		parts = append(parts, "egress added: "+strings.Join(boundedEgressEntries(d.Added), ", "))
+	}
+	if len(d.Removed) > 0 {
+		parts = append(parts, "egress removed: "+strings.Join(boundedEgressEntries(d.Removed), ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func boundedEgressEntries(entries []string) []string {
+	if len(entries) <= maxEgressDiffEntries {
+		return entries
+	}
+	bounded := append([]string{}, entries[:maxEgressDiffEntries]...)
+	return append(bounded, fmt.Sprintf("and %d more", len(entries)-maxEgressDiffEntries))
+}
+
+// egressEntries are the pairs of peer and port allowed by rules, rules without peers allow
+// every destination and rules without ports allow every port
+func egressEntries(rules []netv1.NetworkPolicyEgressRule) map[string]bool {
+	entries := map[string]bool{}
+	for _, rule := range rules {
+		peers := []string{"any destination"}
+		if len(rule.To) > 0 {
+			peers = peers[:0]
+			for _, peer := range rule.To {
+				peers = append(peers, describeEgressPeer(peer))
+			}
+		}
+
+		ports := []string{"any port"}
+		if len(rule.Ports) > 0 {
+			ports = ports[:0]
+			for _, port := range rule.Ports {
+				ports = append(ports, describeEgressPort(port))
+			}
+		}
+
+		for _, peer := range peers {
+			for _, port := range ports {
+				entries[peer+" "+port] = true
+			}
+		}
+	}
+	return entries
+}
+
+func describeEgressPeer(peer netv1.NetworkPolicyPeer) string {
+	if peer.IPBlock != nil {
+		if len(peer.IPBlock.Except) > 0 {
+			return peer.IPBlock.CIDR + " except " + strings.Join(peer.IPBlock.Except, ",")
+		}
+		return peer.IPBlock.CIDR
+	}
+
+	parts := []string{}
+	if peer.NamespaceSelector != nil {
+		parts = append(parts, "namespaces "+describeSelector(peer.NamespaceSelector))
+	}
+	if peer.PodSelector != nil {
+		parts = append(parts, "pods "+describeSelector(peer.PodSelector))
+	}
+	return strings.Join(parts, " ")
+}
+
+func describeSelector(selector *metav1.LabelSelector) string {
+	if formatted := metav1.FormatLabelSelector(selector); formatted != "<none>" {
+		return formatted
+	}
+	return "<all>"
+}
+
+func describeEgressPort(port netv1.NetworkPolicyPort) string {
+	protocol := corev1.ProtocolTCP
+	if port.Protocol != nil {
+		protocol = *port.Protocol
+	}
+	if port.Port == nil {
+		return string(protocol) + "/*"
+	}
+	if port.EndPort != nil {
+		return fmt.Sprintf("%s/%s-%d", protocol, port.Port.String(), *port.EndPort)
+	}
+	return string(protocol) + "/" + port.Port.String()
+}
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestDiffEgressRules(t *testing.T) {
+	udp := corev1.ProtocolUDP
+	https := intstr.FromInt(443)
+	dns := intstr.FromInt(53)
+	ipBlock := func(cidr string) netv1.NetworkPolicyPeer {
+		return netv1.NetworkPolicyPeer{IPBlock: &netv1.IPBlock{CIDR: cidr}}
+	}
+
+	existing := []netv1.NetworkPolicyEgressRule{
+		{
+			To:    []netv1.NetworkPolicyPeer{ipBlock("10.0.0.1/32"), ipBlock("10.0.0.2/32")},
+			Ports: []netv1.NetworkPolicyPort{{Port: &https}},
+		},
+		{
+			To: []netv1.NetworkPolicyPeer{{
+				NamespaceSelector: &metav1.LabelSelector{},
+				PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"k8s-app": "kube-dns"}},
+			}},
+			Ports: []netv1.NetworkPolicyPort{{Protocol: &udp, Port: &dns}},
+		},
+	}
+	desired := []netv1.NetworkPolicyEgressRule{
+		// the peers of merged rules are the same entries of separate rules
+		{
+			To:    []netv1.NetworkPolicyPeer{ipBlock("10.0.0.2/32")},
+			Ports: []netv1.NetworkPolicyPort{{Port: &https}},
+		},
+		{
+			To:    []netv1.NetworkPolicyPeer{ipBlock("10.0.0.3/32")},
+			Ports: []netv1.NetworkPolicyPort{{Port: &https}},
+		},
+		{
+			To: []netv1.NetworkPolicyPeer{{
+				IPBlock: &netv1.IPBlock{CIDR: "192.168.0.0/16", Except: []string{"192.168.1.0/24"}},
+			}},
+		},
+	}
+
+	diff := diffEgressRules(existing, desired)
+	assert.Equal(t, &egressRulesDiff{
+		Added:   []string{"10.0.0.3/32 TCP/443", "192.168.0.0/16 except 192.168.1.0/24 any port"},
+		Removed: []string{"10.0.0.1/32 TCP/443", "namespaces <all> pods k8s-app=kube-dns UDP/53"},
+	}, diff)
+	assert.Equal(t, "egress added: 10.0.0.3/32 TCP/443, 192.168.0.0/16 except 192.168.1.0/24 any port; egress removed: 10.0.0.1/32 TCP/443, namespaces <all> pods k8s-app=kube-dns UDP/53", diff.String())
+
+	assert.True(t, diffEgressRules(existing, existing).empty())
+
+	// large changes are bounded, the totals are kept on logs
+	many := []netv1.NetworkPolicyEgressRule{{}}
+	for i := 0; i < 25; i++ {
+		many[0].To = append(many[0].To, ipBlock(fmt.Sprintf("10.1.0.%d/32", 10+i)))
+	}
+	diff = diffEgressRules(nil, many)
+	keysAndValues := diff.keysAndValues()
+	assert.Len(t, keysAndValues[1], maxEgressDiffEntries+1)
+	assert.Equal(t, "and 15 more", keysAndValues[1].([]string)[maxEgressDiffEntries])
+	assert.Equal(t, 25, keysAndValues[3])
+}
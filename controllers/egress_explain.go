@@ -0,0 +1,256 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+// egressConnection is a connection of the pods of source to ip and port
+type egressConnection struct {
+	source   v1alpha1.ACLSpecSource
+	ip       net.IP
+	port     int32
+	protocol corev1.Protocol
+}
+
+func (c egressConnection) String() string {
+	return fmt.Sprintf("%s to %s", c.protocol, net.JoinHostPort(c.ip.String(), fmt.Sprint(c.port)))
+}
+
+// egressExplanation tells whether the policies of the ACLs of a source allow a connection
+type egressExplanation struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason"`
+
+	// ACLs are the ACLs of source as namespace/name
+	ACLs    []string      `json:"acls,omitempty"`
+	Matches []egressMatch `json:"matches,omitempty"`
+	// Notes are the parts of policies that could not be evaluated, like peers selecting pods
+	Notes []string `json:"notes,omitempty"`
+}
+
+// egressMatch is an egress rule that allows the connection
+type egressMatch struct {
+	ACL           string `json:"acl"`
+	NetworkPolicy string `json:"networkPolicy"`
+	// Rule is the position of the rule on spec.egress of NetworkPolicy
+	Rule  int    `json:"rule"`
+	Peer  string `json:"peer"`
+	Ports string `json:"ports"`
+	// Destinations are the destinations of ACL that generated the peer
+	Destinations []string `json:"destinations,omitempty"`
+}
+
+// explainEgress evaluates the NetworkPolicies of the ACLs of the source of connection, nothing is
+// resolved, the policies are evaluated as they are on the cluster
+func explainEgress(ctx context.Context, reader client.Reader, connection egressConnection) (*egressExplanation, error) {
+	aclList := &v1alpha1.ACLList{}
+	err := reader.List(ctx, aclList)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation := &egressExplanation{}
+	for i := range aclList.Items {
+		acl := &aclList.Items[i]
+		if !sameSource(acl.Spec.Source, connection.source) {
+			continue
+		}
+
+		aclName := acl.Namespace + "/" + acl.Name
+		explanation.ACLs = append(explanation.ACLs, aclName)
+
+		if acl.Status.NetworkPolicy == "" {
+			explanation.Notes = append(explanation.Notes, aclName+" has no policy yet")
+			continue
+		}
+		if acl.Status.PolicyBackend != "" && acl.Status.PolicyBackend != PolicyBackendKubernetes {
+			explanation.Notes = append(explanation.Notes, fmt.Sprintf("%s uses the %s backend, its policy is not evaluated", aclName, acl.Status.PolicyBackend))
+			continue
+		}
+
+		networkPolicy := &netv1.NetworkPolicy{}
+		err = reader.Get(ctx, client.ObjectKey{Namespace: acl.Namespace, Name: acl.Status.NetworkPolicy}, networkPolicy)
+		if k8sErrors.IsNotFound(err) {
+			explanation.Notes = append(explanation.Notes, fmt.Sprintf("NetworkPolicy %s of %s not found", acl.Status.NetworkPolicy, aclName))
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		matches, notes := matchEgressRules(networkPolicy.Spec.Egress, connection)
+		for _, match := range matches {
+			match.ACL = aclName
+			match.NetworkPolicy = acl.Namespace + "/" + networkPolicy.Name
+			match.Destinations = destinationsOfPeer(acl.Status.ResolvedDestinations, match.Peer)
+			explanation.Matches = append(explanation.Matches, match)
+		}
+		for _, note := range notes {
+			explanation.Notes = append(explanation.Notes, "NetworkPolicy "+acl.Namespace+"/"+networkPolicy.Name+": "+note)
+		}
+	}
+
+	switch {
+	case len(explanation.ACLs) == 0:
+		explanation.Allowed = true
+		explanation.Reason = "no ACL has this source, its egress is not restricted by the operator"
+	case len(explanation.Matches) > 0:
+		explanation.Allowed = true
+		explanation.Reason = fmt.Sprintf("%s is allowed by %d egress rules", connection, len(explanation.Matches))
+	default:
+		explanation.Reason = fmt.Sprintf("no egress rule of the policies of ACLs allows %s", connection)
+	}
+
+	return explanation, nil
+}
+
+func sameSource(a, b v1alpha1.ACLSpecSource) bool {
+	if a.TsuruApp != "" || b.TsuruApp != "" {
+		return a.TsuruApp == b.TsuruApp
+	}
+	if a.RpaasInstance != nil && b.RpaasInstance != nil {
+		return *a.RpaasInstance == *b.RpaasInstance
+	}
+	return false
+}
+
+// matchEgressRules returns the rules that allow connection, a rule allows it when a peer
+// contains the IP and a port matches, peers selecting pods and named ports are only noted
+func matchEgressRules(rules []netv1.NetworkPolicyEgressRule, connection egressConnection) ([]egressMatch, []string) {
+	var matches []egressMatch
+	var notes []string
+	for i, rule := range rules {
+		ports, portNote := matchPorts(rule.Ports, connection)
+		if portNote != "" {
+			notes = append(notes, fmt.Sprintf("rule %d %s", i, portNote))
+		}
+		if !ports {
+			continue
+		}
+
+		if len(rule.To) == 0 {
+			matches = append(matches, egressMatch{Rule: i, Peer: "any", Ports: describePorts(rule.Ports)})
+			continue
+		}
+
+		for _, peer := range rule.To {
+			if peer.IPBlock == nil {
+				notes = append(notes, fmt.Sprintf("rule %d allows %s, pod addresses are not evaluated", i, describePeerSelector(peer)))
+				continue
+			}
+
+			if ipBlockContains(peer.IPBlock, connection.ip) {
+				matches = append(matches, egressMatch{Rule: i, Peer: describePeer(peer), Ports: describePorts(rule.Ports)})
+			}
+		}
+	}
+	return matches, notes
+}
+
+func matchPorts(ports []netv1.NetworkPolicyPort, connection egressConnection) (bool, string) {
+	if len(ports) == 0 {
+		return true, ""
+	}
+
+	note := ""
+	for _, port := range ports {
+		protocol := corev1.ProtocolTCP
+		if port.Protocol != nil {
+			protocol = *port.Protocol
+		}
+		if protocol != connection.protocol {
+			continue
+		}
+
+		if port.Port == nil {
+			return true, ""
+		}
+		if port.Port.IntValue() == 0 {
+			note = fmt.Sprintf("has the named port %q, named ports are not evaluated", port.Port.String())
+			continue
+		}
+
+		endPort := int32(port.Port.IntValue())
+		if port.EndPort != nil {
+			endPort = *port.EndPort
+		}
+		if connection.port >= int32(port.Port.IntValue()) && connection.port <= endPort {
+			return true, ""
+		}
+	}
+	return false, note
+}
+
+func ipBlockContains(ipBlock *netv1.IPBlock, ip net.IP) bool {
+	_, network, err := v1alpha1.ParseCIDR(ipBlock.CIDR)
+	if err != nil || !containsIP([]*net.IPNet{network}, ip) {
+		return false
+	}
+
+	except, err := parseNetworks(ipBlock.Except)
+	return err == nil && !containsIP(except, ip)
+}
+
+// destinationsOfPeer finds the destinations of ACL that generated peer on status.resolvedDestinations
+func destinationsOfPeer(resolved []v1alpha1.ACLStatusResolvedDestination, peer string) []string {
+	var destinations []string
+	for _, destination := range resolved {
+		for _, cidr := range destination.CIDRs {
+			if cidr == peer {
+				destinations = append(destinations, destination.Destination)
+				break
+			}
+		}
+	}
+	return destinations
+}
+
+// parseEgressConnection reads a connection from the parameters of the explain endpoint, the source
+// is tsuruApp=name or rpaasInstance=service/instance
+func parseEgressConnection(tsuruApp, rpaasInstance, ip, port, protocol string) (egressConnection, error) {
+	connection := egressConnection{protocol: corev1.ProtocolTCP}
+
+	switch {
+	case tsuruApp != "" && rpaasInstance == "":
+		connection.source.TsuruApp = tsuruApp
+	case rpaasInstance != "" && tsuruApp == "":
+		parts := strings.SplitN(rpaasInstance, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return connection, fmt.Errorf("invalid rpaasInstance %q, use service/instance", rpaasInstance)
+		}
+		connection.source.RpaasInstance = &v1alpha1.ACLSpecRpaasInstance{ServiceName: parts[0], Instance: parts[1]}
+	default:
+		return connection, fmt.Errorf("set exactly one of tsuruApp or rpaasInstance as source")
+	}
+
+	connection.ip = net.ParseIP(ip)
+	if connection.ip == nil {
+		return connection, fmt.Errorf("invalid ip %q", ip)
+	}
+
+	portNumber, err := strconv.Atoi(port)
+	if err != nil || portNumber < 1 || portNumber > 65535 {
+		return connection, fmt.Errorf("invalid port %q, must be between 1 and 65535", port)
+	}
+	connection.port = int32(portNumber)
+
+	if protocol != "" {
+		parsed, err := v1alpha1.ParseProtocol(protocol)
+		if err != nil {
+			return connection, err
+		}
+		connection.protocol = parsed
+	}
+
+	return connection, nil
+}
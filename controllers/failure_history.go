@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+const (
+	// maxRecentFailures bounds status.recentFailures, the oldest failure is dropped first
+	maxRecentFailures = 5
+
+	// failureRefreshInterval avoids an update of status on every failed reconcile only to refresh
+	// lastTimestamp, each update would enqueue the ACL again right away
+	failureRefreshInterval = 5 * time.Minute
+)
+
+// recordFailure appends a failure to status.recentFailures, a failure like the latest one keeps it
+// as it is, only refreshing lastTimestamp every failureRefreshInterval, so an ACL failing on every
+// reconcile keeps its older failures and writes the same status
+func recordFailure(acl *v1alpha1.ACL, reason, message string, now time.Time) {
+	timestamp := now.UTC().Format(time.RFC3339)
+
+	failures := acl.Status.RecentFailures
+	if len(failures) > 0 {
+		last := &failures[len(failures)-1]
+		if last.Reason == reason && last.Message == message {
+			lastTimestamp, _ := time.Parse(time.RFC3339, last.LastTimestamp)
+			if now.Sub(lastTimestamp) >= failureRefreshInterval {
+				last.LastTimestamp = timestamp
+			}
+			return
+		}
+	}
+
+	failures = append(failures, v1alpha1.ACLStatusFailure{
+		Timestamp:     timestamp,
+		LastTimestamp: timestamp,
+		Reason:        reason,
+		Message:       message,
+	})
+	if len(failures) > maxRecentFailures {
+		failures = append([]v1alpha1.ACLStatusFailure{}, failures[len(failures)-maxRecentFailures:]...)
+	}
+	acl.Status.RecentFailures = failures
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+func TestRecordFailure(t *testing.T) {
+	acl := &v1alpha1.ACL{}
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	recordFailure(acl, eventReasonDestinationResolutionFailed, "could not resolve example.com", now)
+	recordFailure(acl, eventReasonDestinationResolutionFailed, "could not resolve example.com", now.Add(time.Minute))
+	expected := []v1alpha1.ACLStatusFailure{
+		{
+			Timestamp:     "2022-10-01T12:00:00Z",
+			LastTimestamp: "2022-10-01T12:00:00Z",
+			Reason:        eventReasonDestinationResolutionFailed,
+			Message:       "could not resolve example.com",
+		},
+	}
+	// the status is the same, so it does not enqueue the ACL again
+	assert.Equal(t, expected, acl.Status.RecentFailures)
+
+	recordFailure(acl, eventReasonDestinationResolutionFailed, "could not resolve example.com", now.Add(failureRefreshInterval))
+	expected[0].LastTimestamp = "2022-10-01T12:05:00Z"
+	assert.Equal(t, expected, acl.Status.RecentFailures)
+
+	for i := 0; i < 2*maxRecentFailures; i++ {
+		recordFailure(acl, eventReasonInvalidPolicyName, fmt.Sprintf("failure %d", i), now.Add(time.Duration(i+6)*time.Minute))
+	}
+	require.Len(t, acl.Status.RecentFailures, maxRecentFailures)
+	assert.Equal(t, "failure 5", acl.Status.RecentFailures[0].Message)
+	assert.Equal(t, "failure 9", acl.Status.RecentFailures[maxRecentFailures-1].Message)
+	assert.Equal(t, "2022-10-01T12:15:00Z", acl.Status.RecentFailures[maxRecentFailures-1].Timestamp)
+	assert.Equal(t, "2022-10-01T12:15:00Z", acl.Status.RecentFailures[maxRecentFailures-1].LastTimestamp)
+}
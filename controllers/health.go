@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/app"
+
+	"github.com/tsuru/acl-operator/clients/tsuruapi"
+)
+
+// DefaultConnectivityFailureWindow is the time Tsuru API and DNS may fail before the operator is not ready
+const DefaultConnectivityFailureWindow = 5 * time.Minute
+
+// ConnectivityTracker records the outcome of the calls to an external dependency,
+// no extra request is made, a single success clears the failure
+type ConnectivityTracker struct {
+	mu           sync.Mutex
+	failingSince time.Time
+	lastErr      error
+	now          func() time.Time
+}
+
+func (t *ConnectivityTracker) record(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err == nil {
+		t.failingSince = time.Time{}
+		t.lastErr = nil
+		return
+	}
+
+	if t.failingSince.IsZero() {
+		t.failingSince = t.timeNow()
+	}
+	t.lastErr = err
+}
+
+// FailingFor returns how long the calls are failing and the last error, zero when the last call succeeded
+func (t *ConnectivityTracker) FailingFor() (time.Duration, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.failingSince.IsZero() {
+		return 0, nil
+	}
+	return t.timeNow().Sub(t.failingSince), t.lastErr
+}
+
+func (t *ConnectivityTracker) timeNow() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now()
+}
+
+// ConnectivityCheck is a readiness check that fails when both Tsuru API and DNS resolution
+// are failing for longer than Window, a single failing dependency keeps the operator ready
+type ConnectivityCheck struct {
+	TsuruAPI *ConnectivityTracker
+	DNS      *ConnectivityTracker
+
+	// Window defaults to DefaultConnectivityFailureWindow
+	Window time.Duration
+}
+
+// Check implements healthz.Checker
+func (c *ConnectivityCheck) Check(_ *http.Request) error {
+	window := c.Window
+	if window <= 0 {
+		window = DefaultConnectivityFailureWindow
+	}
+
+	tsuruFailingFor, tsuruErr := c.TsuruAPI.FailingFor()
+	dnsFailingFor, dnsErr := c.DNS.FailingFor()
+
+	if tsuruFailingFor <= window || dnsFailingFor <= window {
+		return nil
+	}
+
+	return fmt.Errorf("tsuru API and DNS resolution are failing for more than %s, tsuru API: %v, DNS: %v", window, tsuruErr, dnsErr)
+}
+
+// TrackTsuruAPI records the connectivity of calls to client on tracker, responses of
+// Tsuru API that are not transient are successes
+func TrackTsuruAPI(client tsuruapi.Client, tracker *ConnectivityTracker) tsuruapi.Client {
+	return &trackedTsuruAPI{
+		Client:  client,
+		tracker: tracker,
+	}
+}
+
+type trackedTsuruAPI struct {
+	tsuruapi.Client
+	tracker *ConnectivityTracker
+}
+
+func (t *trackedTsuruAPI) AppInfo(ctx context.Context, appName string) (*app.App, error) {
+	appInfo, err := t.Client.AppInfo(ctx, appName)
+	t.tracker.record(tsuruConnectivityError(err))
+	return appInfo, err
+}
+
+func (t *trackedTsuruAPI) ServiceInstanceInfo(ctx context.Context, serviceName, instance string) (*tsuruapi.ServiceInstanceInfo, error) {
+	info, err := t.Client.ServiceInstanceInfo(ctx, serviceName, instance)
+	t.tracker.record(tsuruConnectivityError(err))
+	return info, err
+}
+
+func (t *trackedTsuruAPI) PoolApps(ctx context.Context, pool string) ([]string, error) {
+	apps, err := t.Client.PoolApps(ctx, pool)
+	t.tracker.record(tsuruConnectivityError(err))
+	return apps, err
+}
+
+func tsuruConnectivityError(err error) error {
+	if err == nil || !isTransientError(err) {
+		return nil
+	}
+	return err
+}
+
+// NewResolver returns the resolver used by controllers, the lookups that reach the
+// nameservers are recorded on tracker when it is not nil, cached answers are not
+func NewResolver(tracker *ConnectivityTracker) ACLDNSResolver {
+	return newResolver(&ttlResolver{Fallback: &net.Resolver{}}, tracker)
+}
+
+// NewNameserversResolver returns a resolver like NewResolver that queries nameservers instead of
+// the nameservers of resolv.conf, like tls:// and https:// nameservers of v1alpha1.ParseNameserver,
+// the system resolver is only used when fallback is true and none of them answers
+func NewNameserversResolver(tracker *ConnectivityTracker, nameservers []string, fallback bool) ACLDNSResolver {
+	resolver := &ttlResolver{Nameservers: nameservers}
+	if fallback {
+		resolver.Fallback = &net.Resolver{}
+	}
+	return newResolver(resolver, tracker)
+}
+
+func newResolver(resolver ACLDNSResolver, tracker *ConnectivityTracker) ACLDNSResolver {
+	if tracker != nil {
+		resolver = &trackedResolver{
+			Resolver: resolver,
+			tracker:  tracker,
+		}
+	}
+
+	return &cachingResolver{
+		Resolver:   resolver,
+		MaxEntries: defaultDNSCacheMaxEntries,
+	}
+}
+
+type trackedResolver struct {
+	Resolver ACLDNSResolver
+	tracker  *ConnectivityTracker
+}
+
+func (t *trackedResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	ipAddrs, _, err := t.LookupIPAddrTTL(ctx, host)
+	return ipAddrs, err
+}
+
+func (t *trackedResolver) LookupIPAddrTTL(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+	ipAddrs, ttl, err := lookupIPAddrTTL(ctx, t.Resolver, host)
+	t.tracker.record(dnsConnectivityError(err))
+	return ipAddrs, ttl, err
+}
+
+func (t *trackedResolver) LookupDNSAnswer(ctx context.Context, host string) (*DNSAnswer, error) {
+	answer, err := lookupDNSAnswer(ctx, t.Resolver, host)
+	t.tracker.record(dnsConnectivityError(err))
+	return answer, err
+}
+
+func (t *trackedResolver) LookupSRV(ctx context.Context, name string) ([]*net.SRV, error) {
+	srvs, err := lookupSRV(ctx, t.Resolver, name)
+	t.tracker.record(dnsConnectivityError(err))
+	return srvs, err
+}
+
+// dnsConnectivityError ignores hosts that do not exist, the nameserver answered the query
+func dnsConnectivityError(err error) error {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tsuru/acl-operator/clients/tsuruapi"
+)
+
+func TestConnectivityCheck(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	tsuruTracker := &ConnectivityTracker{now: clock}
+	dnsTracker := &ConnectivityTracker{now: clock}
+	check := &ConnectivityCheck{
+		TsuruAPI: tsuruTracker,
+		DNS:      dnsTracker,
+		Window:   time.Minute,
+	}
+
+	tsuruAPI := TrackTsuruAPI(&failingTsuruAPI{err: &tsuruapi.StatusError{StatusCode: http.StatusBadGateway}}, tsuruTracker)
+	_, err := tsuruAPI.AppInfo(context.Background(), "my-app")
+	require.Error(t, err)
+
+	resolver := &trackedResolver{
+		Resolver: &fakeResolver{errors: map[string]error{"myapp.io": errors.New("i/o timeout")}},
+		tracker:  dnsTracker,
+	}
+	_, err = resolver.LookupIPAddr(context.Background(), "myapp.io")
+	require.Error(t, err)
+
+	assert.NoError(t, check.Check(nil))
+
+	now = now.Add(2 * time.Minute)
+	assert.EqualError(t, check.Check(nil), "tsuru API and DNS resolution are failing for more than 1m0s, tsuru API: failed to request, status code: 502, DNS: i/o timeout")
+
+	// a host that does not exist is answered by the nameserver
+	resolver.Resolver = &fakeResolver{errors: map[string]error{"myapp.io": &net.DNSError{Err: "no such host", IsNotFound: true}}}
+	_, err = resolver.LookupIPAddr(context.Background(), "myapp.io")
+	require.Error(t, err)
+	assert.NoError(t, check.Check(nil))
+
+	failingFor, err := dnsTracker.FailingFor()
+	assert.Zero(t, failingFor)
+	assert.NoError(t, err)
+}
+
+func TestConnectivityCheckPermanentTsuruErrors(t *testing.T) {
+	tracker := &ConnectivityTracker{}
+	tsuruAPI := TrackTsuruAPI(&failingTsuruAPI{err: &tsuruapi.StatusError{StatusCode: http.StatusForbidden}}, tracker)
+
+	_, err := tsuruAPI.AppInfo(context.Background(), "my-app")
+	require.Error(t, err)
+
+	failingFor, err := tracker.FailingFor()
+	assert.Zero(t, failingFor)
+	assert.NoError(t, err)
+}
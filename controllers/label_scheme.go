@@ -0,0 +1,45 @@
+package controllers
+
+// LabelScheme holds the label keys that Tsuru sets on pods and objects, installations
+// of Tsuru with customized labels override them, empty keys use DefaultLabelScheme
+type LabelScheme struct {
+	AppName       string
+	AppProcess    string
+	AppPool       string
+	JobName       string
+	RpaasInstance string
+	RpaasService  string
+}
+
+// DefaultLabelScheme are the labels of a standard installation of Tsuru
+var DefaultLabelScheme = LabelScheme{
+	AppName:       "tsuru.io/app-name",
+	AppProcess:    "tsuru.io/app-process",
+	AppPool:       "tsuru.io/app-pool",
+	JobName:       "tsuru.io/job-name",
+	RpaasInstance: "rpaas.extensions.tsuru.io/instance-name",
+	RpaasService:  "rpaas.extensions.tsuru.io/service-name",
+}
+
+// WithDefaults returns the scheme with empty keys replaced by DefaultLabelScheme
+func (s LabelScheme) WithDefaults() LabelScheme {
+	if s.AppName == "" {
+		s.AppName = DefaultLabelScheme.AppName
+	}
+	if s.AppProcess == "" {
+		s.AppProcess = DefaultLabelScheme.AppProcess
+	}
+	if s.AppPool == "" {
+		s.AppPool = DefaultLabelScheme.AppPool
+	}
+	if s.JobName == "" {
+		s.JobName = DefaultLabelScheme.JobName
+	}
+	if s.RpaasInstance == "" {
+		s.RpaasInstance = DefaultLabelScheme.RpaasInstance
+	}
+	if s.RpaasService == "" {
+		s.RpaasService = DefaultLabelScheme.RpaasService
+	}
+	return s
+}
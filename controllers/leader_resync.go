@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+const (
+	// DefaultResyncDelay leaves time for the controllers of a new leader to start and reconcile the
+	// objects of their initial listing before the resync
+	DefaultResyncDelay = 30 * time.Second
+
+	// DefaultResyncStagger spreads the objects of a resync over 5 minutes
+	DefaultResyncStagger = 5 * time.Minute
+
+	// resyncJitter spreads the wait between the objects of a resync by up to half of it
+	resyncJitter = 0.5
+)
+
+// LeaderResync enqueues every ACL and address object once the replica becomes the leader, and every
+// Interval after, so no object waits for a requeue that was scheduled by the previous leader. The
+// objects are spread over Stagger, so the resync does not burst on Tsuru API and on the resolvers
+type LeaderResync struct {
+	Client  client.Reader
+	Refresh *RefreshTrigger
+
+	// Selector restricts the resynced ACLs to the shard of the operator, like ACLReconciler.Selector
+	Selector labels.Selector
+
+	// AddressObjects resyncs the address objects as well, they are reconciled only by the instance
+	// that manages the shared objects
+	AddressObjects bool
+
+	// Delay is the wait before the first resync, defaults to DefaultResyncDelay
+	Delay time.Duration
+
+	// Stagger is the time the objects of a resync are spread over, defaults to DefaultResyncStagger
+	Stagger time.Duration
+
+	// Interval resyncs again periodically, there is only the resync after becoming the leader when zero
+	Interval time.Duration
+
+	Logger logr.Logger
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the resync starts along with the
+// controllers of the leader
+func (r *LeaderResync) NeedLeaderElection() bool {
+	return true
+}
+
+func (r *LeaderResync) Start(ctx context.Context) error {
+	delay := r.Delay
+	if delay == 0 {
+		delay = DefaultResyncDelay
+	}
+
+	for {
+		if !sleepContext(ctx, delay) {
+			return nil
+		}
+
+		err := r.resync(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.Logger.Error(err, "could not resync objects")
+		}
+
+		if r.Interval <= 0 {
+			<-ctx.Done()
+			return nil
+		}
+		delay = r.Interval
+	}
+}
+
+type resyncObject struct {
+	events chan event.GenericEvent
+	obj    client.Object
+}
+
+// resync enqueues the objects, the address objects first, so ACLs find their addresses resolved again
+func (r *LeaderResync) resync(ctx context.Context) error {
+	objects := []resyncObject{}
+
+	if r.AddressObjects {
+		addressLists := []struct {
+			list   client.ObjectList
+			events chan event.GenericEvent
+		}{
+			{&v1alpha1.ACLDNSEntryList{}, r.Refresh.dnsEntries},
+			{&v1alpha1.TsuruAppAddressList{}, r.Refresh.tsuruAppAddresses},
+			{&v1alpha1.RpaasInstanceAddressList{}, r.Refresh.rpaasInstanceAddresses},
+			{&v1alpha1.TsuruServiceInstanceAddressList{}, r.Refresh.serviceInstanceAddresses},
+		}
+		for _, addressList := range addressLists {
+			err := r.Client.List(ctx, addressList.list)
+			if err != nil {
+				return err
+			}
+			listObjects, err := metaObjects(addressList.list)
+			if err != nil {
+				return err
+			}
+			for _, obj := range listObjects {
+				objects = append(objects, resyncObject{events: addressList.events, obj: obj})
+			}
+		}
+	}
+
+	acls := &v1alpha1.ACLList{}
+	listOptions := &client.ListOptions{}
+	if r.Selector != nil {
+		listOptions.LabelSelector = r.Selector
+	}
+	err := r.Client.List(ctx, acls, listOptions)
+	if err != nil {
+		return err
+	}
+	for i := range acls.Items {
+		objects = append(objects, resyncObject{events: r.Refresh.acls, obj: &acls.Items[i]})
+	}
+
+	if len(objects) == 0 {
+		return nil
+	}
+
+	stagger := r.Stagger
+	if stagger == 0 {
+		stagger = DefaultResyncStagger
+	}
+	step := stagger / time.Duration(len(objects))
+
+	r.Logger.Info("resyncing objects", "objects", len(objects), "stagger", stagger.String())
+	for i, object := range objects {
+		if i > 0 && !sleepContext(ctx, jitterRequeue(step, resyncJitter)) {
+			return ctx.Err()
+		}
+
+		// unlike the refreshes of AdminServer, the resync waits for room on a full queue
+		select {
+		case object.events <- event.GenericEvent{Object: object.obj}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// sleepContext waits for d, returns false when ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/tsuru/acl-operator/api/scheme"
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+func drainRefreshes(events chan event.GenericEvent) []string {
+	names := []string{}
+	for {
+		select {
+		case e := <-events:
+			names = append(names, e.Object.GetName())
+		default:
+			return names
+		}
+	}
+}
+
+func TestLeaderResync(t *testing.T) {
+	objects := []client.Object{
+		&v1alpha1.ACL{ObjectMeta: v1.ObjectMeta{Name: "acl-a", Namespace: "default", Labels: map[string]string{"shard": "a"}}},
+		&v1alpha1.ACL{ObjectMeta: v1.ObjectMeta{Name: "acl-b", Namespace: "default", Labels: map[string]string{"shard": "b"}}},
+		&v1alpha1.ACLDNSEntry{ObjectMeta: v1.ObjectMeta{Name: "example.com"}},
+		&v1alpha1.TsuruAppAddress{ObjectMeta: v1.ObjectMeta{Name: "my-app"}},
+		&v1alpha1.RpaasInstanceAddress{ObjectMeta: v1.ObjectMeta{Name: "rpaasv2-my-instance"}},
+		&v1alpha1.TsuruServiceInstanceAddress{ObjectMeta: v1.ObjectMeta{Name: "mysql-db"}},
+	}
+
+	refresh := NewRefreshTrigger()
+	resync := &LeaderResync{
+		Client:         fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build(),
+		Refresh:        refresh,
+		Selector:       labels.SelectorFromSet(labels.Set{"shard": "a"}),
+		AddressObjects: true,
+		Stagger:        50 * time.Millisecond,
+		Logger:         logr.Discard(),
+	}
+	assert.True(t, resync.NeedLeaderElection())
+
+	start := time.Now()
+	err := resync.resync(context.Background())
+	require.NoError(t, err)
+	// 5 objects wait 4 steps of 10ms, with a jitter of up to half of a step
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	// only the ACLs of the shard
+	assert.Equal(t, []string{"acl-a"}, drainRefreshes(refresh.acls))
+	assert.Equal(t, []string{"example.com"}, drainRefreshes(refresh.dnsEntries))
+	assert.Equal(t, []string{"my-app"}, drainRefreshes(refresh.tsuruAppAddresses))
+	assert.Equal(t, []string{"rpaasv2-my-instance"}, drainRefreshes(refresh.rpaasInstanceAddresses))
+	assert.Equal(t, []string{"mysql-db"}, drainRefreshes(refresh.serviceInstanceAddresses))
+
+	// address objects are left to the instance that manages them
+	resync.AddressObjects = false
+	resync.Selector = nil
+	err = resync.resync(context.Background())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"acl-a", "acl-b"}, drainRefreshes(refresh.acls))
+	assert.Empty(t, drainRefreshes(refresh.dnsEntries))
+}
+
+func TestLeaderResyncStart(t *testing.T) {
+	refresh := NewRefreshTrigger()
+	resync := &LeaderResync{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+			&v1alpha1.ACL{ObjectMeta: v1.ObjectMeta{Name: "acl-a", Namespace: "default"}},
+		).Build(),
+		Refresh:  refresh,
+		Delay:    time.Millisecond,
+		Stagger:  time.Millisecond,
+		Interval: 10 * time.Millisecond,
+		Logger:   logr.Discard(),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- resync.Start(ctx)
+	}()
+
+	// the first resync after becoming the leader and a periodic one
+	for i := 0; i < 2; i++ {
+		select {
+		case e := <-refresh.acls:
+			assert.Equal(t, "acl-a", e.Object.GetName())
+		case <-time.After(5 * time.Second):
+			t.Fatal("ACL was not resynced")
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("resync did not stop")
+	}
+}
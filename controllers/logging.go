@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// logLevelAnnotation raises the log verbosity of reconciles of the annotated object,
+// the value is the V level enabled, e.g. "1" logs l.V(1) messages
+const logLevelAnnotation = "acl.extensions.tsuru.io/log-level"
+
+// objectLogger returns ctx with a logger carrying keysAndValues, the verbosity of the logger is
+// raised by the log-level annotation of obj, loggers of other objects are not affected.
+// The name and namespace of obj are already added by controller-runtime
+func objectLogger(ctx context.Context, obj client.Object, keysAndValues ...interface{}) (context.Context, logr.Logger) {
+	l := log.FromContext(ctx).WithValues(keysAndValues...)
+
+	if value, ok := obj.GetAnnotations()[logLevelAnnotation]; ok {
+		level, err := strconv.Atoi(value)
+		if err != nil || level < 0 {
+			l.Info("ignoring invalid log level annotation", "annotation", logLevelAnnotation, "value", value)
+		} else if level > 0 && l.GetSink() != nil {
+			l = l.WithSink(newVerbositySink(l.GetSink(), level))
+		}
+	}
+
+	return log.IntoContext(ctx, l), l
+}
+
+// verbositySink logs messages of V level up to boost as if they were logged by l.Info
+type verbositySink struct {
+	sink  logr.LogSink
+	boost int
+}
+
+var _ logr.LogSink = &verbositySink{}
+
+func newVerbositySink(sink logr.LogSink, boost int) logr.LogSink {
+	if s, ok := sink.(*verbositySink); ok {
+		return &verbositySink{sink: s.sink, boost: boost}
+	}
+	// messages are attributed to the caller of Logger.Info instead of verbositySink
+	if callDepthSink, ok := sink.(logr.CallDepthLogSink); ok {
+		sink = callDepthSink.WithCallDepth(1)
+	}
+	return &verbositySink{sink: sink, boost: boost}
+}
+
+func (s *verbositySink) level(level int) int {
+	if level -= s.boost; level < 0 {
+		return 0
+	}
+	return level
+}
+
+func (s *verbositySink) Init(info logr.RuntimeInfo) {
+	s.sink.Init(info)
+}
+
+func (s *verbositySink) Enabled(level int) bool {
+	return s.sink.Enabled(s.level(level))
+}
+
+func (s *verbositySink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.sink.Info(s.level(level), msg, keysAndValues...)
+}
+
+func (s *verbositySink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.sink.Error(err, msg, keysAndValues...)
+}
+
+func (s *verbositySink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &verbositySink{sink: s.sink.WithValues(keysAndValues...), boost: s.boost}
+}
+
+func (s *verbositySink) WithName(name string) logr.LogSink {
+	return &verbositySink{sink: s.sink.WithName(name), boost: s.boost}
+}
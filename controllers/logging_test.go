@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+func TestObjectLogger(t *testing.T) {
+	lines := []string{}
+	baseLogger := funcr.New(func(prefix, args string) {
+		lines = append(lines, args)
+	}, funcr.Options{Verbosity: 0})
+	ctx := log.IntoContext(context.Background(), baseLogger)
+
+	debugACL := &v1alpha1.ACL{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "debug",
+			Annotations: map[string]string{logLevelAnnotation: "1"},
+		},
+	}
+	quietACL := &v1alpha1.ACL{
+		ObjectMeta: metav1.ObjectMeta{Name: "quiet"},
+	}
+
+	debugCtx, debugLogger := objectLogger(ctx, debugACL, "destinations", 2)
+	_, quietLogger := objectLogger(ctx, quietACL, "destinations", 1)
+
+	debugLogger.V(1).Info("debug message")
+	debugLogger.V(2).Info("too verbose message")
+	log.FromContext(debugCtx).WithValues("egressRules", 3).V(1).Info("message from context")
+	quietLogger.V(1).Info("hidden message")
+	quietLogger.Info("info message")
+	baseLogger.V(1).Info("hidden base message")
+
+	assert.Equal(t, []string{
+		`"level"=0 "msg"="debug message" "destinations"=2`,
+		`"level"=0 "msg"="message from context" "destinations"=2 "egressRules"=3`,
+		`"level"=0 "msg"="info message" "destinations"=1`,
+	}, lines)
+
+	lines = nil
+	invalidACL := &v1alpha1.ACL{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "invalid",
+			Annotations: map[string]string{logLevelAnnotation: "debug"},
+		},
+	}
+	_, invalidLogger := objectLogger(ctx, invalidACL)
+	invalidLogger.V(1).Info("hidden message")
+
+	assert.Equal(t, []string{
+		`"level"=0 "msg"="ignoring invalid log level annotation" "annotation"="acl.extensions.tsuru.io/log-level" "value"="debug"`,
+	}, lines)
+}
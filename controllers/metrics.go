@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+const (
+	reconcileResultCreated = "created"
+	reconcileResultUpdated = "updated"
+	reconcileResultNoop    = "noop"
+	reconcileResultError   = "error"
+	reconcileResultPaused  = "paused"
+	// reconcileResultDeferred is an ACL requeued while ACLs with a higher priority are pending,
+	// or an ACLSummary requeued until the interval since its last count is over
+	reconcileResultDeferred = "deferred"
+	// reconcileResultIgnored is an ACL that does not match the selector of the operator
+	reconcileResultIgnored = "ignored"
+	// reconcileResultConflict is a policy changed by another writer while it was applied
+	reconcileResultConflict = "conflict"
+)
+
+var (
+	reconcileResultsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "acl_operator_reconcile_results_total",
+		Help: "Number of reconciles by controller and result (created, updated, noop, error, paused, deferred, ignored, conflict)",
+	}, []string{"controller", "result"})
+
+	destinationDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "acl_operator_destination_duration_seconds",
+		Help:    "Time spent to generate the egress rules of a destination",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 8),
+	}, []string{"type"})
+
+	dnsLookupFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "acl_operator_dns_lookup_failures_total",
+		Help: "Number of failed DNS lookups by hostname",
+	}, []string{"host"})
+
+	dnsEntryResolvedIPs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "acl_operator_dns_entry_resolved_ips",
+		Help: "Number of IPs on status of an ACLDNSEntry",
+	}, []string{"name"})
+
+	tsuruAppAddressResolvedIPs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "acl_operator_tsuru_app_address_resolved_ips",
+		Help: "Number of router IPs on status of a TsuruAppAddress",
+	}, []string{"name"})
+
+	tsuruAppAddressLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "acl_operator_tsuru_app_address_last_success_timestamp_seconds",
+		Help: "Unix time of the last reconcile of a TsuruAppAddress that got the app from Tsuru API and resolved its addresses",
+	}, []string{"name"})
+
+	tsuruAPIEndpointActive = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "acl_operator_tsuru_api_endpoint_active",
+		Help: "Set to 1 for the Tsuru API endpoint that answered the last call when many endpoints are configured",
+	}, []string{"endpoint"})
+
+	tsuruAPIEndpointFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "acl_operator_tsuru_api_endpoint_failures_total",
+		Help: "Total number of calls to a Tsuru API endpoint that failed with a transient error",
+	}, []string{"endpoint"})
+)
+
+func init() {
+	// controller-runtime serves this registry on the metrics endpoint of manager
+	metrics.Registry.MustRegister(
+		reconcileResultsTotal,
+		destinationDurationSeconds,
+		dnsLookupFailuresTotal,
+		dnsEntryResolvedIPs,
+		tsuruAppAddressResolvedIPs,
+		tsuruAppAddressLastSuccess,
+		tsuruAPIEndpointActive,
+		tsuruAPIEndpointFailuresTotal,
+	)
+}
+
+func observeReconcileResult(controller, result string, err error) {
+	if err != nil {
+		result = reconcileResultError
+	}
+
+	reconcileResultsTotal.WithLabelValues(controller, result).Inc()
+}
+
+func observeDestinationDuration(destination v1alpha1.ACLSpecDestination, start time.Time) {
+	destinationDurationSeconds.WithLabelValues(destinationType(destination)).Observe(time.Since(start).Seconds())
+}
+
+func destinationType(destination v1alpha1.ACLSpecDestination) string {
+	if destination.TsuruApp != "" {
+		return "tsuruApp"
+	} else if destination.TsuruAppPool != "" {
+		return "tsuruAppPool"
+	} else if destination.ExternalDNS != nil {
+		return "externalDNS"
+	} else if destination.ExternalIP != nil {
+		return "externalIP"
+	} else if len(destination.ExternalEndpoints) > 0 {
+		return "externalEndpoints"
+	} else if destination.ExternalSRV != nil {
+		return "externalSRV"
+	} else if destination.KubernetesService != nil {
+		return "kubernetesService"
+	} else if destination.Deny != nil {
+		return "deny"
+	} else if destination.RpaasInstance != nil {
+		return "rpaasInstance"
+	} else if destination.TsuruServiceInstance != nil {
+		return "tsuruServiceInstance"
+	} else if destination.ConfigMap != nil {
+		return "configMap"
+	}
+	return "unknown"
+}
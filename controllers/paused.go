@@ -0,0 +1,16 @@
+package controllers
+
+import (
+	"strconv"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// pausedAnnotation stops the reconciles of the annotated object, like an ACL being debugged by
+// hand, removing the annotation resumes the reconciles on the next event of the object
+const pausedAnnotation = "acl.extensions.tsuru.io/paused"
+
+func isPaused(obj client.Object) bool {
+	paused, _ := strconv.ParseBool(obj.GetAnnotations()[pausedAnnotation])
+	return paused
+}
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+)
+
+const allPortsDescription = "all ports"
+
+// destinationEgressRules are the rules generated by a destination of spec.destinations
+type destinationEgressRules struct {
+	destination string
+	rules       []netv1.NetworkPolicyEgressRule
+}
+
+type peerPorts struct {
+	destination string
+	ports       string
+}
+
+// overlappingPortsWarnings reports peers allowed by more than one destination with different ports,
+// the rules of a NetworkPolicy are additive, so the policy allows the union of their ports to the peer
+func overlappingPortsWarnings(destinations []destinationEgressRules) []string {
+	byPeer := map[string][]peerPorts{}
+	for _, destination := range destinations {
+		portsByPeer := map[string]map[string]bool{}
+		for _, rule := range destination.rules {
+			ports := describePorts(rule.Ports)
+			for _, peer := range rule.To {
+				key := describePeer(peer)
+				if portsByPeer[key] == nil {
+					portsByPeer[key] = map[string]bool{}
+				}
+				portsByPeer[key][ports] = true
+			}
+		}
+
+		for key, ports := range portsByPeer {
+			byPeer[key] = append(byPeer[key], peerPorts{
+				destination: destination.destination,
+				ports:       joinPortDescriptions(ports),
+			})
+		}
+	}
+
+	peers := make([]string, 0, len(byPeer))
+	for key := range byPeer {
+		peers = append(peers, key)
+	}
+	sort.Strings(peers)
+
+	var warnings []string
+	for _, key := range peers {
+		allowed := byPeer[key]
+		if len(allowed) < 2 || !differentPorts(allowed) {
+			continue
+		}
+
+		parts := make([]string, len(allowed))
+		for i, peerPorts := range allowed {
+			parts[i] = "by " + peerPorts.destination + " on " + peerPorts.ports
+		}
+		warnings = append(warnings, fmt.Sprintf("%s is allowed %s, the policy allows the union of their ports", key, strings.Join(parts, " and ")))
+	}
+
+	return warnings
+}
+
+func differentPorts(allowed []peerPorts) bool {
+	for _, peerPorts := range allowed[1:] {
+		if peerPorts.ports != allowed[0].ports {
+			return true
+		}
+	}
+	return false
+}
+
+// joinPortDescriptions collapses the ports of a peer into all ports when any rule does not restrict them
+func joinPortDescriptions(ports map[string]bool) string {
+	if ports[allPortsDescription] {
+		return allPortsDescription
+	}
+
+	descriptions := make([]string, 0, len(ports))
+	for description := range ports {
+		descriptions = append(descriptions, description)
+	}
+	sort.Strings(descriptions)
+	return strings.Join(descriptions, ",")
+}
+
+func describePeer(peer netv1.NetworkPolicyPeer) string {
+	if peer.IPBlock == nil {
+		return describePeerSelector(peer)
+	}
+
+	cidr := peer.IPBlock.CIDR
+	if len(peer.IPBlock.Except) > 0 {
+		cidr += " except " + strings.Join(peer.IPBlock.Except, ",")
+	}
+	return cidr
+}
+
+// describePorts returns the ports as TCP/443,UDP/53 or TCP/8000-8080, a port without number
+// allows every port of its protocol
+func describePorts(ports []netv1.NetworkPolicyPort) string {
+	if len(ports) == 0 {
+		return allPortsDescription
+	}
+
+	descriptions := make([]string, 0, len(ports))
+	for _, port := range ports {
+		protocol := corev1.ProtocolTCP
+		if port.Protocol != nil {
+			protocol = *port.Protocol
+		}
+
+		description := string(protocol)
+		if port.Port != nil {
+			description += "/" + port.Port.String()
+			if port.EndPort != nil {
+				description += "-" + strconv.Itoa(int(*port.EndPort))
+			}
+		}
+		descriptions = append(descriptions, description)
+	}
+	sort.Strings(descriptions)
+
+	return strings.Join(descriptions, ",")
+}
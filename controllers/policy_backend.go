@@ -0,0 +1,403 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	netv1 "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1alpha1 "github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+
+const (
+	PolicyBackendKubernetes = "kubernetes"
+	PolicyBackendCilium     = "cilium"
+
+	// policyManagedMetadataAnnotation records the keys written by spec.template of ACL on policies
+	// written without server-side apply, so keys removed from the template are removed from the
+	// policy without touching keys of others
+	policyManagedMetadataAnnotation = "acl.extensions.tsuru.io/managed-metadata"
+
+	// policyManagedByLabel is stamped on every policy written by the operator, policies
+	// without it nor an owner reference to the ACL were created by someone else
+	policyManagedByLabel = "app.kubernetes.io/managed-by"
+	policyManagedByValue = "acl-operator"
+
+	// policyACLLabel holds the name of the ACL that generated a policy
+	policyACLLabel = "acl.extensions.tsuru.io/acl"
+
+	// policyFieldOwner is the field manager of the server-side apply of policies
+	policyFieldOwner = "acl-operator"
+)
+
+// aclPolicy is the desired policy of an ACL, independent of the backend that writes it
+type aclPolicy struct {
+	Name        string
+	PodSelector metav1.LabelSelector
+	Egress      []netv1.NetworkPolicyEgressRule
+	Ingress     []netv1.NetworkPolicyIngressRule
+
+	// FQDNs are only filled when the backend supports FQDN, otherwise they are resolved to egress rules
+	FQDNs []v1alpha1.ACLSpecExternalDNS
+
+	Metadata v1alpha1.ACLSpecTemplateMetadata
+
+	// TakeOver overwrites an existing policy with the same name that was not written by the operator
+	TakeOver bool
+}
+
+// applyResult is the outcome of PolicyBackend.Apply
+type applyResult struct {
+	// outcome is reconcileResultCreated, reconcileResultUpdated or reconcileResultNoop
+	outcome string
+	// egressDiff are the changes of egress rules of an updated policy, nil when the backend does not compare them
+	egressDiff *egressRulesDiff
+}
+
+// PolicyBackend writes the policy generated by an ACL
+type PolicyBackend interface {
+	// Name is recorded on status.policyBackend of ACL
+	Name() string
+	// Kind of the object written by the backend
+	Kind() string
+	// NewObject returns an empty object of Kind, used to watch the owned policies
+	NewObject() client.Object
+	// SupportsFQDN means that externalDNS destinations are sent without resolution
+	SupportsFQDN() bool
+	// Apply creates or updates the policy
+	Apply(ctx context.Context, acl *v1alpha1.ACL, policy *aclPolicy) (applyResult, error)
+	// Diff returns the changes that Apply would write, empty when the policy is up to date
+	Diff(ctx context.Context, acl *v1alpha1.ACL, policy *aclPolicy) (string, error)
+	// Render returns the object that Apply writes for a new policy, without reading the cluster
+	Render(acl *v1alpha1.ACL, policy *aclPolicy) (client.Object, error)
+}
+
+func PolicyBackendByName(name string, c client.Client) (PolicyBackend, error) {
+	switch name {
+	case "", PolicyBackendKubernetes:
+		return &kubernetesPolicyBackend{Client: c}, nil
+	case PolicyBackendCilium:
+		return &ciliumPolicyBackend{Client: c}, nil
+	}
+
+	return nil, fmt.Errorf("unknown policy backend %q, use %s or %s", name, PolicyBackendKubernetes, PolicyBackendCilium)
+}
+
+func (r *ACLReconciler) policyBackend() PolicyBackend {
+	if r.PolicyBackend != nil {
+		return r.PolicyBackend
+	}
+
+	return &kubernetesPolicyBackend{Client: r.Client}
+}
+
+// kubernetesPolicyBackend writes plain kubernetes NetworkPolicies
+type kubernetesPolicyBackend struct {
+	client.Client
+}
+
+func (b *kubernetesPolicyBackend) Name() string {
+	return PolicyBackendKubernetes
+}
+
+func (b *kubernetesPolicyBackend) Kind() string {
+	return "NetworkPolicy"
+}
+
+func (b *kubernetesPolicyBackend) NewObject() client.Object {
+	return &netv1.NetworkPolicy{}
+}
+
+func (b *kubernetesPolicyBackend) SupportsFQDN() bool {
+	return false
+}
+
+// Apply writes the policy with server-side apply, the fields of policy are owned by policyFieldOwner and
+// labels or annotations removed from the template are removed by the API server, fields of others are kept
+func (b *kubernetesPolicyBackend) Apply(ctx context.Context, acl *v1alpha1.ACL, policy *aclPolicy) (applyResult, error) {
+	existingPolicy := &netv1.NetworkPolicy{}
+	err := b.Client.Get(ctx, client.ObjectKey{
+		Namespace: acl.Namespace,
+		Name:      policy.Name,
+	}, existingPolicy)
+
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return applyResult{}, errors.Wrap(err, "could not get NetworkPolicy object")
+	}
+	networkPolicyExists := err == nil
+
+	if networkPolicyExists && !policy.TakeOver {
+		err = checkPolicyOwnership(b.Kind(), existingPolicy, acl)
+		if err != nil {
+			return applyResult{}, err
+		}
+	}
+
+	networkPolicy := b.networkPolicy(acl, policy)
+	if controller := metav1.GetControllerOfNoCopy(existingPolicy); controller != nil && controller.UID != acl.UID {
+		// a policy has a single controller, the ACL is kept as a regular owner for GC
+		networkPolicy.OwnerReferences[0].Controller = nil
+		networkPolicy.OwnerReferences[0].BlockOwnerDeletion = nil
+	}
+
+	err = b.Client.Patch(ctx, networkPolicy, client.Apply, client.FieldOwner(policyFieldOwner), client.ForceOwnership)
+	if err != nil {
+		return applyResult{}, errors.Wrap(err, "could not apply NetworkPolicy object")
+	}
+
+	if !networkPolicyExists {
+		return applyResult{outcome: reconcileResultCreated}, nil
+	}
+
+	// the API server keeps the resourceVersion when the applied fields did not change
+	if networkPolicy.ResourceVersion != existingPolicy.ResourceVersion {
+		return applyResult{
+			outcome:    reconcileResultUpdated,
+			egressDiff: diffEgressRules(existingPolicy.Spec.Egress, networkPolicy.Spec.Egress),
+		}, nil
+	}
+
+	return applyResult{outcome: reconcileResultNoop}, nil
+}
+
+func (b *kubernetesPolicyBackend) Render(acl *v1alpha1.ACL, policy *aclPolicy) (client.Object, error) {
+	return b.networkPolicy(acl, policy), nil
+}
+
+// networkPolicy is the NetworkPolicy applied for policy, controlled by acl
+func (b *kubernetesPolicyBackend) networkPolicy(acl *v1alpha1.ACL, policy *aclPolicy) *netv1.NetworkPolicy {
+	return &netv1.NetworkPolicy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: netv1.SchemeGroupVersion.String(),
+			Kind:       b.Kind(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       acl.Namespace,
+			Name:            policy.Name,
+			Labels:          policyLabels(acl, policy.Metadata),
+			Annotations:     policy.Metadata.Annotations,
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(acl, acl.GroupVersionKind())},
+		},
+		Spec: netv1.NetworkPolicySpec{
+			PodSelector: policy.PodSelector,
+			PolicyTypes: policyTypesForACL(acl),
+			Egress:      policy.Egress,
+			Ingress:     policy.Ingress,
+		},
+	}
+}
+
+// ensureControllerRef makes acl the controller of a policy without controller, changes on the policy
+// only enqueue the ACL through the Owns watch when the ACL is its controller, other owners are kept
+func ensureControllerRef(policy metav1.Object, acl *v1alpha1.ACL) bool {
+	if metav1.GetControllerOfNoCopy(policy) != nil {
+		return false
+	}
+
+	policy.SetOwnerReferences(append(policy.GetOwnerReferences(), *metav1.NewControllerRef(acl, acl.GroupVersionKind())))
+	return true
+}
+
+// policyConflictError is returned when the policy of an ACL already exists and was not written by the operator
+type policyConflictError struct {
+	kind string
+	name string
+}
+
+func (e *policyConflictError) Error() string {
+	return fmt.Sprintf("%s %s already exists and is not managed by acl-operator, it is not overwritten, remove it or set --force-policy-ownership to take it over", e.kind, e.name)
+}
+
+// checkPolicyOwnership refuses policies that neither have the label of operator nor an owner
+// reference to acl, policies written by older versions of the operator only have the owner reference
+func checkPolicyOwnership(kind string, policy metav1.Object, acl *v1alpha1.ACL) error {
+	if policy.GetLabels()[policyManagedByLabel] == policyManagedByValue {
+		return nil
+	}
+
+	for _, ref := range policy.GetOwnerReferences() {
+		if ref.Kind == "ACL" && ref.Name == acl.Name && ref.UID == acl.UID {
+			return nil
+		}
+	}
+
+	return &policyConflictError{
+		kind: kind,
+		name: policy.GetName(),
+	}
+}
+
+// policyLabels are the labels of the template of ACL and the labels stamped by the operator
+func policyLabels(acl *v1alpha1.ACL, metadata v1alpha1.ACLSpecTemplateMetadata) map[string]string {
+	labels := make(map[string]string, len(metadata.Labels)+2)
+	for key, value := range metadata.Labels {
+		labels[key] = value
+	}
+	labels[policyManagedByLabel] = policyManagedByValue
+	labels[policyACLLabel] = policyACLLabelValue(acl.Name)
+	return labels
+}
+
+// policyACLLabelValue keeps the name of ACL within the maximum length of label values
+func policyACLLabelValue(name string) string {
+	if len(name) <= validation.LabelValueMaxLength {
+		return name
+	}
+
+	return strings.TrimRight(name[:validation.LabelValueMaxLength-11], "-.") + "-" + sha256String(name)[:10]
+}
+
+// ensurePolicyLabels stamps the labels of operator on policies written without server-side apply
+func ensurePolicyLabels(policy metav1.Object, acl *v1alpha1.ACL) bool {
+	labels := policy.GetLabels()
+	aclLabel := policyACLLabelValue(acl.Name)
+	if labels[policyManagedByLabel] == policyManagedByValue && labels[policyACLLabel] == aclLabel {
+		return false
+	}
+
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[policyManagedByLabel] = policyManagedByValue
+	labels[policyACLLabel] = aclLabel
+	policy.SetLabels(labels)
+	return true
+}
+
+func (b *kubernetesPolicyBackend) Diff(ctx context.Context, acl *v1alpha1.ACL, policy *aclPolicy) (string, error) {
+	networkPolicy := &netv1.NetworkPolicy{}
+	err := b.Client.Get(ctx, client.ObjectKey{
+		Namespace: acl.Namespace,
+		Name:      policy.Name,
+	}, networkPolicy)
+
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return "", errors.Wrap(err, "could not get NetworkPolicy object")
+	}
+
+	desiredSpec := netv1.NetworkPolicySpec{
+		PodSelector: policy.PodSelector,
+		PolicyTypes: policyTypesForACL(acl),
+		Egress:      policy.Egress,
+		Ingress:     policy.Ingress,
+	}
+
+	return policyDiff(networkPolicy.Spec, desiredSpec)
+}
+
+type managedMetadata struct {
+	Labels      []string `json:"labels,omitempty"`
+	Annotations []string `json:"annotations,omitempty"`
+}
+
+// mergePolicyMetadata writes the labels and annotations of metadata on obj and removes the ones
+// written by a previous metadata, returns whether obj has changed
+func mergePolicyMetadata(obj metav1.Object, metadata v1alpha1.ACLSpecTemplateMetadata) bool {
+	previous := managedMetadata{}
+	if value := obj.GetAnnotations()[policyManagedMetadataAnnotation]; value != "" {
+		// an invalid value only prevents the removal of old keys
+		_ = json.Unmarshal([]byte(value), &previous)
+	}
+
+	desiredAnnotations := map[string]string{}
+	for key, value := range metadata.Annotations {
+		if key != policyManagedMetadataAnnotation {
+			desiredAnnotations[key] = value
+		}
+	}
+
+	current := managedMetadata{
+		Labels:      sortedKeys(metadata.Labels),
+		Annotations: sortedKeys(desiredAnnotations),
+	}
+	if len(current.Labels) > 0 || len(current.Annotations) > 0 {
+		value, _ := json.Marshal(current)
+		desiredAnnotations[policyManagedMetadataAnnotation] = string(value)
+	}
+	previous.Annotations = append(previous.Annotations, policyManagedMetadataAnnotation)
+
+	labels, labelsChanged := mergeMetadataMap(obj.GetLabels(), metadata.Labels, previous.Labels)
+	annotations, annotationsChanged := mergeMetadataMap(obj.GetAnnotations(), desiredAnnotations, previous.Annotations)
+
+	if labelsChanged {
+		obj.SetLabels(labels)
+	}
+	if annotationsChanged {
+		obj.SetAnnotations(annotations)
+	}
+
+	return labelsChanged || annotationsChanged
+}
+
+func mergeMetadataMap(existing, desired map[string]string, previousKeys []string) (map[string]string, bool) {
+	result := make(map[string]string, len(existing)+len(desired))
+	for key, value := range existing {
+		result[key] = value
+	}
+
+	for _, key := range previousKeys {
+		if _, ok := desired[key]; !ok {
+			delete(result, key)
+		}
+	}
+
+	for key, value := range desired {
+		result[key] = value
+	}
+
+	if len(result) == 0 {
+		result = nil
+	}
+
+	changed := len(result) != len(existing) || (len(result) > 0 && !reflect.DeepEqual(result, existing))
+	return result, changed
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func policyTypesForACL(acl *v1alpha1.ACL) []netv1.PolicyType {
+	if len(acl.Spec.Ingress) > 0 {
+		return desiredPolicyTypeWithIngress
+	}
+	return desiredPolicyType
+}
+
+// policyDiff returns an unified diff of the indented JSON of specs
+func policyDiff(existing, desired interface{}) (string, error) {
+	existingJSON, err := json.MarshalIndent(existing, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	desiredJSON, err := json.MarshalIndent(desired, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(existingJSON)),
+		B:        difflib.SplitLines(string(desiredJSON)),
+		FromFile: "existing",
+		ToFile:   "desired",
+		Context:  3,
+	})
+}
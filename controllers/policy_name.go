@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+// DefaultPolicyNameTemplate is the name of the policies of ACLs, like acl-myapp
+const DefaultPolicyNameTemplate = "acl-{{.Name}}"
+
+// policyNameData are the fields of an ACL available to the policy name template
+type policyNameData struct {
+	Name      string
+	Namespace string
+	Labels    map[string]string
+}
+
+// ParsePolicyNameTemplate parses a text/template rendering the name of the policy of an ACL from
+// its Name, Namespace and Labels, like "{{.Name}}-egress", missing labels render empty. The
+// template is run on a sample ACL, so unknown fields are refused upfront
+func ParsePolicyNameTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("policy-name").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tmpl.Execute(io.Discard, policyNameData{Name: "sample", Namespace: "default"})
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// policyName is the name of the policy of an ACL without one on status, the rendered name is
+// made a valid resource name by validResourceName
+func (r *ACLReconciler) policyName(acl *v1alpha1.ACL) (string, error) {
+	tmpl := r.PolicyNameTemplate
+	if tmpl == nil {
+		return validResourceName("acl-" + acl.Name), nil
+	}
+	return renderPolicyName(tmpl, acl)
+}
+
+func renderPolicyName(tmpl *template.Template, acl *v1alpha1.ACL) (string, error) {
+	var name bytes.Buffer
+	err := tmpl.Execute(&name, policyNameData{
+		Name:      acl.Name,
+		Namespace: acl.Namespace,
+		Labels:    acl.Labels,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	rendered := strings.TrimSpace(name.String())
+	if rendered == "" {
+		return "", errors.New("policy name template renders an empty name")
+	}
+	return validResourceName(rendered), nil
+}
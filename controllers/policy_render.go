@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/app"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/tsuru/acl-operator/api/scheme"
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+	"github.com/tsuru/acl-operator/clients/tsuruapi"
+)
+
+// maxRenderRounds bounds the reconciles of a rendered ACL, each round resolves the address objects
+// created by the previous one, like the DNS entries of the endpoints of a service instance
+const maxRenderRounds = 5
+
+var errNoTsuruAPI = errors.New("tsuru API is not configured, use --tsuru-api-address to resolve tsuru destinations")
+
+// RenderOptions configure RenderPolicy, like the flags of the operator
+type RenderOptions struct {
+	// Resolver resolves hostnames, a StaticResolver renders offline
+	Resolver ACLDNSResolver
+
+	// TsuruAPI resolves apps and service instances, their destinations fail when nil
+	TsuruAPI tsuruapi.Client
+
+	// PolicyBackend is PolicyBackendKubernetes or PolicyBackendCilium, defaults to PolicyBackendKubernetes
+	PolicyBackend string
+
+	ClusterDNSEgress bool
+	LabelScheme      LabelScheme
+	IPFamilies       []v1alpha1.IPFamily
+}
+
+// RenderPolicy runs the reconcile of acl on an in-memory cluster, with the address objects it
+// creates reconciled in turn, and returns the policy that would be applied without writing anything.
+// The returned ACL has the status of the last reconcile, with the errors and warnings of destinations,
+// the policy is nil when no policy would be applied
+func RenderPolicy(ctx context.Context, acl *v1alpha1.ACL, opts RenderOptions) (client.Object, *v1alpha1.ACL, error) {
+	acl = acl.DeepCopy()
+	if acl.Namespace == "" {
+		acl.Namespace = metav1.NamespaceDefault
+	}
+	acl.ResourceVersion = ""
+	acl.Status = v1alpha1.ACLStatus{}
+
+	cli := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(acl).Build()
+	backend, err := PolicyBackendByName(opts.PolicyBackend, cli)
+	if err != nil {
+		return nil, nil, err
+	}
+	renderer := &renderPolicyBackend{PolicyBackend: backend}
+
+	tsuruAPI := opts.TsuruAPI
+	if tsuruAPI == nil {
+		tsuruAPI = noTsuruAPI{}
+	}
+	resolver := opts.Resolver
+	if resolver == nil {
+		resolver = StaticResolver{}
+	}
+
+	reconciler := &ACLReconciler{
+		Client:           cli,
+		Scheme:           scheme.Scheme,
+		Resolver:         resolver,
+		TsuruAPI:         tsuruAPI,
+		PolicyBackend:    renderer,
+		ClusterDNSEgress: opts.ClusterDNSEgress,
+		LabelScheme:      opts.LabelScheme,
+		IPFamilies:       opts.IPFamilies,
+		MaxPolicySize:    -1,
+	}
+
+	reconciled := map[string]bool{}
+	for round := 0; round < maxRenderRounds; round++ {
+		// the policy of a previous round is not kept when a destination fails, nothing was applied
+		err = forgetLastApplied(ctx, cli, acl)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(acl)})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		created, err := reconcileNewAddressObjects(ctx, reconciler, reconciled)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !created {
+			break
+		}
+	}
+
+	rendered := &v1alpha1.ACL{}
+	err = cli.Get(ctx, client.ObjectKeyFromObject(acl), rendered)
+	if err != nil {
+		return nil, nil, err
+	}
+	return renderer.rendered, rendered, nil
+}
+
+func forgetLastApplied(ctx context.Context, cli client.Client, acl *v1alpha1.ACL) error {
+	existing := &v1alpha1.ACL{}
+	err := cli.Get(ctx, client.ObjectKeyFromObject(acl), existing)
+	if err != nil || existing.Status.LastApplied == nil {
+		return err
+	}
+	existing.Status.LastApplied = nil
+	return cli.Status().Update(ctx, existing)
+}
+
+// reconcileNewAddressObjects reconciles once the address objects that are not on reconciled yet,
+// returns whether there was any
+func reconcileNewAddressObjects(ctx context.Context, reconciler *ACLReconciler, reconciled map[string]bool) (bool, error) {
+	addressReconcilers := []struct {
+		list       client.ObjectList
+		reconciler interface {
+			Reconcile(context.Context, ctrl.Request) (ctrl.Result, error)
+		}
+	}{
+		{&v1alpha1.ACLDNSEntryList{}, &ACLDNSEntryReconciler{Client: reconciler.Client, Scheme: reconciler.Scheme, Resolver: reconciler.Resolver}},
+		{&v1alpha1.TsuruAppAddressList{}, &TsuruAppAddressReconciler{Client: reconciler.Client, Scheme: reconciler.Scheme, Resolver: reconciler.Resolver, TsuruAPI: reconciler.TsuruAPI}},
+		{&v1alpha1.RpaasInstanceAddressList{}, &RpaasInstanceAddressReconciler{Client: reconciler.Client, Scheme: reconciler.Scheme, Resolver: reconciler.Resolver, TsuruAPI: reconciler.TsuruAPI}},
+		{&v1alpha1.TsuruServiceInstanceAddressList{}, &TsuruServiceInstanceAddressReconciler{Client: reconciler.Client, Scheme: reconciler.Scheme, TsuruAPI: reconciler.TsuruAPI}},
+	}
+
+	created := false
+	for _, address := range addressReconcilers {
+		err := reconciler.Client.List(ctx, address.list)
+		if err != nil {
+			return false, err
+		}
+
+		objects, err := metaObjects(address.list)
+		if err != nil {
+			return false, err
+		}
+		for _, obj := range objects {
+			key := fmt.Sprintf("%T/%s", obj, client.ObjectKeyFromObject(obj))
+			if reconciled[key] {
+				continue
+			}
+			reconciled[key] = true
+			created = true
+
+			_, err = address.reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+	return created, nil
+}
+
+func metaObjects(list client.ObjectList) ([]client.Object, error) {
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+	objects := []client.Object{}
+	for _, item := range items {
+		obj, ok := item.(client.Object)
+		if !ok {
+			return nil, errors.Errorf("%T is not an object", item)
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// renderPolicyBackend keeps the object that backend would apply instead of writing it
+type renderPolicyBackend struct {
+	PolicyBackend
+	rendered client.Object
+}
+
+func (b *renderPolicyBackend) Apply(ctx context.Context, acl *v1alpha1.ACL, policy *aclPolicy) (applyResult, error) {
+	rendered, err := b.PolicyBackend.Render(acl, policy)
+	if err != nil {
+		return applyResult{}, err
+	}
+
+	// every round renders the policy from scratch, like the first apply of the ACL
+	b.rendered = rendered
+	return applyResult{outcome: reconcileResultCreated}, nil
+}
+
+func (b *renderPolicyBackend) Diff(ctx context.Context, acl *v1alpha1.ACL, policy *aclPolicy) (string, error) {
+	return "", nil
+}
+
+// StaticResolver resolves the hostnames on the map to their addresses, other hostnames are not found
+type StaticResolver map[string][]string
+
+func (s StaticResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, ok := s[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	ipAddrs := []net.IPAddr{}
+	for _, addr := range addrs {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return nil, errors.Errorf("invalid address %q of host %s", addr, host)
+		}
+		ipAddrs = append(ipAddrs, net.IPAddr{IP: ip})
+	}
+	return ipAddrs, nil
+}
+
+// noTsuruAPI fails the calls of rendered ACLs without a Tsuru API
+type noTsuruAPI struct{}
+
+func (noTsuruAPI) AppInfo(ctx context.Context, appName string) (*app.App, error) {
+	return nil, errNoTsuruAPI
+}
+
+func (noTsuruAPI) ServiceInstanceInfo(ctx context.Context, serviceName, instance string) (*tsuruapi.ServiceInstanceInfo, error) {
+	return nil, errNoTsuruAPI
+}
+
+func (noTsuruAPI) PoolApps(ctx context.Context, pool string) ([]string, error) {
+	return nil, errNoTsuruAPI
+}
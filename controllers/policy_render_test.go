@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	netv1 "k8s.io/api/networking/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+func TestRenderPolicy(t *testing.T) {
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "tsuru",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					ExternalDNS: &v1alpha1.ACLSpecExternalDNS{
+						Name:  "api.example.com",
+						Ports: v1alpha1.ACLSpecProtoPorts{{Protocol: "TCP", Number: 443}},
+					},
+				},
+				{
+					ExternalIP: &v1alpha1.ACLSpecExternalIP{IP: "192.168.0.0/24"},
+				},
+				{
+					ExternalDNS: &v1alpha1.ACLSpecExternalDNS{Name: "missing.example.com"},
+				},
+				{
+					RpaasInstance: &v1alpha1.ACLSpecRpaasInstance{ServiceName: "rpaasv2", Instance: "my-instance"},
+				},
+			},
+		},
+	}
+
+	policy, rendered, err := RenderPolicy(context.Background(), acl, RenderOptions{
+		Resolver: StaticResolver{"api.example.com": {"10.0.0.1", "10.0.0.2"}},
+	})
+	require.NoError(t, err)
+
+	netPolicy, ok := policy.(*netv1.NetworkPolicy)
+	require.True(t, ok, "policy is %T", policy)
+	assert.Equal(t, "acl-myapp", netPolicy.Name)
+	assert.Equal(t, "tsuru", netPolicy.Namespace)
+
+	cidrs := []string{}
+	for _, rule := range netPolicy.Spec.Egress {
+		for _, peer := range rule.To {
+			if peer.IPBlock != nil {
+				cidrs = append(cidrs, peer.IPBlock.CIDR)
+			}
+		}
+	}
+	assert.ElementsMatch(t, []string{"10.0.0.1/32", "10.0.0.2/32", "192.168.0.0/24"}, cidrs)
+
+	ruleErrors := []string{}
+	for _, ruleError := range rendered.Status.RuleErrors {
+		ruleErrors = append(ruleErrors, ruleError.Error)
+	}
+	assert.Equal(t, []string{"lookup missing.example.com: no such host"}, ruleErrors)
+	// the destination that failed is skipped, the ACL is not ready
+	assert.False(t, rendered.Status.Ready)
+
+	// without Tsuru API the pool of the rpaas instance is unknown, only its pods are selected
+	selectors := []string{}
+	for _, resolved := range rendered.Status.ResolvedDestinations {
+		selectors = append(selectors, resolved.Selectors...)
+	}
+	assert.Equal(t, []string{"pods rpaas.extensions.tsuru.io/instance-name=my-instance,rpaas.extensions.tsuru.io/service-name=rpaasv2"}, selectors)
+
+	// the ACL given is not changed
+	assert.Empty(t, acl.Status.RuleErrors)
+
+	policy, _, err = RenderPolicy(context.Background(), acl, RenderOptions{
+		Resolver:      StaticResolver{"api.example.com": {"10.0.0.1"}},
+		PolicyBackend: PolicyBackendCilium,
+	})
+	require.NoError(t, err)
+	ciliumPolicy, ok := policy.(*unstructured.Unstructured)
+	require.True(t, ok, "policy is %T", policy)
+	assert.Equal(t, "CiliumNetworkPolicy", ciliumPolicy.GetKind())
+	assert.Equal(t, "acl-myapp", ciliumPolicy.GetName())
+}
+
+func TestStaticResolver(t *testing.T) {
+	resolver := StaticResolver{"api.example.com": {"10.0.0.1", "2001:db8::1"}}
+
+	addrs, err := resolver.LookupIPAddr(context.Background(), "api.example.com")
+	require.NoError(t, err)
+	require.Len(t, addrs, 2)
+	assert.Equal(t, "10.0.0.1", addrs[0].IP.String())
+	assert.Equal(t, "2001:db8::1", addrs[1].IP.String())
+
+	_, err = resolver.LookupIPAddr(context.Background(), "other.example.com")
+	dnsErr, ok := err.(*net.DNSError)
+	require.True(t, ok, "error is %T", err)
+	assert.True(t, dnsErr.IsNotFound)
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// DefaultMaxPolicySize leaves room below the 1.5MiB limit of objects stored by etcd for the
+// metadata and the managed fields of the policy
+const DefaultMaxPolicySize = 1024 * 1024
+
+// policyTooLargeError is returned when the generated policy would not fit the API server, the
+// policy applied before is kept until the destinations shrink
+type policyTooLargeError struct {
+	name    string
+	size    int
+	maxSize int
+}
+
+func (e *policyTooLargeError) Error() string {
+	return fmt.Sprintf("policy %s is too large, its rules have %d bytes and the limit is %d bytes, reduce the addresses of destinations, like with spec.cidrAggregation or spec.addressFilter", e.name, e.size, e.maxSize)
+}
+
+// checkPolicySize estimates the size of policy by its rules encoded as JSON, the encoding of
+// every backend is about as large, no limit is checked when MaxPolicySize is negative
+func (r *ACLReconciler) checkPolicySize(policy *aclPolicy) error {
+	maxSize := r.MaxPolicySize
+	if maxSize == 0 {
+		maxSize = DefaultMaxPolicySize
+	}
+	if maxSize < 0 {
+		return nil
+	}
+
+	data, err := json.Marshal([]interface{}{policy.PodSelector, policy.Egress, policy.Ingress, policy.FQDNs, policy.Metadata})
+	if err != nil {
+		return err
+	}
+	if len(data) > maxSize {
+		return &policyTooLargeError{name: policy.Name, size: len(data), maxSize: maxSize}
+	}
+	return nil
+}
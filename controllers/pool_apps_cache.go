@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tsuru/acl-operator/clients/tsuruapi"
+)
+
+const poolAppsCacheTTL = time.Minute
+
+type poolAppsCacheEntry struct {
+	apps    []string
+	expires time.Time
+}
+
+// poolAppsCache keeps the apps of pools listed by Tsuru API for poolAppsCacheTTL,
+// ACLs with the same pool share the listing
+type poolAppsCache struct {
+	mu      sync.Mutex
+	entries map[string]poolAppsCacheEntry
+}
+
+func (c *poolAppsCache) Get(ctx context.Context, tsuruAPI tsuruapi.Client, pool string) ([]string, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[pool]
+	c.mu.Unlock()
+
+	if ok && time.Now().Before(entry.expires) {
+		return entry.apps, nil
+	}
+
+	apps, err := tsuruAPI.PoolApps(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(apps)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = map[string]poolAppsCacheEntry{}
+	}
+	c.entries[pool] = poolAppsCacheEntry{
+		apps:    apps,
+		expires: time.Now().Add(poolAppsCacheTTL),
+	}
+
+	return apps, nil
+}
@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+type reconcileMemoEntry struct {
+	key        string
+	aclVersion string
+	policy     *aclPolicy
+}
+
+// reconcileMemo keeps the policy generated by the last reconcile of each ACL with the inputs
+// used to generate it, a reconcile with the same inputs applies the policy again without
+// generating the rules, which would get every address object and look up services
+type reconcileMemo struct {
+	mu      sync.Mutex
+	entries map[types.NamespacedName]reconcileMemoEntry
+}
+
+// Get returns the policy of ACL when key and the resourceVersion of ACL did not change,
+// the status of ACL is an input as well since stale rules are kept there
+func (m *reconcileMemo) Get(acl *v1alpha1.ACL, key string) (*aclPolicy, bool) {
+	if key == "" {
+		return nil, false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[types.NamespacedName{Namespace: acl.Namespace, Name: acl.Name}]
+	if !ok || entry.key != key || entry.aclVersion != acl.ResourceVersion {
+		return nil, false
+	}
+
+	return entry.policy, true
+}
+
+// Set records policy for key, the resourceVersion of ACL must be the one after the status update
+func (m *reconcileMemo) Set(acl *v1alpha1.ACL, key string, policy *aclPolicy) {
+	if key == "" {
+		m.Forget(types.NamespacedName{Namespace: acl.Namespace, Name: acl.Name})
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.entries == nil {
+		m.entries = map[types.NamespacedName]reconcileMemoEntry{}
+	}
+	m.entries[types.NamespacedName{Namespace: acl.Namespace, Name: acl.Name}] = reconcileMemoEntry{
+		key:        key,
+		aclVersion: acl.ResourceVersion,
+		policy:     policy,
+	}
+}
+
+func (m *reconcileMemo) Forget(name types.NamespacedName) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, name)
+}
+
+// memoKey returns the inputs of the rules generated for acl, an empty key means that the
+// rules depend on something that is not tracked, like Tsuru API or SRV lookups, and are
+// generated on every reconcile
+func (r *ACLReconciler) memoKey(ctx context.Context, acl *v1alpha1.ACL) string {
+	if r.DryRun {
+		return ""
+	}
+
+	for _, destination := range acl.Spec.Destinations {
+		if destination.TsuruAppPool != "" || destination.ExternalSRV != nil {
+			return ""
+		}
+	}
+
+	spec, err := json.Marshal(acl.Spec)
+	if err != nil {
+		return ""
+	}
+
+	// the options of reconciler shape the policy as well
+	options, err := json.Marshal([]interface{}{
+		r.policyBackend().Name(), r.NamespaceLabelKey, r.LabelScheme, r.IPFamilies,
+		r.CIDRAggregation, r.AddressFilter, r.ClusterDNSEgress, r.ClusterDNS,
+	})
+	if err != nil {
+		return ""
+	}
+
+	parts := []string{
+		sha256String(string(spec) + string(options)),
+		"services=" + strconv.FormatUint(r.getServiceCache().Generation(), 10),
+	}
+
+	objs, err := r.addressObjectsWithEndpoints(ctx, acl)
+	if err != nil {
+		return ""
+	}
+
+	for _, obj := range objs {
+		err = r.Client.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+		if k8sErrors.IsNotFound(err) {
+			// the object is created by the reconcile, which is pending until it is reconciled
+			parts = append(parts, fmt.Sprintf("%T/%s=", obj, obj.GetName()))
+			continue
+		} else if err != nil {
+			return ""
+		}
+
+		parts = append(parts, fmt.Sprintf("%T/%s=%s", obj, obj.GetName(), obj.GetResourceVersion()))
+	}
+
+	// the pod selector of workload sources is read from their workloads
+	if acl.Spec.Source.Workload != nil {
+		version, err := r.workloadVersion(ctx, acl)
+		if err != nil {
+			return ""
+		}
+		parts = append(parts, "workload="+version)
+	}
+
+	// the addresses of configMap destinations are read from their ConfigMaps
+	for _, destination := range acl.Spec.Destinations {
+		if destination.ConfigMap == nil {
+			continue
+		}
+
+		key := configMapKey(destination.ConfigMap, acl.Namespace)
+		configMap := configMapMetadata()
+		err = r.Client.Get(ctx, key, configMap)
+		if k8sErrors.IsNotFound(err) {
+			parts = append(parts, "configmap/"+key.String()+"=")
+			continue
+		} else if err != nil {
+			return ""
+		}
+
+		parts = append(parts, "configmap/"+key.String()+"="+configMap.ResourceVersion)
+	}
+
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,22 @@
+package controllers
+
+import (
+	"math/rand"
+	"time"
+)
+
+// DefaultRequeueJitter spreads the periodic requeues by up to 10% of the interval
+const DefaultRequeueJitter = 0.1
+
+// jitterRequeue returns interval increased or decreased by a random fraction of up to factor,
+// so objects created together do not reconcile in lockstep, factor is capped at 1
+func jitterRequeue(interval time.Duration, factor float64) time.Duration {
+	if factor <= 0 || interval <= 0 {
+		return interval
+	}
+	if factor > 1 {
+		factor = 1
+	}
+
+	return interval + time.Duration((rand.Float64()*2-1)*factor*float64(interval))
+}
@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJitterRequeue(t *testing.T) {
+	assert.Equal(t, DefaultRequeueInterval, jitterRequeue(DefaultRequeueInterval, 0))
+	assert.Equal(t, time.Duration(0), jitterRequeue(0, DefaultRequeueJitter))
+
+	tests := []struct {
+		factor   float64
+		min, max time.Duration
+	}{
+		{factor: 0.1, min: 9 * time.Minute, max: 11 * time.Minute},
+		{factor: 0.5, min: 5 * time.Minute, max: 15 * time.Minute},
+		{factor: 3, min: 0, max: 20 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		seen := map[time.Duration]bool{}
+		for i := 0; i < 1000; i++ {
+			interval := jitterRequeue(10*time.Minute, tt.factor)
+			assert.GreaterOrEqual(t, interval, tt.min, "factor", tt.factor)
+			assert.LessOrEqual(t, interval, tt.max, "factor", tt.factor)
+			seen[interval] = true
+		}
+		assert.Greater(t, len(seen), 1, "requeues are not spread with factor %v", tt.factor)
+	}
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"strings"
+
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+// newResolvedDestination summarizes the peers of rules generated by the destination on position index of spec
+func newResolvedDestination(index int, destination v1alpha1.ACLSpecDestination, rules []netv1.NetworkPolicyEgressRule, stale bool) v1alpha1.ACLStatusResolvedDestination {
+	resolved := v1alpha1.ACLStatusResolvedDestination{
+		Index:       index,
+		RuleID:      destination.RuleID,
+		Destination: describeDestination(destination),
+		HasRules:    len(rules) > 0,
+		Stale:       stale,
+	}
+
+	seen := map[string]bool{}
+	for _, rule := range rules {
+		for _, to := range rule.To {
+			if to.IPBlock != nil {
+				cidr := describePeer(to)
+				if !seen[cidr] {
+					seen[cidr] = true
+					resolved.CIDRs = append(resolved.CIDRs, cidr)
+				}
+				continue
+			}
+
+			selector := describePeerSelector(to)
+			if selector != "" && !seen[selector] {
+				seen[selector] = true
+				resolved.Selectors = append(resolved.Selectors, selector)
+			}
+		}
+	}
+
+	return resolved
+}
+
+func describePeerSelector(peer netv1.NetworkPolicyPeer) string {
+	if peer.PodSelector == nil && peer.NamespaceSelector == nil {
+		return ""
+	}
+
+	description := "pods " + metav1.FormatLabelSelector(peer.PodSelector)
+	if peer.NamespaceSelector != nil {
+		description += " in namespaces " + metav1.FormatLabelSelector(peer.NamespaceSelector)
+	}
+
+	return description
+}
+
+func describeDestination(destination v1alpha1.ACLSpecDestination) string {
+	switch {
+	case destination.TsuruApp != "":
+		description := "tsuruApp " + destination.TsuruApp
+		if destination.TsuruAppProcess != "" {
+			description += " process " + destination.TsuruAppProcess
+		}
+		if len(destination.TsuruAppRouters) > 0 {
+			description += " routers " + strings.Join(destination.TsuruAppRouters, ",")
+		}
+		return description
+	case destination.TsuruAppPool != "" && len(destination.TsuruAppPoolExcept) > 0:
+		return "tsuruAppPool " + destination.TsuruAppPool + " except " + strings.Join(destination.TsuruAppPoolExcept, ",")
+	case destination.TsuruAppPool != "":
+		return "tsuruAppPool " + destination.TsuruAppPool
+	case destination.RpaasInstance != nil:
+		return "rpaasInstance " + destination.RpaasInstance.ServiceName + "/" + destination.RpaasInstance.Instance
+	case destination.TsuruServiceInstance != nil:
+		return "tsuruServiceInstance " + destination.TsuruServiceInstance.ServiceName + "/" + destination.TsuruServiceInstance.Instance
+	case destination.ExternalDNS != nil:
+		return "externalDNS " + destination.ExternalDNS.Name
+	case destination.ExternalIP != nil:
+		return "externalIP " + destination.ExternalIP.IP
+	case len(destination.ExternalEndpoints) > 0:
+		return "externalEndpoints " + strings.Join(destination.ExternalEndpoints, ",")
+	case destination.ExternalSRV != nil:
+		return "externalSRV " + destination.ExternalSRV.Name
+	case destination.KubernetesService != nil && destination.KubernetesService.Namespace != "":
+		return "kubernetesService " + destination.KubernetesService.Namespace + "/" + destination.KubernetesService.Name
+	case destination.KubernetesService != nil:
+		return "kubernetesService " + destination.KubernetesService.Name
+	case destination.Deny != nil:
+		return "deny " + strings.Join(destination.Deny.CIDRs, ",")
+	case destination.ConfigMap != nil && destination.ConfigMap.Namespace != "":
+		return "configMap " + destination.ConfigMap.Namespace + "/" + destination.ConfigMap.Name + " key " + destination.ConfigMap.Key
+	case destination.ConfigMap != nil:
+		return "configMap " + destination.ConfigMap.Name + " key " + destination.ConfigMap.Key
+	}
+
+	return ""
+}
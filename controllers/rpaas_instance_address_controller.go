@@ -27,7 +27,9 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	extensionstsuruiov1alpha1 "github.com/tsuru/acl-operator/api/v1alpha1"
 	v1alpha1 "github.com/tsuru/acl-operator/api/v1alpha1"
@@ -42,17 +44,39 @@ type RpaasInstanceAddressReconciler struct {
 	Scheme   *runtime.Scheme
 	Resolver ACLDNSResolver
 	TsuruAPI tsuruapi.Client
+
+	RequeueInterval time.Duration
+
+	// RequeueJitter spreads each periodic requeue by up to this fraction of the interval,
+	// more or less, the requeues are not spread when zero
+	RequeueJitter float64
+
+	// TsuruAPITimeout is the deadline of each call to Tsuru API, defaults to DefaultTsuruAPITimeout
+	TsuruAPITimeout time.Duration
+
+	// Refresh enqueues objects on demand, like the resyncs of LeaderResync
+	Refresh *RefreshTrigger
+
+	// MaxConcurrentReconciles is the number of objects reconciled at once, defaults to DefaultConcurrency
+	MaxConcurrentReconciles int
+
+	backoff requeueBackoff
 }
 
 //+kubebuilder:rbac:groups=extensions.tsuru.io,resources=rpaasinstanceaddresses,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=extensions.tsuru.io,resources=rpaasinstanceaddresses/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=extensions.tsuru.io,resources=rpaasinstanceaddresses/finalizers,verbs=update
 
-func (r *RpaasInstanceAddressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *RpaasInstanceAddressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
 	l := log.FromContext(ctx)
 
 	rpaasInstanceAddress := &v1alpha1.RpaasInstanceAddress{}
-	err := r.Client.Get(ctx, req.NamespacedName, rpaasInstanceAddress)
+	outcome := reconcileResultNoop
+	defer func() {
+		observeReconcileResult("rpaasinstanceaddress", outcome, err)
+	}()
+
+	err = r.Client.Get(ctx, req.NamespacedName, rpaasInstanceAddress)
 	if k8sErrors.IsNotFound(err) {
 		return ctrl.Result{}, nil
 	} else if err != nil {
@@ -60,37 +84,63 @@ func (r *RpaasInstanceAddressReconciler) Reconcile(ctx context.Context, req ctrl
 		return ctrl.Result{}, err
 	}
 
+	ctx, l = objectLogger(ctx, rpaasInstanceAddress, "serviceName", rpaasInstanceAddress.Spec.ServiceName, "instance", rpaasInstanceAddress.Spec.Instance)
+
+	// the addresses are kept as they are, the ACLs using them are not affected
+	if isPaused(rpaasInstanceAddress) {
+		l.V(1).Info("RpaasInstanceAddress is paused, skipping reconcile", "annotation", pausedAnnotation)
+		outcome = reconcileResultPaused
+		return ctrl.Result{}, nil
+	}
+
 	oldStatus := rpaasInstanceAddress.Status.DeepCopy()
 	err = r.FillStatus(ctx, rpaasInstanceAddress)
-
+	var retryAfter time.Duration
 	if err != nil {
 		rpaasInstanceAddress.Status.Ready = false
 		rpaasInstanceAddress.Status.Reason = err.Error()
+		outcome = reconcileResultError
+
+		if isTransientError(err) {
+			retryAfter = r.backoff.Next(req.Name)
+			l.Error(err, "transient error on RpaasInstanceAddress, retrying", "retryAfter", retryAfter)
+		} else {
+			// a missing instance is not retried sooner, it may be created later
+			r.backoff.Reset(req.Name)
+			retryAfter = jitterRequeue(requeueInterval(r.RequeueInterval), r.RequeueJitter)
+			l.Info("RpaasInstanceAddress can not be resolved", "reason", err.Error())
+		}
+	} else {
+		r.backoff.Reset(req.Name)
+	}
 
+	if oldStatus.Pool != rpaasInstanceAddress.Status.Pool || oldStatus.Ready != rpaasInstanceAddress.Status.Ready || oldStatus.Reason != rpaasInstanceAddress.Status.Reason || !reflect.DeepEqual(oldStatus.IPs, rpaasInstanceAddress.Status.IPs) {
 		err = r.Client.Status().Update(ctx, rpaasInstanceAddress)
 		if err != nil {
 			return ctrl.Result{}, err
 		}
+		if outcome == reconcileResultNoop {
+			outcome = reconcileResultUpdated
+		}
+	}
 
+	// instances without address are reconciled again on their next event
+	if retryAfter > 0 {
 		return ctrl.Result{
 			Requeue:      true,
-			RequeueAfter: requeueAfter,
+			RequeueAfter: retryAfter,
 		}, nil
 	}
 
-	if oldStatus.Pool != rpaasInstanceAddress.Status.Pool || oldStatus.Ready != rpaasInstanceAddress.Status.Ready || !reflect.DeepEqual(oldStatus.IPs, rpaasInstanceAddress.Status.IPs) {
-		err = r.Client.Status().Update(ctx, rpaasInstanceAddress)
-		if err != nil {
-			return ctrl.Result{}, err
-		}
-	}
-
 	return ctrl.Result{}, nil
 }
 
 func (r *RpaasInstanceAddressReconciler) FillStatus(ctx context.Context, rpaasInstanceAddress *v1alpha1.RpaasInstanceAddress) error {
-	serviceInfo, err := r.TsuruAPI.ServiceInstanceInfo(ctx, rpaasInstanceAddress.Spec.ServiceName, rpaasInstanceAddress.Spec.Instance)
-
+	var serviceInfo *tsuruapi.ServiceInstanceInfo
+	err := callTsuruAPI(ctx, r.TsuruAPITimeout, "service instance info of "+rpaasInstanceAddress.Spec.Instance, func(ctx context.Context) (err error) {
+		serviceInfo, err = r.TsuruAPI.ServiceInstanceInfo(ctx, rpaasInstanceAddress.Spec.ServiceName, rpaasInstanceAddress.Spec.Instance)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -130,8 +180,13 @@ func (r *RpaasInstanceAddressReconciler) FillStatus(ctx context.Context, rpaasIn
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *RpaasInstanceAddressReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&extensionstsuruiov1alpha1.RpaasInstanceAddress{}).
-		WithOptions(controller.Options{MaxConcurrentReconciles: 2, RecoverPanic: true}).
-		Complete(r)
+		WithOptions(controller.Options{MaxConcurrentReconciles: maxConcurrentReconciles(r.MaxConcurrentReconciles, DefaultConcurrency), RecoverPanic: true})
+
+	if r.Refresh != nil {
+		builder = builder.Watches(&source.Channel{Source: r.Refresh.rpaasInstanceAddresses}, &handler.EnqueueRequestForObject{})
+	}
+
+	return builder.Complete(r)
 }
@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tsuru/acl-operator/api/scheme"
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+	"github.com/tsuru/acl-operator/clients/tsuruapi"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// serviceInstanceAPI answers the service instance info with info or err
+type serviceInstanceAPI struct {
+	fakeTsuruAPI
+	info *tsuruapi.ServiceInstanceInfo
+	err  error
+}
+
+func (s *serviceInstanceAPI) ServiceInstanceInfo(ctx context.Context, service, instance string) (*tsuruapi.ServiceInstanceInfo, error) {
+	return s.info, s.err
+}
+
+func TestRpaasInstanceAddressTsuruAPIErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedReason string
+		minRequeue     time.Duration
+		maxRequeue     time.Duration
+	}{
+		{
+			name:           "unavailable",
+			err:            &tsuruapi.StatusError{StatusCode: http.StatusServiceUnavailable},
+			expectedReason: "failed to request, status code: 503",
+			minRequeue:     defaultBackoffBase,
+			maxRequeue:     defaultBackoffBase * 3 / 2,
+		},
+		{
+			name:           "network",
+			err:            errors.New("connection refused"),
+			expectedReason: "connection refused",
+			minRequeue:     defaultBackoffBase,
+			maxRequeue:     defaultBackoffBase * 3 / 2,
+		},
+		{
+			name:           "not found",
+			expectedReason: "Service instance not found",
+			minRequeue:     DefaultRequeueInterval,
+			maxRequeue:     DefaultRequeueInterval,
+		},
+		{
+			name:           "forbidden",
+			err:            &tsuruapi.StatusError{StatusCode: http.StatusForbidden},
+			expectedReason: "failed to request, status code: 403",
+			minRequeue:     DefaultRequeueInterval,
+			maxRequeue:     DefaultRequeueInterval,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rpaasInstanceAddress := &v1alpha1.RpaasInstanceAddress{
+				ObjectMeta: v1.ObjectMeta{
+					Name: "rpaasv2-my-instance",
+				},
+				Spec: v1alpha1.RpaasInstanceAddressSpec{
+					ServiceName: "rpaasv2",
+					Instance:    "my-instance",
+				},
+			}
+
+			controller := &RpaasInstanceAddressReconciler{
+				Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(rpaasInstanceAddress).Build(),
+				Scheme:   scheme.Scheme,
+				TsuruAPI: &serviceInstanceAPI{err: tt.err},
+				Resolver: &fakeResolver{},
+			}
+
+			result, err := controller.Reconcile(context.Background(), controllerruntime.Request{
+				NamespacedName: types.NamespacedName{
+					Name: rpaasInstanceAddress.Name,
+				},
+			})
+			require.NoError(t, err)
+			assert.GreaterOrEqual(t, result.RequeueAfter, tt.minRequeue)
+			assert.LessOrEqual(t, result.RequeueAfter, tt.maxRequeue)
+
+			existingRpaasInstanceAddress := &v1alpha1.RpaasInstanceAddress{}
+			err = controller.Client.Get(context.Background(), types.NamespacedName{
+				Name: rpaasInstanceAddress.Name,
+			}, existingRpaasInstanceAddress)
+			require.NoError(t, err)
+			assert.False(t, existingRpaasInstanceAddress.Status.Ready)
+			assert.Equal(t, tt.expectedReason, existingRpaasInstanceAddress.Status.Reason)
+		})
+	}
+}
+
+func TestRpaasInstanceAddressRecovers(t *testing.T) {
+	rpaasInstanceAddress := &v1alpha1.RpaasInstanceAddress{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "rpaasv2-my-instance",
+		},
+		Spec: v1alpha1.RpaasInstanceAddressSpec{
+			ServiceName: "rpaasv2",
+			Instance:    "my-instance",
+		},
+	}
+
+	tsuruAPI := &serviceInstanceAPI{err: errors.New("connection refused")}
+	controller := &RpaasInstanceAddressReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(rpaasInstanceAddress).Build(),
+		Scheme:   scheme.Scheme,
+		TsuruAPI: tsuruAPI,
+		Resolver: &fakeResolver{},
+	}
+	reconcile := func() (controllerruntime.Result, *v1alpha1.RpaasInstanceAddress) {
+		result, err := controller.Reconcile(context.Background(), controllerruntime.Request{
+			NamespacedName: types.NamespacedName{Name: rpaasInstanceAddress.Name},
+		})
+		require.NoError(t, err)
+
+		existingRpaasInstanceAddress := &v1alpha1.RpaasInstanceAddress{}
+		err = controller.Client.Get(context.Background(), types.NamespacedName{Name: rpaasInstanceAddress.Name}, existingRpaasInstanceAddress)
+		require.NoError(t, err)
+		return result, existingRpaasInstanceAddress
+	}
+
+	// transient errors back off exponentially
+	result, _ := reconcile()
+	assert.LessOrEqual(t, result.RequeueAfter, defaultBackoffBase*3/2)
+	result, _ = reconcile()
+	assert.GreaterOrEqual(t, result.RequeueAfter, defaultBackoffBase*2)
+
+	tsuruAPI.err = nil
+	tsuruAPI.info = &tsuruapi.ServiceInstanceInfo{
+		Pool:       "my-pool",
+		CustomInfo: map[string]interface{}{"Address": "3.3.3.3"},
+	}
+	result, existingRpaasInstanceAddress := reconcile()
+	assert.Equal(t, controllerruntime.Result{}, result)
+	assert.True(t, existingRpaasInstanceAddress.Status.Ready)
+	assert.Equal(t, "", existingRpaasInstanceAddress.Status.Reason)
+	assert.Equal(t, []string{"3.3.3.3"}, existingRpaasInstanceAddress.Status.IPs)
+
+	// the backoff starts over after a success
+	tsuruAPI.err = errors.New("connection refused")
+	result, existingRpaasInstanceAddress = reconcile()
+	assert.LessOrEqual(t, result.RequeueAfter, defaultBackoffBase*3/2)
+	assert.False(t, existingRpaasInstanceAddress.Status.Ready)
+}
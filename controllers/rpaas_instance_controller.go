@@ -22,6 +22,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -40,6 +41,14 @@ import (
 type RpaasInstanceReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	RequeueInterval time.Duration
+
+	// LabelScheme are the labels of RPaaS instances, empty keys use DefaultLabelScheme
+	LabelScheme LabelScheme
+
+	// MaxConcurrentReconciles is the number of objects reconciled at once, defaults to DefaultConcurrency
+	MaxConcurrentReconciles int
 }
 
 func (r *RpaasInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -53,8 +62,11 @@ func (r *RpaasInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{}, err
 	}
 
-	rpaasInstanceName := rpaasInstance.Labels["rpaas.extensions.tsuru.io/instance-name"]
-	rpaasServiceName := rpaasInstance.Labels["rpaas.extensions.tsuru.io/service-name"]
+	ctx, l = objectLogger(ctx, rpaasInstance)
+
+	labelScheme := r.LabelScheme.WithDefaults()
+	rpaasInstanceName := rpaasInstance.Labels[labelScheme.RpaasInstance]
+	rpaasServiceName := rpaasInstance.Labels[labelScheme.RpaasService]
 
 	destinations, errs := convertRPaasAllowedUpstreamsToOperatorRules(rpaasInstance.Spec.AllowedUpstreams, rpaasInstance.Spec.Binds)
 	warningErrors := []string{}
@@ -101,7 +113,7 @@ func (r *RpaasInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 
 		return ctrl.Result{
 			Requeue:      true,
-			RequeueAfter: requeueAfter,
+			RequeueAfter: requeueInterval(r.RequeueInterval),
 		}, nil
 	} else if err != nil {
 		l.Error(err, "could not get ACL object")
@@ -144,7 +156,7 @@ func (r *RpaasInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 
 	return ctrl.Result{
 		Requeue:      true,
-		RequeueAfter: requeueAfter,
+		RequeueAfter: requeueInterval(r.RequeueInterval),
 	}, nil
 }
 
@@ -213,14 +225,7 @@ func convertRPaasAllowedUpstreamsToOperatorRules(allowedUpstreams []rpaasv1alpha
 }
 
 func isIPRange(name string) bool {
-	var address string
-	if strings.Contains(name, "/") {
-		address = name
-	} else {
-		address = name + "/32"
-	}
-
-	_, _, err := net.ParseCIDR(address)
+	_, _, err := v1alpha1.ParseCIDR(name)
 	return err == nil
 }
 
@@ -269,7 +274,7 @@ func isKubernetesInternal(host string) bool {
 func (r *RpaasInstanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&rpaasv1alpha1.RpaasInstance{}).
-		WithOptions(controller.Options{MaxConcurrentReconciles: 2, RecoverPanic: true}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: maxConcurrentReconciles(r.MaxConcurrentReconciles, DefaultConcurrency), RecoverPanic: true}).
 		Owns(&rpaasv1alpha1.RpaasInstance{}).
 		Complete(r)
 }
@@ -2,29 +2,104 @@ package controllers
 
 import (
 	"context"
+	"sort"
+	"sync"
 	"time"
 
-	"sync/atomic"
-
+	"golang.org/x/sync/singleflight"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+//+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
+//+kubebuilder:rbac:groups=discovery.k8s.io,resources=endpointslices,verbs=get;list;watch
+
+const (
+	// serviceCacheTTL lists the services again periodically, so services whose deletion event
+	// was missed do not stay on the cache
+	serviceCacheTTL = 15 * time.Minute
+
+	// defaultServiceCacheMaxEntries bounds each index of serviceCache, the addresses of services,
+	// the services by name and the addresses of endpoints
+	defaultServiceCacheMaxEntries = 50000
+
+	// serviceIPIndex and endpointSliceIPIndex index the services and EndpointSlices of the cache of
+	// manager by their addresses, so the lookups of a truncated serviceCache do not list every one
+	serviceIPIndex       = "service-ip"
+	endpointSliceIPIndex = "endpoint-slice-ip"
 )
 
+// serviceIPIndexKeys are the addresses of a service on serviceIPIndex
+func serviceIPIndexKeys(obj client.Object) []string {
+	service, ok := obj.(*corev1.Service)
+	if !ok {
+		return nil
+	}
+	return serviceIPs(service)
+}
+
+// endpointSliceIPIndexKeys are the addresses of an EndpointSlice on endpointSliceIPIndex, slices
+// that indexEndpointSlice ignores have none
+func endpointSliceIPIndexKeys(obj client.Object) []string {
+	slice, ok := obj.(*discoveryv1.EndpointSlice)
+	if !ok || slice.Labels[discoveryv1.LabelServiceName] == "" {
+		return nil
+	}
+	return endpointSliceIPs(slice)
+}
+
 type mapServiceCache map[string]*corev1.Service
 
 type serviceCache struct {
 	client.Client
 
-	allServices        atomic.Pointer[mapServiceCache]
-	allServicesExpires atomic.Pointer[time.Time]
+	mu          sync.RWMutex
+	allServices mapServiceCache
+	byName      map[types.NamespacedName]*corev1.Service
+	expires     time.Time
+
+	// byEndpoint maps the endpoint addresses, like the IPs of pods, to the EndpointSlices
+	// that have them and the service of each slice
+	byEndpoint map[string]map[types.NamespacedName]types.NamespacedName
+
+	// generation changes whenever the cached services change
+	generation uint64
+
+	// maxEntries bounds each index, defaults to defaultServiceCacheMaxEntries. Arbitrary entries
+	// are evicted from a full index and the cache is marked truncated
+	maxEntries int
+
+	// truncated caches lack some entries, so a missing entry is looked up on the client
+	// instead of meaning that there is no service, until the next listing fits the cache
+	truncated bool
+
+	// fill shares a listing of services between the reconciles that find the cache empty or
+	// expired at once, so concurrent reconciles do not list every service of the cluster each
+	fill singleflight.Group
+}
+
+// Generation allows to know whether the services were changed since a previous call
+func (s *serviceCache) Generation() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.generation
 }
 
 func (s *serviceCache) GetByIP(ctx context.Context, ip string) (*corev1.Service, error) {
-	allServices := s.allServices.Load()
-	expires := s.allServicesExpires.Load()
+	s.mu.RLock()
+	allServices := s.allServices
+	expired := time.Now().UTC().After(s.expires)
+	s.mu.RUnlock()
 
-	if allServices == nil || expires == nil || expires.After(time.Now().UTC()) {
+	if allServices == nil || expired {
 		var err error
 		allServices, err = s.fillCache(ctx)
 		if err != nil {
@@ -32,10 +107,275 @@ func (s *serviceCache) GetByIP(ctx context.Context, ip string) (*corev1.Service,
 		}
 	}
 
-	return (*allServices)[ip], nil
+	s.mu.RLock()
+	svc := allServices[ip]
+	truncated := s.truncated
+	s.mu.RUnlock()
+
+	if svc == nil && truncated {
+		return s.lookupByIP(ctx, ip)
+	}
+	return svc, nil
+}
+
+// GetAllByIP returns the services whose cluster or load balancer IP is ip and the services
+// with an endpoint of ip, an endpoint may belong to many services, sorted by namespace and name
+func (s *serviceCache) GetAllByIP(ctx context.Context, ip string) ([]*corev1.Service, error) {
+	svc, err := s.GetByIP(ctx, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	serviceNames := []types.NamespacedName{}
+	for _, serviceName := range s.byEndpoint[ip] {
+		serviceNames = append(serviceNames, serviceName)
+	}
+	truncated := s.truncated
+	s.mu.RUnlock()
+
+	if len(serviceNames) == 0 && truncated {
+		serviceNames, err = s.lookupEndpointServices(ctx, ip)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var services []*corev1.Service
+	seen := map[types.NamespacedName]bool{}
+	if svc != nil {
+		services = append(services, svc)
+		seen[client.ObjectKeyFromObject(svc)] = true
+	}
+
+	for _, serviceName := range serviceNames {
+		if seen[serviceName] {
+			continue
+		}
+		seen[serviceName] = true
+		endpointService, err := s.GetByName(ctx, serviceName.Namespace, serviceName.Name)
+		if err != nil {
+			return nil, err
+		}
+		if endpointService != nil {
+			services = append(services, endpointService)
+		}
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		if services[i].Namespace != services[j].Namespace {
+			return services[i].Namespace < services[j].Namespace
+		}
+		return services[i].Name < services[j].Name
+	})
+
+	return services, nil
+}
+
+// GetByName returns the service of namespace and name, services without addresses like ExternalName
+// services are returned as well
+func (s *serviceCache) GetByName(ctx context.Context, namespace, name string) (*corev1.Service, error) {
+	s.mu.RLock()
+	byName := s.byName
+	expired := time.Now().UTC().After(s.expires)
+	s.mu.RUnlock()
+
+	if byName == nil || expired {
+		_, err := s.fillCache(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.RLock()
+	svc := s.byName[types.NamespacedName{Namespace: namespace, Name: name}]
+	truncated := s.truncated
+	s.mu.RUnlock()
+
+	if svc == nil && truncated {
+		svc = &corev1.Service{}
+		err := s.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, svc)
+		if k8sErrors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return svc, nil
+}
+
+// GetByLabels returns the services of namespace whose labels match selector, sorted by name
+func (s *serviceCache) GetByLabels(ctx context.Context, namespace string, selector labels.Selector) ([]*corev1.Service, error) {
+	s.mu.RLock()
+	byName := s.byName
+	expired := time.Now().UTC().After(s.expires)
+	s.mu.RUnlock()
+
+	if byName == nil || expired {
+		_, err := s.fillCache(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.RLock()
+	truncated := s.truncated
+	var services []*corev1.Service
+	if !truncated {
+		for name, svc := range s.byName {
+			if name.Namespace == namespace && selector.Matches(labels.Set(svc.Labels)) {
+				services = append(services, svc)
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	// evicted services may match as well, the cache has no index by labels to know it
+	if truncated {
+		serviceList := corev1.ServiceList{}
+		err := s.Client.List(ctx, &serviceList, &client.ListOptions{Namespace: namespace, LabelSelector: selector})
+		if err != nil {
+			return nil, err
+		}
+		for i := range serviceList.Items {
+			services = append(services, &serviceList.Items[i])
+		}
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+	return services, nil
 }
 
-func (s *serviceCache) fillCache(ctx context.Context) (*mapServiceCache, error) {
+// lookupByIP lists the services with ip on serviceIPIndex, for truncated caches, the addresses are
+// checked again since readers that do not know the index may ignore the field selector
+func (s *serviceCache) lookupByIP(ctx context.Context, ip string) (*corev1.Service, error) {
+	allServices := corev1.ServiceList{}
+	err := s.Client.List(ctx, &allServices, client.MatchingFields{serviceIPIndex: ip})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range allServices.Items {
+		for _, serviceIP := range serviceIPs(&allServices.Items[i]) {
+			if serviceIP == ip {
+				return &allServices.Items[i], nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// lookupEndpointServices lists the EndpointSlices with ip on endpointSliceIPIndex to find the services
+// with an endpoint of ip, for truncated caches
+func (s *serviceCache) lookupEndpointServices(ctx context.Context, ip string) ([]types.NamespacedName, error) {
+	allEndpointSlices := discoveryv1.EndpointSliceList{}
+	err := s.Client.List(ctx, &allEndpointSlices, client.MatchingFields{endpointSliceIPIndex: ip})
+	if err != nil {
+		return nil, err
+	}
+
+	byEndpoint := map[string]map[types.NamespacedName]types.NamespacedName{}
+	for i := range allEndpointSlices.Items {
+		indexEndpointSlice(byEndpoint, &allEndpointSlices.Items[i])
+	}
+
+	serviceNames := []types.NamespacedName{}
+	for _, serviceName := range byEndpoint[ip] {
+		serviceNames = append(serviceNames, serviceName)
+	}
+	return serviceNames, nil
+}
+
+// Invalidate removes the service of ip from the cache, the ip is not translated to a
+// pod selector until an event of a service with the ip or the next refresh of the cache
+func (s *serviceCache) Invalidate(ip string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.allServices, ip)
+	s.generation++
+}
+
+// update replaces the addresses of oldService by the addresses of newService,
+// any of them may be nil when the service is created or deleted
+func (s *serviceCache) update(oldService, newService *corev1.Service) {
+	if oldService != nil {
+		for _, ip := range serviceIPs(oldService) {
+			s.Invalidate(ip)
+		}
+
+		s.mu.Lock()
+		delete(s.byName, client.ObjectKeyFromObject(oldService))
+		s.generation++
+		s.mu.Unlock()
+	}
+
+	if newService == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// a cache not filled yet will list the service anyway
+	if s.allServices == nil {
+		return
+	}
+
+	for _, ip := range serviceIPs(newService) {
+		makeRoom(s, s.allServices, ip)
+		s.allServices[ip] = newService
+	}
+	makeRoom(s, s.byName, client.ObjectKeyFromObject(newService))
+	s.byName[client.ObjectKeyFromObject(newService)] = newService
+	s.generation++
+}
+
+// updateEndpointSlice replaces the endpoints of oldSlice by the endpoints of newSlice,
+// any of them may be nil when the slice is created or deleted
+func (s *serviceCache) updateEndpointSlice(oldSlice, newSlice *discoveryv1.EndpointSlice) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// a cache not filled yet will list the slice anyway
+	if s.byEndpoint == nil {
+		return
+	}
+
+	if oldSlice != nil {
+		sliceName := client.ObjectKeyFromObject(oldSlice)
+		for _, ip := range endpointSliceIPs(oldSlice) {
+			delete(s.byEndpoint[ip], sliceName)
+			if len(s.byEndpoint[ip]) == 0 {
+				delete(s.byEndpoint, ip)
+			}
+		}
+	}
+
+	if newSlice != nil {
+		for _, ip := range endpointSliceIPs(newSlice) {
+			makeRoom(s, s.byEndpoint, ip)
+		}
+		indexEndpointSlice(s.byEndpoint, newSlice)
+	}
+	s.generation++
+}
+
+func (s *serviceCache) fillCache(ctx context.Context) (mapServiceCache, error) {
+	cache, err, _ := s.fill.Do("services", func() (interface{}, error) {
+		return s.listServices(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cache.(mapServiceCache), nil
+}
+
+// listServices lists the services and EndpointSlices of the cluster and replaces the cache with them
+func (s *serviceCache) listServices(ctx context.Context) (mapServiceCache, error) {
 	allServices := corev1.ServiceList{}
 
 	err := s.Client.List(ctx, &allServices, &client.ListOptions{Namespace: metav1.NamespaceAll})
@@ -43,36 +383,183 @@ func (s *serviceCache) fillCache(ctx context.Context) (*mapServiceCache, error)
 		return nil, err
 	}
 
+	allEndpointSlices := discoveryv1.EndpointSliceList{}
+	err = s.Client.List(ctx, &allEndpointSlices, &client.ListOptions{Namespace: metav1.NamespaceAll})
+	if err != nil {
+		return nil, err
+	}
+
+	byEndpoint := map[string]map[types.NamespacedName]types.NamespacedName{}
+	for i := range allEndpointSlices.Items {
+		indexEndpointSlice(byEndpoint, &allEndpointSlices.Items[i])
+	}
+
 	cache := mapServiceCache{}
+	byName := make(map[types.NamespacedName]*corev1.Service, len(allServices.Items))
 
-	// store also cluster IPs
-	for i, service := range allServices.Items {
-		if service.Spec.ClusterIP != "" {
-			cache[service.Spec.ClusterIP] = &allServices.Items[i]
-		}
-		for _, clusterIP := range service.Spec.ClusterIPs {
-			cache[clusterIP] = &allServices.Items[i]
+	for i := range allServices.Items {
+		for _, ip := range serviceIPs(&allServices.Items[i]) {
+			cache[ip] = &allServices.Items[i]
 		}
+		byName[client.ObjectKeyFromObject(&allServices.Items[i])] = &allServices.Items[i]
 	}
 
-	for i, service := range allServices.Items {
-		if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
-			continue
+	maxEntries := s.limit()
+	truncated := trimEntries(cache, maxEntries)
+	truncated = trimEntries(byName, maxEntries) || truncated
+	truncated = trimEntries(byEndpoint, maxEntries) || truncated
+
+	s.mu.Lock()
+	s.allServices = cache
+	s.byName = byName
+	s.byEndpoint = byEndpoint
+	s.truncated = truncated
+	s.expires = time.Now().UTC().Add(serviceCacheTTL)
+	s.generation++
+	s.mu.Unlock()
+
+	return cache, nil
+}
+
+func (s *serviceCache) limit() int {
+	if s.maxEntries <= 0 {
+		return defaultServiceCacheMaxEntries
+	}
+	return s.maxEntries
+}
+
+// makeRoom evicts an arbitrary entry of index of s when it is full and key is not on it yet,
+// marking s truncated, it is called with the lock of s held
+func makeRoom[K comparable, V any](s *serviceCache, index map[K]V, key K) {
+	if _, ok := index[key]; ok || len(index) < s.limit() {
+		return
+	}
+	for existing := range index {
+		delete(index, existing)
+		break
+	}
+	s.truncated = true
+}
+
+// trimEntries removes arbitrary entries of index until it fits maxEntries, returns whether any
+// entry was removed
+func trimEntries[K comparable, V any](index map[K]V, maxEntries int) bool {
+	trimmed := false
+	for key := range index {
+		if len(index) <= maxEntries {
+			break
 		}
-		if len(service.Status.LoadBalancer.Ingress) == 0 {
+		delete(index, key)
+		trimmed = true
+	}
+	return trimmed
+}
+
+// serviceIPs returns cluster IPs and the first load balancer IP of a service
+func serviceIPs(service *corev1.Service) []string {
+	ips := []string{}
+
+	if service.Spec.ClusterIP != "" {
+		ips = append(ips, service.Spec.ClusterIP)
+	}
+	ips = append(ips, service.Spec.ClusterIPs...)
+
+	if service.Spec.Type == corev1.ServiceTypeLoadBalancer &&
+		len(service.Status.LoadBalancer.Ingress) > 0 &&
+		service.Status.LoadBalancer.Ingress[0].IP != "" {
+		ips = append(ips, service.Status.LoadBalancer.Ingress[0].IP)
+	}
+
+	return ips
+}
+
+// serviceClusterIPs returns the cluster IPs of every family of service, none for headless services
+func serviceClusterIPs(service *corev1.Service) []string {
+	ips := []string{}
+	for _, ip := range append([]string{service.Spec.ClusterIP}, service.Spec.ClusterIPs...) {
+		if ip == "" || ip == corev1.ClusterIPNone || containsString(ips, ip) {
 			continue
 		}
+		ips = append(ips, ip)
+	}
+	return ips
+}
 
-		if service.Status.LoadBalancer.Ingress[0].IP == "" {
-			continue
+// indexEndpointSlice adds the endpoints of slice to byEndpoint, slices without the label of their
+// service, like the ones mirrored by hand, are ignored
+func indexEndpointSlice(byEndpoint map[string]map[types.NamespacedName]types.NamespacedName, slice *discoveryv1.EndpointSlice) {
+	serviceName := slice.Labels[discoveryv1.LabelServiceName]
+	if serviceName == "" {
+		return
+	}
+
+	sliceName := client.ObjectKeyFromObject(slice)
+	for _, ip := range endpointSliceIPs(slice) {
+		if byEndpoint[ip] == nil {
+			byEndpoint[ip] = map[types.NamespacedName]types.NamespacedName{}
 		}
+		byEndpoint[ip][sliceName] = types.NamespacedName{Namespace: slice.Namespace, Name: serviceName}
+	}
+}
+
+// endpointSliceIPs returns the IPv4 and IPv6 addresses of the endpoints of slice, ready or not,
+// the addresses of FQDN slices are hostnames
+func endpointSliceIPs(slice *discoveryv1.EndpointSlice) []string {
+	if slice.AddressType == discoveryv1.AddressTypeFQDN {
+		return nil
+	}
 
-		cache[service.Status.LoadBalancer.Ingress[0].IP] = &allServices.Items[i]
+	ips := []string{}
+	for _, endpoint := range slice.Endpoints {
+		ips = append(ips, endpoint.Addresses...)
 	}
+	return ips
+}
 
-	s.allServices.Store(&cache)
-	expires := time.Now().UTC().Add(time.Minute * 15)
-	s.allServicesExpires.Store(&expires)
+// serviceCacheEventHandler keeps the serviceCache up to date with the events of services,
+// no ACL is enqueued, the next reconcile of ACLs uses the new addresses
+func serviceCacheEventHandler(s func() *serviceCache) handler.EventHandler {
+	return handler.Funcs{
+		CreateFunc: func(e event.CreateEvent, _ workqueue.RateLimitingInterface) {
+			if service, ok := e.Object.(*corev1.Service); ok {
+				s().update(nil, service)
+			}
+		},
+		UpdateFunc: func(e event.UpdateEvent, _ workqueue.RateLimitingInterface) {
+			oldService, _ := e.ObjectOld.(*corev1.Service)
+			newService, ok := e.ObjectNew.(*corev1.Service)
+			if ok {
+				s().update(oldService, newService)
+			}
+		},
+		DeleteFunc: func(e event.DeleteEvent, _ workqueue.RateLimitingInterface) {
+			if service, ok := e.Object.(*corev1.Service); ok {
+				s().update(service, nil)
+			}
+		},
+	}
+}
 
-	return &cache, err
+// endpointSliceCacheEventHandler keeps the endpoints of serviceCache up to date with the events
+// of EndpointSlices, like serviceCacheEventHandler no ACL is enqueued
+func endpointSliceCacheEventHandler(s func() *serviceCache) handler.EventHandler {
+	return handler.Funcs{
+		CreateFunc: func(e event.CreateEvent, _ workqueue.RateLimitingInterface) {
+			if slice, ok := e.Object.(*discoveryv1.EndpointSlice); ok {
+				s().updateEndpointSlice(nil, slice)
+			}
+		},
+		UpdateFunc: func(e event.UpdateEvent, _ workqueue.RateLimitingInterface) {
+			oldSlice, _ := e.ObjectOld.(*discoveryv1.EndpointSlice)
+			newSlice, ok := e.ObjectNew.(*discoveryv1.EndpointSlice)
+			if ok {
+				s().updateEndpointSlice(oldSlice, newSlice)
+			}
+		},
+		DeleteFunc: func(e event.DeleteEvent, _ workqueue.RateLimitingInterface) {
+			if slice, ok := e.Object.(*discoveryv1.EndpointSlice); ok {
+				s().updateEndpointSlice(slice, nil)
+			}
+		},
+	}
 }
@@ -0,0 +1,486 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tsuru/acl-operator/api/scheme"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestServiceCacheIPChange(t *testing.T) {
+	ctx := context.Background()
+	oldService := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "old-service",
+			Namespace: "default",
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "10.96.0.10",
+			Selector:  map[string]string{"app": "old"},
+		},
+	}
+
+	cli := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(oldService).Build()
+	r := &ACLReconciler{Client: cli}
+	rules := []netv1.NetworkPolicyEgressRule{
+		{
+			To: []netv1.NetworkPolicyPeer{
+				{IPBlock: &netv1.IPBlock{CIDR: "10.96.0.10/32"}},
+			},
+		},
+	}
+
+	selectorOf := func() map[string]string {
+		result, err := r.fillPodSelectorByCIDR(ctx, rules)
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+		return result[1].To[0].PodSelector.MatchLabels
+	}
+
+	assert.Equal(t, map[string]string{"app": "old"}, selectorOf())
+
+	// the IP moves to a new service, the cache is kept until the events arrive
+	err := cli.Delete(ctx, oldService)
+	require.NoError(t, err)
+	newService := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "new-service",
+			Namespace: "default",
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "10.96.0.10",
+			Selector:  map[string]string{"app": "new"},
+		},
+	}
+	err = cli.Create(ctx, newService)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"app": "old"}, selectorOf())
+
+	eventHandler := serviceCacheEventHandler(r.getServiceCache)
+	eventHandler.Delete(event.DeleteEvent{Object: oldService}, nil)
+	result, err := r.fillPodSelectorByCIDR(ctx, rules)
+	require.NoError(t, err)
+	assert.Len(t, result, 1)
+
+	eventHandler.Create(event.CreateEvent{Object: newService}, nil)
+	assert.Equal(t, map[string]string{"app": "new"}, selectorOf())
+
+	updatedService := newService.DeepCopy()
+	updatedService.Spec.ClusterIP = "10.96.0.20"
+	err = cli.Update(ctx, updatedService)
+	require.NoError(t, err)
+	eventHandler.Update(event.UpdateEvent{ObjectOld: newService, ObjectNew: updatedService}, nil)
+
+	svc, err := r.getServiceCache().GetByIP(ctx, "10.96.0.10")
+	require.NoError(t, err)
+	assert.Nil(t, svc)
+
+	svc, err = r.getServiceCache().GetByIP(ctx, "10.96.0.20")
+	require.NoError(t, err)
+	require.NotNil(t, svc)
+	assert.Equal(t, "new-service", svc.Name)
+
+	r.getServiceCache().Invalidate("10.96.0.20")
+	svc, err = r.getServiceCache().GetByIP(ctx, "10.96.0.20")
+	require.NoError(t, err)
+	assert.Nil(t, svc)
+}
+
+func TestServiceCacheEndpointSlices(t *testing.T) {
+	ctx := context.Background()
+	newService := func(name string, selector map[string]string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: corev1.ServiceSpec{
+				ClusterIP: corev1.ClusterIPNone,
+				Selector:  selector,
+			},
+		}
+	}
+	newEndpointSlice := func(name, serviceName string, addresses ...string) *discoveryv1.EndpointSlice {
+		return &discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels:    map[string]string{discoveryv1.LabelServiceName: serviceName},
+			},
+			AddressType: discoveryv1.AddressTypeIPv4,
+			Endpoints:   []discoveryv1.Endpoint{{Addresses: addresses}},
+		}
+	}
+
+	web := newService("web", map[string]string{"app": "web"})
+	webCanary := newService("web-canary", map[string]string{"app": "web", "track": "canary"})
+	legacy := newService("legacy", nil)
+	webSlice := newEndpointSlice("web-abcde", "web", "10.1.0.1", "10.1.0.2")
+
+	cli := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(
+		web, webCanary, legacy,
+		webSlice,
+		newEndpointSlice("web-canary-abcde", "web-canary", "10.1.0.2"),
+		newEndpointSlice("legacy-abcde", "legacy", "192.168.0.1"),
+	).Build()
+	r := &ACLReconciler{Client: cli}
+
+	peersOf := func(cidr string) []netv1.NetworkPolicyPeer {
+		result, err := r.fillPodSelectorByCIDR(ctx, []netv1.NetworkPolicyEgressRule{
+			{To: []netv1.NetworkPolicyPeer{{IPBlock: &netv1.IPBlock{CIDR: cidr}}}},
+		})
+		require.NoError(t, err)
+		if len(result) == 1 {
+			return nil
+		}
+		require.Len(t, result, 2)
+		return result[1].To
+	}
+	selectorsOf := func(peers []netv1.NetworkPolicyPeer) []map[string]string {
+		var selectors []map[string]string
+		for _, peer := range peers {
+			selectors = append(selectors, peer.PodSelector.MatchLabels)
+		}
+		return selectors
+	}
+
+	assert.Equal(t, []map[string]string{{"app": "web"}}, selectorsOf(peersOf("10.1.0.1/32")))
+
+	// a pod of many services is allowed by the selector of each service
+	assert.Equal(t, []map[string]string{{"app": "web"}, {"app": "web", "track": "canary"}}, selectorsOf(peersOf("10.1.0.2/32")))
+
+	// endpoints of services without selector can not be selected
+	assert.Nil(t, peersOf("192.168.0.1/32"))
+	assert.Nil(t, peersOf("10.1.0.3/32"))
+
+	eventHandler := endpointSliceCacheEventHandler(r.getServiceCache)
+	updatedSlice := newEndpointSlice("web-abcde", "web", "10.1.0.3")
+	eventHandler.Update(event.UpdateEvent{ObjectOld: webSlice, ObjectNew: updatedSlice}, nil)
+	assert.Nil(t, peersOf("10.1.0.1/32"))
+	assert.Equal(t, []map[string]string{{"app": "web"}}, selectorsOf(peersOf("10.1.0.3/32")))
+
+	eventHandler.Delete(event.DeleteEvent{Object: updatedSlice}, nil)
+	assert.Nil(t, peersOf("10.1.0.3/32"))
+
+	services, err := r.getServiceCache().GetAllByIP(ctx, "10.1.0.2")
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+	assert.Equal(t, "web-canary", services[0].Name)
+}
+
+// blockingListClient counts the listings of services, which wait for release
+type blockingListClient struct {
+	client.Client
+	lists   int32
+	release chan struct{}
+}
+
+func (c *blockingListClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if _, ok := list.(*corev1.ServiceList); ok {
+		atomic.AddInt32(&c.lists, 1)
+		<-c.release
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
+func TestServiceCacheConcurrentFill(t *testing.T) {
+	ctx := context.Background()
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "my-service",
+			Namespace: "default",
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "10.96.0.10",
+		},
+	}
+
+	cli := &blockingListClient{
+		Client:  fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(service).Build(),
+		release: make(chan struct{}),
+	}
+	r := &ACLReconciler{Client: cli}
+
+	var wg sync.WaitGroup
+	names := make(chan string, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			svc, err := r.getServiceCache().GetByIP(ctx, "10.96.0.10")
+			assert.NoError(t, err)
+			if svc != nil {
+				names <- svc.Name
+			}
+		}()
+	}
+
+	// the reconciles that find the cache empty wait for the first listing instead of listing again
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&cli.lists) > 0 }, time.Second, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	close(cli.release)
+	wg.Wait()
+	close(names)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&cli.lists))
+	count := 0
+	for name := range names {
+		assert.Equal(t, "my-service", name)
+		count++
+	}
+	assert.Equal(t, 8, count)
+}
+
+func TestServiceCacheConcurrentLookups(t *testing.T) {
+	ctx := context.Background()
+	const total = 20
+	services := []*corev1.Service{}
+	slices := []*discoveryv1.EndpointSlice{}
+	objects := []client.Object{}
+	for i := 0; i < total; i++ {
+		name := fmt.Sprintf("service-%d", i)
+		services = append(services, &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       corev1.ServiceSpec{ClusterIP: fmt.Sprintf("10.96.0.%d", i)},
+		})
+		slices = append(slices, &discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name + "-abcde",
+				Namespace: "default",
+				Labels:    map[string]string{discoveryv1.LabelServiceName: name},
+			},
+			AddressType: discoveryv1.AddressTypeIPv4,
+			Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{fmt.Sprintf("10.1.0.%d", i)}}},
+		})
+		objects = append(objects, services[i], slices[i])
+	}
+
+	r := &ACLReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()}
+	cache := r.getServiceCache()
+	cache.maxEntries = 5
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			cache.update(services[i%total], services[i%total])
+			cache.updateEndpointSlice(nil, slices[(i+7)%total])
+		}
+	}()
+
+	// every lookup finds its service, the ones evicted from the full cache are looked up on the client
+	var readers sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		readers.Add(1)
+		go func(g int) {
+			defer readers.Done()
+			for n := 0; n < 50; n++ {
+				i := (g + n) % total
+				name := fmt.Sprintf("service-%d", i)
+
+				svc, err := cache.GetByIP(ctx, fmt.Sprintf("10.96.0.%d", i))
+				if assert.NoError(t, err) && assert.NotNil(t, svc) {
+					assert.Equal(t, name, svc.Name)
+				}
+
+				endpointServices, err := cache.GetAllByIP(ctx, fmt.Sprintf("10.1.0.%d", i))
+				if assert.NoError(t, err) && assert.Len(t, endpointServices, 1) {
+					assert.Equal(t, name, endpointServices[0].Name)
+				}
+
+				svc, err = cache.GetByName(ctx, "default", name)
+				if assert.NoError(t, err) && assert.NotNil(t, svc) {
+					assert.Equal(t, name, svc.Name)
+				}
+			}
+		}(g)
+	}
+	readers.Wait()
+	close(stop)
+	wg.Wait()
+
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	assert.True(t, cache.truncated)
+	assert.LessOrEqual(t, len(cache.allServices), 5)
+	assert.LessOrEqual(t, len(cache.byName), 5)
+	assert.LessOrEqual(t, len(cache.byEndpoint), 5)
+}
+
+func TestServiceCacheGetByLabels(t *testing.T) {
+	ctx := context.Background()
+	appLabels := map[string]string{"tsuru.io/app-name": "my-app"}
+	objects := []client.Object{
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-app-web", Namespace: "tsuru-my-pool", Labels: appLabels},
+			Spec:       corev1.ServiceSpec{ClusterIP: "10.96.0.10"},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-app-worker", Namespace: "tsuru-my-pool", Labels: appLabels},
+			Spec:       corev1.ServiceSpec{ClusterIP: "10.96.0.11"},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-app-web", Namespace: "other", Labels: appLabels},
+			Spec:       corev1.ServiceSpec{ClusterIP: "10.96.0.12"},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-app-web", Namespace: "tsuru-my-pool", Labels: map[string]string{"tsuru.io/app-name": "other-app"}},
+			Spec:       corev1.ServiceSpec{ClusterIP: "10.96.0.13"},
+		},
+	}
+
+	names := func(services []*corev1.Service) []string {
+		result := []string{}
+		for _, svc := range services {
+			result = append(result, svc.Namespace+"/"+svc.Name)
+		}
+		return result
+	}
+
+	for _, maxEntries := range []int{0, 1} {
+		r := &ACLReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()}
+		cache := r.getServiceCache()
+		cache.maxEntries = maxEntries
+
+		// a truncated cache lists the services on the client instead
+		services, err := cache.GetByLabels(ctx, "tsuru-my-pool", labels.SelectorFromSet(appLabels))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"tsuru-my-pool/my-app-web", "tsuru-my-pool/my-app-worker"}, names(services), "maxEntries %d", maxEntries)
+	}
+}
+
+// fieldSelectorClient records the field selectors of the listings after the cache is filled
+type fieldSelectorClient struct {
+	client.Client
+	mu        sync.Mutex
+	recording bool
+	selectors []string
+}
+
+func (c *fieldSelectorClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	c.mu.Lock()
+	if c.recording {
+		selector := ""
+		if listOpts.FieldSelector != nil {
+			selector = listOpts.FieldSelector.String()
+		}
+		c.selectors = append(c.selectors, fmt.Sprintf("%T %s", list, selector))
+	}
+	c.mu.Unlock()
+	return c.Client.List(ctx, list, opts...)
+}
+
+func TestServiceCacheTruncatedLookupsUseIndexes(t *testing.T) {
+	ctx := context.Background()
+	objects := []client.Object{}
+	for i := 0; i < 3; i++ {
+		name := fmt.Sprintf("service-%d", i)
+		objects = append(objects,
+			&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec:       corev1.ServiceSpec{ClusterIP: fmt.Sprintf("10.96.0.%d", i)},
+			},
+			&discoveryv1.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name + "-abcde",
+					Namespace: "default",
+					Labels:    map[string]string{discoveryv1.LabelServiceName: name},
+				},
+				AddressType: discoveryv1.AddressTypeIPv4,
+				Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{fmt.Sprintf("10.1.0.%d", i)}}},
+			},
+		)
+	}
+
+	cli := &fieldSelectorClient{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()}
+	r := &ACLReconciler{Client: cli}
+	cache := r.getServiceCache()
+	cache.maxEntries = 1
+	_, err := cache.fillCache(ctx)
+	require.NoError(t, err)
+	require.True(t, cache.truncated)
+
+	// misses of a truncated cache list only the objects with the address on the indexes of manager
+	cache.mu.Lock()
+	cache.allServices = mapServiceCache{}
+	cache.byEndpoint = map[string]map[types.NamespacedName]types.NamespacedName{}
+	cache.mu.Unlock()
+	cli.mu.Lock()
+	cli.recording = true
+	cli.mu.Unlock()
+
+	svc, err := cache.GetByIP(ctx, "10.96.0.2")
+	require.NoError(t, err)
+	require.NotNil(t, svc)
+	assert.Equal(t, "service-2", svc.Name)
+
+	serviceNames, err := cache.lookupEndpointServices(ctx, "10.1.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, []types.NamespacedName{{Namespace: "default", Name: "service-1"}}, serviceNames)
+
+	svc, err = cache.GetByIP(ctx, "10.96.0.9")
+	require.NoError(t, err)
+	assert.Nil(t, svc)
+
+	assert.Equal(t, []string{
+		"*v1.ServiceList service-ip=10.96.0.2",
+		"*v1.EndpointSliceList endpoint-slice-ip=10.1.0.1",
+		"*v1.ServiceList service-ip=10.96.0.9",
+	}, cli.selectors)
+}
+
+func TestServiceCacheIndexKeys(t *testing.T) {
+	assert.Equal(t, []string{"10.96.0.10", "10.96.0.10", "fd00::10", "192.0.2.1"}, serviceIPIndexKeys(&corev1.Service{
+		Spec: corev1.ServiceSpec{
+			Type:       corev1.ServiceTypeLoadBalancer,
+			ClusterIP:  "10.96.0.10",
+			ClusterIPs: []string{"10.96.0.10", "fd00::10"},
+		},
+		Status: corev1.ServiceStatus{
+			LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{{IP: "192.0.2.1"}}},
+		},
+	}))
+	assert.Nil(t, serviceIPIndexKeys(&discoveryv1.EndpointSlice{}))
+
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta:  metav1.ObjectMeta{Labels: map[string]string{discoveryv1.LabelServiceName: "web"}},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{"10.1.0.1", "10.1.0.2"}}},
+	}
+	assert.Equal(t, []string{"10.1.0.1", "10.1.0.2"}, endpointSliceIPIndexKeys(slice))
+	slice.Labels = nil
+	assert.Nil(t, endpointSliceIPIndexKeys(slice))
+}
+
+func TestServiceClusterIPs(t *testing.T) {
+	assert.Equal(t, []string{"10.96.0.10", "fd00::10"}, serviceClusterIPs(&corev1.Service{
+		Spec: corev1.ServiceSpec{ClusterIP: "10.96.0.10", ClusterIPs: []string{"10.96.0.10", "fd00::10"}},
+	}))
+	assert.Equal(t, []string{}, serviceClusterIPs(&corev1.Service{
+		Spec: corev1.ServiceSpec{ClusterIP: corev1.ClusterIPNone, ClusterIPs: []string{corev1.ClusterIPNone}},
+	}))
+}
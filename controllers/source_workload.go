@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+)
+
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch
+
+const (
+	workloadIndex = "workload"
+
+	// missingWorkloadRequeueInterval retries ACLs whose workload does not exist yet, the watch on
+	// workloads enqueues them as soon as it is created, the interval covers a missed event
+	missingWorkloadRequeueInterval = 30 * time.Second
+)
+
+// errEmptyWorkloadSelector is returned for workloads without selector, which would select every pod
+// of the namespace
+var errEmptyWorkloadSelector = errors.New("the selector of workload is empty")
+
+// workloadNotFoundError is returned while the workload of spec.source does not exist, like an ACL
+// created before its workload
+type workloadNotFoundError struct {
+	key string
+}
+
+func (e *workloadNotFoundError) Error() string {
+	return fmt.Sprintf("workload %s not found", e.key)
+}
+
+// workloadKey is the key of a workload on workloadIndex, as namespace/kind/name
+func workloadKey(namespace, kind, name string) string {
+	return namespace + "/" + kind + "/" + name
+}
+
+// workloadObject returns an empty object of the kind of workload
+func workloadObject(workload *v1alpha1.ACLSpecWorkload) (client.Object, error) {
+	switch workload.Kind {
+	case v1alpha1.WorkloadKindDeployment:
+		return &appsv1.Deployment{}, nil
+	case v1alpha1.WorkloadKindStatefulSet:
+		return &appsv1.StatefulSet{}, nil
+	}
+	return nil, workload.Validate()
+}
+
+// workloadMetadata is the metadata of a workload of kind, workloads are watched by their metadata
+// so the manager does not cache every Deployment and StatefulSet of the cluster
+func workloadMetadata(kind string) *metav1.PartialObjectMetadata {
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind(kind))
+	return obj
+}
+
+// podSelectorForWorkload returns the selector of a workload of namespace, the selector is used
+// instead of the labels of the pod template, labels like versions change on every rollout while the
+// pods of the previous template still run, and the selector always matches the template
+func (r *ACLReconciler) podSelectorForWorkload(ctx context.Context, namespace string, workload *v1alpha1.ACLSpecWorkload) (*metav1.LabelSelector, error) {
+	obj, err := workloadObject(workload)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.apiReader().Get(ctx, client.ObjectKey{Namespace: namespace, Name: workload.Name}, obj)
+	if k8sErrors.IsNotFound(err) {
+		return nil, &workloadNotFoundError{key: workloadKey(namespace, workload.Kind, workload.Name)}
+	} else if err != nil {
+		return nil, err
+	}
+
+	var selector *metav1.LabelSelector
+	switch obj := obj.(type) {
+	case *appsv1.Deployment:
+		selector = obj.Spec.Selector
+	case *appsv1.StatefulSet:
+		selector = obj.Spec.Selector
+	}
+
+	if selector == nil || (len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0) {
+		return nil, errors.Wrapf(errEmptyWorkloadSelector, "%s %s/%s", workload.Kind, namespace, workload.Name)
+	}
+	return selector.DeepCopy(), nil
+}
+
+// workloadVersion is the uid and generation of the workload of acl, the selector only changes along
+// with them, an empty string when the workload does not exist
+func (r *ACLReconciler) workloadVersion(ctx context.Context, acl *v1alpha1.ACL) (string, error) {
+	workload := acl.Spec.Source.Workload
+	if workload == nil {
+		return "", nil
+	}
+
+	if err := workload.Validate(); err != nil {
+		return "", err
+	}
+
+	obj := workloadMetadata(workload.Kind)
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: acl.Namespace, Name: workload.Name}, obj)
+	if k8sErrors.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%d", obj.GetUID(), obj.GetGeneration()), nil
+}
@@ -1,9 +1,17 @@
 package controllers
 
 import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
+	netv1 "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type ControllerSuite struct {
@@ -13,3 +21,97 @@ type ControllerSuite struct {
 func TestSuite(t *testing.T) {
 	suite.Run(t, new(ControllerSuite))
 }
+
+// applyClient emulates the server-side apply of NetworkPolicies, which is not supported by the fake client,
+// labels and annotations applied before by a field owner are removed when they are not applied again
+type applyClient struct {
+	client.Client
+
+	mu      sync.Mutex
+	applied map[string]map[string]bool
+}
+
+func withServerSideApply(c client.Client) *applyClient {
+	return &applyClient{Client: c, applied: map[string]map[string]bool{}}
+}
+
+func (c *applyClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+
+	appliedPolicy, ok := obj.(*netv1.NetworkPolicy)
+	if !ok {
+		return fmt.Errorf("apply of %T is not supported", obj)
+	}
+
+	patchOptions := &client.PatchOptions{}
+	patchOptions.ApplyOptions(opts)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ownerKey := patchOptions.FieldManager + "/" + client.ObjectKeyFromObject(appliedPolicy).String()
+	previous := c.applied[ownerKey]
+	current := map[string]bool{}
+	for key := range appliedPolicy.Labels {
+		current["label/"+key] = true
+	}
+	for key := range appliedPolicy.Annotations {
+		current["annotation/"+key] = true
+	}
+	c.applied[ownerKey] = current
+
+	existingPolicy := &netv1.NetworkPolicy{}
+	err := c.Client.Get(ctx, client.ObjectKeyFromObject(appliedPolicy), existingPolicy)
+	if k8sErrors.IsNotFound(err) {
+		appliedPolicy.ResourceVersion = ""
+		return c.Client.Create(ctx, appliedPolicy)
+	} else if err != nil {
+		return err
+	}
+
+	mergedPolicy := existingPolicy.DeepCopy()
+	mergedPolicy.Labels = applyMap(existingPolicy.Labels, appliedPolicy.Labels, previous, "label/")
+	mergedPolicy.Annotations = applyMap(existingPolicy.Annotations, appliedPolicy.Annotations, previous, "annotation/")
+	for _, ref := range appliedPolicy.OwnerReferences {
+		found := false
+		for i := range mergedPolicy.OwnerReferences {
+			if mergedPolicy.OwnerReferences[i].UID == ref.UID {
+				mergedPolicy.OwnerReferences[i] = ref
+				found = true
+			}
+		}
+		if !found {
+			mergedPolicy.OwnerReferences = append(mergedPolicy.OwnerReferences, ref)
+		}
+	}
+	// podSelector is an atomic struct, apply replaces it with the applied one like the rest of spec
+	mergedPolicy.Spec = appliedPolicy.Spec
+
+	if !reflect.DeepEqual(existingPolicy, mergedPolicy) {
+		err = c.Client.Update(ctx, mergedPolicy)
+		if err != nil {
+			return err
+		}
+	}
+
+	mergedPolicy.DeepCopyInto(appliedPolicy)
+	return nil
+}
+
+func applyMap(existing, applied map[string]string, previous map[string]bool, prefix string) map[string]string {
+	result := map[string]string{}
+	for key, value := range existing {
+		if !previous[prefix+key] {
+			result[key] = value
+		}
+	}
+	for key, value := range applied {
+		result[key] = value
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/go-logr/logr"
+	"github.com/tsuru/tsuru/app"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/tsuru/acl-operator/clients/tsuruapi"
+)
+
+// TsuruAPIEndpoint is the client of a single Tsuru API endpoint, Address names it on logs and metrics
+type TsuruAPIEndpoint struct {
+	Address string
+	Client  tsuruapi.Client
+}
+
+// FailoverTsuruAPI calls the endpoints in turn starting from the last one that answered, a call
+// moves to the next endpoint on transient errors, like connection errors and 5xx responses
+func FailoverTsuruAPI(endpoints []TsuruAPIEndpoint) tsuruapi.Client {
+	f := &failoverTsuruAPI{
+		endpoints: endpoints,
+	}
+	f.answered.Store(-1)
+	return f
+}
+
+type failoverTsuruAPI struct {
+	endpoints []TsuruAPIEndpoint
+
+	// preferred is the index of the endpoint tried first, the last one that answered unless
+	// a call ran out of time on it
+	preferred atomic.Int32
+	// answered is the index of the last endpoint that answered, -1 before the first answer
+	answered atomic.Int32
+}
+
+func (f *failoverTsuruAPI) AppInfo(ctx context.Context, appName string) (*app.App, error) {
+	var appInfo *app.App
+	err := f.call(ctx, "AppInfo", func(client tsuruapi.Client) (err error) {
+		appInfo, err = client.AppInfo(ctx, appName)
+		return err
+	})
+	return appInfo, err
+}
+
+func (f *failoverTsuruAPI) ServiceInstanceInfo(ctx context.Context, serviceName, instance string) (*tsuruapi.ServiceInstanceInfo, error) {
+	var info *tsuruapi.ServiceInstanceInfo
+	err := f.call(ctx, "ServiceInstanceInfo", func(client tsuruapi.Client) (err error) {
+		info, err = client.ServiceInstanceInfo(ctx, serviceName, instance)
+		return err
+	})
+	return info, err
+}
+
+func (f *failoverTsuruAPI) PoolApps(ctx context.Context, pool string) ([]string, error) {
+	var apps []string
+	err := f.call(ctx, "PoolApps", func(client tsuruapi.Client) (err error) {
+		apps, err = client.PoolApps(ctx, pool)
+		return err
+	})
+	return apps, err
+}
+
+func (f *failoverTsuruAPI) call(ctx context.Context, name string, call func(client tsuruapi.Client) error) error {
+	l := log.FromContext(ctx)
+
+	preferred := f.preferred.Load()
+	start := int(preferred)
+
+	var err error
+	for i := range f.endpoints {
+		index := (start + i) % len(f.endpoints)
+		endpoint := f.endpoints[index]
+
+		err = call(endpoint.Client)
+		if err == nil || !isTransientError(err) {
+			// answers that are not transient, like a missing app, come from a healthy endpoint
+			f.answeredBy(l, index)
+			return err
+		}
+
+		tsuruAPIEndpointFailuresTotal.WithLabelValues(endpoint.Address).Inc()
+		if ctx.Err() != nil {
+			// the deadline is shared by every endpoint, the next call starts on the next one
+			f.preferred.CompareAndSwap(preferred, int32((index+1)%len(f.endpoints)))
+			return err
+		}
+
+		if i < len(f.endpoints)-1 {
+			l.Info("tsuru API endpoint failed, trying the next one", "call", name, "endpoint", endpoint.Address, "error", err.Error())
+		}
+	}
+
+	return err
+}
+
+func (f *failoverTsuruAPI) answeredBy(l logr.Logger, index int) {
+	f.preferred.Store(int32(index))
+	previous := f.answered.Swap(int32(index))
+	if int(previous) == index {
+		return
+	}
+
+	address := f.endpoints[index].Address
+	if previous >= 0 {
+		l.Info("tsuru API endpoint changed", "endpoint", address, "previous", f.endpoints[previous].Address)
+	}
+
+	tsuruAPIEndpointActive.Reset()
+	tsuruAPIEndpointActive.WithLabelValues(address).Set(1)
+}
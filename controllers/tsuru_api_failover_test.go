@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tsuru/tsuru/app"
+
+	"github.com/tsuru/acl-operator/clients/tsuruapi"
+)
+
+type countingTsuruAPI struct {
+	tsuruapi.Client
+	calls int
+}
+
+func (c *countingTsuruAPI) AppInfo(ctx context.Context, appName string) (*app.App, error) {
+	c.calls++
+	return c.Client.AppInfo(ctx, appName)
+}
+
+func TestFailoverTsuruAPI(t *testing.T) {
+	region1 := &countingTsuruAPI{Client: &failingTsuruAPI{err: errors.New("connection refused")}}
+	region2 := &countingTsuruAPI{Client: &fakeTsuruAPI{}}
+	client := FailoverTsuruAPI([]TsuruAPIEndpoint{
+		{Address: "https://region1.tsuru.io", Client: region1},
+		{Address: "https://region2.tsuru.io", Client: region2},
+	})
+
+	failuresBefore := testutil.ToFloat64(tsuruAPIEndpointFailuresTotal.WithLabelValues("https://region1.tsuru.io"))
+	appInfo, err := client.AppInfo(context.Background(), "my-other-app")
+	require.NoError(t, err)
+	assert.Equal(t, "my-pool", appInfo.Pool)
+	assert.Equal(t, 1, region1.calls)
+	assert.Equal(t, 1, region2.calls)
+	assert.Equal(t, failuresBefore+1, testutil.ToFloat64(tsuruAPIEndpointFailuresTotal.WithLabelValues("https://region1.tsuru.io")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(tsuruAPIEndpointActive.WithLabelValues("https://region2.tsuru.io")))
+
+	// the last endpoint that answered is tried first, responses that are not transient are answers
+	region2.Client = &failingTsuruAPI{err: &tsuruapi.StatusError{StatusCode: http.StatusForbidden}}
+	_, err = client.AppInfo(context.Background(), "my-other-app")
+	assert.Error(t, err)
+	assert.Equal(t, 1, region1.calls)
+	assert.Equal(t, 2, region2.calls)
+
+	region2.Client = &failingTsuruAPI{err: &tsuruapi.StatusError{StatusCode: http.StatusBadGateway}}
+	_, err = client.AppInfo(context.Background(), "my-other-app")
+	assert.Equal(t, errors.New("connection refused"), err)
+	assert.Equal(t, 2, region1.calls)
+	assert.Equal(t, 3, region2.calls)
+
+	region1.Client = &fakeTsuruAPI{}
+	_, err = client.AppInfo(context.Background(), "my-other-app")
+	require.NoError(t, err)
+	assert.Equal(t, 3, region1.calls)
+	assert.Equal(t, 4, region2.calls)
+	assert.Equal(t, float64(1), testutil.ToFloat64(tsuruAPIEndpointActive.WithLabelValues("https://region1.tsuru.io")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(tsuruAPIEndpointActive.WithLabelValues("https://region2.tsuru.io")))
+}
+
+func TestFailoverTsuruAPIDeadline(t *testing.T) {
+	region1 := &countingTsuruAPI{Client: &slowTsuruAPI{cancelled: make(chan struct{})}}
+	region2 := &countingTsuruAPI{Client: &fakeTsuruAPI{}}
+	client := FailoverTsuruAPI([]TsuruAPIEndpoint{
+		{Address: "https://region1.tsuru.io", Client: region1},
+		{Address: "https://region2.tsuru.io", Client: region2},
+	})
+
+	// the deadline is spent on the first endpoint, the next call starts on the second one
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := client.AppInfo(ctx, "my-other-app")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, region1.calls)
+	assert.Equal(t, 0, region2.calls)
+
+	_, err = client.AppInfo(context.Background(), "my-other-app")
+	require.NoError(t, err)
+	assert.Equal(t, 1, region1.calls)
+	assert.Equal(t, 1, region2.calls)
+}
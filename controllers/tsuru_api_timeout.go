@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultTsuruAPITimeout is used by reconcilers without a TsuruAPITimeout
+const DefaultTsuruAPITimeout = 15 * time.Second
+
+// tsuruAPITimeoutError is returned when a call to Tsuru API does not answer in time,
+// it is a transient error so the object is requeued with backoff
+type tsuruAPITimeoutError struct {
+	call    string
+	timeout time.Duration
+}
+
+func (e *tsuruAPITimeoutError) Error() string {
+	return fmt.Sprintf("tsuru API did not answer %s in %s", e.call, e.timeout)
+}
+
+func (e *tsuruAPITimeoutError) Timeout() bool {
+	return true
+}
+
+func tsuruAPITimeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return DefaultTsuruAPITimeout
+	}
+	return timeout
+}
+
+// callTsuruAPI runs call with a deadline of timeout, the call is cancelled when the
+// deadline expires and a tsuruAPITimeoutError is returned unless ctx was cancelled first
+func callTsuruAPI(ctx context.Context, timeout time.Duration, name string, call func(ctx context.Context) error) error {
+	timeout = tsuruAPITimeout(timeout)
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := call(callCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		return &tsuruAPITimeoutError{call: name, timeout: timeout}
+	}
+	return err
+}
@@ -23,74 +23,166 @@ import (
 	"net"
 	"reflect"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"golang.org/x/sync/errgroup"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/tsuru/acl-operator/api/v1alpha1"
 	extensionstsuruiov1alpha1 "github.com/tsuru/acl-operator/api/v1alpha1"
 	"github.com/tsuru/acl-operator/clients/tsuruapi"
+	"github.com/tsuru/tsuru/app"
 	tsuruNet "github.com/tsuru/tsuru/net"
+	appTypes "github.com/tsuru/tsuru/types/app"
 )
 
 var errAppNotFound = errors.New("App not found")
 
+const maxConcurrentResolves = 8
+
 // TsuruAppAddressReconciler reconciles a TsuruAppAddress object
 type TsuruAppAddressReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Resolver ACLDNSResolver
 	TsuruAPI tsuruapi.Client
+
+	RequeueInterval time.Duration
+
+	// RequeueJitter spreads each periodic requeue by up to this fraction of the interval,
+	// more or less, the requeues are not spread when zero
+	RequeueJitter float64
+
+	// TsuruAPITimeout is the deadline of each call to Tsuru API, defaults to DefaultTsuruAPITimeout
+	TsuruAPITimeout time.Duration
+
+	// InternalAddresses resolves the internal addresses of apps as well, the services used by
+	// app-to-app traffic inside the cluster, the router addresses are always resolved
+	InternalAddresses bool
+
+	// LookupTimeout is the deadline of the lookups of the addresses of an app, defaults to DefaultDNSLookupTimeout
+	LookupTimeout time.Duration
+
+	// Refresh enqueues objects on demand, like the resyncs of LeaderResync
+	Refresh *RefreshTrigger
+
+	// MaxConcurrentReconciles is the number of objects reconciled at once, defaults to DefaultConcurrency
+	MaxConcurrentReconciles int
+
+	backoff requeueBackoff
 }
 
 //+kubebuilder:rbac:groups=extensions.tsuru.io,resources=tsuruappaddresses,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=extensions.tsuru.io,resources=tsuruappaddresses/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=extensions.tsuru.io,resources=tsuruappaddresses/finalizers,verbs=update
 
-func (r *TsuruAppAddressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *TsuruAppAddressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
 	l := log.FromContext(ctx)
 
 	appAddress := &v1alpha1.TsuruAppAddress{}
-	err := r.Client.Get(ctx, req.NamespacedName, appAddress)
+	outcome := reconcileResultNoop
+	defer func() {
+		observeReconcileResult("tsuruappaddress", outcome, err)
+	}()
+
+	err = r.Client.Get(ctx, req.NamespacedName, appAddress)
 	if k8sErrors.IsNotFound(err) {
+		tsuruAppAddressResolvedIPs.DeleteLabelValues(req.Name)
+		tsuruAppAddressLastSuccess.DeleteLabelValues(req.Name)
 		return ctrl.Result{}, nil
 	} else if err != nil {
 		l.Error(err, "could not get TsuruAppAddress object")
 		return ctrl.Result{}, err
 	}
 
+	ctx, l = objectLogger(ctx, appAddress, "app", appAddress.Spec.Name)
+
+	// the addresses are kept as they are, the ACLs using them are not affected
+	if isPaused(appAddress) {
+		l.V(1).Info("TsuruAppAddress is paused, skipping reconcile", "annotation", pausedAnnotation)
+		outcome = reconcileResultPaused
+		return ctrl.Result{}, nil
+	}
+
 	oldStatus := appAddress.Status.DeepCopy()
-	err = r.FillStatus(ctx, appAddress)
+	ttl, err := r.fillStatus(ctx, appAddress)
+	var retryAfter time.Duration
 	if err != nil {
 		appAddress.Status.Ready = false
 		appAddress.Status.Reason = err.Error()
+		outcome = reconcileResultError
+
+		if isTransientError(err) {
+			retryAfter = r.backoff.Next(req.Name)
+			l.Error(err, "transient error on TsuruAppAddress, retrying", "retryAfter", retryAfter)
+		} else {
+			r.backoff.Reset(req.Name)
+			retryAfter = jitterRequeue(requeueInterval(r.RequeueInterval), r.RequeueJitter)
+		}
+	} else {
+		r.backoff.Reset(req.Name)
+		tsuruAppAddressLastSuccess.WithLabelValues(appAddress.Name).SetToCurrentTime()
 	}
+	tsuruAppAddressResolvedIPs.WithLabelValues(appAddress.Name).Set(float64(len(appAddress.Status.IPs)))
 
-	if oldStatus.Pool != appAddress.Status.Pool || oldStatus.Ready != appAddress.Status.Ready || !reflect.DeepEqual(oldStatus.IPs, appAddress.Status.IPs) {
+	if oldStatus.Pool != appAddress.Status.Pool || oldStatus.Ready != appAddress.Status.Ready || oldStatus.Reason != appAddress.Status.Reason || !reflect.DeepEqual(oldStatus.IPs, appAddress.Status.IPs) || !reflect.DeepEqual(oldStatus.InternalIPs, appAddress.Status.InternalIPs) || !reflect.DeepEqual(oldStatus.Failures, appAddress.Status.Failures) {
 		err = r.Client.Status().Update(ctx, appAddress)
-		return ctrl.Result{}, err
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if outcome == reconcileResultNoop {
+			outcome = reconcileResultUpdated
+		}
+	}
+
+	if !appAddress.Status.Ready {
+		return ctrl.Result{
+			Requeue:      true,
+			RequeueAfter: retryAfter,
+		}, nil
 	}
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{
+		Requeue:      true,
+		RequeueAfter: jitterRequeue(requeueAfterForTTL(ttl, r.RequeueInterval), r.RequeueJitter),
+	}, nil
 }
 
 func (r *TsuruAppAddressReconciler) FillStatus(ctx context.Context, appAddress *v1alpha1.TsuruAppAddress) error {
-	appInfo, err := r.TsuruAPI.AppInfo(ctx, appAddress.Spec.Name)
-	if err != nil {
+	_, err := r.fillStatus(ctx, appAddress)
+	return err
+}
+
+func (r *TsuruAppAddressReconciler) fillStatus(ctx context.Context, appAddress *v1alpha1.TsuruAppAddress) (time.Duration, error) {
+	var appInfo *app.App
+	err := callTsuruAPI(ctx, r.TsuruAPITimeout, "app info of "+appAddress.Spec.Name, func(ctx context.Context) (err error) {
+		appInfo, err = r.TsuruAPI.AppInfo(ctx, appAddress.Spec.Name)
 		return err
+	})
+	if err != nil {
+		return 0, err
 	}
 
 	if appInfo == nil {
-		return errAppNotFound
+		return 0, errAppNotFound
 	}
 
-	addrs := make([]string, 0, len(appInfo.Routers))
-	for _, r := range appInfo.Routers {
+	routers, err := appAddressRouters(appInfo, appAddress.Spec.Routers)
+	if err != nil {
+		return 0, err
+	}
+
+	addrs := make([]string, 0, len(routers))
+	for _, r := range routers {
 		if len(r.Addresses) > 0 {
 			for _, addr := range r.Addresses {
 				addrs = append(addrs, tsuruNet.URLToHost(addr))
@@ -100,50 +192,156 @@ func (r *TsuruAppAddressReconciler) FillStatus(ctx context.Context, appAddress *
 		}
 	}
 
+	// addresses after the routers ones are internal
+	routerAddrs := len(addrs)
+	if r.InternalAddresses {
+		addrs = append(addrs, internalAddressHosts(appInfo)...)
+	}
+
+	// all addresses share the same deadline, so many routers do not multiply the reconcile time
+	resolveCtx, cancel := withDNSLookupTimeout(ctx, r.LookupTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var ttl time.Duration
 	foundIPs := map[string]bool{}
-	for _, addr := range addrs {
-		ipAddrs, err := r.resolveAddress(ctx, addr)
-		if err != nil {
-			return err
-		}
+	foundInternalIPs := map[string]bool{}
 
-		if len(ipAddrs) == 0 {
-			return fmt.Errorf("host %s returned a empty string by resolver", addr)
-		}
+	// errors are kept by address to report the same error of a serial resolution
+	errs := make([]error, len(addrs))
+
+	var g errgroup.Group
+	g.SetLimit(maxConcurrentResolves)
+	for i, addr := range addrs {
+		i, addr := i, addr
+		g.Go(func() error {
+			ipAddrs, addrTTL, err := r.resolveAddress(resolveCtx, addr)
+			if err != nil {
+				errs[i] = err
+				return nil
+			}
+
+			if len(ipAddrs) == 0 {
+				errs[i] = fmt.Errorf("host %s returned a empty string by resolver", addr)
+				return nil
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			found := foundIPs
+			if i >= routerAddrs {
+				found = foundInternalIPs
+			}
 
-		for _, ipAddr := range ipAddrs {
-			foundIPs[ipAddr.IP.String()] = true
+			ttl = minTTL(ttl, addrTTL)
+			for _, ipAddr := range ipAddrs {
+				found[ipAddr.IP.String()] = true
+			}
+			return nil
+		})
+	}
+	g.Wait()
+
+	// a partial resolution is still ready, the failed hosts are only reported
+	var failures []v1alpha1.ResolutionFailure
+	var firstErr error
+	for i, err := range errs {
+		if err == nil {
+			continue
 		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		failures = append(failures, resolutionFailure(addrs[i], err, appAddress.Status.Failures))
 	}
+	appAddress.Status.Failures = failures
 
-	var resolvedIPs []string
-	for ip := range foundIPs {
-		resolvedIPs = append(resolvedIPs, ip)
+	if len(foundIPs) == 0 && len(foundInternalIPs) == 0 && firstErr != nil {
+		return 0, firstErr
 	}
-	sort.Strings(resolvedIPs)
+
+	resolvedIPs := sortedIPs(foundIPs)
+	resolvedInternalIPs := sortedIPs(foundInternalIPs)
 
 	appAddress.Status.Pool = appInfo.Pool
 
-	if !appAddress.Status.Ready || !reflect.DeepEqual(resolvedIPs, appAddress.Status.IPs) {
+	if !appAddress.Status.Ready || !reflect.DeepEqual(resolvedIPs, appAddress.Status.IPs) || !reflect.DeepEqual(resolvedInternalIPs, appAddress.Status.InternalIPs) {
 		appAddress.Status.Ready = true
 		appAddress.Status.Reason = ""
 		appAddress.Status.IPs = resolvedIPs
+		appAddress.Status.InternalIPs = resolvedInternalIPs
 		appAddress.Status.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
 	}
 
-	return nil
+	return ttl, nil
 }
 
-func (r *TsuruAppAddressReconciler) resolveAddress(ctx context.Context, addr string) ([]net.IPAddr, error) {
-	timoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-	return r.Resolver.LookupIPAddr(timoutCtx, addr)
+// internalAddressHosts returns the domains of the internal addresses of app, each domain is
+// listed once though it is repeated for every port of the service
+func internalAddressHosts(appInfo *app.App) []string {
+	seen := map[string]bool{}
+	hosts := []string{}
+	for _, internalAddress := range appInfo.InternalAddresses {
+		if internalAddress.Domain == "" || seen[internalAddress.Domain] {
+			continue
+		}
+		seen[internalAddress.Domain] = true
+		hosts = append(hosts, internalAddress.Domain)
+	}
+	return hosts
+}
+
+// sortedIPs returns nil for an empty set, like the IPs of a status that were never resolved
+func sortedIPs(set map[string]bool) []string {
+	var ips []string
+	for ip := range set {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+func (r *TsuruAppAddressReconciler) resolveAddress(ctx context.Context, addr string) ([]net.IPAddr, time.Duration, error) {
+	ipAddrs, ttl, err := lookupIPAddrTTL(ctx, r.Resolver, addr)
+	if err != nil {
+		dnsLookupFailuresTotal.WithLabelValues(addr).Inc()
+	}
+	return ipAddrs, ttl, err
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *TsuruAppAddressReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&extensionstsuruiov1alpha1.TsuruAppAddress{}).
-		WithOptions(controller.Options{MaxConcurrentReconciles: 2, RecoverPanic: true}).
-		Complete(r)
+		WithOptions(controller.Options{MaxConcurrentReconciles: maxConcurrentReconciles(r.MaxConcurrentReconciles, DefaultConcurrency), RecoverPanic: true})
+
+	if r.Refresh != nil {
+		builder = builder.Watches(&source.Channel{Source: r.Refresh.tsuruAppAddresses}, &handler.EnqueueRequestForObject{})
+	}
+
+	return builder.Complete(r)
+}
+
+// appAddressRouters returns the routers of app named on names, every router when names is empty, it
+// fails when app has none of them, like when the router was renamed or removed from app
+func appAddressRouters(appInfo *app.App, names []string) ([]appTypes.AppRouter, error) {
+	if len(names) == 0 {
+		return appInfo.Routers, nil
+	}
+
+	routers := []appTypes.AppRouter{}
+	for _, router := range appInfo.Routers {
+		if containsString(names, router.Name) {
+			routers = append(routers, router)
+		}
+	}
+	if len(routers) == 0 {
+		available := make([]string, 0, len(appInfo.Routers))
+		for _, router := range appInfo.Routers {
+			available = append(available, router.Name)
+		}
+		return nil, fmt.Errorf("app %s has none of the routers %s, its routers are %s", appInfo.Name, strings.Join(names, ", "), strings.Join(available, ", "))
+	}
+	return routers, nil
 }
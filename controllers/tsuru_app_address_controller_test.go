@@ -3,13 +3,18 @@ package controllers
 import (
 	"context"
 	"errors"
+	"net/http"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tsuru/acl-operator/api/scheme"
 	"github.com/tsuru/acl-operator/api/v1alpha1"
+	"github.com/tsuru/acl-operator/clients/tsuruapi"
+	"github.com/tsuru/tsuru/app"
+	appTypes "github.com/tsuru/tsuru/types/app"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	controllerruntime "sigs.k8s.io/controller-runtime"
@@ -110,4 +115,400 @@ func TestControllerResolveWithError(t *testing.T) {
 	assert.Equal(t, []string{"10.1.1.57"}, existingTsuruAppAddress.Status.IPs)
 	assert.False(t, existingTsuruAppAddress.Status.Ready)
 	assert.Equal(t, "a error", existingTsuruAppAddress.Status.Reason)
+	require.Len(t, existingTsuruAppAddress.Status.Failures, 2)
+	assert.Equal(t, "myapp.io", existingTsuruAppAddress.Status.Failures[0].Host)
+	assert.Equal(t, "a error", existingTsuruAppAddress.Status.Failures[0].Error)
+	assert.NotEmpty(t, existingTsuruAppAddress.Status.Failures[0].Timestamp)
+	assert.Equal(t, "http.myapp.io", existingTsuruAppAddress.Status.Failures[1].Host)
+}
+
+func TestControllerResolvePartialFailure(t *testing.T) {
+	tsuruAppAddress := &v1alpha1.TsuruAppAddress{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "my-other-app",
+		},
+		Spec: v1alpha1.TsuruAppAddressSpec{
+			Name: "my-other-app",
+		},
+		Status: v1alpha1.ResourceAddressStatus{
+			Failures: []v1alpha1.ResolutionFailure{
+				{Host: "http.myapp.io", Error: "a error", Timestamp: "2022-01-01T00:00:00Z"},
+			},
+		},
+	}
+
+	controller := &TsuruAppAddressReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(tsuruAppAddress).Build(),
+		Scheme:   scheme.Scheme,
+		TsuruAPI: &fakeTsuruAPI{},
+		Resolver: &fakeResolver{
+			hosts: map[string][]string{
+				"myapp.io": {"10.1.1.2"},
+			},
+			errors: map[string]error{
+				"http.myapp.io": errors.New("a error"),
+			},
+		},
+	}
+
+	_, err := controller.Reconcile(context.Background(), controllerruntime.Request{
+		NamespacedName: types.NamespacedName{
+			Name: tsuruAppAddress.Name,
+		},
+	})
+	require.NoError(t, err)
+
+	existingTsuruAppAddress := &v1alpha1.TsuruAppAddress{}
+	err = controller.Client.Get(context.Background(), types.NamespacedName{
+		Name: tsuruAppAddress.Name,
+	}, existingTsuruAppAddress)
+	require.NoError(t, err)
+
+	assert.True(t, existingTsuruAppAddress.Status.Ready)
+	assert.Equal(t, []string{"10.1.1.2"}, existingTsuruAppAddress.Status.IPs)
+	// the timestamp of a failure that persists is kept
+	assert.Equal(t, []v1alpha1.ResolutionFailure{
+		{Host: "http.myapp.io", Error: "a error", Timestamp: "2022-01-01T00:00:00Z"},
+	}, existingTsuruAppAddress.Status.Failures)
+}
+
+func TestControllerResolveMultipleRouters(t *testing.T) {
+	tsuruAppAddress := &v1alpha1.TsuruAppAddress{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "my-other-app",
+		},
+		Spec: v1alpha1.TsuruAppAddressSpec{
+			Name: "my-other-app",
+		},
+	}
+
+	controller := &TsuruAppAddressReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(tsuruAppAddress).Build(),
+		Scheme:   scheme.Scheme,
+		TsuruAPI: &fakeTsuruAPI{},
+		Resolver: &fakeResolver{
+			hosts: map[string][]string{
+				"myapp.io":      {"10.1.1.2"},
+				"http.myapp.io": {"10.1.1.3", "10.1.1.2"},
+			},
+		},
+	}
+
+	_, err := controller.Reconcile(context.Background(), controllerruntime.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      tsuruAppAddress.Name,
+			Namespace: tsuruAppAddress.Namespace,
+		},
+	})
+
+	require.NoError(t, err)
+
+	existingTsuruAppAddress := &v1alpha1.TsuruAppAddress{}
+	err = controller.Client.Get(context.Background(), types.NamespacedName{
+		Name:      tsuruAppAddress.Name,
+		Namespace: tsuruAppAddress.Namespace,
+	}, existingTsuruAppAddress)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"10.1.1.2", "10.1.1.3"}, existingTsuruAppAddress.Status.IPs)
+	assert.True(t, existingTsuruAppAddress.Status.Ready)
+	assert.Equal(t, "my-pool", existingTsuruAppAddress.Status.Pool)
+}
+
+func TestControllerMetrics(t *testing.T) {
+	tsuruAppAddress := &v1alpha1.TsuruAppAddress{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "metrics-app",
+		},
+		Spec: v1alpha1.TsuruAppAddressSpec{
+			Name: "my-other-app",
+		},
+	}
+
+	controller := &TsuruAppAddressReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(tsuruAppAddress).Build(),
+		Scheme:   scheme.Scheme,
+		TsuruAPI: &fakeTsuruAPI{},
+		Resolver: &fakeResolver{
+			hosts: map[string][]string{
+				"myapp.io":      {"10.1.1.2"},
+				"http.myapp.io": {"10.1.1.3"},
+			},
+		},
+	}
+	req := controllerruntime.Request{
+		NamespacedName: types.NamespacedName{
+			Name: tsuruAppAddress.Name,
+		},
+	}
+
+	before := float64(time.Now().Unix())
+	_, err := controller.Reconcile(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), testutil.ToFloat64(tsuruAppAddressResolvedIPs.WithLabelValues("metrics-app")))
+	lastSuccess := testutil.ToFloat64(tsuruAppAddressLastSuccess.WithLabelValues("metrics-app"))
+	assert.GreaterOrEqual(t, lastSuccess, before)
+
+	controller.TsuruAPI = &failingTsuruAPI{err: errors.New("tsuru is down")}
+	_, err = controller.Reconcile(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), testutil.ToFloat64(tsuruAppAddressResolvedIPs.WithLabelValues("metrics-app")))
+	assert.Equal(t, lastSuccess, testutil.ToFloat64(tsuruAppAddressLastSuccess.WithLabelValues("metrics-app")))
+
+	require.NoError(t, controller.Client.Delete(context.Background(), tsuruAppAddress))
+	_, err = controller.Reconcile(context.Background(), req)
+	require.NoError(t, err)
+	assert.False(t, tsuruAppAddressLastSuccess.DeleteLabelValues("metrics-app"), "metric should be deleted with the object")
+	assert.False(t, tsuruAppAddressResolvedIPs.DeleteLabelValues("metrics-app"), "metric should be deleted with the object")
+}
+
+func TestControllerResolveInternalAddresses(t *testing.T) {
+	tsuruAppAddress := &v1alpha1.TsuruAppAddress{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "my-other-app",
+		},
+		Spec: v1alpha1.TsuruAppAddressSpec{
+			Name: "my-other-app",
+		},
+	}
+
+	resolver := &fakeResolver{
+		hosts: map[string][]string{
+			"myapp.io":      {"10.1.1.2"},
+			"http.myapp.io": {"10.1.1.3"},
+			"my-other-app-web.tsuru-my-pool.svc.cluster.local":    {"172.16.0.10"},
+			"my-other-app-worker.tsuru-my-pool.svc.cluster.local": {"172.16.0.11"},
+		},
+	}
+	controller := &TsuruAppAddressReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(tsuruAppAddress).Build(),
+		Scheme:   scheme.Scheme,
+		TsuruAPI: &fakeTsuruAPI{},
+		Resolver: resolver,
+	}
+
+	reconcile := func() *v1alpha1.TsuruAppAddress {
+		_, err := controller.Reconcile(context.Background(), controllerruntime.Request{
+			NamespacedName: types.NamespacedName{Name: tsuruAppAddress.Name},
+		})
+		require.NoError(t, err)
+
+		existingTsuruAppAddress := &v1alpha1.TsuruAppAddress{}
+		err = controller.Client.Get(context.Background(), types.NamespacedName{Name: tsuruAppAddress.Name}, existingTsuruAppAddress)
+		require.NoError(t, err)
+		return existingTsuruAppAddress
+	}
+
+	// only router addresses are resolved by default
+	existingTsuruAppAddress := reconcile()
+	assert.Equal(t, []string{"10.1.1.2", "10.1.1.3"}, existingTsuruAppAddress.Status.IPs)
+	assert.Nil(t, existingTsuruAppAddress.Status.InternalIPs)
+
+	controller.InternalAddresses = true
+	existingTsuruAppAddress = reconcile()
+	assert.True(t, existingTsuruAppAddress.Status.Ready)
+	assert.Equal(t, []string{"10.1.1.2", "10.1.1.3"}, existingTsuruAppAddress.Status.IPs)
+	assert.Equal(t, []string{"172.16.0.10", "172.16.0.11"}, existingTsuruAppAddress.Status.InternalIPs)
+
+	// the internal addresses are allowed along with the router ones
+	aclReconciler := &ACLReconciler{}
+	egress, errs := aclReconciler.egressRulesForResourceAddressStatus(context.Background(), existingTsuruAppAddress.Status)
+	require.Empty(t, errs)
+	assert.Len(t, egress, 4)
+}
+
+type failingTsuruAPI struct {
+	fakeTsuruAPI
+	err error
+}
+
+func (f *failingTsuruAPI) AppInfo(ctx context.Context, appName string) (*app.App, error) {
+	return nil, f.err
+}
+
+func TestControllerTsuruAPIErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedReason string
+		minRequeue     time.Duration
+		maxRequeue     time.Duration
+	}{
+		{
+			name:           "unavailable",
+			err:            &tsuruapi.StatusError{StatusCode: http.StatusServiceUnavailable},
+			expectedReason: "failed to request, status code: 503",
+			minRequeue:     defaultBackoffBase,
+			maxRequeue:     defaultBackoffBase * 3 / 2,
+		},
+		{
+			name:           "network",
+			err:            errors.New("connection refused"),
+			expectedReason: "connection refused",
+			minRequeue:     defaultBackoffBase,
+			maxRequeue:     defaultBackoffBase * 3 / 2,
+		},
+		{
+			name:           "not found",
+			expectedReason: "App not found",
+			minRequeue:     DefaultRequeueInterval,
+			maxRequeue:     DefaultRequeueInterval,
+		},
+		{
+			name:           "forbidden",
+			err:            &tsuruapi.StatusError{StatusCode: http.StatusForbidden},
+			expectedReason: "failed to request, status code: 403",
+			minRequeue:     DefaultRequeueInterval,
+			maxRequeue:     DefaultRequeueInterval,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tsuruAppAddress := &v1alpha1.TsuruAppAddress{
+				ObjectMeta: v1.ObjectMeta{
+					Name: "my-app",
+				},
+				Spec: v1alpha1.TsuruAppAddressSpec{
+					Name: "my-app",
+				},
+			}
+
+			controller := &TsuruAppAddressReconciler{
+				Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(tsuruAppAddress).Build(),
+				Scheme:   scheme.Scheme,
+				TsuruAPI: &failingTsuruAPI{err: tt.err},
+				Resolver: &fakeResolver{},
+			}
+
+			result, err := controller.Reconcile(context.Background(), controllerruntime.Request{
+				NamespacedName: types.NamespacedName{
+					Name: tsuruAppAddress.Name,
+				},
+			})
+			require.NoError(t, err)
+			assert.GreaterOrEqual(t, result.RequeueAfter, tt.minRequeue)
+			assert.LessOrEqual(t, result.RequeueAfter, tt.maxRequeue)
+
+			existingTsuruAppAddress := &v1alpha1.TsuruAppAddress{}
+			err = controller.Client.Get(context.Background(), types.NamespacedName{
+				Name: tsuruAppAddress.Name,
+			}, existingTsuruAppAddress)
+			require.NoError(t, err)
+			assert.False(t, existingTsuruAppAddress.Status.Ready)
+			assert.Equal(t, tt.expectedReason, existingTsuruAppAddress.Status.Reason)
+		})
+	}
+}
+
+type slowTsuruAPI struct {
+	fakeTsuruAPI
+	cancelled chan struct{}
+}
+
+func (s *slowTsuruAPI) AppInfo(ctx context.Context, appName string) (*app.App, error) {
+	<-ctx.Done()
+	close(s.cancelled)
+	return nil, ctx.Err()
+}
+
+func TestControllerTsuruAPITimeout(t *testing.T) {
+	tsuruAppAddress := &v1alpha1.TsuruAppAddress{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "my-app",
+		},
+		Spec: v1alpha1.TsuruAppAddressSpec{
+			Name: "my-app",
+		},
+	}
+
+	tsuruAPI := &slowTsuruAPI{cancelled: make(chan struct{})}
+	controller := &TsuruAppAddressReconciler{
+		Client:          fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(tsuruAppAddress).Build(),
+		Scheme:          scheme.Scheme,
+		TsuruAPI:        tsuruAPI,
+		TsuruAPITimeout: 50 * time.Millisecond,
+		Resolver:        &fakeResolver{},
+	}
+
+	result, err := controller.Reconcile(context.Background(), controllerruntime.Request{
+		NamespacedName: types.NamespacedName{
+			Name: tsuruAppAddress.Name,
+		},
+	})
+	require.NoError(t, err)
+
+	select {
+	case <-tsuruAPI.cancelled:
+	default:
+		t.Fatal("call to Tsuru API was not cancelled")
+	}
+
+	assert.True(t, result.Requeue)
+	assert.GreaterOrEqual(t, result.RequeueAfter, defaultBackoffBase)
+	assert.LessOrEqual(t, result.RequeueAfter, defaultBackoffBase*3/2)
+
+	existingTsuruAppAddress := &v1alpha1.TsuruAppAddress{}
+	err = controller.Client.Get(context.Background(), types.NamespacedName{
+		Name: tsuruAppAddress.Name,
+	}, existingTsuruAppAddress)
+	require.NoError(t, err)
+	assert.False(t, existingTsuruAppAddress.Status.Ready)
+	assert.Equal(t, "tsuru API did not answer app info of my-app in 50ms", existingTsuruAppAddress.Status.Reason)
+}
+
+func TestCallTsuruAPI(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := callTsuruAPI(ctx, time.Minute, "app info", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	var timeoutErr *tsuruAPITimeoutError
+	err = callTsuruAPI(context.Background(), time.Millisecond, "app info", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.True(t, isTransientError(err))
+}
+
+func TestRequeueBackoff(t *testing.T) {
+	backoff := &requeueBackoff{
+		Base: time.Second,
+		Max:  4 * time.Second,
+	}
+
+	for _, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		d := backoff.Next("my-app")
+		assert.GreaterOrEqual(t, d, expected)
+		assert.LessOrEqual(t, d, expected*3/2)
+	}
+
+	backoff.Reset("my-app")
+	assert.LessOrEqual(t, backoff.Next("my-app"), 3*time.Second/2)
+}
+
+func TestAppAddressRouters(t *testing.T) {
+	appInfo := &app.App{
+		Name: "my-app",
+		Routers: []appTypes.AppRouter{
+			{Name: "internal-router", Address: "my-app.internal.example.com"},
+			{Name: "external-router", Address: "my-app.example.com"},
+		},
+	}
+
+	routers, err := appAddressRouters(appInfo, nil)
+	require.NoError(t, err)
+	assert.Equal(t, appInfo.Routers, routers)
+
+	routers, err = appAddressRouters(appInfo, []string{"external-router", "other-router"})
+	require.NoError(t, err)
+	assert.Equal(t, []appTypes.AppRouter{{Name: "external-router", Address: "my-app.example.com"}}, routers)
+
+	_, err = appAddressRouters(appInfo, []string{"other-router"})
+	assert.EqualError(t, err, "app my-app has none of the routers other-router, its routers are internal-router, external-router")
 }
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"time"
 
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -41,6 +42,11 @@ type TsuruAppReconciler struct {
 	Scheme *runtime.Scheme
 
 	ACLAPI aclapi.Client
+
+	RequeueInterval time.Duration
+
+	// MaxConcurrentReconciles is the number of objects reconciled at once, defaults to DefaultConcurrency
+	MaxConcurrentReconciles int
 }
 
 func (r *TsuruAppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -54,6 +60,8 @@ func (r *TsuruAppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{}, err
 	}
 
+	ctx, l = objectLogger(ctx, app)
+
 	rules, err := r.ACLAPI.AppRules(ctx, app.Name)
 	if err != nil {
 		l.Error(err, "could not get Tsuru App Rules from ACLAPI")
@@ -100,7 +108,7 @@ func (r *TsuruAppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 
 		return ctrl.Result{
 			Requeue:      true,
-			RequeueAfter: requeueAfter,
+			RequeueAfter: requeueInterval(r.RequeueInterval),
 		}, nil
 	} else if err != nil {
 		l.Error(err, "could not get ACL object")
@@ -136,7 +144,7 @@ func (r *TsuruAppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 
 	return ctrl.Result{
 		Requeue:      true,
-		RequeueAfter: requeueAfter,
+		RequeueAfter: requeueInterval(r.RequeueInterval),
 	}, nil
 }
 
@@ -252,6 +260,6 @@ func convertPorts(ports aclapi.ProtoPorts) v1alpha1.ACLSpecProtoPorts {
 func (r *TsuruAppReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&tsuruv1.App{}).
-		WithOptions(controller.Options{MaxConcurrentReconciles: 2, RecoverPanic: true}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: maxConcurrentReconciles(r.MaxConcurrentReconciles, DefaultConcurrency), RecoverPanic: true}).
 		Complete(r)
 }
@@ -18,6 +18,7 @@ package controllers
 
 import (
 	"context"
+	"time"
 
 	v1alpha1 "github.com/tsuru/acl-operator/api/v1alpha1"
 	aclapi "github.com/tsuru/acl-operator/clients/aclapi"
@@ -32,7 +33,6 @@ import (
 )
 
 const (
-	tsuruJobLabel     = "tsuru.io/job-name"
 	tsuruJobACLPrefix = "tsuru-job-"
 )
 
@@ -42,6 +42,14 @@ type TsuruCronJobReconciler struct {
 	Scheme *runtime.Scheme
 
 	ACLAPI aclapi.Client
+
+	RequeueInterval time.Duration
+
+	// LabelScheme are the labels of tsuru jobs, empty keys use DefaultLabelScheme
+	LabelScheme LabelScheme
+
+	// MaxConcurrentReconciles is the number of objects reconciled at once, defaults to DefaultConcurrency
+	MaxConcurrentReconciles int
 }
 
 func (r *TsuruCronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -54,7 +62,9 @@ func (r *TsuruCronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, err
 	}
 
-	jobName := job.Labels[tsuruJobLabel]
+	ctx, l = objectLogger(ctx, job)
+
+	jobName := job.Labels[r.LabelScheme.WithDefaults().JobName]
 
 	if jobName == "" {
 		return ctrl.Result{}, nil
@@ -108,7 +118,7 @@ func (r *TsuruCronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request
 
 		return ctrl.Result{
 			Requeue:      true,
-			RequeueAfter: requeueAfter,
+			RequeueAfter: requeueInterval(r.RequeueInterval),
 		}, nil
 	} else if err != nil {
 		l.Error(err, "could not get ACL object")
@@ -144,7 +154,7 @@ func (r *TsuruCronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request
 
 	return ctrl.Result{
 		Requeue:      true,
-		RequeueAfter: requeueAfter,
+		RequeueAfter: requeueInterval(r.RequeueInterval),
 	}, nil
 }
 
@@ -152,6 +162,6 @@ func (r *TsuruCronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request
 func (r *TsuruCronJobReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&batchv1.CronJob{}).
-		WithOptions(controller.Options{MaxConcurrentReconciles: 2, RecoverPanic: true}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: maxConcurrentReconciles(r.MaxConcurrentReconciles, DefaultConcurrency), RecoverPanic: true}).
 		Complete(r)
 }
@@ -20,7 +20,7 @@ func (suite *ControllerSuite) TestTsuruCronJobReconcilerSimpleReconcile() {
 			Name:      "myjob",
 			Namespace: "default",
 			Labels: map[string]string{
-				tsuruJobLabel: "myjob",
+				DefaultLabelScheme.JobName: "myjob",
 			},
 		},
 	}
@@ -89,7 +89,7 @@ func (suite *ControllerSuite) TestTsuruCronJobReconcilerReconcileJobWithNoRules(
 			Name:      "myjob-no-rules",
 			Namespace: "default",
 			Labels: map[string]string{
-				tsuruJobLabel: "myjob",
+				DefaultLabelScheme.JobName: "myjob",
 			},
 		},
 	}
@@ -122,7 +122,7 @@ func (suite *ControllerSuite) TestTsuruCronJobReconcilerReconcileExistingJobWith
 			Name:      "myjob-no-rules",
 			Namespace: "default",
 			Labels: map[string]string{
-				tsuruJobLabel: "myjob-no-rules",
+				DefaultLabelScheme.JobName: "myjob-no-rules",
 			},
 		},
 	}
@@ -166,7 +166,7 @@ func (suite *ControllerSuite) TestTsuruCronJobReconcilerReconcileExistingJob() {
 			Name:      "myjob",
 			Namespace: "default",
 			Labels: map[string]string{
-				tsuruJobLabel: "myjob",
+				DefaultLabelScheme.JobName: "myjob",
 			},
 		},
 	}
@@ -210,7 +210,7 @@ func (suite *ControllerSuite) TestTsuruJobReconcilerReconcileJobWithErrors() {
 			Name:      "myjob-with-errors",
 			Namespace: "default",
 			Labels: map[string]string{
-				tsuruJobLabel: "myjob-with-errors",
+				DefaultLabelScheme.JobName: "myjob-with-errors",
 			},
 		},
 	}
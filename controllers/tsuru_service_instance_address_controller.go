@@ -0,0 +1,288 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	v1alpha1 "github.com/tsuru/acl-operator/api/v1alpha1"
+	"github.com/tsuru/acl-operator/clients/tsuruapi"
+)
+
+// serviceInstanceEndpointKeys are the keys of the custom info of service instances that hold their
+// endpoints, compared ignoring case. Values are lists separated by commas or spaces of hostnames,
+// IP addresses or CIDRs, with an optional port, or URLs, like mysql://db.example.com:3306
+var serviceInstanceEndpointKeys = []string{"Address", "Addresses", "Endpoint", "Endpoints", "Host", "Hosts"}
+
+// defaultURLPorts are the ports of endpoints written as URLs without port
+var defaultURLPorts = map[string]uint16{
+	"http":  80,
+	"https": 443,
+}
+
+// TsuruServiceInstanceAddressReconciler reconciles a TsuruServiceInstanceAddress object
+type TsuruServiceInstanceAddressReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	TsuruAPI tsuruapi.Client
+
+	RequeueInterval time.Duration
+
+	// RequeueJitter spreads each periodic requeue by up to this fraction of the interval,
+	// more or less, the requeues are not spread when zero
+	RequeueJitter float64
+
+	// TsuruAPITimeout is the deadline of each call to Tsuru API, defaults to DefaultTsuruAPITimeout
+	TsuruAPITimeout time.Duration
+
+	// Refresh enqueues objects on demand, like the resyncs of LeaderResync
+	Refresh *RefreshTrigger
+
+	// MaxConcurrentReconciles is the number of objects reconciled at once, defaults to DefaultConcurrency
+	MaxConcurrentReconciles int
+
+	backoff requeueBackoff
+}
+
+//+kubebuilder:rbac:groups=extensions.tsuru.io,resources=tsuruserviceinstanceaddresses,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=extensions.tsuru.io,resources=tsuruserviceinstanceaddresses/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=extensions.tsuru.io,resources=tsuruserviceinstanceaddresses/finalizers,verbs=update
+
+func (r *TsuruServiceInstanceAddressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
+	l := log.FromContext(ctx)
+
+	instanceAddress := &v1alpha1.TsuruServiceInstanceAddress{}
+	outcome := reconcileResultNoop
+	defer func() {
+		observeReconcileResult("tsuruserviceinstanceaddress", outcome, err)
+	}()
+
+	err = r.Client.Get(ctx, req.NamespacedName, instanceAddress)
+	if k8sErrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		l.Error(err, "could not get TsuruServiceInstanceAddress object")
+		return ctrl.Result{}, err
+	}
+
+	ctx, l = objectLogger(ctx, instanceAddress, "serviceName", instanceAddress.Spec.ServiceName, "instance", instanceAddress.Spec.Instance)
+
+	// the endpoints are kept as they are, the ACLs using them are not affected
+	if isPaused(instanceAddress) {
+		l.V(1).Info("TsuruServiceInstanceAddress is paused, skipping reconcile", "annotation", pausedAnnotation)
+		outcome = reconcileResultPaused
+		return ctrl.Result{}, nil
+	}
+
+	oldStatus := instanceAddress.Status.DeepCopy()
+	err = r.FillStatus(ctx, instanceAddress)
+	retryAfter := jitterRequeue(requeueInterval(r.RequeueInterval), r.RequeueJitter)
+	if err != nil {
+		instanceAddress.Status.Ready = false
+		instanceAddress.Status.Reason = err.Error()
+		outcome = reconcileResultError
+
+		if isTransientError(err) {
+			retryAfter = r.backoff.Next(req.Name)
+			l.Error(err, "transient error on TsuruServiceInstanceAddress, retrying", "retryAfter", retryAfter)
+		} else {
+			// a missing instance is not retried sooner, it may be created later
+			r.backoff.Reset(req.Name)
+			l.Info("TsuruServiceInstanceAddress can not be resolved", "reason", err.Error())
+		}
+	} else {
+		r.backoff.Reset(req.Name)
+	}
+
+	if oldStatus.Pool != instanceAddress.Status.Pool || oldStatus.Ready != instanceAddress.Status.Ready || oldStatus.Reason != instanceAddress.Status.Reason || !reflect.DeepEqual(oldStatus.Endpoints, instanceAddress.Status.Endpoints) {
+		err = r.Client.Status().Update(ctx, instanceAddress)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if outcome == reconcileResultNoop {
+			outcome = reconcileResultUpdated
+		}
+	}
+
+	// endpoints of instances change without events on the cluster, they are read again periodically
+	return ctrl.Result{
+		Requeue:      true,
+		RequeueAfter: retryAfter,
+	}, nil
+}
+
+func (r *TsuruServiceInstanceAddressReconciler) FillStatus(ctx context.Context, instanceAddress *v1alpha1.TsuruServiceInstanceAddress) error {
+	var serviceInfo *tsuruapi.ServiceInstanceInfo
+	err := callTsuruAPI(ctx, r.TsuruAPITimeout, "service instance info of "+instanceAddress.Spec.Instance, func(ctx context.Context) (err error) {
+		serviceInfo, err = r.TsuruAPI.ServiceInstanceInfo(ctx, instanceAddress.Spec.ServiceName, instanceAddress.Spec.Instance)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if serviceInfo == nil {
+		return errInstanceNotFound
+	}
+
+	endpoints, invalid := serviceInstanceEndpoints(serviceInfo.CustomInfo)
+	if len(invalid) > 0 {
+		log.FromContext(ctx).Info("invalid endpoints on custom info of service instance are ignored", "endpoints", invalid)
+	}
+	if len(endpoints) == 0 && len(invalid) > 0 {
+		return fmt.Errorf("no valid endpoint on custom info of service instance, invalid endpoints: %s", strings.Join(invalid, ", "))
+	}
+
+	instanceAddress.Status.Pool = serviceInfo.Pool
+
+	if !instanceAddress.Status.Ready || !reflect.DeepEqual(endpoints, instanceAddress.Status.Endpoints) {
+		instanceAddress.Status.Ready = true
+		instanceAddress.Status.Reason = ""
+		instanceAddress.Status.Endpoints = endpoints
+		instanceAddress.Status.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	return nil
+}
+
+// serviceInstanceEndpoints reads the endpoints of the keys of serviceInstanceEndpointKeys on
+// customInfo, returns the valid endpoints without duplicates and the values that are not endpoints
+func serviceInstanceEndpoints(customInfo map[string]interface{}) ([]v1alpha1.TsuruServiceInstanceEndpoint, []string) {
+	endpoints := []v1alpha1.TsuruServiceInstanceEndpoint{}
+	invalid := []string{}
+	seen := map[v1alpha1.TsuruServiceInstanceEndpoint]bool{}
+
+	for key, value := range customInfo {
+		if !isServiceInstanceEndpointKey(key) {
+			continue
+		}
+
+		text, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		for _, field := range strings.FieldsFunc(text, func(r rune) bool {
+			return r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\n'
+		}) {
+			endpoint, ok := parseServiceInstanceEndpoint(field)
+			if !ok {
+				invalid = append(invalid, field)
+				continue
+			}
+			if !seen[endpoint] {
+				seen[endpoint] = true
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+	}
+
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].Host != endpoints[j].Host {
+			return endpoints[i].Host < endpoints[j].Host
+		}
+		return endpoints[i].Port < endpoints[j].Port
+	})
+	sort.Strings(invalid)
+
+	if len(endpoints) == 0 {
+		endpoints = nil
+	}
+	return endpoints, invalid
+}
+
+func isServiceInstanceEndpointKey(key string) bool {
+	for _, endpointKey := range serviceInstanceEndpointKeys {
+		if strings.EqualFold(key, endpointKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseServiceInstanceEndpoint accepts hostnames, IP addresses and CIDRs with an optional port, and
+// URLs with a host, hostnames must be fully qualified like the names of externalDNS destinations
+func parseServiceInstanceEndpoint(value string) (v1alpha1.TsuruServiceInstanceEndpoint, bool) {
+	endpoint := v1alpha1.TsuruServiceInstanceEndpoint{}
+
+	host, port := value, ""
+	if strings.Contains(value, "://") {
+		u, err := url.Parse(value)
+		if err != nil || u.Hostname() == "" {
+			return endpoint, false
+		}
+		host, port = u.Hostname(), u.Port()
+		if port == "" {
+			endpoint.Port = defaultURLPorts[strings.ToLower(u.Scheme)]
+		}
+	} else if !isIPRange(value) {
+		if splitHost, splitPort, err := net.SplitHostPort(value); err == nil {
+			host, port = splitHost, splitPort
+		}
+	}
+
+	if port != "" {
+		number, err := strconv.ParseUint(port, 10, 16)
+		if err != nil || number == 0 {
+			return endpoint, false
+		}
+		endpoint.Port = uint16(number)
+	}
+
+	if isIPRange(host) {
+		endpoint.Host = host
+		return endpoint, true
+	}
+
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if len(validation.IsDNS1123Subdomain(host)) > 0 || v1alpha1.IsShortHostname(host) {
+		return endpoint, false
+	}
+	endpoint.Host = host
+	return endpoint, true
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *TsuruServiceInstanceAddressReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	builder := ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.TsuruServiceInstanceAddress{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: maxConcurrentReconciles(r.MaxConcurrentReconciles, DefaultConcurrency), RecoverPanic: true})
+
+	if r.Refresh != nil {
+		builder = builder.Watches(&source.Channel{Source: r.Refresh.serviceInstanceAddresses}, &handler.EnqueueRequestForObject{})
+	}
+
+	return builder.Complete(r)
+}
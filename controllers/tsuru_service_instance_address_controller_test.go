@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tsuru/acl-operator/api/scheme"
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+	"github.com/tsuru/acl-operator/clients/tsuruapi"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestServiceInstanceEndpoints(t *testing.T) {
+	endpoints, invalid := serviceInstanceEndpoints(map[string]interface{}{
+		"Address":   "10.0.0.1",
+		"endpoints": "DB-1.example.com:5432, db-2.example.com:5432;10.1.0.0/24 [2001:db8::1]:6379",
+		"URL":       "https://ignored.example.com",
+		"hosts":     "https://api.example.com/v1 amqp://queue.example.com:5672 mysql://db.example.com db-1.example.com.:5432",
+		"Host":      "db localhost:80 db.example.com:0 db.example.com:http",
+		"Endpoint":  42,
+	})
+	assert.Equal(t, []v1alpha1.TsuruServiceInstanceEndpoint{
+		{Host: "10.0.0.1"},
+		{Host: "10.1.0.0/24"},
+		{Host: "2001:db8::1", Port: 6379},
+		{Host: "api.example.com", Port: 443},
+		{Host: "db-1.example.com", Port: 5432},
+		{Host: "db-2.example.com", Port: 5432},
+		{Host: "db.example.com"},
+		{Host: "queue.example.com", Port: 5672},
+	}, endpoints)
+	assert.Equal(t, []string{"db", "db.example.com:0", "db.example.com:http", "localhost:80"}, invalid)
+
+	endpoints, invalid = serviceInstanceEndpoints(map[string]interface{}{"Plan": "small"})
+	assert.Nil(t, endpoints)
+	assert.Empty(t, invalid)
+}
+
+func TestTsuruServiceInstanceAddressReconciler(t *testing.T) {
+	ctx := context.Background()
+	instanceAddress := &v1alpha1.TsuruServiceInstanceAddress{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "mysql-my-database",
+		},
+		Spec: v1alpha1.TsuruServiceInstanceAddressSpec{
+			ServiceName: "mysql",
+			Instance:    "my-database",
+		},
+	}
+
+	api := &serviceInstanceAPI{
+		info: &tsuruapi.ServiceInstanceInfo{
+			Pool: "my-pool",
+			CustomInfo: map[string]interface{}{
+				"Endpoints": "primary.example.com:3306,replica.example.com:3306",
+			},
+		},
+	}
+	reconciler := &TsuruServiceInstanceAddressReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(instanceAddress).Build(),
+		Scheme:   scheme.Scheme,
+		TsuruAPI: api,
+	}
+	reconcile := func() (controllerruntime.Result, *v1alpha1.TsuruServiceInstanceAddress) {
+		result, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(instanceAddress),
+		})
+		require.NoError(t, err)
+
+		existing := &v1alpha1.TsuruServiceInstanceAddress{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(instanceAddress), existing)
+		require.NoError(t, err)
+		return result, existing
+	}
+
+	result, existing := reconcile()
+	assert.Equal(t, controllerruntime.Result{Requeue: true, RequeueAfter: DefaultRequeueInterval}, result)
+	assert.True(t, existing.Status.Ready)
+	assert.Equal(t, "my-pool", existing.Status.Pool)
+	assert.Equal(t, []v1alpha1.TsuruServiceInstanceEndpoint{
+		{Host: "primary.example.com", Port: 3306},
+		{Host: "replica.example.com", Port: 3306},
+	}, existing.Status.Endpoints)
+
+	api.info.CustomInfo = map[string]interface{}{"Endpoints": "db"}
+	_, existing = reconcile()
+	assert.False(t, existing.Status.Ready)
+	assert.Equal(t, "no valid endpoint on custom info of service instance, invalid endpoints: db", existing.Status.Reason)
+
+	api.info = nil
+	_, existing = reconcile()
+	assert.False(t, existing.Status.Ready)
+	assert.Equal(t, "Service instance not found", existing.Status.Reason)
+}
+
+func (suite *ControllerSuite) TestACLReconcilerTsuruServiceInstance() {
+	ctx := context.Background()
+	acl := &v1alpha1.ACL{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ACLSpec{
+			Source: v1alpha1.ACLSpecSource{
+				TsuruApp: "myapp",
+			},
+			Destinations: []v1alpha1.ACLSpecDestination{
+				{
+					TsuruServiceInstance: &v1alpha1.ACLSpecTsuruServiceInstance{
+						ServiceName: "mysql",
+						Instance:    "my-database",
+					},
+				},
+			},
+		},
+	}
+	instanceAddress := &v1alpha1.TsuruServiceInstanceAddress{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "mysql-my-database",
+		},
+		Spec: v1alpha1.TsuruServiceInstanceAddressSpec{
+			ServiceName: "mysql",
+			Instance:    "my-database",
+		},
+		Status: v1alpha1.TsuruServiceInstanceAddressStatus{
+			Ready: true,
+			Endpoints: []v1alpha1.TsuruServiceInstanceEndpoint{
+				{Host: "10.1.0.0/24"},
+				{Host: "db.example.com", Port: 3306},
+			},
+		},
+	}
+
+	reconciler := &ACLReconciler{
+		Client:   withServerSideApply(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(acl, instanceAddress).Build()),
+		Scheme:   scheme.Scheme,
+		Resolver: &fakeResolver{},
+		TsuruAPI: &fakeTsuruAPI{},
+	}
+	reconcile := func() *v1alpha1.ACL {
+		_, err := reconciler.Reconcile(ctx, controllerruntime.Request{
+			NamespacedName: client.ObjectKeyFromObject(acl),
+		})
+		suite.Require().NoError(err)
+
+		existingACL := &v1alpha1.ACL{}
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(acl), existingACL)
+		if k8sErrors.IsNotFound(err) {
+			return nil
+		}
+		suite.Require().NoError(err)
+		return existingACL
+	}
+
+	// the hostname of endpoint waits for its entry, the other endpoints are allowed meanwhile
+	reconcile()
+	dnsEntry := &v1alpha1.ACLDNSEntry{}
+	err := reconciler.Client.Get(ctx, client.ObjectKey{Name: "db.example.com"}, dnsEntry)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("db.example.com", dnsEntry.Spec.Host)
+	suite.Assert().Equal("default/myapp", dnsEntry.Annotations[aclOwnersAnnotation])
+
+	dnsEntry.Status = v1alpha1.ACLDNSEntryStatus{
+		Ready: true,
+		IPs: []v1alpha1.ACLDNSEntryStatusIP{
+			{Address: "3.3.3.3", ValidUntil: time.Now().Format(time.RFC3339)},
+		},
+	}
+	err = reconciler.Client.Status().Update(ctx, dnsEntry)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]controllerruntime.Request{{NamespacedName: client.ObjectKeyFromObject(acl)}}, appendOwnerRequests(nil, dnsEntry))
+
+	existingACL := reconcile()
+	suite.Assert().True(existingACL.Status.Ready)
+	existingNP := &netv1.NetworkPolicy{}
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: existingACL.Status.NetworkPolicy}, existingNP)
+	suite.Require().NoError(err)
+	tcp := corev1.ProtocolTCP
+	mysql := intstr.FromInt(3306)
+	suite.Assert().Equal([]netv1.NetworkPolicyEgressRule{
+		{
+			To: []netv1.NetworkPolicyPeer{{IPBlock: &netv1.IPBlock{CIDR: "10.1.0.0/24"}}},
+		},
+		{
+			Ports: []netv1.NetworkPolicyPort{{Protocol: &tcp, Port: &mysql}},
+			To:    []netv1.NetworkPolicyPeer{{IPBlock: &netv1.IPBlock{CIDR: "3.3.3.3/32"}}},
+		},
+	}, existingNP.Spec.Egress)
+
+	// the entries of endpoints are released with the instance
+	err = reconciler.Client.Delete(ctx, existingACL)
+	suite.Require().NoError(err)
+	reconcile()
+	err = reconciler.Client.Get(ctx, client.ObjectKey{Name: "db.example.com"}, &v1alpha1.ACLDNSEntry{})
+	suite.Assert().True(k8sErrors.IsNotFound(err))
+	err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(instanceAddress), &v1alpha1.TsuruServiceInstanceAddress{})
+	suite.Assert().True(k8sErrors.IsNotFound(err))
+}
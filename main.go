@@ -21,13 +21,17 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	"go.uber.org/zap/zapcore"
+	"k8s.io/apimachinery/pkg/labels"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"github.com/tsuru/acl-operator/api/scheme"
+	"github.com/tsuru/acl-operator/api/v1alpha1"
 
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -44,6 +48,10 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "render" {
+		os.Exit(runRender(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
@@ -55,24 +63,184 @@ func main() {
 	var tsuruAPIAddr string
 	var tsuruAPIToken string
 
+	var adminAddr string
+	var adminToken string
+
 	var gcDryRun bool
+	var enableWebhooks bool
+
+	var requeueInterval time.Duration
+	var requeueJitter float64
+	var tsuruAPITimeout time.Duration
+	var namespaceLabelKey string
+	var policyBackendName string
+	var ipFamilies string
+	var aggregateCIDRs bool
+	var dnsAllowedCIDRs string
+	var dnsDeniedCIDRs string
+	var maxIPsPerDNSEntry int
+	var dnsEntryGracePeriod time.Duration
+	var dnsLookupTimeout time.Duration
+	var dnsNameservers string
+	var dnsFallback bool
+	var dnsFailureBackoffThreshold int
+	var dnsMaxFailureBackoff time.Duration
+	var namespacedDNSEntries bool
+	var dryRun bool
+	var abortOnDestinationError bool
+	var reconcileDeadline time.Duration
+	var aclSelector string
+	var manageSharedObjects bool
+	var leaderResync bool
+	var resyncStagger time.Duration
+	var resyncInterval time.Duration
+	var leaderElectionID string
+	var forcePolicyOwnership bool
+	var clusterDNSEgress bool
+	var clusterDNSNamespace string
+	var clusterDNSPodLabels string
+	var connectivityFailureWindow time.Duration
+	var summaryInterval time.Duration
+	var tsuruAppInternalAddresses bool
+	var tsuruAppServiceIPs bool
+	var labelScheme controllers.LabelScheme
+	var policyNameTemplate string
+	var maxPolicySize int
+	var aclConcurrency int
+	var dnsEntryConcurrency int
+	var tsuruAppAddressConcurrency int
+	var rpaasInstanceAddressConcurrency int
+	var tsuruServiceInstanceAddressConcurrency int
+	var tsuruAppConcurrency int
+	var tsuruCronJobConcurrency int
+	var rpaasInstanceConcurrency int
+	var aclGroupConcurrency int
 
 	flag.StringVar(&aclAPIAddr, "acl-api-address", "", "The address of ACL API [required]")
 	flag.StringVar(&aclAPIUser, "acl-api-user", "", "The user of ACL API [required]")
 	flag.StringVar(&aclAPIPassword, "acl-api-password", "", "The password of ACL API [required]")
 
-	flag.StringVar(&tsuruAPIAddr, "tsuru-api-address", "", "The address of Tsuru API [required]")
+	flag.StringVar(&tsuruAPIAddr, "tsuru-api-address", "", "The address of Tsuru API, a comma separated list of endpoints fails over between them [required]")
 	flag.StringVar(&tsuruAPIToken, "tsuru-api-token", "", "The token of Tsuru API [required")
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.StringVar(&adminAddr, "admin-bind-address", "0",
+		"The address the admin endpoint binds to, like POST /refresh/dnsentry/{name}. Set 0 to disable it.")
+	flag.StringVar(&adminToken, "admin-token", "", "The bearer token required by the admin endpoint, ADMIN_TOKEN env is used when empty.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&leaderElectionID, "leader-election-id", "e1803b8b.extensions.tsuru.io",
+		"The name of the leader election lock, every shard of --acl-selector needs its own")
+	flag.StringVar(&aclSelector, "acl-selector", "",
+		"Label selector of the ACLs reconciled by this instance, like shard=a, so many instances reconcile a shard of the ACLs each. Every ACL when empty")
+	flag.BoolVar(&manageSharedObjects, "manage-shared-objects", true,
+		"Run the reconcilers of the objects shared by every shard: address objects, the ACLs generated from Tsuru apps, jobs, RPaaS instances and ACL groups, and the garbage collector. Disable it on every shard but one")
+	flag.BoolVar(&leaderResync, "leader-resync", true,
+		"Reconcile every ACL and address object shortly after this instance becomes the leader, so no object waits for a requeue scheduled by the previous leader")
+	flag.DurationVar(&resyncStagger, "resync-stagger", controllers.DefaultResyncStagger,
+		"The time the objects of a resync of --leader-resync are spread over")
+	flag.DurationVar(&resyncInterval, "resync-interval", 0,
+		"Resync every ACL and address object periodically as well, only after becoming the leader when 0")
 
 	flag.BoolVar(&gcDryRun, "gc-dry-run", false,
 		"Enable Dry run for garbage collector")
 
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Enable admission webhooks, requires serving certificates on the webhook server")
+
+	flag.DurationVar(&requeueInterval, "requeue-interval", controllers.DefaultRequeueInterval,
+		"The interval to reconcile again ACLs and resolved addresses")
+	flag.Float64Var(&requeueJitter, "requeue-jitter", controllers.DefaultRequeueJitter,
+		"The fraction of the requeue interval added or removed at random, so objects do not reconcile in lockstep, between 0 and 1")
+	flag.DurationVar(&tsuruAPITimeout, "tsuru-api-timeout", controllers.DefaultTsuruAPITimeout,
+		"The deadline of each call to Tsuru API, calls that time out are retried with backoff")
+	flag.StringVar(&namespaceLabelKey, "namespace-label-key", controllers.DefaultNamespaceLabelKey,
+		"The label of namespaces that holds its name, used to scope the peers of network policies")
+	flag.StringVar(&policyBackendName, "policy-backend", controllers.PolicyBackendKubernetes,
+		"The backend that writes the policies of ACLs: kubernetes or cilium, cilium allows externalDNS with wildcards")
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"Compute the policies of ACLs without writing them, the changes are reported on status.dryRunDiff of ACLs")
+	flag.BoolVar(&abortOnDestinationError, "abort-on-destination-error", false,
+		"Keep the policy of an ACL untouched when a destination without ruleID fails, by default the destination is skipped")
+	flag.DurationVar(&reconcileDeadline, "acl-reconcile-deadline", controllers.DefaultReconcileDeadline,
+		"The time an ACL reconcile spends resolving destinations, the rules resolved so far are applied and the ACL is requeued to resolve the remaining ones")
+	flag.BoolVar(&forcePolicyOwnership, "force-policy-ownership", false,
+		"Overwrite existing policies with the name of an ACL policy that were not created by the operator, by default the ACL reports a conflict")
+	flag.BoolVar(&clusterDNSEgress, "cluster-dns-egress", true,
+		"Add to every policy an egress rule allowing DNS queries (UDP and TCP 53) to the cluster DNS pods")
+	flag.StringVar(&clusterDNSNamespace, "cluster-dns-namespace", controllers.DefaultClusterDNS.Namespace,
+		"The namespace of the cluster DNS pods allowed by --cluster-dns-egress")
+	flag.StringVar(&clusterDNSPodLabels, "cluster-dns-pod-labels", labels.FormatLabels(controllers.DefaultClusterDNS.PodLabels),
+		"Comma separated list of key=value labels of the cluster DNS pods allowed by --cluster-dns-egress")
+	flag.StringVar(&ipFamilies, "ip-families", "",
+		"Comma separated list of IP families (IPv4, IPv6) allowed for resolved addresses of ACLs without spec.ipFamilies, all families when empty")
+	flag.BoolVar(&aggregateCIDRs, "aggregate-cidrs", false,
+		"Summarize the resolved addresses of ACLs without spec.cidrAggregation into the smallest list of CIDRs")
+	flag.StringVar(&dnsAllowedCIDRs, "dns-allowed-cidrs", "",
+		"Comma separated list of CIDRs the resolved addresses of ACLs without spec.addressFilter must be contained in, all addresses when empty")
+	flag.StringVar(&dnsDeniedCIDRs, "dns-denied-cidrs", "",
+		"Comma separated list of CIDRs of resolved addresses dropped from ACLs without spec.addressFilter, like 10.0.0.0/8,172.16.0.0/12,192.168.0.0/16")
+	flag.IntVar(&maxIPsPerDNSEntry, "max-ips-per-dns-entry", 0,
+		"The maximum of addresses kept by an ACLDNSEntry, the least recently seen are evicted first, no limit when zero")
+	flag.DurationVar(&dnsEntryGracePeriod, "dns-entry-grace-period", controllers.DefaultDNSEntryGracePeriod,
+		"The time an address is kept by an ACLDNSEntry after it stops resolving")
+	flag.BoolVar(&tsuruAppInternalAddresses, "tsuru-app-internal-addresses", false,
+		"Allow the internal addresses of apps on tsuruApp destinations, resolved from the cluster DNS, besides their router addresses.")
+	flag.BoolVar(&tsuruAppServiceIPs, "tsuru-app-service-ips", false,
+		"Allow the cluster IPs of the services of apps on tsuruApp destinations as ipBlock peers, for network plugins that evaluate policies before the service VIP is translated.")
+	flag.StringVar(&dnsNameservers, "dns-nameservers", "",
+		"Comma separated list of nameservers queried instead of the ones of resolv.conf, like tls://1.1.1.1 for DNS-over-TLS or https://dns.example.com/dns-query for DNS-over-HTTPS")
+	flag.BoolVar(&dnsFallback, "dns-fallback", false,
+		"Fall back to the system resolver when none of --dns-nameservers answers")
+	flag.DurationVar(&dnsLookupTimeout, "dns-lookup-timeout", controllers.DefaultDNSLookupTimeout,
+		"The deadline of the DNS lookups of an ACLDNSEntry or of the addresses of an app.")
+	flag.IntVar(&dnsFailureBackoffThreshold, "dns-failure-backoff-threshold", controllers.DefaultDNSFailureBackoffThreshold,
+		"The consecutive failed lookups of an ACLDNSEntry after which its requeue interval doubles on each failure")
+	flag.DurationVar(&dnsMaxFailureBackoff, "dns-max-failure-backoff", controllers.DefaultDNSMaxFailureBackoff,
+		"The maximum requeue interval of an ACLDNSEntry whose lookups keep failing")
+	flag.BoolVar(&namespacedDNSEntries, "namespaced-dns-entries", false,
+		"Create an ACLDNSEntry per namespace for each host, so namespaces do not share resolutions, by default entries are shared by the whole cluster")
+	flag.DurationVar(&connectivityFailureWindow, "readiness-failure-window", controllers.DefaultConnectivityFailureWindow,
+		"The time Tsuru API and DNS resolution may both fail before the readiness check fails")
+	flag.DurationVar(&summaryInterval, "acl-summary-interval", controllers.DefaultSummaryInterval,
+		"The minimum time between the counts of an ACLSummary, changes of ACLs meanwhile are counted on the next count")
+	flag.StringVar(&labelScheme.AppName, "label-app-name", controllers.DefaultLabelScheme.AppName,
+		"The label of pods that holds the name of tsuru app")
+	flag.StringVar(&labelScheme.AppProcess, "label-app-process", controllers.DefaultLabelScheme.AppProcess,
+		"The label of pods that holds the process of tsuru app")
+	flag.StringVar(&labelScheme.AppPool, "label-app-pool", controllers.DefaultLabelScheme.AppPool,
+		"The label of pods that holds the pool of tsuru app")
+	flag.StringVar(&labelScheme.JobName, "label-job-name", controllers.DefaultLabelScheme.JobName,
+		"The label of pods and cronjobs that holds the name of tsuru job")
+	flag.StringVar(&labelScheme.RpaasInstance, "label-rpaas-instance", controllers.DefaultLabelScheme.RpaasInstance,
+		"The label of pods and RPaaS instances that holds the name of RPaaS instance")
+	flag.StringVar(&labelScheme.RpaasService, "label-rpaas-service", controllers.DefaultLabelScheme.RpaasService,
+		"The label of pods and RPaaS instances that holds the service name of RPaaS instance")
+	flag.StringVar(&policyNameTemplate, "policy-name-template", controllers.DefaultPolicyNameTemplate,
+		"The text/template naming the NetworkPolicies of new ACLs from .Name, .Namespace and .Labels, existing policies keep their name")
+	flag.IntVar(&maxPolicySize, "max-policy-size", controllers.DefaultMaxPolicySize,
+		"The largest policy applied, in bytes of its rules encoded as JSON, ACLs with larger policies keep the applied policy and are not ready, there is no limit when negative")
+	flag.IntVar(&aclConcurrency, "acl-concurrency", controllers.DefaultACLConcurrency,
+		"The number of ACLs reconciled at once")
+	flag.IntVar(&dnsEntryConcurrency, "dns-entry-concurrency", controllers.DefaultDNSEntryConcurrency,
+		"The number of ACLDNSEntries resolved at once")
+	flag.IntVar(&tsuruAppAddressConcurrency, "tsuru-app-address-concurrency", controllers.DefaultConcurrency,
+		"The number of TsuruAppAddresses reconciled at once")
+	flag.IntVar(&rpaasInstanceAddressConcurrency, "rpaas-instance-address-concurrency", controllers.DefaultConcurrency,
+		"The number of RpaasInstanceAddresses reconciled at once")
+	flag.IntVar(&tsuruServiceInstanceAddressConcurrency, "tsuru-service-instance-address-concurrency", controllers.DefaultConcurrency,
+		"The number of TsuruServiceInstanceAddresses reconciled at once")
+	flag.IntVar(&tsuruAppConcurrency, "tsuru-app-concurrency", controllers.DefaultConcurrency,
+		"The number of TsuruApps reconciled at once")
+	flag.IntVar(&tsuruCronJobConcurrency, "tsuru-cronjob-concurrency", controllers.DefaultConcurrency,
+		"The number of cronjobs of tsuru jobs reconciled at once")
+	flag.IntVar(&rpaasInstanceConcurrency, "rpaas-instance-concurrency", controllers.DefaultConcurrency,
+		"The number of RPaaS instances reconciled at once")
+	flag.IntVar(&aclGroupConcurrency, "acl-group-concurrency", controllers.DefaultConcurrency,
+		"The number of ACLGroups reconciled at once")
+
 	opts := zap.Options{
 		Development:     true,
 		StacktraceLevel: zapcore.DPanicLevel,
@@ -102,7 +270,18 @@ func main() {
 		aclAPIPassword = os.Getenv("ACL_API_PASSWORD")
 	}
 
-	if tsuruAPIAddr == "" {
+	var tsuruAPIEndpoints []controllers.TsuruAPIEndpoint
+	for _, addr := range strings.Split(tsuruAPIAddr, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr != "" {
+			tsuruAPIEndpoints = append(tsuruAPIEndpoints, controllers.TsuruAPIEndpoint{
+				Address: addr,
+				Client:  tsuruapi.New(addr, tsuruAPIToken),
+			})
+		}
+	}
+
+	if len(tsuruAPIEndpoints) == 0 {
 		fmt.Println("TSURU_TARGET env or tsuru-api-address flag is not defined")
 		os.Exit(1)
 	}
@@ -118,18 +297,55 @@ func main() {
 		hasACLAPI = false
 	}
 
+	if adminToken == "" {
+		adminToken = os.Getenv("ADMIN_TOKEN")
+	}
+
+	if adminAddr != "0" && adminAddr != "" && adminToken == "" {
+		fmt.Println("ADMIN_TOKEN env or admin-token flag is required by admin-bind-address")
+		os.Exit(1)
+	}
+
+	if resyncStagger <= 0 || resyncInterval < 0 {
+		fmt.Println("resync-stagger must be positive and resync-interval can not be negative")
+		os.Exit(1)
+	}
+
 	if v := os.Getenv("GC_DRY_RUN"); v != "" {
 		gcDryRun = true
 	}
 
-	tsuruAPI := tsuruapi.New(tsuruAPIAddr, tsuruAPIToken)
+	if v := os.Getenv("ENABLE_WEBHOOKS"); v == "true" {
+		enableWebhooks = true
+	}
+
+	tsuruAPITracker := &controllers.ConnectivityTracker{}
+	dnsTracker := &controllers.ConnectivityTracker{}
+	tsuruAPIClient := tsuruAPIEndpoints[0].Client
+	if len(tsuruAPIEndpoints) > 1 {
+		tsuruAPIClient = controllers.FailoverTsuruAPI(tsuruAPIEndpoints)
+	}
+	tsuruAPI := controllers.TrackTsuruAPI(tsuruAPIClient, tsuruAPITracker)
+	resolver := controllers.NewResolver(dnsTracker)
+	if dnsNameservers != "" {
+		nameservers := []string{}
+		for _, nameserver := range strings.Split(dnsNameservers, ",") {
+			address, err := v1alpha1.ParseNameserver(strings.TrimSpace(nameserver))
+			if err != nil {
+				setupLog.Error(err, "invalid --dns-nameservers")
+				os.Exit(1)
+			}
+			nameservers = append(nameservers, address)
+		}
+		resolver = controllers.NewNameserversResolver(dnsTracker, nameservers, dnsFallback)
+	}
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme.Scheme,
 		MetricsBindAddress:     metricsAddr,
 		Port:                   9443,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "e1803b8b.extensions.tsuru.io",
+		LeaderElectionID:       leaderElectionID,
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -147,78 +363,275 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = (&controllers.ACLReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Resolver: controllers.DefaultResolver,
-		TsuruAPI: tsuruAPI,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "ACL")
+	policyBackend, err := controllers.PolicyBackendByName(policyBackendName, mgr.GetClient())
+	if err != nil {
+		setupLog.Error(err, "unable to create policy backend")
+		os.Exit(1)
+	}
+
+	selector, err := labels.Parse(aclSelector)
+	if err != nil {
+		setupLog.Error(err, "invalid --acl-selector")
+		os.Exit(1)
+	}
+	if selector.Empty() {
+		selector = nil
+	}
+
+	if requeueJitter < 0 || requeueJitter >= 1 {
+		setupLog.Error(fmt.Errorf("%v is not between 0 and 1", requeueJitter), "invalid --requeue-jitter")
 		os.Exit(1)
 	}
-	if err = (&controllers.ACLDNSEntryReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Resolver: controllers.DefaultResolver,
+
+	for name, concurrency := range map[string]int{
+		"acl-concurrency":                            aclConcurrency,
+		"dns-entry-concurrency":                      dnsEntryConcurrency,
+		"tsuru-app-address-concurrency":              tsuruAppAddressConcurrency,
+		"rpaas-instance-address-concurrency":         rpaasInstanceAddressConcurrency,
+		"tsuru-service-instance-address-concurrency": tsuruServiceInstanceAddressConcurrency,
+		"tsuru-app-concurrency":                      tsuruAppConcurrency,
+		"tsuru-cronjob-concurrency":                  tsuruCronJobConcurrency,
+		"rpaas-instance-concurrency":                 rpaasInstanceConcurrency,
+		"acl-group-concurrency":                      aclGroupConcurrency,
+	} {
+		if concurrency < 1 {
+			setupLog.Error(fmt.Errorf("%d is less than 1", concurrency), "invalid --"+name)
+			os.Exit(1)
+		}
+	}
+
+	policyName, err := controllers.ParsePolicyNameTemplate(policyNameTemplate)
+	if err != nil {
+		setupLog.Error(err, "invalid --policy-name-template")
+		os.Exit(1)
+	}
+
+	defaultIPFamilies, err := v1alpha1.ParseIPFamilies(ipFamilies)
+	if err != nil {
+		setupLog.Error(err, "invalid --ip-families")
+		os.Exit(1)
+	}
+
+	clusterDNSLabels, err := labels.ConvertSelectorToLabelsMap(clusterDNSPodLabels)
+	if err != nil || len(clusterDNSLabels) == 0 {
+		setupLog.Error(err, "invalid --cluster-dns-pod-labels")
+		os.Exit(1)
+	}
+	clusterDNS := &controllers.ClusterDNS{
+		Namespace: clusterDNSNamespace,
+		PodLabels: clusterDNSLabels,
+	}
+
+	var cidrAggregation *v1alpha1.ACLSpecCIDRAggregation
+	if aggregateCIDRs {
+		cidrAggregation = &v1alpha1.ACLSpecCIDRAggregation{Enabled: true}
+	}
+
+	addressFilter, err := v1alpha1.ParseAddressFilter(dnsAllowedCIDRs, dnsDeniedCIDRs)
+	if err != nil {
+		setupLog.Error(err, "invalid --dns-allowed-cidrs or --dns-denied-cidrs")
+		os.Exit(1)
+	}
+
+	refresh := controllers.NewRefreshTrigger()
+	if err = (&controllers.ACLReconciler{
+		Client:                  mgr.GetClient(),
+		APIReader:               mgr.GetAPIReader(),
+		Scheme:                  mgr.GetScheme(),
+		Resolver:                resolver,
+		TsuruAPI:                tsuruAPI,
+		TsuruAPITimeout:         tsuruAPITimeout,
+		RequeueInterval:         requeueInterval,
+		RequeueJitter:           requeueJitter,
+		NamespaceLabelKey:       namespaceLabelKey,
+		LabelScheme:             labelScheme,
+		PolicyBackend:           policyBackend,
+		IPFamilies:              defaultIPFamilies,
+		CIDRAggregation:         cidrAggregation,
+		AddressFilter:           addressFilter,
+		DryRun:                  dryRun,
+		ClusterDNSEgress:        clusterDNSEgress,
+		ClusterDNS:              clusterDNS,
+		TsuruAppServiceIPs:      tsuruAppServiceIPs,
+		NamespacedDNSEntries:    namespacedDNSEntries,
+		AbortOnDestinationError: abortOnDestinationError,
+		ReconcileDeadline:       reconcileDeadline,
+		Selector:                selector,
+		ForcePolicyOwnership:    forcePolicyOwnership,
+		Refresh:                 refresh,
+		PolicyNameTemplate:      policyName,
+		MaxPolicySize:           maxPolicySize,
+		MaxConcurrentReconciles: aclConcurrency,
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "ACLDNSEntry")
+		setupLog.Error(err, "unable to create controller", "controller", "ACL")
 		os.Exit(1)
 	}
 
-	if hasACLAPI {
-		if err = (&controllers.TsuruAppReconciler{
-			Client: mgr.GetClient(),
-			Scheme: mgr.GetScheme(),
-			ACLAPI: aclapi.New(aclAPIAddr, aclAPIUser, aclAPIPassword),
+	// address objects and the ACLs generated by the operator are shared by every shard of ACLs,
+	// a single instance reconciles them
+	if manageSharedObjects {
+		if err = (&controllers.ACLDNSEntryReconciler{
+			Client:                  mgr.GetClient(),
+			Scheme:                  mgr.GetScheme(),
+			Resolver:                resolver,
+			RequeueInterval:         requeueInterval,
+			RequeueJitter:           requeueJitter,
+			MaxIPsPerEntry:          maxIPsPerDNSEntry,
+			GracePeriod:             dnsEntryGracePeriod,
+			LookupTimeout:           dnsLookupTimeout,
+			Refresh:                 refresh,
+			FailureBackoffThreshold: dnsFailureBackoffThreshold,
+			MaxFailureBackoff:       dnsMaxFailureBackoff,
+			MaxConcurrentReconciles: dnsEntryConcurrency,
 		}).SetupWithManager(mgr); err != nil {
-			setupLog.Error(err, "unable to create controller", "controller", "TsuruAppReconciler")
+			setupLog.Error(err, "unable to create controller", "controller", "ACLDNSEntry")
 			os.Exit(1)
 		}
 
-		if err = (&controllers.TsuruCronJobReconciler{
-			Client: mgr.GetClient(),
-			Scheme: mgr.GetScheme(),
-			ACLAPI: aclapi.New(aclAPIAddr, aclAPIUser, aclAPIPassword),
+		if hasACLAPI {
+			if err = (&controllers.TsuruAppReconciler{
+				Client:                  mgr.GetClient(),
+				Scheme:                  mgr.GetScheme(),
+				ACLAPI:                  aclapi.New(aclAPIAddr, aclAPIUser, aclAPIPassword),
+				RequeueInterval:         requeueInterval,
+				MaxConcurrentReconciles: tsuruAppConcurrency,
+			}).SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create controller", "controller", "TsuruAppReconciler")
+				os.Exit(1)
+			}
+
+			if err = (&controllers.TsuruCronJobReconciler{
+				Client:                  mgr.GetClient(),
+				Scheme:                  mgr.GetScheme(),
+				ACLAPI:                  aclapi.New(aclAPIAddr, aclAPIUser, aclAPIPassword),
+				RequeueInterval:         requeueInterval,
+				LabelScheme:             labelScheme,
+				MaxConcurrentReconciles: tsuruCronJobConcurrency,
+			}).SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create controller", "controller", "TsuruCronJobReconciler")
+				os.Exit(1)
+			}
+		}
+
+		if err = (&controllers.RpaasInstanceReconciler{
+			Client:                  mgr.GetClient(),
+			Scheme:                  mgr.GetScheme(),
+			RequeueInterval:         requeueInterval,
+			LabelScheme:             labelScheme,
+			MaxConcurrentReconciles: rpaasInstanceConcurrency,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "RpaasInstanceReconciler")
+			os.Exit(1)
+		}
+
+		if err = (&controllers.TsuruAppAddressReconciler{
+			Client:                  mgr.GetClient(),
+			Scheme:                  mgr.GetScheme(),
+			Resolver:                resolver,
+			TsuruAPI:                tsuruAPI,
+			TsuruAPITimeout:         tsuruAPITimeout,
+			RequeueInterval:         requeueInterval,
+			RequeueJitter:           requeueJitter,
+			InternalAddresses:       tsuruAppInternalAddresses,
+			LookupTimeout:           dnsLookupTimeout,
+			Refresh:                 refresh,
+			MaxConcurrentReconciles: tsuruAppAddressConcurrency,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "TsuruAppAddress")
+			os.Exit(1)
+		}
+		if err = (&controllers.RpaasInstanceAddressReconciler{
+			Client:                  mgr.GetClient(),
+			Scheme:                  mgr.GetScheme(),
+			Resolver:                resolver,
+			TsuruAPI:                tsuruAPI,
+			TsuruAPITimeout:         tsuruAPITimeout,
+			RequeueInterval:         requeueInterval,
+			RequeueJitter:           requeueJitter,
+			Refresh:                 refresh,
+			MaxConcurrentReconciles: rpaasInstanceAddressConcurrency,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "RpaasInstanceAddress")
+			os.Exit(1)
+		}
+		if err = (&controllers.TsuruServiceInstanceAddressReconciler{
+			Client:                  mgr.GetClient(),
+			Scheme:                  mgr.GetScheme(),
+			TsuruAPI:                tsuruAPI,
+			TsuruAPITimeout:         tsuruAPITimeout,
+			RequeueInterval:         requeueInterval,
+			RequeueJitter:           requeueJitter,
+			Refresh:                 refresh,
+			MaxConcurrentReconciles: tsuruServiceInstanceAddressConcurrency,
 		}).SetupWithManager(mgr); err != nil {
-			setupLog.Error(err, "unable to create controller", "controller", "TsuruCronJobReconciler")
+			setupLog.Error(err, "unable to create controller", "controller", "TsuruServiceInstanceAddress")
+			os.Exit(1)
+		}
+		if err = (&controllers.ACLGroupReconciler{
+			Client:                  mgr.GetClient(),
+			Scheme:                  mgr.GetScheme(),
+			MaxConcurrentReconciles: aclGroupConcurrency,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ACLGroup")
+			os.Exit(1)
+		}
+		if err = (&controllers.ACLSummaryReconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   mgr.GetScheme(),
+			Interval: summaryInterval,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ACLSummary")
 			os.Exit(1)
 		}
 	}
 
-	if err = (&controllers.RpaasInstanceReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "RpaasInstanceReconciler")
-		os.Exit(1)
+	if enableWebhooks {
+		if err = (&v1alpha1.ACL{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ACL")
+			os.Exit(1)
+		}
 	}
 
-	if err = (&controllers.TsuruAppAddressReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Resolver: controllers.DefaultResolver,
-		TsuruAPI: tsuruAPI,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "TsuruAppAddress")
-		os.Exit(1)
+	if manageSharedObjects {
+		gc := &controllers.ACLGarbageCollector{
+			Client:               mgr.GetClient(),
+			APIReader:            mgr.GetAPIReader(),
+			DryRunOutput:         os.Stdout,
+			DryRun:               gcDryRun,
+			Logger:               ctrl.Log.WithName("acl-gc"),
+			LabelScheme:          labelScheme,
+			NamespacedDNSEntries: namespacedDNSEntries,
+		}
+		go gc.Run(context.Background())
 	}
-	if err = (&controllers.RpaasInstanceAddressReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Resolver: controllers.DefaultResolver,
-		TsuruAPI: tsuruAPI,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "RpaasInstanceAddress")
-		os.Exit(1)
+
+	if leaderResync {
+		if err := mgr.Add(&controllers.LeaderResync{
+			Client:         mgr.GetClient(),
+			Refresh:        refresh,
+			Selector:       selector,
+			AddressObjects: manageSharedObjects,
+			Stagger:        resyncStagger,
+			Interval:       resyncInterval,
+			Logger:         ctrl.Log.WithName("leader-resync"),
+		}); err != nil {
+			setupLog.Error(err, "unable to set up leader resync")
+			os.Exit(1)
+		}
 	}
 
-	gc := &controllers.ACLGarbageCollector{
-		Client:       mgr.GetClient(),
-		DryRunOutput: os.Stdout,
-		DryRun:       gcDryRun,
-		Logger:       ctrl.Log.WithName("acl-gc"),
+	if adminAddr != "" && adminAddr != "0" {
+		if err := mgr.Add(&controllers.AdminServer{
+			Client:  mgr.GetClient(),
+			Refresh: refresh,
+			Addr:    adminAddr,
+			Token:   adminToken,
+			Logger:  ctrl.Log.WithName("admin"),
+		}); err != nil {
+			setupLog.Error(err, "unable to set up admin server")
+			os.Exit(1)
+		}
 	}
-	go gc.Run(context.Background())
 
 	//+kubebuilder:scaffold:builder
 
@@ -230,6 +643,15 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	connectivityCheck := &controllers.ConnectivityCheck{
+		TsuruAPI: tsuruAPITracker,
+		DNS:      dnsTracker,
+		Window:   connectivityFailureWindow,
+	}
+	if err := mgr.AddReadyzCheck("connectivity", connectivityCheck.Check); err != nil {
+		setupLog.Error(err, "unable to set up connectivity check")
+		os.Exit(1)
+	}
 
 	setupLog.Info("starting manager")
 
@@ -0,0 +1,195 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/tsuru/acl-operator/api/v1alpha1"
+	"github.com/tsuru/acl-operator/clients/tsuruapi"
+	"github.com/tsuru/acl-operator/controllers"
+)
+
+const renderUsage = `Usage: manager render [flags] <acl.yaml>
+
+Prints the policy that the ACL of the manifest would produce, without a cluster. Hostnames are
+resolved by --hosts, or by the resolver of the system with --resolve, and tsuru destinations by
+the Tsuru API of --tsuru-api-address, a manifest of "-" is read from stdin.
+
+`
+
+// runRender is the render subcommand, it returns the exit code of the operator binary
+func runRender(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, renderUsage)
+		flags.PrintDefaults()
+	}
+
+	var hosts string
+	var resolve bool
+	var tsuruAPIAddr string
+	var tsuruAPIToken string
+	var policyBackend string
+	var clusterDNSEgress bool
+	var ipFamilies string
+	flags.StringVar(&hosts, "hosts", "",
+		"The addresses of hostnames, like example.com=10.0.0.1;10.0.0.2,api.example.com=10.0.1.1, other hostnames are not found")
+	flags.BoolVar(&resolve, "resolve", false,
+		"Resolve hostnames with the resolver of the system instead of --hosts")
+	flags.StringVar(&tsuruAPIAddr, "tsuru-api-address", "",
+		"The address of Tsuru API, tsuru destinations fail without it")
+	flags.StringVar(&tsuruAPIToken, "tsuru-api-token", "", "The token of Tsuru API, TSURU_TOKEN env is used when empty")
+	flags.StringVar(&policyBackend, "policy-backend", controllers.PolicyBackendKubernetes,
+		"The kind of policy rendered, kubernetes for NetworkPolicies or cilium for CiliumNetworkPolicies")
+	flags.BoolVar(&clusterDNSEgress, "cluster-dns-egress", true,
+		"Add to the policy an egress rule allowing DNS queries to the cluster DNS")
+	flags.StringVar(&ipFamilies, "ip-families", "",
+		"The IP families of resolved addresses of ACLs without spec.ipFamilies, IPv4 and IPv6, all families when empty")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	acl, err := readACLManifest(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "could not read ACL: %v\n", err)
+		return 1
+	}
+
+	opts := controllers.RenderOptions{
+		PolicyBackend:    policyBackend,
+		ClusterDNSEgress: clusterDNSEgress,
+	}
+	opts.IPFamilies, err = v1alpha1.ParseIPFamilies(ipFamilies)
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid --ip-families: %v\n", err)
+		return 2
+	}
+
+	if resolve {
+		opts.Resolver = controllers.NewResolver(nil)
+	} else {
+		opts.Resolver, err = parseHosts(hosts)
+		if err != nil {
+			fmt.Fprintf(stderr, "invalid --hosts: %v\n", err)
+			return 2
+		}
+	}
+
+	if tsuruAPIAddr != "" {
+		if tsuruAPIToken == "" {
+			tsuruAPIToken = os.Getenv("TSURU_TOKEN")
+		}
+		opts.TsuruAPI = tsuruapi.New(tsuruAPIAddr, tsuruAPIToken)
+	}
+
+	policy, renderedACL, err := controllers.RenderPolicy(context.Background(), acl, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "could not render ACL: %v\n", err)
+		return 1
+	}
+
+	// errors and warnings of destinations are reported like on the status of the ACL
+	for _, ruleError := range renderedACL.Status.RuleErrors {
+		fmt.Fprintf(stderr, "error: %s\n", ruleError.Error)
+	}
+	for _, warning := range renderedACL.Status.Warnings {
+		fmt.Fprintf(stderr, "warning: %s\n", warning)
+	}
+
+	if policy == nil {
+		fmt.Fprintf(stderr, "no policy is rendered: %s\n", renderedACL.Status.Reason)
+		return 1
+	}
+
+	// the ACL is not on a cluster, so the policy has no owner yet
+	policy.SetOwnerReferences(nil)
+	data, err := yaml.Marshal(policy)
+	if err != nil {
+		fmt.Fprintf(stderr, "could not encode policy: %v\n", err)
+		return 1
+	}
+	fmt.Fprint(stdout, string(data))
+
+	if !renderedACL.Status.Ready {
+		fmt.Fprintf(stderr, "ACL would not be ready: %s\n", renderedACL.Status.Reason)
+		return 1
+	}
+	return 0
+}
+
+func readACLManifest(path string) (*v1alpha1.ACL, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	acl := &v1alpha1.ACL{}
+	err = yaml.UnmarshalStrict(data, acl)
+	if err != nil {
+		return nil, err
+	}
+	if acl.Kind != "" && acl.Kind != "ACL" {
+		return nil, fmt.Errorf("manifest has kind %s, not ACL", acl.Kind)
+	}
+	if acl.Name == "" {
+		return nil, fmt.Errorf("manifest has no metadata.name")
+	}
+	return acl, nil
+}
+
+// parseHosts parses the host=address;address pairs of --hosts
+func parseHosts(value string) (controllers.StaticResolver, error) {
+	resolver := controllers.StaticResolver{}
+	if value == "" {
+		return resolver, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		host, addresses, ok := strings.Cut(pair, "=")
+		if !ok || host == "" || addresses == "" {
+			return nil, fmt.Errorf("%q is not host=address", pair)
+		}
+		for _, address := range strings.Split(addresses, ";") {
+			if net.ParseIP(address) == nil {
+				return nil, fmt.Errorf("%q of host %s is not an IP address", address, host)
+			}
+			resolver[host] = append(resolver[host], address)
+		}
+	}
+	return resolver, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tsuru/acl-operator/controllers"
+)
+
+const renderTestACL = `apiVersion: extensions.tsuru.io/v1alpha1
+kind: ACL
+metadata:
+  name: myapp
+  namespace: default
+spec:
+  source:
+    tsuruApp: myapp
+  destinations:
+  - externalIP:
+      ip: 10.0.0.1/32
+  - externalDNS:
+      name: api.example.com
+`
+
+func writeManifest(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "acl.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestParseHosts(t *testing.T) {
+	resolver, err := parseHosts("example.com=10.0.0.1;10.0.0.2,api.example.com=10.0.1.1,ipv6.example.com=2001:db8::1")
+	require.NoError(t, err)
+	assert.Equal(t, controllers.StaticResolver{
+		"example.com":      {"10.0.0.1", "10.0.0.2"},
+		"api.example.com":  {"10.0.1.1"},
+		"ipv6.example.com": {"2001:db8::1"},
+	}, resolver)
+
+	resolver, err = parseHosts("")
+	require.NoError(t, err)
+	assert.Empty(t, resolver)
+
+	_, err = parseHosts("example.com")
+	assert.EqualError(t, err, `"example.com" is not host=address`)
+
+	_, err = parseHosts("=10.0.0.1")
+	assert.EqualError(t, err, `"=10.0.0.1" is not host=address`)
+
+	_, err = parseHosts("example.com=")
+	assert.EqualError(t, err, `"example.com=" is not host=address`)
+
+	_, err = parseHosts("example.com=10.0.0.1;other.example.com")
+	assert.EqualError(t, err, `"other.example.com" of host example.com is not an IP address`)
+}
+
+func TestRunRender(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := runRender([]string{"--hosts", "api.example.com=10.0.1.1", writeManifest(t, renderTestACL)}, stdout, stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Empty(t, stderr.String())
+	assert.Contains(t, stdout.String(), "kind: NetworkPolicy")
+	assert.Contains(t, stdout.String(), "name: acl-myapp")
+	assert.Contains(t, stdout.String(), "cidr: 10.0.0.1/32")
+	assert.Contains(t, stdout.String(), "cidr: 10.0.1.1/32")
+	assert.NotContains(t, stdout.String(), "ownerReferences")
+
+	// hostnames missing from --hosts are not found and the ACL would not be ready
+	stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+	code = runRender([]string{writeManifest(t, renderTestACL)}, stdout, stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout.String(), "cidr: 10.0.0.1/32")
+	assert.NotContains(t, stdout.String(), "10.0.1.1")
+	assert.Contains(t, stderr.String(), "ACL would not be ready")
+
+	stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+	code = runRender([]string{"--policy-backend", controllers.PolicyBackendCilium, "--cluster-dns-egress=false", "--hosts", "api.example.com=10.0.1.1", writeManifest(t, renderTestACL)}, stdout, stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "kind: CiliumNetworkPolicy")
+}
+
+func TestRunRenderFlags(t *testing.T) {
+	manifest := writeManifest(t, renderTestACL)
+	tests := []struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		{args: []string{}, code: 2, stderr: "Usage: manager render [flags] <acl.yaml>"},
+		{args: []string{manifest, manifest}, code: 2, stderr: "Usage: manager render [flags] <acl.yaml>"},
+		{args: []string{"--unknown", manifest}, code: 2, stderr: "flag provided but not defined: -unknown"},
+		{args: []string{"--ip-families", "IPv5", manifest}, code: 2, stderr: "invalid --ip-families:"},
+		{args: []string{"--hosts", "api.example.com", manifest}, code: 2, stderr: `invalid --hosts: "api.example.com" is not host=address`},
+		{args: []string{filepath.Join(t.TempDir(), "missing.yaml")}, code: 1, stderr: "could not read ACL:"},
+		{args: []string{writeManifest(t, "kind: ConfigMap\nmetadata:\n  name: other\n")}, code: 1, stderr: "could not read ACL: manifest has kind ConfigMap, not ACL"},
+		{args: []string{writeManifest(t, "kind: ACL\nspec: {}\n")}, code: 1, stderr: "could not read ACL: manifest has no metadata.name"},
+		{args: []string{writeManifest(t, "kind: ACL\nmetadata:\n  name: myapp\nspec:\n  unknown: true\n")}, code: 1, stderr: `unknown field "unknown"`},
+	}
+
+	for _, tt := range tests {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		code := runRender(tt.args, stdout, stderr)
+		assert.Equal(t, tt.code, code, "args %v", tt.args)
+		assert.Contains(t, stderr.String(), tt.stderr, "args %v", tt.args)
+		assert.Empty(t, stdout.String(), "args %v", tt.args)
+	}
+}